@@ -18,4 +18,4 @@ package sync_pb
 
 ////go:generate protoc --plugin=/Users/mac/go/bin/protoc-gen-go-cast -I=../ -I=. -I=../include --go-cast_out=plugins=protoc-gen-go-cast,paths=source_relative:. types.proto
 //go:generate protoc  -I=../ -I=. -I=../include --go-cast_out=paths=source_relative:. sync_pb.proto
-//go:generate sszgen -path=. -objs=BodiesByRangeRequest,HeadersByRangeRequest,Ping,ForkData,Status --include=../types_pb -output=generated.ssz.go
+//go:generate sszgen -path=. -objs=BodiesByRangeRequest,HeadersByRangeRequest,Ping,ForkData,Status,NewBlockHash --include=../types_pb -output=generated.ssz.go
@@ -1,5 +1,5 @@
 // Code generated by fastssz. DO NOT EDIT.
-// Hash: b39bb565b34add934304fdd716d14cb913acca74f226a8fd1f0c0c390e3bc247
+// Hash: 8d4ab891ca77b9d1f6548c29abb13c9b126c47a2db278cb38af14383dcc4e2d3
 package sync_pb
 
 import (
@@ -547,3 +547,135 @@ func (b *BodiesByRangeRequest) HashTreeRootWith(hh *ssz.Hasher) (err error) {
 	}
 	return
 }
+
+// MarshalSSZ ssz marshals the NewBlockHash object
+func (n *NewBlockHash) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(n)
+}
+
+// MarshalSSZTo ssz marshals the NewBlockHash object to a target array
+func (n *NewBlockHash) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+	offset := int(8)
+
+	// Offset (0) 'Hash'
+	dst = ssz.WriteOffset(dst, offset)
+	if n.Hash == nil {
+		n.Hash = new(types_pb.H256)
+	}
+	offset += n.Hash.SizeSSZ()
+
+	// Offset (1) 'Number'
+	dst = ssz.WriteOffset(dst, offset)
+	if n.Number == nil {
+		n.Number = new(types_pb.H256)
+	}
+	offset += n.Number.SizeSSZ()
+
+	// Field (0) 'Hash'
+	if dst, err = n.Hash.MarshalSSZTo(dst); err != nil {
+		return
+	}
+
+	// Field (1) 'Number'
+	if dst, err = n.Number.MarshalSSZTo(dst); err != nil {
+		return
+	}
+
+	return
+}
+
+// UnmarshalSSZ ssz unmarshals the NewBlockHash object
+func (n *NewBlockHash) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size < 8 {
+		return ssz.ErrSize
+	}
+
+	tail := buf
+	var o0, o1 uint64
+
+	// Offset (0) 'Hash'
+	if o0 = ssz.ReadOffset(buf[0:4]); o0 > size {
+		return ssz.ErrOffset
+	}
+
+	if o0 < 8 {
+		return ssz.ErrInvalidVariableOffset
+	}
+
+	// Offset (1) 'Number'
+	if o1 = ssz.ReadOffset(buf[4:8]); o1 > size || o0 > o1 {
+		return ssz.ErrOffset
+	}
+
+	// Field (0) 'Hash'
+	{
+		buf = tail[o0:o1]
+		if n.Hash == nil {
+			n.Hash = new(types_pb.H256)
+		}
+		if err = n.Hash.UnmarshalSSZ(buf); err != nil {
+			return err
+		}
+	}
+
+	// Field (1) 'Number'
+	{
+		buf = tail[o1:]
+		if n.Number == nil {
+			n.Number = new(types_pb.H256)
+		}
+		if err = n.Number.UnmarshalSSZ(buf); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// SizeSSZ returns the ssz encoded size in bytes for the NewBlockHash object
+func (n *NewBlockHash) SizeSSZ() (size int) {
+	size = 8
+
+	// Field (0) 'Hash'
+	if n.Hash == nil {
+		n.Hash = new(types_pb.H256)
+	}
+	size += n.Hash.SizeSSZ()
+
+	// Field (1) 'Number'
+	if n.Number == nil {
+		n.Number = new(types_pb.H256)
+	}
+	size += n.Number.SizeSSZ()
+
+	return
+}
+
+// HashTreeRoot ssz hashes the NewBlockHash object
+func (n *NewBlockHash) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(n)
+}
+
+// HashTreeRootWith ssz hashes the NewBlockHash object with a hasher
+func (n *NewBlockHash) HashTreeRootWith(hh *ssz.Hasher) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'Hash'
+	if err = n.Hash.HashTreeRootWith(hh); err != nil {
+		return
+	}
+
+	// Field (1) 'Number'
+	if err = n.Number.HashTreeRootWith(hh); err != nil {
+		return
+	}
+
+	if ssz.EnableVectorizedHTR {
+		hh.MerkleizeVectorizedHTR(indx)
+	} else {
+		hh.Merkleize(indx)
+	}
+	return
+}
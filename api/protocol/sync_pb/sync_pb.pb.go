@@ -323,6 +323,61 @@ func (x *BodiesByRangeRequest) GetStep() uint64 {
 	return 0
 }
 
+type NewBlockHash struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Hash   *types_pb.H256 `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	Number *types_pb.H256 `protobuf:"bytes,2,opt,name=number,proto3" json:"number,omitempty"`
+}
+
+func (x *NewBlockHash) Reset() {
+	*x = NewBlockHash{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sync_pb_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *NewBlockHash) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NewBlockHash) ProtoMessage() {}
+
+func (x *NewBlockHash) ProtoReflect() protoreflect.Message {
+	mi := &file_sync_pb_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NewBlockHash.ProtoReflect.Descriptor instead.
+func (*NewBlockHash) Descriptor() ([]byte, []int) {
+	return file_sync_pb_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *NewBlockHash) GetHash() *types_pb.H256 {
+	if x != nil {
+		return x.Hash
+	}
+	return nil
+}
+
+func (x *NewBlockHash) GetNumber() *types_pb.H256 {
+	if x != nil {
+		return x.Number
+	}
+	return nil
+}
+
 var File_sync_pb_proto protoreflect.FileDescriptor
 
 var file_sync_pb_proto_rawDesc = []byte{
@@ -363,10 +418,16 @@ var file_sync_pb_proto_rawDesc = []byte{
 	0x63, 0x6b, 0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x75, 0x6e,
 	0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x12,
 	0x0a, 0x04, 0x73, 0x74, 0x65, 0x70, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x04, 0x73, 0x74,
-	0x65, 0x70, 0x42, 0x30, 0x5a, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d,
-	0x2f, 0x61, 0x6d, 0x61, 0x7a, 0x65, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x2f, 0x61, 0x6d, 0x63, 0x2f,
-	0x61, 0x70, 0x69, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x2f, 0x73, 0x79, 0x6e,
-	0x63, 0x5f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x65, 0x70, 0x22, 0x5a, 0x0a, 0x0c, 0x4e, 0x65, 0x77, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x61,
+	0x73, 0x68, 0x12, 0x22, 0x0a, 0x04, 0x68, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x0e, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x5f, 0x70, 0x62, 0x2e, 0x48, 0x32, 0x35, 0x36,
+	0x52, 0x04, 0x68, 0x61, 0x73, 0x68, 0x12, 0x26, 0x0a, 0x06, 0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x5f, 0x70,
+	0x62, 0x2e, 0x48, 0x32, 0x35, 0x36, 0x52, 0x06, 0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x42, 0x33,
+	0x5a, 0x31, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6e, 0x34, 0x32,
+	0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x2f, 0x4e, 0x34, 0x32, 0x2f, 0x61,
+	0x70, 0x69, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x2f, 0x73, 0x79, 0x6e, 0x63,
+	0x5f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -381,27 +442,30 @@ func file_sync_pb_proto_rawDescGZIP() []byte {
 	return file_sync_pb_proto_rawDescData
 }
 
-var file_sync_pb_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_sync_pb_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
 var file_sync_pb_proto_goTypes = []interface{}{
 	(*HeadersByRangeRequest)(nil), // 0: sync_bp.HeadersByRangeRequest
 	(*Ping)(nil),                  // 1: sync_bp.Ping
 	(*Status)(nil),                // 2: sync_bp.Status
 	(*ForkData)(nil),              // 3: sync_bp.ForkData
 	(*BodiesByRangeRequest)(nil),  // 4: sync_bp.BodiesByRangeRequest
-	(*types_pb.H256)(nil),         // 5: types_pb.H256
+	(*NewBlockHash)(nil),          // 5: sync_bp.NewBlockHash
+	(*types_pb.H256)(nil),         // 6: types_pb.H256
 }
 var file_sync_pb_proto_depIdxs = []int32{
-	5, // 0: sync_bp.HeadersByRangeRequest.startBlockNumber:type_name -> types_pb.H256
-	5, // 1: sync_bp.Status.genesisHash:type_name -> types_pb.H256
-	5, // 2: sync_bp.Status.currentHeight:type_name -> types_pb.H256
-	5, // 3: sync_bp.ForkData.current_version:type_name -> types_pb.H256
-	5, // 4: sync_bp.ForkData.genesis_validators_root:type_name -> types_pb.H256
-	5, // 5: sync_bp.BodiesByRangeRequest.startBlockNumber:type_name -> types_pb.H256
-	6, // [6:6] is the sub-list for method output_type
-	6, // [6:6] is the sub-list for method input_type
-	6, // [6:6] is the sub-list for extension type_name
-	6, // [6:6] is the sub-list for extension extendee
-	0, // [0:6] is the sub-list for field type_name
+	6, // 0: sync_bp.HeadersByRangeRequest.startBlockNumber:type_name -> types_pb.H256
+	6, // 1: sync_bp.Status.genesisHash:type_name -> types_pb.H256
+	6, // 2: sync_bp.Status.currentHeight:type_name -> types_pb.H256
+	6, // 3: sync_bp.ForkData.current_version:type_name -> types_pb.H256
+	6, // 4: sync_bp.ForkData.genesis_validators_root:type_name -> types_pb.H256
+	6, // 5: sync_bp.BodiesByRangeRequest.startBlockNumber:type_name -> types_pb.H256
+	6, // 6: sync_bp.NewBlockHash.hash:type_name -> types_pb.H256
+	6, // 7: sync_bp.NewBlockHash.number:type_name -> types_pb.H256
+	8, // [8:8] is the sub-list for method output_type
+	8, // [8:8] is the sub-list for method input_type
+	8, // [8:8] is the sub-list for extension type_name
+	8, // [8:8] is the sub-list for extension extendee
+	0, // [0:8] is the sub-list for field type_name
 }
 
 func init() { file_sync_pb_proto_init() }
@@ -470,6 +534,18 @@ func file_sync_pb_proto_init() {
 				return nil
 			}
 		}
+		file_sync_pb_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*NewBlockHash); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -477,7 +553,7 @@ func file_sync_pb_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_sync_pb_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   5,
+			NumMessages:   6,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
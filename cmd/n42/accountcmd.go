@@ -27,7 +27,6 @@ import (
 	"github.com/n42blockchain/N42/accounts"
 	"github.com/n42blockchain/N42/accounts/keystore"
 	"github.com/n42blockchain/N42/common/crypto"
-	"github.com/n42blockchain/N42/conf"
 	"github.com/n42blockchain/N42/internal/node"
 )
 
@@ -218,13 +217,10 @@ func importWallet(ctx *cli.Context) error {
 }
 
 func accountList(ctx *cli.Context) error {
-	cfg := DefaultConfig
-	// Load config file.
-	if len(cfgFile) > 0 {
-		if err := conf.LoadConfigFromFile(cfgFile, &cfg); err != nil {
-			utils.Fatalf("%v", err)
-		}
+	if err := loadConfig(ctx); err != nil {
+		utils.Fatalf("%v", err)
 	}
+	cfg := DefaultConfig
 
 	stack, err := node.NewNode(ctx, &cfg)
 	if err != nil {
@@ -243,13 +239,10 @@ func accountList(ctx *cli.Context) error {
 
 // accountCreate creates a new account into the keystore defined by the CLI flags.
 func accountCreate(ctx *cli.Context) error {
-	cfg := DefaultConfig
-	// Load config file.
-	if len(cfgFile) > 0 {
-		if err := conf.LoadConfigFromFile(cfgFile, &cfg); err != nil {
-			utils.Fatalf("%v", err)
-		}
+	if err := loadConfig(ctx); err != nil {
+		utils.Fatalf("%v", err)
 	}
+	cfg := DefaultConfig
 
 	keydir, err := cfg.NodeCfg.KeyDirConfig()
 	if err != nil {
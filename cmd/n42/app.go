@@ -40,25 +40,37 @@ import (
 )
 
 func appRun(ctx *cli.Context) error {
-	if len(cfgFile) > 0 {
-		if err := conf.LoadConfigFromFile(cfgFile, &DefaultConfig); err != nil {
-			return err
-		}
-	} else {
-		DefaultConfig.NetworkCfg.ListenersAddress = listenAddress.Value()
-		DefaultConfig.NetworkCfg.BootstrapPeers = bootstraps.Value()
-		if len(privateKey) > 0 {
-			DefaultConfig.NetworkCfg.LocalPeerKey = privateKey
-		}
+	stack, dev, err := makeNode(ctx)
+	if err != nil {
+		return err
+	}
+	defer dev.cleanup()
+	startNode(ctx, stack, false)
+	stack.Wait()
 
-		DefaultConfig.P2PCfg.StaticPeers = p2pStaticPeers.Value()
-		DefaultConfig.P2PCfg.BootstrapNodeAddr = p2pBootstrapNode.Value()
-		DefaultConfig.P2PCfg.DenyListCIDR = p2pDenyList.Value()
+	return nil
+}
 
-		//
+// makeNode loads the configuration and creates the node, which is shared by the
+// default command and the console. In developer mode it also returns the dev
+// account, whose cleanup the caller defers.
+func makeNode(ctx *cli.Context) (*node.Node, *developer, error) {
+	// Loaded again as the flags of a command may have reset the settings.
+	if err := loadConfig(ctx); err != nil {
+		return nil, nil, err
+	}
+	if DefaultConfig.P2PCfg.DataDir == "" || ctx.IsSet(DataDirFlag.Name) {
 		DefaultConfig.P2PCfg.DataDir = DefaultConfig.NodeCfg.DataDir
 	}
 
+	var dev *developer
+	if ctx.Bool(DeveloperFlag.Name) {
+		var err error
+		if dev, err = setDeveloperConfig(ctx, &DefaultConfig); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	log.Init(DefaultConfig.NodeCfg, DefaultConfig.LoggerCfg)
 
 	if DefaultConfig.PprofCfg.Pprof {
@@ -83,10 +95,23 @@ func appRun(ctx *cli.Context) error {
 	stack, err := node.NewNode(ctx, &DefaultConfig)
 	if err != nil {
 		log.Error("Failed start Node", "err", err)
-		return err
+		dev.cleanup()
+		return nil, nil, err
+	}
+	if dev != nil {
+		if err := unlockDeveloper(stack, dev); err != nil {
+			stack.Close()
+			dev.cleanup()
+			return nil, nil, err
+		}
 	}
+	return stack, dev, nil
+}
 
-	StartNode(ctx, stack, false)
+// startNode boots up the node, unlocks the requested accounts and starts
+// tracking the wallets.
+func startNode(ctx *cli.Context, stack *node.Node, isConsole bool) {
+	StartNode(ctx, stack, isConsole)
 
 	// Unlock any account specifically requested
 	unlockAccounts(ctx, stack, &DefaultConfig)
@@ -127,10 +152,6 @@ func appRun(ctx *cli.Context) error {
 			}
 		}
 	}()
-
-	stack.Wait()
-
-	return nil
 }
 
 // unlockAccounts unlocks any account specifically requested.
@@ -0,0 +1,155 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/gofrs/flock"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/n42blockchain/N42/internal/node"
+	"github.com/n42blockchain/N42/log"
+	"github.com/n42blockchain/N42/modules/rpc/jsonrpc"
+	"github.com/n42blockchain/N42/turbo/backup"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	backupCompressFlag = &cli.BoolFlag{
+		Name:  "compress",
+		Usage: "Gzip compress the files of the backup",
+	}
+	backupChecksumFlag = &cli.BoolFlag{
+		Name:  "checksum",
+		Usage: "Record the SHA-256 checksums of the files of the backup",
+		Value: true,
+	}
+
+	backupCommand = &cli.Command{
+		Name:      "backup",
+		Usage:     "Back up the chain database of a running or stopped node",
+		ArgsUsage: "<dir>",
+		Action:    backupNode,
+		Flags: []cli.Flag{
+			DataDirFlag,
+			AncientDirFlag,
+			backupCompressFlag,
+			backupChecksumFlag,
+		},
+		Description: `
+Writes a consistent snapshot of the chain database and of the ancient store into
+the given directory, which must not exist or be empty, along with a manifest
+recording the head block and the checksums of the files.
+
+If the node of the data directory is running with --ipc, the backup is taken by
+the node itself through its IPC endpoint while it keeps running, and its progress is
+reported in the log of the node. Otherwise the data directory is opened
+directly.`,
+	}
+
+	restoreCommand = &cli.Command{
+		Name:      "restore",
+		Usage:     "Restore the chain database from a backup",
+		ArgsUsage: "<dir>",
+		Action:    restoreNode,
+		Flags: []cli.Flag{
+			DataDirFlag,
+			AncientDirFlag,
+		},
+		Description: `
+Restores a backup written by the backup command or the admin_backup RPC into
+the data directory. The checksums of the files and the head block of the
+restored chain are verified before the chain database and the ancient store are
+swapped in; their previous content is kept with the suffix ".old". The node
+must not be running.`,
+	}
+)
+
+func backupNode(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return errors.New("usage: backup <dir>")
+	}
+	// The running node resolves the path relative to its own directory.
+	dir, err := filepath.Abs(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	cfg := backup.Config{
+		Compress: ctx.Bool(backupCompressFlag.Name),
+		Checksum: ctx.Bool(backupChecksumFlag.Name),
+	}
+
+	var m *backup.Manifest
+	if client, err := jsonrpc.DialIPC(ctx.Context, DefaultConfig.NodeCfg.IPCEndpoint()); err == nil {
+		defer client.Close()
+		fmt.Println("Node is running, taking the backup through its IPC endpoint, progress is logged by the node")
+		if err := client.CallContext(ctx.Context, &m, "admin_backup", dir, cfg.Compress, cfg.Checksum); err != nil {
+			return err
+		}
+	} else {
+		stack, err := node.NewNode(ctx, &DefaultConfig)
+		if err != nil {
+			return err
+		}
+		defer stack.Close()
+		if m, err = stack.Backup(dir, cfg); err != nil {
+			return err
+		}
+	}
+	var size int64
+	for _, file := range m.Files {
+		size += file.Size
+	}
+	fmt.Printf("Backed up block %d [%x] into %s, %d files, %d bytes\n", m.Head.Number, m.Head.Hash, dir, len(m.Files), size)
+	return nil
+}
+
+func restoreNode(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return errors.New("usage: restore <dir>")
+	}
+	datadir := DefaultConfig.NodeCfg.DataDir
+	if datadir == "" {
+		return errors.New("a data directory is required")
+	}
+	if client, err := jsonrpc.DialIPC(ctx.Context, DefaultConfig.NodeCfg.IPCEndpoint()); err == nil {
+		client.Close()
+		return errors.New("node is running, stop it before restoring")
+	}
+	ancientDir := DefaultConfig.DatabaseCfg.AncientPath(datadir)
+	if _, err := os.Stat(ancientDir); err == nil {
+		// The ancient store is locked for as long as a node uses it.
+		lock := flock.New(filepath.Join(ancientDir, "FLOCK"))
+		if locked, err := lock.TryLock(); err != nil {
+			return err
+		} else if !locked {
+			return errors.New("ancient store in use, stop the node before restoring")
+		}
+		defer lock.Unlock()
+	}
+
+	m, err := backup.Restore(ctx.Context, ctx.Args().First(), filepath.Join(datadir, kv.ChainDB.String()), ancientDir, node.LogBackupProgress("Restore in progress"))
+	if err != nil {
+		return err
+	}
+	log.Info("Restored backup", "dir", ctx.Args().First(), "number", m.Head.Number, "hash", m.Head.Hash, "created", m.Created)
+	fmt.Printf("Restored block %d [%x] into %s\n", m.Head.Number, m.Head.Hash, datadir)
+	return nil
+}
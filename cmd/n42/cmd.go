@@ -17,6 +17,7 @@
 package main
 
 import (
+	"github.com/n42blockchain/N42/conf"
 	"github.com/n42blockchain/N42/params/networkname"
 	"github.com/urfave/cli/v2"
 )
@@ -31,6 +32,8 @@ var (
 	cfgFile       string
 
 	p2pStaticPeers   = cli.NewStringSlice()
+	p2pTrustedPeers  = cli.NewStringSlice()
+	p2pDNSDiscovery  = cli.NewStringSlice()
 	p2pBootstrapNode = cli.NewStringSlice()
 	p2pDenyList      = cli.NewStringSlice()
 )
@@ -71,6 +74,12 @@ var nodeFlg = []cli.Flag{
 
 var rpcFlags = []cli.Flag{
 
+	&cli.BoolFlag{
+		Name:        "ipc",
+		Usage:       "Enable the IPC json-rpc server, serving all APIs (admin included) to local users allowed to open the socket",
+		Value:       false,
+		Destination: &DefaultConfig.NodeCfg.IPC,
+	},
 	&cli.StringFlag{
 		Name:        "ipcpath",
 		Usage:       "Filename for IPC socket/pipe within the data dir (explicit paths escape it)",
@@ -261,6 +270,18 @@ var (
 		Usage:       "Connect with this peer. This flag may be used multiple times.",
 		Destination: p2pStaticPeers,
 	}
+	// P2PTrustedPeers specifies a set of peers that are always connected, even above the peer limit.
+	P2PTrustedPeers = &cli.StringSliceFlag{
+		Name:        "p2p.trusted-peer",
+		Usage:       "Always keep a connection with this peer, exempt from the peer limit and scoring. This flag may be used multiple times.",
+		Destination: p2pTrustedPeers,
+	}
+	// P2PDNSDiscovery specifies the DNS node lists the node is discovered from.
+	P2PDNSDiscovery = &cli.StringSliceFlag{
+		Name:        "p2p.dns-discovery",
+		Usage:       "Discover peers from the EIP-1459 node list at this enrtree:// URL. This flag may be used multiple times.",
+		Destination: p2pDNSDiscovery,
+	}
 	// P2PBootstrapNode tells the beacon node which bootstrap node to connect to
 	P2PBootstrapNode = &cli.StringSliceFlag{
 		Name:        "p2p.bootstrap-node",
@@ -310,6 +331,41 @@ var (
 		Value:       "",
 		Destination: &DefaultConfig.P2PCfg.HostDNS,
 	}
+	// P2PNAT enables port mapping on the local router.
+	P2PNAT = &cli.BoolFlag{
+		Name:        "p2p.nat",
+		Usage:       "Map the p2p port on the local router with UPnP or NAT-PMP.",
+		Value:       false,
+		Destination: &DefaultConfig.P2PCfg.EnableUPnP,
+	}
+	// P2PNATService lets peers ask the node to dial them back to learn their reachability.
+	P2PNATService = &cli.BoolFlag{
+		Name:        "p2p.nat-service",
+		Usage:       "Dial back peers asking whether they are publicly reachable (AutoNAT).",
+		Value:       true,
+		Destination: &DefaultConfig.P2PCfg.EnableNATService,
+	}
+	// P2PHolePunching enables direct connections through NATs.
+	P2PHolePunching = &cli.BoolFlag{
+		Name:        "p2p.hole-punching",
+		Usage:       "Upgrade relayed connections to direct ones by hole punching (DCUtR).",
+		Value:       true,
+		Destination: &DefaultConfig.P2PCfg.EnableHolePunching,
+	}
+	// P2PAutoRelay makes a node behind a NAT reachable via relays among its peers.
+	P2PAutoRelay = &cli.BoolFlag{
+		Name:        "p2p.auto-relay",
+		Usage:       "Advertise addresses via relays picked from connected peers when the node is not publicly reachable.",
+		Value:       false,
+		Destination: &DefaultConfig.P2PCfg.EnableAutoRelay,
+	}
+	// P2PRelayService lets a publicly reachable node relay for peers behind NATs.
+	P2PRelayService = &cli.BoolFlag{
+		Name:        "p2p.relay-service",
+		Usage:       "Relay connections for peers behind NATs once the node is publicly reachable.",
+		Value:       false,
+		Destination: &DefaultConfig.P2PCfg.EnableRelayService,
+	}
 	// P2PPrivKey defines a flag to specify the location of the private key file for libp2p.
 	P2PPrivKey = &cli.StringFlag{
 		Name:        "p2p.priv-key",
@@ -386,6 +442,48 @@ var (
 		Value:       5,
 		Destination: &DefaultConfig.P2PCfg.P2PLimit.BlockBatchLimiterPeriod,
 	}
+	// P2PPeerRequestsLimit limits the requests per second a peer may send on each protocol.
+	P2PPeerRequestsLimit = &cli.Float64Flag{
+		Name:        "p2p.limit.peer-requests",
+		Usage:       "The requests per second a single peer may send on each rpc or gossip protocol (0 = unlimited).",
+		Value:       0,
+		Destination: &DefaultConfig.P2PCfg.P2PLimit.Protocol.PeerRequests,
+	}
+	// P2PPeerBandwidthLimit limits the bytes per second served to or received from a peer on each protocol.
+	P2PPeerBandwidthLimit = &cli.Int64Flag{
+		Name:        "p2p.limit.peer-bandwidth",
+		Usage:       "The bytes per second exchanged with a single peer on each rpc or gossip protocol (0 = unlimited).",
+		Value:       0,
+		Destination: &DefaultConfig.P2PCfg.P2PLimit.Protocol.PeerBytes,
+	}
+	// P2PPeerStreamsLimit limits the concurrent streams of a peer on each rpc protocol.
+	P2PPeerStreamsLimit = &cli.IntFlag{
+		Name:        "p2p.limit.peer-streams",
+		Usage:       "The concurrent requests a single peer may have in flight on each rpc protocol (0 = unlimited).",
+		Value:       4,
+		Destination: &DefaultConfig.P2PCfg.P2PLimit.Protocol.PeerStreams,
+	}
+	// P2PGlobalRequestsLimit limits the requests per second of all peers on each protocol.
+	P2PGlobalRequestsLimit = &cli.Float64Flag{
+		Name:        "p2p.limit.global-requests",
+		Usage:       "The requests per second all peers together may send on each rpc or gossip protocol (0 = unlimited).",
+		Value:       0,
+		Destination: &DefaultConfig.P2PCfg.P2PLimit.Protocol.GlobalRequests,
+	}
+	// P2PGlobalBandwidthLimit limits the bytes per second of all peers on each protocol.
+	P2PGlobalBandwidthLimit = &cli.Int64Flag{
+		Name:        "p2p.limit.global-bandwidth",
+		Usage:       "The bytes per second exchanged with all peers together on each rpc or gossip protocol (0 = unlimited).",
+		Value:       0,
+		Destination: &DefaultConfig.P2PCfg.P2PLimit.Protocol.GlobalBytes,
+	}
+	// P2PGlobalStreamsLimit limits the concurrent streams of all peers on each rpc protocol.
+	P2PGlobalStreamsLimit = &cli.IntFlag{
+		Name:        "p2p.limit.global-streams",
+		Usage:       "The concurrent requests all peers together may have in flight on each rpc protocol (0 = unlimited).",
+		Value:       128,
+		Destination: &DefaultConfig.P2PCfg.P2PLimit.Protocol.GlobalStreams,
+	}
 )
 
 var (
@@ -418,6 +516,55 @@ var (
 		Value:       networkname.MainnetChainName,
 		Destination: &DefaultConfig.NodeCfg.Chain,
 	}
+
+	DeveloperFlag = &cli.BoolFlag{
+		Name:  "dev",
+		Usage: "Ephemeral single-node network with a pre-funded developer account, mining enabled",
+	}
+	DeveloperPeriodFlag = &cli.Uint64Flag{
+		Name:        "dev.period",
+		Usage:       "Block period to use in developer mode, also applied to an existing dev datadir (0 = mine only if transaction pending)",
+		Value:       0,
+		Destination: &DefaultConfig.NodeCfg.DevPeriod,
+	}
+	SyncTrustedBlockFlag = &cli.StringFlag{
+		Name:        "sync.trusted-block",
+		Usage:       "Only sync from peers whose chain contains this trusted block, given as <number>:<hash>. Blocks below it are still downloaded and executed (default: built-in trusted block of the chain)",
+		Destination: &DefaultConfig.NodeCfg.SyncTrustedBlock,
+	}
+)
+
+var (
+	PruneHistoryFlag = &cli.Uint64Flag{
+		Name:        "prune.history",
+		Usage:       "Keep the state history of the last N blocks only (0 = archive, minimum 90000)",
+		Value:       0,
+		Destination: &DefaultConfig.DatabaseCfg.PruneHistory,
+	}
+	PruneReceiptsFlag = &cli.Uint64Flag{
+		Name:        "prune.receipts",
+		Usage:       "Keep the receipts and logs of the last N blocks only (0 = archive)",
+		Value:       0,
+		Destination: &DefaultConfig.DatabaseCfg.PruneReceipts,
+	}
+	PruneTxIndexFlag = &cli.Uint64Flag{
+		Name:        "prune.txindex",
+		Usage:       "Keep the transaction hash index of the last N blocks only (0 = archive)",
+		Value:       0,
+		Destination: &DefaultConfig.DatabaseCfg.PruneTxIndex,
+	}
+	AncientDirFlag = &cli.StringFlag{
+		Name:        "db.ancient",
+		Usage:       "Directory of the ancient store, relative to the data directory unless absolute",
+		Value:       "ancient",
+		Destination: &DefaultConfig.DatabaseCfg.AncientDir,
+	}
+	FreezeFlag = &cli.BoolFlag{
+		Name:        "db.freeze",
+		Usage:       "Move the finalized blocks into the ancient store in the background",
+		Value:       false,
+		Destination: &DefaultConfig.DatabaseCfg.Freeze,
+	}
 )
 
 var (
@@ -503,6 +650,51 @@ Please note that --` + MetricsHTTPFlag.Name + ` must be set to start the server.
 		// Category: flags.MetricsCategory,
 		Destination: &DefaultConfig.MetricsCfg.Port,
 	}
+
+	// BundlerEnabledFlag enables the ERC-4337 user operation pool and RPCs.
+	BundlerEnabledFlag = &cli.BoolFlag{
+		Name:        "bundler",
+		Usage:       "Enable the ERC-4337 user operation mempool and bundler RPCs",
+		Value:       false,
+		Destination: &DefaultConfig.Bundler.Enabled,
+	}
+	BundlerEntryPointFlag = &cli.StringFlag{
+		Name:        "bundler.entrypoint",
+		Usage:       "Address of the ERC-4337 entry point served by the bundler",
+		Destination: &DefaultConfig.Bundler.EntryPoint,
+	}
+	BundlerAccountFlag = &cli.StringFlag{
+		Name: "bundler.account",
+		Usage: `Unlocked account signing the handleOps bundles.
+Without it user operations are pooled but never bundled.`,
+		Destination: &DefaultConfig.Bundler.Account,
+	}
+	BundlerBeneficiaryFlag = &cli.StringFlag{
+		Name:        "bundler.beneficiary",
+		Usage:       "Address receiving the bundle fees (default = bundler account)",
+		Destination: &DefaultConfig.Bundler.Beneficiary,
+	}
+	BundlerIntervalFlag = &cli.DurationFlag{
+		Name:        "bundler.interval",
+		Usage:       "Interval between bundles",
+		Value:       conf.DefaultBundlerConfig.Interval,
+		Destination: &DefaultConfig.Bundler.Interval,
+	}
+
+	// Console settings
+	JSpathFlag = &cli.StringFlag{
+		Name:  "jspath",
+		Usage: "JavaScript root path for `loadScript`",
+		Value: ".",
+	}
+	ExecFlag = &cli.StringFlag{
+		Name:  "exec",
+		Usage: "Execute JavaScript statement",
+	}
+	PreloadJSFlag = &cli.StringFlag{
+		Name:  "preload",
+		Usage: "Comma separated list of JavaScript files to preload into the console",
+	}
 )
 
 var (
@@ -516,6 +708,16 @@ var (
 		DataDirFlag,
 		ChainFlag,
 		MinFreeDiskSpaceFlag,
+		DeveloperFlag,
+		DeveloperPeriodFlag,
+		SyncTrustedBlockFlag,
+	}
+	pruneFlags = []cli.Flag{
+		PruneHistoryFlag,
+		PruneReceiptsFlag,
+		PruneTxIndexFlag,
+		AncientDirFlag,
+		FreezeFlag,
 	}
 	accountFlag = []cli.Flag{
 		PasswordFileFlag,
@@ -543,16 +745,43 @@ var (
 		P2PStaticID,
 		P2PPrivKey,
 		P2PHostDNS,
+		P2PNAT,
+		P2PNATService,
+		P2PHolePunching,
+		P2PAutoRelay,
+		P2PRelayService,
 		P2PRelayNode,
 		P2PStaticPeers,
+		P2PTrustedPeers,
+		P2PDNSDiscovery,
 		P2PUDPPort,
 		P2PTCPPort,
 		P2PMinSyncPeers,
 	}
 
+	bundlerFlags = []cli.Flag{
+		BundlerEnabledFlag,
+		BundlerEntryPointFlag,
+		BundlerAccountFlag,
+		BundlerBeneficiaryFlag,
+		BundlerIntervalFlag,
+	}
+
+	consoleFlags = []cli.Flag{
+		JSpathFlag,
+		ExecFlag,
+		PreloadJSFlag,
+	}
+
 	p2pLimitFlags = []cli.Flag{
 		P2PBlockBatchLimit,
 		P2PBlockBatchLimitBurstFactor,
 		P2PBlockBatchLimiterPeriod,
+		P2PPeerRequestsLimit,
+		P2PPeerBandwidthLimit,
+		P2PPeerStreamsLimit,
+		P2PGlobalRequestsLimit,
+		P2PGlobalBandwidthLimit,
+		P2PGlobalStreamsLimit,
 	}
 )
@@ -60,6 +60,7 @@ var DefaultConfig = conf.Config{
 		IsMem:      false,
 		MaxDB:      100,
 		MaxReaders: 1000,
+		AncientDir: "ancient",
 	},
 	MetricsCfg: conf.MetricsConfig{
 		Port: 6060,
@@ -75,4 +76,5 @@ var DefaultConfig = conf.Config{
 		GasPrice: big.NewInt(params.GWei),
 		Recommit: 4 * time.Second,
 	},
+	Bundler: conf.DefaultBundlerConfig,
 }
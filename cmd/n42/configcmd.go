@@ -0,0 +1,122 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/n42blockchain/N42/conf"
+	"github.com/n42blockchain/N42/log"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	dumpConfigFormatFlag = &cli.StringFlag{
+		Name:  "format",
+		Usage: "Format of the configuration, yaml or toml",
+		Value: conf.FormatYAML,
+	}
+
+	dumpConfigCommand = &cli.Command{
+		Name:      "dumpconfig",
+		Usage:     "Print the effective configuration",
+		ArgsUsage: "[<file>]",
+		Action:    dumpConfig,
+		Flags: []cli.Flag{
+			dumpConfigFormatFlag,
+		},
+		Description: `
+Prints the configuration the node would run with: the command line flags, given
+before the command, over the N42_ environment variables over the --blockchain
+file over the defaults. It is written to the given file instead, in the format of its extension, if one is
+given. Settings are named by their YAML keys in both YAML and TOML; the
+environment variable of a setting is N42_ followed by its upper-cased keys
+joined by underscores, e.g. N42_NODE_HTTP_PORT for node.http_port.`,
+	}
+)
+
+// loadConfig layers the configuration: the command line flags over the
+// environment variables over the --blockchain file over the defaults. The flags
+// were already parsed into DefaultConfig, so those set explicitly are applied
+// again once the file and the environment have been loaded. It runs before any
+// command, and again in the commands whose own flags share the destinations of
+// the global ones, as parsing those resets the settings to the flag defaults.
+func loadConfig(ctx *cli.Context) error {
+	flags := make(map[string]string)
+	for _, name := range ctx.FlagNames() {
+		// String slices have their own destinations, copied over below.
+		if _, ok := ctx.Value(name).(cli.StringSlice); !ok {
+			flags[name] = fmt.Sprint(ctx.Value(name))
+		}
+	}
+	if len(cfgFile) > 0 {
+		if err := conf.LoadConfigFromFile(cfgFile, &DefaultConfig); err != nil {
+			return err
+		}
+	}
+	unknown, err := conf.ApplyEnv(&DefaultConfig, os.Environ())
+	if err != nil {
+		return err
+	}
+	for _, name := range unknown {
+		log.Warn("Ignoring unknown configuration environment variable", "name", name)
+	}
+	for name, value := range flags {
+		if err := ctx.Set(name, value); err != nil {
+			return err
+		}
+	}
+
+	if ctx.IsSet("p2p.listen") {
+		DefaultConfig.NetworkCfg.ListenersAddress = listenAddress.Value()
+	}
+	if ctx.IsSet("p2p.bootstrap") {
+		DefaultConfig.NetworkCfg.BootstrapPeers = bootstraps.Value()
+	}
+	if len(privateKey) > 0 {
+		DefaultConfig.NetworkCfg.LocalPeerKey = privateKey
+	}
+	if ctx.IsSet("p2p.peer") {
+		DefaultConfig.P2PCfg.StaticPeers = p2pStaticPeers.Value()
+	}
+	if ctx.IsSet(P2PTrustedPeers.Name) {
+		DefaultConfig.P2PCfg.TrustedPeers = p2pTrustedPeers.Value()
+	}
+	if ctx.IsSet(P2PDNSDiscovery.Name) {
+		DefaultConfig.P2PCfg.DNSDiscoveryURLs = p2pDNSDiscovery.Value()
+	}
+	if ctx.IsSet("p2p.bootstrap-node") {
+		DefaultConfig.P2PCfg.BootstrapNodeAddr = p2pBootstrapNode.Value()
+	}
+	if ctx.IsSet(P2PDenyList.Name) {
+		DefaultConfig.P2PCfg.DenyListCIDR = p2pDenyList.Value()
+	}
+	return nil
+}
+
+func dumpConfig(ctx *cli.Context) error {
+	if file := ctx.Args().First(); file != "" {
+		return conf.SaveConfigToFile(file, DefaultConfig)
+	}
+	out, err := conf.MarshalConfig(DefaultConfig, ctx.String(dumpConfigFormatFlag.Name))
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
@@ -0,0 +1,134 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/n42blockchain/N42/cmd/utils"
+	"github.com/n42blockchain/N42/console"
+	"github.com/n42blockchain/N42/modules/rpc/jsonrpc"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	consoleCommand = &cli.Command{
+		Action: localConsole,
+		Name:   "console",
+		Usage:  "Start an interactive JavaScript environment",
+		Flags:  consoleFlags,
+		Description: `
+The console starts the node and opens an interactive JavaScript environment
+bound to all of its RPC namespaces (eth, txpool, apos, debug, net, ...).`,
+	}
+
+	attachCommand = &cli.Command{
+		Action:    remoteConsole,
+		Name:      "attach",
+		Usage:     "Start an interactive JavaScript environment (connect to node)",
+		ArgsUsage: "[endpoint]",
+		Flags:     append([]cli.Flag{DataDirFlag}, consoleFlags...),
+		Description: `
+The attach command opens an interactive JavaScript environment connected to a
+running node. The endpoint is an IPC socket path or an http(s):// or ws(s)://
+URL and defaults to the IPC socket within the data directory, which the node
+only serves when started with --ipc.`,
+	}
+)
+
+// localConsole starts a new node, attaching a JavaScript console to it at the
+// same time.
+func localConsole(ctx *cli.Context) error {
+	stack, dev, err := makeNode(ctx)
+	if err != nil {
+		return err
+	}
+	defer dev.cleanup()
+	startNode(ctx, stack, true)
+	defer stack.Close()
+
+	// Attach to the newly started node and create the JavaScript console.
+	client := stack.Attach()
+	defer client.Close()
+	return runConsole(ctx, client, DefaultConfig.NodeCfg.DataDir)
+}
+
+// remoteConsole attaches a JavaScript console to an already running node,
+// connecting through the given or the default IPC endpoint.
+func remoteConsole(ctx *cli.Context) error {
+	if ctx.Args().Len() > 1 {
+		utils.Fatalf("invalid command-line: too many arguments")
+	}
+	endpoint := ctx.Args().First()
+	if endpoint == "" {
+		endpoint = DefaultConfig.NodeCfg.IPCEndpoint()
+	}
+	client, err := jsonrpc.DialContext(ctx.Context, endpoint)
+	if err != nil {
+		utils.Fatalf("Unable to attach to remote node: %v", err)
+	}
+	defer client.Close()
+	// The history of a remote console lives next to the node it is attached to.
+	var dataDir string
+	if !strings.Contains(endpoint, "://") {
+		dataDir = filepath.Dir(endpoint)
+	}
+	return runConsole(ctx, client, dataDir)
+}
+
+// runConsole executes the --exec statement if given, or runs an interactive
+// session until the user exits.
+func runConsole(ctx *cli.Context, client *jsonrpc.Client, dataDir string) error {
+	config := console.Config{
+		DataDir: dataDir,
+		DocRoot: ctx.String(JSpathFlag.Name),
+		Client:  client,
+		Preload: makeConsolePreloads(ctx),
+	}
+	c, err := console.New(config)
+	if err != nil {
+		return fmt.Errorf("failed to start the JavaScript console: %v", err)
+	}
+	defer c.Stop()
+
+	if script := ctx.String(ExecFlag.Name); script != "" {
+		c.Evaluate(script)
+		return nil
+	}
+	c.Welcome()
+	c.Interactive()
+	return nil
+}
+
+// makeConsolePreloads retrieves the absolute paths for the console JavaScript
+// scripts to preload before starting.
+func makeConsolePreloads(ctx *cli.Context) []string {
+	if ctx.String(PreloadJSFlag.Name) == "" {
+		return nil
+	}
+	var preloads []string
+	for _, file := range strings.Split(ctx.String(PreloadJSFlag.Name), ",") {
+		file = strings.TrimSpace(file)
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(ctx.String(JSpathFlag.Name), file)
+		}
+		preloads = append(preloads, file)
+	}
+	return preloads
+}
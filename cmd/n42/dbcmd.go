@@ -0,0 +1,620 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/internal/node"
+	"github.com/n42blockchain/N42/internal/pruner"
+	"github.com/n42blockchain/N42/modules"
+	"github.com/n42blockchain/N42/modules/ancient"
+	"github.com/n42blockchain/N42/modules/rawdb"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	dbCommand = &cli.Command{
+		Name:  "db",
+		Usage: "Low level database operations",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "inspect",
+				Usage:     "Show the key counts and value sizes of the database tables",
+				ArgsUsage: "",
+				Action:    inspectDB,
+				Flags: []cli.Flag{
+					DataDirFlag,
+					AncientDirFlag,
+				},
+				Description: `
+Prints the number of entries, the total key and value sizes and a histogram of
+the value sizes of every non-empty table, followed by the head pointers, the
+prune progress and the content of the ancient store.`,
+			},
+			{
+				Name:      "verify",
+				Usage:     "Verify the integrity of the canonical chain",
+				ArgsUsage: "",
+				Action:    verifyDB,
+				Flags: []cli.Flag{
+					DataDirFlag,
+					AncientDirFlag,
+				},
+				Description: `
+Walks the canonical chain from genesis to the head block, checking the hash
+linkage of the headers, the HeaderNumber, CanonicalHeader and transaction
+lookup indexes, the transaction sequence of the bodies and the receipts. Data
+which was pruned is not checked.`,
+			},
+			{
+				Name:      "get",
+				Usage:     "Show the value of a key",
+				ArgsUsage: "<table> <hex key>",
+				Action:    dbGet,
+				Flags: []cli.Flag{
+					DataDirFlag,
+				},
+			},
+			{
+				Name:      "put",
+				Usage:     "Set the value of a key",
+				ArgsUsage: "<table> <hex key> <hex value>",
+				Action:    dbPut,
+				Flags: []cli.Flag{
+					DataDirFlag,
+				},
+				Description: `
+Writes a raw value into a table. This is meant for surgical repairs only: the
+value is stored as given, without any checks. In tables with duplicate keys the
+value is added next to the existing ones, delete the key first to replace them.
+The node must not be running.`,
+			},
+			{
+				Name:      "delete",
+				Usage:     "Delete a key",
+				ArgsUsage: "<table> <hex key>",
+				Action:    dbDelete,
+				Flags: []cli.Flag{
+					DataDirFlag,
+				},
+				Description: `
+Deletes a key, along with all its values in tables with duplicate keys. This is
+meant for surgical repairs only. The node must not be running.`,
+			},
+			{
+				Name:      "freeze",
+				Usage:     "Move the finalized blocks into the ancient store",
+				ArgsUsage: "",
+				Action:    freezeDB,
+				Flags: []cli.Flag{
+					DataDirFlag,
+					AncientDirFlag,
+				},
+				Description: `
+Moves the headers, bodies, transactions and receipts of the canonical blocks
+older than the finality threshold out of the chain database into the ancient
+store, which is append-only and compressed. The node must not be running.`,
+			},
+		},
+	}
+)
+
+// openChainDB opens the chain database and the ancient store of the data
+// directory on their own, without the services of a node, so that the db
+// commands also work on data directories a node fails to start on. The
+// returned function closes both.
+func openChainDB() (kv.RwDB, *ancient.Freezer, func(), error) {
+	datadir := DefaultConfig.NodeCfg.DataDir
+	if datadir == "" {
+		return nil, nil, nil, errors.New("a data directory is required")
+	}
+	if _, err := os.Stat(filepath.Join(datadir, kv.ChainDB.String())); err != nil {
+		return nil, nil, nil, fmt.Errorf("no chain database in %s: %v", datadir, err)
+	}
+	db, err := node.OpenDatabase(&DefaultConfig, nil, kv.ChainDB.String())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var ancients *ancient.Freezer
+	if dir := DefaultConfig.DatabaseCfg.AncientPath(datadir); dir != "" {
+		if ancients, err = ancient.Open(dir); err != nil {
+			db.Close()
+			return nil, nil, nil, err
+		}
+		rawdb.SetAncientStore(ancients)
+	}
+	return db, ancients, func() {
+		if ancients != nil {
+			rawdb.SetAncientStore(nil)
+			ancients.Close()
+		}
+		db.Close()
+	}, nil
+}
+
+// readHead returns the number of the head block of the chain database.
+func readHead(tx kv.Getter) (uint64, error) {
+	hash := rawdb.ReadHeadBlockHash(tx)
+	number := rawdb.ReadHeaderNumber(tx, hash)
+	if number == nil {
+		return 0, fmt.Errorf("head block %v not found", hash)
+	}
+	return *number, nil
+}
+
+func freezeDB(ctx *cli.Context) error {
+	db, ancients, closeDB, err := openChainDB()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	if ancients == nil {
+		return errors.New("no ancient store configured")
+	}
+	var head uint64
+	if err := db.View(ctx.Context, func(tx kv.Tx) (err error) {
+		head, err = readHead(tx)
+		return err
+	}); err != nil {
+		return err
+	}
+	if head <= pruner.FreezeThreshold {
+		fmt.Printf("Nothing to freeze, head block %d within the finality threshold of %d blocks\n", head, pruner.FreezeThreshold)
+		return nil
+	}
+	frozen, err := pruner.Freeze(ctx.Context, db, ancients, head-pruner.FreezeThreshold)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Froze %d blocks, %d blocks in the ancient store\n", frozen, ancients.Ancients())
+	return nil
+}
+
+// sizeBuckets are the upper bounds of the value size histogram of db inspect.
+var sizeBuckets = []uint64{32, 128, 1024, 4096, 16384}
+
+// tableStats gathers the key count and sizes of a table.
+type tableStats struct {
+	name      string
+	count     uint64
+	keySize   uint64
+	valueSize uint64
+	histogram []uint64 // one entry per size bucket and one for larger values
+}
+
+func inspectTable(tx kv.Tx, name string) (*tableStats, error) {
+	stats := &tableStats{name: name, histogram: make([]uint64, len(sizeBuckets)+1)}
+	if err := tx.ForEach(name, nil, func(k, v []byte) error {
+		stats.count++
+		stats.keySize += uint64(len(k))
+		stats.valueSize += uint64(len(v))
+		bucket := sort.Search(len(sizeBuckets), func(i int) bool { return uint64(len(v)) <= sizeBuckets[i] })
+		stats.histogram[bucket]++
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func inspectDB(ctx *cli.Context) error {
+	db, ancients, closeDB, err := openChainDB()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	tx, err := db.BeginRo(ctx.Context)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	migrator, ok := tx.(kv.BucketMigrator)
+	if !ok {
+		return fmt.Errorf("cannot open db as BucketMigrator")
+	}
+	tables, err := migrator.ListBuckets()
+	if err != nil {
+		return err
+	}
+	sort.Strings(tables)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	header := "Table\tEntries\tKeys\tValues\t"
+	for _, bound := range sizeBuckets {
+		header += fmt.Sprintf("<=%s\t", types.StorageSize(bound))
+	}
+	fmt.Fprintln(w, header+fmt.Sprintf(">%s\t", types.StorageSize(sizeBuckets[len(sizeBuckets)-1])))
+
+	var total tableStats
+	for _, name := range tables {
+		stats, err := inspectTable(tx, name)
+		if err != nil {
+			return err
+		}
+		if stats.count == 0 {
+			continue
+		}
+		total.count += stats.count
+		total.keySize += stats.keySize
+		total.valueSize += stats.valueSize
+		line := fmt.Sprintf("%s\t%d\t%s\t%s\t", name, stats.count, types.StorageSize(stats.keySize), types.StorageSize(stats.valueSize))
+		for _, n := range stats.histogram {
+			line += fmt.Sprintf("%d\t", n)
+		}
+		fmt.Fprintln(w, line)
+	}
+	fmt.Fprintf(w, "Total\t%d\t%s\t%s\t\n", total.count, types.StorageSize(total.keySize), types.StorageSize(total.valueSize))
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	for _, head := range []struct {
+		name string
+		hash types.Hash
+	}{
+		{modules.HeadHeaderKey, rawdb.ReadHeadHeaderHash(tx)},
+		{modules.HeadBlockKey, rawdb.ReadHeadBlockHash(tx)},
+	} {
+		if number := rawdb.ReadHeaderNumber(tx, head.hash); number != nil {
+			fmt.Printf("%-12s #%d %v\n", head.name, *number, head.hash)
+		} else {
+			fmt.Printf("%-12s %v (unknown block)\n", head.name, head.hash)
+		}
+	}
+	for _, table := range []string{modules.AccountChangeSet, modules.Receipts, modules.TxLookup} {
+		from, err := rawdb.ReadPruneProgress(tx, table)
+		if err != nil {
+			return err
+		}
+		if from > 0 {
+			fmt.Printf("%-12s pruned below block %d\n", table, from)
+		}
+	}
+	if ancients != nil {
+		sizes, err := ancients.Size()
+		if err != nil {
+			return err
+		}
+		var total uint64
+		for _, size := range sizes {
+			total += size
+		}
+		fmt.Printf("%-12s %d blocks, %s\n", "Ancients", ancients.Ancients(), types.StorageSize(total))
+	}
+	return nil
+}
+
+// maxReportedIssues bounds the number of integrity issues printed by db verify.
+const maxReportedIssues = 100
+
+func verifyDB(ctx *cli.Context) error {
+	db, _, closeDB, err := openChainDB()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	tx, err := db.BeginRo(ctx.Context)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var issues uint64
+	report := func(number uint64, format string, args ...interface{}) {
+		issues++
+		if issues <= maxReportedIssues {
+			fmt.Printf("block %d: %s\n", number, fmt.Sprintf(format, args...))
+		}
+	}
+
+	head, err := readHead(tx)
+	if err != nil {
+		return err
+	}
+	headNumber := &head
+	receiptsFrom, err := rawdb.ReadPruneProgress(tx, modules.Receipts)
+	if err != nil {
+		return err
+	}
+	txIndexFrom, err := rawdb.ReadPruneProgress(tx, modules.TxLookup)
+	if err != nil {
+		return err
+	}
+
+	var (
+		parent    types.Hash
+		nextTxId  uint64
+		checkedTx uint64
+	)
+	for number := uint64(0); number <= *headNumber; number++ {
+		if err := ctx.Context.Err(); err != nil {
+			return err
+		}
+		hash, err := rawdb.ReadCanonicalHash(tx, number)
+		if err != nil {
+			return err
+		}
+		if hash == (types.Hash{}) {
+			report(number, "canonical hash missing")
+			continue
+		}
+		if stored := rawdb.ReadHeaderNumber(tx, hash); stored == nil || *stored != number {
+			report(number, "HeaderNumber of %v is %s", hash, formatNumber(stored))
+		}
+
+		header := rawdb.ReadHeader(tx, hash, number)
+		if header == nil {
+			report(number, "header %v missing", hash)
+			parent = hash
+			continue
+		}
+		if header.Hash() != hash {
+			report(number, "header hashes to %v, canonical hash is %v", header.Hash(), hash)
+		}
+		if header.Number64().Uint64() != number {
+			report(number, "header has number %d", header.Number64().Uint64())
+		}
+		if number > 0 && header.ParentHash != parent {
+			report(number, "parent hash %v, canonical parent is %v", header.ParentHash, parent)
+		}
+		parent = hash
+
+		bodyRaw := rawdb.ReadStorageBodyRAW(tx, hash, number)
+		if len(bodyRaw) != 8+4 {
+			report(number, "body missing")
+			continue
+		}
+		baseTxId, txAmount := binary.BigEndian.Uint64(bodyRaw[:8]), binary.BigEndian.Uint32(bodyRaw[8:])
+		if txAmount < 2 {
+			report(number, "body has %d transaction slots, at least 2 expected", txAmount)
+			continue
+		}
+		if number > 0 && baseTxId < nextTxId {
+			report(number, "transactions start at id %d, overlapping the parent ending at %d", baseTxId, nextTxId)
+		}
+		nextTxId = baseTxId + uint64(txAmount)
+
+		body := rawdb.ReadCanonicalBodyWithTransactions(tx, hash, number)
+		if body == nil {
+			report(number, "transactions missing")
+			continue
+		}
+		txs := body.Transactions()
+		if len(txs) != int(txAmount)-2 {
+			report(number, "%d transactions found, body has %d", len(txs), txAmount-2)
+		}
+		if number >= txIndexFrom {
+			for _, t := range txs {
+				h := t.Hash()
+				lookup, err := rawdb.ReadTxLookupEntry(tx, h)
+				if err != nil {
+					return err
+				}
+				if lookup == nil || *lookup != number {
+					report(number, "transaction %v indexed at block %s", h, formatNumber(lookup))
+				}
+			}
+		}
+		if number >= receiptsFrom && len(txs) > 0 {
+			if receipts := rawdb.ReadRawReceipts(tx, number); len(receipts) != len(txs) {
+				report(number, "%d receipts for %d transactions", len(receipts), len(txs))
+			}
+		}
+		checkedTx += uint64(len(txs))
+	}
+	if issues > maxReportedIssues {
+		fmt.Printf("... %d more issues\n", issues-maxReportedIssues)
+	}
+	if issues > 0 {
+		return fmt.Errorf("%d integrity issues found in %d blocks", issues, *headNumber+1)
+	}
+	fmt.Printf("Verified %d blocks and %d transactions, no issues found\n", *headNumber+1, checkedTx)
+	return nil
+}
+
+// formatNumber prints an optional block number.
+func formatNumber(number *uint64) string {
+	if number == nil {
+		return "none"
+	}
+	return fmt.Sprintf("%d", *number)
+}
+
+// parseTableKey parses the table and hex key arguments of the db get, put and
+// delete commands.
+func parseTableKey(ctx *cli.Context, args int) (string, []byte, error) {
+	if ctx.Args().Len() != args {
+		return "", nil, fmt.Errorf("usage: db %s %s", ctx.Command.Name, ctx.Command.ArgsUsage)
+	}
+	table := ctx.Args().Get(0)
+	if _, ok := modules.AstTableCfg[table]; !ok {
+		return "", nil, fmt.Errorf("unknown table %q", table)
+	}
+	key, err := parseHex(ctx.Args().Get(1))
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid key: %v", err)
+	}
+	return table, key, nil
+}
+
+func parseHex(s string) ([]byte, error) {
+	return hex.DecodeString(strings.TrimPrefix(s, "0x"))
+}
+
+// isDupSort reports whether a table may hold several values per key.
+func isDupSort(table string) bool {
+	return modules.AstTableCfg[table].Flags&kv.DupSort != 0
+}
+
+// readValues returns the values stored under key, several of them in tables
+// with duplicate keys.
+func readValues(tx kv.Tx, table string, key []byte) ([][]byte, error) {
+	if !isDupSort(table) {
+		v, err := tx.GetOne(table, key)
+		if err != nil || v == nil {
+			return nil, err
+		}
+		return [][]byte{v}, nil
+	}
+	c, err := tx.CursorDupSort(table)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	var values [][]byte
+	for k, v, err := c.SeekExact(key); k != nil; k, v, err = c.NextDup() {
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(k, key) {
+			break
+		}
+		values = append(values, bytes.Clone(v))
+	}
+	return values, nil
+}
+
+// putValue stores value under key and returns the values it was stored next
+// to. A plain table replaces its only value, while a table with duplicate keys
+// adds the value to the others, unless it is already among them.
+func putValue(tx kv.RwTx, table string, key, value []byte) (old [][]byte, added bool, err error) {
+	if old, err = readValues(tx, table, key); err != nil {
+		return nil, false, err
+	}
+	if isDupSort(table) {
+		for _, v := range old {
+			if bytes.Equal(v, value) {
+				return old, false, nil
+			}
+		}
+	}
+	if err := tx.Put(table, key, value); err != nil {
+		return nil, false, err
+	}
+	return old, true, nil
+}
+
+func dbGet(ctx *cli.Context) error {
+	modules.AstInit()
+	table, key, err := parseTableKey(ctx, 2)
+	if err != nil {
+		return err
+	}
+	db, _, closeDB, err := openChainDB()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	return db.View(ctx.Context, func(tx kv.Tx) error {
+		values, err := readValues(tx, table, key)
+		if err != nil {
+			return err
+		}
+		if len(values) == 0 {
+			return fmt.Errorf("key %x not found in %s", key, table)
+		}
+		for _, v := range values {
+			fmt.Printf("%x\n", v)
+		}
+		return nil
+	})
+}
+
+func dbPut(ctx *cli.Context) error {
+	modules.AstInit()
+	table, key, err := parseTableKey(ctx, 3)
+	if err != nil {
+		return err
+	}
+	value, err := parseHex(ctx.Args().Get(2))
+	if err != nil {
+		return fmt.Errorf("invalid value: %v", err)
+	}
+	db, _, closeDB, err := openChainDB()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	return db.Update(ctx.Context, func(tx kv.RwTx) error {
+		old, added, err := putValue(tx, table, key, value)
+		if err != nil {
+			return err
+		}
+		switch {
+		case !isDupSort(table):
+			var prev []byte
+			if len(old) > 0 {
+				prev = old[0]
+			}
+			fmt.Printf("%s %x: %x -> %x\n", table, key, prev, value)
+		case added:
+			fmt.Printf("%s %x: added %x to %d existing values\n", table, key, value, len(old))
+		default:
+			fmt.Printf("%s %x: %x already stored\n", table, key, value)
+		}
+		return nil
+	})
+}
+
+func dbDelete(ctx *cli.Context) error {
+	modules.AstInit()
+	table, key, err := parseTableKey(ctx, 2)
+	if err != nil {
+		return err
+	}
+	db, _, closeDB, err := openChainDB()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	return db.Update(ctx.Context, func(tx kv.RwTx) error {
+		old, err := readValues(tx, table, key)
+		if err != nil {
+			return err
+		}
+		if len(old) == 0 {
+			return fmt.Errorf("key %x not found in %s", key, table)
+		}
+		if err := tx.Delete(table, key); err != nil {
+			return err
+		}
+		for _, v := range old {
+			fmt.Printf("%s %x: deleted %x\n", table, key, v)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,102 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
+	log2 "github.com/ledgerwatch/log/v3"
+	"github.com/n42blockchain/N42/modules"
+)
+
+func TestPutValue(t *testing.T) {
+	modules.AstInit()
+	kv.ChaindataTablesCfg = modules.AstTableCfg
+	db := mdbx.NewMDBX(log2.New()).InMem(t.TempDir()).Label(kv.ChainDB).MustOpen()
+	defer db.Close()
+
+	if !isDupSort(modules.AccountChangeSet) || isDupSort(modules.HeaderCanonical) {
+		t.Fatalf("unexpected table flags")
+	}
+	key := modules.EncodeBlockNumber(1)
+	tests := []struct {
+		table  string
+		value  string
+		old    []string
+		added  bool
+		stored []string
+	}{
+		// A plain table replaces its value.
+		{modules.HeaderCanonical, "aa", nil, true, []string{"aa"}},
+		{modules.HeaderCanonical, "bb", []string{"aa"}, true, []string{"bb"}},
+		// A table with duplicate keys adds values next to the others.
+		{modules.AccountChangeSet, "bb", nil, true, []string{"bb"}},
+		{modules.AccountChangeSet, "aa", []string{"bb"}, true, []string{"aa", "bb"}},
+		{modules.AccountChangeSet, "bb", []string{"aa", "bb"}, false, []string{"aa", "bb"}},
+	}
+	for i, tt := range tests {
+		if err := db.Update(context.Background(), func(tx kv.RwTx) error {
+			old, added, err := putValue(tx, tt.table, key, []byte(tt.value))
+			if err != nil {
+				return err
+			}
+			if !equalValues(old, tt.old) || added != tt.added {
+				t.Errorf("test %d: have old %q added %v, want %q %v", i, old, added, tt.old, tt.added)
+			}
+			stored, err := readValues(tx, tt.table, key)
+			if err != nil {
+				return err
+			}
+			if !equalValues(stored, tt.stored) {
+				t.Errorf("test %d: stored %q, want %q", i, stored, tt.stored)
+			}
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func equalValues(have [][]byte, want []string) bool {
+	if len(have) != len(want) {
+		return false
+	}
+	for i := range have {
+		if !bytes.Equal(have[i], []byte(want[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestOpenChainDBMissing(t *testing.T) {
+	defer func(datadir string) { DefaultConfig.NodeCfg.DataDir = datadir }(DefaultConfig.NodeCfg.DataDir)
+
+	// The db commands must not create an empty database in a wrong directory.
+	DefaultConfig.NodeCfg.DataDir = t.TempDir()
+	if _, _, _, err := openChainDB(); err == nil {
+		t.Errorf("empty data directory: have no error")
+	}
+	DefaultConfig.NodeCfg.DataDir = ""
+	if _, _, _, err := openChainDB(); err == nil {
+		t.Errorf("no data directory: have no error")
+	}
+}
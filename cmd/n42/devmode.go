@@ -0,0 +1,109 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/n42blockchain/N42/accounts"
+	"github.com/n42blockchain/N42/accounts/keystore"
+	"github.com/n42blockchain/N42/cmd/utils"
+	"github.com/n42blockchain/N42/conf"
+	"github.com/n42blockchain/N42/internal/node"
+	"github.com/n42blockchain/N42/log"
+	"github.com/n42blockchain/N42/params/networkname"
+	"github.com/urfave/cli/v2"
+)
+
+// developer is the pre-funded signer of the developer chain, along with the
+// passphrase protecting its key and the throwaway data directory, if any.
+type developer struct {
+	account    accounts.Account
+	passphrase string
+	tempDir    string
+}
+
+// setDeveloperConfig turns the configuration into a single-node developer
+// network: a throwaway data directory unless one is given, no peer discovery
+// or initial sync, and mining enabled on a local, pre-funded dev account.
+func setDeveloperConfig(ctx *cli.Context, cfg *conf.Config) (*developer, error) {
+	cfg.NodeCfg.Chain = networkname.DevChainName
+	dev := new(developer)
+	if !ctx.IsSet(DataDirFlag.Name) {
+		dir, err := os.MkdirTemp("", "n42-dev-")
+		if err != nil {
+			return nil, err
+		}
+		cfg.NodeCfg.DataDir = dir
+		dev.tempDir = dir
+	}
+	cfg.P2PCfg.DataDir = cfg.NodeCfg.DataDir
+	cfg.P2PCfg.NoDiscovery = true
+	cfg.P2PCfg.EnableUPnP = false
+	cfg.P2PCfg.EnableAutoRelay = false
+	cfg.P2PCfg.MinSyncPeers = 0
+	cfg.NodeCfg.Miner = true
+
+	keydir, err := cfg.NodeCfg.KeyDirConfig()
+	if err != nil {
+		dev.cleanup()
+		return nil, err
+	}
+	ks := keystore.NewKeyStore(keydir, keystore.LightScryptN, keystore.LightScryptP)
+	if passwords := MakePasswordList(ctx); len(passwords) > 0 {
+		dev.passphrase = passwords[0]
+	}
+	switch {
+	case cfg.Miner.Etherbase != "":
+		if dev.account, err = utils.MakeAddress(ks, cfg.Miner.Etherbase); err != nil {
+			dev.cleanup()
+			return nil, fmt.Errorf("invalid developer account: %v", err)
+		}
+	case len(ks.Accounts()) > 0:
+		dev.account = ks.Accounts()[0]
+	default:
+		if dev.account, err = ks.NewAccount(dev.passphrase); err != nil {
+			dev.cleanup()
+			return nil, fmt.Errorf("failed to create developer account: %v", err)
+		}
+	}
+	cfg.Miner.Etherbase = dev.account.Address.Hex()
+	return dev, nil
+}
+
+// unlockDeveloper unlocks the developer account in the keystore of the node, so
+// that it can seal blocks and sign transactions without prompting.
+func unlockDeveloper(stack *node.Node, dev *developer) error {
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	if err := ks.Unlock(dev.account, dev.passphrase); err != nil {
+		return fmt.Errorf("failed to unlock developer account: %v", err)
+	}
+	log.Info("Using developer account", "address", dev.account.Address, "datadir", DefaultConfig.NodeCfg.DataDir)
+	return nil
+}
+
+// cleanup removes the throwaway data directory of the developer chain once the
+// node using it has been closed. It is a no-op for a user-supplied datadir.
+func (dev *developer) cleanup() {
+	if dev == nil || dev.tempDir == "" {
+		return
+	}
+	if err := os.RemoveAll(dev.tempDir); err != nil {
+		log.Warn("Failed to remove developer datadir", "dir", dev.tempDir, "err", err)
+	}
+}
@@ -0,0 +1,409 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/n42blockchain/N42/common/crypto"
+	"github.com/n42blockchain/N42/internal/p2p"
+	"github.com/n42blockchain/N42/internal/p2p/crawler"
+	"github.com/n42blockchain/N42/internal/p2p/discover"
+	"github.com/n42blockchain/N42/internal/p2p/dnsdisc"
+	"github.com/n42blockchain/N42/internal/p2p/enode"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	// treeInfoFile is the file of a tree directory holding the domain, the
+	// sequence number, the signature and the links of the tree.
+	treeInfoFile = "enrtree-info.json"
+	// treeNodesFile is the file of a tree directory holding its nodes.
+	treeNodesFile = "nodes.json"
+)
+
+var (
+	dnsDomainFlag = &cli.StringFlag{
+		Name:  "domain",
+		Usage: "Domain name of the tree",
+	}
+	dnsSeqFlag = &cli.UintFlag{
+		Name:  "seq",
+		Usage: "New sequence number of the tree, the current one plus one by default",
+	}
+
+	devp2pCommand = &cli.Command{
+		Name:  "devp2p",
+		Usage: "Tools for the N42 p2p network",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "dns",
+				Usage: "Build, sign and resolve EIP-1459 DNS node lists",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "crawl",
+						Usage:     "Update the nodes of a tree directory by crawling the discovery v5 DHT",
+						ArgsUsage: "<tree-dir>",
+						Action:    dnsCrawl,
+						Flags:     []cli.Flag{crawlTimeoutFlag, crawlBootnodesFlag, crawlListenFlag},
+						Description: `
+Walks the discovery v5 DHT from the bootnodes and records in the nodes.json file
+of the tree directory the nodes announcing a TCP port and a fork ID. The nodes
+already present are checked again and dropped if they no longer respond.`,
+					},
+					{
+						Name:      "sign",
+						Usage:     "Sign the tree of a tree directory",
+						ArgsUsage: "<tree-dir> <key-file>",
+						Action:    dnsSign,
+						Flags:     []cli.Flag{dnsDomainFlag, dnsSeqFlag},
+						Description: `
+Builds the tree of the nodes.json and enrtree-info.json files of the tree
+directory and signs its root with the hex encoded secp256k1 key of the key file.
+The signature, the sequence number and the domain are written to
+enrtree-info.json, and the enrtree:// URL of the tree is printed.`,
+					},
+					{
+						Name:      "to-txt",
+						Usage:     "Write the TXT records of a signed tree directory as JSON",
+						ArgsUsage: "<tree-dir> [output]",
+						Action:    dnsToTXT,
+						Description: `
+Writes the TXT records of the signed tree of the tree directory as a JSON object
+mapping the names to their records, to the output file or to the standard output.`,
+					},
+					{
+						Name:      "sync",
+						Usage:     "Download a DNS node list into a tree directory",
+						ArgsUsage: "<url> [tree-dir]",
+						Action:    dnsSync,
+						Description: `
+Resolves the tree at the enrtree:// URL and writes it to the tree directory, or
+prints a summary of the tree if none is given.`,
+					},
+				},
+			},
+		},
+	}
+)
+
+// dnsDefinition is the content of the enrtree-info.json file of a tree directory.
+type dnsDefinition struct {
+	Meta  dnsMetaJSON
+	Nodes []*enode.Node
+}
+
+type dnsMetaJSON struct {
+	URL          string    `json:"url,omitempty"`
+	Seq          uint      `json:"seq"`
+	Sig          string    `json:"signature,omitempty"`
+	Links        []string  `json:"links"`
+	LastModified time.Time `json:"lastModified"`
+}
+
+// crawledNode is an entry of the nodes.json file of a tree directory.
+type crawledNode struct {
+	Seq           uint64      `json:"seq"`
+	N             *enode.Node `json:"record"`
+	FirstResponse time.Time   `json:"firstResponse,omitempty"`
+	LastResponse  time.Time   `json:"lastResponse,omitempty"`
+}
+
+// nodeSet is the content of the nodes.json file of a tree directory.
+type nodeSet map[enode.ID]crawledNode
+
+func (ns nodeSet) nodes() []*enode.Node {
+	result := make([]*enode.Node, 0, len(ns))
+	for _, n := range ns {
+		result = append(result, n.N)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID().String() < result[j].ID().String()
+	})
+	return result
+}
+
+func dnsCrawl(ctx *cli.Context) error {
+	if ctx.NArg() < 1 {
+		return errors.New("need the tree directory as argument")
+	}
+	dir := ctx.Args().First()
+	inputSet, err := loadNodesJSON(filepath.Join(dir, treeNodesFile))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if inputSet == nil {
+		inputSet = make(nodeSet)
+	}
+
+	bootnodes, err := crawlBootnodes(ctx)
+	if err != nil {
+		return err
+	}
+	disc, err := crawler.StartDiscovery(ctx.String(crawlListenFlag.Name), bootnodes)
+	if err != nil {
+		return err
+	}
+	defer disc.Close()
+
+	output := crawlNodes(disc, inputSet, ctx.Duration(crawlTimeoutFlag.Name))
+	fmt.Printf("Crawled %d nodes (%d before)\n", len(output), len(inputSet))
+	return writeJSON(filepath.Join(dir, treeNodesFile), output)
+}
+
+// crawlNodes checks the nodes of input again and collects the nodes found in
+// the DHT until timeout, keeping those which can be dialed by the N42 nodes.
+func crawlNodes(disc *discover.UDPv5, input nodeSet, timeout time.Duration) nodeSet {
+	output := make(nodeSet, len(input))
+	now := time.Now()
+	add := func(n *enode.Node, first time.Time) bool {
+		if !dialableNode(n) {
+			return false
+		}
+		entry, ok := output[n.ID()]
+		if !ok {
+			entry.FirstResponse = first
+		}
+		if entry.N == nil || n.Seq() > entry.N.Seq() {
+			entry.N, entry.Seq = n, n.Seq()
+		}
+		entry.LastResponse = now
+		output[n.ID()] = entry
+		return !ok
+	}
+	for _, entry := range input {
+		if n, err := disc.RequestENR(entry.N); err == nil {
+			add(n, entry.FirstResponse)
+		}
+	}
+
+	it := disc.RandomNodes()
+	timer := time.AfterFunc(timeout, it.Close)
+	defer timer.Stop()
+	for it.Next() {
+		now = time.Now()
+		if add(it.Node(), now) {
+			fmt.Printf("Found %s\n", it.Node().URLv4())
+		}
+	}
+	return output
+}
+
+// dialableNode reports whether n announces an IP address, a TCP port and a
+// fork ID, which the N42 nodes require of the nodes they dial.
+func dialableNode(n *enode.Node) bool {
+	if n.IP() == nil || n.TCP() == 0 {
+		return false
+	}
+	_, err := p2p.NodeForkID(n)
+	return err == nil
+}
+
+func dnsSign(ctx *cli.Context) error {
+	if ctx.NArg() < 2 {
+		return errors.New("need the tree directory and the key file as arguments")
+	}
+	var (
+		dir     = ctx.Args().Get(0)
+		keyfile = ctx.Args().Get(1)
+	)
+	def, err := loadTreeDefinition(dir)
+	if err != nil {
+		return err
+	}
+	domain, err := treeDomain(ctx.String(dnsDomainFlag.Name), def)
+	if err != nil {
+		return err
+	}
+	key, err := crypto.LoadECDSA(keyfile)
+	if err != nil {
+		return fmt.Errorf("can't load the key: %w", err)
+	}
+
+	seq := def.Meta.Seq + 1
+	if ctx.IsSet(dnsSeqFlag.Name) {
+		seq = ctx.Uint(dnsSeqFlag.Name)
+	}
+	t, err := dnsdisc.MakeTree(seq, def.Nodes, def.Meta.Links)
+	if err != nil {
+		return err
+	}
+	url, err := t.Sign(key, domain)
+	if err != nil {
+		return fmt.Errorf("can't sign: %w", err)
+	}
+	def = treeToDefinition(url, t)
+	def.Meta.LastModified = time.Now()
+	if err := writeJSON(filepath.Join(dir, treeInfoFile), def.Meta); err != nil {
+		return err
+	}
+	fmt.Println(url)
+	return nil
+}
+
+func dnsToTXT(ctx *cli.Context) error {
+	if ctx.NArg() < 1 {
+		return errors.New("need the tree directory as argument")
+	}
+	def, err := loadTreeDefinition(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	t, err := signedTree(def)
+	if err != nil {
+		return err
+	}
+	domain, _, err := dnsdisc.ParseURL(def.Meta.URL)
+	if err != nil {
+		return err
+	}
+	return writeJSON(ctx.Args().Get(1), t.ToTXT(domain))
+}
+
+func dnsSync(ctx *cli.Context) error {
+	if ctx.NArg() < 1 {
+		return errors.New("need the tree URL as argument")
+	}
+	url := ctx.Args().First()
+	client := dnsdisc.NewClient(dnsdisc.Config{})
+	t, err := client.SyncTree(url)
+	if err != nil {
+		return err
+	}
+	def := treeToDefinition(url, t)
+	def.Meta.LastModified = time.Now()
+	dir := ctx.Args().Get(1)
+	if dir == "" {
+		fmt.Printf("Tree %s: seq %d, %d nodes, %d links\n", url, t.Seq(), len(def.Nodes), len(def.Meta.Links))
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := writeJSON(filepath.Join(dir, treeInfoFile), def.Meta); err != nil {
+		return err
+	}
+	set := make(nodeSet, len(def.Nodes))
+	for _, n := range def.Nodes {
+		set[n.ID()] = crawledNode{Seq: n.Seq(), N: n}
+	}
+	return writeJSON(filepath.Join(dir, treeNodesFile), set)
+}
+
+// treeDomain returns the domain of the tree, given by the flag or taken from
+// the URL of its previous signature.
+func treeDomain(domain string, def *dnsDefinition) (string, error) {
+	if domain != "" {
+		return domain, nil
+	}
+	if def.Meta.URL == "" {
+		return "", errors.New("need the domain of the tree, none was signed before")
+	}
+	domain, _, err := dnsdisc.ParseURL(def.Meta.URL)
+	return domain, err
+}
+
+// signedTree rebuilds the tree of def and sets the signature it records.
+func signedTree(def *dnsDefinition) (*dnsdisc.Tree, error) {
+	if def.Meta.URL == "" || def.Meta.Sig == "" {
+		return nil, errors.New("the tree is not signed")
+	}
+	_, pubkey, err := dnsdisc.ParseURL(def.Meta.URL)
+	if err != nil {
+		return nil, err
+	}
+	t, err := dnsdisc.MakeTree(def.Meta.Seq, def.Nodes, def.Meta.Links)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.SetSignature(pubkey, def.Meta.Sig); err != nil {
+		return nil, fmt.Errorf("invalid signature of the tree, sign it again: %w", err)
+	}
+	return t, nil
+}
+
+func treeToDefinition(url string, t *dnsdisc.Tree) *dnsDefinition {
+	meta := dnsMetaJSON{
+		URL:   url,
+		Seq:   t.Seq(),
+		Sig:   t.Signature(),
+		Links: t.Links(),
+	}
+	if meta.Links == nil {
+		meta.Links = []string{}
+	}
+	return &dnsDefinition{Meta: meta, Nodes: t.Nodes()}
+}
+
+// loadTreeDefinition reads the tree of a tree directory. A missing
+// enrtree-info.json describes an empty tree which was never signed.
+func loadTreeDefinition(dir string) (*dnsDefinition, error) {
+	var def dnsDefinition
+	blob, err := os.ReadFile(filepath.Join(dir, treeInfoFile))
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(blob, &def.Meta); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", treeInfoFile, err)
+		}
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+	if def.Meta.URL != "" {
+		if _, _, err := dnsdisc.ParseURL(def.Meta.URL); err != nil {
+			return nil, fmt.Errorf("invalid URL in %s: %w", treeInfoFile, err)
+		}
+	}
+	set, err := loadNodesJSON(filepath.Join(dir, treeNodesFile))
+	if err != nil {
+		return nil, err
+	}
+	def.Nodes = set.nodes()
+	return &def, nil
+}
+
+func loadNodesJSON(file string) (nodeSet, error) {
+	blob, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var set nodeSet
+	if err := json.Unmarshal(blob, &set); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", file, err)
+	}
+	return set, nil
+}
+
+// writeJSON writes v as indented JSON to file, or to the standard output if
+// file is empty or "-".
+func writeJSON(file string, v interface{}) error {
+	blob, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	blob = append(blob, '\n')
+	if file == "" || file == "-" {
+		_, err := os.Stdout.Write(blob)
+		return err
+	}
+	return os.WriteFile(file, blob, 0644)
+}
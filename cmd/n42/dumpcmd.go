@@ -0,0 +1,165 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/n42blockchain/N42/common/block"
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/internal/node"
+	"github.com/n42blockchain/N42/modules"
+	"github.com/n42blockchain/N42/modules/rawdb"
+	"github.com/n42blockchain/N42/modules/state"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	dumpStartFlag = &cli.StringFlag{
+		Name:  "start",
+		Usage: "Address of the first account to dump",
+	}
+	dumpLimitFlag = &cli.IntFlag{
+		Name:  "limit",
+		Usage: "Maximum number of accounts to dump, 0 for all",
+	}
+	dumpNoCodeFlag = &cli.BoolFlag{
+		Name:  "nocode",
+		Usage: "Exclude the contract code",
+	}
+	dumpNoStorageFlag = &cli.BoolFlag{
+		Name:  "nostorage",
+		Usage: "Exclude the contract storage",
+	}
+
+	dumpCommand = &cli.Command{
+		Name:      "dump",
+		Usage:     "Dump the state of a block as JSON lines",
+		ArgsUsage: "[<block number|block hash|latest>]",
+		Action:    dumpState,
+		Flags: []cli.Flag{
+			DataDirFlag,
+			AncientDirFlag,
+			dumpStartFlag,
+			dumpLimitFlag,
+			dumpNoCodeFlag,
+			dumpNoStorageFlag,
+		},
+		Description: `
+Writes the accounts of the state after the given block, the head block by
+default, to the standard output. The first line holds the state root, followed
+by one line per account in address order. If the dump stopped at --limit
+accounts, the last line holds the address to pass as --start to continue it.`,
+	}
+)
+
+// dumpHeader resolves the block argument of the dump command, head being the
+// header of the current head block.
+func dumpHeader(tx kv.Tx, arg string, head *block.Header) (*block.Header, error) {
+	switch {
+	case arg == "" || arg == "latest":
+		return head, nil
+	case strings.HasPrefix(arg, "0x") && len(arg) == 2*types.HashLength+2:
+		header, err := rawdb.ReadHeaderByHash(tx, types.HexToHash(arg))
+		if err != nil {
+			return nil, err
+		}
+		if header == nil {
+			return nil, fmt.Errorf("block %s not found", arg)
+		}
+		return header, nil
+	default:
+		number, err := strconv.ParseUint(arg, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid block %q", arg)
+		}
+		if header := rawdb.ReadHeaderByNumber(tx, number); header != nil {
+			return header, nil
+		}
+		return nil, fmt.Errorf("block %d not found", number)
+	}
+}
+
+func dumpState(ctx *cli.Context) error {
+	if ctx.NArg() > 1 {
+		return fmt.Errorf("usage: %s", ctx.Command.ArgsUsage)
+	}
+	cfg := state.DumpConfig{
+		Max:            ctx.Int(dumpLimitFlag.Name),
+		ExcludeCode:    ctx.Bool(dumpNoCodeFlag.Name),
+		ExcludeStorage: ctx.Bool(dumpNoStorageFlag.Name),
+	}
+	if start := ctx.String(dumpStartFlag.Name); start != "" {
+		if !types.IsHexAddress(start) {
+			return fmt.Errorf("invalid start address %q", start)
+		}
+		cfg.Start = types.HexToAddress(start)
+	}
+
+	stack, err := node.NewNode(ctx, &DefaultConfig)
+	if err != nil {
+		return err
+	}
+	defer stack.Close()
+
+	tx, err := stack.Database().BeginRo(ctx.Context)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	head := stack.BlockChain().CurrentBlock().Header().(*block.Header)
+	header, err := dumpHeader(tx, ctx.Args().First(), head)
+	if err != nil {
+		return err
+	}
+	number := header.Number64().Uint64()
+	if err := rawdb.CheckPruned(tx, modules.AccountChangeSet, number); err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	enc := json.NewEncoder(out)
+	if err := enc.Encode(struct {
+		Root   types.Hash `json:"root"`
+		Number uint64     `json:"number"`
+	}{header.Root, number}); err != nil {
+		return err
+	}
+	next, err := state.NewDumper(tx, number).Dump(cfg, func(account *state.DumpAccount) error {
+		if err := enc.Encode(account); err != nil {
+			return err
+		}
+		return ctx.Context.Err()
+	})
+	if err != nil {
+		return err
+	}
+	if next != nil {
+		return enc.Encode(struct {
+			Next types.Address `json:"next"`
+		}{*next})
+	}
+	return nil
+}
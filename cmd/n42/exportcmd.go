@@ -24,6 +24,7 @@ import (
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/n42blockchain/N42/common/account"
 	common "github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/internal/blockarchive"
 	"github.com/n42blockchain/N42/internal/node"
 	"github.com/n42blockchain/N42/log"
 	"github.com/n42blockchain/N42/modules"
@@ -32,6 +33,7 @@ import (
 	"github.com/urfave/cli/v2"
 	"math/big"
 	"os"
+	"strconv"
 	"time"
 )
 
@@ -62,6 +64,18 @@ var (
 				},
 				Description: ``,
 			},
+			{
+				Name:      "chain",
+				Usage:     "Export the canonical chain to a block archive",
+				ArgsUsage: "<file> [from] [to]",
+				Action:    exportChain,
+				Flags: []cli.Flag{
+					DataDirFlag,
+				},
+				Description: `
+Exports the canonical blocks from, to (default 1 to the current head) into a
+portable block archive, which is gzip compressed if the file name ends in ".gz".`,
+			},
 			{
 				Name:      "dbState",
 				Usage:     "Export All MDBX Buckets disk space",
@@ -123,6 +137,41 @@ func exportTransactions(ctx *cli.Context) error {
 	return nil
 }
 
+func exportChain(ctx *cli.Context) error {
+	if ctx.Args().Len() < 1 || ctx.Args().Len() > 3 {
+		return errors.New("usage: export chain <file> [from] [to]")
+	}
+
+	stack, err := node.NewNode(ctx, &DefaultConfig)
+	if err != nil {
+		return err
+	}
+	defer stack.Close()
+	blockChain := stack.BlockChain()
+
+	first, last := uint64(1), blockChain.CurrentBlock().Number64().Uint64()
+	if ctx.Args().Len() > 1 {
+		if first, err = strconv.ParseUint(ctx.Args().Get(1), 10, 64); err != nil {
+			return fmt.Errorf("invalid first block: %v", err)
+		}
+	}
+	if ctx.Args().Len() > 2 {
+		if last, err = strconv.ParseUint(ctx.Args().Get(2), 10, 64); err != nil {
+			return fmt.Errorf("invalid last block: %v", err)
+		}
+	}
+
+	w, err := blockarchive.Create(ctx.Args().First(), blockChain.GenesisBlock().Hash())
+	if err != nil {
+		return err
+	}
+	if err := blockarchive.ExportChain(ctx.Context, blockChain, w, first, last); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
 func exportBalance(ctx *cli.Context) error {
 
 	stack, err := node.NewNode(ctx, &DefaultConfig)
@@ -0,0 +1,78 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/n42blockchain/N42/internal/blockarchive"
+	"github.com/n42blockchain/N42/internal/consensus/apos"
+	"github.com/n42blockchain/N42/internal/node"
+	"github.com/n42blockchain/N42/log"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	importCommand = &cli.Command{
+		Name:      "import",
+		Usage:     "Import blocks from block archives",
+		ArgsUsage: "<files...>",
+		Action:    importChain,
+		Flags: []cli.Flag{
+			DataDirFlag,
+		},
+		Description: `
+The import command inserts the blocks of one or more archives written by
+"export chain" into the local chain, fully validating them. Blocks already on
+the canonical chain are skipped, so an interrupted import can be resumed by
+running it again with the same files.`,
+	}
+)
+
+func importChain(ctx *cli.Context) error {
+	if ctx.Args().Len() < 1 {
+		return errors.New("usage: import <files...>")
+	}
+
+	stack, err := node.NewNode(ctx, &DefaultConfig)
+	if err != nil {
+		return err
+	}
+	defer stack.Close()
+	blockChain := stack.BlockChain()
+
+	// The node is not started, so hand the chain to the engine by hand for it
+	// to verify the signers of the imported blocks.
+	if pos, ok := stack.Engine().(*apos.APos); ok {
+		pos.SetBlockChain(blockChain)
+	}
+
+	for _, file := range ctx.Args().Slice() {
+		r, err := blockarchive.Open(file)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", file, err)
+		}
+		n, err := blockarchive.ImportChain(ctx.Context, blockChain, r)
+		r.Close()
+		if err != nil {
+			return fmt.Errorf("import of %s failed after %d blocks: %w", file, n, err)
+		}
+		log.Info("Imported block archive", "file", file, "blocks", n)
+	}
+	return nil
+}
@@ -28,9 +28,9 @@ import (
 )
 
 func main() {
-	fmt.Printf("┏┓╻╻ ╻┏━┓\n")
-	fmt.Printf("┃┗┫┗━┫┏━┛\n")
-	fmt.Printf("╹ ╹  ╹┗━╸\n")
+	fmt.Fprintf(os.Stderr, "┏┓╻╻ ╻┏━┓\n")
+	fmt.Fprintf(os.Stderr, "┃┗┫┗━┫┏━┛\n")
+	fmt.Fprintf(os.Stderr, "╹ ╹  ╹┗━╸\n")
 	flags := append(networkFlags, consensusFlag...)
 	flags = append(flags, loggerFlag...)
 	flags = append(flags, pprofCfg...)
@@ -39,12 +39,14 @@ func main() {
 	flags = append(flags, authRPCFlag...)
 	flags = append(flags, configFlag...)
 	flags = append(flags, settingFlag...)
+	flags = append(flags, pruneFlags...)
 	flags = append(flags, accountFlag...)
 	flags = append(flags, metricsFlags...)
 	flags = append(flags, p2pFlags...)
 	flags = append(flags, p2pLimitFlags...)
+	flags = append(flags, bundlerFlags...)
 
-	rootCmd = append(rootCmd, walletCommand, accountCommand, exportCommand, initCommand)
+	rootCmd = append(rootCmd, walletCommand, accountCommand, exportCommand, importCommand, initCommand, consoleCommand, attachCommand, dbCommand, dumpCommand, backupCommand, restoreCommand, dumpConfigCommand, devp2pCommand, p2pCommand)
 	commands := rootCmd
 
 	app := &cli.App{
@@ -55,6 +57,7 @@ func main() {
 		//Version:                version.FormatVersion(),
 		Version:                params.VersionWithCommit(params.GitCommit, ""),
 		UseShortOptionHandling: true,
+		Before:                 loadConfig,
 		Action:                 appRun,
 	}
 
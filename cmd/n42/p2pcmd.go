@@ -0,0 +1,175 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/n42blockchain/N42/internal/p2p/crawler"
+	"github.com/n42blockchain/N42/internal/p2p/enode"
+	"github.com/n42blockchain/N42/params"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	crawlTimeoutFlag = &cli.DurationFlag{
+		Name:  "timeout",
+		Usage: "Time the network is crawled for",
+		Value: 30 * time.Minute,
+	}
+	crawlBootnodesFlag = &cli.StringSliceFlag{
+		Name:  "bootnodes",
+		Usage: "ENR of a node the crawl starts from, the bootnodes of the mainnet by default. This flag may be used multiple times.",
+	}
+	crawlListenFlag = &cli.StringFlag{
+		Name:  "addr",
+		Usage: "UDP listening address of the discovery of the crawler",
+		Value: "0.0.0.0:0",
+	}
+	crawlWorkersFlag = &cli.IntFlag{
+		Name:  "workers",
+		Usage: "Number of nodes checked concurrently",
+		Value: 16,
+	}
+
+	p2pCommand = &cli.Command{
+		Name:  "p2p",
+		Usage: "Inspect the N42 p2p network",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "crawl",
+				Usage:     "Crawl the network and record its nodes",
+				ArgsUsage: "<nodes.json>",
+				Action:    p2pCrawl,
+				Flags:     []cli.Flag{crawlTimeoutFlag, crawlBootnodesFlag, crawlListenFlag, crawlWorkersFlag},
+				Description: `
+Walks the discovery v5 DHT from the bootnodes, connects to every node found and
+performs the status handshake with it. The ENR, head, fork digest, client
+version and reachability of the nodes are recorded in the nodes file. The nodes
+already present in the file are checked again first.`,
+			},
+			{
+				Name:      "summary",
+				Usage:     "Summarize the client versions and fork digests of a nodes file",
+				ArgsUsage: "<nodes.json>",
+				Action:    p2pSummary,
+			},
+		},
+	}
+)
+
+// crawlBootnodes returns the ENRs of the bootnodes flag, or those of the
+// bootnodes of the mainnet.
+func crawlBootnodes(ctx *cli.Context) ([]*enode.Node, error) {
+	addrs := crawlBootnodesFlag.Get(ctx)
+	if len(addrs) == 0 {
+		addrs = params.MainnetBootnodes
+	}
+	var nodes []*enode.Node
+	for _, addr := range addrs {
+		node, err := enode.Parse(enode.ValidSchemes, addr)
+		if err != nil {
+			// the bootnodes may also be multiaddrs, which are not of use here
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 0 {
+		return nil, errors.New("no ENR bootnodes to crawl from")
+	}
+	return nodes, nil
+}
+
+func p2pCrawl(ctx *cli.Context) error {
+	if ctx.NArg() < 1 {
+		return errors.New("need the nodes file as argument")
+	}
+	file := ctx.Args().First()
+	input, err := crawler.LoadNodes(file)
+	if os.IsNotExist(err) {
+		input, err = make(crawler.NodeSet), nil
+	}
+	if err != nil {
+		return err
+	}
+	bootnodes, err := crawlBootnodes(ctx)
+	if err != nil {
+		return err
+	}
+	c, err := crawler.New(crawler.Config{
+		Bootnodes:  bootnodes,
+		ListenAddr: ctx.String(crawlListenFlag.Name),
+		Workers:    ctx.Int(crawlWorkersFlag.Name),
+	})
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	runCtx, cancel := context.WithTimeout(context.Background(), ctx.Duration(crawlTimeoutFlag.Name))
+	defer cancel()
+	go func() {
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+		defer signal.Stop(sigs)
+		select {
+		case <-sigs:
+			cancel()
+		case <-runCtx.Done():
+		}
+	}()
+	output := c.Run(runCtx, input)
+	if err := crawler.WriteNodes(file, output); err != nil {
+		return err
+	}
+	summary := crawler.Summarize(output)
+	fmt.Printf("Crawled %d nodes, %d reachable, written to %s\n", summary.Nodes, summary.Reachable, file)
+	return nil
+}
+
+func p2pSummary(ctx *cli.Context) error {
+	if ctx.NArg() < 1 {
+		return errors.New("need the nodes file as argument")
+	}
+	set, err := crawler.LoadNodes(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	summary := crawler.Summarize(set)
+	fmt.Printf("Nodes:        %d\n", summary.Nodes)
+	fmt.Printf("Reachable:    %d\n", summary.Reachable)
+	fmt.Printf("Highest head: %d\n", summary.HighestHead)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	printCounts(w, "CLIENT VERSION", summary.Versions, summary.Reachable)
+	printCounts(w, "FORK DIGEST", summary.Forks, summary.Nodes)
+	return w.Flush()
+}
+
+func printCounts(w *tabwriter.Writer, title string, counts []crawler.Count, total int) {
+	fmt.Fprintf(w, "\n%s\tNODES\tSHARE\n", title)
+	for _, count := range counts {
+		fmt.Fprintf(w, "%s\t%d\t%.1f%%\n", count.Value, count.Nodes, 100*float64(count.Nodes)/float64(total))
+	}
+}
@@ -48,6 +48,9 @@ type IBlockChain interface {
 	SetEngine(engine consensus.Engine)
 	GetBlocksFromHash(hash types.Hash, n int) (blocks []block.IBlock)
 	SealedBlock(b block.IBlock) error
+	// VerifyBlockSeal verifies the seal and the verifier signatures of a block
+	// without executing it.
+	VerifyBlockSeal(b block.IBlock) error
 	Engine() consensus.Engine
 	GetReceipts(blockHash types.Hash) (block.Receipts, error)
 	GetLogs(blockHash types.Hash) ([][]*block.Log, error)
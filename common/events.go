@@ -29,6 +29,12 @@ type NewLocalTxsEvent struct{ Txs []*transaction.Transaction }
 // NewTxsEvent txs
 type NewTxsEvent struct{ Txs []*transaction.Transaction }
 
+// EvictedTxsEvent is posted when the txs pool drops transactions to stay within its limits
+type EvictedTxsEvent struct {
+	Txs    []*transaction.Transaction
+	Reason string
+}
+
 // NewLogsEvent new logs
 type NewLogsEvent struct{ Logs []*block.Log }
 
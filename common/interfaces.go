@@ -147,4 +147,6 @@ type ITxsPool interface {
 	Stats() (int, int, int, int)
 	Nonce(addr types.Address) uint64
 	Content() (map[types.Address][]*transaction.Transaction, map[types.Address][]*transaction.Transaction)
+	ContentFrom(addr types.Address) ([]*transaction.Transaction, []*transaction.Transaction, map[types.Hash]error)
+	PriceBump() uint64
 }
@@ -18,16 +18,147 @@ package transaction
 
 import (
 	"github.com/holiman/uint256"
+	"github.com/n42blockchain/N42/common/crypto"
+	"github.com/n42blockchain/N42/common/hash"
 	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/internal/avm/common"
 )
 
+// SakuragiTx is a fee-delegated dynamic fee transaction. The sender signs the
+// payload with V, R, S, the fee payer then signs over the signed payload and pays
+// for the gas, while the value is still taken from the sender.
 type SakuragiTx struct {
-	Nonce    uint64      // nonce of sender account
-	GasPrice uint256.Int // wei per gas
-	Gas      uint64      // gas limit
-	To       *types.Address
-	From     *types.Address
-	Value    uint256.Int // wei amount
-	Data     []byte      // contract invocation input data
-	Sign     []byte      // signature values
+	ChainID    *uint256.Int
+	Nonce      uint64       // nonce of sender account
+	GasTipCap  *uint256.Int // a.k.a. maxPriorityFeePerGas
+	GasFeeCap  *uint256.Int // a.k.a. maxFeePerGas
+	Gas        uint64       // gas limit
+	To         *types.Address
+	From       *types.Address
+	Value      *uint256.Int // wei amount
+	Data       []byte       // contract invocation input data
+	AccessList AccessList
+	FeePayer   *types.Address // account paying for the gas
+	Sign       []byte         // fee payer signature values, [R || S || V] with V 0 or 1
+	V, R, S    *uint256.Int   // sender signature values
+}
+
+// copy creates a deep copy of the transaction data and initializes all fields.
+func (tx *SakuragiTx) copy() TxData {
+	cpy := &SakuragiTx{
+		Nonce:    tx.Nonce,
+		To:       copyAddressPtr(tx.To),
+		From:     copyAddressPtr(tx.From),
+		FeePayer: copyAddressPtr(tx.FeePayer),
+		Data:     common.CopyBytes(tx.Data),
+		Sign:     common.CopyBytes(tx.Sign),
+		Gas:      tx.Gas,
+		// These are copied below.
+		AccessList: make(AccessList, len(tx.AccessList)),
+		Value:      new(uint256.Int),
+		ChainID:    new(uint256.Int),
+		GasTipCap:  new(uint256.Int),
+		GasFeeCap:  new(uint256.Int),
+		V:          new(uint256.Int),
+		R:          new(uint256.Int),
+		S:          new(uint256.Int),
+	}
+	copy(cpy.AccessList, tx.AccessList)
+	if tx.Value != nil {
+		cpy.Value.Set(tx.Value)
+	}
+	if tx.ChainID != nil {
+		cpy.ChainID.Set(tx.ChainID)
+	}
+	if tx.GasTipCap != nil {
+		cpy.GasTipCap.Set(tx.GasTipCap)
+	}
+	if tx.GasFeeCap != nil {
+		cpy.GasFeeCap.Set(tx.GasFeeCap)
+	}
+	if tx.V != nil {
+		cpy.V.Set(tx.V)
+	}
+	if tx.R != nil {
+		cpy.R.Set(tx.R)
+	}
+	if tx.S != nil {
+		cpy.S.Set(tx.S)
+	}
+	return cpy
+}
+
+// accessors for innerTx.
+func (tx *SakuragiTx) txType() byte            { return SakuragiTxType }
+func (tx *SakuragiTx) chainID() *uint256.Int   { return tx.ChainID }
+func (tx *SakuragiTx) accessList() AccessList  { return tx.AccessList }
+func (tx *SakuragiTx) data() []byte            { return tx.Data }
+func (tx *SakuragiTx) gas() uint64             { return tx.Gas }
+func (tx *SakuragiTx) gasFeeCap() *uint256.Int { return tx.GasFeeCap }
+func (tx *SakuragiTx) gasTipCap() *uint256.Int { return tx.GasTipCap }
+func (tx *SakuragiTx) gasPrice() *uint256.Int  { return tx.GasFeeCap }
+func (tx *SakuragiTx) value() *uint256.Int     { return tx.Value }
+func (tx *SakuragiTx) nonce() uint64           { return tx.Nonce }
+func (tx *SakuragiTx) to() *types.Address      { return tx.To }
+func (tx *SakuragiTx) from() *types.Address    { return tx.From }
+func (tx *SakuragiTx) sign() []byte            { return tx.Sign }
+
+// Hash computes the hash (but not for signatures!)
+func (tx *SakuragiTx) hash() types.Hash {
+	payerV, payerR, payerS := tx.feePayerSignatureValues()
+	hash := hash.PrefixedRlpHash(SakuragiTxType, []interface{}{
+		tx.ChainID,
+		tx.Nonce,
+		tx.GasTipCap,
+		tx.GasFeeCap,
+		tx.Gas,
+		tx.To,
+		tx.Value,
+		tx.Data,
+		tx.AccessList,
+		tx.V, tx.R, tx.S,
+		tx.FeePayer,
+		payerV, payerR, payerS,
+	})
+	return hash
+}
+
+func (tx *SakuragiTx) rawSignatureValues() (v, r, s *uint256.Int) {
+	return tx.V, tx.R, tx.S
+}
+
+func (tx *SakuragiTx) setSignatureValues(chainID, v, r, s *uint256.Int) {
+	tx.ChainID, tx.V, tx.R, tx.S = chainID, v, r, s
+}
+
+// feePayerSignatureValues splits the fee payer signature into its V, R, S
+// values, which are zero if the transaction is not signed by the fee payer yet.
+func (tx *SakuragiTx) feePayerSignatureValues() (v, r, s *uint256.Int) {
+	if len(tx.Sign) != crypto.SignatureLength {
+		return new(uint256.Int), new(uint256.Int), new(uint256.Int)
+	}
+	r = new(uint256.Int).SetBytes(tx.Sign[:32])
+	s = new(uint256.Int).SetBytes(tx.Sign[32:64])
+	v = uint256.NewInt(uint64(tx.Sign[64]))
+	return v, r, s
+}
+
+// encodeFeePayer packs the fee payer and its signature into the sign field of
+// the protobuf encoding, which has no dedicated fields for them.
+func (tx *SakuragiTx) encodeFeePayer() []byte {
+	if tx.FeePayer == nil {
+		return common.CopyBytes(tx.Sign)
+	}
+	return append(tx.FeePayer.Bytes(), tx.Sign...)
+}
+
+// decodeFeePayer is the inverse of encodeFeePayer.
+func (tx *SakuragiTx) decodeFeePayer(sign []byte) {
+	if len(sign) < types.AddressLength {
+		tx.Sign = common.CopyBytes(sign)
+		return
+	}
+	payer := types.BytesToAddress(sign[:types.AddressLength])
+	tx.FeePayer = &payer
+	tx.Sign = common.CopyBytes(sign[types.AddressLength:])
 }
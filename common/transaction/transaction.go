@@ -33,6 +33,7 @@ import (
 var (
 	ErrGasFeeCapTooLow = fmt.Errorf("fee cap less than base fee")
 	ErrUnmarshalHash   = fmt.Errorf("hash verify falied")
+	ErrInvalidFeePayer = fmt.Errorf("invalid fee payer signature")
 )
 
 // Transaction types.
@@ -40,6 +41,10 @@ const (
 	LegacyTxType = iota
 	AccessListTxType
 	DynamicFeeTxType
+
+	// SakuragiTxType is numbered clear of the types Ethereum has assigned
+	// (0x03 blob, 0x04 set code) and is likely to assign next.
+	SakuragiTxType = 0x10
 )
 
 type TxData interface {
@@ -194,6 +199,35 @@ func txDataFromProtoMessage(message proto.Message) (TxData, error) {
 		dftt.From = utils.ConvertH160ToPAddress(pbTx.From)
 		dftt.Sign = pbTx.Sign
 		inner = &dftt
+	case SakuragiTxType:
+		var stx SakuragiTx
+		stx.ChainID = uint256.NewInt(pbTx.ChainID)
+		stx.Nonce = pbTx.Nonce
+		stx.Gas = pbTx.Gas
+		stx.GasFeeCap = utils.ConvertH256ToUint256Int(pbTx.FeePerGas)
+		stx.GasTipCap = utils.ConvertH256ToUint256Int(pbTx.PriorityFeePerGas)
+		stx.Value = utils.ConvertH256ToUint256Int(pbTx.Value)
+		if nil != pbTx.V {
+			stx.V = utils.ConvertH256ToUint256Int(pbTx.V)
+		}
+		if nil != pbTx.R {
+			stx.R = utils.ConvertH256ToUint256Int(pbTx.R)
+		}
+		if nil != pbTx.S {
+			stx.S = utils.ConvertH256ToUint256Int(pbTx.S)
+		}
+		stx.Data = pbTx.Data
+		if nil != pbTx.To {
+			stx.To = utils.ConvertH160ToPAddress(pbTx.To)
+			if *stx.To == (types.Address{}) {
+				stx.To = nil
+			}
+		}
+		stx.From = utils.ConvertH160ToPAddress(pbTx.From)
+		stx.decodeFeePayer(pbTx.Sign)
+		inner = &stx
+	default:
+		return nil, ErrTxTypeNotSupported
 	}
 
 	// todo
@@ -249,6 +283,17 @@ func (tx *Transaction) ToProtoMessage() proto.Message {
 		pbTx.Sign = t.Sign
 		pbTx.FeePerGas = utils.ConvertUint256IntToH256(t.GasFeeCap)
 		pbTx.PriorityFeePerGas = utils.ConvertUint256IntToH256(t.GasTipCap)
+	case *SakuragiTx:
+		pbTx.ChainID = t.ChainID.Uint64()
+		pbTx.Nonce = tx.Nonce()
+		pbTx.Gas = tx.Gas()
+		pbTx.GasPrice = utils.ConvertUint256IntToH256(tx.GasPrice())
+		pbTx.Value = utils.ConvertUint256IntToH256(tx.Value())
+		pbTx.Data = tx.Data()
+		pbTx.From = utils.ConvertAddressToH160(*tx.From())
+		pbTx.Sign = t.encodeFeePayer()
+		pbTx.FeePerGas = utils.ConvertUint256IntToH256(t.GasFeeCap)
+		pbTx.PriorityFeePerGas = utils.ConvertUint256IntToH256(t.GasTipCap)
 	}
 	if tx.To() != nil {
 		pbTx.To = utils.ConvertAddressToH160(*tx.To())
@@ -324,6 +369,17 @@ func (tx Transaction) Marshal() ([]byte, error) {
 		pbTx.Sign = t.Sign
 		pbTx.FeePerGas = utils.ConvertUint256IntToH256(t.GasFeeCap)
 		pbTx.PriorityFeePerGas = utils.ConvertUint256IntToH256(t.GasTipCap)
+	case *SakuragiTx:
+		pbTx.ChainID = t.ChainID.Uint64()
+		pbTx.Nonce = tx.Nonce()
+		pbTx.Gas = tx.Gas()
+		pbTx.GasPrice = utils.ConvertUint256IntToH256(tx.GasPrice())
+		pbTx.Value = utils.ConvertUint256IntToH256(tx.Value())
+		pbTx.Data = tx.Data()
+		pbTx.From = utils.ConvertAddressToH160(*tx.From())
+		pbTx.Sign = t.encodeFeePayer()
+		pbTx.FeePerGas = utils.ConvertUint256IntToH256(t.GasFeeCap)
+		pbTx.PriorityFeePerGas = utils.ConvertUint256IntToH256(t.GasTipCap)
 	}
 	if tx.To() != nil {
 		pbTx.To = utils.ConvertAddressToH160(*tx.To())
@@ -425,6 +481,8 @@ func (tx *Transaction) SetFrom(addr types.Address) {
 		t.From = &addr
 	case *DynamicFeeTx:
 		t.From = &addr
+	case *SakuragiTx:
+		t.From = &addr
 	}
 }
 
@@ -436,6 +494,8 @@ func (tx *Transaction) SetNonce(nonce uint64) {
 		t.Nonce = nonce
 	case *DynamicFeeTx:
 		t.Nonce = nonce
+	case *SakuragiTx:
+		t.Nonce = nonce
 	}
 }
 
@@ -443,14 +503,33 @@ func (tx *Transaction) Sign() []byte {
 	return tx.inner.sign()
 }
 
+// FeePayer returns the account paying for the gas of a fee-delegated
+// transaction, or nil for transactions paid by their sender.
+func (tx *Transaction) FeePayer() *types.Address {
+	if t, ok := tx.inner.(*SakuragiTx); ok {
+		return copyAddressPtr(t.FeePayer)
+	}
+	return nil
+}
+
+// Cost returns the amount the sender pays, value + gasPrice * gas. The gas of a
+// fee-delegated transaction is paid by the fee payer and is not included.
 func (tx *Transaction) Cost() *uint256.Int {
-	price := tx.inner.gasPrice()
-	gas := uint256.NewInt(tx.inner.gas())
-	total := new(uint256.Int).Mul(price, gas)
+	if tx.FeePayer() != nil {
+		return new(uint256.Int).Set(tx.Value())
+	}
+	total := tx.GasCost()
 	total = total.Add(total, tx.Value())
 	return total
 }
 
+// GasCost returns the maximum amount paid for the gas, gasPrice * gas.
+func (tx *Transaction) GasCost() *uint256.Int {
+	price := tx.inner.gasPrice()
+	gas := uint256.NewInt(tx.inner.gas())
+	return new(uint256.Int).Mul(price, gas)
+}
+
 func (tx *Transaction) Hash() types.Hash {
 	if hash := tx.hash.Load(); hash != nil {
 		return hash.(types.Hash)
@@ -612,6 +691,7 @@ type Message struct {
 	tip        uint256.Int
 	data       []byte
 	accessList AccessList
+	feePayer   *types.Address
 	checkNonce bool
 	isFree     bool
 }
@@ -673,9 +753,21 @@ func (tx *Transaction) AsMessage(s Signer, baseFee *uint256.Int) (Message, error
 	//}
 	msg.from = *tx.From()
 
+	// The fee payer is charged for the gas, make sure it agreed to it.
+	if payer := tx.FeePayer(); payer != nil {
+		signer, err := FeePayerSender(s, tx)
+		if err != nil {
+			return msg, err
+		}
+		if signer != *payer {
+			return msg, fmt.Errorf("%w: have %v, want %v", ErrInvalidFeePayer, signer, payer)
+		}
+		msg.feePayer = payer
+	}
 	return msg, nil
 }
-func (m Message) From() types.Address    { return m.from }
+func (m Message) From() types.Address { return m.from }
+
 func (m Message) To() *types.Address     { return m.to }
 func (m Message) GasPrice() *uint256.Int { return &m.gasPrice }
 func (m Message) FeeCap() *uint256.Int   { return &m.feeCap }
@@ -693,3 +785,12 @@ func (m Message) IsFree() bool { return m.isFree }
 func (m *Message) SetIsFree(isFree bool) {
 	m.isFree = isFree
 }
+
+// FeePayer returns the account paying for the gas, the sender unless the
+// message comes from a fee-delegated transaction.
+func (m Message) FeePayer() types.Address {
+	if m.feePayer != nil {
+		return *m.feePayer
+	}
+	return m.from
+}
@@ -46,6 +46,8 @@ type sigCache struct {
 func MakeSigner(config *params.ChainConfig, blockNumber *big.Int) Signer {
 	var signer Signer
 	switch {
+	case config.IsSakuragi(blockNumber.Uint64()):
+		signer = NewSakuragiSigner(config.ChainID)
 	case config.IsLondon(blockNumber.Uint64()):
 		signer = NewLondonSigner(config.ChainID)
 	case config.IsBerlin(blockNumber.Uint64()):
@@ -71,7 +73,7 @@ func LatestSignerForChainID(chainID *big.Int) Signer {
 	if chainID == nil {
 		return HomesteadSigner{}
 	}
-	return NewLondonSigner(chainID)
+	return NewSakuragiSigner(chainID)
 }
 
 // SignNewTx creates a transaction and signs it.
@@ -177,14 +179,17 @@ func (s londonSigner) Equal(s2 Signer) bool {
 }
 
 func (s londonSigner) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big.Int, err error) {
-	txdata, ok := tx.inner.(*DynamicFeeTx)
-	if !ok {
+	var txChainID *uint256.Int
+	switch txdata := tx.inner.(type) {
+	case *DynamicFeeTx:
+		txChainID = txdata.ChainID
+	default:
 		return s.eip2930Signer.SignatureValues(tx, sig)
 	}
 	// Check that chain ID of tx matches the signer. We also accept ID zero here,
 	// because it indicates that the chain ID was not specified in the tx.
 	chainId, _ := uint256.FromBig(s.chainId)
-	if txdata.ChainID.Sign() != 0 && txdata.ChainID.Cmp(chainId) != 0 {
+	if txChainID.Sign() != 0 && txChainID.Cmp(chainId) != 0 {
 		return nil, nil, nil, ErrInvalidChainId
 	}
 	R, S, _ = decodeSignature(sig)
@@ -198,10 +203,96 @@ func (s londonSigner) Hash(tx *Transaction) types.Hash {
 	if tx.Type() != DynamicFeeTxType {
 		return s.eip2930Signer.Hash(tx)
 	}
+	return dynamicFeeHash(s.chainId, tx)
+}
+
+// dynamicFeeHash returns the hash signed by the sender of a dynamic fee or a
+// fee-delegated transaction.
+func dynamicFeeHash(chainId *big.Int, tx *Transaction) types.Hash {
+	return hash.PrefixedRlpHash(
+		tx.Type(),
+		[]interface{}{
+			chainId,
+			tx.Nonce(),
+			tx.GasTipCap(),
+			tx.GasFeeCap(),
+			tx.Gas(),
+			tx.To(),
+			tx.Value(),
+			tx.Data(),
+			tx.AccessList(),
+		})
+}
+
+type sakuragiSigner struct{ londonSigner }
+
+// NewSakuragiSigner returns a signer that accepts
+// - fee-delegated Sakuragi transactions,
+// - EIP-1559 dynamic fee transactions,
+// - EIP-2930 access list transactions,
+// - EIP-155 replay protected transactions, and
+// - legacy Homestead transactions.
+func NewSakuragiSigner(chainId *big.Int) Signer {
+	return sakuragiSigner{londonSigner{eip2930Signer{NewEIP155Signer(chainId)}}}
+}
+
+func (s sakuragiSigner) Sender(tx *Transaction) (types.Address, error) {
+	if tx.Type() != SakuragiTxType {
+		return s.londonSigner.Sender(tx)
+	}
+	V, R, S := tx.RawSignatureValues()
+	// The sender of a fee-delegated tx uses 0 and 1 as its recovery id, like
+	// for a dynamic fee tx.
+	V1 := new(big.Int).Add(V.ToBig(), big.NewInt(27))
+	chainId, _ := uint256.FromBig(s.chainId)
+	id := tx.ChainId()
+	if id.Cmp(chainId) != 0 {
+		return types.Address{}, ErrInvalidChainId
+	}
+	return recoverPlain(s.Hash(tx), R.ToBig(), S.ToBig(), V1, true)
+}
+
+func (s sakuragiSigner) Equal(s2 Signer) bool {
+	x, ok := s2.(sakuragiSigner)
+	return ok && x.chainId.Cmp(s.chainId) == 0
+}
+
+func (s sakuragiSigner) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big.Int, err error) {
+	txdata, ok := tx.inner.(*SakuragiTx)
+	if !ok {
+		return s.londonSigner.SignatureValues(tx, sig)
+	}
+	// Check that chain ID of tx matches the signer. We also accept ID zero here,
+	// because it indicates that the chain ID was not specified in the tx.
+	chainId, _ := uint256.FromBig(s.chainId)
+	if txdata.ChainID.Sign() != 0 && txdata.ChainID.Cmp(chainId) != 0 {
+		return nil, nil, nil, ErrInvalidChainId
+	}
+	R, S, _ = decodeSignature(sig)
+	V = big.NewInt(int64(sig[64]))
+	return R, S, V, nil
+}
+
+// Hash returns the hash to be signed by the sender.
+// It does not uniquely identify the transaction.
+//
+// The sender of a fee-delegated transaction signs the same fields as for a
+// dynamic fee transaction, leaving the choice of the fee payer open.
+func (s sakuragiSigner) Hash(tx *Transaction) types.Hash {
+	if tx.Type() != SakuragiTxType {
+		return s.londonSigner.Hash(tx)
+	}
+	return dynamicFeeHash(s.chainId, tx)
+}
+
+// FeePayerHash returns the hash signed by the fee payer of a fee-delegated
+// transaction. It covers the sender signed payload and the fee payer address.
+func FeePayerHash(chainID *big.Int, tx *Transaction) types.Hash {
+	v, r, s := tx.RawSignatureValues()
 	return hash.PrefixedRlpHash(
 		tx.Type(),
 		[]interface{}{
-			s.chainId,
+			chainID,
 			tx.Nonce(),
 			tx.GasTipCap(),
 			tx.GasFeeCap(),
@@ -210,9 +301,60 @@ func (s londonSigner) Hash(tx *Transaction) types.Hash {
 			tx.Value(),
 			tx.Data(),
 			tx.AccessList(),
+			v, r, s,
+			tx.FeePayer(),
 		})
 }
 
+// FeePayerSender returns the address derived from the fee payer signature of a
+// fee-delegated transaction.
+//
+// Signers of the rules before the Sakuragi upgrade do not accept fee-delegated
+// transactions.
+func FeePayerSender(signer Signer, tx *Transaction) (types.Address, error) {
+	txdata, ok := tx.inner.(*SakuragiTx)
+	if !ok {
+		return types.Address{}, ErrTxTypeNotSupported
+	}
+	if _, ok := signer.(sakuragiSigner); !ok {
+		return types.Address{}, ErrTxTypeNotSupported
+	}
+	if txdata.FeePayer == nil || len(txdata.Sign) != crypto.SignatureLength {
+		return types.Address{}, ErrInvalidFeePayer
+	}
+	if signer.ChainID() == nil {
+		return types.Address{}, ErrInvalidChainId
+	}
+	chainId, _ := uint256.FromBig(signer.ChainID())
+	if tx.ChainId().Cmp(chainId) != 0 {
+		return types.Address{}, ErrInvalidChainId
+	}
+	// The fee payer uses 0 and 1 as its recovery id, like the sender.
+	R, S, V := decodeSignature(txdata.Sign)
+	return recoverPlain(FeePayerHash(signer.ChainID(), tx), R, S, V, true)
+}
+
+// SignFeePayerTx signs a sender signed fee-delegated transaction as its fee
+// payer, which is set to the address of the given private key.
+func SignFeePayerTx(tx *Transaction, s Signer, prv *ecdsa.PrivateKey) (*Transaction, error) {
+	txdata, ok := tx.inner.(*SakuragiTx)
+	if !ok {
+		return nil, ErrTxTypeNotSupported
+	}
+	cpy := txdata.copy().(*SakuragiTx)
+	payer := crypto.PubkeyToAddress(prv.PublicKey)
+	cpy.FeePayer, cpy.Sign = &payer, nil
+	unsigned := &Transaction{inner: cpy, time: tx.time}
+
+	h := FeePayerHash(s.ChainID(), unsigned)
+	sig, err := crypto.Sign(h[:], prv)
+	if err != nil {
+		return nil, err
+	}
+	cpy.Sign = sig
+	return unsigned, nil
+}
+
 type eip2930Signer struct{ EIP155Signer }
 
 // NewEIP2930Signer returns a signer that accepts EIP-2930 access list transactions,
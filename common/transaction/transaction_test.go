@@ -21,7 +21,10 @@ import (
 	"encoding/json"
 	"github.com/holiman/uint256"
 	"github.com/libp2p/go-libp2p/core/crypto"
+	n42crypto "github.com/n42blockchain/N42/common/crypto"
 	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/params"
+	"math/big"
 	"testing"
 )
 
@@ -86,3 +89,114 @@ func TestNewDynamicTx(t *testing.T) {
 	//addr := types.PublicToAddress(pub)
 
 }
+
+func TestSakuragiTxFeePayer(t *testing.T) {
+	senderKey, _ := n42crypto.GenerateKey()
+	payerKey, _ := n42crypto.GenerateKey()
+	sender := n42crypto.PubkeyToAddress(senderKey.PublicKey)
+	payer := n42crypto.PubkeyToAddress(payerKey.PublicKey)
+	to := types.HexToAddress("0x1000000000000000000000000000000000000001")
+
+	signer := NewSakuragiSigner(big.NewInt(42))
+	tx, err := SignNewTx(senderKey, signer, &SakuragiTx{
+		ChainID:   uint256.NewInt(42),
+		Nonce:     3,
+		GasTipCap: uint256.NewInt(1),
+		GasFeeCap: uint256.NewInt(100),
+		Gas:       21000,
+		To:        &to,
+		From:      &sender,
+		Value:     uint256.NewInt(7),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from, err := Sender(signer, tx); err != nil || from != sender {
+		t.Fatalf("sender mismatch: have %v, want %v, err %v", from, sender, err)
+	}
+	if _, err := FeePayerSender(signer, tx); err == nil {
+		t.Fatal("recovered fee payer of a transaction without fee payer signature")
+	}
+
+	tx, err = SignFeePayerTx(tx, signer, payerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tx.FeePayer(); got == nil || *got != payer {
+		t.Fatalf("fee payer mismatch: have %v, want %v", got, payer)
+	}
+	if got, err := FeePayerSender(signer, tx); err != nil || got != payer {
+		t.Fatalf("fee payer signer mismatch: have %v, want %v, err %v", got, payer, err)
+	}
+	if tx.Cost().Cmp(uint256.NewInt(7)) != 0 {
+		t.Fatalf("sender cost mismatch: have %v, want 7", tx.Cost())
+	}
+
+	// The fee payer and its signature must survive the protobuf encoding.
+	enc, err := tx.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dec Transaction
+	if err := dec.Unmarshal(enc); err != nil {
+		t.Fatal(err)
+	}
+	if dec.Hash() != tx.Hash() {
+		t.Fatalf("hash mismatch after decoding: have %v, want %v", dec.Hash(), tx.Hash())
+	}
+	msg, err := dec.AsMessage(signer, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.From() != sender || msg.FeePayer() != payer {
+		t.Fatalf("message accounts mismatch: from %v, fee payer %v", msg.From(), msg.FeePayer())
+	}
+
+	// Swapping the fee payer invalidates its signature.
+	dec.inner.(*SakuragiTx).FeePayer = &sender
+	if _, err := dec.AsMessage(signer, nil); err == nil {
+		t.Fatal("accepted transaction with forged fee payer")
+	}
+}
+
+func TestSakuragiTxSignerFork(t *testing.T) {
+	senderKey, _ := n42crypto.GenerateKey()
+	payerKey, _ := n42crypto.GenerateKey()
+	sender := n42crypto.PubkeyToAddress(senderKey.PublicKey)
+	to := types.HexToAddress("0x1000000000000000000000000000000000000001")
+
+	signer := NewSakuragiSigner(big.NewInt(42))
+	tx, err := SignNewTx(senderKey, signer, &SakuragiTx{
+		ChainID:   uint256.NewInt(42),
+		GasTipCap: uint256.NewInt(1),
+		GasFeeCap: uint256.NewInt(100),
+		Gas:       21000,
+		To:        &to,
+		From:      &sender,
+		Value:     uint256.NewInt(7),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx, err = SignFeePayerTx(tx, signer, payerKey); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &params.ChainConfig{ChainID: big.NewInt(42), LondonBlock: big.NewInt(0), SakuragiBlock: big.NewInt(10)}
+	tests := []struct {
+		number uint64
+		valid  bool
+	}{
+		{9, false},
+		{10, true},
+	}
+	for _, tt := range tests {
+		signer := MakeSigner(config, new(big.Int).SetUint64(tt.number))
+		if _, err := Sender(signer, tx); (err == nil) != tt.valid {
+			t.Errorf("block %d: sender error %v, want valid %v", tt.number, err, tt.valid)
+		}
+		if _, err := tx.AsMessage(signer, nil); (err == nil) != tt.valid {
+			t.Errorf("block %d: message error %v, want valid %v", tt.number, err, tt.valid)
+		}
+	}
+}
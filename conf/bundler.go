@@ -0,0 +1,44 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package conf
+
+import "time"
+
+// BundlerConfig configures the ERC-4337 UserOperation mempool and bundler.
+type BundlerConfig struct {
+	Enabled     bool          `json:"enabled" yaml:"enabled"`
+	EntryPoint  string        `json:"entryPoint" yaml:"entryPoint"`   // Address of the supported entry point contract
+	Account     string        `json:"account" yaml:"account"`         // Local account signing the handleOps transactions
+	Beneficiary string        `json:"beneficiary" yaml:"beneficiary"` // Receiver of the bundle fees, defaults to Account
+	Interval    time.Duration `json:"interval" yaml:"interval"`       // Time between two bundles
+
+	MaxBundleSize      int    `json:"maxBundleSize" yaml:"maxBundleSize"`           // Maximum operations in a bundle
+	MaxOpsPerSender    int    `json:"maxOpsPerSender" yaml:"maxOpsPerSender"`       // Maximum pooled operations of one sender
+	MaxPoolSize        int    `json:"maxPoolSize" yaml:"maxPoolSize"`               // Maximum pooled operations
+	MaxVerificationGas uint64 `json:"maxVerificationGas" yaml:"maxVerificationGas"` // Upper bound of verificationGasLimit
+	PriceBump          uint64 `json:"priceBump" yaml:"priceBump"`                   // Minimum fee bump percentage to replace an operation
+}
+
+// DefaultBundlerConfig contains the default bundler settings.
+var DefaultBundlerConfig = BundlerConfig{
+	Interval:           4 * time.Second,
+	MaxBundleSize:      16,
+	MaxOpsPerSender:    4,
+	MaxPoolSize:        4096,
+	MaxVerificationGas: 5000000,
+	PriceBump:          10,
+}
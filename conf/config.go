@@ -17,11 +17,21 @@
 package conf
 
 import (
-	"bufio"
+	"bytes"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
 	"github.com/n42blockchain/N42/params"
 	"gopkg.in/yaml.v2"
-	"os"
+)
+
+// Formats of the configuration files.
+const (
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
 )
 
 type Config struct {
@@ -37,34 +47,115 @@ type Config struct {
 	// Gas Price Oracle options
 	GPO   GpoConfig   `json:"gpo" yaml:"gpo"`
 	Miner MinerConfig `json:"miner"`
+	// ERC-4337 bundler options
+	Bundler BundlerConfig `json:"bundler" yaml:"bundler"`
 }
 
+// FormatOf returns the format of a configuration file from its extension,
+// YAML unless the extension is ".toml".
+func FormatOf(file string) string {
+	if strings.EqualFold(filepath.Ext(file), ".toml") {
+		return FormatTOML
+	}
+	return FormatYAML
+}
+
+// MarshalConfig encodes the configuration in the given format. TOML uses the
+// same keys as YAML, settings without value are omitted from it.
+func MarshalConfig(config Config, format string) ([]byte, error) {
+	out, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case FormatYAML:
+		return out, nil
+	case FormatTOML:
+		var tree interface{}
+		if err := yaml.Unmarshal(out, &tree); err != nil {
+			return nil, err
+		}
+		buf := new(bytes.Buffer)
+		if err := toml.NewEncoder(buf).Encode(tomlTree(tree)); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unknown configuration format %q", format)
+	}
+}
+
+// UnmarshalConfig decodes a configuration in the given format over config,
+// which keeps the settings missing from data. Unknown settings are an error.
+func UnmarshalConfig(data []byte, format string, config *Config) error {
+	switch format {
+	case FormatYAML:
+	case FormatTOML:
+		var tree map[string]interface{}
+		if err := toml.Unmarshal(data, &tree); err != nil {
+			return err
+		}
+		var err error
+		if data, err = yaml.Marshal(tree); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown configuration format %q", format)
+	}
+	return yaml.UnmarshalStrict(data, config)
+}
+
+// tomlTree converts a YAML document into a tree TOML can encode, dropping the
+// null values TOML cannot represent.
+func tomlTree(node interface{}) interface{} {
+	switch node := node.(type) {
+	case map[interface{}]interface{}:
+		tree := make(map[string]interface{}, len(node))
+		for k, v := range node {
+			if v != nil {
+				tree[fmt.Sprint(k)] = tomlTree(v)
+			}
+		}
+		return tree
+	case []interface{}:
+		list := make([]interface{}, 0, len(node))
+		for _, v := range node {
+			if v != nil {
+				list = append(list, tomlTree(v))
+			}
+		}
+		return list
+	default:
+		return node
+	}
+}
+
+// SaveConfigToFile writes the configuration to file, in TOML if its extension
+// is ".toml" and in YAML otherwise. The file may hold private keys, so it is
+// only readable by its owner.
 func SaveConfigToFile(file string, config Config) error {
 	if len(file) == 0 {
-		file = "./config2.yaml"
+		return fmt.Errorf("failed to save config, file is empty")
 	}
-
-	fd, err := os.OpenFile(file, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0777)
+	data, err := MarshalConfig(config, FormatOf(file))
 	if err != nil {
-		//log.Errorf("filed open file %v, err %v", file, err)
 		return err
 	}
-	defer fd.Close()
-	return yaml.NewEncoder(fd).Encode(config)
-	//return toml.NewEncoder(fd).Encode(blockchain)
+	return os.WriteFile(file, data, 0600)
 }
 
+// LoadConfigFromFile loads the configuration in file over config, in TOML if
+// its extension is ".toml" and in YAML otherwise.
 func LoadConfigFromFile(file string, config *Config) error {
 	if len(file) <= 0 {
 		return fmt.Errorf("failed to load blockchain from file, file is nil")
 	}
-	//_, err := toml.DecodeFile(file, blockchain)
-	fd, err := os.Open(file)
+	data, err := os.ReadFile(file)
 	if err != nil {
 		return err
 	}
-	defer fd.Close()
-	reader := bufio.NewReader(fd)
-	//return toml.NewDecoder(reader).Decode(blockchain)
-	return yaml.NewDecoder(reader).Decode(config)
+	if err := UnmarshalConfig(data, FormatOf(file), config); err != nil {
+		return fmt.Errorf("invalid config file %s: %v", file, err)
+	}
+	return nil
 }
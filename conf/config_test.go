@@ -0,0 +1,106 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package conf
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testConfig() Config {
+	return Config{
+		NodeCfg:     NodeConfig{HTTPPort: "20012", DataDir: "data"},
+		DatabaseCfg: DatabaseConfig{DBPath: "chaindata", SubDB: []string{"chain"}},
+		GPO:         GpoConfig{MaxHeaderHistory: 1024, SampleWindow: 5 * time.Minute},
+	}
+}
+
+func TestConfigRoundTrip(t *testing.T) {
+	for _, file := range []string{"config.yaml", "config.toml"} {
+		path := filepath.Join(t.TempDir(), file)
+		config := testConfig()
+		if err := SaveConfigToFile(path, config); err != nil {
+			t.Fatalf("%s: save failed: %v", file, err)
+		}
+		var loaded Config
+		if err := LoadConfigFromFile(path, &loaded); err != nil {
+			t.Fatalf("%s: load failed: %v", file, err)
+		}
+		// Compared in YAML, which does not tell empty lists from nil ones.
+		have, _ := MarshalConfig(loaded, FormatYAML)
+		want, _ := MarshalConfig(config, FormatYAML)
+		if !bytes.Equal(have, want) {
+			t.Fatalf("%s: loaded\n%s\nwant\n%s", file, have, want)
+		}
+	}
+}
+
+func TestConfigUnknownKey(t *testing.T) {
+	for format, data := range map[string]string{
+		FormatYAML: "node:\n  http_port: \"1\"\n  bogus: 1\n",
+		FormatTOML: "[node]\nhttp_port = \"1\"\nbogus = 1\n",
+	} {
+		var config Config
+		if err := UnmarshalConfig([]byte(data), format, &config); err == nil || !strings.Contains(err.Error(), "bogus") {
+			t.Fatalf("%s: unknown key: got %v, want an error naming it", format, err)
+		}
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	config := testConfig()
+	unknown, err := ApplyEnv(&config, []string{
+		"N42_NODE_HTTP_PORT=9999",
+		"N42_DATABASE_SUB_NAME=a, b",
+		"N42_GPO_SAMPLEWINDOW=1m",
+		"HOME=/root",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unknown) != 0 {
+		t.Errorf("unknown variables %q, want none", unknown)
+	}
+	if config.NodeCfg.HTTPPort != "9999" {
+		t.Errorf("http port %q, want 9999", config.NodeCfg.HTTPPort)
+	}
+	if !reflect.DeepEqual(config.DatabaseCfg.SubDB, []string{"a", "b"}) {
+		t.Errorf("sub databases %q, want [a b]", config.DatabaseCfg.SubDB)
+	}
+	if config.GPO.SampleWindow != time.Minute {
+		t.Errorf("sample window %v, want 1m", config.GPO.SampleWindow)
+	}
+
+	// Unknown variables are skipped, the known ones still applied.
+	unknown, err = ApplyEnv(&config, []string{"N42_NODE_BOGUS=1", "N42_NODE_HTTP_PORT=8888", "N42_A=1"})
+	if err != nil {
+		t.Fatalf("unknown variable: %v", err)
+	}
+	if !reflect.DeepEqual(unknown, []string{"N42_A", "N42_NODE_BOGUS"}) {
+		t.Errorf("unknown variables %q, want [N42_A N42_NODE_BOGUS]", unknown)
+	}
+	if config.NodeCfg.HTTPPort != "8888" {
+		t.Errorf("http port %q, want 8888", config.NodeCfg.HTTPPort)
+	}
+	if _, err := ApplyEnv(&config, []string{"N42_GPO_BLOCKS=many"}); err == nil {
+		t.Fatal("invalid value accepted")
+	}
+}
@@ -16,6 +16,8 @@
 
 package conf
 
+import "path/filepath"
+
 type DatabaseConfig struct {
 	DBType     string   `json:"db_type" yaml:"db_type"`
 	DBPath     string   `json:"path" yaml:"path"`
@@ -25,4 +27,33 @@ type DatabaseConfig struct {
 	IsMem      bool     `json:"memory" yaml:"memory"`
 	MaxDB      uint64   `json:"max_db" yaml:"max_db"`
 	MaxReaders uint64   `json:"max_readers" yaml:"max_readers"`
+
+	// Prune modes, each keeping the data of the last N blocks only. Zero
+	// keeps everything, which is the archive mode.
+	PruneHistory  uint64 `json:"prune_history" yaml:"prune_history"`   // state changesets and history indexes
+	PruneReceipts uint64 `json:"prune_receipts" yaml:"prune_receipts"` // receipts and transaction logs
+	PruneTxIndex  uint64 `json:"prune_tx_index" yaml:"prune_tx_index"` // transaction hash lookups
+
+	// Ancient store of the blocks past the finality threshold, relative to the
+	// data directory unless absolute. Blocks are only moved there in the
+	// background if Freeze is set.
+	AncientDir string `json:"ancient" yaml:"ancient"`
+	Freeze     bool   `json:"freeze" yaml:"freeze"`
+}
+
+// AncientPath returns the directory of the ancient store, empty if the node
+// runs without a data directory.
+func (c *DatabaseConfig) AncientPath(datadir string) string {
+	if datadir == "" || c.AncientDir == "" {
+		return ""
+	}
+	if filepath.IsAbs(c.AncientDir) {
+		return c.AncientDir
+	}
+	return filepath.Join(datadir, c.AncientDir)
+}
+
+// Pruning reports whether any kind of history is pruned.
+func (c *DatabaseConfig) Pruning() bool {
+	return c.PruneHistory != 0 || c.PruneReceipts != 0 || c.PruneTxIndex != 0
 }
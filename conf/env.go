@@ -0,0 +1,142 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package conf
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// EnvPrefix is the prefix of the environment variables overriding settings.
+const EnvPrefix = "N42_"
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// EnvName returns the environment variable overriding the setting at the given
+// path of YAML keys, e.g. N42_NODE_HTTP_PORT for node.http_port.
+func EnvName(keys ...string) string {
+	name := EnvPrefix + strings.Join(keys, "_")
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// ApplyEnv overrides the settings of config with the environment variables
+// named after them by EnvName. Values are given as in YAML, except for strings,
+// which are taken verbatim, and lists of strings, which are comma separated.
+// Variables with the EnvPrefix that name no setting are skipped and returned,
+// sorted, for the caller to warn about.
+func ApplyEnv(config *Config, environ []string) (unknown []string, err error) {
+	fields := make(map[string][]int)
+	collectEnv(reflect.TypeOf(config).Elem(), nil, nil, fields)
+
+	for _, entry := range environ {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok || !strings.HasPrefix(name, EnvPrefix) {
+			continue
+		}
+		index, ok := fields[name]
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		if err := setEnv(reflect.ValueOf(config).Elem(), index, value); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", name, err)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown, nil
+}
+
+// collectEnv maps the environment variables of the settings of struct type t,
+// found at the given keys and field index path, to the index path of their field.
+func collectEnv(t reflect.Type, keys []string, index []int, fields map[string][]int) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		key, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if key == "-" {
+			continue
+		}
+		if key == "" {
+			key = strings.ToLower(f.Name)
+		}
+		var (
+			fieldKeys  = append(keys[:len(keys):len(keys)], key)
+			fieldIndex = append(index[:len(index):len(index)], i)
+			ft         = f.Type
+		)
+		if ft.Kind() == reflect.Ptr && !ft.Implements(textUnmarshalerType) {
+			ft = ft.Elem()
+		}
+		switch ft.Kind() {
+		case reflect.Struct:
+			if reflect.PtrTo(ft).Implements(textUnmarshalerType) {
+				fields[EnvName(fieldKeys...)] = fieldIndex
+			} else {
+				collectEnv(ft, fieldKeys, fieldIndex, fields)
+			}
+		case reflect.Map, reflect.Interface, reflect.Func, reflect.Chan:
+			// Not settable from the environment.
+		default:
+			fields[EnvName(fieldKeys...)] = fieldIndex
+		}
+	}
+}
+
+// setEnv sets the field at the given index path of v, allocating the structs
+// on the way, to the value of its environment variable.
+func setEnv(v reflect.Value, index []int, value string) error {
+	for _, i := range index {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	switch {
+	case v.Kind() == reflect.String:
+		v.SetString(value)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		list := reflect.MakeSlice(v.Type(), 0, 0)
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = reflect.Append(list, reflect.ValueOf(item).Convert(v.Type().Elem()))
+			}
+		}
+		v.Set(list)
+	default:
+		return yaml.UnmarshalStrict([]byte(value), v.Addr().Interface())
+	}
+	return nil
+}
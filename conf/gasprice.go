@@ -19,6 +19,7 @@ package conf
 import (
 	"github.com/n42blockchain/N42/params"
 	"math/big"
+	"time"
 )
 
 var (
@@ -34,6 +35,12 @@ type GpoConfig struct {
 	Default          *big.Int `toml:",omitempty"`
 	MaxPrice         *big.Int `toml:",omitempty"`
 	IgnorePrice      *big.Int `toml:",omitempty"`
+
+	// SampleWindow is the minimum span of chain history sampled. It is converted
+	// to blocks using the APos period and raises Blocks if that is shorter.
+	SampleWindow time.Duration `toml:",omitempty"`
+	// PendingTips adds the tips of the executable txs pool transactions to the sample.
+	PendingTips bool
 }
 
 // FullNodeGPO contains default gasprice oracle settings for full node.
@@ -44,6 +51,8 @@ var FullNodeGPO = GpoConfig{
 	MaxBlockHistory:  1024,
 	MaxPrice:         DefaultMaxPrice,
 	IgnorePrice:      DefaultIgnorePrice,
+	SampleWindow:     5 * time.Minute,
+	PendingTips:      true,
 }
 
 // LightClientGPO contains default gasprice oracle settings for light client.
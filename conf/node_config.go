@@ -19,6 +19,8 @@ package conf
 import (
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 )
 
 const (
@@ -43,12 +45,21 @@ type NodeConfig struct {
 	// WSOrigins is the list of domain to accept websocket requests from. Please be
 	// aware that the server can only act upon the HTTP request the client sends and
 	// cannot verify the validity of the request header.
-	WSOrigins        string `toml:",omitempty"`
+	WSOrigins string `toml:",omitempty"`
+	// IPC enables the IPC endpoint. It serves every API, admin included, to the
+	// local users allowed to open the socket.
+	IPC              bool   `json:"ipc" yaml:"ipc"`
 	IPCPath          string `json:"ipc_path" yaml:"ipc_path"`
 	DataDir          string `json:"data_dir" yaml:"data_dir"`
 	MinFreeDiskSpace int    `json:"min_free_disk_space" yaml:"min_free_disk_space"`
 	Chain            string `json:"chain" yaml:"chain"`
 	Miner            bool   `json:"miner" yaml:"miner"`
+	// DevPeriod is the block period of the developer chain, zero sealing a block
+	// as soon as a transaction arrives.
+	DevPeriod uint64 `json:"dev_period" yaml:"dev_period"`
+	// TrustedBlock is a trusted block given as <number>:<hash>, overriding the
+	// built-in trusted block of the chain.
+	SyncTrustedBlock string `json:"sync_trusted_block" yaml:"sync_trusted_block"`
 
 	AuthRPC bool `json:"auth_rpc" yaml:"auth_rpc"`
 	// AuthAddr is the listening address on which authenticated APIs are provided.
@@ -136,3 +147,27 @@ func getKeyStoreDir(conf *NodeConfig) (string, bool, error) {
 func (c *NodeConfig) ExtRPCEnabled() bool {
 	return c.HTTPHost != "" || c.WSHost != ""
 }
+
+// IPCEndpoint resolves an IPC endpoint based on a configured value, taking into
+// account the set data folders as well as the designated platform we're
+// currently running on. An empty IPCPath disables the endpoint.
+func (c *NodeConfig) IPCEndpoint() string {
+	if c.IPCPath == "" {
+		return ""
+	}
+	// On windows we can only use plain top-level pipes
+	if runtime.GOOS == "windows" {
+		if strings.HasPrefix(c.IPCPath, `\\.\pipe\`) {
+			return c.IPCPath
+		}
+		return `\\.\pipe\` + c.IPCPath
+	}
+	// Resolve names into the data directory full paths otherwise
+	if filepath.Base(c.IPCPath) == c.IPCPath {
+		if c.DataDir == "" {
+			return filepath.Join(os.TempDir(), c.IPCPath)
+		}
+		return filepath.Join(c.DataDir, c.IPCPath)
+	}
+	return c.IPCPath
+}
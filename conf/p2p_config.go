@@ -26,8 +26,14 @@ type NetWorkConfig struct {
 type P2PConfig struct {
 	NoDiscovery         bool     `json:"no_discovery" yaml:"no_discovery"`
 	EnableUPnP          bool     `json:"enable_upnp" yaml:"enable_upnp"`
+	EnableNATService    bool     `json:"enable_nat_service" yaml:"enable_nat_service"`
+	EnableHolePunching  bool     `json:"enable_hole_punching" yaml:"enable_hole_punching"`
+	EnableAutoRelay     bool     `json:"enable_auto_relay" yaml:"enable_auto_relay"`
+	EnableRelayService  bool     `json:"enable_relay_service" yaml:"enable_relay_service"`
 	StaticPeerID        bool     `json:"static_peer_id" yaml:"static_peer_id"`
 	StaticPeers         []string `json:"static_peers" yaml:"static_peers"`
+	TrustedPeers        []string `json:"trusted_peers" yaml:"trusted_peers"`
+	DNSDiscoveryURLs    []string `json:"dns_discovery" yaml:"dns_discovery"`
 	BootstrapNodeAddr   []string `json:"bootstrap_node_addr" yaml:"bootstrap_node_addr"`
 	Discv5BootStrapAddr []string `json:"discv5_bootstrap_addr" yaml:"discv5_bootstrap_addr"`
 	RelayNodeAddr       string   `json:"relay_node_addr" yaml:"relay_node_addr"`
@@ -51,4 +57,30 @@ type P2PLimit struct {
 	BlockBatchLimit            int `json:"block_batch_limit" yaml:"block_batch_limit"`
 	BlockBatchLimitBurstFactor int `json:"block_batch_limit_burst_factor" yaml:"block_batch_limit_burst_factor"`
 	BlockBatchLimiterPeriod    int `json:"block_batch_limiter_period" yaml:"block_batch_limiter_period"`
+
+	// Protocol limits every RPC and gossip protocol which has no entry in Protocols.
+	Protocol ProtocolLimit `json:"protocol" yaml:"protocol"`
+	// Protocols overrides the limits of single protocols, keyed by message name
+	// such as "bodies_by_range" or "block".
+	Protocols map[string]ProtocolLimit `json:"protocols" yaml:"protocols"`
+}
+
+// ProtocolLimit holds the per-peer and global limits of a protocol, zero meaning
+// unlimited. Requests and bytes are per second, streams are concurrent and only
+// limit rpc protocols, as gossip messages do not hold a stream.
+type ProtocolLimit struct {
+	PeerRequests   float64 `json:"peer_requests" yaml:"peer_requests"`
+	PeerBytes      int64   `json:"peer_bytes" yaml:"peer_bytes"`
+	PeerStreams    int     `json:"peer_streams" yaml:"peer_streams"`
+	GlobalRequests float64 `json:"global_requests" yaml:"global_requests"`
+	GlobalBytes    int64   `json:"global_bytes" yaml:"global_bytes"`
+	GlobalStreams  int     `json:"global_streams" yaml:"global_streams"`
+}
+
+// ProtocolLimit returns the limits of the named protocol.
+func (l *P2PLimit) ProtocolLimit(name string) ProtocolLimit {
+	if limit, ok := l.Protocols[name]; ok {
+		return limit
+	}
+	return l.Protocol
 }
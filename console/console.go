@@ -0,0 +1,535 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package console implements the interactive JavaScript console of the node,
+// which talks to a local or remote node over JSON-RPC.
+package console
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/dop251/goja"
+	"github.com/n42blockchain/N42/common/hexutil"
+	"github.com/n42blockchain/N42/console/prompt"
+	"github.com/n42blockchain/N42/modules/rpc/jsonrpc"
+	"github.com/peterh/liner"
+)
+
+var (
+	// u: unlock, s: signXX, sendXX, n: newAccount, i: importXX
+	passwordRegexp = regexp.MustCompile(`personal\.[nusi]|(?i)passw|passphrase|privatekey`)
+	onlyWhitespace = regexp.MustCompile(`^\s*$`)
+	exit           = regexp.MustCompile(`^\s*exit\s*;*\s*$`)
+)
+
+// HistoryFile is the file within the data directory to store input scrollback.
+const HistoryFile = "console_history"
+
+// DefaultPrompt is the default prompt line prefix to use for user input querying.
+const DefaultPrompt = "> "
+
+// web3Namespace is the RPC namespace whose methods live directly on the web3
+// object rather than on a child object of it.
+const web3Namespace = "web3"
+
+// Config is the collection of configurations to fine tune the behavior of the
+// JavaScript console.
+type Config struct {
+	DataDir  string              // Data directory to store the console history at
+	DocRoot  string              // Filesystem path from where to load JavaScript files from
+	Client   *jsonrpc.Client     // RPC client to execute N42 requests through
+	Prompt   string              // Input prompt prefix string (defaults to DefaultPrompt)
+	Prompter prompt.UserPrompter // Input prompter to allow interactive user feedback (defaults to TerminalPrompter)
+	Printer  io.Writer           // Output writer to serialize any display strings to (defaults to os.Stdout)
+	Preload  []string            // Absolute paths to JavaScript files to preload
+}
+
+// Console is a JavaScript interpreted runtime environment. It is a fully fledged
+// JavaScript console attached to a running node via an external or in-process RPC
+// client.
+type Console struct {
+	client   *jsonrpc.Client     // RPC client to execute N42 requests through
+	vm       *goja.Runtime       // JavaScript interpreter, not safe for concurrent use
+	docRoot  string              // Filesystem path from where to load JavaScript files from
+	prompt   string              // Input prompt prefix string
+	prompter prompt.UserPrompter // Input prompter to allow interactive user feedback
+	histPath string              // Absolute path to the console scrollback history
+	history  []string            // Scroll history maintained by the console
+	printer  io.Writer           // Output writer to serialize any display strings to
+	modules  map[string][]string // RPC methods exposed by the node, keyed by namespace
+
+	jsonParse     goja.Callable
+	jsonStringify goja.Callable
+}
+
+// New initializes a JavaScript interpreted runtime environment and sets defaults
+// with the config struct.
+func New(config Config) (*Console, error) {
+	if config.Client == nil {
+		return nil, errors.New("console: no RPC client")
+	}
+	if config.Prompter == nil {
+		config.Prompter = prompt.Stdin
+	}
+	if config.Prompt == "" {
+		config.Prompt = DefaultPrompt
+	}
+	if config.Printer == nil {
+		config.Printer = os.Stdout
+	}
+
+	console := &Console{
+		client:   config.Client,
+		vm:       goja.New(),
+		docRoot:  config.DocRoot,
+		prompt:   config.Prompt,
+		prompter: config.Prompter,
+		printer:  config.Printer,
+	}
+	if config.DataDir != "" {
+		console.histPath = filepath.Join(config.DataDir, HistoryFile)
+	}
+	if err := console.init(config.Preload); err != nil {
+		return nil, err
+	}
+	return console, nil
+}
+
+func (c *Console) init(preload []string) error {
+	if err := c.initModules(); err != nil {
+		return err
+	}
+	json := c.vm.Get("JSON").ToObject(c.vm)
+	c.jsonParse, _ = goja.AssertFunction(json.Get("parse"))
+	c.jsonStringify, _ = goja.AssertFunction(json.Get("stringify"))
+
+	c.initConsoleObject()
+	if err := c.initWeb3(); err != nil {
+		return err
+	}
+	if err := c.vm.Set("loadScript", c.loadScript); err != nil {
+		return err
+	}
+
+	// Preload JavaScript files.
+	for _, path := range preload {
+		if err := c.Execute(path); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+
+	// Configure the input prompter for history and tab completion.
+	if c.histPath != "" {
+		if content, err := os.ReadFile(c.histPath); err != nil {
+			c.prompter.SetHistory(nil)
+		} else {
+			c.history = strings.Split(string(content), "\n")
+			c.prompter.SetHistory(c.history)
+		}
+	}
+	c.prompter.SetWordCompleter(c.AutoCompleteInput)
+	return nil
+}
+
+// initModules fetches the methods the node exposes. Nodes that cannot list their
+// methods still get an object per namespace, reachable through web3.send.
+func (c *Console) initModules() error {
+	methods, err := c.client.SupportedMethods()
+	if err == nil {
+		c.modules = methods
+		return nil
+	}
+	modules, err := c.client.SupportedModules()
+	if err != nil {
+		return fmt.Errorf("api modules: %v", err)
+	}
+	c.modules = make(map[string][]string, len(modules))
+	for name := range modules {
+		c.modules[name] = nil
+	}
+	return nil
+}
+
+// initConsoleObject installs console.log and console.error, which goja lacks.
+func (c *Console) initConsoleObject() {
+	console := c.vm.NewObject()
+	console.Set("log", c.consoleOutput)
+	console.Set("error", c.consoleOutput)
+	c.vm.Set("console", console)
+}
+
+// initWeb3 creates the web3 object with a child object per RPC namespace, and
+// binds the namespaces to the global scope too unless the name is taken.
+func (c *Console) initWeb3() error {
+	web3 := c.vm.NewObject()
+	if err := web3.Set("send", c.send); err != nil {
+		return err
+	}
+	for _, namespace := range c.Namespaces() {
+		obj := web3
+		if namespace != web3Namespace {
+			obj = c.vm.NewObject()
+			if err := web3.Set(namespace, obj); err != nil {
+				return err
+			}
+		}
+		for _, method := range c.modules[namespace] {
+			if err := obj.Set(method, c.method(namespace+"_"+method)); err != nil {
+				return err
+			}
+		}
+		if namespace != web3Namespace && c.vm.Get(namespace) == nil {
+			if err := c.vm.Set(namespace, obj); err != nil {
+				return err
+			}
+		}
+	}
+	return c.vm.Set("web3", web3)
+}
+
+// Namespaces returns the sorted RPC namespaces the console is bound to.
+func (c *Console) Namespaces() []string {
+	namespaces := make([]string, 0, len(c.modules))
+	for name := range c.modules {
+		namespaces = append(namespaces, name)
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// method returns a JavaScript function calling the given RPC method with its
+// arguments and returning the decoded result.
+func (c *Console) method(name string) func(call goja.FunctionCall) goja.Value {
+	return func(call goja.FunctionCall) goja.Value {
+		return c.call(name, call.Arguments)
+	}
+}
+
+// send is web3.send(method, params...), calling any RPC method by its full name.
+func (c *Console) send(call goja.FunctionCall) goja.Value {
+	if len(call.Arguments) == 0 {
+		panic(c.vm.NewTypeError("send: missing method name"))
+	}
+	return c.call(call.Argument(0).String(), call.Arguments[1:])
+}
+
+func (c *Console) call(method string, args []goja.Value) goja.Value {
+	params := make([]interface{}, len(args))
+	for i, arg := range args {
+		params[i] = arg.Export()
+	}
+	var result json.RawMessage
+	if err := c.client.Call(&result, method, params...); err != nil {
+		panic(c.vm.NewGoError(err))
+	}
+	if len(result) == 0 || string(result) == "null" {
+		return goja.Null()
+	}
+	value, err := c.jsonParse(goja.Undefined(), c.vm.ToValue(string(result)))
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+// loadScript executes a JS file in the console, relative to the document root.
+func (c *Console) loadScript(call goja.FunctionCall) goja.Value {
+	file := call.Argument(0)
+	if goja.IsUndefined(file) {
+		panic(c.vm.NewTypeError("loadScript: missing file name"))
+	}
+	if err := c.Execute(file.String()); err != nil {
+		panic(c.vm.NewGoError(err))
+	}
+	return c.vm.ToValue(true)
+}
+
+// consoleOutput is an override for the console.log and console.error methods to
+// stream the output into the configured output stream instead of stdout.
+func (c *Console) consoleOutput(call goja.FunctionCall) goja.Value {
+	var output []string
+	for _, argument := range call.Arguments {
+		output = append(output, fmt.Sprintf("%v", argument))
+	}
+	fmt.Fprintln(c.printer, strings.Join(output, " "))
+	return goja.Undefined()
+}
+
+// AutoCompleteInput is a pre-assembled word completer to be used by the user
+// input prompter to provide hints to the user about the methods available.
+func (c *Console) AutoCompleteInput(line string, pos int) (string, []string, string) {
+	// No completions can be provided for empty inputs
+	if len(line) == 0 || pos == 0 {
+		return "", nil, ""
+	}
+	// Chunk data to relevant part for autocompletion
+	// E.g. in case of nested lines eth.getBalance(eth.coinb<tab><tab>
+	start := pos - 1
+	for ; start > 0; start-- {
+		// Skip all methods and namespaces (i.e. including the dot)
+		if isIdentPart(line[start]) || line[start] == '.' {
+			continue
+		}
+		// We've hit an unexpected character, autocomplete form here
+		start++
+		break
+	}
+	return line[:start], c.completions(line[start:pos]), line[pos:]
+}
+
+// completions returns the properties of the object the dotted token refers to
+// that start with the token's last part.
+func (c *Console) completions(token string) []string {
+	parts := strings.Split(token, ".")
+	obj := c.vm.GlobalObject()
+	for _, part := range parts[:len(parts)-1] {
+		value := obj.Get(part)
+		if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+			return nil
+		}
+		obj = value.ToObject(c.vm)
+	}
+	var (
+		prefix  = strings.Join(parts[:len(parts)-1], ".")
+		partial = parts[len(parts)-1]
+		results []string
+	)
+	for _, key := range obj.Keys() {
+		if !strings.HasPrefix(key, partial) {
+			continue
+		}
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		results = append(results, key)
+	}
+	sort.Strings(results)
+	return results
+}
+
+func isIdentPart(ch byte) bool {
+	return ch == '_' || ch == '$' || ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z') || ('0' <= ch && ch <= '9')
+}
+
+// Welcome shows a summary of the current N42 instance and some metadata about
+// the console's available modules.
+func (c *Console) Welcome() {
+	message := "Welcome to the N42 JavaScript console!\n\n"
+
+	var version string
+	if err := c.client.Call(&version, "web3_clientVersion"); err == nil {
+		message += "instance: " + version + "\n"
+	}
+	var number hexutil.Uint64
+	if err := c.client.Call(&number, "eth_blockNumber"); err == nil {
+		message += fmt.Sprintf("at block: %d\n", uint64(number))
+	}
+	if len(c.modules) > 0 {
+		message += " modules: " + strings.Join(c.Namespaces(), " ") + "\n"
+	}
+	message += "\nTo exit, press ctrl-d or type exit"
+	fmt.Fprintln(c.printer, message)
+}
+
+// Evaluate executes code and pretty prints the result to the specified output
+// stream.
+func (c *Console) Evaluate(statement string) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(c.printer, "[native] error: %v\n", r)
+		}
+	}()
+	value, err := c.vm.RunString(statement)
+	if err != nil {
+		fmt.Fprintln(c.printer, formatError(err))
+		return
+	}
+	fmt.Fprintln(c.printer, c.format(value))
+}
+
+// Execute runs the JavaScript file specified as the argument, relative to the
+// document root unless it is an absolute path.
+func (c *Console) Execute(path string) error {
+	if !filepath.IsAbs(path) && c.docRoot != "" {
+		path = filepath.Join(c.docRoot, path)
+	}
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	_, err = c.vm.RunScript(path, string(code))
+	return err
+}
+
+// format renders a JavaScript value for display, as indented JSON where possible.
+func (c *Console) format(value goja.Value) string {
+	if value == nil || goja.IsUndefined(value) {
+		return "undefined"
+	}
+	if goja.IsNull(value) {
+		return "null"
+	}
+	if _, ok := goja.AssertFunction(value); ok {
+		return "function()"
+	}
+	out, err := c.jsonStringify(goja.Undefined(), value, goja.Null(), c.vm.ToValue("  "))
+	if err != nil || goja.IsUndefined(out) {
+		return value.String()
+	}
+	return out.String()
+}
+
+func formatError(err error) string {
+	var exc *goja.Exception
+	if errors.As(err, &exc) {
+		return exc.Error()
+	}
+	var interrupted *goja.InterruptedError
+	if errors.As(err, &interrupted) {
+		return "interrupted"
+	}
+	return err.Error()
+}
+
+// Interactive starts an interactive user session, where input is prompted from
+// the configured user prompter.
+func (c *Console) Interactive() {
+	// Interrupt a running statement on SIGINT instead of killing the console.
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, syscall.SIGINT)
+	defer signal.Stop(interrupt)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-interrupt:
+				c.vm.Interrupt("interrupted")
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var (
+		input  string // Current user input
+		indent int    // Current number of open brackets
+	)
+	for {
+		prompt := c.prompt
+		if indent > 0 {
+			prompt = strings.Repeat(".", indent*3) + " "
+		}
+		line, err := c.prompter.PromptInput(prompt)
+		if err != nil {
+			if errors.Is(err, liner.ErrPromptAborted) {
+				// Ctrl-C discards the current input.
+				input, indent = "", 0
+				continue
+			}
+			// Ctrl-D or a broken terminal ends the session.
+			fmt.Fprintln(c.printer)
+			return
+		}
+		if indent <= 0 && exit.MatchString(line) {
+			return
+		}
+		if onlyWhitespace.MatchString(line) && indent <= 0 {
+			continue
+		}
+		input += line + "\n"
+		indent = countIndents(input)
+		if indent > 0 {
+			continue
+		}
+		// Append the line to history, unless it is a duplicate or may hold a secret.
+		if command := strings.TrimSpace(input); len(c.history) == 0 || command != c.history[len(c.history)-1] {
+			if !passwordRegexp.MatchString(command) {
+				c.history = append(c.history, command)
+				c.prompter.AppendHistory(command)
+			}
+		}
+		c.vm.ClearInterrupt()
+		c.Evaluate(input)
+		input = ""
+	}
+}
+
+// countIndents returns the number of brackets left open in the input, skipping
+// brackets in string literals.
+func countIndents(input string) int {
+	var (
+		indents     = 0
+		inString    = false
+		strOpenChar = ' '   // keep track of the string open char to allow var str = "I'm ....";
+		charEscaped = false // keep track if the previous char was the '\' char, allow var str = "abc\"def";
+	)
+	for _, c := range input {
+		switch c {
+		case '\\':
+			// indicate next char as escaped when in string and previous char isn't escaping this backslash
+			if !charEscaped && inString {
+				charEscaped = true
+			}
+		case '\'', '"', '`':
+			if inString && !charEscaped && strOpenChar == c { // end string
+				inString = false
+			} else if !inString && !charEscaped { // begin string
+				inString = true
+				strOpenChar = c
+			}
+			charEscaped = false
+		case '{', '(', '[':
+			if !inString { // ignore brackets when in string, allow var str = "a{"; without indenting
+				indents++
+			}
+			charEscaped = false
+		case '}', ')', ']':
+			if !inString {
+				indents--
+			}
+			charEscaped = false
+		default:
+			charEscaped = false
+		}
+	}
+	return indents
+}
+
+// Stop cleans up the console and terminates the runtime environment, saving the
+// scrollback history.
+func (c *Console) Stop() error {
+	if c.histPath == "" {
+		return nil
+	}
+	if len(c.history) > 1000 {
+		c.history = c.history[len(c.history)-1000:]
+	}
+	if err := os.WriteFile(c.histPath, []byte(strings.Join(c.history, "\n")), 0600); err != nil {
+		return err
+	}
+	return os.Chmod(c.histPath, 0600) // Force 0600, even if it was different previously
+}
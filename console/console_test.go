@@ -0,0 +1,235 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/n42blockchain/N42/console/prompt"
+	"github.com/n42blockchain/N42/modules/rpc/jsonrpc"
+)
+
+// hookedPrompter implements UserPrompter to simulate use input via channels.
+type hookedPrompter struct {
+	scheduler chan string
+}
+
+func (p *hookedPrompter) PromptInput(prompt string) (string, error) {
+	input, ok := <-p.scheduler
+	if !ok {
+		return "", io.EOF
+	}
+	return input, nil
+}
+
+func (p *hookedPrompter) PromptPassword(prompt string) (string, error) {
+	return "", errors.New("not implemented")
+}
+
+func (p *hookedPrompter) PromptConfirm(prompt string) (bool, error) {
+	return false, errors.New("not implemented")
+}
+
+func (p *hookedPrompter) SetHistory(history []string)                     {}
+func (p *hookedPrompter) AppendHistory(command string)                    {}
+func (p *hookedPrompter) ClearHistory()                                   {}
+func (p *hookedPrompter) SetWordCompleter(completer prompt.WordCompleter) {}
+
+type testService struct{}
+
+type echoResult struct {
+	Name   string `json:"name"`
+	Number int    `json:"number"`
+}
+
+func (s *testService) BlockNumber() string { return "0x2a" }
+
+func (s *testService) Echo(name string, number int) echoResult {
+	return echoResult{Name: name, Number: number}
+}
+
+type web3Service struct{}
+
+func (s *web3Service) ClientVersion() string { return "N42/test" }
+
+// newTester creates a console attached to an in-process server exposing the
+// test services.
+func newTester(t *testing.T, preload ...string) (*Console, *bytes.Buffer, *hookedPrompter) {
+	server := jsonrpc.NewServer()
+	if err := server.RegisterName("eth", new(testService)); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterName("web3", new(web3Service)); err != nil {
+		t.Fatal(err)
+	}
+	client := jsonrpc.DialInProc(server)
+	t.Cleanup(func() {
+		client.Close()
+		server.Stop()
+	})
+
+	var (
+		printer  = new(bytes.Buffer)
+		prompter = &hookedPrompter{scheduler: make(chan string)}
+	)
+	console, err := New(Config{
+		DataDir:  t.TempDir(),
+		DocRoot:  t.TempDir(),
+		Client:   client,
+		Prompter: prompter,
+		Printer:  printer,
+		Preload:  preload,
+	})
+	if err != nil {
+		t.Fatalf("failed to create console: %v", err)
+	}
+	return console, printer, prompter
+}
+
+func TestWelcome(t *testing.T) {
+	console, printer, _ := newTester(t)
+	console.Welcome()
+
+	output := printer.String()
+	for _, want := range []string{"Welcome to the N42 JavaScript console!", "instance: N42/test", "at block: 42", "modules: eth rpc web3"} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("welcome message missing %q: %q", want, output)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	console, printer, _ := newTester(t)
+
+	console.Evaluate("eth.blockNumber()")
+	if output := printer.String(); !strings.Contains(output, `"0x2a"`) {
+		t.Fatalf("global namespace call: %q", output)
+	}
+	printer.Reset()
+	console.Evaluate("web3.eth.echo('n42', 7).number + 1")
+	if output := printer.String(); output != "8\n" {
+		t.Fatalf("web3 namespace call: %q", output)
+	}
+	printer.Reset()
+	console.Evaluate("web3.clientVersion()")
+	if output := printer.String(); !strings.Contains(output, "N42/test") {
+		t.Fatalf("web3 method call: %q", output)
+	}
+	printer.Reset()
+	console.Evaluate("web3.send('eth_echo', 'raw', 1).name")
+	if output := printer.String(); !strings.Contains(output, `"raw"`) {
+		t.Fatalf("raw send: %q", output)
+	}
+	printer.Reset()
+	console.Evaluate("eth.echo()")
+	if output := printer.String(); !strings.Contains(output, "missing value") {
+		t.Fatalf("rpc error not reported: %q", output)
+	}
+}
+
+func TestInteractive(t *testing.T) {
+	console, printer, prompter := newTester(t)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		console.Interactive()
+	}()
+	// A statement spread over lines is only evaluated once complete.
+	prompter.scheduler <- "var x = eth.echo("
+	prompter.scheduler <- "'multi', 2)"
+	prompter.scheduler <- "x.name"
+	prompter.scheduler <- "exit"
+	<-done
+
+	if output := printer.String(); !strings.Contains(output, `"multi"`) {
+		t.Fatalf("multi-line statement: %q", output)
+	}
+	if err := console.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	history, err := os.ReadFile(console.histPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "var x = eth.echo(\n'multi', 2)\nx.name"; string(history) != want {
+		t.Fatalf("history mismatch: have %q, want %q", history, want)
+	}
+}
+
+func TestPreloadAndLoadScript(t *testing.T) {
+	dir := t.TempDir()
+	preload := filepath.Join(dir, "preload.js")
+	if err := os.WriteFile(preload, []byte("var preloaded = eth.blockNumber();"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	console, printer, _ := newTester(t, preload)
+
+	console.Evaluate("preloaded")
+	if output := printer.String(); !strings.Contains(output, `"0x2a"`) {
+		t.Fatalf("preloaded script not executed: %q", output)
+	}
+	if err := os.WriteFile(filepath.Join(console.docRoot, "script.js"), []byte("var loaded = 1;"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	printer.Reset()
+	console.Evaluate("loadScript('script.js') && loaded")
+	if output := printer.String(); output != "1\n" {
+		t.Fatalf("loadScript relative to the document root: %q", output)
+	}
+}
+
+func TestAutoComplete(t *testing.T) {
+	console, _, _ := newTester(t)
+
+	head, completions, tail := console.AutoCompleteInput("x = eth.ec)", 10)
+	if head != "x = " || tail != ")" {
+		t.Fatalf("unexpected split: %q %q", head, tail)
+	}
+	if len(completions) != 1 || completions[0] != "eth.echo" {
+		t.Fatalf("unexpected completions: %v", completions)
+	}
+	if _, completions, _ = console.AutoCompleteInput("web3.e", 6); len(completions) != 1 || completions[0] != "web3.eth" {
+		t.Fatalf("unexpected web3 completions: %v", completions)
+	}
+}
+
+func TestCountIndents(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int
+	}{
+		{"var a = {", 1},
+		{"var a = {}", 0},
+		{"f(g([", 3},
+		{`var s = "a{(";`, 0},
+		{`var s = "a\"{";`, 0},
+		{"var s = 'it\\'s'; [", 1},
+		{"}", -1},
+	}
+	for _, tt := range tests {
+		if have := countIndents(tt.input); have != tt.want {
+			t.Errorf("countIndents(%q) = %d, want %d", tt.input, have, tt.want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ module github.com/n42blockchain/N42
 go 1.22
 
 require (
+	github.com/BurntSushi/toml v1.4.0
 	github.com/RoaringBitmap/roaring v1.2.3
 	github.com/VictoriaMetrics/metrics v1.23.1
 	github.com/btcsuite/btcd/btcec/v2 v2.3.3
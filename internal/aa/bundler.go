@@ -0,0 +1,157 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package aa
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/log"
+)
+
+// Backend is the chain access the bundler needs to simulate and submit bundles.
+type Backend interface {
+	// BaseFee returns the base fee of the next block.
+	BaseFee() *uint256.Int
+	// EstimateGas estimates a call, returning the revert data if it reverts.
+	EstimateGas(ctx context.Context, from, to types.Address, data []byte) (uint64, []byte, error)
+	// SendTransaction signs a dynamic fee transaction with a local account and submits it.
+	SendTransaction(ctx context.Context, from, to types.Address, data []byte, gas uint64, feeCap, tip *uint256.Int) (types.Hash, error)
+}
+
+// Bundler periodically packs the pooled user operations into handleOps
+// transactions signed by a local account.
+type Bundler struct {
+	pool        *Pool
+	backend     Backend
+	account     types.Address
+	beneficiary types.Address
+	interval    time.Duration
+	maxBundle   int
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewBundler creates a bundler submitting the operations of pool from the
+// configured account.
+func NewBundler(ctx context.Context, pool *Pool, backend Backend) (*Bundler, error) {
+	config := pool.Config()
+	var account, beneficiary types.Address
+	if !account.DecodeString(config.Account) {
+		return nil, fmt.Errorf("invalid bundler account: %q", config.Account)
+	}
+	beneficiary = account
+	if config.Beneficiary != "" && !beneficiary.DecodeString(config.Beneficiary) {
+		return nil, fmt.Errorf("invalid bundler beneficiary: %q", config.Beneficiary)
+	}
+	interval := config.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	c, cancel := context.WithCancel(ctx)
+	return &Bundler{
+		pool:        pool,
+		backend:     backend,
+		account:     account,
+		beneficiary: beneficiary,
+		interval:    interval,
+		maxBundle:   config.MaxBundleSize,
+		ctx:         c,
+		cancel:      cancel,
+	}, nil
+}
+
+// Start begins bundling.
+func (b *Bundler) Start() error {
+	go b.loop()
+	return nil
+}
+
+// Stop terminates the bundler.
+func (b *Bundler) Stop() error {
+	b.cancel()
+	return nil
+}
+
+func (b *Bundler) loop() {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := b.SendBundle(b.ctx); err != nil {
+				log.Warn("Failed to send user operation bundle", "err", err)
+			}
+		case <-b.ctx.Done():
+			return
+		}
+	}
+}
+
+// SendBundle packs the best pending operations into a handleOps transaction and
+// submits it. Operations the entry point rejects are dropped from the pool and
+// the bundle is rebuilt without them. An empty hash is returned if there was
+// nothing to bundle.
+func (b *Bundler) SendBundle(ctx context.Context) (types.Hash, error) {
+	baseFee := b.backend.BaseFee()
+	ops := b.pool.Pending(baseFee, b.maxBundle)
+
+	for len(ops) > 0 {
+		data, err := PackHandleOps(ops, b.beneficiary)
+		if err != nil {
+			return types.Hash{}, err
+		}
+		gas, revert, err := b.backend.EstimateGas(ctx, b.account, b.pool.EntryPoint(), data)
+		if err != nil {
+			failed := UnpackFailedOp(revert)
+			if failed == nil || failed.OpIndex >= len(ops) {
+				return types.Hash{}, err
+			}
+			hash := b.pool.Hash(ops[failed.OpIndex])
+			log.Debug("Dropping user operation rejected by entry point", "hash", hash, "reason", failed.Reason)
+			b.pool.Remove(hash)
+			ops = append(ops[:failed.OpIndex], ops[failed.OpIndex+1:]...)
+			continue
+		}
+		// The bundle pays the lowest fees any of its operations pays, so that
+		// every operation compensates the beneficiary for its gas.
+		feeCap, tip := new(uint256.Int).Set(ops[0].MaxFeePerGas), new(uint256.Int).Set(ops[0].MaxPriorityFeePerGas)
+		hashes := make([]types.Hash, len(ops))
+		for i, op := range ops {
+			if op.MaxFeePerGas.Lt(feeCap) {
+				feeCap.Set(op.MaxFeePerGas)
+			}
+			if op.MaxPriorityFeePerGas.Lt(tip) {
+				tip.Set(op.MaxPriorityFeePerGas)
+			}
+			hashes[i] = b.pool.Hash(op)
+		}
+		tx, err := b.backend.SendTransaction(ctx, b.account, b.pool.EntryPoint(), data, gas, feeCap, tip)
+		if err != nil {
+			return types.Hash{}, err
+		}
+		b.pool.MarkBundled(tx, hashes)
+		log.Info("Sent user operation bundle", "tx", tx, "ops", len(ops), "gas", gas)
+		return tx, nil
+	}
+	return types.Hash{}, nil
+}
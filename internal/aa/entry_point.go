@@ -0,0 +1,285 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package aa
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/n42blockchain/N42/accounts/abi"
+	"github.com/n42blockchain/N42/common/block"
+	"github.com/n42blockchain/N42/common/types"
+)
+
+// entryPointABIJSON is the subset of the ERC-4337 v0.6 EntryPoint interface used by
+// the bundler.
+const entryPointABIJSON = `[
+{"type":"function","name":"handleOps","stateMutability":"nonpayable","outputs":[],"inputs":[
+ {"name":"ops","type":"tuple[]","components":[
+  {"name":"sender","type":"address"},{"name":"nonce","type":"uint256"},{"name":"initCode","type":"bytes"},
+  {"name":"callData","type":"bytes"},{"name":"callGasLimit","type":"uint256"},{"name":"verificationGasLimit","type":"uint256"},
+  {"name":"preVerificationGas","type":"uint256"},{"name":"maxFeePerGas","type":"uint256"},{"name":"maxPriorityFeePerGas","type":"uint256"},
+  {"name":"paymasterAndData","type":"bytes"},{"name":"signature","type":"bytes"}]},
+ {"name":"beneficiary","type":"address"}]},
+{"type":"function","name":"simulateValidation","stateMutability":"nonpayable","outputs":[],"inputs":[
+ {"name":"userOp","type":"tuple","components":[
+  {"name":"sender","type":"address"},{"name":"nonce","type":"uint256"},{"name":"initCode","type":"bytes"},
+  {"name":"callData","type":"bytes"},{"name":"callGasLimit","type":"uint256"},{"name":"verificationGasLimit","type":"uint256"},
+  {"name":"preVerificationGas","type":"uint256"},{"name":"maxFeePerGas","type":"uint256"},{"name":"maxPriorityFeePerGas","type":"uint256"},
+  {"name":"paymasterAndData","type":"bytes"},{"name":"signature","type":"bytes"}]}]},
+{"type":"error","name":"FailedOp","inputs":[{"name":"opIndex","type":"uint256"},{"name":"reason","type":"string"}]},
+{"type":"error","name":"ValidationResult","inputs":[
+ {"name":"returnInfo","type":"tuple","components":[
+  {"name":"preOpGas","type":"uint256"},{"name":"prefund","type":"uint256"},{"name":"sigFailed","type":"bool"},
+  {"name":"validAfter","type":"uint48"},{"name":"validUntil","type":"uint48"},{"name":"paymasterContext","type":"bytes"}]},
+ {"name":"senderInfo","type":"tuple","components":[{"name":"stake","type":"uint256"},{"name":"unstakeDelaySec","type":"uint256"}]},
+ {"name":"factoryInfo","type":"tuple","components":[{"name":"stake","type":"uint256"},{"name":"unstakeDelaySec","type":"uint256"}]},
+ {"name":"paymasterInfo","type":"tuple","components":[{"name":"stake","type":"uint256"},{"name":"unstakeDelaySec","type":"uint256"}]}]},
+{"type":"event","name":"UserOperationEvent","anonymous":false,"inputs":[
+ {"name":"userOpHash","type":"bytes32","indexed":true},{"name":"sender","type":"address","indexed":true},
+ {"name":"paymaster","type":"address","indexed":true},{"name":"nonce","type":"uint256","indexed":false},
+ {"name":"success","type":"bool","indexed":false},{"name":"actualGasCost","type":"uint256","indexed":false},
+ {"name":"actualGasUsed","type":"uint256","indexed":false}]},
+{"type":"event","name":"UserOperationRevertReason","anonymous":false,"inputs":[
+ {"name":"userOpHash","type":"bytes32","indexed":true},{"name":"sender","type":"address","indexed":true},
+ {"name":"nonce","type":"uint256","indexed":false},{"name":"revertReason","type":"bytes","indexed":false}]}
+]`
+
+var (
+	entryPointABI abi.ABI
+
+	// userOpHashArgs and userOpHashWrapArgs are the two encoding rounds of the
+	// EntryPoint's getUserOpHash.
+	userOpHashArgs     abi.Arguments
+	userOpHashWrapArgs abi.Arguments
+
+	// userOpArgs encodes a single operation the way handleOps receives it.
+	userOpArgs abi.Arguments
+
+	// UserOperationEventID is the topic of the entry point's UserOperationEvent.
+	UserOperationEventID types.Hash
+	// UserOperationRevertReasonID is the topic of the entry point's UserOperationRevertReason.
+	UserOperationRevertReasonID types.Hash
+)
+
+func init() {
+	var err error
+	if entryPointABI, err = abi.JSON(strings.NewReader(entryPointABIJSON)); err != nil {
+		panic(fmt.Sprintf("invalid entry point abi: %v", err))
+	}
+	newType := func(t string) abi.Type {
+		typ, err := abi.NewType(t, "", nil)
+		if err != nil {
+			panic(err)
+		}
+		return typ
+	}
+	var (
+		address = abi.Argument{Type: newType("address")}
+		uint256 = abi.Argument{Type: newType("uint256")}
+		bytes32 = abi.Argument{Type: newType("bytes32")}
+	)
+	userOpHashArgs = abi.Arguments{address, uint256, bytes32, bytes32, uint256, uint256, uint256, uint256, uint256, bytes32}
+	userOpHashWrapArgs = abi.Arguments{bytes32, address, uint256}
+	userOpArgs = abi.Arguments{entryPointABI.Methods["simulateValidation"].Inputs[0]}
+
+	UserOperationEventID = entryPointABI.Events["UserOperationEvent"].ID
+	UserOperationRevertReasonID = entryPointABI.Events["UserOperationRevertReason"].ID
+}
+
+// abiUserOp mirrors the UserOperation struct of the entry point for abi encoding.
+type abiUserOp struct {
+	Sender               types.Address
+	Nonce                *big.Int
+	InitCode             []byte
+	CallData             []byte
+	CallGasLimit         *big.Int
+	VerificationGasLimit *big.Int
+	PreVerificationGas   *big.Int
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+	PaymasterAndData     []byte
+	Signature            []byte
+}
+
+func toABIUserOp(op *UserOperation) abiUserOp {
+	return abiUserOp{
+		Sender:               op.Sender,
+		Nonce:                op.Nonce.ToBig(),
+		InitCode:             op.InitCode,
+		CallData:             op.CallData,
+		CallGasLimit:         op.CallGasLimit.ToBig(),
+		VerificationGasLimit: op.VerificationGasLimit.ToBig(),
+		PreVerificationGas:   op.PreVerificationGas.ToBig(),
+		MaxFeePerGas:         op.MaxFeePerGas.ToBig(),
+		MaxPriorityFeePerGas: op.MaxPriorityFeePerGas.ToBig(),
+		PaymasterAndData:     op.PaymasterAndData,
+		Signature:            op.Signature,
+	}
+}
+
+// packUserOp returns the abi encoding of a single operation.
+func packUserOp(op *UserOperation) ([]byte, error) {
+	return userOpArgs.Pack(toABIUserOp(op))
+}
+
+// PackHandleOps returns the calldata of an entry point handleOps call.
+func PackHandleOps(ops []*UserOperation, beneficiary types.Address) ([]byte, error) {
+	packed := make([]abiUserOp, len(ops))
+	for i, op := range ops {
+		packed[i] = toABIUserOp(op)
+	}
+	return entryPointABI.Pack("handleOps", packed, beneficiary)
+}
+
+// PackSimulateValidation returns the calldata of an entry point simulateValidation call.
+func PackSimulateValidation(op *UserOperation) ([]byte, error) {
+	return entryPointABI.Pack("simulateValidation", toABIUserOp(op))
+}
+
+// StakeInfo is the stake an entity holds in the entry point.
+type StakeInfo struct {
+	Stake           *big.Int
+	UnstakeDelaySec *big.Int
+}
+
+// abiReturnInfo mirrors the ReturnInfo struct of the entry point for abi decoding.
+type abiReturnInfo struct {
+	PreOpGas         *big.Int
+	Prefund          *big.Int
+	SigFailed        bool
+	ValidAfter       *big.Int
+	ValidUntil       *big.Int
+	PaymasterContext []byte
+}
+
+// ValidationResult is the outcome of a successful simulateValidation.
+type ValidationResult struct {
+	PreOpGas         *big.Int
+	Prefund          *big.Int
+	SigFailed        bool
+	ValidAfter       uint64
+	ValidUntil       uint64
+	PaymasterContext []byte
+
+	Sender    StakeInfo
+	Factory   StakeInfo
+	Paymaster StakeInfo
+}
+
+// FailedOpError is returned when the entry point rejects an operation.
+type FailedOpError struct {
+	OpIndex int
+	Reason  string
+}
+
+func (e *FailedOpError) Error() string {
+	return fmt.Sprintf("entry point rejected operation %d: %s", e.OpIndex, e.Reason)
+}
+
+// ErrUnexpectedRevert is returned if the entry point reverted with unknown data.
+var ErrUnexpectedRevert = errors.New("unexpected entry point revert")
+
+// UnpackFailedOp decodes a FailedOp revert, returning nil if data is a different revert.
+func UnpackFailedOp(data []byte) *FailedOpError {
+	failedOp := entryPointABI.Errors["FailedOp"]
+	if len(data) < 4 || !bytes.Equal(data[:4], failedOp.ID[:4]) {
+		return nil
+	}
+	values, err := failedOp.Inputs.Unpack(data[4:])
+	if err != nil || len(values) != 2 {
+		return nil
+	}
+	index, _ := values[0].(*big.Int)
+	reason, _ := values[1].(string)
+	if index == nil {
+		return nil
+	}
+	return &FailedOpError{OpIndex: int(index.Int64()), Reason: reason}
+}
+
+// UnpackValidationResult decodes the revert data of simulateValidation, which
+// always reverts: with ValidationResult on success or FailedOp on failure.
+func UnpackValidationResult(data []byte) (*ValidationResult, error) {
+	if failed := UnpackFailedOp(data); failed != nil {
+		return nil, failed
+	}
+	validation := entryPointABI.Errors["ValidationResult"]
+	if len(data) < 4 || !bytes.Equal(data[:4], validation.ID[:4]) {
+		return nil, ErrUnexpectedRevert
+	}
+	values, err := validation.Inputs.Unpack(data[4:])
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 4 {
+		return nil, ErrUnexpectedRevert
+	}
+	returnInfo := abi.ConvertType(values[0], new(abiReturnInfo)).(*abiReturnInfo)
+	res := &ValidationResult{
+		PreOpGas:         returnInfo.PreOpGas,
+		Prefund:          returnInfo.Prefund,
+		SigFailed:        returnInfo.SigFailed,
+		ValidAfter:       returnInfo.ValidAfter.Uint64(),
+		ValidUntil:       returnInfo.ValidUntil.Uint64(),
+		PaymasterContext: returnInfo.PaymasterContext,
+	}
+	for i, info := range []*StakeInfo{&res.Sender, &res.Factory, &res.Paymaster} {
+		*info = *abi.ConvertType(values[i+1], new(StakeInfo)).(*StakeInfo)
+	}
+	return res, nil
+}
+
+// UserOperationEvent is the entry point log emitted for every executed operation.
+type UserOperationEvent struct {
+	UserOpHash    types.Hash
+	Sender        types.Address
+	Paymaster     types.Address
+	Nonce         *big.Int
+	Success       bool
+	ActualGasCost *big.Int
+	ActualGasUsed *big.Int
+	Log           *block.Log
+}
+
+// UnpackUserOperationEvent decodes a UserOperationEvent log.
+func UnpackUserOperationEvent(log *block.Log) (*UserOperationEvent, error) {
+	if len(log.Topics) != 4 || log.Topics[0] != UserOperationEventID {
+		return nil, errors.New("not a UserOperationEvent log")
+	}
+	values, err := entryPointABI.Events["UserOperationEvent"].Inputs.NonIndexed().Unpack(log.Data)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 4 {
+		return nil, errors.New("malformed UserOperationEvent log")
+	}
+	ev := &UserOperationEvent{
+		UserOpHash: log.Topics[1],
+		Sender:     types.BytesToAddress(log.Topics[2][:]),
+		Paymaster:  types.BytesToAddress(log.Topics[3][:]),
+		Log:        log,
+	}
+	ev.Nonce, _ = values[0].(*big.Int)
+	ev.Success, _ = values[1].(bool)
+	ev.ActualGasCost, _ = values[2].(*big.Int)
+	ev.ActualGasUsed, _ = values[3].(*big.Int)
+	return ev, nil
+}
@@ -0,0 +1,350 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package aa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/holiman/uint256"
+	"github.com/n42blockchain/N42/common"
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/conf"
+	"github.com/n42blockchain/N42/log"
+	event "github.com/n42blockchain/N42/modules/event/v2"
+)
+
+// minCallGasLimit is the lowest callGasLimit accepted, the cost of a value transfer call.
+const minCallGasLimit = 9100
+
+// receiptCacheSize is the number of executed operations remembered for receipt lookups.
+// Receipts are only kept in memory, operations executed before the last restart
+// or evicted from the cache have none.
+const receiptCacheSize = 16384
+
+// bundleTimeout is the time after which operations of an unmined bundle are released.
+const bundleTimeout = 2 * time.Minute
+
+var (
+	ErrAlreadyKnown             = errors.New("user operation already known")
+	ErrUnsupportedEntryPoint    = errors.New("unsupported entry point")
+	ErrReplaceUnderpriced       = errors.New("replacement user operation underpriced")
+	ErrSenderLimit              = errors.New("too many user operations from sender")
+	ErrPoolFull                 = errors.New("user operation pool is full")
+	ErrTipAboveFeeCap           = errors.New("maxPriorityFeePerGas higher than maxFeePerGas")
+	ErrGasOverflow              = errors.New("user operation gas exceeds 64 bits")
+	ErrCallGasTooLow            = errors.New("callGasLimit too low")
+	ErrVerificationGasTooHigh   = errors.New("verificationGasLimit too high")
+	ErrPreVerificationGasTooLow = errors.New("preVerificationGas too low")
+)
+
+// poolEntry is a pooled user operation.
+type poolEntry struct {
+	op     *UserOperation
+	hash   types.Hash
+	bundle types.Hash // hash of the handleOps transaction including the operation, if any
+	sent   time.Time
+}
+
+// Pool is the alt-mempool holding the ERC-4337 user operations of a single
+// entry point until they are bundled and executed.
+type Pool struct {
+	mu sync.RWMutex
+
+	entryPoint types.Address
+	chainID    *big.Int
+	config     conf.BundlerConfig
+
+	all      map[types.Hash]*poolEntry
+	bySender map[types.Address]map[uint256.Int]*poolEntry
+	receipts *lru.Cache[types.Hash, *UserOperationEvent]
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewPool creates a user operation pool for the configured entry point.
+func NewPool(ctx context.Context, chainID *big.Int, config conf.BundlerConfig) (*Pool, error) {
+	var entryPoint types.Address
+	if !entryPoint.DecodeString(config.EntryPoint) {
+		return nil, fmt.Errorf("invalid entry point address: %q", config.EntryPoint)
+	}
+	if config.MaxOpsPerSender < 1 {
+		config.MaxOpsPerSender = conf.DefaultBundlerConfig.MaxOpsPerSender
+	}
+	if config.MaxPoolSize < 1 {
+		config.MaxPoolSize = conf.DefaultBundlerConfig.MaxPoolSize
+	}
+	if config.MaxVerificationGas == 0 {
+		config.MaxVerificationGas = conf.DefaultBundlerConfig.MaxVerificationGas
+	}
+	receipts, _ := lru.New[types.Hash, *UserOperationEvent](receiptCacheSize)
+
+	c, cancel := context.WithCancel(ctx)
+	return &Pool{
+		entryPoint: entryPoint,
+		chainID:    chainID,
+		config:     config,
+		all:        make(map[types.Hash]*poolEntry),
+		bySender:   make(map[types.Address]map[uint256.Int]*poolEntry),
+		receipts:   receipts,
+		ctx:        c,
+		cancel:     cancel,
+	}, nil
+}
+
+// Start begins tracking the executed operations.
+func (p *Pool) Start() error {
+	go p.loop()
+	return nil
+}
+
+// Stop terminates the pool.
+func (p *Pool) Stop() error {
+	p.cancel()
+	return nil
+}
+
+// EntryPoint returns the address of the entry point served by the pool.
+func (p *Pool) EntryPoint() types.Address { return p.entryPoint }
+
+// Config returns the bundler configuration of the pool.
+func (p *Pool) Config() conf.BundlerConfig { return p.config }
+
+// Hash returns the user operation hash of op.
+func (p *Pool) Hash(op *UserOperation) types.Hash {
+	return op.Hash(p.entryPoint, p.chainID)
+}
+
+// ValidateStatic checks the fields of an operation that can be verified
+// without executing it.
+func (p *Pool) ValidateStatic(op *UserOperation) error {
+	if op.MaxPriorityFeePerGas.Gt(op.MaxFeePerGas) {
+		return ErrTipAboveFeeCap
+	}
+	if _, err := op.TotalGas(); err != nil {
+		return err
+	}
+	if op.CallGasLimit.LtUint64(minCallGasLimit) {
+		return fmt.Errorf("%w: have %v, want at least %d", ErrCallGasTooLow, op.CallGasLimit, minCallGasLimit)
+	}
+	if op.VerificationGasLimit.GtUint64(p.config.MaxVerificationGas) {
+		return fmt.Errorf("%w: have %v, max %d", ErrVerificationGasTooHigh, op.VerificationGasLimit, p.config.MaxVerificationGas)
+	}
+	if min := CalcPreVerificationGas(op); op.PreVerificationGas.LtUint64(min) {
+		return fmt.Errorf("%w: have %v, want at least %d", ErrPreVerificationGasTooLow, op.PreVerificationGas, min)
+	}
+	return nil
+}
+
+// Add inserts a validated operation into the pool, replacing an operation with
+// the same sender and nonce if its fees are sufficiently bumped.
+func (p *Pool) Add(op *UserOperation) (types.Hash, error) {
+	if err := p.ValidateStatic(op); err != nil {
+		return types.Hash{}, err
+	}
+	hash := p.Hash(op)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.all[hash]; ok {
+		return types.Hash{}, ErrAlreadyKnown
+	}
+	ops := p.bySender[op.Sender]
+	if old, ok := ops[*op.Nonce]; ok {
+		if !bumped(old.op.MaxFeePerGas, op.MaxFeePerGas, p.config.PriceBump) ||
+			!bumped(old.op.MaxPriorityFeePerGas, op.MaxPriorityFeePerGas, p.config.PriceBump) {
+			return types.Hash{}, ErrReplaceUnderpriced
+		}
+		p.remove(old)
+	} else {
+		if len(ops) >= p.config.MaxOpsPerSender {
+			return types.Hash{}, ErrSenderLimit
+		}
+		if len(p.all) >= p.config.MaxPoolSize {
+			return types.Hash{}, ErrPoolFull
+		}
+	}
+	entry := &poolEntry{op: op.Copy(), hash: hash}
+	if p.bySender[op.Sender] == nil {
+		p.bySender[op.Sender] = make(map[uint256.Int]*poolEntry)
+	}
+	p.bySender[op.Sender][*op.Nonce] = entry
+	p.all[hash] = entry
+
+	log.Debug("Pooled user operation", "hash", hash, "sender", op.Sender, "nonce", op.Nonce)
+	return hash, nil
+}
+
+// bumped reports whether next exceeds old by at least bump percent.
+func bumped(old, next *uint256.Int, bump uint64) bool {
+	threshold := new(uint256.Int).Mul(old, uint256.NewInt(100+bump))
+	threshold.Div(threshold, uint256.NewInt(100))
+	return !next.Lt(threshold)
+}
+
+// Get returns a pooled operation by its hash.
+func (p *Pool) Get(hash types.Hash) *UserOperation {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if entry, ok := p.all[hash]; ok {
+		return entry.op.Copy()
+	}
+	return nil
+}
+
+// Remove drops the given operations from the pool.
+func (p *Pool) Remove(hashes ...types.Hash) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, hash := range hashes {
+		if entry, ok := p.all[hash]; ok {
+			p.remove(entry)
+		}
+	}
+}
+
+// remove drops an entry from all indexes.
+//
+// Note, this method assumes the pool lock is held!
+func (p *Pool) remove(entry *poolEntry) {
+	delete(p.all, entry.hash)
+	if ops := p.bySender[entry.op.Sender]; ops != nil {
+		delete(ops, *entry.op.Nonce)
+		if len(ops) == 0 {
+			delete(p.bySender, entry.op.Sender)
+		}
+	}
+}
+
+// Pending returns up to limit operations ready for bundling, at most one per
+// sender, ordered by the priority fee they pay at the given base fee. Operations
+// of an in-flight bundle and those not covering the base fee are skipped.
+func (p *Pool) Pending(baseFee *uint256.Int, limit int) []*UserOperation {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var ops []*UserOperation
+	for _, byNonce := range p.bySender {
+		var lowest *poolEntry
+		for _, entry := range byNonce {
+			if lowest == nil || entry.op.Nonce.Lt(lowest.op.Nonce) {
+				lowest = entry
+			}
+		}
+		if lowest.bundle != (types.Hash{}) {
+			continue
+		}
+		if baseFee != nil && lowest.op.MaxFeePerGas.Lt(baseFee) {
+			continue
+		}
+		ops = append(ops, lowest.op.Copy())
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		return ops[i].EffectiveGasPrice(baseFee).Gt(ops[j].EffectiveGasPrice(baseFee))
+	})
+	if limit > 0 && len(ops) > limit {
+		ops = ops[:limit]
+	}
+	return ops
+}
+
+// MarkBundled records that the operations were sent in the given handleOps
+// transaction, excluding them from further bundles until it's mined or timed out.
+func (p *Pool) MarkBundled(tx types.Hash, hashes []types.Hash) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	for _, hash := range hashes {
+		if entry, ok := p.all[hash]; ok {
+			entry.bundle, entry.sent = tx, now
+		}
+	}
+}
+
+// Receipt returns the execution event of an operation, if it was seen on chain
+// since the node started and is still among the last receiptCacheSize ones.
+func (p *Pool) Receipt(hash types.Hash) *UserOperationEvent {
+	ev, _ := p.receipts.Get(hash)
+	return ev
+}
+
+// Stats returns the number of pooled operations and of those awaiting inclusion in a bundle.
+func (p *Pool) Stats() (int, int) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	var bundled int
+	for _, entry := range p.all {
+		if entry.bundle != (types.Hash{}) {
+			bundled++
+		}
+	}
+	return len(p.all), bundled
+}
+
+// loop indexes the executed operations from the new chain logs and drops them,
+// releasing the operations of bundles that failed to get mined.
+func (p *Pool) loop() {
+	logsCh := make(chan common.NewLogsEvent, 16)
+	logsSub := event.GlobalEvent.Subscribe(logsCh)
+	defer logsSub.Unsubscribe()
+
+	expire := time.NewTicker(bundleTimeout / 4)
+	defer expire.Stop()
+
+	for {
+		select {
+		case ev := <-logsCh:
+			var executed []types.Hash
+			for _, l := range ev.Logs {
+				if l.Address != p.entryPoint || len(l.Topics) == 0 || l.Topics[0] != UserOperationEventID {
+					continue
+				}
+				opEvent, err := UnpackUserOperationEvent(l)
+				if err != nil {
+					log.Warn("Failed to decode UserOperationEvent", "tx", l.TxHash, "err", err)
+					continue
+				}
+				p.receipts.Add(opEvent.UserOpHash, opEvent)
+				executed = append(executed, opEvent.UserOpHash)
+			}
+			if len(executed) > 0 {
+				p.Remove(executed...)
+			}
+		case <-expire.C:
+			p.mu.Lock()
+			for _, entry := range p.all {
+				if entry.bundle != (types.Hash{}) && time.Since(entry.sent) > bundleTimeout {
+					log.Debug("Releasing user operation of unmined bundle", "hash", entry.hash, "bundle", entry.bundle)
+					entry.bundle = types.Hash{}
+				}
+			}
+			p.mu.Unlock()
+		case <-logsSub.Err():
+			return
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package aa
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/conf"
+)
+
+func newTestPool(t *testing.T, perSender, size int) *Pool {
+	t.Helper()
+	pool, err := NewPool(context.Background(), big.NewInt(1), conf.BundlerConfig{
+		EntryPoint:         "0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789",
+		MaxOpsPerSender:    perSender,
+		MaxPoolSize:        size,
+		MaxVerificationGas: 500_000,
+		PriceBump:          10,
+	})
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	return pool
+}
+
+func TestPoolValidateStatic(t *testing.T) {
+	pool := newTestPool(t, 4, 16)
+	tests := []struct {
+		name   string
+		modify func(op *UserOperation)
+		err    error
+	}{
+		{"valid", func(op *UserOperation) {}, nil},
+		{"tip above fee cap", func(op *UserOperation) { op.MaxPriorityFeePerGas.SetUint64(101) }, ErrTipAboveFeeCap},
+		{"tip equal to fee cap", func(op *UserOperation) { op.MaxPriorityFeePerGas.SetUint64(100) }, nil},
+		{"call gas too low", func(op *UserOperation) { op.CallGasLimit.SetUint64(minCallGasLimit - 1) }, ErrCallGasTooLow},
+		{"verification gas too high", func(op *UserOperation) { op.VerificationGasLimit.SetUint64(500_001) }, ErrVerificationGasTooHigh},
+		{"verification gas at limit", func(op *UserOperation) { op.VerificationGasLimit.SetUint64(500_000) }, nil},
+		{"pre-verification gas too low", func(op *UserOperation) { op.PreVerificationGas.SubUint64(op.PreVerificationGas, 1) }, ErrPreVerificationGasTooLow},
+		{"call gas overflow", func(op *UserOperation) { op.CallGasLimit.Lsh(uint256.NewInt(1), 64) }, ErrGasOverflow},
+		{"total gas overflow", func(op *UserOperation) { op.CallGasLimit.SetUint64(1 << 63); op.PreVerificationGas.SetUint64(1 << 63) }, ErrGasOverflow},
+	}
+	for i, tt := range tests {
+		op := testOp(byte(i+1), 0, 100, 10)
+		tt.modify(op)
+		if err := pool.ValidateStatic(op); !errors.Is(err, tt.err) {
+			t.Errorf("%s: error mismatch: have %v, want %v", tt.name, err, tt.err)
+		}
+		if _, err := pool.Add(op); !errors.Is(err, tt.err) {
+			t.Errorf("%s: add error mismatch: have %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestPoolReplacement(t *testing.T) {
+	pool := newTestPool(t, 4, 16)
+	hash, err := pool.Add(testOp(1, 0, 100, 10))
+	if err != nil {
+		t.Fatalf("failed to add operation: %v", err)
+	}
+	if _, err := pool.Add(testOp(1, 0, 100, 10)); err != ErrAlreadyKnown {
+		t.Fatalf("duplicate error mismatch: have %v, want %v", err, ErrAlreadyKnown)
+	}
+	tests := []struct {
+		feeCap, tip uint64
+		err         error
+	}{
+		{109, 11, ErrReplaceUnderpriced},  // fee cap not bumped
+		{110, 10, ErrReplaceUnderpriced},  // tip not bumped
+		{200, 10, ErrReplaceUnderpriced},  // only the fee cap bumped
+		{100, 100, ErrReplaceUnderpriced}, // only the tip bumped
+		{110, 11, nil},                    // both bumped by exactly 10%
+	}
+	for i, tt := range tests {
+		replacement, err := pool.Add(testOp(1, 0, tt.feeCap, tt.tip))
+		if err != tt.err {
+			t.Fatalf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+		if err == nil {
+			if pool.Get(hash) != nil {
+				t.Errorf("test %d: replaced operation still pooled", i)
+			}
+			if op := pool.Get(replacement); op == nil || !op.MaxFeePerGas.Eq(uint256.NewInt(tt.feeCap)) {
+				t.Errorf("test %d: replacement not pooled", i)
+			}
+		}
+	}
+	if pending, _ := pool.Stats(); pending != 1 {
+		t.Errorf("pooled operations mismatch: have %d, want 1", pending)
+	}
+}
+
+func TestPoolLimits(t *testing.T) {
+	pool := newTestPool(t, 2, 3)
+	for nonce := uint64(0); nonce < 2; nonce++ {
+		if _, err := pool.Add(testOp(1, nonce, 100, 10)); err != nil {
+			t.Fatalf("failed to add operation %d: %v", nonce, err)
+		}
+	}
+	if _, err := pool.Add(testOp(1, 2, 100, 10)); err != ErrSenderLimit {
+		t.Fatalf("sender limit error mismatch: have %v, want %v", err, ErrSenderLimit)
+	}
+	// Replacements are accepted at the sender limit.
+	if _, err := pool.Add(testOp(1, 1, 200, 20)); err != nil {
+		t.Fatalf("failed to replace operation at sender limit: %v", err)
+	}
+	if _, err := pool.Add(testOp(2, 0, 100, 10)); err != nil {
+		t.Fatalf("failed to add operation: %v", err)
+	}
+	if _, err := pool.Add(testOp(3, 0, 100, 10)); err != ErrPoolFull {
+		t.Fatalf("pool limit error mismatch: have %v, want %v", err, ErrPoolFull)
+	}
+	// Removing an operation frees its slot.
+	pool.Remove(pool.Hash(testOp(2, 0, 100, 10)))
+	if _, err := pool.Add(testOp(3, 0, 100, 10)); err != nil {
+		t.Fatalf("failed to add operation after removal: %v", err)
+	}
+}
+
+func TestPoolPending(t *testing.T) {
+	pool := newTestPool(t, 4, 16)
+	ops := []*UserOperation{
+		testOp(1, 1, 300, 30), // not the lowest nonce of its sender
+		testOp(1, 0, 100, 10),
+		testOp(2, 0, 200, 50),
+		testOp(3, 0, 40, 5), // below the base fee
+		testOp(4, 0, 80, 80),
+		testOp(5, 0, 500, 100), // bundled
+	}
+	for _, op := range ops {
+		if _, err := pool.Add(op); err != nil {
+			t.Fatalf("failed to add operation: %v", err)
+		}
+	}
+	pool.MarkBundled(types.Hash{0x01}, []types.Hash{pool.Hash(ops[5])})
+	if pooled, bundled := pool.Stats(); pooled != 6 || bundled != 1 {
+		t.Fatalf("stats mismatch: have %d/%d, want 6/1", pooled, bundled)
+	}
+
+	// Effective prices at base fee 50: sender 2 pays 100, sender 4 pays 80, sender 1 pays 60.
+	want := []types.Address{{2}, {4}, {1}}
+	pending := pool.Pending(uint256.NewInt(50), 0)
+	if len(pending) != len(want) {
+		t.Fatalf("pending count mismatch: have %d, want %d", len(pending), len(want))
+	}
+	for i, op := range pending {
+		if op.Sender != want[i] {
+			t.Errorf("pending %d: sender mismatch: have %x, want %x", i, op.Sender, want[i])
+		}
+		if op.Sender == (types.Address{1}) && !op.Nonce.IsZero() {
+			t.Errorf("pending %d: nonce mismatch: have %v, want 0", i, op.Nonce)
+		}
+	}
+	if pending := pool.Pending(uint256.NewInt(50), 2); len(pending) != 2 {
+		t.Errorf("limited pending count mismatch: have %d, want 2", len(pending))
+	}
+	// Without a base fee the operations are ordered by their fee cap.
+	if pending := pool.Pending(nil, 0); len(pending) != 4 || pending[0].Sender != (types.Address{2}) {
+		t.Errorf("pending without base fee mismatch: have %d operations", len(pending))
+	}
+}
+
+func TestBumped(t *testing.T) {
+	tests := []struct {
+		old, next, bump uint64
+		want            bool
+	}{
+		{100, 110, 10, true},
+		{100, 109, 10, false},
+		{100, 100, 0, true},
+		{0, 0, 10, true},
+		{7, 7, 10, true}, // 7 * 1.1 rounds down to 7
+		{10, 11, 10, true},
+	}
+	for _, tt := range tests {
+		if have := bumped(uint256.NewInt(tt.old), uint256.NewInt(tt.next), tt.bump); have != tt.want {
+			t.Errorf("bumped(%d, %d, %d) = %v, want %v", tt.old, tt.next, tt.bump, have, tt.want)
+		}
+	}
+}
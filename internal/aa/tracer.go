@@ -0,0 +1,132 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package aa
+
+import (
+	"fmt"
+
+	"github.com/holiman/uint256"
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/internal/vm"
+)
+
+// bannedOpCodes may not be used by the account, factory or paymaster during
+// validation, as their result differs between simulation and inclusion.
+var bannedOpCodes = map[vm.OpCode]struct{}{
+	vm.GASPRICE:     {},
+	vm.GASLIMIT:     {},
+	vm.DIFFICULTY:   {},
+	vm.TIMESTAMP:    {},
+	vm.BASEFEE:      {},
+	vm.BLOCKHASH:    {},
+	vm.NUMBER:       {},
+	vm.SELFBALANCE:  {},
+	vm.BALANCE:      {},
+	vm.ORIGIN:       {},
+	vm.CREATE:       {},
+	vm.COINBASE:     {},
+	vm.SELFDESTRUCT: {},
+	vm.INVALID:      {},
+}
+
+// OpcodeViolation describes a banned opcode executed during validation.
+type OpcodeViolation struct {
+	Contract types.Address
+	Op       vm.OpCode
+	Depth    int
+}
+
+func (v *OpcodeViolation) Error() string {
+	return fmt.Sprintf("contract %v used banned opcode %v during validation", v.Contract, v.Op)
+}
+
+// ValidationTracer is an EVM logger recording the opcode rule violations of a
+// simulateValidation call. The entry point itself runs at depth 1 and is trusted,
+// every frame below it belongs to the account, factory or paymaster.
+type ValidationTracer struct {
+	allowCreate2 bool // whether the operation deploys its sender through the factory
+
+	pendingGas *OpcodeViolation // GAS opcode that must be followed by a call
+	create2s   int
+	violation  *OpcodeViolation
+}
+
+// NewValidationTracer returns a tracer for simulating the validation of op.
+func NewValidationTracer(op *UserOperation) *ValidationTracer {
+	return &ValidationTracer{allowCreate2: len(op.InitCode) > 0}
+}
+
+// Err returns the first rule violation encountered, if any.
+func (t *ValidationTracer) Err() error {
+	if t.violation == nil && t.pendingGas != nil {
+		return t.pendingGas
+	}
+	if t.violation == nil {
+		return nil
+	}
+	return t.violation
+}
+
+func (t *ValidationTracer) CaptureTxStart(gasLimit uint64) {}
+
+func (t *ValidationTracer) CaptureTxEnd(restGas uint64) {}
+
+func (t *ValidationTracer) CaptureStart(env vm.VMInterface, from types.Address, to types.Address, create bool, input []byte, gas uint64, value *uint256.Int) {
+}
+
+func (t *ValidationTracer) CaptureEnd(output []byte, usedGas uint64, err error) {}
+
+func (t *ValidationTracer) CaptureEnter(typ vm.OpCode, from types.Address, to types.Address, input []byte, gas uint64, value *uint256.Int) {
+}
+
+func (t *ValidationTracer) CaptureExit(output []byte, usedGas uint64, err error) {}
+
+func (t *ValidationTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if t.violation != nil || depth <= 1 {
+		return
+	}
+	// GAS is only allowed to forward gas to a call
+	if t.pendingGas != nil {
+		switch op {
+		case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+			t.pendingGas = nil
+		default:
+			t.violation = t.pendingGas
+			return
+		}
+	}
+	violation := &OpcodeViolation{Contract: scope.Contract.Address(), Op: op, Depth: depth}
+	switch op {
+	case vm.GAS:
+		t.pendingGas = violation
+		return
+	case vm.CREATE2:
+		// The factory may deploy the sender, once.
+		t.create2s++
+		if t.allowCreate2 && t.create2s == 1 {
+			return
+		}
+		t.violation = violation
+		return
+	}
+	if _, banned := bannedOpCodes[op]; banned {
+		t.violation = violation
+	}
+}
+
+func (t *ValidationTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
@@ -0,0 +1,241 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package aa
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+
+	"github.com/holiman/uint256"
+	"github.com/n42blockchain/N42/common/crypto"
+	"github.com/n42blockchain/N42/common/hexutil"
+	"github.com/n42blockchain/N42/common/types"
+)
+
+// UserOperation is an ERC-4337 user operation, as sent to the entry point's
+// handleOps by a bundler.
+type UserOperation struct {
+	Sender               types.Address
+	Nonce                *uint256.Int
+	InitCode             []byte
+	CallData             []byte
+	CallGasLimit         *uint256.Int
+	VerificationGasLimit *uint256.Int
+	PreVerificationGas   *uint256.Int
+	MaxFeePerGas         *uint256.Int
+	MaxPriorityFeePerGas *uint256.Int
+	PaymasterAndData     []byte
+	Signature            []byte
+}
+
+// userOperationJSON is the RPC representation of a UserOperation.
+type userOperationJSON struct {
+	Sender               *types.Address `json:"sender"`
+	Nonce                *hexutil.Big   `json:"nonce"`
+	InitCode             hexutil.Bytes  `json:"initCode"`
+	CallData             hexutil.Bytes  `json:"callData"`
+	CallGasLimit         *hexutil.Big   `json:"callGasLimit"`
+	VerificationGasLimit *hexutil.Big   `json:"verificationGasLimit"`
+	PreVerificationGas   *hexutil.Big   `json:"preVerificationGas"`
+	MaxFeePerGas         *hexutil.Big   `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big   `json:"maxPriorityFeePerGas"`
+	PaymasterAndData     hexutil.Bytes  `json:"paymasterAndData"`
+	Signature            hexutil.Bytes  `json:"signature"`
+}
+
+// MarshalJSON marshals as JSON.
+func (op *UserOperation) MarshalJSON() ([]byte, error) {
+	enc := userOperationJSON{
+		Sender:               &op.Sender,
+		Nonce:                (*hexutil.Big)(op.Nonce.ToBig()),
+		InitCode:             op.InitCode,
+		CallData:             op.CallData,
+		CallGasLimit:         (*hexutil.Big)(op.CallGasLimit.ToBig()),
+		VerificationGasLimit: (*hexutil.Big)(op.VerificationGasLimit.ToBig()),
+		PreVerificationGas:   (*hexutil.Big)(op.PreVerificationGas.ToBig()),
+		MaxFeePerGas:         (*hexutil.Big)(op.MaxFeePerGas.ToBig()),
+		MaxPriorityFeePerGas: (*hexutil.Big)(op.MaxPriorityFeePerGas.ToBig()),
+		PaymasterAndData:     op.PaymasterAndData,
+		Signature:            op.Signature,
+	}
+	return json.Marshal(&enc)
+}
+
+// UnmarshalJSON unmarshals from JSON. Missing gas fields are left zero so that
+// partially filled operations can be passed to gas estimation.
+func (op *UserOperation) UnmarshalJSON(input []byte) error {
+	var dec userOperationJSON
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	if dec.Sender == nil {
+		return errors.New("missing required field 'sender' for UserOperation")
+	}
+	if dec.Nonce == nil {
+		return errors.New("missing required field 'nonce' for UserOperation")
+	}
+	op.Sender = *dec.Sender
+	var overflow bool
+	toU256 := func(b *hexutil.Big) *uint256.Int {
+		if b == nil {
+			return new(uint256.Int)
+		}
+		v, o := uint256.FromBig(b.ToInt())
+		overflow = overflow || o
+		return v
+	}
+	op.Nonce = toU256(dec.Nonce)
+	op.InitCode = dec.InitCode
+	op.CallData = dec.CallData
+	op.CallGasLimit = toU256(dec.CallGasLimit)
+	op.VerificationGasLimit = toU256(dec.VerificationGasLimit)
+	op.PreVerificationGas = toU256(dec.PreVerificationGas)
+	op.MaxFeePerGas = toU256(dec.MaxFeePerGas)
+	op.MaxPriorityFeePerGas = toU256(dec.MaxPriorityFeePerGas)
+	op.PaymasterAndData = dec.PaymasterAndData
+	op.Signature = dec.Signature
+	if overflow {
+		return errors.New("UserOperation field exceeds 256 bits")
+	}
+	return nil
+}
+
+// Copy returns a deep copy of the operation.
+func (op *UserOperation) Copy() *UserOperation {
+	return &UserOperation{
+		Sender:               op.Sender,
+		Nonce:                new(uint256.Int).Set(op.Nonce),
+		InitCode:             append([]byte(nil), op.InitCode...),
+		CallData:             append([]byte(nil), op.CallData...),
+		CallGasLimit:         new(uint256.Int).Set(op.CallGasLimit),
+		VerificationGasLimit: new(uint256.Int).Set(op.VerificationGasLimit),
+		PreVerificationGas:   new(uint256.Int).Set(op.PreVerificationGas),
+		MaxFeePerGas:         new(uint256.Int).Set(op.MaxFeePerGas),
+		MaxPriorityFeePerGas: new(uint256.Int).Set(op.MaxPriorityFeePerGas),
+		PaymasterAndData:     append([]byte(nil), op.PaymasterAndData...),
+		Signature:            append([]byte(nil), op.Signature...),
+	}
+}
+
+// Factory returns the address of the account factory, or the zero address if
+// the operation does not deploy the sender.
+func (op *UserOperation) Factory() types.Address {
+	if len(op.InitCode) < types.AddressLength {
+		return types.Address{}
+	}
+	return types.BytesToAddress(op.InitCode[:types.AddressLength])
+}
+
+// Paymaster returns the address of the paymaster, or the zero address if the
+// sender pays for itself.
+func (op *UserOperation) Paymaster() types.Address {
+	if len(op.PaymasterAndData) < types.AddressLength {
+		return types.Address{}
+	}
+	return types.BytesToAddress(op.PaymasterAndData[:types.AddressLength])
+}
+
+// TotalGas returns the gas the bundler has to provide for the operation, or
+// ErrGasOverflow if it does not fit into 64 bits.
+func (op *UserOperation) TotalGas() (uint64, error) {
+	verification := new(uint256.Int).Set(op.VerificationGasLimit)
+	if len(op.PaymasterAndData) > 0 {
+		// validatePaymasterUserOp and postOp are both bounded by the verification limit
+		if _, overflow := verification.MulOverflow(verification, uint256.NewInt(3)); overflow {
+			return 0, ErrGasOverflow
+		}
+	}
+	gas, overflow := new(uint256.Int).AddOverflow(op.CallGasLimit, op.PreVerificationGas)
+	if overflow {
+		return 0, ErrGasOverflow
+	}
+	if _, overflow := gas.AddOverflow(gas, verification); overflow || !gas.IsUint64() {
+		return 0, ErrGasOverflow
+	}
+	return gas.Uint64(), nil
+}
+
+// EffectiveGasPrice returns the gas price the operation pays at the given base fee.
+func (op *UserOperation) EffectiveGasPrice(baseFee *uint256.Int) *uint256.Int {
+	if baseFee == nil {
+		return new(uint256.Int).Set(op.MaxFeePerGas)
+	}
+	price := new(uint256.Int).Add(baseFee, op.MaxPriorityFeePerGas)
+	if price.Gt(op.MaxFeePerGas) {
+		return new(uint256.Int).Set(op.MaxFeePerGas)
+	}
+	return price
+}
+
+// Hash returns the user operation hash the entry point emits and the account signs.
+func (op *UserOperation) Hash(entryPoint types.Address, chainID *big.Int) types.Hash {
+	packed, _ := userOpHashArgs.Pack(
+		op.Sender,
+		op.Nonce.ToBig(),
+		crypto.Keccak256Hash(op.InitCode),
+		crypto.Keccak256Hash(op.CallData),
+		op.CallGasLimit.ToBig(),
+		op.VerificationGasLimit.ToBig(),
+		op.PreVerificationGas.ToBig(),
+		op.MaxFeePerGas.ToBig(),
+		op.MaxPriorityFeePerGas.ToBig(),
+		crypto.Keccak256Hash(op.PaymasterAndData),
+	)
+	enc, _ := userOpHashWrapArgs.Pack(crypto.Keccak256Hash(packed), entryPoint, chainID)
+	return crypto.Keccak256Hash(enc)
+}
+
+// Gas overheads of the entry point, used to derive the minimal preVerificationGas.
+const (
+	preVerificationFixedGas   = 21000 // transaction base cost, shared by the bundle
+	preVerificationPerOpGas   = 18300 // entry point bookkeeping of a single operation
+	preVerificationPerWordGas = 4     // abi encoding overhead per packed word
+	preVerificationBundleSize = 1     // operations the fixed cost is assumed to be shared with
+	dummySignatureSize        = 65    // signature size assumed when estimating unsigned operations
+)
+
+// CalcPreVerificationGas returns the minimal preVerificationGas of an operation,
+// covering the calldata and entry point overhead not metered by the entry point.
+func CalcPreVerificationGas(op *UserOperation) uint64 {
+	op = op.Copy()
+	// Estimate with maximal values so that the result stays valid once the gas
+	// fields and the signature are filled in.
+	max := new(uint256.Int).SetAllOne()
+	op.CallGasLimit, op.VerificationGasLimit, op.PreVerificationGas = max, max, max
+	op.MaxFeePerGas, op.MaxPriorityFeePerGas = max, max
+	if len(op.Signature) < dummySignatureSize {
+		op.Signature = make([]byte, dummySignatureSize)
+		for i := range op.Signature {
+			op.Signature[i] = 0xff
+		}
+	}
+	packed, err := packUserOp(op)
+	if err != nil {
+		return preVerificationFixedGas + preVerificationPerOpGas
+	}
+	var calldata uint64
+	for _, b := range packed {
+		if b == 0 {
+			calldata += 4
+		} else {
+			calldata += 16
+		}
+	}
+	words := uint64(len(packed)+31) / 32
+	return calldata + preVerificationFixedGas/preVerificationBundleSize + preVerificationPerOpGas + words*preVerificationPerWordGas
+}
@@ -0,0 +1,126 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package aa
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/n42blockchain/N42/common/types"
+)
+
+// testOp returns a statically valid operation of sender paying the given fees.
+func testOp(sender byte, nonce, feeCap, tip uint64) *UserOperation {
+	op := &UserOperation{
+		Sender:               types.Address{sender},
+		Nonce:                uint256.NewInt(nonce),
+		CallData:             []byte{0x01, 0x02},
+		CallGasLimit:         uint256.NewInt(minCallGasLimit),
+		VerificationGasLimit: uint256.NewInt(100_000),
+		PreVerificationGas:   new(uint256.Int),
+		MaxFeePerGas:         uint256.NewInt(feeCap),
+		MaxPriorityFeePerGas: uint256.NewInt(tip),
+		Signature:            make([]byte, dummySignatureSize),
+	}
+	op.PreVerificationGas.SetUint64(CalcPreVerificationGas(op))
+	return op
+}
+
+func TestUserOperationJSON(t *testing.T) {
+	op := testOp(1, 7, 100, 10)
+	op.PaymasterAndData = []byte{0xaa}
+	enc, err := json.Marshal(op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dec UserOperation
+	if err := json.Unmarshal(enc, &dec); err != nil {
+		t.Fatal(err)
+	}
+	if have, want := dec.Hash(types.Address{0xee}, big.NewInt(1)), op.Hash(types.Address{0xee}, big.NewInt(1)); have != want {
+		t.Fatalf("hash mismatch after round trip: have %v, want %v", have, want)
+	}
+
+	// Gas fields are optional for estimation, sender and nonce are not.
+	if err := json.Unmarshal([]byte(`{"sender":"0x0000000000000000000000000000000000000001","nonce":"0x1"}`), &dec); err != nil {
+		t.Fatalf("partial operation rejected: %v", err)
+	}
+	if !dec.CallGasLimit.IsZero() {
+		t.Errorf("missing callGasLimit not zero: %v", dec.CallGasLimit)
+	}
+	if err := json.Unmarshal([]byte(`{"nonce":"0x1"}`), &dec); err == nil {
+		t.Errorf("operation without sender accepted")
+	}
+	huge := `{"sender":"0x0000000000000000000000000000000000000001","nonce":"0x1","callGasLimit":"0x10000000000000000000000000000000000000000000000000000000000000000"}`
+	if err := json.Unmarshal([]byte(huge), &dec); err == nil {
+		t.Errorf("operation with a field over 256 bits accepted")
+	}
+}
+
+func TestUserOperationTotalGas(t *testing.T) {
+	max := new(uint256.Int).SetAllOne()
+	tests := []struct {
+		call, verification, pre *uint256.Int
+		paymaster               bool
+		want                    uint64
+		err                     error
+	}{
+		{uint256.NewInt(10), uint256.NewInt(20), uint256.NewInt(30), false, 60, nil},
+		{uint256.NewInt(10), uint256.NewInt(20), uint256.NewInt(30), true, 100, nil},
+		{uint256.NewInt(math.MaxUint64 - 1), uint256.NewInt(0), uint256.NewInt(1), false, math.MaxUint64, nil},
+		{uint256.NewInt(math.MaxUint64), uint256.NewInt(0), uint256.NewInt(1), false, 0, ErrGasOverflow},
+		{uint256.NewInt(0), uint256.NewInt(math.MaxUint64 / 2), uint256.NewInt(0), true, 0, ErrGasOverflow},
+		{max, uint256.NewInt(0), uint256.NewInt(1), false, 0, ErrGasOverflow},
+		{uint256.NewInt(0), max, uint256.NewInt(0), true, 0, ErrGasOverflow},
+		{uint256.NewInt(1), max, uint256.NewInt(0), false, 0, ErrGasOverflow},
+	}
+	for i, tt := range tests {
+		op := &UserOperation{CallGasLimit: tt.call, VerificationGasLimit: tt.verification, PreVerificationGas: tt.pre}
+		if tt.paymaster {
+			op.PaymasterAndData = []byte{0x01}
+		}
+		have, err := op.TotalGas()
+		if !errors.Is(err, tt.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+		if have != tt.want {
+			t.Errorf("test %d: gas mismatch: have %d, want %d", i, have, tt.want)
+		}
+	}
+}
+
+func TestUserOperationEffectiveGasPrice(t *testing.T) {
+	op := testOp(1, 0, 100, 10)
+	tests := []struct {
+		baseFee *uint256.Int
+		want    uint64
+	}{
+		{nil, 100},
+		{uint256.NewInt(50), 60},
+		{uint256.NewInt(90), 100},
+		{uint256.NewInt(200), 100},
+	}
+	for _, tt := range tests {
+		if have := op.EffectiveGasPrice(tt.baseFee); !have.Eq(uint256.NewInt(tt.want)) {
+			t.Errorf("base fee %v: price mismatch: have %v, want %d", tt.baseFee, have, tt.want)
+		}
+	}
+}
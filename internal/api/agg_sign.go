@@ -33,6 +33,8 @@ import (
 	event "github.com/n42blockchain/N42/modules/event/v2"
 	"github.com/n42blockchain/N42/modules/rawdb"
 	"github.com/n42blockchain/N42/modules/state"
+	"github.com/n42blockchain/N42/params"
+	"github.com/n42blockchain/N42/params/networkname"
 	"golang.org/x/crypto/sha3"
 )
 
@@ -42,6 +44,22 @@ var validVerifers = map[string]string{
 	//"astb9e94477f5f88b5e8da2e97e8506d6e4fcf04e5b": "2c02dd3cf600af9a8567e5cc5ff158c1b89e1f3ea21bff61f505d141a96a60ee",
 }
 
+// devVerifiers are well-known verifier keys that let a single developer node
+// gather the signatures Beijing blocks need. They must never hold real funds.
+var devVerifiers = map[string]string{
+	"0x0000000000000000000000000000000000000d01": "723e4eb47b420162fe348b1467cb7603e09568149fc0ee39cc81fde1b1e86f13",
+	"0x0000000000000000000000000000000000000d02": "27a84cc50757e91834550ff134bf18f814a5c6682477478c24b399580958a01f",
+	"0x0000000000000000000000000000000000000d03": "b8dfba1df6bd415faf961953a2702e022628d802e32415e95d22ed5aaf833279",
+}
+
+// localVerifiers returns the verifier keys this node signs mined blocks with.
+func localVerifiers(chainConfig *params.ChainConfig) map[string]string {
+	if chainConfig != nil && chainConfig.ChainName == networkname.DevChainName {
+		return devVerifiers
+	}
+	return validVerifers
+}
+
 //type WithCodeAndHash struct {
 //	CodeIndex []byte `json:"codeIndex"`
 //	Code      []byte `json:"code"`
@@ -254,7 +272,9 @@ LOOP:
 	return aggSign, verifiers, nil
 }
 
-func MachineVerify(ctx context.Context) error {
+func MachineVerify(ctx context.Context, chainConfig *params.ChainConfig) error {
+	verifiers := localVerifiers(chainConfig)
+
 	entire := make(chan common.MinedEntireEvent)
 	blocksSub := event.GlobalEvent.Subscribe(entire)
 	defer blocksSub.Unsubscribe()
@@ -266,7 +286,7 @@ func MachineVerify(ctx context.Context) error {
 		select {
 		case b := <-entire:
 			log.Tracef("machine verify accept entire, number: %d", b.Entire.Entire.Header.Number.Uint64())
-			for k, s := range validVerifers {
+			for k, s := range verifiers {
 				go func(seckey string, address string) {
 					// recover private key
 					sByte, err := hex.DecodeString(seckey)
@@ -23,9 +23,12 @@ import (
 	"github.com/holiman/uint256"
 	"github.com/n42blockchain/N42/conf"
 	"github.com/n42blockchain/N42/internal"
+	"github.com/n42blockchain/N42/internal/aa"
 	"github.com/n42blockchain/N42/internal/api/filters"
+	"github.com/n42blockchain/N42/internal/sync/progress"
 	vm2 "github.com/n42blockchain/N42/internal/vm"
 	"github.com/n42blockchain/N42/internal/vm/evmtypes"
+	"github.com/n42blockchain/N42/modules"
 	event "github.com/n42blockchain/N42/modules/event/v2"
 	"github.com/n42blockchain/N42/modules/state"
 	"github.com/n42blockchain/N42/turbo/rpchelper"
@@ -71,6 +74,10 @@ type API struct {
 	chainConfig    *params.ChainConfig
 
 	gpo *Oracle
+
+	userOpPool *aa.Pool
+
+	syncTracker *progress.Tracker
 }
 
 // NewAPI creates a new protocol API.
@@ -89,9 +96,20 @@ func (api *API) SetGpo(gpo *Oracle) {
 	api.gpo = gpo
 }
 
+// SetUserOpPool enables the ERC-4337 user operation RPCs backed by the given pool.
+func (api *API) SetUserOpPool(pool *aa.Pool) {
+	api.userOpPool = pool
+}
+
+// SetSyncTracker enables eth_syncing and the syncing subscription backed by
+// the given tracker.
+func (api *API) SetSyncTracker(tracker *progress.Tracker) {
+	api.syncTracker = tracker
+}
+
 func (api *API) Apis() []jsonrpc.API {
 	nonceLock := new(AddrLocker)
-	return []jsonrpc.API{
+	apis := []jsonrpc.API{
 		{
 			Namespace: "eth",
 			Service:   NewBlockChainAPI(api),
@@ -120,6 +138,19 @@ func (api *API) Apis() []jsonrpc.API {
 			Service:   filters.NewFilterAPI(api, 5*time.Minute),
 		},
 	}
+	if api.userOpPool != nil {
+		apis = append(apis, jsonrpc.API{
+			Namespace: "eth",
+			Service:   NewUserOperationAPI(api),
+		})
+	}
+	if api.syncTracker != nil {
+		apis = append(apis, jsonrpc.API{
+			Namespace: "eth",
+			Service:   NewSyncAPI(api),
+		})
+	}
+	return apis
 }
 
 func (n *API) TxsPool() common.ITxsPool       { return n.txspool }
@@ -142,20 +173,26 @@ func (n *API) GetEvm(ctx context.Context, msg internal.Message, ibs evmtypes.Int
 	return vm2.NewEVM(context, txContext, ibs, n.GetChainConfig(), *vmConfig), vmError, nil
 }
 
-func (n *API) State(tx kv.Tx, blockNrOrHash jsonrpc.BlockNumberOrHash) evmtypes.IntraBlockState {
+// State returns the state after the given block, nil if the block is unknown.
+// A *rawdb.PrunedError is returned if the state history of the block has been
+// pruned.
+func (n *API) State(tx kv.Tx, blockNrOrHash jsonrpc.BlockNumberOrHash) (evmtypes.IntraBlockState, error) {
 
 	_, blockHash, err := rpchelper.GetCanonicalBlockNumber(blockNrOrHash, tx)
 	if err != nil {
-		return nil
+		return nil, nil
 	}
 
 	blockNr := rawdb.ReadHeaderNumber(tx, blockHash)
 	if nil == blockNr {
-		return nil
+		return nil, nil
+	}
+	if err := rawdb.CheckPruned(tx, modules.AccountChangeSet, *blockNr); err != nil {
+		return nil, err
 	}
 
 	stateReader := state.NewPlainState(tx, *blockNr+1)
-	return state.New(stateReader)
+	return state.New(stateReader), nil
 }
 
 func (n *API) GetChainConfig() *params.ChainConfig {
@@ -252,52 +289,6 @@ func (s *astAPI) FeeHistory(ctx context.Context, blockCount jsonrpc.DecimalOrHex
 	return results, nil
 }
 
-// TxPoolAPI offers and API for the transaction pool. It only operates on data that is non confidential.
-type TxPoolAPI struct {
-	api *API
-}
-
-// NewTxPoolAPI creates a new tx pool service that gives information about the transaction pool.
-func NewTxPoolAPI(api *API) *TxPoolAPI {
-	return &TxPoolAPI{api}
-}
-
-// Content returns the transactions contained within the transaction pool.
-func (s *TxPoolAPI) Content() map[string]map[string]map[string]*RPCTransaction {
-	content := map[string]map[string]map[string]*RPCTransaction{
-		"pending": make(map[string]map[string]*RPCTransaction),
-		"queued":  make(map[string]map[string]*RPCTransaction),
-	}
-	return content
-}
-
-// ContentFrom returns the transactions contained within the transaction pool.
-func (s *TxPoolAPI) ContentFrom(addr types.Address) map[string]map[string]*RPCTransaction {
-	content := make(map[string]map[string]*RPCTransaction, 2)
-
-	return content
-}
-
-// Status returns the number of pending and queued transaction in the pool.
-func (s *TxPoolAPI) Status() map[string]hexutil.Uint {
-	_, pending, _, queue := s.api.TxsPool().Stats()
-	return map[string]hexutil.Uint{
-		"pending": hexutil.Uint(pending),
-		"queued":  hexutil.Uint(queue),
-	}
-}
-
-// Inspect retrieves the content of the transaction pool and flattens it into an
-// easily inspectable list.
-func (s *TxPoolAPI) Inspect() map[string]map[string]map[string]string {
-	content := map[string]map[string]map[string]string{
-		"pending": make(map[string]map[string]string),
-		"queued":  make(map[string]map[string]string),
-	}
-
-	return content
-}
-
 // AccountAPI provides an API to access accounts managed by this node.
 // It offers only methods that can retrieve accounts.
 type AccountAPI struct {
@@ -329,6 +320,19 @@ func (api *BlockChainAPI) ChainId() *hexutil.Big {
 	return (*hexutil.Big)(api.api.GetChainConfig().ChainID)
 }
 
+// Syncing returns false if the node is in sync with the network, otherwise
+// the progress of the synchronization.
+func (s *BlockChainAPI) Syncing() (interface{}, error) {
+	if s.api.syncTracker == nil {
+		return false, nil
+	}
+	progress, syncing := s.api.syncTracker.Progress()
+	if !syncing {
+		return false, nil
+	}
+	return marshalSyncProgress(progress), nil
+}
+
 // GetBalance get balance
 func (s *BlockChainAPI) GetBalance(ctx context.Context, address mvm_common.Address, blockNrOrHash jsonrpc.BlockNumberOrHash) (*hexutil.Big, error) {
 	tx, err := s.api.db.BeginRo(ctx)
@@ -337,7 +341,10 @@ func (s *BlockChainAPI) GetBalance(ctx context.Context, address mvm_common.Addre
 	}
 	defer tx.Rollback()
 
-	state := s.api.State(tx, blockNrOrHash)
+	state, err := s.api.State(tx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
 	if state == nil {
 		return nil, nil
 	}
@@ -359,7 +366,10 @@ func (s *BlockChainAPI) GetCode(ctx context.Context, address mvm_common.Address,
 	}
 	defer tx.Rollback()
 
-	state := s.api.State(tx, blockNrOrHash)
+	state, err := s.api.State(tx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
 	if state == nil {
 		return nil, nil
 	}
@@ -377,7 +387,10 @@ func (s *BlockChainAPI) GetStorageAt(ctx context.Context, address types.Address,
 	}
 	defer tx.Rollback()
 
-	state := s.api.State(tx, blockNrOrHash)
+	state, err := s.api.State(tx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
 	if state == nil {
 		return nil, nil
 	}
@@ -528,6 +541,12 @@ func (diff *BlockOverrides) Apply(blockCtx *evmtypes.BlockContext) {
 }
 
 func DoCall(ctx context.Context, api *API, args TransactionArgs, blockNrOrHash jsonrpc.BlockNumberOrHash, overrides *StateOverride, timeout time.Duration, globalGasCap uint64) (*internal.ExecutionResult, error) {
+	return doCall(ctx, api, args, blockNrOrHash, overrides, timeout, globalGasCap, &vm2.Config{NoBaseFee: true})
+}
+
+// doCall executes a call with the given vm configuration, allowing the caller to
+// attach a tracer.
+func doCall(ctx context.Context, api *API, args TransactionArgs, blockNrOrHash jsonrpc.BlockNumberOrHash, overrides *StateOverride, timeout time.Duration, globalGasCap uint64, vmConfig *vm2.Config) (*internal.ExecutionResult, error) {
 	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
 
 	// header := api.BlockChain().CurrentBlock().Header()
@@ -556,7 +575,10 @@ func DoCall(ctx context.Context, api *API, args TransactionArgs, blockNrOrHash j
 
 	//reader := state.NewPlainStateReader(tx)
 	//ibs := state.New(reader)
-	ibs := api.State(tx, blockNrOrHash)
+	ibs, err := api.State(tx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
 	if ibs == nil {
 		return nil, errors.New("cannot load state")
 	}
@@ -582,7 +604,7 @@ func DoCall(ctx context.Context, api *API, args TransactionArgs, blockNrOrHash j
 	}
 
 	//todo debug: , Debug: true, Tracer: vm.NewMarkdownLogger(os.Stdout)
-	evm, vmError, err := api.GetEvm(ctx, msg, ibs, header, &vm2.Config{NoBaseFee: true})
+	evm, vmError, err := api.GetEvm(ctx, msg, ibs, header, vmConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -800,7 +822,10 @@ func DoEstimateGas(ctx context.Context, n *API, args TransactionArgs, blockNrOrH
 			return 0, err
 		}
 		defer tx.Rollback()
-		statedb := n.State(tx, blockNrOrHash)
+		statedb, err := n.State(tx, blockNrOrHash)
+		if err != nil {
+			return 0, err
+		}
 		if statedb == nil {
 			return 0, errors.New("cannot load stateDB")
 		}
@@ -1017,7 +1042,10 @@ func (s *TransactionAPI) GetTransactionCount(ctx context.Context, address mvm_co
 	}
 	defer tx.Rollback()
 
-	state := s.api.State(tx, blockNrOrHash)
+	state, err := s.api.State(tx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
 	if state == nil {
 		return nil, nil
 	}
@@ -1262,6 +1290,7 @@ func (s *TransactionAPI) SendTransaction(ctx context.Context, args TransactionAr
 	}
 	// todo sign?
 	//signed := tx
+	signed.SetFrom(account.Address)
 	return SubmitTransaction(ctx, s.api, signed)
 }
 
@@ -1305,10 +1334,6 @@ func (api *DebugAPI) SetHead(number hexutil.Uint64) {
 	api.api.BlockChain().SetHead(uint64(number))
 }
 
-func (debug *DebugAPI) GetAccount(ctx context.Context, address types.Address) {
-
-}
-
 // NetAPI offers network related RPC methods
 type NetAPI struct {
 	api            *API
@@ -1374,6 +1399,218 @@ func (s *TxsPoolAPI) Content() map[string]map[string]map[string]*RPCTransaction
 	return content
 }
 
+// ContentFrom returns the transactions contained within the transaction pool.
+func (s *TxsPoolAPI) ContentFrom(addr mvm_common.Address) map[string]map[string]*RPCTransaction {
+	content := map[string]map[string]*RPCTransaction{
+		"pending": make(map[string]*RPCTransaction),
+		"queued":  make(map[string]*RPCTransaction),
+	}
+	pending, queue, _ := s.api.TxsPool().ContentFrom(*mvm_types.ToastAddress(&addr))
+	curHeader := s.api.BlockChain().CurrentBlock().Header()
+	for _, tx := range pending {
+		content["pending"][fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx, curHeader)
+	}
+	for _, tx := range queue {
+		content["queued"][fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx, curHeader)
+	}
+	return content
+}
+
+// QueuedReasons returns, keyed by nonce, why each queued transaction of an
+// address is not executable yet.
+func (s *TxsPoolAPI) QueuedReasons(addr mvm_common.Address) map[string]string {
+	_, queue, reasons := s.api.TxsPool().ContentFrom(*mvm_types.ToastAddress(&addr))
+	content := make(map[string]string, len(queue))
+	for _, tx := range queue {
+		nonce := fmt.Sprintf("%d", tx.Nonce())
+		if err := reasons[tx.Hash()]; err != nil {
+			content[nonce] = err.Error()
+		} else {
+			content[nonce] = "awaiting promotion"
+		}
+	}
+	return content
+}
+
+// Status returns the number of pending and queued transaction in the pool.
+func (s *TxsPoolAPI) Status() map[string]hexutil.Uint {
+	_, pending, _, queue := s.api.TxsPool().Stats()
+	return map[string]hexutil.Uint{
+		"pending": hexutil.Uint(pending),
+		"queued":  hexutil.Uint(queue),
+	}
+}
+
+// Inspect retrieves the content of the transaction pool and flattens it into an
+// easily inspectable list.
+func (s *TxsPoolAPI) Inspect() map[string]map[string]map[string]string {
+	content := map[string]map[string]map[string]string{
+		"pending": make(map[string]map[string]string),
+		"queued":  make(map[string]map[string]string),
+	}
+	pending, queue := s.api.TxsPool().Content()
+
+	// Define a formatter to flatten a transaction into a string
+	var format = func(tx *transaction.Transaction) string {
+		if to := tx.To(); to != nil {
+			return fmt.Sprintf("%s: %v wei + %v gas × %v wei", mvm_types.FromastAddress(to).Hex(), tx.Value(), tx.Gas(), tx.GasPrice())
+		}
+		return fmt.Sprintf("contract creation: %v wei + %v gas × %v wei", tx.Value(), tx.Gas(), tx.GasPrice())
+	}
+	for account, txs := range pending {
+		dump := make(map[string]string)
+		for _, tx := range txs {
+			dump[fmt.Sprintf("%d", tx.Nonce())] = format(tx)
+		}
+		content["pending"][mvm_types.FromastAddress(&account).Hex()] = dump
+	}
+	for account, txs := range queue {
+		dump := make(map[string]string)
+		for _, tx := range txs {
+			dump[fmt.Sprintf("%d", tx.Nonce())] = format(tx)
+		}
+		content["queued"][mvm_types.FromastAddress(&account).Hex()] = dump
+	}
+	return content
+}
+
+// Replace builds, signs and submits a replacement for a pooled transaction sent by
+// an account managed by this node. The fees are bumped by the pool's price bump.
+// If cancel is set, the replacement is an empty self transfer which cancels the
+// original transaction instead of re-sending it.
+func (s *TxsPoolAPI) Replace(ctx context.Context, hash mvm_common.Hash, cancel bool) (mvm_common.Hash, error) {
+	tx := s.api.TxsPool().GetTx(mvm_types.ToastHash(hash))
+	if tx == nil {
+		return mvm_common.Hash{}, fmt.Errorf("transaction %v not found in txpool", hash)
+	}
+	// The fee payer signs the fees of a fee-delegated transaction, the sender
+	// alone can't bump them.
+	if tx.Type() == transaction.SakuragiTxType {
+		return mvm_common.Hash{}, fmt.Errorf("fee-delegated transaction %v can't be replaced: the fee payer has to sign the replacement", hash)
+	}
+	from := *tx.From()
+	account := accounts.Account{Address: from}
+	wallet, err := s.api.accountManager.Find(account)
+	if err != nil {
+		return mvm_common.Hash{}, fmt.Errorf("sender %v is not a local account: %w", from, err)
+	}
+
+	var (
+		to    = tx.To()
+		value = tx.Value()
+		gas   = tx.Gas()
+		data  = tx.Data()
+		al    = tx.AccessList()
+		bump  = s.api.TxsPool().PriceBump()
+	)
+	if cancel {
+		to, value, gas, data, al = &from, uint256.NewInt(0), params.TxGas, nil, nil
+	}
+
+	var inner transaction.TxData
+	switch tx.Type() {
+	case transaction.DynamicFeeTxType:
+		tip := bumpPrice(tx.GasTipCap(), bump)
+		if suggested, err := s.api.gpo.SuggestTipCap(ctx, s.api.GetChainConfig()); err == nil {
+			if st, overflow := uint256.FromBig(suggested); !overflow && st.Cmp(tip) > 0 {
+				tip = st
+			}
+		}
+		feeCap := bumpPrice(tx.GasFeeCap(), bump)
+		if min := new(uint256.Int).Add(s.api.BlockChain().CurrentBlock().Header().BaseFee64(), tip); min.Cmp(feeCap) > 0 {
+			feeCap = min
+		}
+		inner = &transaction.DynamicFeeTx{
+			ChainID:    tx.ChainId(),
+			Nonce:      tx.Nonce(),
+			GasTipCap:  tip,
+			GasFeeCap:  feeCap,
+			Gas:        gas,
+			To:         to,
+			From:       &from,
+			Value:      value,
+			Data:       data,
+			AccessList: al,
+		}
+	case transaction.AccessListTxType:
+		inner = &transaction.AccessListTx{
+			ChainID:    tx.ChainId(),
+			Nonce:      tx.Nonce(),
+			GasPrice:   bumpPrice(tx.GasPrice(), bump),
+			Gas:        gas,
+			To:         to,
+			From:       &from,
+			Value:      value,
+			Data:       data,
+			AccessList: al,
+		}
+	default:
+		inner = &transaction.LegacyTx{
+			Nonce:    tx.Nonce(),
+			GasPrice: bumpPrice(tx.GasPrice(), bump),
+			Gas:      gas,
+			To:       to,
+			From:     &from,
+			Value:    value,
+			Data:     data,
+		}
+	}
+
+	signed, err := wallet.SignTx(account, transaction.NewTx(inner), s.api.GetChainConfig().ChainID)
+	if err != nil {
+		return mvm_common.Hash{}, err
+	}
+	return SubmitTransaction(ctx, s.api, signed)
+}
+
+// bumpPrice returns price increased by bump percent, rounded up so that the
+// result always satisfies the txpool replacement threshold.
+func bumpPrice(price *uint256.Int, bump uint64) *uint256.Int {
+	bumped := new(uint256.Int).Mul(price, uint256.NewInt(100+bump))
+	bumped.Add(bumped, uint256.NewInt(99))
+	return bumped.Div(bumped, uint256.NewInt(100))
+}
+
+// EvictionEvents creates a subscription that fires every time the txpool drops
+// transactions to stay within its limits, reporting the hashes and the reason.
+func (s *TxsPoolAPI) EvictionEvents(ctx context.Context) (*jsonrpc.Subscription, error) {
+	notifier, supported := jsonrpc.NotifierFromContext(ctx)
+	if !supported {
+		return &jsonrpc.Subscription{}, jsonrpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		evictedCh := make(chan common.EvictedTxsEvent, 32)
+		evictedSub := event.GlobalEvent.Subscribe(evictedCh)
+		defer evictedSub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-evictedCh:
+				for _, tx := range ev.Txs {
+					hash := tx.Hash()
+					notifier.Notify(rpcSub.ID, map[string]interface{}{
+						"hash":   mvm_types.FromastHash(hash),
+						"from":   mvm_types.FromastAddress(tx.From()),
+						"nonce":  hexutil.Uint64(tx.Nonce()),
+						"reason": ev.Reason,
+					})
+				}
+			case <-evictedSub.Err():
+				return
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 func (api *TransactionAPI) TestBatchTxs(ctx context.Context) {
 	go batchTxs(api.api, 0, 1000000)
 }
@@ -30,6 +30,7 @@ import (
 	"github.com/n42blockchain/N42/internal"
 	"github.com/n42blockchain/N42/internal/vm"
 	"github.com/n42blockchain/N42/internal/vm/evmtypes"
+	"github.com/n42blockchain/N42/modules"
 	"github.com/n42blockchain/N42/modules/rawdb"
 	rpc "github.com/n42blockchain/N42/modules/rpc/jsonrpc"
 	"github.com/n42blockchain/N42/modules/state"
@@ -517,6 +518,9 @@ func (eth *API) StateAtBlock(ctx context.Context, tx kv.Tx, blk *types.Block) (s
 	// The state is available in live database, create a reference
 	// on top to prevent garbage collection and return a release
 	// function to deref it.
+	if err := rawdb.CheckPruned(tx, modules.AccountChangeSet, origin); err != nil {
+		return nil, err
+	}
 	statedb = eth.BlockChain().StateAt(tx, origin)
 	//statedb.Database().TrieDB().Reference(block.Root(), common.Hash{})
 	return statedb, nil
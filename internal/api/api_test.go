@@ -0,0 +1,264 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/n42blockchain/N42/common"
+	"github.com/n42blockchain/N42/common/crypto/bls"
+	"github.com/n42blockchain/N42/common/hexutil"
+	"github.com/n42blockchain/N42/common/transaction"
+	"github.com/n42blockchain/N42/common/types"
+	mvm_types "github.com/n42blockchain/N42/internal/avm/types"
+	event "github.com/n42blockchain/N42/modules/event/v2"
+	"github.com/n42blockchain/N42/modules/rpc/jsonrpc"
+	"github.com/n42blockchain/N42/params"
+)
+
+func TestBumpPrice(t *testing.T) {
+	for _, bump := range []uint64{0, 10, 25} {
+		for _, price := range []uint64{1, 9, 10, 99, 100, 12345, 1_000_000_007} {
+			bumped := bumpPrice(uint256.NewInt(price), bump)
+			// The txpool accepts a replacement at price * (100 + bump) / 100.
+			threshold := new(uint256.Int).Mul(uint256.NewInt(price), uint256.NewInt(100+bump))
+			threshold.Div(threshold, uint256.NewInt(100))
+			if bumped.Cmp(threshold) < 0 {
+				t.Errorf("bump %d%% of %d: have %v, below threshold %v", bump, price, bumped, threshold)
+			}
+			// Rounding up must not add more than a single wei.
+			if limit := new(uint256.Int).AddUint64(threshold, 1); bumped.Cmp(limit) > 0 {
+				t.Errorf("bump %d%% of %d: have %v, above %v", bump, price, bumped, limit)
+			}
+		}
+	}
+}
+
+func TestLocalVerifiers(t *testing.T) {
+	if have := localVerifiers(params.MainnetChainConfig); len(have) != len(validVerifers) {
+		t.Errorf("mainnet: have %d verifiers, want %d", len(have), len(validVerifers))
+	}
+	verifiers := localVerifiers(params.DevChainConfig)
+	// SignMerge needs at least three signatures to seal a Beijing block.
+	if len(verifiers) < 3 {
+		t.Fatalf("developer chain has %d verifiers, need at least 3", len(verifiers))
+	}
+	keys := make(map[string]struct{})
+	for address, secret := range verifiers {
+		var addr types.Address
+		if !addr.DecodeString(address) {
+			t.Errorf("invalid verifier address %s", address)
+		}
+		b, err := hex.DecodeString(secret)
+		if err != nil || len(b) != 32 {
+			t.Fatalf("verifier %s: invalid secret key", address)
+		}
+		var ikm [32]byte
+		copy(ikm[:], b)
+		key, err := bls.SecretKeyFromRandom32Byte(ikm)
+		if err != nil {
+			t.Fatalf("verifier %s: %v", address, err)
+		}
+		keys[hex.EncodeToString(key.PublicKey().Marshal())] = struct{}{}
+	}
+	if len(keys) != len(verifiers) {
+		t.Errorf("have %d distinct verifier keys, want %d", len(keys), len(verifiers))
+	}
+}
+
+// testTxsPool is a transaction pool serving fixed content.
+type testTxsPool struct {
+	common.ITxsPool
+	pending, queued map[types.Address][]*transaction.Transaction
+	reasons         map[types.Hash]error
+}
+
+func (p *testTxsPool) Content() (map[types.Address][]*transaction.Transaction, map[types.Address][]*transaction.Transaction) {
+	return p.pending, p.queued
+}
+
+func (p *testTxsPool) ContentFrom(addr types.Address) ([]*transaction.Transaction, []*transaction.Transaction, map[types.Hash]error) {
+	return p.pending[addr], p.queued[addr], p.reasons
+}
+
+func (p *testTxsPool) GetTx(hash types.Hash) *transaction.Transaction {
+	for _, lists := range []map[types.Address][]*transaction.Transaction{p.pending, p.queued} {
+		for _, txs := range lists {
+			for _, tx := range txs {
+				if tx.Hash() == hash {
+					return tx
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (p *testTxsPool) Stats() (int, int, int, int) {
+	var pending, queued int
+	for _, txs := range p.pending {
+		pending += len(txs)
+	}
+	for _, txs := range p.queued {
+		queued += len(txs)
+	}
+	return len(p.pending), pending, len(p.queued), queued
+}
+
+func testTransfer(from types.Address, nonce uint64) *transaction.Transaction {
+	to := types.Address{0x01}
+	return transaction.NewTx(&transaction.LegacyTx{
+		Nonce:    nonce,
+		GasPrice: uint256.NewInt(2),
+		Gas:      21000,
+		To:       &to,
+		From:     &from,
+		Value:    uint256.NewInt(3),
+	})
+}
+
+func newTxsPoolTestClient(t *testing.T, pool common.ITxsPool) *jsonrpc.Client {
+	server := jsonrpc.NewServer()
+	if err := server.RegisterName("txpool", NewTxsPoolAPI(&API{txspool: pool})); err != nil {
+		t.Fatal(err)
+	}
+	client := jsonrpc.DialInProc(server)
+	t.Cleanup(client.Close)
+	return client
+}
+
+func TestTxsPoolAPIContent(t *testing.T) {
+	addr := types.Address{0xaa}
+	gapped, awaiting := testTransfer(addr, 3), testTransfer(addr, 4)
+	pool := &testTxsPool{
+		pending: map[types.Address][]*transaction.Transaction{addr: {testTransfer(addr, 0), testTransfer(addr, 1)}},
+		queued:  map[types.Address][]*transaction.Transaction{addr: {gapped, awaiting}},
+		reasons: map[types.Hash]error{gapped.Hash(): fmt.Errorf("nonce gap: next executable nonce is 2")},
+	}
+	client := newTxsPoolTestClient(t, pool)
+	account := mvm_types.FromastAddress(&addr)
+
+	var status map[string]hexutil.Uint
+	if err := client.Call(&status, "txpool_status"); err != nil {
+		t.Fatal(err)
+	}
+	if status["pending"] != 2 || status["queued"] != 2 {
+		t.Errorf("status mismatch: have %v, want 2 pending and 2 queued", status)
+	}
+
+	var reasons map[string]string
+	if err := client.Call(&reasons, "txpool_queuedReasons", account); err != nil {
+		t.Fatal(err)
+	}
+	if len(reasons) != 2 || reasons["3"] != "nonce gap: next executable nonce is 2" || reasons["4"] != "awaiting promotion" {
+		t.Errorf("queued reasons mismatch: have %v", reasons)
+	}
+
+	var inspect map[string]map[string]map[string]string
+	if err := client.Call(&inspect, "txpool_inspect"); err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("%s: 3 wei + 21000 gas × 2 wei", mvm_types.FromastAddress(&types.Address{0x01}).Hex())
+	if pending := inspect["pending"][account.Hex()]; len(pending) != 2 || pending["1"] != want {
+		t.Errorf("inspected pending transactions mismatch: have %v, want nonce 1 as %q", pending, want)
+	}
+	if queued := inspect["queued"][account.Hex()]; len(queued) != 2 || queued["3"] != want {
+		t.Errorf("inspected queued transactions mismatch: have %v, want nonce 3 as %q", queued, want)
+	}
+}
+
+func TestTxsPoolAPIReplaceSponsored(t *testing.T) {
+	from, payer, to := types.Address{0xaa}, types.Address{0xbb}, types.Address{0x01}
+	tx := transaction.NewTx(&transaction.SakuragiTx{
+		ChainID:   uint256.NewInt(1),
+		GasTipCap: uint256.NewInt(1),
+		GasFeeCap: uint256.NewInt(2),
+		Gas:       21000,
+		To:        &to,
+		From:      &from,
+		Value:     uint256.NewInt(3),
+		FeePayer:  &payer,
+	})
+	api := NewTxsPoolAPI(&API{txspool: &testTxsPool{pending: map[types.Address][]*transaction.Transaction{from: {tx}}}})
+
+	// The sender can't sign for the fee payer, so the fee payer would be dropped.
+	for _, cancel := range []bool{false, true} {
+		if _, err := api.Replace(context.Background(), mvm_types.FromastHash(tx.Hash()), cancel); err == nil {
+			t.Errorf("cancel %v: replaced a fee-delegated transaction", cancel)
+		}
+	}
+}
+
+func TestTxsPoolAPIEvictionEvents(t *testing.T) {
+	server := jsonrpc.NewServer()
+	if err := server.RegisterName("txpool", NewTxsPoolAPI(&API{txspool: &testTxsPool{}})); err != nil {
+		t.Fatal(err)
+	}
+	// Subscriptions are read off the raw connection, the client doesn't deliver them.
+	serverConn, conn := net.Pipe()
+	go server.ServeCodec(jsonrpc.NewCodec(serverConn), 0)
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(10 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	enc, dec := json.NewEncoder(conn), json.NewDecoder(conn)
+
+	if err := enc.Encode(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": "txpool_subscribe", "params": []string{"evictionEvents"}}); err != nil {
+		t.Fatal(err)
+	}
+	var resp struct {
+		Result string `json:"result"`
+	}
+	if err := dec.Decode(&resp); err != nil || resp.Result == "" {
+		t.Fatalf("subscription failed: %v, %+v", err, resp)
+	}
+
+	addr := types.Address{0xaa}
+	ev := common.EvictedTxsEvent{Txs: []*transaction.Transaction{testTransfer(addr, 5), testTransfer(addr, 6)}, Reason: "queue-limit"}
+	// The subscription listens for evictions once it started up.
+	for deadline := time.Now().Add(5 * time.Second); event.GlobalEvent.Send(ev) == 0; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("subscription not listening for evictions")
+		}
+	}
+	for i, tx := range ev.Txs {
+		var n struct {
+			Method string `json:"method"`
+			Params struct {
+				Subscription string            `json:"subscription"`
+				Result       map[string]string `json:"result"`
+			} `json:"params"`
+		}
+		if err := dec.Decode(&n); err != nil {
+			t.Fatalf("notification %d: %v", i, err)
+		}
+		result := n.Params.Result
+		if n.Method != "txpool_subscription" || n.Params.Subscription != resp.Result {
+			t.Errorf("notification %d: method %s, subscription %s, want subscription %s", i, n.Method, n.Params.Subscription, resp.Result)
+		}
+		if result["hash"] != mvm_types.FromastHash(tx.Hash()).Hex() || result["nonce"] != hexutil.Uint64(tx.Nonce()).String() || result["reason"] != ev.Reason {
+			t.Errorf("notification %d mismatch: have %v", i, result)
+		}
+	}
+}
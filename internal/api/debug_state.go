@@ -0,0 +1,242 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/n42blockchain/N42/common/block"
+	"github.com/n42blockchain/N42/common/hexutil"
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/modules"
+	"github.com/n42blockchain/N42/modules/changeset"
+	"github.com/n42blockchain/N42/modules/rawdb"
+	"github.com/n42blockchain/N42/modules/rpc/jsonrpc"
+	"github.com/n42blockchain/N42/modules/state"
+	"github.com/n42blockchain/N42/turbo/rpchelper"
+)
+
+// AccountRangeMaxResults is the maximum number of accounts returned by one
+// debug_accountRange call.
+const AccountRangeMaxResults = 256
+
+// DumpStorageMaxResults is the maximum number of storage slots returned with
+// each account of a state dump. The rest is paged with debug_getAccount.
+const DumpStorageMaxResults = 1024
+
+// StateDump is a set of accounts of the state after a block.
+type StateDump struct {
+	Root     types.Hash                           `json:"root"`
+	Accounts map[types.Address]*state.DumpAccount `json:"accounts"`
+	// Next is the address to continue the dump from, nil if it is complete.
+	Next *types.Address `json:"next,omitempty"`
+}
+
+// StorageEntry is a storage slot returned by debug_storageRangeAt.
+type StorageEntry struct {
+	Key   *types.Hash `json:"key"`
+	Value types.Hash  `json:"value"`
+}
+
+// StorageRangeResult is a page of storage slots returned by
+// debug_storageRangeAt. Slots are keyed by their plain key.
+type StorageRangeResult struct {
+	Storage map[types.Hash]StorageEntry `json:"storage"`
+	NextKey *types.Hash                 `json:"nextKey"`
+}
+
+// StateDumper returns a dumper of the state after the given block, along with
+// the header of the block.
+func (n *API) StateDumper(tx kv.Tx, blockNrOrHash jsonrpc.BlockNumberOrHash) (*state.Dumper, *block.Header, error) {
+	_, hash, err := rpchelper.GetCanonicalBlockNumber(blockNrOrHash, tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	number := rawdb.ReadHeaderNumber(tx, hash)
+	if number == nil {
+		return nil, nil, fmt.Errorf("block %v not found", hash)
+	}
+	header := rawdb.ReadHeader(tx, hash, *number)
+	if header == nil {
+		return nil, nil, fmt.Errorf("header of block %d not found", *number)
+	}
+	if err := rawdb.CheckPruned(tx, modules.AccountChangeSet, *number); err != nil {
+		return nil, nil, err
+	}
+	return state.NewDumper(tx, *number), header, nil
+}
+
+func (n *API) dumpState(ctx context.Context, blockNrOrHash jsonrpc.BlockNumberOrHash, cfg state.DumpConfig) (*StateDump, error) {
+	tx, err := n.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	dumper, header, err := n.StateDumper(tx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	dump := &StateDump{
+		Root:     header.Root,
+		Accounts: make(map[types.Address]*state.DumpAccount),
+	}
+	dump.Next, err = dumper.Dump(cfg, func(account *state.DumpAccount) error {
+		dump.Accounts[account.Address] = account
+		return ctx.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dump, nil
+}
+
+// GetAccount returns the account at the given block, the latest one if none
+// is given, or nil if it does not exist. Its storage is returned from slot
+// storageStart on, at most DumpStorageMaxResults slots of it.
+func (debug *DebugAPI) GetAccount(ctx context.Context, address types.Address, blockNrOrHash *jsonrpc.BlockNumberOrHash, storageStart *types.Hash) (*state.DumpAccount, error) {
+	number := jsonrpc.BlockNumberOrHashWithNumber(jsonrpc.LatestBlockNumber)
+	if blockNrOrHash != nil {
+		number = *blockNrOrHash
+	}
+	cfg := state.DumpConfig{Start: address, Max: 1, MaxStorage: DumpStorageMaxResults}
+	if storageStart != nil {
+		cfg.StorageStart = *storageStart
+	}
+	dump, err := debug.api.dumpState(ctx, number, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return dump.Accounts[address], nil
+}
+
+// AccountRange returns a page of at most maxResults accounts of the state after
+// the given block, starting at address start.
+func (debug *DebugAPI) AccountRange(ctx context.Context, blockNrOrHash jsonrpc.BlockNumberOrHash, start hexutil.Bytes, maxResults int, nocode, nostorage bool) (*StateDump, error) {
+	if maxResults <= 0 || maxResults > AccountRangeMaxResults {
+		maxResults = AccountRangeMaxResults
+	}
+	return debug.api.dumpState(ctx, blockNrOrHash, state.DumpConfig{
+		Start:          types.BytesToAddress(start),
+		Max:            maxResults,
+		ExcludeCode:    nocode,
+		ExcludeStorage: nostorage,
+		MaxStorage:     DumpStorageMaxResults,
+	})
+}
+
+// DumpBlock returns all the accounts of the state after the given block, with
+// at most DumpStorageMaxResults storage slots each.
+func (debug *DebugAPI) DumpBlock(ctx context.Context, blockNr jsonrpc.BlockNumber) (*StateDump, error) {
+	return debug.api.dumpState(ctx, jsonrpc.BlockNumberOrHashWithNumber(blockNr), state.DumpConfig{MaxStorage: DumpStorageMaxResults})
+}
+
+// StorageRangeAt returns a page of at most maxResult storage slots of a
+// contract, starting at slot keyStart, as they were before the transaction at
+// txIndex of the given block was executed.
+func (debug *DebugAPI) StorageRangeAt(ctx context.Context, blockHash types.Hash, txIndex int, contractAddress types.Address, keyStart hexutil.Bytes, maxResult int) (*StorageRangeResult, error) {
+	tx, err := debug.api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	blk, err := rawdb.ReadBlockByHash(tx, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	if blk == nil {
+		return nil, fmt.Errorf("block %v not found", blockHash)
+	}
+	number := blk.Number64().Uint64()
+	if number == 0 {
+		return nil, errors.New("no transaction in genesis")
+	}
+	if txIndex < 0 || txIndex > len(blk.Transactions()) {
+		return nil, fmt.Errorf("transaction index %d out of range", txIndex)
+	}
+	_, _, statedb, err := debug.api.StateAtTransaction(ctx, tx, blk, txIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	// The slots set before the block are read from the state of its parent,
+	// the ones set by earlier transactions of the block can only be among the
+	// slots changed by the block.
+	start := types.BytesToHash(keyStart)
+	var changed []types.Hash
+	if err := changeset.ForRange(tx, modules.StorageChangeSet, number, number+1, func(_ uint64, k, _ []byte) error {
+		if bytes.HasPrefix(k, contractAddress[:]) {
+			if slot := types.BytesToHash(k[types.AddressLength+types.IncarnationLength:]); bytes.Compare(slot[:], start[:]) >= 0 {
+				changed = append(changed, slot)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if maxResult <= 0 || maxResult > AccountRangeMaxResults {
+		maxResult = AccountRangeMaxResults
+	}
+	slots := make(map[types.Hash]struct{})
+	for _, slot := range changed {
+		slots[slot] = struct{}{}
+	}
+	// Changed slots may have been cleared, fetch enough to fill the page anyway.
+	// A contract created by the block has no storage in the parent state.
+	parent := state.NewPlainState(tx, number)
+	acc, err := parent.ReadAccountData(contractAddress)
+	if err != nil {
+		return nil, err
+	}
+	if acc != nil && acc.Incarnation > 0 {
+		if err := parent.ForEachStorage(contractAddress, start, func(key, _ types.Hash, _ uint256.Int) bool {
+			slots[key] = struct{}{}
+			return true
+		}, maxResult+len(changed)+1); err != nil {
+			return nil, err
+		}
+	}
+	keys := make([]types.Hash, 0, len(slots))
+	for slot := range slots {
+		keys = append(keys, slot)
+	}
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i][:], keys[j][:]) < 0 })
+
+	result := &StorageRangeResult{Storage: make(map[types.Hash]StorageEntry)}
+	for _, key := range keys {
+		var value uint256.Int
+		statedb.GetState(contractAddress, &key, &value)
+		if value.IsZero() {
+			continue
+		}
+		if len(result.Storage) == maxResult {
+			next := key
+			result.NextKey = &next
+			break
+		}
+		k := key
+		result.Storage[key] = StorageEntry{Key: &k, Value: value.Bytes32()}
+	}
+	return result, nil
+}
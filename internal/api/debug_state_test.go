@@ -0,0 +1,120 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
+	log2 "github.com/ledgerwatch/log/v3"
+	"github.com/n42blockchain/N42/common/account"
+	"github.com/n42blockchain/N42/common/block"
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/modules"
+	"github.com/n42blockchain/N42/modules/rawdb"
+	"github.com/n42blockchain/N42/modules/rpc/jsonrpc"
+	"github.com/n42blockchain/N42/modules/state"
+)
+
+func slotKey(i int) types.Hash {
+	return types.Hash{30: byte(i >> 8), 31: byte(i)}
+}
+
+func TestDebugGetAccount(t *testing.T) {
+	modules.AstInit()
+	kv.ChaindataTablesCfg = modules.AstTableCfg
+	db := mdbx.NewMDBX(log2.New()).InMem(t.TempDir()).Label(kv.ChainDB).MustOpen()
+	defer db.Close()
+
+	var (
+		addr     = types.Address{1}
+		contract = types.Address{2}
+	)
+	if err := db.Update(context.Background(), func(tx kv.RwTx) error {
+		genesis := block.NewBlock(&block.Header{Number: uint256.NewInt(0), Difficulty: uint256.NewInt(1), BaseFee: uint256.NewInt(0)}, nil).(*block.Block)
+		if err := rawdb.WriteBlock(tx, genesis); err != nil {
+			return err
+		}
+		if err := rawdb.WriteCanonicalHash(tx, genesis.Hash(), 0); err != nil {
+			return err
+		}
+		rawdb.WriteHeadBlockHash(tx, genesis.Hash())
+
+		w := state.NewPlainStateWriterNoHistory(tx)
+		acc := account.NewAccount()
+		acc.Balance.SetUint64(7)
+		if err := w.UpdateAccountData(addr, &account.StateAccount{}, &acc); err != nil {
+			return err
+		}
+		acc = account.NewAccount()
+		acc.Incarnation = 1
+		if err := w.UpdateAccountData(contract, &account.StateAccount{}, &acc); err != nil {
+			return err
+		}
+		for slot := 1; slot <= DumpStorageMaxResults+1; slot++ {
+			key := slotKey(slot)
+			if err := w.WriteAccountStorage(contract, 1, &key, uint256.NewInt(0), uint256.NewInt(1)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	server := jsonrpc.NewServer()
+	if err := server.RegisterName("debug", NewDebugAPI(&API{db: db})); err != nil {
+		t.Fatal(err)
+	}
+	client := jsonrpc.DialInProc(server)
+	defer client.Close()
+
+	// The address alone still reads the latest state.
+	var dump *state.DumpAccount
+	if err := client.Call(&dump, "debug_getAccount", addr); err != nil {
+		t.Fatal(err)
+	}
+	if dump == nil || dump.Balance != "7" {
+		t.Fatalf("have %+v, want balance 7", dump)
+	}
+	dump = nil
+	if err := client.Call(&dump, "debug_getAccount", types.Address{9}, "latest"); err != nil {
+		t.Fatal(err)
+	}
+	if dump != nil {
+		t.Errorf("missing account: have %+v", dump)
+	}
+
+	// Storage beyond the limit is paged.
+	if err := client.Call(&dump, "debug_getAccount", contract, "latest"); err != nil {
+		t.Fatal(err)
+	}
+	last := slotKey(DumpStorageMaxResults + 1)
+	if len(dump.Storage) != DumpStorageMaxResults || dump.NextStorage == nil || *dump.NextStorage != last {
+		t.Fatalf("have %d slots, next %v, want %d slots, next %v", len(dump.Storage), dump.NextStorage, DumpStorageMaxResults, last)
+	}
+	dump = nil
+	if err := client.Call(&dump, "debug_getAccount", contract, "latest", last); err != nil {
+		t.Fatal(err)
+	}
+	if len(dump.Storage) != 1 || dump.NextStorage != nil {
+		t.Errorf("last page: have %d slots, next %v, want 1 slot", len(dump.Storage), dump.NextStorage)
+	}
+}
@@ -12,6 +12,8 @@ import (
 	"github.com/n42blockchain/N42/internal/consensus"
 	vm2 "github.com/n42blockchain/N42/internal/vm"
 	"github.com/n42blockchain/N42/internal/vm/evmtypes"
+	"github.com/n42blockchain/N42/modules"
+	"github.com/n42blockchain/N42/modules/rawdb"
 	"github.com/n42blockchain/N42/modules/rpc/jsonrpc"
 	"math/big"
 )
@@ -103,6 +105,9 @@ func (f *Filter) Logs(ctx context.Context) ([]*block.Log, error) {
 		if header == nil {
 			return nil, errors.New("unknown block")
 		}
+		if err := f.checkPruned(ctx, header.Number64().Uint64()); err != nil {
+			return nil, err
+		}
 		return f.blockLogs(ctx, header)
 	}
 	// Short-cut if all we care about is pending logs
@@ -128,6 +133,11 @@ func (f *Filter) Logs(ctx context.Context) ([]*block.Log, error) {
 	if f.end == jsonrpc.LatestBlockNumber.Int64() || f.end == jsonrpc.PendingBlockNumber.Int64() {
 		end = head
 	}
+	if f.begin >= 0 && uint64(f.begin) <= end {
+		if err := f.checkPruned(ctx, uint64(f.begin)); err != nil {
+			return nil, err
+		}
+	}
 	// Gather all indexed logs, and finish with non indexed ones
 	var (
 		logs           []*block.Log
@@ -157,11 +167,22 @@ func (f *Filter) Logs(ctx context.Context) ([]*block.Log, error) {
 	return logs, err
 }
 
+// checkPruned returns a *rawdb.PrunedError if the receipts of the block, and
+// with them its logs, have been pruned.
+func (f *Filter) checkPruned(ctx context.Context, number uint64) error {
+	return f.db.View(ctx, func(tx kv.Tx) error {
+		return rawdb.CheckPruned(tx, modules.Receipts, number)
+	})
+}
+
 // indexedLogs returns the logs matching the filter criteria based on the bloom
 // bits indexed available locally or via the network.
 func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*block.Log, error) {
 	// Create a matcher session and request servicing from the backend
 	matches := make(chan uint64, 64)
+	// todo: without a bloombits matcher nothing serves the session, so
+	// unindexedLogs handles the whole range instead of waiting on it.
+	close(matches)
 
 	//session, err := f.matcher.Start(ctx, uint64(f.begin), end, matches)
 	//if err != nil {
@@ -0,0 +1,141 @@
+package filters
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
+	log2 "github.com/ledgerwatch/log/v3"
+	"github.com/n42blockchain/N42/common"
+	"github.com/n42blockchain/N42/common/block"
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/modules"
+	"github.com/n42blockchain/N42/modules/rawdb"
+	"github.com/n42blockchain/N42/modules/rpc/jsonrpc"
+)
+
+// testChain serves a chain of headers with one log each. Like the database of
+// a pruned node, it returns no logs at all for blocks below prunedTo.
+type testChain struct {
+	common.IBlockChain
+	headers  []*block.Header
+	prunedTo uint64
+}
+
+func newTestChain(n int, prunedTo uint64) *testChain {
+	c := &testChain{prunedTo: prunedTo}
+	for i := 0; i < n; i++ {
+		c.headers = append(c.headers, &block.Header{Number: uint256.NewInt(uint64(i)), Time: uint64(i)})
+	}
+	return c
+}
+
+func (c *testChain) CurrentBlock() block.IBlock {
+	return block.NewBlock(c.headers[len(c.headers)-1], nil)
+}
+
+func (c *testChain) GetHeaderByNumber(number *uint256.Int) block.IHeader {
+	if number.Uint64() >= uint64(len(c.headers)) {
+		return nil
+	}
+	return c.headers[number.Uint64()]
+}
+
+func (c *testChain) GetHeaderByHash(hash types.Hash) (block.IHeader, error) {
+	for _, h := range c.headers {
+		if h.Hash() == hash {
+			return h, nil
+		}
+	}
+	return nil, nil
+}
+
+func (c *testChain) GetLogs(hash types.Hash) ([][]*block.Log, error) {
+	header, _ := c.GetHeaderByHash(hash)
+	number := header.Number64().Uint64()
+	if number < c.prunedTo {
+		return nil, nil
+	}
+	return [][]*block.Log{{{BlockNumber: uint256.NewInt(number), TxHash: types.Hash{1}}}}, nil
+}
+
+type testApi struct {
+	Api
+	db    kv.RwDB
+	chain *testChain
+}
+
+func (a *testApi) Database() kv.RwDB              { return a.db }
+func (a *testApi) BlockChain() common.IBlockChain { return a.chain }
+
+func newTestApi(t *testing.T, prunedTo uint64) *testApi {
+	modules.AstInit()
+	kv.ChaindataTablesCfg = modules.AstTableCfg
+	db := mdbx.NewMDBX(log2.New()).InMem(t.TempDir()).Label(kv.ChainDB).MustOpen()
+	t.Cleanup(db.Close)
+	if prunedTo > 0 {
+		if err := db.Update(context.Background(), func(tx kv.RwTx) error {
+			return rawdb.WritePruneProgress(tx, modules.Receipts, prunedTo)
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &testApi{db: db, chain: newTestChain(10, prunedTo)}
+}
+
+func TestFilterLogsPruned(t *testing.T) {
+	latest := jsonrpc.LatestBlockNumber.Int64()
+	tests := []struct {
+		prunedTo   uint64
+		begin, end int64
+		logs       int
+		pruned     bool
+	}{
+		{0, 0, latest, 10, false},
+		{5, 5, latest, 5, false},
+		{5, 6, 8, 3, false},
+		{5, latest, latest, 1, false},
+		{5, 0, latest, 0, true},
+		{5, 4, 9, 0, true},
+		// An empty range touches no block.
+		{5, 4, 3, 0, false},
+	}
+	for i, tt := range tests {
+		api := newTestApi(t, tt.prunedTo)
+		logs, err := NewRangeFilter(api, tt.begin, tt.end, nil, nil).Logs(context.Background())
+
+		var pruned *rawdb.PrunedError
+		if errors.As(err, &pruned) != tt.pruned {
+			t.Errorf("test %d: have error %v, want pruned %v", i, err, tt.pruned)
+			continue
+		}
+		if tt.pruned && pruned.AvailableFrom != tt.prunedTo {
+			t.Errorf("test %d: available from %d, want %d", i, pruned.AvailableFrom, tt.prunedTo)
+		}
+		if !tt.pruned && err != nil {
+			t.Errorf("test %d: unexpected error %v", i, err)
+		}
+		if len(logs) != tt.logs {
+			t.Errorf("test %d: have %d logs, want %d", i, len(logs), tt.logs)
+		}
+	}
+}
+
+func TestBlockFilterLogsPruned(t *testing.T) {
+	api := newTestApi(t, 5)
+	for number, pruned := range map[int]bool{2: true, 4: true, 5: false, 9: false} {
+		hash := api.chain.headers[number].Hash()
+		logs, err := NewBlockFilter(api, hash, nil, nil).Logs(context.Background())
+
+		var perr *rawdb.PrunedError
+		if errors.As(err, &perr) != pruned {
+			t.Errorf("block %d: have error %v, want pruned %v", number, err, pruned)
+		}
+		if !pruned && len(logs) != 1 {
+			t.Errorf("block %d: have %d logs, want 1", number, len(logs))
+		}
+	}
+}
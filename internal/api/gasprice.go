@@ -21,10 +21,12 @@ import (
 	lru "github.com/hashicorp/golang-lru"
 	"github.com/holiman/uint256"
 	common2 "github.com/n42blockchain/N42/common"
+	"github.com/n42blockchain/N42/common/block"
 	"github.com/n42blockchain/N42/common/transaction"
 	types2 "github.com/n42blockchain/N42/common/types"
 	"github.com/n42blockchain/N42/conf"
 	"github.com/n42blockchain/N42/internal/avm/types"
+	"github.com/n42blockchain/N42/internal/consensus/misc"
 	"github.com/n42blockchain/N42/log"
 	event "github.com/n42blockchain/N42/modules/event/v2"
 	"github.com/n42blockchain/N42/modules/rpc/jsonrpc"
@@ -41,6 +43,7 @@ const sampleNumber = 3 // Number of transactions sampled in a block
 type Oracle struct {
 	backend     common2.IBlockChain
 	miner       common2.IMiner
+	pool        common2.ITxsPool
 	lastHead    types2.Hash
 	lastPrice   *big.Int
 	maxPrice    *big.Int
@@ -49,6 +52,7 @@ type Oracle struct {
 	fetchLock   sync.Mutex
 
 	checkBlocks, percentile           int
+	pendingTips                       bool
 	maxHeaderHistory, maxBlockHistory int
 	historyCache                      *lru.Cache
 	//
@@ -56,13 +60,26 @@ type Oracle struct {
 }
 
 // NewOracle returns a new gasprice oracle which can recommend suitable
-// gasprice for newly created transaction.
-func NewOracle(backend common2.IBlockChain, miner common2.IMiner, chainConfig *params.ChainConfig, params conf.GpoConfig) *Oracle {
+// gasprice for newly created transaction. If pool is not nil, the pending
+// transactions are sampled along with the recent blocks.
+func NewOracle(backend common2.IBlockChain, miner common2.IMiner, pool common2.ITxsPool, chainConfig *params.ChainConfig, params conf.GpoConfig) *Oracle {
 	blocks := params.Blocks
 	if blocks < 1 {
 		blocks = 1
 		log.Warn("Sanitizing invalid gasprice oracle sample blocks", "provided", params.Blocks, "updated", blocks)
 	}
+	// Blocks are only a few seconds apart on APos chains, widen the sample so that
+	// it spans the configured window instead of a handful of mostly empty blocks.
+	if chainConfig.Apos != nil && chainConfig.Apos.Period > 0 && params.SampleWindow > 0 {
+		if window := int(uint64(params.SampleWindow.Seconds()) / chainConfig.Apos.Period); window > blocks {
+			blocks = window
+		}
+	}
+	defaultPrice := params.Default
+	if defaultPrice == nil || defaultPrice.Sign() < 0 {
+		defaultPrice = new(big.Int)
+		log.Warn("Sanitizing invalid gasprice oracle default price", "provided", params.Default, "updated", defaultPrice)
+	}
 	percent := params.Percentile
 	if percent < 0 {
 		percent = 0
@@ -97,28 +114,34 @@ func NewOracle(backend common2.IBlockChain, miner common2.IMiner, chainConfig *p
 	cache, _ := lru.New(2048)
 
 	highestBlockCh := make(chan common2.ChainHighestBlock)
-	defer close(highestBlockCh)
 	highestSub := event.GlobalEvent.Subscribe(highestBlockCh)
-	defer highestSub.Unsubscribe()
 
 	go func() {
+		defer highestSub.Unsubscribe()
 		var lastHead types2.Hash
-		for ev := range highestBlockCh {
-			if ev.Block.ParentHash() != lastHead {
-				cache.Purge()
+		for {
+			select {
+			case ev := <-highestBlockCh:
+				if ev.Block.ParentHash() != lastHead {
+					cache.Purge()
+				}
+				lastHead = ev.Block.Hash()
+			case <-highestSub.Err():
+				return
 			}
-			lastHead = ev.Block.Hash()
 		}
 	}()
 
 	return &Oracle{
 		backend:          backend,
 		miner:            miner,
-		lastPrice:        params.Default,
+		pool:             pool,
+		lastPrice:        defaultPrice,
 		maxPrice:         maxPrice,
 		ignorePrice:      ignorePrice,
 		checkBlocks:      blocks,
 		percentile:       percent,
+		pendingTips:      params.PendingTips && pool != nil,
 		maxHeaderHistory: maxHeaderHistory,
 		maxBlockHistory:  maxBlockHistory,
 		historyCache:     cache,
@@ -199,6 +222,9 @@ func (oracle *Oracle) SuggestTipCap(ctx context.Context, chainConfig *params.Cha
 		}
 		results = append(results, res.values...)
 	}
+	if oracle.pendingTips {
+		results = append(results, oracle.getPendingValues(head)...)
+	}
 	price := lastPrice
 	if len(results) > 0 {
 		sort.Sort(bigIntArray(results))
@@ -215,6 +241,30 @@ func (oracle *Oracle) SuggestTipCap(ctx context.Context, chainConfig *params.Cha
 	return new(big.Int).Set(price), nil
 }
 
+// getPendingValues returns the effective tips the executable pool transactions pay
+// on top of the next block's base fee, one per sender as the lowest nonce gates the rest.
+func (oracle *Oracle) getPendingValues(head block.IHeader) []*big.Int {
+	var baseFee *uint256.Int
+	if h, ok := head.(*block.Header); ok && oracle.chainConfig.IsLondon(h.Number.Uint64()+1) {
+		baseFee, _ = uint256.FromBig(misc.CalcBaseFee(oracle.chainConfig, h))
+	}
+	var prices []*big.Int
+	for _, txs := range oracle.pool.Pending(false) {
+		if len(txs) == 0 {
+			continue
+		}
+		tip, err := txs[0].EffectiveGasTip(baseFee)
+		if err != nil {
+			continue
+		}
+		if oracle.ignorePrice != nil && tip.ToBig().Cmp(oracle.ignorePrice) < 0 {
+			continue
+		}
+		prices = append(prices, tip.ToBig())
+	}
+	return prices
+}
+
 type results struct {
 	values []*big.Int
 	err    error
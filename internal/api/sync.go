@@ -0,0 +1,107 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/n42blockchain/N42/common"
+	"github.com/n42blockchain/N42/common/hexutil"
+	"github.com/n42blockchain/N42/internal/sync/progress"
+	event "github.com/n42blockchain/N42/modules/event/v2"
+	"github.com/n42blockchain/N42/modules/rpc/jsonrpc"
+)
+
+// syncCheckInterval is the interval at which the syncing subscription reports
+// the progress of a running synchronization.
+const syncCheckInterval = 3 * time.Second
+
+// SyncAPI provides the syncing subscription of the eth namespace.
+type SyncAPI struct {
+	api *API
+}
+
+// NewSyncAPI creates a new sync API.
+func NewSyncAPI(api *API) *SyncAPI {
+	return &SyncAPI{api}
+}
+
+// SyncingResult is the notification of the syncing subscription while the
+// node is syncing.
+type SyncingResult struct {
+	Syncing bool                   `json:"syncing"`
+	Status  map[string]interface{} `json:"status"`
+}
+
+// Syncing notifies the progress of the synchronization when it starts and
+// while it runs, and false when it finishes.
+func (s *SyncAPI) Syncing(ctx context.Context) (*jsonrpc.Subscription, error) {
+	notifier, supported := jsonrpc.NotifierFromContext(ctx)
+	if !supported {
+		return &jsonrpc.Subscription{}, jsonrpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		startCh := make(chan common.DownloaderStartEvent)
+		startSub := event.GlobalEvent.Subscribe(startCh)
+		defer startSub.Unsubscribe()
+		ticker := time.NewTicker(syncCheckInterval)
+		defer ticker.Stop()
+
+		var (
+			wasSyncing bool
+			last       progress.Progress
+		)
+		check := func() {
+			prog, syncing := s.api.syncTracker.Progress()
+			switch {
+			case syncing && (!wasSyncing || prog != last):
+				notifier.Notify(rpcSub.ID, &SyncingResult{Syncing: true, Status: marshalSyncProgress(prog)})
+			case !syncing && wasSyncing:
+				notifier.Notify(rpcSub.ID, false)
+			}
+			wasSyncing, last = syncing, prog
+		}
+		check()
+		for {
+			select {
+			case <-startCh:
+				check()
+			case <-ticker.C:
+				check()
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// marshalSyncProgress returns the RPC representation of a sync progress.
+func marshalSyncProgress(prog progress.Progress) map[string]interface{} {
+	return map[string]interface{}{
+		"startingBlock": hexutil.Uint64(prog.StartingBlock),
+		"currentBlock":  hexutil.Uint64(prog.CurrentBlock),
+		"highestBlock":  hexutil.Uint64(prog.HighestBlock),
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"encoding/json"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/n42blockchain/N42/common"
+	"github.com/n42blockchain/N42/common/hexutil"
+	"github.com/n42blockchain/N42/internal/sync/progress"
+	event "github.com/n42blockchain/N42/modules/event/v2"
+	"github.com/n42blockchain/N42/modules/rpc/jsonrpc"
+)
+
+func TestSyncing(t *testing.T) {
+	tests := []struct {
+		name             string
+		current, highest uint64
+		want             interface{}
+	}{
+		{"in sync", 10, 10 + progress.MaxLag, false},
+		{"behind", 10, 11 + progress.MaxLag, map[string]interface{}{
+			"startingBlock": hexutil.Uint64(10),
+			"currentBlock":  hexutil.Uint64(10),
+			"highestBlock":  hexutil.Uint64(11 + progress.MaxLag),
+		}},
+	}
+	for _, tt := range tests {
+		tracker := progress.NewTracker(func() uint64 { return tt.current }, func() uint64 { return tt.highest })
+		have, err := NewBlockChainAPI(&API{syncTracker: tracker}).Syncing()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		haveJSON, _ := json.Marshal(have)
+		wantJSON, _ := json.Marshal(tt.want)
+		if string(haveJSON) != string(wantJSON) {
+			t.Errorf("%s: have %s, want %s", tt.name, haveJSON, wantJSON)
+		}
+	}
+	// Without a tracker the node always reports to be in sync.
+	if have, err := NewBlockChainAPI(&API{}).Syncing(); err != nil || have != false {
+		t.Errorf("without tracker: have %v, %v, want false", have, err)
+	}
+}
+
+func TestSyncingSubscription(t *testing.T) {
+	var highest atomic.Uint64
+	highest.Store(100)
+	tracker := progress.NewTracker(func() uint64 { return 10 }, highest.Load)
+
+	server := jsonrpc.NewServer()
+	if err := server.RegisterName("eth", NewSyncAPI(&API{syncTracker: tracker})); err != nil {
+		t.Fatal(err)
+	}
+	// Subscriptions are read off the raw connection, the client doesn't deliver them.
+	serverConn, conn := net.Pipe()
+	go server.ServeCodec(jsonrpc.NewCodec(serverConn), 0)
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(10 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	enc, dec := json.NewEncoder(conn), json.NewDecoder(conn)
+
+	if err := enc.Encode(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": "eth_subscribe", "params": []string{"syncing"}}); err != nil {
+		t.Fatal(err)
+	}
+	var resp struct {
+		Result string `json:"result"`
+	}
+	if err := dec.Decode(&resp); err != nil || resp.Result == "" {
+		t.Fatalf("subscription failed: %v, %+v", err, resp)
+	}
+	type notification struct {
+		Method string `json:"method"`
+		Params struct {
+			Subscription string          `json:"subscription"`
+			Result       json.RawMessage `json:"result"`
+		} `json:"params"`
+	}
+
+	// The subscription reports the running synchronization right away.
+	var n notification
+	if err := dec.Decode(&n); err != nil {
+		t.Fatal(err)
+	}
+	var result SyncingResult
+	if err := json.Unmarshal(n.Params.Result, &result); err != nil {
+		t.Fatalf("syncing notification %s: %v", n.Params.Result, err)
+	}
+	if n.Method != "eth_subscription" || n.Params.Subscription != resp.Result || !result.Syncing ||
+		result.Status["currentBlock"] != "0xa" || result.Status["highestBlock"] != "0x64" {
+		t.Errorf("syncing notification mismatch: method %s, subscription %s, result %s", n.Method, n.Params.Subscription, n.Params.Result)
+	}
+
+	// A sync start makes it check the progress again, which has finished.
+	highest.Store(10)
+	event.GlobalEvent.Send(common.DownloaderStartEvent{})
+	if err := dec.Decode(&n); err != nil {
+		t.Fatal(err)
+	}
+	if string(n.Params.Result) != "false" {
+		t.Errorf("finished notification mismatch: have %s, want false", n.Params.Result)
+	}
+}
@@ -72,6 +72,7 @@ type RPCTransaction struct {
 	Type             hexutil.Uint64        `json:"type"`
 	Accesses         *mvm_types.AccessList `json:"accessList,omitempty"`
 	ChainID          *hexutil.Big          `json:"chainId,omitempty"`
+	FeePayer         *mvm_common.Address   `json:"feePayer,omitempty"`
 	V                *hexutil.Big          `json:"v"`
 	R                *hexutil.Big          `json:"r"`
 	S                *hexutil.Big          `json:"s"`
@@ -357,10 +358,11 @@ func newRPCTransaction(tx *transaction.Transaction, blockHash types.Hash, blockN
 		//al := tx.AccessList()
 		//result.Accesses = &al
 		result.ChainID = (*hexutil.Big)(tx.ChainId().ToBig())
-	case transaction.DynamicFeeTxType:
+	case transaction.DynamicFeeTxType, transaction.SakuragiTxType:
 		// todo copy al
 		//al := tx.AccessList()
 		//result.Accesses = &al
+		result.FeePayer = mvm_types.FromastAddress(tx.FeePayer())
 		result.ChainID = (*hexutil.Big)(tx.ChainId().ToBig())
 		result.GasFeeCap = (*hexutil.Big)(tx.GasFeeCap().ToBig())
 		result.GasTipCap = (*hexutil.Big)(tx.GasTipCap().ToBig())
@@ -0,0 +1,265 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/n42blockchain/N42/accounts"
+	"github.com/n42blockchain/N42/common/block"
+	"github.com/n42blockchain/N42/common/hexutil"
+	"github.com/n42blockchain/N42/common/transaction"
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/internal/aa"
+	mvm_common "github.com/n42blockchain/N42/internal/avm/common"
+	mvm_types "github.com/n42blockchain/N42/internal/avm/types"
+	"github.com/n42blockchain/N42/internal/consensus/misc"
+	vm2 "github.com/n42blockchain/N42/internal/vm"
+	"github.com/n42blockchain/N42/modules/rpc/jsonrpc"
+)
+
+// UserOperationAPI provides the ERC-4337 bundler RPCs on top of the user operation pool.
+type UserOperationAPI struct {
+	api *API
+}
+
+// NewUserOperationAPI creates a new user operation API.
+func NewUserOperationAPI(api *API) *UserOperationAPI {
+	return &UserOperationAPI{api}
+}
+
+// UserOperationGasEstimate is the result of eth_estimateUserOperationGas.
+type UserOperationGasEstimate struct {
+	PreVerificationGas   hexutil.Uint64 `json:"preVerificationGas"`
+	VerificationGasLimit hexutil.Uint64 `json:"verificationGasLimit"`
+	CallGasLimit         hexutil.Uint64 `json:"callGasLimit"`
+}
+
+// SupportedEntryPoints returns the entry points served by the bundler.
+func (s *UserOperationAPI) SupportedEntryPoints() []mvm_common.Address {
+	entryPoint := s.api.userOpPool.EntryPoint()
+	return []mvm_common.Address{*mvm_types.FromastAddress(&entryPoint)}
+}
+
+// SendUserOperation validates an operation by simulating it against the entry
+// point and adds it to the pool, returning its user operation hash.
+func (s *UserOperationAPI) SendUserOperation(ctx context.Context, op aa.UserOperation, entryPoint mvm_common.Address) (mvm_common.Hash, error) {
+	pool := s.api.userOpPool
+	if err := s.checkEntryPoint(entryPoint); err != nil {
+		return mvm_common.Hash{}, err
+	}
+	if err := pool.ValidateStatic(&op); err != nil {
+		return mvm_common.Hash{}, err
+	}
+	result, err := s.simulateValidation(ctx, &op)
+	if err != nil {
+		return mvm_common.Hash{}, err
+	}
+	if result.SigFailed {
+		return mvm_common.Hash{}, errors.New("invalid user operation signature")
+	}
+	if result.ValidUntil != 0 && result.ValidUntil < uint64(time.Now().Unix()) {
+		return mvm_common.Hash{}, fmt.Errorf("user operation expired at %d", result.ValidUntil)
+	}
+	hash, err := pool.Add(&op)
+	if err != nil {
+		return mvm_common.Hash{}, err
+	}
+	return mvm_types.FromastHash(hash), nil
+}
+
+// EstimateUserOperationGas estimates the gas fields of a possibly unsigned
+// operation. The verification gas is measured by simulating the validation with
+// the maximal allowance, the call gas by estimating the account call.
+func (s *UserOperationAPI) EstimateUserOperationGas(ctx context.Context, op aa.UserOperation, entryPoint mvm_common.Address) (*UserOperationGasEstimate, error) {
+	pool := s.api.userOpPool
+	if err := s.checkEntryPoint(entryPoint); err != nil {
+		return nil, err
+	}
+	preVerificationGas := aa.CalcPreVerificationGas(&op)
+
+	sim := op.Copy()
+	sim.PreVerificationGas = uint256.NewInt(preVerificationGas)
+	sim.VerificationGasLimit = uint256.NewInt(pool.Config().MaxVerificationGas)
+	// Fees don't affect validation, zero them so the sender needs no deposit.
+	sim.MaxFeePerGas, sim.MaxPriorityFeePerGas = new(uint256.Int), new(uint256.Int)
+	result, err := s.simulateValidation(ctx, sim)
+	if err != nil {
+		return nil, err
+	}
+	verificationGas := result.PreOpGas.Uint64() - preVerificationGas
+	// Leave room for the signature check of the signed operation.
+	verificationGas += verificationGas / 10
+
+	from := pool.EntryPoint()
+	data := hexutil.Bytes(op.CallData)
+	callGas, err := DoEstimateGas(ctx, s.api, TransactionArgs{
+		From: mvm_types.FromastAddress(&from),
+		To:   mvm_types.FromastAddress(&op.Sender),
+		Data: &data,
+	}, jsonrpc.BlockNumberOrHashWithNumber(jsonrpc.LatestBlockNumber), rpcGasCap)
+	if err != nil {
+		return nil, fmt.Errorf("call gas estimation failed: %w", err)
+	}
+	return &UserOperationGasEstimate{
+		PreVerificationGas:   hexutil.Uint64(preVerificationGas),
+		VerificationGasLimit: hexutil.Uint64(verificationGas),
+		CallGasLimit:         callGas,
+	}, nil
+}
+
+// GetUserOperationReceipt returns the execution result of an operation along
+// with the receipt of the bundle transaction that included it, or nil if the
+// operation has not been executed yet.
+//
+// Receipts are kept in memory for the most recent operations executed since the
+// node started. Older operations return nil, their UserOperationEvent can still
+// be found with eth_getLogs on the entry point.
+func (s *UserOperationAPI) GetUserOperationReceipt(ctx context.Context, hash mvm_common.Hash) (map[string]interface{}, error) {
+	ev := s.api.userOpPool.Receipt(mvm_types.ToastHash(hash))
+	if ev == nil {
+		return nil, nil
+	}
+	receipt, err := NewTransactionAPI(s.api, new(AddrLocker)).GetTransactionReceipt(ctx, mvm_types.FromastHash(ev.Log.TxHash))
+	if err != nil {
+		return nil, err
+	}
+	entryPoint := s.api.userOpPool.EntryPoint()
+	fields := map[string]interface{}{
+		"userOpHash":    hash,
+		"entryPoint":    mvm_types.FromastAddress(&entryPoint),
+		"sender":        mvm_types.FromastAddress(&ev.Sender),
+		"nonce":         (*hexutil.Big)(ev.Nonce),
+		"paymaster":     mvm_types.FromastAddress(&ev.Paymaster),
+		"actualGasCost": (*hexutil.Big)(ev.ActualGasCost),
+		"actualGasUsed": (*hexutil.Big)(ev.ActualGasUsed),
+		"success":       ev.Success,
+		"logs":          []*block.Log{ev.Log},
+		"receipt":       receipt,
+	}
+	return fields, nil
+}
+
+func (s *UserOperationAPI) checkEntryPoint(entryPoint mvm_common.Address) error {
+	if *mvm_types.ToastAddress(&entryPoint) != s.api.userOpPool.EntryPoint() {
+		return fmt.Errorf("%w: %v", aa.ErrUnsupportedEntryPoint, entryPoint)
+	}
+	return nil
+}
+
+// simulateValidation runs the entry point's simulateValidation for op on the
+// latest state, enforcing the opcode rules on the account, factory and paymaster.
+func (s *UserOperationAPI) simulateValidation(ctx context.Context, op *aa.UserOperation) (*aa.ValidationResult, error) {
+	data, err := aa.PackSimulateValidation(op)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		entryPoint = s.api.userOpPool.EntryPoint()
+		input      = hexutil.Bytes(data)
+		gas        = hexutil.Uint64(rpcGasCap)
+		tracer     = aa.NewValidationTracer(op)
+	)
+	args := TransactionArgs{
+		To:   mvm_types.FromastAddress(&entryPoint),
+		Gas:  &gas,
+		Data: &input,
+	}
+	result, err := doCall(ctx, s.api, args, jsonrpc.BlockNumberOrHashWithNumber(jsonrpc.LatestBlockNumber), nil, rpcEVMTimeout, rpcGasCap,
+		&vm2.Config{NoBaseFee: true, Debug: true, Tracer: tracer})
+	if err != nil {
+		return nil, err
+	}
+	if err := tracer.Err(); err != nil {
+		return nil, err
+	}
+	// simulateValidation always reverts, with either the result or the failure.
+	if failed := aa.UnpackFailedOp(result.Revert()); failed != nil {
+		return nil, failed
+	}
+	return aa.UnpackValidationResult(result.Revert())
+}
+
+// userOpBackend gives the bundler access to the chain through the API.
+type userOpBackend struct {
+	api *API
+}
+
+// NewUserOpBackend returns the bundler backend of api.
+func NewUserOpBackend(api *API) aa.Backend {
+	return &userOpBackend{api}
+}
+
+func (b *userOpBackend) BaseFee() *uint256.Int {
+	header, ok := b.api.BlockChain().CurrentBlock().Header().(*block.Header)
+	if !ok || !b.api.GetChainConfig().IsLondon(header.Number.Uint64()+1) {
+		return nil
+	}
+	baseFee, _ := uint256.FromBig(misc.CalcBaseFee(b.api.GetChainConfig(), header))
+	return baseFee
+}
+
+func (b *userOpBackend) EstimateGas(ctx context.Context, from, to types.Address, data []byte) (uint64, []byte, error) {
+	input := hexutil.Bytes(data)
+	gas, err := DoEstimateGas(ctx, b.api, TransactionArgs{
+		From: mvm_types.FromastAddress(&from),
+		To:   mvm_types.FromastAddress(&to),
+		Data: &input,
+	}, jsonrpc.BlockNumberOrHashWithNumber(jsonrpc.LatestBlockNumber), rpcGasCap)
+	if err != nil {
+		var revert *revertError
+		if errors.As(err, &revert) {
+			data, _ := hexutil.Decode(revert.reason)
+			return 0, data, err
+		}
+		return 0, nil, err
+	}
+	return uint64(gas), nil, nil
+}
+
+func (b *userOpBackend) SendTransaction(ctx context.Context, from, to types.Address, data []byte, gas uint64, feeCap, tip *uint256.Int) (types.Hash, error) {
+	account := accounts.Account{Address: from}
+	wallet, err := b.api.accountManager.Find(account)
+	if err != nil {
+		return types.Hash{}, fmt.Errorf("bundler account %v is not a local account: %w", from, err)
+	}
+	chainID, _ := uint256.FromBig(b.api.GetChainConfig().ChainID)
+	tx := transaction.NewTx(&transaction.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     b.api.TxsPool().Nonce(from),
+		GasTipCap: tip,
+		GasFeeCap: feeCap,
+		Gas:       gas,
+		To:        &to,
+		From:      &from,
+		Value:     new(uint256.Int),
+		Data:      data,
+	})
+	signed, err := wallet.SignTx(account, tx, b.api.GetChainConfig().ChainID)
+	if err != nil {
+		return types.Hash{}, err
+	}
+	hash, err := SubmitTransaction(ctx, b.api, signed)
+	if err != nil {
+		return types.Hash{}, err
+	}
+	return mvm_types.ToastHash(hash), nil
+}
@@ -9,6 +9,10 @@ const (
 	LegacyTxType = iota
 	AccessListTxType
 	DynamicFeeTxType
+
+	// SakuragiTxType is numbered clear of the types Ethereum has assigned
+	// (0x03 blob, 0x04 set code) and is likely to assign next.
+	SakuragiTxType = 0x10
 )
 
 type TxData interface {
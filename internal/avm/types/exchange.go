@@ -310,6 +310,10 @@ func (tx *Transaction) decodeTyped(b []byte) (TxData, error) {
 		var inner DynamicFeeTx
 		err := rlp.DecodeBytes(b[1:], &inner)
 		return &inner, err
+	case SakuragiTxType:
+		var inner SakuragiTx
+		err := rlp.DecodeBytes(b[1:], &inner)
+		return &inner, err
 	default:
 		return nil, fmt.Errorf("transaction type not valid in this context")
 	}
@@ -401,6 +405,33 @@ func (tx *Transaction) ToastTransaction(chainConfig *params.ChainConfig, blockNu
 		dft.GasFeeCap, _ = uint256.FromBig(tx.GasFeeCap())
 		inner = dft
 		log.Debug("tx type is DynamicFeeTxType")
+	case SakuragiTxType:
+		itx := tx.inner.(*SakuragiTx)
+		if itx.FeePayerV == nil || itx.FeePayerR == nil || itx.FeePayerS == nil ||
+			itx.FeePayerV.BitLen() > 8 || itx.FeePayerR.BitLen() > 256 || itx.FeePayerS.BitLen() > 256 {
+			return nil, transaction.ErrInvalidFeePayer
+		}
+		stx := &transaction.SakuragiTx{
+			Nonce:      tx.Nonce(),
+			Gas:        tx.Gas(),
+			To:         ToastAddress(tx.To()),
+			Data:       common.CopyBytes(tx.Data()),
+			AccessList: ToastAccessList(tx.AccessList()),
+			Value:      vl,
+			From:       ToastAddress(&from),
+			FeePayer:   ToastAddress(&itx.FeePayer),
+			Sign:       itx.feePayerSignature(),
+			V:          V,
+			R:          R,
+			S:          S,
+		}
+		stx.ChainID, _ = uint256.FromBig(tx.ChainId())
+		stx.GasTipCap, _ = uint256.FromBig(tx.GasTipCap())
+		stx.GasFeeCap, _ = uint256.FromBig(tx.GasFeeCap())
+		inner = stx
+		log.Debug("tx type is SakuragiTxType")
+	default:
+		return nil, transaction.ErrTxTypeNotSupported
 	}
 
 	astTx := transaction.NewTx(inner)
@@ -451,6 +482,28 @@ func (tx *Transaction) FromastTransaction(astTx *transaction.Transaction) {
 		dft.GasTipCap = astTx.GasTipCap().ToBig()
 		dft.GasFeeCap = astTx.GasFeeCap().ToBig()
 		inner = dft
+
+	case transaction.SakuragiTxType:
+		stx := &SakuragiTx{
+			Nonce:      astTx.Nonce(),
+			Gas:        astTx.Gas(),
+			To:         FromastAddress(astTx.To()),
+			Data:       common.CopyBytes(astTx.Data()),
+			AccessList: FromastAccessList(astTx.AccessList()),
+			Value:      vl,
+		}
+		stx.ChainID = astTx.ChainId().ToBig()
+		stx.GasTipCap = astTx.GasTipCap().ToBig()
+		stx.GasFeeCap = astTx.GasFeeCap().ToBig()
+		if payer := astTx.FeePayer(); payer != nil {
+			stx.FeePayer = *FromastAddress(payer)
+		}
+		if sig := astTx.Sign(); len(sig) == crypto.SignatureLength {
+			stx.FeePayerR = new(big.Int).SetBytes(sig[:32])
+			stx.FeePayerS = new(big.Int).SetBytes(sig[32:64])
+			stx.FeePayerV = new(big.Int).SetUint64(uint64(sig[64]))
+		}
+		inner = stx
 	}
 
 	v, r, s := astTx.RawSignatureValues()
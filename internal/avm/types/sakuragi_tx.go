@@ -0,0 +1,127 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+
+	"github.com/n42blockchain/N42/internal/avm/common"
+)
+
+// SakuragiTx is the canonical encoding of a fee-delegated transaction: a
+// dynamic fee transaction signed by its sender, followed by the fee payer and
+// its signature over the signed payload.
+type SakuragiTx struct {
+	ChainID    *big.Int
+	Nonce      uint64
+	GasTipCap  *big.Int // a.k.a. maxPriorityFeePerGas
+	GasFeeCap  *big.Int // a.k.a. maxFeePerGas
+	Gas        uint64
+	To         *common.Address `rlp:"nil"` // nil means contract creation
+	Value      *big.Int
+	Data       []byte
+	AccessList AccessList
+
+	// Sender signature values
+	V *big.Int `json:"v" gencodec:"required"`
+	R *big.Int `json:"r" gencodec:"required"`
+	S *big.Int `json:"s" gencodec:"required"`
+
+	FeePayer common.Address
+
+	// Fee payer signature values
+	FeePayerV *big.Int `json:"feePayerV" gencodec:"required"`
+	FeePayerR *big.Int `json:"feePayerR" gencodec:"required"`
+	FeePayerS *big.Int `json:"feePayerS" gencodec:"required"`
+}
+
+// copy creates a deep copy of the transaction data and initializes all fields.
+func (tx *SakuragiTx) copy() TxData {
+	cpy := &SakuragiTx{
+		Nonce:    tx.Nonce,
+		To:       copyAddressPtr(tx.To),
+		Data:     common.CopyBytes(tx.Data),
+		Gas:      tx.Gas,
+		FeePayer: tx.FeePayer,
+		// These are copied below.
+		AccessList: make(AccessList, len(tx.AccessList)),
+		Value:      new(big.Int),
+		ChainID:    new(big.Int),
+		GasTipCap:  new(big.Int),
+		GasFeeCap:  new(big.Int),
+		V:          new(big.Int),
+		R:          new(big.Int),
+		S:          new(big.Int),
+		FeePayerV:  new(big.Int),
+		FeePayerR:  new(big.Int),
+		FeePayerS:  new(big.Int),
+	}
+	copy(cpy.AccessList, tx.AccessList)
+	for _, v := range []struct{ dst, src *big.Int }{
+		{cpy.Value, tx.Value},
+		{cpy.ChainID, tx.ChainID},
+		{cpy.GasTipCap, tx.GasTipCap},
+		{cpy.GasFeeCap, tx.GasFeeCap},
+		{cpy.V, tx.V},
+		{cpy.R, tx.R},
+		{cpy.S, tx.S},
+		{cpy.FeePayerV, tx.FeePayerV},
+		{cpy.FeePayerR, tx.FeePayerR},
+		{cpy.FeePayerS, tx.FeePayerS},
+	} {
+		if v.src != nil {
+			v.dst.Set(v.src)
+		}
+	}
+	return cpy
+}
+
+// accessors for innerTx.
+func (tx *SakuragiTx) txType() byte           { return SakuragiTxType }
+func (tx *SakuragiTx) chainID() *big.Int      { return tx.ChainID }
+func (tx *SakuragiTx) accessList() AccessList { return tx.AccessList }
+func (tx *SakuragiTx) data() []byte           { return tx.Data }
+func (tx *SakuragiTx) gas() uint64            { return tx.Gas }
+func (tx *SakuragiTx) gasFeeCap() *big.Int    { return tx.GasFeeCap }
+func (tx *SakuragiTx) gasTipCap() *big.Int    { return tx.GasTipCap }
+func (tx *SakuragiTx) gasPrice() *big.Int     { return tx.GasFeeCap }
+func (tx *SakuragiTx) value() *big.Int        { return tx.Value }
+func (tx *SakuragiTx) nonce() uint64          { return tx.Nonce }
+func (tx *SakuragiTx) to() *common.Address    { return tx.To }
+
+func (tx *SakuragiTx) rawSignatureValues() (v, r, s *big.Int) {
+	return tx.V, tx.R, tx.S
+}
+
+func (tx *SakuragiTx) setSignatureValues(chainID, v, r, s *big.Int) {
+	tx.ChainID, tx.V, tx.R, tx.S = chainID, v, r, s
+}
+
+// feePayerSignature returns the fee payer signature in the [R || S || V] format.
+func (tx *SakuragiTx) feePayerSignature() []byte {
+	sig := make([]byte, 65)
+	if tx.FeePayerR != nil {
+		tx.FeePayerR.FillBytes(sig[:32])
+	}
+	if tx.FeePayerS != nil {
+		tx.FeePayerS.FillBytes(sig[32:64])
+	}
+	if tx.FeePayerV != nil {
+		sig[64] = byte(tx.FeePayerV.Uint64())
+	}
+	return sig
+}
@@ -53,7 +53,57 @@ func NewBlockValidator(config *params.ChainConfig, blockchain *BlockChain, engin
 // header's transaction and uncle roots. The headers are assumed to be already
 // validated at this point.
 func (v *BlockValidator) ValidateBody(b block.IBlock) error {
-	// Check Signature valid
+	if err := v.ValidateSignature(b); err != nil {
+		return err
+	}
+
+	// Check whether the block's known, and if not, that it's linkable
+	if v.bc.HasBlockAndState(b.Hash(), b.Number64().Uint64()) {
+		return ErrKnownBlock
+	}
+
+	if err := validateTxRoot(b); err != nil {
+		return err
+	}
+	if err := validateTxTypes(v.config, b); err != nil {
+		return err
+	}
+
+	if !v.bc.HasBlockAndState(b.ParentHash(), b.Number64().Uint64()-1) {
+		if !v.bc.HasBlock(b.ParentHash(), b.Number64().Uint64()-1) {
+			return ErrUnknownAncestor
+		}
+		return ErrPrunedAncestor
+	}
+	return nil
+}
+
+// validateTxRoot checks the transaction root of the header against the
+// transactions of the block body.
+func validateTxRoot(b block.IBlock) error {
+	if hash := DeriveSha(transaction.Transactions(b.Transactions())); hash != b.TxHash() {
+		return fmt.Errorf("transaction root hash mismatch: have %x, want %x", hash, b.TxHash())
+	}
+	return nil
+}
+
+// validateTxTypes checks that the block carries no fee-delegated transactions
+// before the Sakuragi upgrade.
+func validateTxTypes(config *params.ChainConfig, b block.IBlock) error {
+	if config.IsSakuragi(b.Number64().Uint64()) {
+		return nil
+	}
+	for i, tx := range b.Transactions() {
+		if tx.Type() == transaction.SakuragiTxType {
+			return fmt.Errorf("%w: transaction %d of type %d before the Sakuragi upgrade", ErrTxTypeNotSupported, i, tx.Type())
+		}
+	}
+	return nil
+}
+
+// ValidateSignature verifies the aggregate signature of the block verifiers
+// over the state root of the header.
+func (v *BlockValidator) ValidateSignature(b block.IBlock) error {
 	vfs := b.Body().Verifier()
 	addrs := make([]types.Address, len(vfs))
 	ss := make([]bls.PublicKey, len(vfs))
@@ -80,22 +130,6 @@ func (v *BlockValidator) ValidateBody(b block.IBlock) error {
 			return fmt.Errorf("AggSignature verify falied")
 		}
 	}
-
-	// Check whether the block's known, and if not, that it's linkable
-	if v.bc.HasBlockAndState(b.Hash(), b.Number64().Uint64()) {
-		return ErrKnownBlock
-	}
-
-	if hash := DeriveSha(transaction.Transactions(b.Transactions())); hash != b.TxHash() {
-		return fmt.Errorf("transaction root hash mismatch: have %x, want %x", hash, b.TxHash())
-	}
-
-	if !v.bc.HasBlockAndState(b.ParentHash(), b.Number64().Uint64()-1) {
-		if !v.bc.HasBlock(b.ParentHash(), b.Number64().Uint64()-1) {
-			return ErrUnknownAncestor
-		}
-		return ErrPrunedAncestor
-	}
 	return nil
 }
 
@@ -0,0 +1,59 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package internal
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/n42blockchain/N42/common/block"
+	"github.com/n42blockchain/N42/common/transaction"
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/params"
+)
+
+func TestValidateTxTypes(t *testing.T) {
+	from, payer, to := types.Address{0xaa}, types.Address{0xbb}, types.Address{0x01}
+	sponsored := transaction.NewTx(&transaction.SakuragiTx{
+		ChainID:   uint256.NewInt(1),
+		GasTipCap: uint256.NewInt(1),
+		GasFeeCap: uint256.NewInt(2),
+		Gas:       21000,
+		To:        &to,
+		From:      &from,
+		Value:     uint256.NewInt(3),
+		FeePayer:  &payer,
+	})
+	config := &params.ChainConfig{ChainID: big.NewInt(1), SakuragiBlock: big.NewInt(10)}
+
+	tests := []struct {
+		name   string
+		number uint64
+		want   error
+	}{
+		{"before the fork", 9, ErrTxTypeNotSupported},
+		{"at the fork", 10, nil},
+	}
+	for _, tt := range tests {
+		b := block.NewBlock(&block.Header{Number: uint256.NewInt(tt.number)}, []*transaction.Transaction{sponsored})
+		if err := validateTxTypes(config, b); !errors.Is(err, tt.want) {
+			t.Errorf("%s: error %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
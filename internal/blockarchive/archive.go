@@ -0,0 +1,235 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package blockarchive implements a portable, streaming archive of canonical
+// blocks used to move a chain between nodes without the p2p network.
+//
+// An archive starts with the magic string "N42BLKARCH", a version byte and the
+// hash of the genesis block the chain builds on. It is followed by the blocks in
+// ascending order, each as a uvarint length and the protobuf encoding of the
+// block, which includes the header, the transactions, the verifiers and the
+// rewards. Archives whose file name ends in ".gz" are gzip compressed.
+package blockarchive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/n42blockchain/N42/common/block"
+	"github.com/n42blockchain/N42/common/types"
+)
+
+const (
+	magic   = "N42BLKARCH"
+	version = 1
+
+	// maxBlockSize is the largest encoded block accepted, guarding against
+	// allocating for a corrupt length prefix.
+	maxBlockSize = 64 * 1024 * 1024
+)
+
+var (
+	ErrBadMagic          = errors.New("not a block archive")
+	ErrVersion           = errors.New("unsupported block archive version")
+	ErrBlockTooLarge     = errors.New("archived block too large")
+	ErrGenesisMismatch   = errors.New("archive built on a different genesis block")
+	ErrImportInterrupted = errors.New("block import interrupted")
+)
+
+// Writer appends blocks to an archive.
+type Writer struct {
+	buf     *bufio.Writer
+	closers []io.Closer
+	lenBuf  [binary.MaxVarintLen64]byte
+}
+
+// NewWriter writes the archive header for a chain with the given genesis to w
+// and returns a writer for its blocks.
+func NewWriter(w io.Writer, genesis types.Hash) (*Writer, error) {
+	aw := &Writer{buf: bufio.NewWriter(w)}
+	if err := aw.writeHeader(genesis); err != nil {
+		return nil, err
+	}
+	return aw, nil
+}
+
+// Create creates the archive file at path, compressing it if the name ends in ".gz".
+func Create(path string, genesis types.Hash) (*Writer, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		w       io.Writer = f
+		closers           = []io.Closer{f}
+	)
+	if strings.HasSuffix(path, ".gz") {
+		gz := gzip.NewWriter(f)
+		w, closers = gz, []io.Closer{gz, f}
+	}
+	aw := &Writer{buf: bufio.NewWriter(w), closers: closers}
+	if err := aw.writeHeader(genesis); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return aw, nil
+}
+
+func (w *Writer) writeHeader(genesis types.Hash) error {
+	if _, err := w.buf.WriteString(magic); err != nil {
+		return err
+	}
+	if err := w.buf.WriteByte(version); err != nil {
+		return err
+	}
+	_, err := w.buf.Write(genesis[:])
+	return err
+}
+
+// WriteBlock appends a block to the archive.
+func (w *Writer) WriteBlock(b *block.Block) error {
+	enc, err := b.Marshal()
+	if err != nil {
+		return err
+	}
+	n := binary.PutUvarint(w.lenBuf[:], uint64(len(enc)))
+	if _, err := w.buf.Write(w.lenBuf[:n]); err != nil {
+		return err
+	}
+	_, err = w.buf.Write(enc)
+	return err
+}
+
+// Close flushes the buffered blocks and closes the underlying file, if the
+// writer was created by Create.
+func (w *Writer) Close() error {
+	err := w.buf.Flush()
+	for _, c := range w.closers {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// Reader streams the blocks of an archive.
+type Reader struct {
+	buf     *bufio.Reader
+	genesis types.Hash
+	closers []io.Closer
+}
+
+// NewReader reads the archive header from r and returns a reader for its blocks.
+func NewReader(r io.Reader) (*Reader, error) {
+	ar := &Reader{buf: bufio.NewReader(r)}
+	if err := ar.readHeader(); err != nil {
+		return nil, err
+	}
+	return ar, nil
+}
+
+// Open opens the archive file at path, decompressing it if the name ends in ".gz".
+func Open(path string) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		r       io.Reader = f
+		closers           = []io.Closer{f}
+	)
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		r, closers = gz, []io.Closer{gz, f}
+	}
+	ar := &Reader{buf: bufio.NewReader(r), closers: closers}
+	if err := ar.readHeader(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return ar, nil
+}
+
+func (r *Reader) readHeader() error {
+	head := make([]byte, len(magic)+1+types.HashLength)
+	if _, err := io.ReadFull(r.buf, head); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return ErrBadMagic
+		}
+		return err
+	}
+	if !bytes.Equal(head[:len(magic)], []byte(magic)) {
+		return ErrBadMagic
+	}
+	if v := head[len(magic)]; v != version {
+		return fmt.Errorf("%w: %d", ErrVersion, v)
+	}
+	copy(r.genesis[:], head[len(magic)+1:])
+	return nil
+}
+
+// Genesis returns the hash of the genesis block the archived chain builds on.
+func (r *Reader) Genesis() types.Hash {
+	return r.genesis
+}
+
+// Next returns the next block of the archive, or io.EOF once all blocks were read.
+func (r *Reader) Next() (*block.Block, error) {
+	size, err := binary.ReadUvarint(r.buf)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	if size > maxBlockSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrBlockTooLarge, size)
+	}
+	enc := make([]byte, size)
+	if _, err := io.ReadFull(r.buf, enc); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	b := new(block.Block)
+	if err := b.Unmarshal(enc); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Close closes the underlying file, if the reader was created by Open.
+func (r *Reader) Close() error {
+	var err error
+	for _, c := range r.closers {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,116 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package blockarchive
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/n42blockchain/N42/common/block"
+	"github.com/n42blockchain/N42/common/types"
+)
+
+func testBlocks(n int) []*block.Block {
+	blocks := make([]*block.Block, n)
+	parent := types.Hash{}
+	for i := range blocks {
+		header := &block.Header{
+			ParentHash: parent,
+			Difficulty: uint256.NewInt(2),
+			Number:     uint256.NewInt(uint64(i + 1)),
+			GasLimit:   30000000,
+			Time:       uint64(1000 + i),
+			BaseFee:    uint256.NewInt(7),
+			Extra:      []byte{byte(i)},
+		}
+		rewards := []*block.Reward{{Address: types.Address{byte(i)}, Amount: uint256.NewInt(uint64(i))}}
+		blocks[i] = block.NewBlockFromReceipt(header, nil, nil, nil, rewards).(*block.Block)
+		parent = blocks[i].Hash()
+	}
+	return blocks
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	genesis := types.Hash{0x42}
+	for _, name := range []string{"chain.n42", "chain.n42.gz"} {
+		path := filepath.Join(t.TempDir(), name)
+		blocks := testBlocks(5)
+
+		w, err := Create(path, genesis)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, b := range blocks {
+			if err := w.WriteBlock(b); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Genesis() != genesis {
+			t.Fatalf("%s: genesis mismatch: have %v, want %v", name, r.Genesis(), genesis)
+		}
+		for i, want := range blocks {
+			have, err := r.Next()
+			if err != nil {
+				t.Fatalf("%s: block %d: %v", name, i, err)
+			}
+			if have.Hash() != want.Hash() {
+				t.Fatalf("%s: block %d hash mismatch: have %v, want %v", name, i, have.Hash(), want.Hash())
+			}
+			if len(have.Body().Reward()) != 1 {
+				t.Fatalf("%s: block %d lost its rewards", name, i)
+			}
+		}
+		if _, err := r.Next(); !errors.Is(err, io.EOF) {
+			t.Fatalf("%s: expected EOF after last block, got %v", name, err)
+		}
+		r.Close()
+	}
+}
+
+func TestArchiveBadInput(t *testing.T) {
+	if _, err := NewReader(bytes.NewReader([]byte("N42"))); !errors.Is(err, ErrBadMagic) {
+		t.Fatalf("short input: have %v, want %v", err, ErrBadMagic)
+	}
+	if _, err := NewReader(bytes.NewReader(make([]byte, 64))); !errors.Is(err, ErrBadMagic) {
+		t.Fatalf("bad magic: have %v, want %v", err, ErrBadMagic)
+	}
+
+	// A block cut short must not be mistaken for the end of the archive.
+	var buf bytes.Buffer
+	w, _ := NewWriter(&buf, types.Hash{})
+	w.WriteBlock(testBlocks(1)[0])
+	w.Close()
+	r, err := NewReader(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Next(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("truncated block: have %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
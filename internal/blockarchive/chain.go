@@ -0,0 +1,157 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package blockarchive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/n42blockchain/N42/common"
+	"github.com/n42blockchain/N42/common/block"
+	"github.com/n42blockchain/N42/log"
+)
+
+// importBatchSize is the number of blocks handed to InsertChain at once.
+const importBatchSize = 2500
+
+// logInterval is the interval between progress reports.
+const logInterval = 8 * time.Second
+
+// ExportChain writes the canonical blocks first to last, inclusive, to w.
+func ExportChain(ctx context.Context, bc common.IBlockChain, w *Writer, first, last uint64) error {
+	if head := bc.CurrentBlock().Number64().Uint64(); last > head {
+		return fmt.Errorf("export range end %d beyond head %d", last, head)
+	}
+	if first > last {
+		return fmt.Errorf("invalid export range %d-%d", first, last)
+	}
+	log.Info("Exporting blockchain", "first", first, "last", last)
+
+	var (
+		start  = time.Now()
+		logged = time.Now()
+	)
+	for nr := first; nr <= last; nr++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		iblock, err := bc.GetBlockByNumber(uint256.NewInt(nr))
+		if err != nil {
+			return fmt.Errorf("export failed on #%d: %w", nr, err)
+		}
+		b, ok := iblock.(*block.Block)
+		if !ok || b == nil {
+			return fmt.Errorf("export failed on #%d: block not found", nr)
+		}
+		if err := w.WriteBlock(b); err != nil {
+			return err
+		}
+		if time.Since(logged) > logInterval {
+			log.Info("Exporting blocks", "exported", nr-first+1, "number", nr, "elapsed", time.Since(start))
+			logged = time.Now()
+		}
+	}
+	log.Info("Exported blockchain", "blocks", last-first+1, "elapsed", time.Since(start))
+	return nil
+}
+
+// ImportChain inserts the blocks of an archive into the chain, fully validating
+// them. Blocks already on the canonical chain are skipped, so an interrupted
+// import can be resumed by importing the same archive again. It returns the
+// number of inserted blocks.
+func ImportChain(ctx context.Context, bc common.IBlockChain, r *Reader) (int, error) {
+	if genesis := bc.GenesisBlock().Hash(); r.Genesis() != genesis {
+		return 0, fmt.Errorf("%w: archive %v, local %v", ErrGenesisMismatch, r.Genesis(), genesis)
+	}
+	var (
+		start    = time.Now()
+		logged   = time.Now()
+		imported int
+		skipped  int
+		batch    = make([]block.IBlock, 0, importBatchSize)
+	)
+	insert := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		n, err := bc.InsertChain(batch)
+		if err != nil {
+			if n >= 0 && n < len(batch) {
+				return fmt.Errorf("invalid block #%v: %w", batch[n].Number64(), err)
+			}
+			return err
+		}
+		// A stopped chain returns without error and without inserting, only
+		// count the blocks that made it into the canonical chain.
+		if !isCanonical(bc, batch[len(batch)-1]) {
+			for _, b := range batch {
+				if !isCanonical(bc, b) {
+					break
+				}
+				imported++
+			}
+			return fmt.Errorf("%w at block #%v", ErrImportInterrupted, batch[0].Number64().Uint64()+uint64(imported))
+		}
+		imported += len(batch)
+		batch = batch[:0]
+		if time.Since(logged) > logInterval {
+			log.Info("Importing blocks", "imported", imported, "skipped", skipped, "head", bc.CurrentBlock().Number64(), "elapsed", time.Since(start))
+			logged = time.Now()
+		}
+		return nil
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return imported, err
+		}
+		b, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return imported, err
+		}
+		if len(batch) == 0 && isCanonical(bc, b) {
+			skipped++
+			continue
+		}
+		batch = append(batch, b)
+		if len(batch) == importBatchSize {
+			if err := insert(); err != nil {
+				return imported, err
+			}
+		}
+	}
+	if err := insert(); err != nil {
+		return imported, err
+	}
+	log.Info("Imported blockchain", "imported", imported, "skipped", skipped, "head", bc.CurrentBlock().Number64(), "elapsed", time.Since(start))
+	return imported, nil
+}
+
+// isCanonical reports whether the block is already part of the local canonical chain.
+func isCanonical(bc common.IBlockChain, b block.IBlock) bool {
+	if b.Number64().Uint64() > bc.CurrentBlock().Number64().Uint64() {
+		return false
+	}
+	header := bc.GetHeaderByNumber(b.Number64())
+	return header != nil && header.Hash() == b.Hash()
+}
@@ -0,0 +1,173 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package blockarchive
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/n42blockchain/N42/common"
+	"github.com/n42blockchain/N42/common/block"
+	"github.com/n42blockchain/N42/common/types"
+)
+
+var errTestInvalid = errors.New("invalid block")
+
+// testChain is a blockchain inserting blocks into an in-memory canonical chain.
+// It stops accepting blocks after stopAt blocks and fails the block numbered
+// failAt, reporting failIndex as its index in the batch.
+type testChain struct {
+	common.IBlockChain
+
+	genesis   *block.Block
+	canonical []block.IBlock // canonical[n] is block #n
+	stopAt    int
+	failAt    uint64
+	failIndex int
+}
+
+func newTestChain() *testChain {
+	genesis := block.NewBlock(&block.Header{Number: uint256.NewInt(0), Difficulty: uint256.NewInt(1)}, nil).(*block.Block)
+	return &testChain{genesis: genesis, canonical: []block.IBlock{genesis}, stopAt: -1, failIndex: -1}
+}
+
+func (c *testChain) GenesisBlock() block.IBlock { return c.genesis }
+func (c *testChain) CurrentBlock() block.IBlock { return c.canonical[len(c.canonical)-1] }
+
+func (c *testChain) GetHeaderByNumber(number *uint256.Int) block.IHeader {
+	if n := number.Uint64(); n < uint64(len(c.canonical)) {
+		return c.canonical[n].Header()
+	}
+	return nil
+}
+
+func (c *testChain) InsertChain(blocks []block.IBlock) (int, error) {
+	for i, b := range blocks {
+		if c.stopAt == 0 {
+			return 0, nil
+		}
+		if b.Number64().Uint64() == c.failAt {
+			if c.failIndex >= 0 {
+				i = c.failIndex
+			}
+			return i, errTestInvalid
+		}
+		c.canonical = append(c.canonical, b)
+		c.stopAt--
+	}
+	return len(blocks), nil
+}
+
+func writeTestArchive(t *testing.T, genesis types.Hash, blocks []*block.Block) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "chain.n42")
+	w, err := Create(path, genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range blocks {
+		if err := w.WriteBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestImportChain(t *testing.T) {
+	blocks := testBlocks(10)
+	tests := []struct {
+		name      string
+		stopAt    int
+		failAt    uint64
+		failIndex int
+		imported  int
+		err       error
+	}{
+		{name: "complete", stopAt: -1, failIndex: -1, imported: 10},
+		{name: "stopped", stopAt: 0, failIndex: -1, imported: 0, err: ErrImportInterrupted},
+		{name: "stopped midway", stopAt: 4, failIndex: -1, imported: 4, err: ErrImportInterrupted},
+		{name: "invalid block", stopAt: -1, failAt: 6, failIndex: -1, imported: 0, err: errTestInvalid},
+		{name: "index out of range", stopAt: -1, failAt: 6, failIndex: 10, imported: 0, err: errTestInvalid},
+	}
+	for _, tt := range tests {
+		chain := newTestChain()
+		chain.stopAt, chain.failAt, chain.failIndex = tt.stopAt, tt.failAt, tt.failIndex
+
+		r, err := Open(writeTestArchive(t, chain.genesis.Hash(), blocks))
+		if err != nil {
+			t.Fatal(err)
+		}
+		imported, err := ImportChain(context.Background(), chain, r)
+		r.Close()
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: error mismatch: have %v, want %v", tt.name, err, tt.err)
+		}
+		if imported != tt.imported {
+			t.Errorf("%s: imported mismatch: have %d, want %d", tt.name, imported, tt.imported)
+		}
+	}
+}
+
+func TestImportChainResume(t *testing.T) {
+	blocks := testBlocks(10)
+	chain := newTestChain()
+	chain.stopAt = 4
+
+	path := writeTestArchive(t, chain.genesis.Hash(), blocks)
+	r, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ImportChain(context.Background(), chain, r); !errors.Is(err, ErrImportInterrupted) {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrImportInterrupted)
+	}
+	r.Close()
+
+	// Importing the archive again skips the blocks imported before the stop.
+	chain.stopAt = -1
+	if r, err = Open(path); err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	imported, err := ImportChain(context.Background(), chain, r)
+	if err != nil {
+		t.Fatalf("failed to resume import: %v", err)
+	}
+	if imported != 6 {
+		t.Fatalf("imported mismatch: have %d, want 6", imported)
+	}
+	if head := chain.CurrentBlock().Hash(); head != blocks[9].Hash() {
+		t.Fatalf("head mismatch: have %v, want %v", head, blocks[9].Hash())
+	}
+}
+
+func TestImportChainGenesisMismatch(t *testing.T) {
+	r, err := Open(writeTestArchive(t, types.Hash{0x42}, testBlocks(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, err := ImportChain(context.Background(), newTestChain(), r); !errors.Is(err, ErrGenesisMismatch) {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrGenesisMismatch)
+	}
+}
@@ -31,12 +31,14 @@ import (
 	"time"
 
 	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/n42blockchain/N42/modules"
 	"github.com/n42blockchain/N42/modules/state"
 	"github.com/n42blockchain/N42/params"
 
 	lru "github.com/hashicorp/golang-lru/v2"
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/n42blockchain/N42/api/protocol/msg_proto"
+	"github.com/n42blockchain/N42/api/protocol/sync_pb"
 	"github.com/n42blockchain/N42/common"
 	block2 "github.com/n42blockchain/N42/common/block"
 	"github.com/n42blockchain/N42/common/types"
@@ -44,6 +46,7 @@ import (
 	"github.com/n42blockchain/N42/log"
 	event "github.com/n42blockchain/N42/modules/event/v2"
 	"github.com/n42blockchain/N42/modules/rawdb"
+	"github.com/n42blockchain/N42/utils"
 )
 
 var (
@@ -278,7 +281,14 @@ func (bc *BlockChain) GetReceipts(blockHash types.Hash) (block2.Receipts, error)
 	if nil != err {
 		return nil, err
 	}
-	return rawdb.ReadReceiptsByHash(rtx, blockHash)
+	receipts, err := rawdb.ReadReceiptsByHash(rtx, blockHash)
+	if err != nil || receipts != nil {
+		return receipts, err
+	}
+	if number := rawdb.ReadHeaderNumber(rtx, blockHash); number != nil {
+		return nil, rawdb.CheckPruned(rtx, modules.Receipts, *number)
+	}
+	return nil, nil
 }
 
 func (bc *BlockChain) GetLogs(blockHash types.Hash) ([][]*block2.Log, error) {
@@ -728,7 +738,27 @@ func (bc *BlockChain) GetBlock(hash types.Hash, number uint64) block2.IBlock {
 func (bc *BlockChain) SealedBlock(b block2.IBlock) error {
 	pbBlock := b.ToProtoMessage()
 	//_ = bc.pubsub.Publish(message.GossipBlockMessage, pbBlock)
-	return bc.p2p.Broadcast(context.TODO(), pbBlock)
+	if err := bc.p2p.Broadcast(context.TODO(), pbBlock); err != nil {
+		return err
+	}
+	// announce the block for the peers the full block does not reach
+	return bc.p2p.Broadcast(context.TODO(), &sync_pb.NewBlockHash{
+		Hash:   utils.ConvertHashToH256(b.Hash()),
+		Number: utils.ConvertUint256IntToH256(b.Number64()),
+	})
+}
+
+// VerifyBlockSeal checks the header of b against the consensus rules, its seal
+// included, the aggregate signature of the verifiers and the transaction root,
+// without executing the block. The parent of b must be known.
+func (bc *BlockChain) VerifyBlockSeal(b block2.IBlock) error {
+	if err := bc.engine.VerifyHeader(bc, b.Header(), true); err != nil {
+		return err
+	}
+	if err := bc.validator.ValidateSignature(b); err != nil {
+		return err
+	}
+	return validateTxRoot(b)
 }
 
 // StopInsert stop insert
@@ -934,7 +964,7 @@ func (bc *BlockChain) insertChain(chain []block2.IBlock) (int, error) {
 		bc.futureBlocks.Remove(block.Hash())
 		stats.ignored += len(it.chain)
 		bc.reportBlock(block, nil, err)
-		return it.index, err
+		return it.index, invalidBlockError(err)
 	}
 
 	//wtx, err := bc.ChainDB.BeginRw(bc.ctx)
@@ -1019,7 +1049,7 @@ func (bc *BlockChain) insertChain(chain []block2.IBlock) (int, error) {
 			if err != nil {
 				bc.reportBlock(block, receipts, err)
 				//atomic.StoreUint32(&followupInterrupt, 1)
-				return nil, err
+				return nil, invalidBlockError(err)
 			}
 			ptime := time.Since(pstart)
 			vstart := time.Now()
@@ -1027,7 +1057,7 @@ func (bc *BlockChain) insertChain(chain []block2.IBlock) (int, error) {
 			if err := bc.validator.ValidateState(block, ibs, receipts, usedGas); err != nil {
 				bc.reportBlock(block, receipts, err)
 				//atomic.StoreUint32(&followupInterrupt, 1)
-				return nil, err
+				return nil, invalidBlockError(err)
 			}
 			vtime := time.Since(vstart)
 
@@ -1410,6 +1440,17 @@ func (bc *BlockChain) writeHeadBlock(tx kv.RwTx, block block2.IBlock) error {
 	return nil
 }
 
+// invalidBlockError marks err as a failure of the block itself, unless it only
+// reports a block that cannot be imported yet.
+func invalidBlockError(err error) error {
+	switch {
+	case errors.Is(err, ErrUnknownAncestor), errors.Is(err, ErrPrunedAncestor), errors.Is(err, ErrFutureBlock),
+		errors.Is(err, consensus.ErrUnknownAncestor), errors.Is(err, consensus.ErrPrunedAncestor), errors.Is(err, consensus.ErrFutureBlock):
+		return err
+	}
+	return fmt.Errorf("%w: %w", consensus.ErrInvalidBlock, err)
+}
+
 // reportBlock logs a bad block error.
 func (bc *BlockChain) reportBlock(block block2.IBlock, receipts []*block2.Receipt, err error) {
 
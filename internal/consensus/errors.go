@@ -40,6 +40,11 @@ var (
 	// ErrInvalidNumber is returned if a block's number doesn't equal its parent's
 	// plus one.
 	ErrInvalidNumber = errors.New("invalid block number")
+
+	// ErrInvalidBlock is returned by block import when a block failed its
+	// validation or execution, as opposed to not being importable yet.
+	ErrInvalidBlock = errors.New("invalid block")
+
 	// ErrNotEnoughSign bls Sign
 	ErrNotEnoughSign = errors.New("not enough sign")
 )
@@ -32,7 +32,7 @@ func VerifyEip1559Header(config *params.ChainConfig, parent, header *block.Heade
 	// Verify that the gas limit remains within allowed bounds
 	parentGasLimit := parent.GasLimit
 	if !config.IsLondon(parent.Number.Uint64()) {
-		parentGasLimit = parent.GasLimit * params.ElasticityMultiplier
+		parentGasLimit = parent.GasLimit * config.BaseFeeConfig(header.Number.Uint64()).ElasticityMultiplier
 	}
 	if err := VerifyGaslimit(parentGasLimit, header.GasLimit); err != nil {
 		return err
@@ -50,21 +50,24 @@ func VerifyEip1559Header(config *params.ChainConfig, parent, header *block.Heade
 	return nil
 }
 
-// CalcBaseFee calculates the basefee of the header.
+// CalcBaseFee calculates the basefee of the header, using the base fee parameters
+// the chain config schedules for the child of parent.
 func CalcBaseFee(config *params.ChainConfig, parent *block.Header) *big.Int {
+	feeConfig := config.BaseFeeConfig(parent.Number.Uint64() + 1)
+
 	// If the current block is the first EIP-1559 block, return the InitialBaseFee.
 	if !config.IsLondon(parent.Number.Uint64()) {
-		return new(big.Int).SetUint64(params.InitialBaseFee)
+		return math.BigMax(new(big.Int).SetUint64(params.InitialBaseFee), feeConfig.MinBaseFee)
 	}
 
 	var (
-		parentGasTarget          = parent.GasLimit / params.ElasticityMultiplier
+		parentGasTarget          = parent.GasLimit / feeConfig.ElasticityMultiplier
 		parentGasTargetBig       = new(big.Int).SetUint64(parentGasTarget)
-		baseFeeChangeDenominator = new(big.Int).SetUint64(params.BaseFeeChangeDenominator)
+		baseFeeChangeDenominator = new(big.Int).SetUint64(feeConfig.BaseFeeChangeDenominator)
 	)
 	// If the parent gasUsed is the same as the target, the baseFee remains unchanged.
 	if parent.GasUsed == parentGasTarget {
-		return new(big.Int).Set(parent.BaseFee.ToBig())
+		return math.BigMax(new(big.Int).Set(parent.BaseFee.ToBig()), feeConfig.MinBaseFee)
 	}
 	if parent.GasUsed > parentGasTarget {
 		// If the parent block used more gas than its target, the baseFee should increase.
@@ -76,7 +79,7 @@ func CalcBaseFee(config *params.ChainConfig, parent *block.Header) *big.Int {
 			common.Big1,
 		)
 
-		return x.Add(parent.BaseFee.ToBig(), baseFeeDelta)
+		return math.BigMax(x.Add(parent.BaseFee.ToBig(), baseFeeDelta), feeConfig.MinBaseFee)
 	} else {
 		// Otherwise if the parent block used less gas than its target, the baseFee should decrease.
 		gasUsedDelta := new(big.Int).SetUint64(parentGasTarget - parent.GasUsed)
@@ -86,7 +89,7 @@ func CalcBaseFee(config *params.ChainConfig, parent *block.Header) *big.Int {
 
 		return math.BigMax(
 			x.Sub(parent.BaseFee.ToBig(), baseFeeDelta),
-			common.Big0,
+			feeConfig.MinBaseFee,
 		)
 	}
 }
@@ -0,0 +1,101 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package misc
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/n42blockchain/N42/common/block"
+	"github.com/n42blockchain/N42/params"
+)
+
+// scheduleConfig activates London at block 5 and tightens the base fee
+// parameters at block 10 and again at block 20.
+func scheduleConfig() *params.ChainConfig {
+	return &params.ChainConfig{
+		ChainID:     big.NewInt(1),
+		LondonBlock: big.NewInt(5),
+		BaseFeeSchedule: []*params.BaseFeeConfig{
+			{Block: big.NewInt(10), ElasticityMultiplier: 4, BaseFeeChangeDenominator: 4},
+			{Block: big.NewInt(20), MinBaseFee: big.NewInt(900_000_000)},
+		},
+	}
+}
+
+func TestCalcBaseFee(t *testing.T) {
+	tests := []struct {
+		parentNumber  uint64
+		parentBaseFee uint64
+		gasLimit      uint64
+		gasUsed       uint64
+		want          int64
+	}{
+		// Before London the initial base fee is returned.
+		{3, 0, 20_000_000, 0, params.InitialBaseFee},
+		// Protocol defaults: elasticity 2, change denominator 8.
+		{8, params.InitialBaseFee, 20_000_000, 10_000_000, params.InitialBaseFee},
+		{8, params.InitialBaseFee, 20_000_000, 20_000_000, 1_125_000_000},
+		{8, params.InitialBaseFee, 20_000_000, 0, 875_000_000},
+		// The child of block 9 is the first block of the second entry: target
+		// 5M gas and changes of up to a quarter.
+		{9, params.InitialBaseFee, 20_000_000, 10_000_000, 1_250_000_000},
+		{9, params.InitialBaseFee, 20_000_000, 5_000_000, params.InitialBaseFee},
+		{9, params.InitialBaseFee, 20_000_000, 0, 750_000_000},
+		// From block 20 on, the base fee does not fall below the minimum.
+		{18, params.InitialBaseFee, 20_000_000, 0, 750_000_000},
+		{19, params.InitialBaseFee, 20_000_000, 0, 900_000_000},
+		{19, 800_000_000, 20_000_000, 5_000_000, 900_000_000},
+		{25, 1_000_000_000, 20_000_000, 20_000_000, 1_750_000_000},
+	}
+	config := scheduleConfig()
+	for i, tt := range tests {
+		parent := &block.Header{
+			Number:   uint256.NewInt(tt.parentNumber),
+			GasLimit: tt.gasLimit,
+			GasUsed:  tt.gasUsed,
+			BaseFee:  uint256.NewInt(tt.parentBaseFee),
+		}
+		if have := CalcBaseFee(config, parent); have.Cmp(big.NewInt(tt.want)) != 0 {
+			t.Errorf("test %d: base fee mismatch: have %v, want %d", i, have, tt.want)
+		}
+	}
+}
+
+func TestVerifyEip1559Header(t *testing.T) {
+	config := scheduleConfig()
+	parent := &block.Header{
+		Number:   uint256.NewInt(9),
+		GasLimit: 20_000_000,
+		GasUsed:  0,
+		BaseFee:  uint256.NewInt(params.InitialBaseFee),
+	}
+	header := &block.Header{
+		Number:   uint256.NewInt(10),
+		GasLimit: 20_000_000,
+		BaseFee:  uint256.NewInt(750_000_000),
+	}
+	if err := VerifyEip1559Header(config, parent, header); err != nil {
+		t.Fatalf("valid header rejected: %v", err)
+	}
+	// The base fee of the protocol defaults is not valid past the schedule boundary.
+	header.BaseFee = uint256.NewInt(875_000_000)
+	if err := VerifyEip1559Header(config, parent, header); err == nil {
+		t.Fatalf("header with stale base fee parameters accepted")
+	}
+}
@@ -195,6 +195,8 @@ func (d *Downloader) Start() error {
 	if d.network.Bootstrapped() {
 		//todo
 		//log.Debugf("boot node")
+		// There is nothing to sync, only let the miner start. The sync
+		// progress tracker ignores this end without a start.
 		event.GlobalEvent.Send(common.DownloaderFinishEvent{})
 		return nil
 	}
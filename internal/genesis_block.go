@@ -207,6 +207,9 @@ func (g *GenesisBlock) ToBlock() (*block2.Block, *state.IntraBlockState, error)
 			head.BaseFee = g.GenesisConfig.BaseFee
 		} else {
 			head.BaseFee = uint256.NewInt(params.InitialBaseFee)
+			if minBaseFee, overflow := uint256.FromBig(g.GenesisConfig.Config.BaseFeeConfig(0).MinBaseFee); !overflow && minBaseFee.Cmp(head.BaseFee) > 0 {
+				head.BaseFee = minBaseFee
+			}
 		}
 	}
 
@@ -282,3 +285,24 @@ func testnetGenesisBlock() *conf.Genesis {
 		Miners:    []string{"0xAA824Bf8afa35061d5b9FeBD0AD47642Cd3b1d17"},
 	}
 }
+
+// DeveloperGenesisBlock returns the genesis block of the single-node developer
+// network, sealed by faucet every period seconds (or on every transaction if
+// period is zero), which is also prefunded with an effectively unlimited balance.
+func DeveloperGenesisBlock(period uint64, faucet types.Address) *conf.Genesis {
+	config := *params.DevChainConfig
+	apos := *params.DevChainConfig.Apos
+	apos.Period = period
+	config.Apos = &apos
+
+	// Leave some headroom below 2^256 so that transfers to the faucet never overflow.
+	balance := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(9))
+	return &conf.Genesis{
+		Config:   &config,
+		GasLimit: 30000000,
+		Miners:   []string{faucet.Hex()},
+		Alloc: conf.GenesisAlloc{
+			faucet: {Balance: balance.String()},
+		},
+	}
+}
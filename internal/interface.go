@@ -28,6 +28,9 @@ type Validator interface {
 	// ValidateBody validates the given block's content.
 	ValidateBody(block block.IBlock) error
 
+	// ValidateSignature verifies the aggregate signature of the block verifiers.
+	ValidateSignature(block block.IBlock) error
+
 	// ValidateState validates the given statedb and optionally the receipts and
 	// gas used.
 	ValidateState(block block.IBlock, state *state.IntraBlockState, receipts block.Receipts, usedGas uint64) error
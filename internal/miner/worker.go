@@ -208,7 +208,7 @@ func newWorker(ctx context.Context, group *errgroup.Group, chainConfig *params.C
 
 	// machine verify
 	group.Go(func() error {
-		return api.MachineVerify(ctx)
+		return api.MachineVerify(ctx, chainConfig)
 	})
 
 	group.Go(func() error {
@@ -523,6 +523,12 @@ func (w *worker) workLoop(recommit time.Duration) error {
 	newBlockSub := event.GlobalEvent.Subscribe(newBlockCh)
 	defer newBlockSub.Unsubscribe()
 
+	newTxsCh := make(chan common.NewTxsEvent, 10)
+	defer close(newTxsCh)
+
+	newTxsSub := event.GlobalEvent.Subscribe(newTxsCh)
+	defer newTxsSub.Unsubscribe()
+
 	timer := time.NewTimer(0)
 	defer timer.Stop()
 	<-timer.C // discard the initial tick
@@ -567,6 +573,16 @@ func (w *worker) workLoop(recommit time.Duration) error {
 		case err := <-newBlockSub.Err():
 			return err
 
+		case <-newTxsCh:
+			// Zero period chains refuse to seal empty blocks, so the arrival of
+			// transactions is what triggers the next block.
+			if w.isRunning() && w.isInstantSeal() {
+				timestamp = time.Now().Unix()
+				commit(true, commitInterruptNewHead)
+			}
+		case err := <-newTxsSub.Err():
+			return err
+
 		case <-timer.C:
 			// If sealing is running resubmit a new work cycle periodically to pull in
 			// higher priced transactions. Disable this overhead for pending blocks.
@@ -590,6 +606,18 @@ func (w *worker) workLoop(recommit time.Duration) error {
 	}
 }
 
+// isInstantSeal reports whether the chain seals a block per transaction batch
+// instead of on a fixed period.
+func (w *worker) isInstantSeal() bool {
+	switch {
+	case w.chainConfig.Clique != nil:
+		return w.chainConfig.Clique.Period == 0
+	case w.chainConfig.Apos != nil:
+		return w.chainConfig.Apos.Period == 0
+	}
+	return false
+}
+
 func (w *worker) fillTransactions(interrupt *atomic.Int32, env *environment, ibs *state.IntraBlockState, getHeader func(hash types.Hash, number uint64) *block.Header) error {
 	// todo fillTx
 	env.txs = []*transaction.Transaction{}
@@ -686,7 +714,7 @@ func (w *worker) prepareWork(param *generateParams) (*environment, error) {
 	if w.chainConfig.IsLondon(header.Number.Uint64()) {
 		header.BaseFee, _ = uint256.FromBig(misc.CalcBaseFee(w.chainConfig, parent))
 		if !w.chainConfig.IsLondon(parent.Number64().Uint64()) {
-			parentGasLimit := parent.GasLimit * params.ElasticityMultiplier
+			parentGasLimit := parent.GasLimit * w.chainConfig.BaseFeeConfig(header.Number.Uint64()).ElasticityMultiplier
 			header.GasLimit = CalcGasLimit(parentGasLimit, w.minerConf.GasCeil)
 		}
 	}
@@ -0,0 +1,362 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/n42blockchain/N42/common/hexutil"
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/internal/blockarchive"
+	"github.com/n42blockchain/N42/internal/p2p"
+	"github.com/n42blockchain/N42/modules/rpc/jsonrpc"
+	"github.com/n42blockchain/N42/turbo/backup"
+	"github.com/n42blockchain/N42/utils"
+)
+
+// apis returns the collection of built-in RPC APIs of the node itself.
+func (n *Node) apis() []jsonrpc.API {
+	return []jsonrpc.API{
+		{
+			Namespace: "admin",
+			Service:   &adminAPI{n},
+			// Node management must not leak to the public endpoints, so it is
+			// only served over IPC and the JWT authenticated endpoint unless
+			// explicitly listed in --http.api or --ws.api.
+			Authenticated: true,
+		},
+	}
+}
+
+// adminAPI is the collection of administrative API methods for peer and node
+// management.
+type adminAPI struct {
+	node *Node // Node interfaced by this API
+}
+
+// NodeHead is the current head of the local chain.
+type NodeHead struct {
+	Number hexutil.Uint64 `json:"number"`
+	Hash   types.Hash     `json:"hash"`
+}
+
+// NodeInfo represents a short summary of the information known about the host.
+type NodeInfo struct {
+	ID         string        `json:"id"`
+	Enode      string        `json:"enode,omitempty"`
+	ENR        string        `json:"enr,omitempty"`
+	ListenAddr []string      `json:"listenAddrs"`
+	Discovery  []string      `json:"discoveryAddrs,omitempty"`
+	Genesis    types.Hash    `json:"genesis"`
+	Head       NodeHead      `json:"head"`
+	ForkDigest hexutil.Bytes `json:"forkDigest"`
+}
+
+// PeerScores are the per-scorer scores of a peer and their weighted total.
+type PeerScores struct {
+	Total        float64 `json:"total"`
+	BadResponses float64 `json:"badResponses"`
+	BlockProvide float64 `json:"blockProvider"`
+	PeerStatus   float64 `json:"peerStatus"`
+	Gossip       float64 `json:"gossip"`
+	TxProvider   float64 `json:"txProvider"`
+}
+
+// PeerInfo represents a short summary of the information known about a
+// connected peer.
+type PeerInfo struct {
+	ID        string          `json:"id"`
+	ENR       string          `json:"enr,omitempty"`
+	Address   string          `json:"address,omitempty"`
+	Direction string          `json:"direction"`
+	State     string          `json:"state"`
+	Trusted   bool            `json:"trusted"`
+	Head      *hexutil.Uint64 `json:"head,omitempty"`
+	Scores    PeerScores      `json:"scores"`
+}
+
+// NodeInfo retrieves all the information we know about the host node at the
+// protocol granularity.
+func (api *adminAPI) NodeInfo() (*NodeInfo, error) {
+	var (
+		n       = api.node
+		current = n.blockChain.CurrentBlock()
+		host    = n.p2p.Host()
+	)
+	info := &NodeInfo{
+		ID:      n.p2p.PeerID().String(),
+		Genesis: n.blockChain.GenesisBlock().Hash(),
+		Head: NodeHead{
+			Number: hexutil.Uint64(current.Number64().Uint64()),
+			Hash:   current.Hash(),
+		},
+	}
+	for _, addr := range host.Addrs() {
+		info.ListenAddr = append(info.ListenAddr, fmt.Sprintf("%s/p2p/%s", addr, host.ID()))
+	}
+	if local := n.p2p.LocalNode(); local != nil {
+		info.Enode = local.URLv4()
+		info.ENR = local.String()
+	}
+	if addrs, err := n.p2p.DiscoveryAddresses(); err == nil {
+		for _, addr := range addrs {
+			info.Discovery = append(info.Discovery, addr.String())
+		}
+	}
+	digest, err := n.p2p.ForkDigest()
+	if err != nil {
+		return nil, err
+	}
+	info.ForkDigest = digest[:]
+	return info, nil
+}
+
+// Peers retrieves all the information we know about each individual peer at the
+// protocol granularity.
+func (api *adminAPI) Peers() []*PeerInfo {
+	var (
+		status  = api.node.p2p.Peers()
+		scorers = status.Scorers()
+		infos   = make([]*PeerInfo, 0)
+	)
+	for _, pid := range status.Active() {
+		info := &PeerInfo{
+			ID:      pid.String(),
+			Trusted: status.IsTrusted(pid),
+			Scores: PeerScores{
+				Total:        scorers.Score(pid),
+				BadResponses: scorers.BadResponsesScorer().Score(pid),
+				BlockProvide: scorers.BlockProviderScorer().Score(pid),
+				PeerStatus:   scorers.PeerStatusScorer().Score(pid),
+				Gossip:       scorers.GossipScorer().Score(pid),
+				TxProvider:   scorers.TxProviderScorer().Score(pid),
+			},
+		}
+		if record, err := status.ENR(pid); err == nil && record != nil {
+			if enr, err := p2p.SerializeENR(record); err == nil {
+				info.ENR = "enr:" + enr
+			}
+		}
+		if addr, err := status.Address(pid); err == nil && addr != nil {
+			info.Address = addr.String()
+		}
+		if direction, err := status.Direction(pid); err == nil {
+			info.Direction = strings.ToLower(direction.String())
+		}
+		if state, err := status.ConnState(pid); err == nil {
+			info.State = strings.ToLower(strings.TrimPrefix(state.String(), "Peer"))
+		}
+		if chainState, err := status.ChainState(pid); err == nil && chainState != nil && chainState.CurrentHeight != nil {
+			head := hexutil.Uint64(utils.ConvertH256ToUint256Int(chainState.CurrentHeight).Uint64())
+			info.Head = &head
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// AddPeer connects to the given peer, specified by its multiaddr or ENR.
+func (api *adminAPI) AddPeer(url string) (bool, error) {
+	if _, err := api.node.p2p.AddPeer(url, false); err != nil {
+		return false, fmt.Errorf("failed to add peer: %v", err)
+	}
+	return true, nil
+}
+
+// AddTrustedPeer connects to the given peer and marks it as trusted, exempting
+// it from the peer limit, peer scoring and pruning. Trusted peers are redialed
+// whenever disconnected and kept in the peer store across restarts.
+func (api *adminAPI) AddTrustedPeer(url string) (bool, error) {
+	if _, err := api.node.p2p.AddPeer(url, true); err != nil {
+		return false, fmt.Errorf("failed to add trusted peer: %v", err)
+	}
+	return true, nil
+}
+
+// RemoveTrustedPeer revokes the trust of the given peer, specified by its peer
+// ID, multiaddr or ENR, without disconnecting it.
+func (api *adminAPI) RemoveTrustedPeer(url string) (bool, error) {
+	pid, err := parsePeerID(url)
+	if err != nil {
+		return false, err
+	}
+	api.node.p2p.RemoveTrustedPeer(pid)
+	return true, nil
+}
+
+// TrustedPeers returns the IDs of the trusted peers.
+func (api *adminAPI) TrustedPeers() []string {
+	trusted := api.node.p2p.Peers().Trusted()
+	ids := make([]string, 0, len(trusted))
+	for _, pid := range trusted {
+		ids = append(ids, pid.String())
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// RemovePeer disconnects from the given peer, specified by its peer ID,
+// multiaddr or ENR, and revokes its trust.
+func (api *adminAPI) RemovePeer(url string) (bool, error) {
+	pid, err := parsePeerID(url)
+	if err != nil {
+		return false, err
+	}
+	if err := api.node.p2p.RemovePeer(pid); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// parsePeerID extracts the peer ID from a bare peer ID, a multiaddr or an ENR.
+func parsePeerID(url string) (peer.ID, error) {
+	if pid, err := peer.Decode(url); err == nil {
+		return pid, nil
+	}
+	addrs, err := p2p.PeersFromStringAddrs([]string{url})
+	if err != nil {
+		return "", err
+	}
+	if len(addrs) == 0 {
+		return "", fmt.Errorf("invalid peer %q", url)
+	}
+	info, err := peer.AddrInfoFromP2pAddr(addrs[0])
+	if err != nil {
+		return "", err
+	}
+	return info.ID, nil
+}
+
+// StartHTTP starts the HTTP RPC API server, defaulting every unset parameter to
+// the node configuration.
+func (api *adminAPI) StartHTTP(host *string, port *int, cors *string, apis *string) (bool, error) {
+	api.node.lock.Lock()
+	defer api.node.lock.Unlock()
+
+	cfg := api.node.config.NodeCfg
+	if host == nil {
+		host = &cfg.HTTPHost
+	}
+	if port == nil {
+		p, err := strconv.Atoi(cfg.HTTPPort)
+		if err != nil {
+			return false, fmt.Errorf("invalid HTTP port %q: %v", cfg.HTTPPort, err)
+		}
+		port = &p
+	}
+	config := httpConfig{
+		CorsAllowedOrigins: utils.SplitAndTrim(cfg.HTTPCors),
+		Vhosts:             []string{"*"},
+		Modules:            utils.SplitAndTrim(cfg.HTTPApi),
+	}
+	if cors != nil {
+		config.CorsAllowedOrigins = utils.SplitAndTrim(*cors)
+	}
+	if apis != nil {
+		config.Modules = utils.SplitAndTrim(*apis)
+	}
+
+	server := api.node.http
+	if err := server.setListenAddr(*host, *port); err != nil {
+		return false, err
+	}
+	if err := server.enableRPC(api.node.rpcAPIs, config); err != nil {
+		return false, err
+	}
+	if err := server.start(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// StopHTTP shuts down the HTTP server.
+func (api *adminAPI) StopHTTP() (bool, error) {
+	api.node.lock.Lock()
+	defer api.node.lock.Unlock()
+
+	api.node.http.stop()
+	return true, nil
+}
+
+// ExportChain exports the canonical chain, or the blocks first to last, into a
+// block archive, which is compressed if the file name ends in ".gz".
+func (api *adminAPI) ExportChain(file string, first *uint64, last *uint64) (bool, error) {
+	bc := api.node.blockChain
+	if first == nil && last != nil {
+		return false, errors.New("last cannot be specified without first")
+	}
+	from, to := uint64(1), bc.CurrentBlock().Number64().Uint64()
+	if first != nil {
+		from = *first
+	}
+	if last != nil {
+		to = *last
+	}
+	if _, err := os.Stat(file); err == nil {
+		// File already exists. Allowing overwrite could be a DoS vector,
+		// since the 'file' may point to arbitrary paths on the drive.
+		return false, errors.New("location would overwrite an existing file")
+	}
+	w, err := blockarchive.Create(file, bc.GenesisBlock().Hash())
+	if err != nil {
+		return false, err
+	}
+	if err := blockarchive.ExportChain(context.Background(), bc, w, from, to); err != nil {
+		w.Close()
+		return false, err
+	}
+	if err := w.Close(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ImportChain imports the blocks of a block archive, skipping those already
+// on the canonical chain.
+func (api *adminAPI) ImportChain(file string) (bool, error) {
+	r, err := blockarchive.Open(file)
+	if err != nil {
+		return false, err
+	}
+	defer r.Close()
+	if _, err := blockarchive.ImportChain(context.Background(), api.node.blockChain, r); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Backup writes a consistent snapshot of the chain database and of the ancient
+// store into dir, which must not exist or be empty, while the node keeps
+// running. The files are checksummed unless checksum is false, and gzip
+// compressed if compress is true.
+func (api *adminAPI) Backup(dir string, compress *bool, checksum *bool) (*backup.Manifest, error) {
+	cfg := backup.Config{Checksum: true}
+	if compress != nil {
+		cfg.Compress = *compress
+	}
+	if checksum != nil {
+		cfg.Checksum = *checksum
+	}
+	return api.node.Backup(dir, cfg)
+}
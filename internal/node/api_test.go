@@ -0,0 +1,248 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/n42blockchain/N42/conf"
+	"github.com/n42blockchain/N42/internal/p2p"
+	"github.com/n42blockchain/N42/internal/p2p/peers"
+	"github.com/n42blockchain/N42/internal/p2p/peers/scorers"
+	"github.com/n42blockchain/N42/modules/rpc/jsonrpc"
+)
+
+// testP2P is a p2p service recording the peer management calls of the admin API.
+type testP2P struct {
+	p2p.P2P
+
+	status  *peers.Status
+	added   map[peer.ID]bool // added peers and whether they're trusted
+	removed []peer.ID
+}
+
+func newTestP2P() *testP2P {
+	return &testP2P{
+		status: peers.NewStatus(context.Background(), &peers.StatusConfig{PeerLimit: 10, ScorerParams: &scorers.Config{}}),
+		added:  make(map[peer.ID]bool),
+	}
+}
+
+func (s *testP2P) Peers() *peers.Status { return s.status }
+
+func (s *testP2P) AddPeer(addr string, trusted bool) (peer.ID, error) {
+	pid, err := parsePeerID(addr)
+	if err != nil {
+		return "", err
+	}
+	s.added[pid] = trusted
+	if trusted {
+		s.status.SetTrusted(pid, true)
+	}
+	return pid, nil
+}
+
+func (s *testP2P) RemovePeer(pid peer.ID) error {
+	if _, ok := s.added[pid]; !ok {
+		return errors.New("unknown peer")
+	}
+	s.removed = append(s.removed, pid)
+	return nil
+}
+
+func (s *testP2P) RemoveTrustedPeer(pid peer.ID) { s.status.SetTrusted(pid, false) }
+
+func testPeerID(t *testing.T) peer.ID {
+	t.Helper()
+	key, _, err := crypto.GenerateSecp256k1Key(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, err := peer.IDFromPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pid
+}
+
+func TestParsePeerID(t *testing.T) {
+	pid := testPeerID(t)
+	tests := []struct {
+		url string
+		ok  bool
+	}{
+		{pid.String(), true},
+		{fmt.Sprintf("/ip4/127.0.0.1/tcp/61016/p2p/%s", pid), true},
+		{"/ip4/127.0.0.1/tcp/61016", false},
+		{"not a peer", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		have, err := parsePeerID(tt.url)
+		if (err == nil) != tt.ok {
+			t.Errorf("%q: error mismatch: have %v, want ok %v", tt.url, err, tt.ok)
+			continue
+		}
+		if tt.ok && have != pid {
+			t.Errorf("%q: peer mismatch: have %v, want %v", tt.url, have, pid)
+		}
+	}
+}
+
+func TestAdminPeers(t *testing.T) {
+	service := newTestP2P()
+	api := &adminAPI{node: &Node{p2p: service}}
+
+	inbound, outbound, gone := testPeerID(t), testPeerID(t), testPeerID(t)
+	addr := ma.StringCast("/ip4/127.0.0.1/tcp/61016")
+	service.status.Add(nil, inbound, addr, network.DirInbound)
+	service.status.SetConnectionState(inbound, peers.PeerConnected)
+	service.status.Add(nil, outbound, addr, network.DirOutbound)
+	service.status.SetConnectionState(outbound, peers.PeerConnecting)
+	service.status.Add(nil, gone, addr, network.DirOutbound)
+	service.status.SetConnectionState(gone, peers.PeerDisconnected)
+
+	if _, err := api.AddTrustedPeer(fmt.Sprintf("%s/p2p/%s", addr, outbound)); err != nil {
+		t.Fatalf("failed to add trusted peer: %v", err)
+	}
+	infos := api.Peers()
+	if len(infos) != 2 {
+		t.Fatalf("peer count mismatch: have %d, want 2", len(infos))
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Direction < infos[j].Direction })
+	want := []PeerInfo{
+		{ID: inbound.String(), Address: addr.String(), Direction: "inbound", State: "connected"},
+		{ID: outbound.String(), Address: addr.String(), Direction: "outbound", State: "connecting", Trusted: true},
+	}
+	for i, info := range infos {
+		if info.ID != want[i].ID || info.Address != want[i].Address || info.Direction != want[i].Direction ||
+			info.State != want[i].State || info.Trusted != want[i].Trusted || info.Head != nil {
+			t.Errorf("peer %d mismatch: have %+v, want %+v", i, *info, want[i])
+		}
+	}
+	if trusted := api.TrustedPeers(); len(trusted) != 1 || trusted[0] != outbound.String() {
+		t.Errorf("trusted peers mismatch: have %v, want [%v]", trusted, outbound)
+	}
+	if _, err := api.RemoveTrustedPeer(outbound.String()); err != nil {
+		t.Fatalf("failed to remove trusted peer: %v", err)
+	}
+	if trusted := api.TrustedPeers(); len(trusted) != 0 {
+		t.Errorf("trusted peers left after removal: %v", trusted)
+	}
+}
+
+func TestAdminAddRemovePeer(t *testing.T) {
+	service := newTestP2P()
+	api := &adminAPI{node: &Node{p2p: service}}
+	pid := testPeerID(t)
+
+	if _, err := api.AddPeer("/ip4/127.0.0.1/tcp/61016"); err == nil {
+		t.Fatal("peer without id added")
+	}
+	if ok, err := api.AddPeer(fmt.Sprintf("/ip4/127.0.0.1/tcp/61016/p2p/%s", pid)); !ok || err != nil {
+		t.Fatalf("failed to add peer: %v", err)
+	}
+	if trusted, ok := service.added[pid]; !ok || trusted {
+		t.Fatalf("peer not added untrusted: added %v, trusted %v", ok, trusted)
+	}
+	if _, err := api.RemovePeer("bogus"); err == nil {
+		t.Fatal("removed peer with invalid id")
+	}
+	if ok, err := api.RemovePeer(pid.String()); !ok || err != nil {
+		t.Fatalf("failed to remove peer: %v", err)
+	}
+	if len(service.removed) != 1 || service.removed[0] != pid {
+		t.Fatalf("removed peers mismatch: have %v, want [%v]", service.removed, pid)
+	}
+	if _, err := api.RemovePeer(testPeerID(t).String()); err == nil {
+		t.Fatal("removing an unknown peer succeeded")
+	}
+}
+
+// testService is an RPC service served by the HTTP server of the tests.
+type testService struct{}
+
+func (testService) Echo(s string) string { return s }
+
+func TestAdminStartStopHTTP(t *testing.T) {
+	node := &Node{
+		config: &conf.Config{NodeCfg: conf.NodeConfig{HTTPHost: "127.0.0.1", HTTPPort: "0"}},
+		http:   newHTTPServer(),
+		rpcAPIs: []jsonrpc.API{{
+			Namespace: "test",
+			Service:   testService{},
+		}},
+	}
+	api := &adminAPI{node: node}
+	apis := "test"
+	if ok, err := api.StartHTTP(nil, nil, nil, &apis); !ok || err != nil {
+		t.Fatalf("failed to start HTTP: %v", err)
+	}
+	endpoint := "http://" + node.http.listenAddr()
+	client, err := jsonrpc.Dial(endpoint)
+	if err != nil {
+		t.Fatalf("failed to dial %s: %v", endpoint, err)
+	}
+	var result string
+	if err := client.Call(&result, "test_echo", "hello"); err != nil || result != "hello" {
+		t.Fatalf("call mismatch: have %q, err %v", result, err)
+	}
+	client.Close()
+
+	// Starting again on another port fails while the server runs.
+	port := 1
+	if _, err := api.StartHTTP(nil, &port, nil, &apis); err == nil {
+		t.Fatal("started HTTP twice")
+	}
+	if ok, err := api.StopHTTP(); !ok || err != nil {
+		t.Fatalf("failed to stop HTTP: %v", err)
+	}
+	if addr := node.http.listenAddr(); addr != "" {
+		t.Fatalf("HTTP server still listening on %s", addr)
+	}
+
+	// Concurrent starts and stops leave the server in a consistent state.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() { defer wg.Done(); api.StartHTTP(nil, nil, nil, &apis) }()
+		go func() { defer wg.Done(); api.StopHTTP() }()
+	}
+	wg.Wait()
+	api.StopHTTP()
+	if addr := node.http.listenAddr(); addr != "" {
+		t.Fatalf("HTTP server still listening on %s", addr)
+	}
+}
+
+func TestAdminExportChainChecks(t *testing.T) {
+	api := &adminAPI{node: &Node{}}
+	last := uint64(5)
+	if _, err := api.ExportChain(filepath.Join(t.TempDir(), "chain.n42"), nil, &last); err == nil {
+		t.Fatal("export with last but no first accepted")
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/n42blockchain/N42/log"
+	"github.com/n42blockchain/N42/turbo/backup"
+	"github.com/n42blockchain/N42/utils"
+)
+
+var errBackupInProgress = errors.New("backup already in progress")
+
+// Backup writes a consistent snapshot of the chain database and of the ancient
+// store of the node into dir, logging its progress. The node keeps running
+// during the backup; only one backup runs at a time.
+func (n *Node) Backup(dir string, cfg backup.Config) (*backup.Manifest, error) {
+	if !n.backupLock.TryLock() {
+		return nil, errBackupInProgress
+	}
+	defer n.backupLock.Unlock()
+
+	log.Info("Starting backup", "dir", dir, "compress", cfg.Compress, "checksum", cfg.Checksum)
+	start := time.Now()
+	cfg.Progress = LogBackupProgress("Backup in progress")
+	m, err := backup.Backup(n.ctx, n.db, n.ancients, dir, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("backup failed: %w", err)
+	}
+	log.Info("Backup completed", "dir", dir, "number", m.Head.Number, "hash", m.Head.Hash, "files", len(m.Files), "elapsed", time.Since(start))
+	return m, nil
+}
+
+// LogBackupProgress returns a progress callback of a backup or restore which
+// logs the progress with the given message.
+func LogBackupProgress(msg string) func(backup.Progress) {
+	return func(p backup.Progress) {
+		var percent float64
+		if p.Total > 0 {
+			percent = 100 * float64(p.Done) / float64(p.Total)
+		}
+		done, total := fmt.Sprint(p.Done), fmt.Sprint(p.Total)
+		if p.Stage == "files" {
+			done, total = utils.ByteCount(p.Done), utils.ByteCount(p.Total)
+		}
+		log.Info(msg, "stage", p.Stage, "item", p.Item, "done", done, "total", total, "progress", fmt.Sprintf("%.1f%%", percent))
+	}
+}
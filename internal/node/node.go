@@ -30,8 +30,10 @@ import (
 	"github.com/n42blockchain/N42/internal/debug"
 	"github.com/n42blockchain/N42/internal/metrics/prometheus"
 	"github.com/n42blockchain/N42/internal/p2p"
+	"github.com/n42blockchain/N42/internal/pruner"
 	astsync "github.com/n42blockchain/N42/internal/sync"
 	initialsync "github.com/n42blockchain/N42/internal/sync/initial-sync"
+	"github.com/n42blockchain/N42/internal/sync/progress"
 	"github.com/n42blockchain/N42/internal/tracers"
 	"github.com/pkg/errors"
 	"github.com/urfave/cli/v2"
@@ -42,6 +44,7 @@ import (
 	"strings"
 
 	"github.com/n42blockchain/N42/internal"
+	"github.com/n42blockchain/N42/internal/aa"
 	"github.com/n42blockchain/N42/internal/api"
 
 	"github.com/c2h5oh/datasize"
@@ -50,6 +53,7 @@ import (
 	"github.com/ledgerwatch/erigon-lib/kv/memdb"
 	log2 "github.com/ledgerwatch/log/v3"
 	"github.com/n42blockchain/N42/modules"
+	"github.com/n42blockchain/N42/modules/ancient"
 	"golang.org/x/sync/semaphore"
 
 	"github.com/n42blockchain/N42/log"
@@ -61,7 +65,6 @@ import (
 	"github.com/n42blockchain/N42/accounts/keystore"
 	"github.com/n42blockchain/N42/common"
 	"github.com/n42blockchain/N42/common/block"
-	"github.com/n42blockchain/N42/common/transaction"
 	"github.com/n42blockchain/N42/common/types"
 	"github.com/n42blockchain/N42/conf"
 	"sync"
@@ -74,6 +77,7 @@ import (
 	"github.com/n42blockchain/N42/modules/rawdb"
 	"github.com/n42blockchain/N42/modules/rpc/jsonrpc"
 	"github.com/n42blockchain/N42/params"
+	"github.com/n42blockchain/N42/params/networkname"
 	"github.com/n42blockchain/N42/utils"
 	"go.uber.org/zap"
 )
@@ -93,6 +97,7 @@ type Node struct {
 	state         int           // Tracks state of node lifecycle
 	shutDown      chan struct{} // Channel to wait for termination notifications
 	dirLock       *flock.Flock  // prevents concurrent use of instance directory
+	backupLock    sync.Mutex    // allows a single backup at a time
 
 	// s
 	miner           *miner.Miner
@@ -101,10 +106,16 @@ type Node struct {
 	db              kv.RwDB
 	txspool         common.ITxsPool
 	depositContract *deposit.Deposit
+	pruner          *pruner.Pruner
+	ancients        *ancient.Freezer
+	freezer         *pruner.Freezer
 	p2p             p2p.P2P
 	sync            *astsync.Service
 	is              *initialsync.Service
+	syncTracker     *progress.Tracker
 	accman          *accounts.Manager
+	userOpPool      *aa.Pool
+	bundler         *aa.Bundler
 
 	api     *api.API
 	rpcAPIs []jsonrpc.API
@@ -128,6 +139,14 @@ const (
 )
 
 func NewNode(cliCtx *cli.Context, cfg *conf.Config) (*Node, error) {
+	trustedBlock := params.TrustedBlockByChainName(cfg.NodeCfg.Chain)
+	if cfg.NodeCfg.SyncTrustedBlock != "" {
+		b, err := params.ParseTrustedBlock(cfg.NodeCfg.SyncTrustedBlock)
+		if err != nil {
+			return nil, err
+		}
+		trustedBlock = b
+	}
 
 	ctx, cancel := context.WithCancel(cliCtx.Context)
 
@@ -149,6 +168,15 @@ func NewNode(cliCtx *cli.Context, cfg *conf.Config) (*Node, error) {
 		return nil, err
 	}
 
+	var ancients *ancient.Freezer
+	if dir := cfg.DatabaseCfg.AncientPath(cfg.NodeCfg.DataDir); dir != "" {
+		if ancients, err = ancient.Open(dir); err != nil {
+			chainKv.Close()
+			return nil, err
+		}
+		rawdb.SetAncientStore(ancients)
+	}
+
 	if err := chainKv.View(ctx, func(tx kv.Tx) error {
 		//
 		genesisHash, err = rawdb.ReadCanonicalHash(tx, 0)
@@ -177,9 +205,15 @@ func NewNode(cliCtx *cli.Context, cfg *conf.Config) (*Node, error) {
 	}
 
 	if genesisHash == (types.Hash{}) {
-		genesisHash = *params.GenesisHashByChainName(cfg.NodeCfg.Chain)
-		genesisConfig = internal.GenesisByChainName(cfg.NodeCfg.Chain)
-		chainConfig = params.ChainConfigByChainName(cfg.NodeCfg.Chain)
+		if cfg.NodeCfg.Chain == networkname.DevChainName {
+			// The developer genesis is generated around the local dev account.
+			genesisConfig = internal.DeveloperGenesisBlock(cfg.NodeCfg.DevPeriod, types.HexToAddress(cfg.Miner.Etherbase))
+			chainConfig = genesisConfig.Config
+		} else {
+			genesisHash = *params.GenesisHashByChainName(cfg.NodeCfg.Chain)
+			genesisConfig = internal.GenesisByChainName(cfg.NodeCfg.Chain)
+			chainConfig = params.ChainConfigByChainName(cfg.NodeCfg.Chain)
+		}
 		if err := chainKv.Update(ctx, func(tx kv.RwTx) error {
 			var genesisErr error
 			genesisBlock, genesisErr = WriteGenesisBlock(tx, genesisConfig)
@@ -192,8 +226,14 @@ func NewNode(cliCtx *cli.Context, cfg *conf.Config) (*Node, error) {
 		}
 	}
 
+	// The developer genesis is only generated once, so a changed --dev.period
+	// has to be applied to the stored chain config.
+	if cfg.NodeCfg.Chain == networkname.DevChainName {
+		setDevPeriod(chainConfig, cfg.NodeCfg.DevPeriod)
+	}
+
 	// update ChainConfig everytime
-	if cfg.NodeCfg.Chain != "private" {
+	if cfg.NodeCfg.Chain != "private" && cfg.NodeCfg.Chain != networkname.DevChainName {
 		if err := chainKv.Update(ctx, func(tx kv.RwTx) error {
 			genesisHash = *params.GenesisHashByChainName(cfg.NodeCfg.Chain)
 			genesisConfig = internal.GenesisByChainName(cfg.NodeCfg.Chain)
@@ -213,7 +253,7 @@ func NewNode(cliCtx *cli.Context, cfg *conf.Config) (*Node, error) {
 
 	cfg.ChainCfg = chainConfig
 
-	p2p, err := p2p.NewService(ctx, genesisBlock.Hash(), cfg.P2PCfg, cfg.NodeCfg)
+	p2p, err := p2p.NewService(ctx, cfg.ChainCfg, genesisBlock.Hash(), cfg.P2PCfg, cfg.NodeCfg)
 	if err != nil {
 		return nil, err
 	}
@@ -228,6 +268,7 @@ func NewNode(cliCtx *cli.Context, cfg *conf.Config) (*Node, error) {
 	}
 
 	bc, _ := internal.NewBlockChain(ctx, genesisBlock, engine, chainKv, p2p, cfg.ChainCfg)
+	p2p.SetForkHead(bc.CurrentBlock().Number64().Uint64())
 
 	if cfg.ChainCfg.Apos != nil {
 		depositContracts := make(map[types.Address]deposit.DepositContract, 0)
@@ -259,34 +300,35 @@ func NewNode(cliCtx *cli.Context, cfg *conf.Config) (*Node, error) {
 	pool, _ := txspool.NewTxsPool(ctx, bc, depositContract)
 
 	is := initialsync.NewService(ctx, &initialsync.Config{
-		Chain: bc,
-		P2P:   p2p,
+		Chain:        bc,
+		P2P:          p2p,
+		TrustedBlock: trustedBlock,
 	})
 
+	syncTracker := progress.NewTracker(
+		func() uint64 { return bc.CurrentBlock().Number64().Uint64() },
+		func() uint64 { return p2p.Peers().HighestBlockNumber().Uint64() },
+	)
+
 	syncServer := astsync.NewService(
 		ctx,
 		astsync.WithP2P(p2p),
 		astsync.WithChainService(bc),
 		astsync.WithInitialSync(is),
+		astsync.WithTxsPool(pool),
 	)
 
-	//todo
-	var txs []*transaction.Transaction
-	pending := pool.Pending(false)
-	for _, batch := range pending {
-		txs = append(txs, batch...)
+	miner := miner.NewMiner(ctx, cfg, bc, engine, pool, nil)
+
+	var dbPruner *pruner.Pruner
+	if cfg.DatabaseCfg.Pruning() {
+		dbPruner = pruner.New(ctx, chainKv, bc, cfg.DatabaseCfg)
 	}
-	var bloom *types.Bloom
-	if len(txs) > 0 {
-		bloom, _ = types.NewBloom(uint64(len(txs)))
-		for _, tx := range txs {
-			hash := tx.Hash()
-			bloom.Add(hash.Bytes())
-		}
+	var freezer *pruner.Freezer
+	if cfg.DatabaseCfg.Freeze && ancients != nil {
+		freezer = pruner.NewFreezer(ctx, chainKv, bc, ancients)
 	}
 
-	miner := miner.NewMiner(ctx, cfg, bc, engine, pool, nil)
-
 	keyDir, isEphem, err := getKeyStoreDir(&cfg.NodeCfg)
 	if err != nil {
 		return nil, err
@@ -310,6 +352,9 @@ func NewNode(cliCtx *cli.Context, cfg *conf.Config) (*Node, error) {
 		txspool:         pool,
 		engine:          engine,
 		depositContract: depositContract,
+		pruner:          dbPruner,
+		ancients:        ancients,
+		freezer:         freezer,
 
 		inprocHandler: jsonrpc.NewServer(),
 		http:          newHTTPServer(),
@@ -326,6 +371,8 @@ func NewNode(cliCtx *cli.Context, cfg *conf.Config) (*Node, error) {
 		p2p:  p2p,
 		sync: syncServer,
 		is:   is,
+
+		syncTracker: syncTracker,
 	}
 
 	// Apply flags.
@@ -350,7 +397,22 @@ func NewNode(cliCtx *cli.Context, cfg *conf.Config) (*Node, error) {
 	log.Info("")
 
 	node.api = api.NewAPI(bc, chainKv, engine, pool, node.AccountManager(), cfg.ChainCfg)
-	node.api.SetGpo(api.NewOracle(bc, miner, cfg.ChainCfg, gpoParams))
+	node.api.SetGpo(api.NewOracle(bc, miner, pool, cfg.ChainCfg, gpoParams))
+	node.api.SetSyncTracker(syncTracker)
+
+	if cfg.Bundler.Enabled {
+		userOpPool, err := aa.NewPool(ctx, cfg.ChainCfg.ChainID, cfg.Bundler)
+		if err != nil {
+			return nil, err
+		}
+		node.userOpPool = userOpPool
+		node.api.SetUserOpPool(userOpPool)
+		if cfg.Bundler.Account != "" {
+			if node.bundler, err = aa.NewBundler(ctx, userOpPool, api.NewUserOpBackend(node.api)); err != nil {
+				return nil, err
+			}
+		}
+	}
 	return &node, nil
 }
 
@@ -412,6 +474,7 @@ func (n *Node) Start() error {
 	n.rpcAPIs = append(n.rpcAPIs, n.api.Apis()...)
 	n.rpcAPIs = append(n.rpcAPIs, tracers.APIs(n.api)...)
 	n.rpcAPIs = append(n.rpcAPIs, debug.APIs()...)
+	n.rpcAPIs = append(n.rpcAPIs, n.apis()...)
 
 	if err := n.startRPC(); err != nil {
 		log.Error("failed start jsonrpc service", zap.Error(err))
@@ -428,6 +491,21 @@ func (n *Node) Start() error {
 		n.depositContract.Start()
 	}
 
+	if n.pruner != nil {
+		n.pruner.Start()
+	}
+	if n.freezer != nil {
+		n.freezer.Start()
+	}
+
+	if n.userOpPool != nil {
+		n.userOpPool.Start()
+	}
+	if n.bundler != nil {
+		n.bundler.Start()
+	}
+
+	n.syncTracker.Start(n.ctx)
 	go n.is.Start()
 
 	log.Debug("node setup success!")
@@ -528,10 +606,10 @@ func (n *Node) startRPC() error {
 		return err
 	}
 
-	if n.ipc.endpoint != "" {
-		//if err := n.ipc.start(n.rpcAPIs); err != nil {
-		//	return err
-		//}
+	if n.config.NodeCfg.IPC && n.ipc.endpoint != "" {
+		if err := n.ipc.start(n.rpcAPIs); err != nil {
+			return err
+		}
 	}
 	if n.config.NodeCfg.HTTP {
 		//todo []string{"eth", "web3", "debug", "net", "apoa", "txpool", "apos"}
@@ -609,6 +687,16 @@ func (n *Node) stopRPC() {
 	n.stopInProc()
 }
 
+// Attach creates an RPC client attached to an in-process API handler.
+func (n *Node) Attach() *jsonrpc.Client {
+	return jsonrpc.DialInProc(n.inprocHandler)
+}
+
+// IPCEndpoint retrieves the current IPC endpoint used by the protocol stack.
+func (n *Node) IPCEndpoint() string {
+	return n.ipc.endpoint
+}
+
 // InstanceDir retrieves the instance directory used by the protocol stack.
 func (n *Node) InstanceDir() string {
 	return n.config.NodeCfg.DataDir
@@ -659,8 +747,22 @@ func (n *Node) stopServices() []error {
 		errs = append(errs, err)
 	}
 
-	if err := n.depositContract.Stop(); err != nil {
-		errs = append(errs, err)
+	if n.depositContract != nil {
+		if err := n.depositContract.Stop(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if n.pruner != nil {
+		if err := n.pruner.Stop(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if n.freezer != nil {
+		if err := n.freezer.Stop(); err != nil {
+			errs = append(errs, err)
+		}
 	}
 
 	if err := n.is.Stop(); err != nil {
@@ -675,6 +777,18 @@ func (n *Node) stopServices() []error {
 		errs = append(errs, err)
 	}
 
+	if n.bundler != nil {
+		if err := n.bundler.Stop(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if n.userOpPool != nil {
+		if err := n.userOpPool.Stop(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
 	return errs
 }
 
@@ -685,6 +799,12 @@ func (n *Node) doClose(errs []error) error {
 	n.lock.Lock()
 	n.state = closedState
 	n.db.Close()
+	if n.ancients != nil {
+		rawdb.SetAncientStore(nil)
+		if err := n.ancients.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
 	n.lock.Unlock()
 
 	if err := n.accman.Close(); err != nil {
@@ -713,6 +833,17 @@ func (n *Node) doClose(errs []error) error {
 	}
 }
 
+// setDevPeriod overrides the block period of a developer chain config.
+func setDevPeriod(chainConfig *params.ChainConfig, period uint64) {
+	if chainConfig.Apos == nil {
+		return
+	}
+	if current := chainConfig.Apos.Period; current != period {
+		log.Warn("Overriding the block period of the developer chain", "stored", current, "period", period)
+		chainConfig.Apos.Period = period
+	}
+}
+
 func (n *Node) Wait() {
 	<-n.shutDown
 }
@@ -731,6 +862,12 @@ func (n *Node) Database() kv.RwDB {
 	return n.db
 }
 
+// AncientStore returns the ancient store of the node, nil if it runs without a
+// data directory.
+func (n *Node) AncientStore() *ancient.Freezer {
+	return n.ancients
+}
+
 // getKeyStoreDir retrieves the key directory and will create
 // and ephemeral one if necessary.
 func getKeyStoreDir(conf *conf.NodeConfig) (string, bool, error) {
@@ -0,0 +1,42 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"testing"
+
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/internal"
+	"github.com/n42blockchain/N42/params"
+)
+
+func TestSetDevPeriod(t *testing.T) {
+	genesis := internal.DeveloperGenesisBlock(5, types.Address{1})
+	if genesis.Config.Apos == nil || genesis.Config.Apos.Period != 5 {
+		t.Fatalf("developer genesis: have apos config %v, want period 5", genesis.Config.Apos)
+	}
+	if params.DevChainConfig.Apos.Period != 0 {
+		t.Fatalf("developer genesis modified the shared chain config")
+	}
+
+	setDevPeriod(genesis.Config, 2)
+	if genesis.Config.Apos.Period != 2 {
+		t.Errorf("apos: have period %d, want 2", genesis.Config.Apos.Period)
+	}
+	// Configs without APos are left alone.
+	setDevPeriod(&params.ChainConfig{}, 1)
+}
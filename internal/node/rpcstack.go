@@ -447,7 +447,7 @@ type ipcServer struct {
 }
 
 func newIPCServer(config *conf.NodeConfig) *ipcServer {
-	return &ipcServer{endpoint: fmt.Sprintf("%s/%s", config.DataDir, config.IPCPath)}
+	return &ipcServer{endpoint: config.IPCEndpoint()}
 }
 
 func (is *ipcServer) start(apis []jsonrpc.API) error {
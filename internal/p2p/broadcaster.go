@@ -23,7 +23,7 @@ const (
 var ErrMessageNotMapped = errors.New("message type is not mapped to a PubSub topic")
 
 // Broadcast a message to the p2p network, the message is assumed to be
-// broadcasted to the current fork.
+// broadcasted to the current fork, or to the fork of the block for blocks.
 func (s *Service) Broadcast(ctx context.Context, msg proto.Message) error {
 	ctx, span := trace.StartSpan(ctx, "p2p.Broadcast")
 	defer span.End()
@@ -31,7 +31,7 @@ func (s *Service) Broadcast(ctx context.Context, msg proto.Message) error {
 	ctx, cancel := context.WithTimeout(ctx, maxBroadcastTime)
 	defer cancel()
 
-	forkDigest, err := s.currentForkDigest()
+	forkDigest, err := s.messageForkDigest(msg)
 	if err != nil {
 		err := errors.Wrap(err, "could not retrieve fork digest")
 		//tracing.AnnotateError(span, err)
@@ -53,16 +53,44 @@ func (s *Service) InterceptAccept(n network.ConnMultiaddrs) (allow bool) {
 		log.Trace("Not accepting inbound dial from ip address", "peer", n.RemoteMultiaddr(), "reason", "exceeded dial limit")
 		return false
 	}
-	if s.isPeerAtLimit(true /* inbound */) {
+	// The peer is not known before the handshake, so only the connections
+	// from the addresses of trusted peers are let through at the peer limit.
+	if s.isPeerAtLimit(true /* inbound */) && !s.isTrustedAddr(n.RemoteMultiaddr()) {
 		log.Trace("Not accepting inbound dial", "peer", n.RemoteMultiaddr(), "reason", "at peer limit")
 		return false
 	}
 	return filterConnections(s.addrFilter, n.RemoteMultiaddr())
 }
 
+// isTrustedAddr reports whether the ip address of addr is one of the addresses
+// known of the trusted peers.
+func (s *Service) isTrustedAddr(addr multiaddr.Multiaddr) bool {
+	ip, err := manet.ToIP(addr)
+	if err != nil {
+		return false
+	}
+	for _, pid := range s.peers.Trusted() {
+		for _, known := range s.host.Peerstore().Addrs(pid) {
+			if knownIP, err := manet.ToIP(known); err == nil && knownIP.Equal(ip) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // InterceptSecured tests whether a given connection, now authenticated,
-// is allowed.
-func (_ *Service) InterceptSecured(_ network.Direction, _ peer.ID, _ network.ConnMultiaddrs) (allow bool) {
+// is allowed. The connections let through at the peer limit on accept, from
+// the addresses of trusted peers, are checked again now that the identity of
+// the peer is known.
+func (s *Service) InterceptSecured(direction network.Direction, pid peer.ID, n network.ConnMultiaddrs) (allow bool) {
+	if direction != network.DirInbound || s.peers.IsTrusted(pid) {
+		return true
+	}
+	if s.isPeerAtLimit(true /* inbound */) {
+		log.Trace("Not accepting inbound dial", "peer", n.RemoteMultiaddr(), "reason", "at peer limit")
+		return false
+	}
 	return true
 }
 
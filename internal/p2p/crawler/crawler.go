@@ -0,0 +1,356 @@
+// Package crawler walks the discovery v5 DHT of the N42 network, connects to
+// the nodes found and performs the status handshake with them, recording their
+// head, fork digest, client version and reachability.
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/libp2p/go-libp2p/p2p/protocol/identify"
+	"github.com/libp2p/go-libp2p/p2p/security/noise"
+	"github.com/libp2p/go-libp2p/p2p/transport/tcp"
+	"github.com/n42blockchain/N42/api/protocol/sync_pb"
+	"github.com/n42blockchain/N42/common/crypto"
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/internal/p2p"
+	"github.com/n42blockchain/N42/internal/p2p/discover"
+	"github.com/n42blockchain/N42/internal/p2p/encoder"
+	"github.com/n42blockchain/N42/internal/p2p/enode"
+	p2ptypes "github.com/n42blockchain/N42/internal/p2p/types"
+	"github.com/n42blockchain/N42/params"
+	"github.com/n42blockchain/N42/utils"
+	ssz "github.com/prysmaticlabs/fastssz"
+)
+
+const (
+	// defaultWorkers is the default number of nodes checked concurrently.
+	defaultWorkers = 16
+
+	// defaultDialTimeout is the default time given to the connection and to
+	// the status handshake with a node.
+	defaultDialTimeout = 10 * time.Second
+
+	// identifyTimeout is the time waited for the identify exchange, which
+	// carries the client version, once connected.
+	identifyTimeout = 3 * time.Second
+)
+
+// Config are the settings of a crawl.
+type Config struct {
+	Bootnodes   []*enode.Node // Nodes the walk of the DHT starts from
+	ListenAddr  string        // UDP address of the discovery listener, any port of all interfaces if empty
+	Workers     int           // Number of nodes checked concurrently
+	DialTimeout time.Duration // Time given to the connection and the handshake with a node
+}
+
+func (cfg Config) withDefaults() Config {
+	if cfg.ListenAddr == "" {
+		cfg.ListenAddr = "0.0.0.0:0"
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = defaultWorkers
+	}
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = defaultDialTimeout
+	}
+	return cfg
+}
+
+// Crawler finds the nodes of the network through discovery v5 and checks them
+// with a libp2p host of its own. Both use ephemeral keys.
+type Crawler struct {
+	cfg      Config
+	disc     *discover.UDPv5
+	host     host.Host
+	encoding encoder.NetworkEncoding
+}
+
+// New starts the discovery listener and the libp2p host of a crawler.
+func New(cfg Config) (*Crawler, error) {
+	cfg = cfg.withDefaults()
+	disc, err := StartDiscovery(cfg.ListenAddr, cfg.Bootnodes)
+	if err != nil {
+		return nil, err
+	}
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		disc.Close()
+		return nil, err
+	}
+	ifaceKey, err := utils.ConvertToInterfacePrivkey(key)
+	if err != nil {
+		disc.Close()
+		return nil, err
+	}
+	h, err := libp2p.New(
+		libp2p.Identity(ifaceKey),
+		libp2p.NoListenAddrs,
+		libp2p.UserAgent(params.Version+"/crawler"),
+		libp2p.Transport(tcp.NewTCPTransport),
+		libp2p.DefaultMuxers,
+		libp2p.Security(noise.ID, noise.New),
+		libp2p.DisableRelay(),
+		libp2p.Ping(false),
+	)
+	if err != nil {
+		disc.Close()
+		return nil, err
+	}
+	return &Crawler{cfg: cfg, disc: disc, host: h, encoding: &encoder.SszNetworkEncoder{}}, nil
+}
+
+// StartDiscovery starts a discovery v5 listener on the UDP address addr, with
+// an ephemeral key and an in-memory node database.
+func StartDiscovery(addr string, bootnodes []*enode.Node) (*discover.UDPv5, error) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	db, err := enode.OpenDB("", "")
+	if err != nil {
+		return nil, err
+	}
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	ln := enode.NewLocalNode(db, key)
+	ln.SetFallbackIP(net.IP{127, 0, 0, 1})
+	ln.SetFallbackUDP(conn.LocalAddr().(*net.UDPAddr).Port)
+	return discover.ListenV5(conn, ln, discover.Config{PrivateKey: key, Bootnodes: bootnodes})
+}
+
+// Close stops the discovery listener and the libp2p host.
+func (c *Crawler) Close() {
+	c.disc.Close()
+	if err := c.host.Close(); err != nil {
+		log.Debug("Could not close the crawler host", "err", err)
+	}
+}
+
+// Run checks the nodes of input again, then walks the DHT and checks every
+// node found until ctx is done. It returns the nodes of input and those found,
+// updated with the results of their checks.
+func (c *Crawler) Run(ctx context.Context, input NodeSet) NodeSet {
+	var (
+		output = make(NodeSet, len(input))
+		lock   sync.Mutex
+		seen   = make(map[enode.ID]bool)
+		queue  = make(chan *enode.Node)
+		wg     sync.WaitGroup
+
+		checked, reachable int
+	)
+	for id, n := range input {
+		entry := *n
+		output[id] = &entry
+	}
+	for i := 0; i < c.cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range queue {
+				// the checks started before the end of the crawl are completed
+				result := c.check(context.Background(), n)
+
+				lock.Lock()
+				if prev := output[n.ID()]; prev != nil {
+					result.FirstSeen = prev.FirstSeen
+					if !result.Reachable {
+						result.LastSeen = prev.LastSeen
+					}
+				}
+				output[n.ID()] = result
+				if result.Reachable {
+					reachable++
+				}
+				checked++
+				lock.Unlock()
+				log.Info("Checked node", "id", n.ID(), "ip", n.IP(), "reachable", result.Reachable, "version", result.ClientVersion, "head", result.Head, "checked", checked, "reachableNodes", reachable)
+			}
+		}()
+	}
+
+	// feed queues n unless it was already checked during this run.
+	feed := func(n *enode.Node) bool {
+		if seen[n.ID()] {
+			return true
+		}
+		seen[n.ID()] = true
+		select {
+		case queue <- n:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	it := c.disc.RandomNodes()
+	go func() {
+		<-ctx.Done()
+		it.Close()
+	}()
+	func() {
+		for _, n := range input {
+			record := n.Record
+			if fresh, err := c.disc.RequestENR(record); err == nil && fresh.Seq() >= record.Seq() {
+				record = fresh
+			}
+			if !feed(record) {
+				return
+			}
+		}
+		for it.Next() {
+			if !feed(it.Node()) {
+				return
+			}
+		}
+	}()
+	close(queue)
+	wg.Wait()
+	return output
+}
+
+// check connects to n and performs the status handshake with it. A node is
+// reachable if the connection succeeds, even if the handshake fails.
+func (c *Crawler) check(ctx context.Context, n *enode.Node) *Node {
+	now := time.Now()
+	result := &Node{Record: n, FirstSeen: now, LastCheck: now}
+	if id, err := p2p.NodeForkID(n); err == nil {
+		result.ForkDigest = fmt.Sprintf("%#x", id.Hash)
+		result.ForkNext = id.Next
+	}
+	if n.IP() == nil || n.TCP() == 0 {
+		result.Error = "no TCP endpoint"
+		return result
+	}
+	info, err := p2p.AddrInfoFromNode(n)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.PeerID = info.ID.String()
+
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.DialTimeout)
+	defer cancel()
+	defer func() {
+		if err := c.host.Network().ClosePeer(info.ID); err != nil {
+			log.Trace("Could not close the connection", "peer", info.ID, "err", err)
+		}
+		c.host.Peerstore().RemovePeer(info.ID)
+		c.host.Peerstore().ClearAddrs(info.ID)
+	}()
+	if err := c.host.Connect(ctx, *info); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Reachable = true
+	result.LastSeen = now
+	result.ClientVersion = c.clientVersion(ctx, info.ID)
+
+	// The nodes predating the fork ID network upgrade only speak the v1
+	// status protocol, whose status has no fork ID.
+	var status *sync_pb.Status
+	forkStatus := &p2ptypes.ForkStatus{Status: emptyStatus()}
+	if err := c.requestStatus(ctx, info.ID, p2p.RPCStatusTopicV2, forkStatus, forkStatus); err == nil {
+		status = forkStatus.Status
+		result.ForkDigest = fmt.Sprintf("%#x", forkStatus.ForkHash)
+		result.ForkNext = forkStatus.ForkNext
+	} else {
+		status = &sync_pb.Status{}
+		if err := c.requestStatus(ctx, info.ID, p2p.RPCStatusTopicV1, emptyStatus(), status); err != nil {
+			result.Error = err.Error()
+			return result
+		}
+	}
+	if status.GenesisHash == nil {
+		result.Error = "status without genesis hash"
+		return result
+	}
+	result.GenesisHash = types.Hash(utils.ConvertH256ToHash(status.GenesisHash))
+	if status.CurrentHeight != nil {
+		result.Head = utils.ConvertH256ToUint256Int(status.CurrentHeight).Uint64()
+	}
+	return result
+}
+
+// emptyStatus returns the status the crawler sends, of no network.
+func emptyStatus() *sync_pb.Status {
+	return &sync_pb.Status{
+		GenesisHash:   utils.ConvertHashToH256(types.Hash{}),
+		CurrentHeight: utils.ConvertUint256IntToH256(uint256.NewInt(0)),
+	}
+}
+
+// requestStatus performs the status handshake with the peer pid over the given
+// status protocol, reading the status of the peer into resp. The status req
+// sent is empty, the nodes consider the crawler to be of another network:
+// they answer with their own status and then say goodbye.
+func (c *Crawler) requestStatus(ctx context.Context, pid peer.ID, baseTopic string, req ssz.Marshaler, resp ssz.Unmarshaler) error {
+	topic := baseTopic + c.encoding.ProtocolSuffix()
+	stream, err := c.host.NewStream(ctx, pid, protocol.ID(topic))
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := stream.SetDeadline(deadline); err != nil {
+			log.Trace("Could not set the stream deadline", "err", err)
+		}
+	}
+	if _, err := c.encoding.EncodeWithMaxLength(stream, req); err != nil {
+		_ = stream.Reset()
+		return err
+	}
+	if err := stream.CloseWrite(); err != nil {
+		_ = stream.Reset()
+		return err
+	}
+
+	code := make([]byte, 1)
+	if _, err := stream.Read(code); err != nil {
+		return err
+	}
+	if code[0] != 0 {
+		msg := &p2ptypes.ErrorMessage{}
+		if err := c.encoding.DecodeWithMaxLength(stream, msg); err != nil {
+			return err
+		}
+		return fmt.Errorf("status refused with code %d: %s", code[0], string(*msg))
+	}
+	return c.encoding.DecodeWithMaxLength(stream, resp)
+}
+
+// clientVersion returns the agent version the peer pid announced through
+// identify.
+func (c *Crawler) clientVersion(ctx context.Context, pid peer.ID) string {
+	conns := c.host.Network().ConnsToPeer(pid)
+	if ids, ok := c.host.(interface{ IDService() identify.IDService }); ok && len(conns) > 0 {
+		ctx, cancel := context.WithTimeout(ctx, identifyTimeout)
+		defer cancel()
+		select {
+		case <-ids.IDService().IdentifyWait(conns[0]):
+		case <-ctx.Done():
+		}
+	}
+	agent, err := c.host.Peerstore().Get(pid, "AgentVersion")
+	if err != nil {
+		return ""
+	}
+	version, _ := agent.(string)
+	return version
+}
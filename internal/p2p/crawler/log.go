@@ -0,0 +1,7 @@
+package crawler
+
+import (
+	astLog "github.com/n42blockchain/N42/log"
+)
+
+var log = astLog.New("prefix", "crawler")
@@ -0,0 +1,75 @@
+package crawler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/internal/p2p/enode"
+)
+
+// Node is the record of a node found by the crawler.
+type Node struct {
+	Record        *enode.Node `json:"record"`
+	PeerID        string      `json:"peerId,omitempty"`
+	ClientVersion string      `json:"clientVersion,omitempty"`
+	GenesisHash   types.Hash  `json:"genesisHash"`
+	Head          uint64      `json:"head"`
+	ForkDigest    string      `json:"forkDigest,omitempty"`
+	ForkNext      uint64      `json:"forkNext,omitempty"`
+	Reachable     bool        `json:"reachable"`
+	Error         string      `json:"error,omitempty"`
+	FirstSeen     time.Time   `json:"firstSeen"`
+	LastSeen      time.Time   `json:"lastSeen,omitempty"`
+	LastCheck     time.Time   `json:"lastCheck"`
+}
+
+// NodeSet is the content of a nodes file, keyed by node ID.
+type NodeSet map[enode.ID]*Node
+
+// Nodes returns the nodes of the set, sorted by ID.
+func (ns NodeSet) Nodes() []*Node {
+	result := make([]*Node, 0, len(ns))
+	for _, n := range ns {
+		result = append(result, n)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Record.ID().String() < result[j].Record.ID().String()
+	})
+	return result
+}
+
+// LoadNodes reads the nodes file written by WriteNodes.
+func LoadNodes(file string) (NodeSet, error) {
+	blob, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var set NodeSet
+	if err := json.Unmarshal(blob, &set); err != nil {
+		return nil, fmt.Errorf("invalid nodes file %s: %w", file, err)
+	}
+	for id, n := range set {
+		if n == nil || n.Record == nil || n.Record.ID() != id {
+			return nil, fmt.Errorf("invalid nodes file %s: bad entry %v", file, id)
+		}
+	}
+	return set, nil
+}
+
+// WriteNodes writes the set to the nodes file, replacing it atomically.
+func WriteNodes(file string, set NodeSet) error {
+	blob, err := json.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return err
+	}
+	blob = append(blob, '\n')
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, blob, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
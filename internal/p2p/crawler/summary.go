@@ -0,0 +1,71 @@
+package crawler
+
+import (
+	"sort"
+)
+
+// unknown is the key under which the nodes without a client version or a
+// fork digest are counted.
+const unknown = "unknown"
+
+// Count is the number of nodes sharing a value.
+type Count struct {
+	Value string
+	Nodes int
+}
+
+// Summary is the distribution of the client versions and of the fork digests
+// of a set of crawled nodes.
+type Summary struct {
+	Nodes       int
+	Reachable   int
+	HighestHead uint64
+	Versions    []Count // Client versions of the reachable nodes
+	Forks       []Count // Fork digests of all the nodes
+}
+
+// Summarize counts the client versions and the fork digests of the nodes of set.
+// The counts are sorted by decreasing number of nodes.
+func Summarize(set NodeSet) Summary {
+	var (
+		summary  = Summary{Nodes: len(set)}
+		versions = make(map[string]int)
+		forks    = make(map[string]int)
+	)
+	for _, n := range set {
+		fork := n.ForkDigest
+		if fork == "" {
+			fork = unknown
+		}
+		forks[fork]++
+		if !n.Reachable {
+			continue
+		}
+		summary.Reachable++
+		version := n.ClientVersion
+		if version == "" {
+			version = unknown
+		}
+		versions[version]++
+		if n.Head > summary.HighestHead {
+			summary.HighestHead = n.Head
+		}
+	}
+	summary.Versions = sortCounts(versions)
+	summary.Forks = sortCounts(forks)
+	return summary
+}
+
+func sortCounts(counts map[string]int) []Count {
+	result := make([]Count, 0, len(counts))
+	for value, nodes := range counts {
+		result = append(result, Count{Value: value, Nodes: nodes})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Nodes != result[j].Nodes {
+			return result[i].Nodes > result[j].Nodes
+		}
+		return result[i].Value < result[j].Value
+	})
+	return result
+}
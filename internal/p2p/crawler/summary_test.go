@@ -0,0 +1,91 @@
+package crawler
+
+import (
+	"net"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/n42blockchain/N42/common/crypto"
+	"github.com/n42blockchain/N42/internal/p2p/enode"
+	"github.com/n42blockchain/N42/internal/p2p/enr"
+)
+
+func testNode(t *testing.T, port int) *enode.Node {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r enr.Record
+	r.Set(enr.IP(net.IP{127, 0, 0, 1}))
+	r.Set(enr.TCP(port))
+	r.Set(enr.UDP(port))
+	if err := enode.SignV4(&r, key); err != nil {
+		t.Fatal(err)
+	}
+	n, err := enode.New(enode.ValidSchemes, &r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func testSet(t *testing.T) NodeSet {
+	entries := []*Node{
+		{Reachable: true, ClientVersion: "0.1.0", ForkDigest: "0x01020304", Head: 10},
+		{Reachable: true, ClientVersion: "0.1.0", ForkDigest: "0x01020304", Head: 12},
+		{Reachable: true, ClientVersion: "0.2.0", ForkDigest: "0x05060708", Head: 20},
+		{Reachable: true, ForkDigest: "0x01020304", Head: 11},
+		{ForkDigest: "0x05060708"},
+		{},
+	}
+	set := make(NodeSet)
+	now := time.Now().UTC().Truncate(time.Second)
+	for i, entry := range entries {
+		entry.Record = testNode(t, 30000+i)
+		entry.FirstSeen, entry.LastCheck = now, now
+		set[entry.Record.ID()] = entry
+	}
+	return set
+}
+
+func TestSummarize(t *testing.T) {
+	summary := Summarize(testSet(t))
+	want := Summary{
+		Nodes:       6,
+		Reachable:   4,
+		HighestHead: 20,
+		Versions:    []Count{{"0.1.0", 2}, {"0.2.0", 1}, {unknown, 1}},
+		Forks:       []Count{{"0x01020304", 3}, {"0x05060708", 2}, {unknown, 1}},
+	}
+	if !reflect.DeepEqual(summary, want) {
+		t.Fatalf("summary %+v, want %+v", summary, want)
+	}
+}
+
+func TestNodesFile(t *testing.T) {
+	set := testSet(t)
+	file := filepath.Join(t.TempDir(), "nodes.json")
+	if err := WriteNodes(file, set); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadNodes(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != len(set) {
+		t.Fatalf("loaded %d nodes, want %d", len(loaded), len(set))
+	}
+	for id, n := range set {
+		l := loaded[id]
+		if l == nil {
+			t.Fatalf("node %v missing", id)
+		}
+		if l.Record.String() != n.Record.String() || l.ClientVersion != n.ClientVersion ||
+			l.ForkDigest != n.ForkDigest || l.Head != n.Head || l.Reachable != n.Reachable ||
+			!l.FirstSeen.Equal(n.FirstSeen) {
+			t.Errorf("node %v: loaded %+v, want %+v", id, l, n)
+		}
+	}
+}
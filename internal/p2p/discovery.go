@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/n42blockchain/N42/conf"
 	"github.com/n42blockchain/N42/internal/p2p/discover"
+	"github.com/n42blockchain/N42/internal/p2p/dnsdisc"
 	"github.com/n42blockchain/N42/internal/p2p/enode"
 	"github.com/n42blockchain/N42/internal/p2p/enr"
 	"github.com/n42blockchain/N42/params"
@@ -48,9 +49,32 @@ func (s *Service) RefreshENR() {
 	s.pingPeers()
 }
 
+// nodeIterator returns the nodes found by the discovery v5 listener mixed with
+// those of the DNS node lists configured.
+func (s *Service) nodeIterator() (enode.Iterator, error) {
+	mix := enode.NewFairMix(discmixTimeout)
+	if s.dv5Listener != nil {
+		mix.AddSource(s.dv5Listener.RandomNodes())
+	}
+	if len(s.cfg.DNSDiscoveryURLs) > 0 {
+		client := dnsdisc.NewClient(dnsdisc.Config{})
+		it, err := client.NewIterator(s.cfg.DNSDiscoveryURLs...)
+		if err != nil {
+			mix.Close()
+			return nil, err
+		}
+		mix.AddSource(it)
+	}
+	return mix, nil
+}
+
 // listen for new nodes watches for new nodes in the network and adds them to the peerstore.
 func (s *Service) listenForNewNodes() {
-	iterator := s.dv5Listener.RandomNodes()
+	iterator, err := s.nodeIterator()
+	if err != nil {
+		log.Error("Could not start DNS discovery", "err", err)
+		return
+	}
 	iterator = enode.Filter(iterator, s.filterPeer)
 	defer iterator.Close()
 	for {
@@ -193,7 +217,7 @@ func (s *Service) createLocalNode(
 	localNode.SetFallbackIP(ipAddr)
 	localNode.SetFallbackUDP(udpPort)
 
-	localNode, err = addForkEntry(localNode, s.genesisHash)
+	localNode, err = addForkEntry(localNode, s.forkEntry())
 	if err != nil {
 		return nil, errors.Wrap(err, "could not add eth2 fork version entry to enr")
 	}
@@ -371,6 +395,12 @@ func convertToMultiAddr(nodes []*enode.Node) []ma.Multiaddr {
 	return multiAddrs
 }
 
+// AddrInfoFromNode returns the libp2p address of the TCP endpoint of node.
+func AddrInfoFromNode(node *enode.Node) (*peer.AddrInfo, error) {
+	info, _, err := convertToAddrInfo(node)
+	return info, err
+}
+
 func convertToAddrInfo(node *enode.Node) (*peer.AddrInfo, ma.Multiaddr, error) {
 	multiAddr, err := convertToSingleMultiAddr(node)
 	if err != nil {
@@ -0,0 +1,223 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dnsdisc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/n42blockchain/N42/common/crypto"
+	"github.com/n42blockchain/N42/common/mclock"
+	"github.com/n42blockchain/N42/internal/p2p/enode"
+	"github.com/n42blockchain/N42/internal/p2p/enr"
+)
+
+// mapResolver is a stub resolver answering TXT queries from a map.
+type mapResolver map[string]string
+
+func newMapResolver(maps ...map[string]string) mapResolver {
+	mr := make(mapResolver)
+	for _, m := range maps {
+		mr.add(m)
+	}
+	return mr
+}
+
+func (mr mapResolver) add(m map[string]string) {
+	for k, v := range m {
+		mr[k] = v
+	}
+}
+
+func (mr mapResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if record, ok := mr[name]; ok {
+		return []string{record}, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func testKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func testNodes(t *testing.T, n int) []*enode.Node {
+	nodes := make([]*enode.Node, n)
+	for i := range nodes {
+		var r enr.Record
+		r.Set(enr.IP(net.IPv4(127, 0, 0, byte(i+1))))
+		r.Set(enr.TCP(30303))
+		r.Set(enr.UDP(30303))
+		if err := enode.SignV4(&r, testKey(t)); err != nil {
+			t.Fatal(err)
+		}
+		node, err := enode.New(enode.ValidSchemes, &r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodes[i] = node
+	}
+	return nodes
+}
+
+func makeTestTree(t *testing.T, domain string, nodes []*enode.Node, links []string) (*Tree, string) {
+	tree, err := MakeTree(1, nodes, links)
+	if err != nil {
+		t.Fatal(err)
+	}
+	url, err := tree.Sign(testKey(t), domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree, url
+}
+
+func nodeIDs(nodes []*enode.Node) map[enode.ID]bool {
+	ids := make(map[enode.ID]bool, len(nodes))
+	for _, n := range nodes {
+		ids[n.ID()] = true
+	}
+	return ids
+}
+
+func TestClientSyncTree(t *testing.T) {
+	nodes := testNodes(t, 40)
+	tree, url := makeTestTree(t, "n", nodes, nil)
+	c := NewClient(Config{Resolver: newMapResolver(tree.ToTXT("n")), RateLimit: 1000})
+
+	synced, err := c.SyncTree(url)
+	if err != nil {
+		t.Fatal("sync error:", err)
+	}
+	if !reflect.DeepEqual(nodeIDs(synced.Nodes()), nodeIDs(nodes)) {
+		t.Errorf("wrong nodes in synced tree: %d nodes, want %d", len(synced.Nodes()), len(nodes))
+	}
+	if synced.Seq() != tree.Seq() || synced.Signature() != tree.Signature() {
+		t.Errorf("wrong root in synced tree: seq %d sig %s", synced.Seq(), synced.Signature())
+	}
+}
+
+func TestClientSyncTreeBadSignature(t *testing.T) {
+	tree, url := makeTestTree(t, "n", testNodes(t, 3), nil)
+	// Sign the records with another key than the one of the URL.
+	records := tree.ToTXT("n")
+	other, _ := makeTestTree(t, "n", testNodes(t, 3), nil)
+	records["n"] = other.ToTXT("n")["n"]
+
+	c := NewClient(Config{Resolver: newMapResolver(records), RateLimit: 1000})
+	if _, err := c.SyncTree(url); err == nil {
+		t.Fatal("expected error for root signed with another key")
+	}
+}
+
+func TestIteratorLinks(t *testing.T) {
+	nodes := testNodes(t, 20)
+	tree1, url1 := makeTestTree(t, "t1", nodes[:10], nil)
+	tree2, url2 := makeTestTree(t, "t2", nodes[10:], []string{url1})
+	c := NewClient(Config{
+		Resolver:  newMapResolver(tree1.ToTXT("t1"), tree2.ToTXT("t2")),
+		RateLimit: 1000,
+	})
+
+	it, err := c.NewIterator(url2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+
+	want := nodeIDs(nodes)
+	seen := make(map[enode.ID]bool)
+	deadline := time.Now().Add(10 * time.Second)
+	for len(seen) < len(want) && time.Now().Before(deadline) {
+		if !it.Next() {
+			t.Fatal("iterator ended")
+		}
+		id := it.Node().ID()
+		if !want[id] {
+			t.Fatalf("iterator returned unknown node %v", id)
+		}
+		seen[id] = true
+	}
+	if len(seen) != len(want) {
+		t.Fatalf("iterator returned %d of %d nodes", len(seen), len(want))
+	}
+}
+
+func TestIteratorRootRecheck(t *testing.T) {
+	nodes := testNodes(t, 4)
+	key := testKey(t)
+	resolver := newMapResolver()
+	clock := new(mclock.Simulated)
+
+	tree1, err := MakeTree(1, nodes[:2], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	url, _ := tree1.Sign(key, "n")
+	resolver.add(tree1.ToTXT("n"))
+
+	c := NewClient(Config{Resolver: resolver, RateLimit: 1000, RecheckInterval: 20 * time.Minute})
+	c.clock = clock
+	it, err := c.NewIterator(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+
+	checkIterator(t, it, nodes[:2])
+
+	// Publish a new tree with the other nodes under the same domain, and
+	// advance the clock past the recheck interval.
+	tree2, err := MakeTree(2, nodes[2:], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree2.Sign(key, "n")
+	resolver.add(tree2.ToTXT("n"))
+	clock.Run(c.cfg.RecheckInterval + 1*time.Second)
+
+	checkIterator(t, it, nodes[2:])
+}
+
+// checkIterator reads nodes from it until all of want were returned, failing
+// on any other node.
+func checkIterator(t *testing.T, it enode.Iterator, want []*enode.Node) {
+	t.Helper()
+	wantIDs := nodeIDs(want)
+	seen := make(map[enode.ID]bool)
+	for i := 0; len(seen) < len(wantIDs) && i < 100; i++ {
+		if !it.Next() {
+			t.Fatal("iterator ended")
+		}
+		id := it.Node().ID()
+		if !wantIDs[id] {
+			t.Fatalf("iterator returned unexpected node %v", id)
+		}
+		seen[id] = true
+	}
+	if len(seen) != len(wantIDs) {
+		t.Fatalf("iterator returned %d of %d nodes", len(seen), len(wantIDs))
+	}
+}
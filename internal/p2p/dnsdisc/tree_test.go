@@ -0,0 +1,107 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dnsdisc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/n42blockchain/N42/common/crypto"
+	"github.com/n42blockchain/N42/internal/p2p/enode"
+)
+
+func TestTreeToTXT(t *testing.T) {
+	nodes := testNodes(t, 30)
+	tree, url := makeTestTree(t, "nodes.example.org", nodes, nil)
+	records := tree.ToTXT("nodes.example.org")
+
+	root, ok := records["nodes.example.org"]
+	if !ok || !strings.HasPrefix(root, rootPrefix) {
+		t.Fatalf("missing root record: %q", root)
+	}
+	for name, txt := range records {
+		if name == "nodes.example.org" {
+			continue
+		}
+		hash := strings.TrimSuffix(name, ".nodes.example.org")
+		if !isValidHash(hash) {
+			t.Errorf("invalid subdomain %q", name)
+		}
+		e, err := parseEntry(txt, enode.ValidSchemes)
+		if err != nil {
+			t.Errorf("can't parse record %q: %v", txt, err)
+			continue
+		}
+		if subdomain(e) != hash {
+			t.Errorf("record %q stored at %q, want %q", txt, hash, subdomain(e))
+		}
+		if len(txt) > 400 && !strings.HasPrefix(txt, enrPrefix) {
+			t.Errorf("record %q too large for a DNS response", txt)
+		}
+	}
+	if _, _, err := ParseURL(url); err != nil {
+		t.Fatalf("invalid tree URL %q: %v", url, err)
+	}
+}
+
+func TestTreeSignature(t *testing.T) {
+	key := testKey(t)
+	tree, err := MakeTree(3, testNodes(t, 2), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	url, err := tree.Sign(key, "n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	domain, pubkey, err := ParseURL(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if domain != "n" || !reflect.DeepEqual(crypto.FromECDSAPub(pubkey), crypto.FromECDSAPub(&key.PublicKey)) {
+		t.Fatalf("URL %q does not match domain and key", url)
+	}
+	if err := tree.SetSignature(pubkey, tree.Signature()); err != nil {
+		t.Fatalf("signature rejected: %v", err)
+	}
+	if err := tree.SetSignature(&testKey(t).PublicKey, tree.Signature()); err == nil {
+		t.Fatal("signature accepted for another key")
+	}
+}
+
+func TestParseEntry(t *testing.T) {
+	tests := []struct {
+		input string
+		err   error
+	}{
+		{"enrtree-branch:", nil},
+		{"enrtree-branch:AAAAAAAAAAAAAAAAAAAA", nil},
+		{"enrtree-branch:AAAAAAAAAAAAAAAAAAAA,BBBBBBBBBBBBBBBBBBBB", nil},
+		{"enrtree-branch:AAAAAAAAAAAAAAAAAAAA-", entryError{"branch", errInvalidChild}},
+		{"enrtree://AKPYQIUQIL7PSIACI32J7FGZW56E5FKHEFCCOFHILBIMW3M6LWXS2@nodes.example.org", nil},
+		{"enrtree://nodes.example.org", entryError{"link", errNoPubkey}},
+		{"enrtree://AP62DT7WOTEQZGQZOU474PP3KMEGVTTE7A7NPRXKX3DUD57@nodes.example.org", entryError{"link", errBadPubkey}},
+		{"enr:-----", entryError{"enr", errInvalidENR}},
+		{"foo", errUnknownEntry},
+	}
+	for i, test := range tests {
+		if _, err := parseEntry(test.input, enode.ValidSchemes); !reflect.DeepEqual(err, test.err) {
+			t.Errorf("test %d: error %v, want %v", i, err, test.err)
+		}
+	}
+}
@@ -118,9 +118,6 @@ func (f *filterIter) Next() bool {
 	for f.Iterator.Next() {
 		if f.check(f.Node()) {
 			return true
-		} else {
-			//todo
-			return false
 		}
 	}
 	return false
@@ -1,68 +1,130 @@
 package p2p
 
 import (
-	"bytes"
 	"fmt"
-	"github.com/holiman/uint256"
-	"github.com/n42blockchain/N42/common/types"
+
+	"github.com/n42blockchain/N42/api/protocol/sync_pb"
+	"github.com/n42blockchain/N42/api/protocol/types_pb"
+	"github.com/n42blockchain/N42/internal/avm/rlp"
 	"github.com/n42blockchain/N42/internal/p2p/enode"
 	"github.com/n42blockchain/N42/internal/p2p/enr"
 	"github.com/n42blockchain/N42/utils"
-	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
 )
 
 const amtENRKey = "astEnr"
 
 // ForkDigest returns the current fork digest of
-// the node according to the local clock.
+// the node according to the head of its chain.
 func (s *Service) currentForkDigest() ([4]byte, error) {
-	return utils.CreateForkDigest(new(uint256.Int), s.genesisHash)
+	return utils.ForkDigest(s.chainConfig, s.genesisHash, s.forkHead.Load()), nil
 }
 
-// Compares fork ENRs between an incoming peer's record and our node's
-// local record values for current and next fork version/epoch.
-func (s *Service) compareForkENR(record *enr.Record) error {
-	remoteForkDigest := make([]byte, 4)
-	entry := enr.WithEntry(amtENRKey, &remoteForkDigest)
-	err := record.Load(entry)
-	if err != nil {
-		return err
+// currentForkID returns the fork ID of the chain at the head last reported by
+// SetForkHead.
+func (s *Service) currentForkID() utils.ForkID {
+	return utils.NewForkID(s.chainConfig, s.genesisHash, s.forkHead.Load())
+}
+
+// messageForkDigest returns the fork digest of the gossip topic of msg. Blocks
+// are published under the digest of their own number, so that the first block
+// of a fork reaches the peers still at the block before it.
+func (s *Service) messageForkDigest(msg proto.Message) ([4]byte, error) {
+	if b, ok := msg.(*types_pb.Block); ok && b.Header != nil && b.Header.Number != nil {
+		return utils.CreateForkDigest(s.chainConfig, utils.ConvertH256ToUint256Int(b.Header.Number), s.genesisHash)
 	}
-	enrString, err := SerializeENR(record)
-	if err != nil {
-		return err
+	if a, ok := msg.(*sync_pb.NewBlockHash); ok && a.Number != nil {
+		return utils.CreateForkDigest(s.chainConfig, utils.ConvertH256ToUint256Int(a.Number), s.genesisHash)
+	}
+	return s.currentForkDigest()
+}
+
+// SetForkHead records the head block of the local chain, from which the fork
+// ID advertised in the ENR is derived. The ENR is updated when a fork activates.
+func (s *Service) SetForkHead(number uint64) {
+	prev := s.currentForkID()
+	s.forkHead.Store(number)
+	id := s.currentForkID()
+	if id == prev || s.dv5Listener == nil {
+		return
 	}
+	log.Info("Fork activated, updating ENR", "number", number, "forkHash", fmt.Sprintf("%#x", id.Hash), "forkNext", id.Next)
+	s.dv5Listener.LocalNode().Set(s.forkEntry())
+	s.RefreshENR()
+}
 
-	currentForkDigest, err := s.currentForkDigest()
+// forkEntry returns the fork entry of the local ENR: the fork ID from the fork
+// ID network upgrade on, and the legacy fork digest before.
+func (s *Service) forkEntry() enr.Entry {
+	head := s.forkHead.Load()
+	if !s.chainConfig.IsForkID(head) {
+		digest := utils.LegacyForkDigest(s.genesisHash)
+		return enr.WithEntry(amtENRKey, digest[:])
+	}
+	id := s.currentForkID()
+	return enr.WithEntry(amtENRKey, &id)
+}
+
+// Compares the fork ID in the ENR of an incoming peer with the one of the
+// local chain, following the rules of EIP-2124. The legacy fork digest of the
+// peers predating the fork ID network upgrade is accepted until the local
+// chain reaches it.
+func (s *Service) compareForkENR(record *enr.Record) error {
+	remote, legacy, err := loadForkEntry(record)
 	if err != nil {
-		err := errors.Wrap(err, "could not retrieve fork digest")
-		//tracing.AnnotateError(span, err)
 		return err
 	}
-
-	if !bytes.Equal(remoteForkDigest, currentForkDigest[:]) {
-		return fmt.Errorf(
-			"fork digest of peer with ENR %s: %v, does not match local value: %v",
-			enrString,
-			currentForkDigest,
-			remoteForkDigest,
-		)
+	if legacy {
+		if s.chainConfig.IsForkID(s.forkHead.Load()) {
+			enrString, _ := SerializeENR(record)
+			return fmt.Errorf("peer with ENR %s has no fork ID past the fork ID network upgrade", enrString)
+		}
+		if local := utils.LegacyForkDigest(s.genesisHash); remote.Hash != local {
+			enrString, _ := SerializeENR(record)
+			return fmt.Errorf("fork digest of peer with ENR %s: %#x, does not match local value: %#x", enrString, remote.Hash, local)
+		}
+		return nil
+	}
+	if err := s.forkFilter(remote); err != nil {
+		enrString, _ := SerializeENR(record)
+		local := s.currentForkID()
+		return fmt.Errorf("fork ID of peer with ENR %s: %#x/%d, incompatible with local value %#x/%d: %w",
+			enrString, remote.Hash, remote.Next, local.Hash, local.Next, err)
 	}
 	return nil
 }
 
-// Adds a fork entry as an ENR record under the Ethereum consensus EnrKey for
-// the local node. The fork entry is an ssz-encoded enrForkID type
-// which takes into account the current fork version from the current
-// epoch to create a fork digest, the next fork version,
-// and the next fork epoch.
-func addForkEntry(node *enode.LocalNode, genesisHash types.Hash) (*enode.LocalNode, error) {
-	//todo
-	enc, err := utils.CreateForkDigest(new(uint256.Int), genesisHash)
-	if err != nil {
-		return nil, err
+// NodeForkID returns the fork ID advertised in the ENR of node. The legacy fork
+// digest of the nodes predating the fork ID network upgrade is returned as the
+// hash of a fork ID without next fork.
+func NodeForkID(node *enode.Node) (utils.ForkID, error) {
+	id, _, err := loadForkEntry(node.Record())
+	return id, err
+}
+
+// loadForkEntry loads the fork entry of record: the RLP encoded EIP-2124 fork
+// ID, or the legacy fork digest, reported by legacy.
+func loadForkEntry(record *enr.Record) (id utils.ForkID, legacy bool, err error) {
+	var raw rlp.RawValue
+	if err := record.Load(enr.WithEntry(amtENRKey, &raw)); err != nil {
+		return id, false, err
+	}
+	if err := rlp.DecodeBytes(raw, &id); err == nil {
+		return id, false, nil
 	}
-	forkEntry := enr.WithEntry(amtENRKey, enc[:])
-	node.Set(forkEntry)
+	var digest []byte
+	if err := rlp.DecodeBytes(raw, &digest); err != nil || len(digest) != len(id.Hash) {
+		return id, false, fmt.Errorf("invalid fork entry %#x", []byte(raw))
+	}
+	copy(id.Hash[:], digest)
+	return id, true, nil
+}
+
+// Adds a fork entry as an ENR record under the astEnr key for the local node.
+// The fork entry is the RLP encoded EIP-2124 fork ID of the chain: the
+// checksum of the genesis hash and of the forks passed, and the next fork.
+// Before the fork ID network upgrade, it is the legacy fork digest.
+func addForkEntry(node *enode.LocalNode, entry enr.Entry) (*enode.LocalNode, error) {
+	node.Set(entry)
 	return node, nil
 }
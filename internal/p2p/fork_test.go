@@ -0,0 +1,79 @@
+package p2p
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/internal/p2p/enr"
+	"github.com/n42blockchain/N42/params"
+	"github.com/n42blockchain/N42/utils"
+)
+
+func newForkTestService(head uint64) *Service {
+	config := &params.ChainConfig{BeijingBlock: big.NewInt(100), ForkIDBlock: big.NewInt(200)}
+	genesis := types.Hash{0xaa, 0xbb, 0xcc, 0xdd, 0xee}
+	s := &Service{chainConfig: config, genesisHash: genesis}
+	s.forkHead.Store(head)
+	s.forkFilter = utils.NewForkFilter(config, genesis, s.forkHead.Load)
+	return s
+}
+
+func TestForkEntry(t *testing.T) {
+	// Before the fork ID network upgrade the legacy fork digest is advertised.
+	s := newForkTestService(150)
+	var r enr.Record
+	r.Set(s.forkEntry())
+	id, legacy, err := loadForkEntry(&r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !legacy || id.Hash != utils.LegacyForkDigest(s.genesisHash) {
+		t.Errorf("before the upgrade: fork entry %x, legacy %v, want the legacy digest", id, legacy)
+	}
+
+	// From the upgrade on, the fork ID.
+	s.forkHead.Store(200)
+	r.Set(s.forkEntry())
+	if id, legacy, err = loadForkEntry(&r); err != nil {
+		t.Fatal(err)
+	}
+	if legacy || id != utils.NewForkID(s.chainConfig, s.genesisHash, 200) {
+		t.Errorf("after the upgrade: fork entry %x, legacy %v, want the fork ID", id, legacy)
+	}
+
+	r.Set(enr.WithEntry(amtENRKey, []byte{1, 2}))
+	if _, _, err := loadForkEntry(&r); err == nil {
+		t.Error("invalid fork entry loaded")
+	}
+}
+
+func TestCompareForkENR(t *testing.T) {
+	pre, post := newForkTestService(150), newForkTestService(250)
+	var (
+		legacy     = utils.LegacyForkDigest(pre.genesisHash)
+		preForkID  = utils.NewForkID(pre.chainConfig, pre.genesisHash, 150)
+		postForkID = utils.NewForkID(pre.chainConfig, pre.genesisHash, 250)
+	)
+	tests := []struct {
+		name  string
+		local *Service
+		entry enr.Entry
+		ok    bool
+	}{
+		{"legacy peer before the upgrade", pre, enr.WithEntry(amtENRKey, legacy[:]), true},
+		{"legacy peer of another chain", pre, enr.WithEntry(amtENRKey, []byte{1, 2, 3, 4}), false},
+		{"legacy peer after the upgrade", post, enr.WithEntry(amtENRKey, legacy[:]), false},
+		{"upgraded peer ahead", pre, enr.WithEntry(amtENRKey, &postForkID), true},
+		{"upgraded peer behind", post, enr.WithEntry(amtENRKey, &preForkID), true},
+		{"upgraded peer on the same fork", post, enr.WithEntry(amtENRKey, &postForkID), true},
+		{"upgraded peer of another chain", post, enr.WithEntry(amtENRKey, &utils.ForkID{Hash: [4]byte{1, 2, 3, 4}}), false},
+	}
+	for _, tt := range tests {
+		var r enr.Record
+		r.Set(tt.entry)
+		if err := tt.local.compareForkENR(&r); (err == nil) != tt.ok {
+			t.Errorf("%s: error %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
@@ -16,6 +16,9 @@ const (
 	// beaconBlockWeight specifies the scoring weight that we apply to
 	// our beacon block topic.
 	beaconBlockWeight = 0.8
+	// blockHashWeight specifies the scoring weight that we apply to
+	// our block announcement topic.
+	blockHashWeight = 0.05
 	// aggregateWeight specifies the scoring weight that we apply to
 	// our aggregate topic.
 	aggregateWeight = 0.5
@@ -95,6 +98,9 @@ func peerScoringParams() (*pubsub.PeerScoreParams, *pubsub.PeerScoreThresholds)
 
 func (s *Service) topicScoreParams(topic string) (*pubsub.TopicScoreParams, error) {
 	switch {
+	// checked first, the name of the block topic is a prefix of it
+	case strings.Contains(topic, GossipBlockHashMessage):
+		return defaultBlockHashTopicParams(), nil
 	case strings.Contains(topic, GossipBlockMessage):
 		return defaultBlockTopicParams(), nil
 	case strings.Contains(topic, GossipExitMessage):
@@ -137,6 +143,28 @@ func defaultBlockTopicParams() *pubsub.TopicScoreParams {
 	}
 }
 
+func defaultBlockHashTopicParams() *pubsub.TopicScoreParams {
+	return &pubsub.TopicScoreParams{
+		TopicWeight:                     blockHashWeight,
+		TimeInMeshWeight:                maxInMeshScore / inMeshCap(),
+		TimeInMeshQuantum:               inMeshTime(),
+		TimeInMeshCap:                   inMeshCap(),
+		FirstMessageDeliveriesWeight:    2,
+		FirstMessageDeliveriesDecay:     scoreDecay(twentyBlocks),
+		FirstMessageDeliveriesCap:       20,
+		MeshMessageDeliveriesWeight:     0,
+		MeshMessageDeliveriesDecay:      0,
+		MeshMessageDeliveriesCap:        0,
+		MeshMessageDeliveriesThreshold:  0,
+		MeshMessageDeliveriesWindow:     0,
+		MeshMessageDeliveriesActivation: 0,
+		MeshFailurePenaltyWeight:        0,
+		MeshFailurePenaltyDecay:         0,
+		InvalidMessageDeliveriesWeight:  -2000,
+		InvalidMessageDeliveriesDecay:   scoreDecay(invalidDecayPeriod),
+	}
+}
+
 func defaultSyncContributionTopicParams() *pubsub.TopicScoreParams {
 	// Determine the expected message rate for the particular gossip topic.
 	//todo
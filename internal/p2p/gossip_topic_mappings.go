@@ -1,6 +1,7 @@
 package p2p
 
 import (
+	"github.com/n42blockchain/N42/api/protocol/sync_pb"
 	"github.com/n42blockchain/N42/api/protocol/types_pb"
 	"reflect"
 
@@ -11,6 +12,7 @@ import (
 // lookup.
 var gossipTopicMappings = map[string]proto.Message{
 	BlockTopicFormat:       &types_pb.Block{},
+	BlockHashTopicFormat:   &sync_pb.NewBlockHash{},
 	TransactionTopicFormat: &types_pb.Transaction{},
 }
 
@@ -11,6 +11,7 @@ import (
 	"github.com/n42blockchain/N42/api/protocol/sync_pb"
 	"github.com/n42blockchain/N42/conf"
 	"github.com/n42blockchain/N42/internal/p2p/encoder"
+	"github.com/n42blockchain/N42/internal/p2p/enode"
 	"github.com/n42blockchain/N42/internal/p2p/enr"
 	"github.com/n42blockchain/N42/internal/p2p/peers"
 	"google.golang.org/protobuf/proto"
@@ -84,6 +85,12 @@ type PeerManager interface {
 	DiscoveryAddresses() ([]multiaddr.Multiaddr, error)
 	RefreshENR()
 	AddPingMethod(reqFunc func(ctx context.Context, id peer.ID) error)
+	AddPeer(addr string, trusted bool) (peer.ID, error)
+	RemovePeer(peer.ID) error
+	RemoveTrustedPeer(peer.ID)
+	ForkDigest() ([4]byte, error)
+	SetForkHead(number uint64)
+	LocalNode() *enode.Node
 }
 
 // Sender abstracts the sending functionality from libp2p.
@@ -44,6 +44,10 @@ var (
 		Name: "p2p_status_message_missing",
 		Help: "The number of attempts the connection handler rejects a peer for a missing status message.",
 	})
+	reachabilityGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "p2p_reachability",
+		Help: "The reachability detected by AutoNAT: 0 unknown, 1 public, 2 private.",
+	})
 
 	// Gossip Tracer Metrics
 	pubsubTopicsActive = promauto.NewGaugeVec(prometheus.GaugeOpts{
@@ -0,0 +1,143 @@
+package p2p
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/event"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/p2p/protocol/circuitv2/proto"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+	"github.com/n42blockchain/N42/internal/p2p/enr"
+)
+
+// autoRelayBootDelay is how long a node unsure of its reachability waits
+// before it looks for relays.
+const autoRelayBootDelay = 1 * time.Minute
+
+// relayPeerSource offers connected peers as relay candidates to autorelay,
+// those known to run a relay service first.
+func (s *Service) relayPeerSource(ctx context.Context, num int) <-chan peer.AddrInfo {
+	ch := make(chan peer.AddrInfo, num)
+	defer close(ch)
+	if s.host == nil || s.peers == nil {
+		return ch
+	}
+
+	pids := s.host.Network().Peers()
+	rand.Shuffle(len(pids), func(i, j int) { pids[i], pids[j] = pids[j], pids[i] })
+	var relays, others []peer.ID
+	for _, pid := range pids {
+		if s.peers.IsBad(pid) {
+			continue
+		}
+		if supported, err := s.host.Peerstore().SupportsProtocols(pid, proto.ProtoIDv2Hop); err == nil && len(supported) > 0 {
+			relays = append(relays, pid)
+		} else {
+			others = append(others, pid)
+		}
+	}
+	for _, pid := range append(relays, others...) {
+		if len(ch) == num || ctx.Err() != nil {
+			break
+		}
+		info := s.host.Peerstore().PeerInfo(pid)
+		if len(info.Addrs) == 0 {
+			continue
+		}
+		ch <- info
+	}
+	return ch
+}
+
+// watchReachability follows the reachability detected by AutoNAT and the
+// addresses of the host, and advertises the public address in our ENR.
+func (s *Service) watchReachability() {
+	sub, err := s.host.EventBus().Subscribe([]interface{}{
+		new(event.EvtLocalReachabilityChanged),
+		new(event.EvtLocalAddressesUpdated),
+	})
+	if err != nil {
+		log.Error("Could not subscribe to reachability events", "err", err)
+		return
+	}
+	defer sub.Close()
+
+	reachability := network.ReachabilityUnknown
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case e, ok := <-sub.Out():
+			if !ok {
+				return
+			}
+			if ev, ok := e.(event.EvtLocalReachabilityChanged); ok {
+				reachability = ev.Reachability
+				reachabilityGauge.Set(float64(reachability))
+				log.Info("Local reachability changed", "reachability", reachability, "addrs", s.host.Addrs())
+			}
+			if reachability == network.ReachabilityPublic {
+				s.updateENRAddress()
+			}
+		}
+	}
+}
+
+// updateENRAddress sets the public address of the host in our ENR, unless an
+// external address was configured.
+func (s *Service) updateENRAddress() {
+	if s.dv5Listener == nil || s.cfg.HostAddress != "" || s.cfg.HostDNS != "" {
+		return
+	}
+	ip, port := publicAddr(s.host.Addrs())
+	if ip == nil {
+		return
+	}
+	localNode := s.dv5Listener.LocalNode()
+	if record := localNode.Node(); record.IP().Equal(ip) && record.TCP() == port {
+		return
+	}
+	localNode.SetStaticIP(ip)
+	localNode.Set(enr.TCP(port))
+	log.Info("Advertising public address in ENR", "ip", ip, "tcp", port)
+	s.RefreshENR()
+}
+
+// publicAddr returns the ip and tcp port of the first public, direct address,
+// preferring IPv4.
+func publicAddr(addrs []ma.Multiaddr) (net.IP, int) {
+	var (
+		ip   net.IP
+		port int
+	)
+	for _, addr := range addrs {
+		if _, err := addr.ValueForProtocol(ma.P_CIRCUIT); err == nil || !manet.IsPublicAddr(addr) {
+			continue
+		}
+		value, err := addr.ValueForProtocol(ma.P_TCP)
+		if err != nil {
+			continue
+		}
+		addrIP, err := manet.ToIP(addr)
+		if err != nil {
+			continue
+		}
+		addrPort, err := strconv.Atoi(value)
+		if err != nil {
+			continue
+		}
+		if addrIP.To4() != nil {
+			return addrIP, addrPort
+		}
+		if ip == nil {
+			ip, port = addrIP, addrPort
+		}
+	}
+	return ip, port
+}
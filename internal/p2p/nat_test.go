@@ -0,0 +1,120 @@
+package p2p
+
+import (
+	"net"
+	"testing"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	coretest "github.com/libp2p/go-libp2p/core/test"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/n42blockchain/N42/api/protocol/sync_pb"
+	"github.com/n42blockchain/N42/common/crypto"
+	"github.com/n42blockchain/N42/conf"
+	"github.com/n42blockchain/N42/internal/p2p/enode"
+)
+
+func TestPublicAddr(t *testing.T) {
+	relay := coretest.RandPeerIDFatal(t)
+	tests := []struct {
+		name  string
+		addrs []string
+		ip    string
+		port  int
+	}{
+		{"none", nil, "", 0},
+		{"private", []string{"/ip4/192.168.1.2/tcp/1000", "/ip4/127.0.0.1/tcp/1000"}, "", 0},
+		{"relayed", []string{"/ip4/1.2.3.4/tcp/1000/p2p/" + relay.String() + "/p2p-circuit"}, "", 0},
+		{"udp", []string{"/ip4/1.2.3.4/udp/1000/quic-v1"}, "", 0},
+		{"public", []string{"/ip4/192.168.1.2/tcp/1000", "/ip4/1.2.3.4/tcp/2000"}, "1.2.3.4", 2000},
+		{"ipv6", []string{"/ip6/2001:4860::1/tcp/3000"}, "2001:4860::1", 3000},
+		{"ipv4 first", []string{"/ip6/2001:4860::1/tcp/3000", "/ip4/1.2.3.4/tcp/2000"}, "1.2.3.4", 2000},
+	}
+	for _, tt := range tests {
+		var addrs []ma.Multiaddr
+		for _, addr := range tt.addrs {
+			addrs = append(addrs, ma.StringCast(addr))
+		}
+		ip, port := publicAddr(addrs)
+		if want := net.ParseIP(tt.ip); !ip.Equal(want) || port != tt.port {
+			t.Errorf("%s: got %v:%d, want %v:%d", tt.name, ip, port, want, tt.port)
+		}
+	}
+}
+
+// natTestHost is a host listening on the given addresses.
+type natTestHost struct {
+	host.Host
+	addrs []ma.Multiaddr
+}
+
+func (h *natTestHost) Addrs() []ma.Multiaddr { return h.addrs }
+
+// natTestListener is a discovery listener of which only the local node is used.
+type natTestListener struct {
+	Listener
+	localNode *enode.LocalNode
+}
+
+func (l *natTestListener) LocalNode() *enode.LocalNode { return l.localNode }
+
+func newNATTestService(t *testing.T, cfg *conf.P2PConfig, addrs ...string) *Service {
+	db, err := enode.OpenDB("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(db.Close)
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &natTestHost{}
+	for _, addr := range addrs {
+		h.addrs = append(h.addrs, ma.StringCast(addr))
+	}
+	return &Service{
+		cfg:         cfg,
+		host:        h,
+		dv5Listener: &natTestListener{localNode: enode.NewLocalNode(db, key)},
+		ping:        &sync_pb.Ping{},
+	}
+}
+
+func TestUpdateENRAddress(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *conf.P2PConfig
+		addrs   []string
+		updated bool
+	}{
+		{"public", &conf.P2PConfig{}, []string{"/ip4/192.168.1.2/tcp/1000", "/ip4/1.2.3.4/tcp/2000"}, true},
+		{"private", &conf.P2PConfig{}, []string{"/ip4/192.168.1.2/tcp/1000"}, false},
+		{"host address", &conf.P2PConfig{HostAddress: "5.6.7.8"}, []string{"/ip4/1.2.3.4/tcp/2000"}, false},
+		{"host dns", &conf.P2PConfig{HostDNS: "node.example.org"}, []string{"/ip4/1.2.3.4/tcp/2000"}, false},
+	}
+	for _, tt := range tests {
+		s := newNATTestService(t, tt.cfg, tt.addrs...)
+		s.updateENRAddress()
+		record := s.dv5Listener.LocalNode().Node()
+		if updated := record.IP().Equal(net.IPv4(1, 2, 3, 4)) && record.TCP() == 2000; updated != tt.updated {
+			t.Errorf("%s: ENR address %v:%d, updated %v, want %v", tt.name, record.IP(), record.TCP(), updated, tt.updated)
+		}
+		if refreshed := s.ping.SeqNumber == 1; refreshed != tt.updated {
+			t.Errorf("%s: sequence number %d, refreshed %v, want %v", tt.name, s.ping.SeqNumber, refreshed, tt.updated)
+		}
+	}
+
+	// An address already advertised does not refresh the ENR again.
+	s := newNATTestService(t, &conf.P2PConfig{}, "/ip4/1.2.3.4/tcp/2000")
+	s.updateENRAddress()
+	s.updateENRAddress()
+	if s.ping.SeqNumber != 1 {
+		t.Errorf("sequence number %d after an unchanged address, want 1", s.ping.SeqNumber)
+	}
+
+	// Without discovery there is no ENR to update.
+	s.dv5Listener = nil
+	s.updateENRAddress()
+	if s.ping.SeqNumber != 1 {
+		t.Errorf("sequence number %d without discovery, want 1", s.ping.SeqNumber)
+	}
+}
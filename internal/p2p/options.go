@@ -4,6 +4,7 @@ import (
 	"crypto/ecdsa"
 	"fmt"
 	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/p2p/host/autorelay"
 	libp2pquic "github.com/libp2p/go-libp2p/p2p/transport/quic"
 	"github.com/n42blockchain/N42/params"
 	"github.com/n42blockchain/N42/utils"
@@ -67,14 +68,29 @@ func (s *Service) buildOptions(ip net.IP, priKey *ecdsa.PrivateKey) []libp2p.Opt
 	options = append(options, libp2p.Security(noise.ID, noise.New))
 
 	if cfg.EnableUPnP {
-		options = append(options, libp2p.NATPortMap()) // Allow to use UPnP
+		options = append(options, libp2p.NATPortMap()) // Allow to use UPnP and NAT-PMP
+	}
+	if cfg.EnableNATService {
+		options = append(options, libp2p.EnableNATService())
 	}
 	if cfg.RelayNodeAddr != "" {
 		options = append(options, libp2p.AddrsFactory(withRelayAddrs(cfg.RelayNodeAddr)))
+	}
+	if cfg.RelayNodeAddr != "" || cfg.EnableHolePunching || cfg.EnableAutoRelay || cfg.EnableRelayService {
+		options = append(options, libp2p.EnableRelay())
 	} else {
-		// Disable relay if it has not been set.
+		// Disable relay if nothing makes use of it.
 		options = append(options, libp2p.DisableRelay())
 	}
+	if cfg.EnableRelayService {
+		options = append(options, libp2p.EnableRelayService())
+	}
+	if cfg.EnableHolePunching {
+		options = append(options, libp2p.EnableHolePunching())
+	}
+	if cfg.EnableAutoRelay {
+		options = append(options, libp2p.EnableAutoRelayWithPeerSource(s.relayPeerSource, autorelay.WithBootDelay(autoRelayBootDelay)))
+	}
 	if cfg.HostAddress != "" {
 		options = append(options, libp2p.AddrsFactory(func(addrs []ma.Multiaddr) []ma.Multiaddr {
 			external, err := MultiAddressBuilder(cfg.HostAddress, uint(cfg.TCPPort))
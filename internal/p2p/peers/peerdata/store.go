@@ -60,6 +60,7 @@ type PeerData struct {
 	ConnState     PeerConnectionState
 	Enr           *enr.Record
 	NextValidTime time.Time
+	LastSeen      time.Time
 	// Chain related data.
 	Ping                      *sync_pb.Ping
 	ChainState                *sync_pb.Status
@@ -69,6 +70,12 @@ type PeerData struct {
 	BadResponses         int
 	ProcessedBlocks      uint64
 	BlockProviderUpdated time.Time
+	// Transaction propagation data.
+	TxsDelivered     uint64
+	TxsDuplicated    uint64
+	TxsTimeouts      int
+	TxsBytesReceived uint64
+	TxsBytesSent     uint64
 	// Gossip Scoring data.
 	TopicScores      map[string]*msg_proto.TopicScoreSnapshot
 	GossipScore      float64
@@ -0,0 +1,181 @@
+package scorers
+
+import (
+	"github.com/n42blockchain/N42/internal/p2p/peers/peerdata"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+var _ Scorer = (*TxProviderScorer)(nil)
+
+const (
+	// DefaultTxProviderTimeoutThreshold defines how many unanswered transaction requests to tolerate
+	// before peer is deemed bad.
+	DefaultTxProviderTimeoutThreshold = 8
+	// DefaultTxProviderDuplicateThreshold defines the share of duplicate transactions a peer may
+	// deliver before it starts being penalized.
+	DefaultTxProviderDuplicateThreshold = 0.5
+	// DefaultTxProviderDecayInterval defines how often the decaying routine is called.
+	DefaultTxProviderDecayInterval = 1 * time.Minute
+	// DefaultTxProviderPenaltyFactor defines the penalty factor applied to a peer based on its
+	// duplicate share and request timeouts.
+	DefaultTxProviderPenaltyFactor = 5
+)
+
+// TxProviderScorer represents transaction propagation scoring service. It tracks how many
+// useful and duplicate transactions every peer delivered, how often it failed to answer
+// our requests and the bandwidth spent on it in both directions.
+type TxProviderScorer struct {
+	config *TxProviderScorerConfig
+	store  *peerdata.Store
+}
+
+// TxProviderScorerConfig holds configuration parameters for transaction provider scoring service.
+type TxProviderScorerConfig struct {
+	// TimeoutThreshold specifies number of request timeouts tolerated, before peer is banned.
+	TimeoutThreshold int
+	// DuplicateThreshold specifies the tolerated share of duplicate transactions.
+	DuplicateThreshold float64
+	// DecayInterval specifies how often stats should be decayed.
+	DecayInterval time.Duration
+}
+
+// newTxProviderScorer creates transaction provider scoring service.
+func newTxProviderScorer(store *peerdata.Store, config *TxProviderScorerConfig) *TxProviderScorer {
+	if config == nil {
+		config = &TxProviderScorerConfig{}
+	}
+	scorer := &TxProviderScorer{
+		config: config,
+		store:  store,
+	}
+	if scorer.config.TimeoutThreshold == 0 {
+		scorer.config.TimeoutThreshold = DefaultTxProviderTimeoutThreshold
+	}
+	if scorer.config.DuplicateThreshold == 0 {
+		scorer.config.DuplicateThreshold = DefaultTxProviderDuplicateThreshold
+	}
+	if scorer.config.DecayInterval == 0 {
+		scorer.config.DecayInterval = DefaultTxProviderDecayInterval
+	}
+	return scorer
+}
+
+// Score returns score (penalty) of the transaction propagation behaviour of a peer.
+func (s *TxProviderScorer) Score(pid peer.ID) float64 {
+	s.store.RLock()
+	defer s.store.RUnlock()
+	return s.score(pid)
+}
+
+// score is a lock-free version of Score.
+func (s *TxProviderScorer) score(pid peer.ID) float64 {
+	if s.isBadPeer(pid) {
+		return BadPeerScore
+	}
+	score := float64(0)
+	peerData, ok := s.store.PeerData(pid)
+	if !ok {
+		return score
+	}
+	if total := peerData.TxsDelivered + peerData.TxsDuplicated; total > 0 {
+		if share := float64(peerData.TxsDuplicated) / float64(total); share > s.config.DuplicateThreshold {
+			score -= (share - s.config.DuplicateThreshold) / (1 - s.config.DuplicateThreshold) * DefaultTxProviderPenaltyFactor
+		}
+	}
+	if peerData.TxsTimeouts > 0 {
+		score -= float64(peerData.TxsTimeouts) / float64(s.config.TimeoutThreshold) * DefaultTxProviderPenaltyFactor
+	}
+	return score
+}
+
+// Params exposes scorer's parameters.
+func (s *TxProviderScorer) Params() *TxProviderScorerConfig {
+	return s.config
+}
+
+// TxsDelivered records a batch of transactions received from the given peer, split into
+// transactions that were new to us and duplicates, together with the bytes received.
+func (s *TxProviderScorer) TxsDelivered(pid peer.ID, useful, duplicates int, bytes uint64) {
+	s.store.Lock()
+	defer s.store.Unlock()
+
+	peerData := s.store.PeerDataGetOrCreate(pid)
+	peerData.TxsDelivered += uint64(useful)
+	peerData.TxsDuplicated += uint64(duplicates)
+	peerData.TxsBytesReceived += bytes
+}
+
+// TxsServed records the bytes of transactions sent to the given peer.
+func (s *TxProviderScorer) TxsServed(pid peer.ID, bytes uint64) {
+	s.store.Lock()
+	defer s.store.Unlock()
+
+	peerData := s.store.PeerDataGetOrCreate(pid)
+	peerData.TxsBytesSent += bytes
+}
+
+// TxsRequestTimeout records a transaction request the given peer failed to answer in time.
+func (s *TxProviderScorer) TxsRequestTimeout(pid peer.ID) {
+	s.store.Lock()
+	defer s.store.Unlock()
+
+	peerData := s.store.PeerDataGetOrCreate(pid)
+	peerData.TxsTimeouts++
+}
+
+// Bandwidth returns the transaction bytes received from and sent to the given peer.
+func (s *TxProviderScorer) Bandwidth(pid peer.ID) (received, sent uint64) {
+	s.store.RLock()
+	defer s.store.RUnlock()
+
+	if peerData, ok := s.store.PeerData(pid); ok {
+		return peerData.TxsBytesReceived, peerData.TxsBytesSent
+	}
+	return 0, 0
+}
+
+// IsBadPeer states if the peer is to be considered bad.
+func (s *TxProviderScorer) IsBadPeer(pid peer.ID) bool {
+	s.store.RLock()
+	defer s.store.RUnlock()
+	return s.isBadPeer(pid)
+}
+
+// isBadPeer is lock-free version of IsBadPeer.
+func (s *TxProviderScorer) isBadPeer(pid peer.ID) bool {
+	if peerData, ok := s.store.PeerData(pid); ok {
+		return peerData.TxsTimeouts >= s.config.TimeoutThreshold
+	}
+	return false
+}
+
+// BadPeers returns the peers that are considered bad.
+func (s *TxProviderScorer) BadPeers() []peer.ID {
+	s.store.RLock()
+	defer s.store.RUnlock()
+
+	badPeers := make([]peer.ID, 0)
+	for pid := range s.store.Peers() {
+		if s.isBadPeer(pid) {
+			badPeers = append(badPeers, pid)
+		}
+	}
+	return badPeers
+}
+
+// Decay halves the delivery counters and forgives one timeout of every peer, so that
+// the score reflects recent behaviour.
+func (s *TxProviderScorer) Decay() {
+	s.store.Lock()
+	defer s.store.Unlock()
+
+	for _, peerData := range s.store.Peers() {
+		peerData.TxsDelivered /= 2
+		peerData.TxsDuplicated /= 2
+		if peerData.TxsTimeouts > 0 {
+			peerData.TxsTimeouts--
+		}
+	}
+}
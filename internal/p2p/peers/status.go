@@ -79,6 +79,7 @@ type Status struct {
 	scorers   *scorers.Service
 	store     *peerdata.Store
 	ipTracker map[string]uint64
+	trusted   map[peer.ID]bool
 	rand      *rand.Rand
 }
 
@@ -100,6 +101,7 @@ func NewStatus(ctx context.Context, config *StatusConfig) *Status {
 		store:     store,
 		scorers:   scorers.NewService(ctx, store, config.ScorerParams),
 		ipTracker: map[string]uint64{},
+		trusted:   map[peer.ID]bool{},
 		// Random generator used to calculate dial backoff period.
 		// It is ok to use deterministic generator, no need for true entropy.
 		rand: rand.NewDeterministicGenerator(),
@@ -111,6 +113,37 @@ func (p *Status) Scorers() *scorers.Service {
 	return p.scorers
 }
 
+// SetTrusted marks or unmarks a peer as trusted. Trusted peers are never
+// considered bad and are never pruned to make room for other peers.
+func (p *Status) SetTrusted(pid peer.ID, trusted bool) {
+	p.store.Lock()
+	defer p.store.Unlock()
+
+	if trusted {
+		p.trusted[pid] = true
+	} else {
+		delete(p.trusted, pid)
+	}
+}
+
+// IsTrusted reports whether the peer was marked as trusted.
+func (p *Status) IsTrusted(pid peer.ID) bool {
+	p.store.RLock()
+	defer p.store.RUnlock()
+	return p.trusted[pid]
+}
+
+// Trusted returns the peers marked as trusted.
+func (p *Status) Trusted() []peer.ID {
+	p.store.RLock()
+	defer p.store.RUnlock()
+	pids := make([]peer.ID, 0, len(p.trusted))
+	for pid := range p.trusted {
+		pids = append(pids, pid)
+	}
+	return pids
+}
+
 // MaxPeerLimit returns the max peer limit stored in the current peer store.
 func (p *Status) MaxPeerLimit() int {
 	return p.store.Config().MaxPeers
@@ -148,6 +181,39 @@ func (p *Status) Add(record *enr.Record, pid peer.ID, address ma.Multiaddr, dire
 	p.addIpToTracker(pid)
 }
 
+// Restore adds a disconnected peer known from a previous run of the node,
+// along with the time it was last connected and its scorer data.
+func (p *Status) Restore(pid peer.ID, address ma.Multiaddr, lastSeen time.Time, badResponses int, processedBlocks uint64) {
+	p.store.Lock()
+	defer p.store.Unlock()
+
+	if _, ok := p.store.PeerData(pid); ok {
+		return
+	}
+	p.store.SetPeerData(pid, &peerdata.PeerData{
+		Address:         address,
+		Direction:       network.DirOutbound,
+		ConnState:       PeerDisconnected,
+		LastSeen:        lastSeen,
+		BadResponses:    badResponses,
+		ProcessedBlocks: processedBlocks,
+	})
+	p.addIpToTracker(pid)
+}
+
+// LastSeen returns the time the given remote peer was last connected, the
+// zero time if it never was.
+// This will error if the peer does not exist.
+func (p *Status) LastSeen(pid peer.ID) (time.Time, error) {
+	p.store.RLock()
+	defer p.store.RUnlock()
+
+	if peerData, ok := p.store.PeerData(pid); ok {
+		return peerData.LastSeen, nil
+	}
+	return time.Time{}, peerdata.ErrPeerUnknown
+}
+
 // Address returns the multiaddress of the given remote peer.
 // This will error if the peer does not exist.
 func (p *Status) Address(pid peer.ID) (ma.Multiaddr, error) {
@@ -299,6 +365,9 @@ func (p *Status) SetConnectionState(pid peer.ID, state peerdata.PeerConnectionSt
 	defer p.store.Unlock()
 
 	peerData := p.store.PeerDataGetOrCreate(pid)
+	if state == PeerConnected || peerData.ConnState == PeerConnected {
+		peerData.LastSeen = time.Now()
+	}
 	peerData.ConnState = state
 }
 
@@ -336,6 +405,9 @@ func (p *Status) IsBad(pid peer.ID) bool {
 
 // isBad is the lock-free version of IsBad.
 func (p *Status) isBad(pid peer.ID) bool {
+	if p.trusted[pid] {
+		return false
+	}
 	return p.isfromBadIP(pid) || p.scorers.IsBadPeerNoLock(pid)
 }
 
@@ -651,7 +723,7 @@ func (p *Status) PeersToPrune() []peer.ID {
 	// Select connected and inbound peers to prune.
 	for pid, peerData := range p.store.Peers() {
 		if peerData.ConnState == PeerConnected &&
-			peerData.Direction == network.DirInbound {
+			peerData.Direction == network.DirInbound && !p.trusted[pid] {
 			peersToPrune = append(peersToPrune, &peerResp{
 				pid:   pid,
 				score: p.scorers.ScoreNoLock(pid),
@@ -699,9 +771,9 @@ func (p *Status) PeersToPrune() []peer.ID {
 func (p *Status) HighestBlockNumber() *uint256.Int {
 	p.store.RLock()
 	defer p.store.RUnlock()
-	var highestSlot *uint256.Int
+	highestSlot := uint256.NewInt(0)
 	for _, peerData := range p.store.Peers() {
-		if peerData != nil && peerData.ChainState != nil && peerData.CurrentHeight().Cmp(highestSlot) == 1 {
+		if peerData != nil && peerData.ChainState != nil && peerData.ChainState.CurrentHeight != nil && peerData.CurrentHeight().Cmp(highestSlot) == 1 {
 			highestSlot = peerData.CurrentHeight()
 		}
 	}
@@ -0,0 +1,262 @@
+package p2p
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	"github.com/multiformats/go-multiaddr"
+	"github.com/n42blockchain/N42/internal/p2p/peers"
+)
+
+const (
+	// peerStorePath is the file of the data directory the known peers are
+	// persisted to, so that they are dialed again after a restart.
+	peerStorePath = "peers.json"
+
+	// peerStoreInterval is the interval at which the known peers are persisted.
+	peerStoreInterval = 5 * time.Minute
+
+	// maxStoredPeers is the maximum number of peers persisted, trusted peers
+	// coming first and the others by descending score.
+	maxStoredPeers = 256
+
+	// maxStoredPeerAge is the time after which a peer that was not connected
+	// is forgotten, unless it is trusted.
+	maxStoredPeerAge = 7 * 24 * time.Hour
+
+	// reconnectPeers is the interval at which the disconnected trusted and
+	// static peers are redialed.
+	reconnectPeers = 30 * time.Second
+)
+
+// storedPeer is the record of a known peer in the peer store.
+type storedPeer struct {
+	ID              string    `json:"id"`
+	Addrs           []string  `json:"addrs"`
+	Trusted         bool      `json:"trusted,omitempty"`
+	LastSeen        time.Time `json:"lastSeen"`
+	Score           float64   `json:"score"`
+	BadResponses    int       `json:"badResponses,omitempty"`
+	ProcessedBlocks uint64    `json:"processedBlocks,omitempty"`
+}
+
+// peerStore persists the peers known to the node in its data directory.
+type peerStore struct {
+	path  string
+	lock  sync.Mutex
+	peers map[peer.ID]*storedPeer
+}
+
+// loadPeerStore reads the peer store of the data directory dir, dropping the
+// entries that cannot be decoded or are too old.
+func loadPeerStore(dir string) (*peerStore, error) {
+	ps := &peerStore{path: path.Join(dir, peerStorePath), peers: make(map[peer.ID]*storedPeer)}
+	blob, err := os.ReadFile(ps.path)
+	if os.IsNotExist(err) {
+		return ps, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []*storedPeer
+	if err := json.Unmarshal(blob, &entries); err != nil {
+		return nil, fmt.Errorf("invalid peer store %s: %w", ps.path, err)
+	}
+	for _, entry := range entries {
+		pid, err := peer.Decode(entry.ID)
+		if err != nil {
+			log.Debug("Dropping invalid peer store entry", "id", entry.ID, "err", err)
+			continue
+		}
+		if !entry.Trusted && time.Since(entry.LastSeen) > maxStoredPeerAge {
+			continue
+		}
+		ps.peers[pid] = entry
+	}
+	return ps, nil
+}
+
+// save writes the peer store, replacing the previous file atomically.
+func (ps *peerStore) save() error {
+	ps.lock.Lock()
+	entries := make([]*storedPeer, 0, len(ps.peers))
+	for _, entry := range ps.peers {
+		entries = append(entries, entry)
+	}
+	ps.lock.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Trusted != entries[j].Trusted {
+			return entries[i].Trusted
+		}
+		if entries[i].Score != entries[j].Score {
+			return entries[i].Score > entries[j].Score
+		}
+		return entries[i].LastSeen.After(entries[j].LastSeen)
+	})
+	if len(entries) > maxStoredPeers {
+		entries = entries[:maxStoredPeers]
+	}
+	blob, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := ps.path + ".tmp"
+	if err := os.WriteFile(tmp, blob, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ps.path)
+}
+
+// restorePeers adds the peers of the peer store to the peer status and to the
+// address book of the host, marks the trusted ones and dials the trusted peers
+// and the best scored others up to the peer limit.
+func (s *Service) restorePeers() {
+	s.peerStore.lock.Lock()
+	var candidates []peer.AddrInfo
+	for pid, entry := range s.peerStore.peers {
+		if pid == s.host.ID() {
+			continue
+		}
+		var addrs []multiaddr.Multiaddr
+		for _, addr := range entry.Addrs {
+			if maddr, err := multiaddr.NewMultiaddr(addr); err == nil {
+				addrs = append(addrs, maddr)
+			}
+		}
+		if len(addrs) == 0 {
+			continue
+		}
+		ttl := peerstore.AddressTTL
+		if entry.Trusted {
+			ttl = peerstore.PermanentAddrTTL
+			s.peers.SetTrusted(pid, true)
+		}
+		s.host.Peerstore().AddAddrs(pid, addrs, ttl)
+		s.peers.Restore(pid, addrs[0], entry.LastSeen, entry.BadResponses, entry.ProcessedBlocks)
+		if !s.peers.IsBad(pid) {
+			candidates = append(candidates, peer.AddrInfo{ID: pid, Addrs: addrs})
+		}
+	}
+	s.peerStore.lock.Unlock()
+
+	sort.Slice(candidates, func(i, j int) bool {
+		ti, tj := s.peers.IsTrusted(candidates[i].ID), s.peers.IsTrusted(candidates[j].ID)
+		if ti != tj {
+			return ti
+		}
+		return s.peers.Scorers().Score(candidates[i].ID) > s.peers.Scorers().Score(candidates[j].ID)
+	})
+	log.Info("Restored peers from the peer store", "peers", len(candidates), "trusted", len(s.peers.Trusted()))
+	for i, info := range candidates {
+		if i >= s.cfg.MaxPeers && !s.peers.IsTrusted(info.ID) {
+			break
+		}
+		go func(info peer.AddrInfo) {
+			if err := s.redialPeer(s.ctx, info); err != nil {
+				log.Trace("Could not connect with stored peer", "peer", info.ID, "err", err)
+			}
+		}(info)
+	}
+}
+
+// savePeers records the peers known to the peer status in the peer store and
+// persists it.
+func (s *Service) savePeers() {
+	now := time.Now()
+	s.peerStore.lock.Lock()
+	for _, pid := range s.peers.All() {
+		lastSeen, err := s.peers.LastSeen(pid)
+		if err != nil {
+			continue
+		}
+		if connState, err := s.peers.ConnState(pid); err == nil && connState == peers.PeerConnected {
+			lastSeen = now
+		}
+		trusted := s.peers.IsTrusted(pid)
+		if lastSeen.IsZero() && !trusted {
+			continue
+		}
+		var addrs []string
+		for _, addr := range s.host.Peerstore().Addrs(pid) {
+			addrs = append(addrs, addr.String())
+		}
+		if len(addrs) == 0 {
+			if addr, err := s.peers.Address(pid); err == nil && addr != nil {
+				addrs = append(addrs, addr.String())
+			}
+		}
+		if len(addrs) == 0 {
+			continue
+		}
+		badResponses, _ := s.peers.Scorers().BadResponsesScorer().Count(pid)
+		if badResponses < 0 {
+			badResponses = 0
+		}
+		s.peerStore.peers[pid] = &storedPeer{
+			ID:              pid.String(),
+			Addrs:           addrs,
+			Trusted:         trusted,
+			LastSeen:        lastSeen,
+			Score:           s.peers.Scorers().Score(pid),
+			BadResponses:    badResponses,
+			ProcessedBlocks: s.peers.Scorers().BlockProviderScorer().ProcessedBlocks(pid),
+		}
+	}
+	for pid, entry := range s.peerStore.peers {
+		entry.Trusted = s.peers.IsTrusted(pid)
+		if !entry.Trusted && now.Sub(entry.LastSeen) > maxStoredPeerAge {
+			delete(s.peerStore.peers, pid)
+		}
+	}
+	s.peerStore.lock.Unlock()
+
+	if err := s.peerStore.save(); err != nil {
+		log.Error("Failed to save the peer store", "err", err)
+	}
+}
+
+// addTrustedPeers marks the peers of the given multiaddrs or ENRs as trusted
+// and dials them.
+func (s *Service) addTrustedPeers(addrs []string) {
+	for _, addr := range addrs {
+		// make each dial non-blocking
+		go func(addr string) {
+			if _, err := s.AddPeer(addr, true); err != nil {
+				log.Warn("Could not connect with trusted peer", "peer", addr, "err", err)
+			}
+		}(addr)
+	}
+}
+
+// ensureTrustedPeerConnections redials the trusted and static peers that are
+// not connected, at the addresses known to the host.
+func (s *Service) ensureTrustedPeerConnections() {
+	pids := s.peers.Trusted()
+	s.staticPeersLock.Lock()
+	pids = append(pids, s.staticPeers...)
+	s.staticPeersLock.Unlock()
+
+	for _, pid := range pids {
+		if pid == s.host.ID() || s.host.Network().Connectedness(pid) == network.Connected {
+			continue
+		}
+		addrs := s.host.Peerstore().Addrs(pid)
+		if len(addrs) == 0 {
+			continue
+		}
+		go func(info peer.AddrInfo) {
+			if err := s.redialPeer(s.ctx, info); err != nil {
+				log.Debug("Could not reconnect with peer", "peer", info.ID, "err", err)
+			}
+		}(peer.AddrInfo{ID: pid, Addrs: addrs})
+	}
+}
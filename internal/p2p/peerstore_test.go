@@ -0,0 +1,196 @@
+package p2p
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	coretest "github.com/libp2p/go-libp2p/core/test"
+	"github.com/multiformats/go-multiaddr"
+	"github.com/n42blockchain/N42/conf"
+	"github.com/n42blockchain/N42/internal/p2p/peers"
+	"github.com/n42blockchain/N42/internal/p2p/peers/scorers"
+)
+
+func TestPeerStoreSaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	if ps, err := loadPeerStore(dir); err != nil || len(ps.peers) != 0 {
+		t.Fatalf("missing peer store: %d peers, error %v, want none", len(ps.peers), err)
+	}
+
+	var (
+		now     = time.Now()
+		old     = now.Add(-2 * maxStoredPeerAge)
+		trusted = coretest.RandPeerIDFatal(t)
+		stale   = coretest.RandPeerIDFatal(t)
+		low     = coretest.RandPeerIDFatal(t)
+		high    = coretest.RandPeerIDFatal(t)
+	)
+	ps := &peerStore{path: path.Join(dir, peerStorePath), peers: map[peer.ID]*storedPeer{
+		trusted: {ID: trusted.String(), Addrs: []string{"/ip4/1.2.3.4/tcp/1"}, Trusted: true, LastSeen: old, Score: -1},
+		stale:   {ID: stale.String(), Addrs: []string{"/ip4/1.2.3.4/tcp/2"}, LastSeen: old, Score: 5},
+		low:     {ID: low.String(), Addrs: []string{"/ip4/1.2.3.4/tcp/3"}, LastSeen: now, Score: 1, BadResponses: 2},
+		high:    {ID: high.String(), Addrs: []string{"/ip4/1.2.3.4/tcp/4"}, LastSeen: now, Score: 2, ProcessedBlocks: 64},
+	}}
+	if err := ps.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Trusted peers come first, then the others by descending score.
+	blob, err := os.ReadFile(ps.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []*storedPeer
+	if err := json.Unmarshal(blob, &entries); err != nil {
+		t.Fatal(err)
+	}
+	want := []peer.ID{trusted, stale, high, low}
+	if len(entries) != len(want) {
+		t.Fatalf("saved %d peers, want %d", len(entries), len(want))
+	}
+	for i, pid := range want {
+		if entries[i].ID != pid.String() {
+			t.Errorf("entry %d: peer %s, want %s", i, entries[i].ID, pid)
+		}
+	}
+
+	// The untrusted peers not seen for too long are forgotten.
+	loaded, err := loadPeerStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.peers) != 3 || loaded.peers[stale] != nil {
+		t.Fatalf("loaded %d peers, stale one %v, want 3 without the stale one", len(loaded.peers), loaded.peers[stale])
+	}
+	if entry := loaded.peers[low]; entry.BadResponses != 2 || !entry.LastSeen.Equal(ps.peers[low].LastSeen) {
+		t.Errorf("loaded entry %+v, want %+v", entry, ps.peers[low])
+	}
+	if entry := loaded.peers[high]; entry.ProcessedBlocks != 64 || entry.Addrs[0] != "/ip4/1.2.3.4/tcp/4" {
+		t.Errorf("loaded entry %+v, want %+v", entry, ps.peers[high])
+	}
+	if !loaded.peers[trusted].Trusted {
+		t.Error("trusted peer lost its trust")
+	}
+}
+
+func TestPeerStoreLimit(t *testing.T) {
+	ps := &peerStore{path: path.Join(t.TempDir(), peerStorePath), peers: make(map[peer.ID]*storedPeer)}
+	for i := 0; i < maxStoredPeers+10; i++ {
+		pid := coretest.RandPeerIDFatal(t)
+		ps.peers[pid] = &storedPeer{ID: pid.String(), Addrs: []string{"/ip4/1.2.3.4/tcp/1"}, LastSeen: time.Now(), Score: float64(i)}
+	}
+	if err := ps.save(); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadPeerStore(path.Dir(ps.path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.peers) != maxStoredPeers {
+		t.Fatalf("loaded %d peers, want %d", len(loaded.peers), maxStoredPeers)
+	}
+	for _, entry := range loaded.peers {
+		if entry.Score < 10 {
+			t.Fatalf("kept peer of score %v over better ones", entry.Score)
+		}
+	}
+}
+
+func TestPeerStoreInvalid(t *testing.T) {
+	dir := t.TempDir()
+	file := path.Join(dir, peerStorePath)
+	valid := coretest.RandPeerIDFatal(t)
+	blob := `[{"id": "bogus", "addrs": ["/ip4/1.2.3.4/tcp/1"], "lastSeen": "` + time.Now().Format(time.RFC3339) + `"},
+		{"id": "` + valid.String() + `", "addrs": ["/ip4/1.2.3.4/tcp/1"], "lastSeen": "` + time.Now().Format(time.RFC3339) + `"}]`
+	if err := os.WriteFile(file, []byte(blob), 0600); err != nil {
+		t.Fatal(err)
+	}
+	ps, err := loadPeerStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ps.peers) != 1 || ps.peers[valid] == nil {
+		t.Fatalf("loaded %v, want the valid entry only", ps.peers)
+	}
+
+	if err := os.WriteFile(file, []byte("not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadPeerStore(dir); err == nil {
+		t.Fatal("corrupt peer store loaded")
+	}
+}
+
+// newPeerTestService returns a service with a host that does not listen, and
+// its peer status.
+func newPeerTestService(t *testing.T) *Service {
+	h, err := libp2p.New(libp2p.NoListenAddrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { h.Close() })
+	return &Service{
+		ctx:  context.Background(),
+		cfg:  &conf.P2PConfig{DataDir: t.TempDir(), MaxPeers: 10},
+		host: h,
+		peers: peers.NewStatus(context.Background(), &peers.StatusConfig{
+			PeerLimit: 10,
+			ScorerParams: &scorers.Config{
+				BadResponsesScorerConfig: &scorers.BadResponsesScorerConfig{Threshold: maxBadResponses},
+			},
+		}),
+	}
+}
+
+func TestRedialPeerNotScored(t *testing.T) {
+	s := newPeerTestService(t)
+	// Nothing listens on the port, the dials fail.
+	addr := multiaddr.StringCast("/ip4/127.0.0.1/tcp/1")
+
+	redialed := peer.AddrInfo{ID: coretest.RandPeerIDFatal(t), Addrs: []multiaddr.Multiaddr{addr}}
+	s.peers.Restore(redialed.ID, addr, time.Now(), 0, 0)
+	if err := s.redialPeer(s.ctx, redialed); err == nil {
+		t.Fatal("dial of an offline peer succeeded")
+	}
+	if count, _ := s.peers.Scorers().BadResponsesScorer().Count(redialed.ID); count != 0 {
+		t.Errorf("failed redial scored %d bad responses, want none", count)
+	}
+
+	dialed := peer.AddrInfo{ID: coretest.RandPeerIDFatal(t), Addrs: []multiaddr.Multiaddr{addr}}
+	if err := s.connectWithPeer(s.ctx, dialed); err == nil {
+		t.Fatal("dial of an offline peer succeeded")
+	}
+	if count, _ := s.peers.Scorers().BadResponsesScorer().Count(dialed.ID); count != 1 {
+		t.Errorf("failed dial scored %d bad responses, want 1", count)
+	}
+}
+
+func TestIsTrustedAddr(t *testing.T) {
+	s := newPeerTestService(t)
+	trusted, other := coretest.RandPeerIDFatal(t), coretest.RandPeerIDFatal(t)
+	s.host.Peerstore().AddAddr(trusted, multiaddr.StringCast("/ip4/10.0.0.1/tcp/30303"), peerstore.PermanentAddrTTL)
+	s.host.Peerstore().AddAddr(other, multiaddr.StringCast("/ip4/10.0.0.2/tcp/30303"), peerstore.PermanentAddrTTL)
+	s.peers.SetTrusted(trusted, true)
+
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		// Inbound connections come from another port than the one listened on.
+		{"/ip4/10.0.0.1/tcp/51234", true},
+		{"/ip4/10.0.0.2/tcp/51234", false},
+		{"/ip4/10.0.0.3/tcp/30303", false},
+	}
+	for _, tt := range tests {
+		if have := s.isTrustedAddr(multiaddr.StringCast(tt.addr)); have != tt.want {
+			t.Errorf("%s: trusted %v, want %v", tt.addr, have, tt.want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package p2p
 import (
 	"github.com/n42blockchain/N42/api/protocol/sync_pb"
 	ssztype "github.com/n42blockchain/N42/common/types/ssz"
+	p2ptypes "github.com/n42blockchain/N42/internal/p2p/types"
 	"reflect"
 
 	"github.com/pkg/errors"
@@ -11,6 +12,9 @@ import (
 // SchemaVersionV1 specifies the schema version for our rpc protocol ID.
 const SchemaVersionV1 = "/1"
 
+// SchemaVersionV2 specifies the next schema version for our rpc protocol ID.
+const SchemaVersionV2 = "/2"
+
 // Specifies the protocol prefix for all our Req/Resp topics.
 const protocolPrefix = "/rpc"
 
@@ -29,6 +33,12 @@ const BodiesByRangeMessageName = "/bodies_by_range"
 // HeadersByRangeMessageName specifies the name for the Headers by range message topic.
 const HeadersByRangeMessageName = "/headers_by_range"
 
+// TxHashesMessageName specifies the name for the transaction hashes announcement topic.
+const TxHashesMessageName = "/tx_hashes"
+
+// PooledTxsMessageName specifies the name for the pooled transactions request topic.
+const PooledTxsMessageName = "/pooled_txs"
+
 const (
 	// V1 RPC Topics
 	// RPCStatusTopicV1 defines the v1 topic for the status rpc method.
@@ -43,6 +53,16 @@ const (
 
 	// RPCHeadersDataTopicV1 defines the v1 topic for the Headers rpc method.
 	RPCHeadersDataTopicV1 = protocolPrefix + HeadersByRangeMessageName + SchemaVersionV1
+
+	// RPCTxHashesTopicV1 defines the v1 topic for the transaction hashes announcement rpc method.
+	RPCTxHashesTopicV1 = protocolPrefix + TxHashesMessageName + SchemaVersionV1
+	// RPCPooledTxsTopicV1 defines the v1 topic for the pooled transactions rpc method.
+	RPCPooledTxsTopicV1 = protocolPrefix + PooledTxsMessageName + SchemaVersionV1
+
+	// V2 RPC Topics
+	// RPCStatusTopicV2 defines the v2 topic for the status rpc method, whose
+	// status carries the fork ID of the chain.
+	RPCStatusTopicV2 = protocolPrefix + StatusMessageName + SchemaVersionV2
 )
 
 // RPC errors for topic parsing.
@@ -53,11 +73,16 @@ const (
 // RPCTopicMappings map the base message type to the rpc request.
 var RPCTopicMappings = map[string]interface{}{
 	// RPC Status Message
-	RPCStatusTopicV1:     new(sync_pb.Status),
-	RPCBodiesDataTopicV1: new(sync_pb.BodiesByRangeRequest),
+	RPCStatusTopicV1:      new(sync_pb.Status),
+	RPCStatusTopicV2:      new(p2ptypes.ForkStatus),
+	RPCBodiesDataTopicV1:  new(sync_pb.BodiesByRangeRequest),
+	RPCHeadersDataTopicV1: new(sync_pb.HeadersByRangeRequest),
 
 	RPCPingTopicV1:    new(ssztype.SSZUint64),
 	RPCGoodByeTopicV1: new(ssztype.SSZUint64),
+
+	RPCTxHashesTopicV1:  new(p2ptypes.TxHashes),
+	RPCPooledTxsTopicV1: new(p2ptypes.TxHashes),
 }
 
 // Maps all registered protocol prefixes.
@@ -73,10 +98,13 @@ var messageMapping = map[string]bool{
 	PingMessageName:           true,
 	BodiesByRangeMessageName:  true,
 	HeadersByRangeMessageName: true,
+	TxHashesMessageName:       true,
+	PooledTxsMessageName:      true,
 }
 
 var versionMapping = map[string]bool{
 	SchemaVersionV1: true,
+	SchemaVersionV2: true,
 }
 
 // VerifyTopicMapping verifies that the topic and its accompanying
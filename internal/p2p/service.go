@@ -12,6 +12,7 @@ import (
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
 	"github.com/libp2p/go-libp2p/core/protocol"
 	"github.com/multiformats/go-multiaddr"
 	"github.com/n42blockchain/N42/api/protocol/sync_pb"
@@ -24,11 +25,14 @@ import (
 	leakybucket "github.com/n42blockchain/N42/internal/p2p/leaky-bucket"
 	"github.com/n42blockchain/N42/internal/p2p/peers"
 	"github.com/n42blockchain/N42/internal/p2p/peers/scorers"
+	"github.com/n42blockchain/N42/params"
 	"github.com/n42blockchain/N42/utils"
 	"github.com/pkg/errors"
 	"go.opencensus.io/trace"
 	"google.golang.org/protobuf/proto"
+	"path"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -60,6 +64,10 @@ const ttfbTimeout = 10 * time.Second // TtfbTimeout is the maximum time to wait
 // todo
 const reconnectBootNode = 1 * time.Minute
 
+// discmixTimeout is the time the discovery waits for a node of a source before
+// taking one of the others.
+const discmixTimeout = 5 * time.Second
+
 // Service for managing peer to peer (p2p) networking.
 type Service struct {
 	started               bool
@@ -79,15 +87,21 @@ type Service struct {
 	ctx                   context.Context
 	host                  host.Host
 	genesisHash           types.Hash
+	chainConfig           *params.ChainConfig
+	forkHead              atomic.Uint64
+	forkFilter            func(utils.ForkID) error
 	genesisValidatorsRoot []byte
 	activeValidatorCount  uint64
 	ping                  *sync_pb.Ping
+	peerStore             *peerStore
+	staticPeers           []peer.ID
+	staticPeersLock       sync.Mutex
 	wg                    sync.WaitGroup
 }
 
 // NewService initializes a new p2p service compatible with shared.Service interface. No
 // connections are made until the Start function is called during the service registry startup.
-func NewService(ctx context.Context, genesisHash types.Hash, cfg *conf.P2PConfig, nodeCfg conf.NodeConfig) (*Service, error) {
+func NewService(ctx context.Context, chainConfig *params.ChainConfig, genesisHash types.Hash, cfg *conf.P2PConfig, nodeCfg conf.NodeConfig) (*Service, error) {
 	var err error
 	ctx, cancel := context.WithCancel(ctx)
 	_ = cancel // govet fix for lost cancel. Cancel is handled in service.Stop().
@@ -99,7 +113,9 @@ func NewService(ctx context.Context, genesisHash types.Hash, cfg *conf.P2PConfig
 		isPreGenesis: true,
 		joinedTopics: make(map[string]*pubsub.Topic, len(gossipTopicMappings)),
 		genesisHash:  genesisHash,
+		chainConfig:  chainConfig,
 	}
+	s.forkFilter = utils.NewForkFilter(chainConfig, genesisHash, s.forkHead.Load)
 
 	s.ping, err = getSeqNumber(s.cfg)
 	if err != nil {
@@ -107,6 +123,12 @@ func NewService(ctx context.Context, genesisHash types.Hash, cfg *conf.P2PConfig
 		return nil, err
 	}
 
+	s.peerStore, err = loadPeerStore(s.cfg.DataDir)
+	if err != nil {
+		log.Warn("Failed to load the peer store, starting without known peers", "err", err)
+		s.peerStore = &peerStore{path: path.Join(s.cfg.DataDir, peerStorePath), peers: make(map[peer.ID]*storedPeer)}
+	}
+
 	dv5Nodes := parseBootStrapAddrs(cfg.BootstrapNodeAddr, nodeCfg)
 	//
 	cfg.Discv5BootStrapAddr = dv5Nodes
@@ -216,22 +238,28 @@ func (s *Service) Start() {
 			return
 		}
 		s.dv5Listener = listener
-		go s.listenForNewNodes()
 		utils.RunEvery(s.ctx, reconnectBootNode, func() {
 			s.ensureBootPeerConnections(bootnodes)
 		})
 
 	}
+	if s.dv5Listener != nil || len(s.cfg.DNSDiscoveryURLs) > 0 {
+		go s.listenForNewNodes()
+	}
+	go s.watchReachability()
 
 	s.started = true
 
+	s.restorePeers()
 	if len(s.cfg.StaticPeers) > 0 {
 		addrs, err := PeersFromStringAddrs(s.cfg.StaticPeers)
 		if err != nil {
 			log.Error("Could not connect to static peer", "err", err)
 		}
+		s.addStaticPeers(addrs)
 		s.connectWithAllPeers(addrs)
 	}
+	s.addTrustedPeers(s.cfg.TrustedPeers)
 	// Initialize metadata according to the
 	// current epoch.
 	s.RefreshENR()
@@ -243,6 +271,8 @@ func (s *Service) Start() {
 		})
 	}
 
+	utils.RunEvery(s.ctx, reconnectPeers, s.ensureTrustedPeerConnections)
+	utils.RunEvery(s.ctx, peerStoreInterval, s.savePeers)
 	utils.RunEvery(s.ctx, 30*time.Minute, s.Peers().Prune)
 	utils.RunEvery(s.ctx, 10*time.Second, s.updateMetrics)
 	utils.RunEvery(s.ctx, refreshRate, s.RefreshENR)
@@ -300,6 +330,9 @@ func (s *Service) Start() {
 			}
 		}
 
+		if s.dv5Listener == nil {
+			return
+		}
 		allNodes := s.dv5Listener.AllNodes()
 		log.Trace("Nodes stored in the discovery table:")
 		for i, n := range allNodes {
@@ -338,6 +371,7 @@ func (s *Service) loop() {
 			if err != nil {
 				//log.Error("")
 			}
+			s.savePeers()
 			s.wg.Done()
 			return
 
@@ -414,6 +448,68 @@ func (s *Service) Connect(pi peer.AddrInfo) error {
 	return s.host.Connect(s.ctx, pi)
 }
 
+// AddPeer connects to the peer at the given multiaddr or ENR, marking it as
+// trusted first if requested.
+func (s *Service) AddPeer(addr string, trusted bool) (peer.ID, error) {
+	addrs, err := PeersFromStringAddrs([]string{addr})
+	if err != nil {
+		return "", err
+	}
+	if len(addrs) == 0 {
+		return "", fmt.Errorf("invalid peer address %q", addr)
+	}
+	info, err := peer.AddrInfoFromP2pAddr(addrs[0])
+	if err != nil {
+		return "", err
+	}
+	if trusted {
+		s.peers.SetTrusted(info.ID, true)
+		s.host.Peerstore().AddAddrs(info.ID, info.Addrs, peerstore.PermanentAddrTTL)
+	}
+	return info.ID, s.connectWithPeer(s.ctx, *info)
+}
+
+// RemovePeer disconnects from a peer and revokes its trust.
+func (s *Service) RemovePeer(pid peer.ID) error {
+	s.peers.SetTrusted(pid, false)
+	return s.Disconnect(pid)
+}
+
+// RemoveTrustedPeer revokes the trust of a peer, keeping the connection.
+func (s *Service) RemoveTrustedPeer(pid peer.ID) {
+	s.peers.SetTrusted(pid, false)
+}
+
+// addStaticPeers records the peers of the given multiaddrs as static, to be
+// redialed whenever they are disconnected.
+func (s *Service) addStaticPeers(addrs []multiaddr.Multiaddr) {
+	addrInfos, err := peer.AddrInfosFromP2pAddrs(addrs...)
+	if err != nil {
+		log.Error("Could not convert to peer address info's from multiaddresses", "err", err)
+		return
+	}
+	s.staticPeersLock.Lock()
+	defer s.staticPeersLock.Unlock()
+	for _, info := range addrInfos {
+		s.host.Peerstore().AddAddrs(info.ID, info.Addrs, peerstore.PermanentAddrTTL)
+		s.staticPeers = append(s.staticPeers, info.ID)
+	}
+}
+
+// ForkDigest returns the fork digest the node advertises to its peers.
+func (s *Service) ForkDigest() ([4]byte, error) {
+	return s.currentForkDigest()
+}
+
+// LocalNode returns the discovery record of the local node, or nil if
+// discovery is disabled.
+func (s *Service) LocalNode() *enode.Node {
+	if s.dv5Listener == nil {
+		return nil
+	}
+	return s.dv5Listener.Self()
+}
+
 // Peers returns the peer status interface.
 func (s *Service) Peers() *peers.Status {
 	return s.peers
@@ -492,6 +588,21 @@ func (s *Service) connectWithPeer(ctx context.Context, info peer.AddrInfo) error
 	return nil
 }
 
+// redialPeer connects with a peer known from before, a trusted, static or
+// stored one. Unlike connectWithPeer, a failed dial does not count against the
+// peer: it may simply be offline for a while.
+func (s *Service) redialPeer(ctx context.Context, info peer.AddrInfo) error {
+	if info.ID == s.host.ID() {
+		return nil
+	}
+	if s.Peers().IsBad(info.ID) {
+		return errors.New("refused to connect to bad peer")
+	}
+	ctx, cancel := context.WithTimeout(ctx, maxDialTimeout)
+	defer cancel()
+	return s.host.Connect(ctx, info)
+}
+
 func (s *Service) bootnodes() ([]multiaddr.Multiaddr, error) {
 	nodes := make([]*enode.Node, 0, len(s.cfg.Discv5BootStrapAddr))
 	for _, addr := range s.cfg.Discv5BootStrapAddr {
@@ -8,6 +8,8 @@ const (
 
 	// GossipBlockMessage is the name for the block message type.
 	GossipBlockMessage = "block"
+	// GossipBlockHashMessage is the name for the block announcement message type.
+	GossipBlockHashMessage = "block_hash"
 	// GossipExitMessage is the name for the voluntary exit message type.
 	GossipExitMessage = "voluntary_exit"
 	// GossipTransactionMessage is the name for the transaction message type.
@@ -17,6 +19,8 @@ const (
 
 	// BlockTopicFormat is the topic format for the block subnet.
 	BlockTopicFormat = GossipProtocolAndDigest + GossipBlockMessage
+	// BlockHashTopicFormat is the topic format for the block announcements.
+	BlockHashTopicFormat = GossipProtocolAndDigest + GossipBlockHashMessage
 	// ExitBlockTopicFormat is the topic format for the voluntary exit.
 	ExitBlockTopicFormat = GossipProtocolAndDigest + GossipExitMessage
 
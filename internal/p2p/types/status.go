@@ -0,0 +1,58 @@
+package p2ptypes
+
+import (
+	"github.com/n42blockchain/N42/api/protocol/sync_pb"
+	"github.com/pkg/errors"
+	ssz "github.com/prysmaticlabs/fastssz"
+)
+
+// forkStatusFixedSize is the size of the fixed part of a ForkStatus: the
+// offset of the status, the fork hash and the next fork.
+const forkStatusFixedSize = 4 + 4 + 8
+
+// ForkStatus is the status message of the v2 status protocol: the status of the
+// v1 protocol along with the EIP-2124 fork ID of the chain at its head.
+type ForkStatus struct {
+	Status   *sync_pb.Status
+	ForkHash [4]byte
+	ForkNext uint64
+}
+
+// MarshalSSZTo marshals the fork status with the provided byte slice.
+func (s *ForkStatus) MarshalSSZTo(dst []byte) ([]byte, error) {
+	if s.Status == nil {
+		return nil, errors.New("fork status without status")
+	}
+	dst = ssz.WriteOffset(dst, forkStatusFixedSize)
+	dst = append(dst, s.ForkHash[:]...)
+	dst = ssz.MarshalUint64(dst, s.ForkNext)
+	return s.Status.MarshalSSZTo(dst)
+}
+
+// MarshalSSZ marshals the fork status into the serialized object.
+func (s *ForkStatus) MarshalSSZ() ([]byte, error) {
+	return s.MarshalSSZTo(make([]byte, 0, s.SizeSSZ()))
+}
+
+// SizeSSZ returns the size of the serialized representation.
+func (s *ForkStatus) SizeSSZ() int {
+	if s.Status == nil {
+		return forkStatusFixedSize + new(sync_pb.Status).SizeSSZ()
+	}
+	return forkStatusFixedSize + s.Status.SizeSSZ()
+}
+
+// UnmarshalSSZ unmarshals the provided bytes buffer into the fork status
+// object.
+func (s *ForkStatus) UnmarshalSSZ(buf []byte) error {
+	if len(buf) < forkStatusFixedSize {
+		return ssz.ErrSize
+	}
+	if ssz.ReadOffset(buf[0:4]) != forkStatusFixedSize {
+		return ssz.ErrInvalidVariableOffset
+	}
+	copy(s.ForkHash[:], buf[4:8])
+	s.ForkNext = ssz.UnmarshallUint64(buf[8:16])
+	s.Status = new(sync_pb.Status)
+	return s.Status.UnmarshalSSZ(buf[forkStatusFixedSize:])
+}
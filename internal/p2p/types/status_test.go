@@ -0,0 +1,51 @@
+package p2ptypes
+
+import (
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/n42blockchain/N42/api/protocol/sync_pb"
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/utils"
+)
+
+func TestForkStatusSSZ(t *testing.T) {
+	status := &sync_pb.Status{
+		GenesisHash:   utils.ConvertHashToH256(types.Hash{1, 2, 3}),
+		CurrentHeight: utils.ConvertUint256IntToH256(uint256.NewInt(42)),
+	}
+	in := &ForkStatus{Status: status, ForkHash: [4]byte{0xde, 0xad, 0xbe, 0xef}, ForkNext: 1000}
+	enc, err := in.MarshalSSZ()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(enc) != in.SizeSSZ() {
+		t.Fatalf("encoded %d bytes, size %d", len(enc), in.SizeSSZ())
+	}
+
+	out := new(ForkStatus)
+	if err := out.UnmarshalSSZ(enc); err != nil {
+		t.Fatal(err)
+	}
+	if out.ForkHash != in.ForkHash || out.ForkNext != in.ForkNext {
+		t.Errorf("fork ID %x/%d, want %x/%d", out.ForkHash, out.ForkNext, in.ForkHash, in.ForkNext)
+	}
+	if utils.ConvertH256ToHash(out.Status.GenesisHash) != (types.Hash{1, 2, 3}) || utils.ConvertH256ToUint256Int(out.Status.CurrentHeight).Uint64() != 42 {
+		t.Errorf("status %v, want %v", out.Status, status)
+	}
+
+	// A v1 status is not a v2 one.
+	legacy, err := status.MarshalSSZ()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := new(ForkStatus).UnmarshalSSZ(legacy); err == nil {
+		t.Error("v1 status decoded as a v2 one")
+	}
+	if err := new(ForkStatus).UnmarshalSSZ(enc[:forkStatusFixedSize-1]); err == nil {
+		t.Error("truncated fork status decoded")
+	}
+	if _, err := new(ForkStatus).MarshalSSZ(); err == nil {
+		t.Error("fork status without status encoded")
+	}
+}
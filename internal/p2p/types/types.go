@@ -15,6 +15,10 @@ const maxErrorLength = 256
 // todo
 const maxRequestBlocks = 1024
 
+// MaxTxHashes is the maximum number of transaction hashes carried by a single
+// announcement or pooled transactions request.
+const MaxTxHashes = 4096
+
 // SSZBytes is a bytes slice that satisfies the fast-ssz interface.
 type SSZBytes []byte
 
@@ -82,6 +86,57 @@ func (r *BlockByRootsReq) UnmarshalSSZ(buf []byte) error {
 	return nil
 }
 
+// TxHashes specifies the transaction announcement and pooled transactions request type.
+type TxHashes [][rootLength]byte
+
+// MarshalSSZTo marshals the transaction hashes with the provided byte slice.
+func (h *TxHashes) MarshalSSZTo(dst []byte) ([]byte, error) {
+	marshalledObj, err := h.MarshalSSZ()
+	if err != nil {
+		return nil, err
+	}
+	return append(dst, marshalledObj...), nil
+}
+
+// MarshalSSZ Marshals the transaction hashes into the serialized object.
+func (h *TxHashes) MarshalSSZ() ([]byte, error) {
+	if len(*h) > MaxTxHashes {
+		return nil, errors.Errorf("transaction hashes exceed max size: %d > %d", len(*h), MaxTxHashes)
+	}
+	buf := make([]byte, 0, h.SizeSSZ())
+	for _, r := range *h {
+		buf = append(buf, r[:]...)
+	}
+	return buf, nil
+}
+
+// SizeSSZ returns the size of the serialized representation.
+func (h *TxHashes) SizeSSZ() int {
+	return len(*h) * rootLength
+}
+
+// UnmarshalSSZ unmarshals the provided bytes buffer into the
+// transaction hashes object.
+func (h *TxHashes) UnmarshalSSZ(buf []byte) error {
+	bufLen := len(buf)
+	maxLength := MaxTxHashes * rootLength
+	if bufLen > maxLength {
+		return errors.Errorf("expected buffer with length of upto %d but received length %d", maxLength, bufLen)
+	}
+	if bufLen%rootLength != 0 {
+		return ssz.ErrIncorrectByteSize
+	}
+	numOfHashes := bufLen / rootLength
+	hashes := make([][rootLength]byte, 0, numOfHashes)
+	for i := 0; i < numOfHashes; i++ {
+		var hs [rootLength]byte
+		copy(hs[:], buf[i*rootLength:(i+1)*rootLength])
+		hashes = append(hashes, hs)
+	}
+	*h = hashes
+	return nil
+}
+
 // ErrorMessage describes the error message type.
 type ErrorMessage []byte
 
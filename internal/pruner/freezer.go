@@ -0,0 +1,132 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"context"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/n42blockchain/N42/common"
+	"github.com/n42blockchain/N42/log"
+	"github.com/n42blockchain/N42/modules/ancient"
+	"github.com/n42blockchain/N42/modules/rawdb"
+	"github.com/n42blockchain/N42/params"
+)
+
+// freezeBatchSize is the number of blocks moved to the ancient store in a
+// single write transaction.
+const freezeBatchSize = 2048
+
+// FreezeThreshold is the number of most recent blocks kept in the chain
+// database, past which blocks are final and may be frozen.
+const FreezeThreshold = params.FullImmutabilityThreshold
+
+// Freezer periodically moves the blocks past the finality threshold out of the
+// chain database into the ancient store.
+type Freezer struct {
+	db       kv.RwDB
+	chain    common.IBlockChain
+	ancients *ancient.Freezer
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewFreezer creates a background freezer moving blocks into ancients.
+func NewFreezer(ctx context.Context, db kv.RwDB, chain common.IBlockChain, ancients *ancient.Freezer) *Freezer {
+	c, cancel := context.WithCancel(ctx)
+	return &Freezer{
+		db:       db,
+		chain:    chain,
+		ancients: ancients,
+		ctx:      c,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+}
+
+// Start runs the freezer in the background.
+func (f *Freezer) Start() error {
+	go f.loop()
+	return nil
+}
+
+// Stop terminates the freezer, waiting for the running batch to be committed.
+func (f *Freezer) Stop() error {
+	f.cancel()
+	<-f.done
+	return nil
+}
+
+func (f *Freezer) loop() {
+	defer close(f.done)
+
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		head := f.chain.CurrentBlock().Number64().Uint64()
+		if head > FreezeThreshold {
+			if _, err := Freeze(f.ctx, f.db, f.ancients, head-FreezeThreshold); err != nil {
+				if f.ctx.Err() != nil {
+					return
+				}
+				log.Error("Failed to freeze blocks", "err", err)
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-f.ctx.Done():
+			return
+		}
+	}
+}
+
+// Freeze moves the blocks below to out of the chain database into the ancient
+// store, in batches of bounded size. It returns the number of frozen blocks.
+func Freeze(ctx context.Context, db kv.RwDB, ancients *ancient.Freezer, to uint64) (uint64, error) {
+	var (
+		start  = time.Now()
+		from   = ancients.Ancients()
+		frozen uint64
+	)
+	for ancients.Ancients() < to {
+		limit := ancients.Ancients() + freezeBatchSize
+		if limit > to {
+			limit = to
+		}
+		if err := db.Update(ctx, func(tx kv.RwTx) error {
+			n, err := rawdb.FreezeBlocks(tx, ancients, limit)
+			frozen += n
+			return err
+		}); err != nil {
+			return frozen, err
+		}
+
+		select {
+		case <-time.After(batchDelay):
+		case <-ctx.Done():
+			return frozen, ctx.Err()
+		}
+	}
+	if frozen > 0 {
+		log.Info("Froze ancient blocks", "from", from, "to", to, "elapsed", time.Since(start))
+	}
+	return frozen, nil
+}
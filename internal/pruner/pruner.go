@@ -0,0 +1,233 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package pruner deletes the history, receipts and transaction indexes of blocks
+// older than the retention window configured in conf.DatabaseConfig.
+package pruner
+
+import (
+	"context"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/n42blockchain/N42/common"
+	"github.com/n42blockchain/N42/conf"
+	"github.com/n42blockchain/N42/log"
+	"github.com/n42blockchain/N42/modules"
+	"github.com/n42blockchain/N42/modules/changeset"
+	"github.com/n42blockchain/N42/modules/ethdb/bitmapdb"
+	"github.com/n42blockchain/N42/modules/rawdb"
+	"github.com/n42blockchain/N42/params"
+)
+
+const (
+	// batchSize is the number of blocks pruned in a single write transaction,
+	// keeping the database write lock short enough not to stall block import.
+	batchSize = 100
+	// batchDelay is the pause between two batches, letting other writers in.
+	batchDelay = 50 * time.Millisecond
+	// pruneInterval is how often the pruner checks for data to delete.
+	pruneInterval = time.Minute
+)
+
+// mode prunes one kind of data, tracking its progress under table in
+// modules.PruneProgress.
+type mode struct {
+	table    string
+	distance uint64
+	// first returns the oldest block which may still hold data to prune.
+	first func(tx kv.Tx) (uint64, error)
+	prune func(tx kv.RwTx, from, to uint64) error
+}
+
+// Pruner periodically deletes the data of the blocks which fall out of the
+// retention window.
+type Pruner struct {
+	db    kv.RwDB
+	chain common.IBlockChain
+	modes []mode
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New creates a pruner for the prune modes of cfg. State history is always kept
+// for at least params.FullImmutabilityThreshold blocks, as chain reorgs unwind
+// through it.
+func New(ctx context.Context, db kv.RwDB, chain common.IBlockChain, cfg conf.DatabaseConfig) *Pruner {
+	if cfg.PruneHistory > 0 && cfg.PruneHistory < params.FullImmutabilityThreshold {
+		log.Warn("State history retention too low, raising it", "requested", cfg.PruneHistory, "retained", params.FullImmutabilityThreshold)
+		cfg.PruneHistory = params.FullImmutabilityThreshold
+	}
+	c, cancel := context.WithCancel(ctx)
+	p := &Pruner{
+		db:     db,
+		chain:  chain,
+		ctx:    c,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	if cfg.PruneHistory > 0 {
+		p.modes = append(p.modes, mode{table: modules.AccountChangeSet, distance: cfg.PruneHistory, first: historyFrom, prune: pruneHistory})
+	}
+	if cfg.PruneReceipts > 0 {
+		p.modes = append(p.modes, mode{table: modules.Receipts, distance: cfg.PruneReceipts, first: rawdb.ReceiptsAvailableFrom, prune: rawdb.PruneReceipts})
+	}
+	if cfg.PruneTxIndex > 0 {
+		p.modes = append(p.modes, mode{table: modules.TxLookup, distance: cfg.PruneTxIndex, first: txIndexFrom, prune: rawdb.PruneTxLookupEntries})
+	}
+	return p
+}
+
+// Start runs the pruner in the background.
+func (p *Pruner) Start() error {
+	go p.loop()
+	return nil
+}
+
+// Stop terminates the pruner, waiting for the running batch to be committed.
+func (p *Pruner) Stop() error {
+	p.cancel()
+	<-p.done
+	return nil
+}
+
+func (p *Pruner) loop() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		for _, m := range p.modes {
+			if err := p.prune(m); err != nil {
+				if p.ctx.Err() != nil {
+					return
+				}
+				log.Error("Failed to prune database", "table", m.table, "err", err)
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
+
+// prune deletes the data of mode m up to the retention window, one batch of
+// blocks per write transaction.
+func (p *Pruner) prune(m mode) error {
+	head := p.chain.CurrentBlock().Number64().Uint64()
+	if head <= m.distance {
+		return nil
+	}
+	target := head - m.distance
+
+	var from uint64
+	if err := p.db.View(p.ctx, func(tx kv.Tx) error {
+		progress, err := rawdb.ReadPruneProgress(tx, m.table)
+		if err != nil {
+			return err
+		}
+		first, err := m.first(tx)
+		if err != nil {
+			return err
+		}
+		from = progress
+		if first > from {
+			from = first
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	if from >= target {
+		return nil
+	}
+
+	start := time.Now()
+	for begin := from; begin < target; {
+		end := begin + batchSize
+		if end > target {
+			end = target
+		}
+		if err := p.db.Update(p.ctx, func(tx kv.RwTx) error {
+			if err := m.prune(tx, begin, end); err != nil {
+				return err
+			}
+			return rawdb.WritePruneProgress(tx, m.table, end)
+		}); err != nil {
+			return err
+		}
+		begin = end
+
+		select {
+		case <-time.After(batchDelay):
+		case <-p.ctx.Done():
+			return p.ctx.Err()
+		}
+	}
+	log.Info("Pruned database", "table", m.table, "from", from, "to", target, "elapsed", time.Since(start))
+	return nil
+}
+
+// historyFrom returns the oldest block with a state changeset.
+func historyFrom(tx kv.Tx) (uint64, error) {
+	first, err := changeset.AvailableFrom(tx)
+	if err != nil {
+		return 0, err
+	}
+	storageFirst, err := changeset.AvailableStorageFrom(tx)
+	if err != nil {
+		return 0, err
+	}
+	if storageFirst < first {
+		first = storageFirst
+	}
+	return first, nil
+}
+
+// txIndexFrom returns zero, the transaction index being written for every block
+// since genesis.
+func txIndexFrom(kv.Tx) (uint64, error) {
+	return 0, nil
+}
+
+// pruneHistory removes the account and storage changesets of blocks [from, to)
+// along with the matching entries of the history indexes.
+func pruneHistory(tx kv.RwTx, from, to uint64) error {
+	for _, bucket := range []string{modules.AccountChangeSet, modules.StorageChangeSet} {
+		keys := make(map[string]struct{})
+		if err := changeset.ForRange(tx, bucket, from, to, func(_ uint64, k, _ []byte) error {
+			keys[string(modules.CompositeKeyWithoutIncarnation(k))] = struct{}{}
+			return nil
+		}); err != nil {
+			return err
+		}
+		if err := changeset.PruneRange(tx, bucket, from, to); err != nil {
+			return err
+		}
+		indexBucket := changeset.Mapper[bucket].IndexBucket
+		for k := range keys {
+			if err := bitmapdb.TruncateBelow64(tx, indexBucket, []byte(k), to); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
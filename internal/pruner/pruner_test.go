@@ -0,0 +1,163 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
+	log2 "github.com/ledgerwatch/log/v3"
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/modules"
+	"github.com/n42blockchain/N42/modules/changeset"
+	"github.com/n42blockchain/N42/modules/ethdb/bitmapdb"
+	"github.com/n42blockchain/N42/modules/rawdb"
+)
+
+func newTestDB(t *testing.T) kv.RwDB {
+	modules.AstInit()
+	kv.ChaindataTablesCfg = modules.AstTableCfg
+	db := mdbx.NewMDBX(log2.New()).InMem(t.TempDir()).Label(kv.ChainDB).MustOpen()
+	t.Cleanup(db.Close)
+	return db
+}
+
+func putShard(t *testing.T, tx kv.RwTx, key []byte, max uint64, values ...uint64) {
+	bm := roaring64.BitmapOf(values...)
+	buf := bytes.NewBuffer(nil)
+	if _, err := bm.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	chunkKey := make([]byte, len(key)+8)
+	copy(chunkKey, key)
+	binary.BigEndian.PutUint64(chunkKey[len(key):], max)
+	if err := tx.Put(modules.AccountsHistory, chunkKey, buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPruneHistory(t *testing.T) {
+	var (
+		db   = newTestDB(t)
+		addr = types.HexToAddress("0x1000000000000000000000000000000000000001")
+	)
+	if err := db.Update(context.Background(), func(tx kv.RwTx) error {
+		for n := uint64(1); n <= 10; n++ {
+			if err := tx.Put(modules.AccountChangeSet, modules.EncodeBlockNumber(n), append(addr.Bytes(), byte(n))); err != nil {
+				return err
+			}
+		}
+		// Consecutive shards below the pruned range are deleted in one walk.
+		putShard(t, tx, addr.Bytes(), 1, 1)
+		putShard(t, tx, addr.Bytes(), 2, 2)
+		putShard(t, tx, addr.Bytes(), 3, 3)
+		putShard(t, tx, addr.Bytes(), math.MaxUint64, 4, 5, 6, 7, 8, 9, 10)
+		return pruneHistory(tx, 0, 6)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.View(context.Background(), func(tx kv.Tx) error {
+		first, err := changeset.AvailableFrom(tx)
+		if err != nil {
+			return err
+		}
+		if first != 6 {
+			t.Errorf("changesets available from %d, want 6", first)
+		}
+		bm, err := bitmapdb.Get64(tx, modules.AccountsHistory, addr.Bytes(), 0, math.MaxUint64)
+		if err != nil {
+			return err
+		}
+		if want := roaring64.BitmapOf(6, 7, 8, 9, 10); !bm.Equals(want) {
+			t.Errorf("history index %v, want %v", bm.ToArray(), want.ToArray())
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPruneReceipts(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.Update(context.Background(), func(tx kv.RwTx) error {
+		for n := uint64(1); n <= 10; n++ {
+			if err := tx.Put(modules.Receipts, modules.EncodeBlockNumber(n), []byte{1}); err != nil {
+				return err
+			}
+			// Several logs per block, all of them deleted by the same walk.
+			for txID := uint32(0); txID < 3; txID++ {
+				if err := tx.Put(modules.Log, modules.LogKey(n, txID), []byte{1}); err != nil {
+					return err
+				}
+			}
+		}
+		if err := rawdb.PruneReceipts(tx, 1, 4); err != nil {
+			return err
+		}
+		return rawdb.WritePruneProgress(tx, modules.Receipts, 4)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.View(context.Background(), func(tx kv.Tx) error {
+		first, err := rawdb.ReceiptsAvailableFrom(tx)
+		if err != nil {
+			return err
+		}
+		if first != 4 {
+			t.Errorf("receipts available from %d, want 4", first)
+		}
+		k, err := rawdb.FirstKey(tx, modules.Log)
+		if err != nil {
+			return err
+		}
+		if n := binary.BigEndian.Uint64(k); n != 4 {
+			t.Errorf("logs available from %d, want 4", n)
+		}
+		for table, want := range map[string]uint64{modules.Receipts: 7, modules.Log: 21} {
+			var have uint64
+			if err := tx.ForEach(table, nil, func(_, _ []byte) error {
+				have++
+				return nil
+			}); err != nil {
+				return err
+			}
+			if have != want {
+				t.Errorf("%s: have %d entries, want %d", table, have, want)
+			}
+		}
+
+		var pruned *rawdb.PrunedError
+		if err := rawdb.CheckPruned(tx, modules.Receipts, 3); !errors.As(err, &pruned) || pruned.AvailableFrom != 4 {
+			t.Errorf("block 3: got %v, want pruned error", err)
+		}
+		if err := rawdb.CheckPruned(tx, modules.Receipts, 4); err != nil {
+			t.Errorf("block 4: got %v, want nil", err)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
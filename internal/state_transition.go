@@ -81,6 +81,7 @@ type StateTransition struct {
 type Message interface {
 	From() types.Address
 	To() *types.Address
+	FeePayer() types.Address
 
 	GasPrice() *uint256.Int
 	FeeCap() *uint256.Int
@@ -227,32 +228,44 @@ func (st *StateTransition) to() types.Address {
 }
 
 func (st *StateTransition) buyGas(gasBailout bool) error {
+	// The fee payer of a fee-delegated message pays for the gas, the value is
+	// still transferred from the sender.
+	payer := st.msg.FeePayer()
+	sponsored := payer != st.msg.From()
+
 	mgval := st.sharedBuyGas
 	mgval.SetUint64(st.msg.Gas())
 	mgval, overflow := mgval.MulOverflow(mgval, st.gasPrice)
 	if overflow {
-		return fmt.Errorf("%w: address %v", ErrInsufficientFunds, st.msg.From().Hex())
+		return fmt.Errorf("%w: address %v", ErrInsufficientFunds, payer.Hex())
 	}
 	balanceCheck := mgval
 	if st.gasFeeCap != nil {
 		balanceCheck = st.sharedBuyGasBalance.SetUint64(st.msg.Gas())
 		balanceCheck, overflow = balanceCheck.MulOverflow(balanceCheck, st.gasFeeCap)
 		if overflow {
-			return fmt.Errorf("%w: address %v", ErrInsufficientFunds, st.msg.From().Hex())
+			return fmt.Errorf("%w: address %v", ErrInsufficientFunds, payer.Hex())
 		}
-		balanceCheck, overflow = balanceCheck.AddOverflow(balanceCheck, st.value)
-		if overflow {
-			return fmt.Errorf("%w: address %v", ErrInsufficientFunds, st.msg.From().Hex())
+		if !sponsored {
+			balanceCheck, overflow = balanceCheck.AddOverflow(balanceCheck, st.value)
+			if overflow {
+				return fmt.Errorf("%w: address %v", ErrInsufficientFunds, payer.Hex())
+			}
 		}
 	}
 	var subBalance = false
-	if have, want := st.state.GetBalance(st.msg.From()), balanceCheck; have.Cmp(want) < 0 {
+	if have, want := st.state.GetBalance(payer), balanceCheck; have.Cmp(want) < 0 {
 		if !gasBailout {
-			return fmt.Errorf("%w: address %v have %v want %v", ErrInsufficientFunds, st.msg.From().Hex(), have, want)
+			return fmt.Errorf("%w: address %v have %v want %v", ErrInsufficientFunds, payer.Hex(), have, want)
 		}
 	} else {
 		subBalance = true
 	}
+	if sponsored {
+		if have, want := st.state.GetBalance(st.msg.From()), st.value; have.Cmp(want) < 0 && !gasBailout {
+			return fmt.Errorf("%w: address %v have %v want %v", ErrInsufficientFunds, st.msg.From().Hex(), have, want)
+		}
+	}
 	if err := st.gp.SubGas(st.msg.Gas()); err != nil {
 		if !gasBailout {
 			return err
@@ -262,7 +275,7 @@ func (st *StateTransition) buyGas(gasBailout bool) error {
 
 	st.initialGas = st.msg.Gas()
 	if subBalance {
-		st.state.SubBalance(st.msg.From(), mgval)
+		st.state.SubBalance(payer, mgval)
 	}
 	return nil
 }
@@ -281,6 +294,10 @@ func CheckEip1559TxGasFeeCap(from types.Address, gasFeeCap, tip, baseFee *uint25
 
 // DESCRIBED: docs/programmers_guide/guide.md#nonce
 func (st *StateTransition) preCheck(gasBailout bool) error {
+	// Only fee-delegated messages are paid for by somebody else than the sender.
+	if st.msg.FeePayer() != st.msg.From() && !st.evm.ChainRules().IsSakuragi {
+		return fmt.Errorf("%w: fee-delegated message of %v", ErrTxTypeNotSupported, st.msg.From().Hex())
+	}
 	// Make sure this transaction's nonce is correct.
 	if st.msg.CheckNonce() {
 		stNonce := st.state.GetNonce(st.msg.From())
@@ -488,7 +505,7 @@ func (st *StateTransition) refundGas(refundQuotient uint64) {
 
 	// Return ETH for remaining gas, exchanged at the original rate.
 	remaining := new(uint256.Int).Mul(new(uint256.Int).SetUint64(st.gas), st.gasPrice)
-	st.state.AddBalance(st.msg.From(), remaining)
+	st.state.AddBalance(st.msg.FeePayer(), remaining)
 
 	// Also return remaining gas to the block gas counter so it is
 	// available for the next transaction.
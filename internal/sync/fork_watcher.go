@@ -0,0 +1,60 @@
+package sync
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/n42blockchain/N42/internal/p2p"
+	"github.com/n42blockchain/N42/log"
+	"github.com/n42blockchain/N42/utils"
+)
+
+// forkWatchInterval is the interval at which the head of the chain is checked
+// for fork activations.
+const forkWatchInterval = 2 * time.Second
+
+// forkTransitionBlocks is the number of blocks before a fork from which the
+// gossip topics of its digest are subscribed, and after it until which those of
+// the previous digest are kept, so that the meshes are formed in time and the
+// peers lagging behind are still heard.
+const forkTransitionBlocks = 8
+
+// forkWatcher follows the head of the chain, moving the gossip subscriptions
+// over to the digest of a fork around its activation, and reports the head to
+// the p2p service which keeps the fork ID of the ENR current.
+func (s *Service) forkWatcher() {
+	utils.RunEvery(s.ctx, forkWatchInterval, func() {
+		head := s.cfg.chain.CurrentBlock().Number64().Uint64()
+		s.cfg.p2p.SetForkHead(head)
+		s.updateForkSubscriptions(head)
+	})
+}
+
+// updateForkSubscriptions subscribes to the gossip topics of the digests
+// active within forkTransitionBlocks of head, and leaves those of other digests.
+func (s *Service) updateForkSubscriptions(head uint64) {
+	config, genesis := s.cfg.chain.Config(), s.cfg.chain.GenesisBlock().Hash()
+	from := uint64(0)
+	if head > forkTransitionBlocks {
+		from = head - forkTransitionBlocks
+	}
+	wanted := map[[4]byte]bool{
+		utils.ForkDigest(config, genesis, from):                      true,
+		utils.ForkDigest(config, genesis, head):                      true,
+		utils.ForkDigest(config, genesis, head+forkTransitionBlocks): true,
+	}
+	for digest := range wanted {
+		if !s.subHandler.digestExists(digest) {
+			log.Info("Subscribing to the gossip topics of fork digest", "digest", fmt.Sprintf("%#x", digest), "number", head)
+			s.registerSubscribers(digest)
+		}
+	}
+	for _, topic := range s.subHandler.allTopics() {
+		digest, err := p2p.ExtractGossipDigest(topic)
+		if err != nil || wanted[digest] {
+			continue
+		}
+		log.Info("Leaving the gossip topic of past fork digest", "topic", topic, "number", head)
+		s.unSubscribeFromTopic(topic)
+	}
+}
@@ -64,6 +64,7 @@ type blocksFetcher struct {
 	p2p             p2p.P2P
 	blocksPerPeriod uint64
 	rateLimiter     *leakybucket.Collector
+	headerLimiter   *leakybucket.Collector
 	peerLocks       map[peer.ID]*peerLock
 	fetchRequests   chan *fetchRequestParams
 	fetchResponses  chan *fetchRequestResponse
@@ -107,6 +108,8 @@ func newBlocksFetcher(ctx context.Context, cfg *blocksFetcherConfig) *blocksFetc
 	// Allow fetcher to go almost to the full burst capacity (less a single batch).
 	//rateLimiter := leakybucket.NewCollector(allowedBlocksPerSecond, allowedBlocksBurst-allowedBlocksBurst, blockLimiterPeriod, false /* deleteEmptyBuckets */)
 	rateLimiter := leakybucket.NewCollector(allowedBlocksPerSecond, allowedBlocksBurst, blockLimiterPeriod, false /* deleteEmptyBuckets */)
+	// Mirror the serving side, which allows more headers than blocks per period.
+	headerLimiter := leakybucket.NewCollector(allowedBlocksPerSecond*astsync.HeaderLimitFactor, allowedBlocksBurst*astsync.HeaderLimitFactor, blockLimiterPeriod, false /* deleteEmptyBuckets */)
 
 	capacityWeight := cfg.peerFilterCapacityWeight
 	if capacityWeight >= 1 {
@@ -122,6 +125,7 @@ func newBlocksFetcher(ctx context.Context, cfg *blocksFetcherConfig) *blocksFetc
 		p2p:             cfg.p2p,
 		blocksPerPeriod: uint64(allowedBlocksPerSecond),
 		rateLimiter:     rateLimiter,
+		headerLimiter:   headerLimiter,
 		peerLocks:       make(map[peer.ID]*peerLock),
 		fetchRequests:   make(chan *fetchRequestParams, maxPendingRequests),
 		fetchResponses:  make(chan *fetchRequestResponse, maxPendingRequests),
@@ -149,6 +153,10 @@ func (f *blocksFetcher) stop() {
 			f.rateLimiter.Free()
 			f.rateLimiter = nil
 		}
+		if f.headerLimiter != nil {
+			f.headerLimiter.Free()
+			f.headerLimiter = nil
+		}
 	}()
 	f.cancel()
 	<-f.quit // make sure that loop() is done
@@ -294,7 +302,7 @@ func (f *blocksFetcher) requestBlocks(ctx context.Context, req *sync_pb.BodiesBy
 	)
 
 	if f.rateLimiter.Remaining(pid.String()) < int64(req.Count) {
-		if err := f.waitForBandwidth(pid, req.Count); err != nil {
+		if err := f.waitForBandwidth(f.rateLimiter, pid, req.Count); err != nil {
 			l.Unlock()
 			return nil, err
 		}
@@ -304,10 +312,36 @@ func (f *blocksFetcher) requestBlocks(ctx context.Context, req *sync_pb.BodiesBy
 	return astsync.SendBodiesByRangeRequest(ctx, f.chain, f.p2p, pid, req, nil)
 }
 
-// waitForBandwidth blocks up until peer's bandwidth is restored.
-func (f *blocksFetcher) waitForBandwidth(pid peer.ID, count uint64) error {
+// requestHeaders is a wrapper for handling HeadersByRangeRequest requests/streams.
+func (f *blocksFetcher) requestHeaders(ctx context.Context, req *sync_pb.HeadersByRangeRequest, pid peer.ID) ([]*types_pb.Header, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	l := f.peerLock(pid)
+	l.Lock()
+	log.Debug("Requesting headers",
+		"peer", pid,
+		"start", utils.ConvertH256ToUint256Int(req.StartBlockNumber).Uint64(),
+		"count", req.Count,
+		"step", req.Step,
+		"capacity", f.headerLimiter.Remaining(pid.String()),
+	)
+
+	if f.headerLimiter.Remaining(pid.String()) < int64(req.Count) {
+		if err := f.waitForBandwidth(f.headerLimiter, pid, req.Count); err != nil {
+			l.Unlock()
+			return nil, err
+		}
+	}
+	f.headerLimiter.Add(pid.String(), int64(req.Count))
+	l.Unlock()
+	return astsync.SendHeadersByRangeRequest(ctx, f.p2p, pid, req)
+}
+
+// waitForBandwidth blocks up until peer's bandwidth in the given limiter is restored.
+func (f *blocksFetcher) waitForBandwidth(limiter *leakybucket.Collector, pid peer.ID, count uint64) error {
 
-	rem := f.rateLimiter.Remaining(pid.String())
+	rem := limiter.Remaining(pid.String())
 	if uint64(rem) >= count {
 		// Exit early if we have sufficient capacity
 		return nil
@@ -317,7 +351,7 @@ func (f *blocksFetcher) waitForBandwidth(pid peer.ID, count uint64) error {
 	//if err != nil {
 	//	return err
 	//}
-	toWait := timeToWait(int64(count), rem, f.rateLimiter.Capacity(), f.rateLimiter.TillEmpty(pid.String()))
+	toWait := timeToWait(int64(count), rem, limiter.Capacity(), limiter.TillEmpty(pid.String()))
 	timer := time.NewTimer(toWait)
 
 	log.Debug("Slowing down for rate limit", "peer", pid, "timeToWait", common.PrettyDuration(toWait))
@@ -1,6 +1,6 @@
 // Package initialsync includes all initial block download and processing
-// logic for the node, using a round robin strategy and a finite-state-machine
-// to handle edge-cases in a beacon node's sync status.
+// logic for the node, using a skeleton header sync with a round robin strategy
+// and a finite-state-machine as fallback to handle edge-cases in a node's sync status.
 package initialsync
 
 import (
@@ -11,6 +11,7 @@ import (
 	"github.com/n42blockchain/N42/common"
 	"github.com/n42blockchain/N42/internal/p2p"
 	event "github.com/n42blockchain/N42/modules/event/v2"
+	"github.com/n42blockchain/N42/params"
 	"github.com/paulbellamy/ratecounter"
 	"sync/atomic"
 	"time"
@@ -22,6 +23,8 @@ import (
 type Config struct {
 	P2P   p2p.P2P
 	Chain common.IBlockChain
+	// TrustedBlock, if set, is a block the synced chain must contain.
+	TrustedBlock *params.TrustedBlock
 }
 
 // Service service.
@@ -51,13 +54,14 @@ func NewService(ctx context.Context, cfg *Config) *Service {
 
 // Start the initial sync service.
 func (s *Service) Start() {
-
+	s.markSyncing()
 	event.GlobalEvent.Send(common.DownloaderStartEvent{})
 	defer event.GlobalEvent.Send(common.DownloaderFinishEvent{})
 
 	log.Info("Starting initial chain sync...")
+	s.checkLocalTrustedBlock()
 	highestExpectedBlockNr := s.waitForMinimumPeers()
-	if err := s.roundRobinSync(highestExpectedBlockNr); err != nil {
+	if err := s.sync(highestExpectedBlockNr); err != nil {
 		if errors.Is(s.ctx.Err(), context.Canceled) {
 			return
 		}
@@ -105,7 +109,7 @@ func (s *Service) Resync() error {
 	//
 	beforeBlockNr := s.cfg.Chain.CurrentBlock().Number64()
 	highestExpectedBlockNr := s.waitForMinimumPeers()
-	if err := s.roundRobinSync(highestExpectedBlockNr); err != nil {
+	if err := s.sync(highestExpectedBlockNr); err != nil {
 		log.Error("Resync fail", "err", err, "highestExpectedBlockNr", highestExpectedBlockNr, "currentNr", s.cfg.Chain.CurrentBlock().Number64(), "beforeResyncBlockNr", beforeBlockNr)
 		return err
 	}
@@ -114,6 +118,45 @@ func (s *Service) Resync() error {
 	return nil
 }
 
+// sync downloads the chain with the skeleton syncer, falling back to round robin
+// sync when no consistent skeleton can be assembled. Round robin sync does not
+// check the trusted block, so it is only used once the trusted block is behind us.
+func (s *Service) sync(highestExpectedBlockNr *uint256.Int) error {
+	for {
+		err := s.skeletonSync(highestExpectedBlockNr)
+		if err == nil || s.ctx.Err() != nil {
+			return err
+		}
+		if !s.trustedBlockPending() {
+			log.Warn("Skeleton sync failed, falling back to round robin sync", "err", err, "currentNr", s.cfg.Chain.CurrentBlock().Number64().Uint64())
+			return s.roundRobinSync(highestExpectedBlockNr)
+		}
+		log.Warn("Skeleton sync failed before the trusted block, retrying", "err", err, "trustedBlock", s.cfg.TrustedBlock, "currentNr", s.cfg.Chain.CurrentBlock().Number64().Uint64())
+		select {
+		case <-s.ctx.Done():
+			return s.ctx.Err()
+		case <-time.After(handshakePollingInterval):
+		}
+		highestExpectedBlockNr = s.waitForMinimumPeers()
+	}
+}
+
+// checkLocalTrustedBlock warns when the local chain already passed the
+// trusted block on another branch.
+func (s *Service) checkLocalTrustedBlock() {
+	b := s.cfg.TrustedBlock
+	if b == nil {
+		return
+	}
+	if s.trustedBlockPending() {
+		log.Info("Syncing towards trusted block", "trustedBlock", b)
+		return
+	}
+	if header := s.cfg.Chain.GetHeaderByNumber(uint256.NewInt(b.Number)); header != nil && header.Hash() != b.Hash {
+		log.Error("Local chain does not contain the trusted block", "trustedBlock", b, "local", header.Hash())
+	}
+}
+
 func (s *Service) waitForMinimumPeers() (highestExpectedBlockNr *uint256.Int) {
 	required := s.cfg.P2P.GetConfig().MinSyncPeers
 	var peers []peer.ID
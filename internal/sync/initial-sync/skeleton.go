@@ -0,0 +1,540 @@
+package initialsync
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/n42blockchain/N42/api/protocol/sync_pb"
+	"github.com/n42blockchain/N42/api/protocol/types_pb"
+	"github.com/n42blockchain/N42/common"
+	block2 "github.com/n42blockchain/N42/common/block"
+	"github.com/n42blockchain/N42/common/types"
+	astsync "github.com/n42blockchain/N42/internal/sync"
+	"github.com/n42blockchain/N42/utils"
+	"github.com/paulbellamy/ratecounter"
+	"github.com/pkg/errors"
+)
+
+const (
+	// skeletonStride is the distance between two skeleton headers, each gap
+	// is filled with a single headers_by_range request.
+	skeletonStride = astsync.MaxRequestHeaders
+	// maxSkeletonHeaders caps the number of skeleton headers fetched per round.
+	maxSkeletonHeaders = 128
+	// maxFetchAttempts is how many times a single task is retried with other peers.
+	maxFetchAttempts = 4
+	// fetchWindow limits how far ahead of the next undelivered task peers may fetch.
+	fetchWindow = 32
+	// maxSkeletonPeers caps the number of peers used to fill gaps and bodies.
+	maxSkeletonPeers = 64
+)
+
+var (
+	errNoSkeletonProgress   = errors.New("skeleton sync made no progress")
+	errSkeletonMismatch     = errors.New("headers do not match the skeleton")
+	errTaskAttempts         = errors.New("too many failed fetch attempts")
+	errTrustedBlockMismatch = errors.New("chain does not contain the trusted block")
+)
+
+// skeletonChain overlays downloaded, not yet imported, headers on top of the
+// local chain so that the consensus engine can verify them in batches.
+type skeletonChain struct {
+	common.IBlockChain
+	byHash   map[types.Hash]block2.IHeader
+	byNumber map[uint64]block2.IHeader
+}
+
+func newSkeletonChain(chain common.IBlockChain) *skeletonChain {
+	return &skeletonChain{
+		IBlockChain: chain,
+		byHash:      make(map[types.Hash]block2.IHeader),
+		byNumber:    make(map[uint64]block2.IHeader),
+	}
+}
+
+func (c *skeletonChain) add(headers []block2.IHeader) {
+	for _, h := range headers {
+		c.byHash[h.Hash()] = h
+		c.byNumber[h.Number64().Uint64()] = h
+	}
+}
+
+func (c *skeletonChain) GetHeader(hash types.Hash, number *uint256.Int) block2.IHeader {
+	if h, ok := c.byHash[hash]; ok {
+		return h
+	}
+	return c.IBlockChain.GetHeader(hash, number)
+}
+
+func (c *skeletonChain) GetHeaderByNumber(number *uint256.Int) block2.IHeader {
+	if h, ok := c.byNumber[number.Uint64()]; ok {
+		return h
+	}
+	return c.IBlockChain.GetHeaderByNumber(number)
+}
+
+func (c *skeletonChain) GetHeaderByHash(hash types.Hash) (block2.IHeader, error) {
+	if h, ok := c.byHash[hash]; ok {
+		return h, nil
+	}
+	return c.IBlockChain.GetHeaderByHash(hash)
+}
+
+// fetchResult is the outcome of a single task fetched by a peer.
+type fetchResult struct {
+	index int
+	pid   peer.ID
+	err   error
+}
+
+// fetchTask tracks retries of a single task.
+type fetchTask struct {
+	attempts int
+	excluded map[peer.ID]bool
+}
+
+// fetchInOrder fetches n tasks concurrently, one task per peer at a time, and
+// delivers them strictly in order. A task is only handed to peers whose head is
+// at least need(i). Failed fetches or deliveries requeue the task for another peer.
+func (s *Service) fetchInOrder(
+	ctx context.Context,
+	n int,
+	need func(i int) uint64,
+	fetch func(ctx context.Context, pid peer.ID, i int) error,
+	deliver func(pid peer.ID, i int) error,
+) error {
+	tasks := make([]*fetchTask, n)
+	for i := range tasks {
+		tasks[i] = &fetchTask{excluded: make(map[peer.ID]bool)}
+	}
+	pending := make(map[int]bool, n)
+	for i := 0; i < n; i++ {
+		pending[i] = true
+	}
+	fetched := make(map[int]peer.ID, n)
+	busy := make(map[peer.ID]bool)
+	// Buffered, so that workers never block once we bail out.
+	results := make(chan fetchResult, n*maxFetchAttempts)
+	inflight := 0
+
+	retry := func(i int, pid peer.ID, err error) error {
+		t := tasks[i]
+		t.attempts++
+		t.excluded[pid] = true
+		if t.attempts >= maxFetchAttempts {
+			return errors.Wrapf(errTaskAttempts, "task %d: %v", i, err)
+		}
+		pending[i] = true
+		return nil
+	}
+
+	for next := 0; next < n; {
+		_, peers := s.cfg.P2P.Peers().BestPeers(maxSkeletonPeers, s.cfg.Chain.CurrentBlock().Number64())
+		for _, pid := range peers {
+			if busy[pid] {
+				continue
+			}
+			st, err := s.cfg.P2P.Peers().ChainState(pid)
+			if err != nil || st == nil || st.CurrentHeight == nil {
+				continue
+			}
+			head := utils.ConvertH256ToUint256Int(st.CurrentHeight).Uint64()
+			for i := next; i < n && i < next+fetchWindow; i++ {
+				if !pending[i] || tasks[i].excluded[pid] || need(i) > head {
+					continue
+				}
+				delete(pending, i)
+				busy[pid] = true
+				inflight++
+				go func(pid peer.ID, i int) {
+					results <- fetchResult{index: i, pid: pid, err: fetch(ctx, pid, i)}
+				}(pid, i)
+				break
+			}
+		}
+		if inflight == 0 {
+			return errNoPeersAvailable
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case r := <-results:
+			inflight--
+			delete(busy, r.pid)
+			if r.err != nil {
+				log.Debug("Skeleton fetch failed", "peer", r.pid, "task", r.index, "err", r.err)
+				if errors.Is(r.err, astsync.ErrInvalidFetchedData) {
+					s.cfg.P2P.Peers().Scorers().BadResponsesScorer().Increment(r.pid)
+				}
+				if err := retry(r.index, r.pid, r.err); err != nil {
+					return err
+				}
+				continue
+			}
+			fetched[r.index] = r.pid
+		}
+
+		for next < n {
+			pid, ok := fetched[next]
+			if !ok {
+				break
+			}
+			delete(fetched, next)
+			if err := deliver(pid, next); err != nil {
+				log.Warn("Peer delivered invalid chain data", "peer", pid, "task", next, "err", err)
+				s.cfg.P2P.Peers().Scorers().BadResponsesScorer().Increment(pid)
+				if err := retry(next, pid, err); err != nil {
+					return err
+				}
+				break
+			}
+			next++
+		}
+	}
+	return nil
+}
+
+// skeletonPeer selects the peer serving the skeleton: a trusted peer ahead of
+// us if there is one, otherwise the best block provider. While the trusted block
+// is ahead of us, only peers serving it are selected.
+func (s *Service) skeletonPeer(ctx context.Context, f *blocksFetcher) (peer.ID, *uint256.Int, error) {
+	current := s.cfg.Chain.CurrentBlock().Number64()
+	_, peers := s.cfg.P2P.Peers().BestPeers(maxSkeletonPeers, current)
+	peers = s.cfg.P2P.Peers().Scorers().BlockProviderScorer().Sorted(peers, nil)
+	candidates := make([]peer.ID, 0, len(peers))
+	for _, pid := range peers {
+		if s.cfg.P2P.Peers().IsTrusted(pid) {
+			candidates = append(candidates, pid)
+		}
+	}
+	for _, pid := range peers {
+		if !s.cfg.P2P.Peers().IsTrusted(pid) {
+			candidates = append(candidates, pid)
+		}
+	}
+
+	for _, pid := range candidates {
+		st, err := s.cfg.P2P.Peers().ChainState(pid)
+		if err != nil || st == nil || st.CurrentHeight == nil {
+			continue
+		}
+		head := utils.ConvertH256ToUint256Int(st.CurrentHeight)
+		if s.trustedBlockPending() {
+			if head.Uint64() < s.cfg.TrustedBlock.Number {
+				continue
+			}
+			if err := s.verifyTrustedBlock(ctx, f, pid); err != nil {
+				log.Debug("Peer cannot serve the trusted block", "peer", pid, "err", err)
+				continue
+			}
+		}
+		return pid, head, nil
+	}
+	return "", nil, errNoPeersAvailable
+}
+
+// trustedBlockPending reports whether the trusted block is ahead of the local head.
+func (s *Service) trustedBlockPending() bool {
+	return s.cfg.TrustedBlock != nil && s.cfg.Chain.CurrentBlock().Number64().Uint64() < s.cfg.TrustedBlock.Number
+}
+
+// verifyTrustedBlock fetches the trusted block header from a peer and checks its hash,
+// penalizing peers on another chain.
+func (s *Service) verifyTrustedBlock(ctx context.Context, f *blocksFetcher, pid peer.ID) error {
+	pbHeaders, err := f.requestHeaders(ctx, &sync_pb.HeadersByRangeRequest{
+		StartBlockNumber: utils.ConvertUint256IntToH256(uint256.NewInt(s.cfg.TrustedBlock.Number)),
+		Count:            1,
+		Step:             1,
+	}, pid)
+	if err != nil {
+		return err
+	}
+	if len(pbHeaders) != 1 {
+		s.cfg.P2P.Peers().Scorers().BadResponsesScorer().Increment(pid)
+		return astsync.ErrInvalidFetchedData
+	}
+	headers, err := toHeaders(pbHeaders)
+	if err != nil {
+		return err
+	}
+	if headers[0].Hash() != s.cfg.TrustedBlock.Hash {
+		s.cfg.P2P.Peers().Scorers().BadResponsesScorer().Increment(pid)
+		return errTrustedBlockMismatch
+	}
+	return nil
+}
+
+// skeletonSync downloads the chain up to highestExpectedBlockNr. Sparse skeleton
+// headers are fetched from a single peer, the gaps in between are filled from many
+// peers in parallel and verified, then bodies are downloaded concurrently and
+// imported in order.
+func (s *Service) skeletonSync(highestExpectedBlockNr *uint256.Int) error {
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	s.counter = ratecounter.NewRateCounter(counterSeconds * time.Second)
+	s.highestExpectedBlockNr = highestExpectedBlockNr.Clone()
+	if s.cfg.Chain.CurrentBlock().Number64().Cmp(highestExpectedBlockNr) >= 0 {
+		log.Debug("Already synced to highest expected block number")
+		return nil
+	}
+
+	fetcher := newBlocksFetcher(ctx, &blocksFetcherConfig{
+		chain: s.cfg.Chain,
+		p2p:   s.cfg.P2P,
+		mode:  modeStopOnFinalizedEpoch,
+	})
+	if err := fetcher.start(); err != nil {
+		return err
+	}
+	defer fetcher.stop()
+
+	for s.cfg.Chain.CurrentBlock().Number64().Cmp(highestExpectedBlockNr) < 0 {
+		before := s.cfg.Chain.CurrentBlock().Number64().Uint64()
+		if err := s.skeletonRound(ctx, fetcher, highestExpectedBlockNr.Uint64()); err != nil {
+			return err
+		}
+		if s.cfg.Chain.CurrentBlock().Number64().Uint64() <= before {
+			return errNoSkeletonProgress
+		}
+	}
+	return nil
+}
+
+// skeletonRound syncs a single skeleton of at most maxSkeletonHeaders gaps.
+func (s *Service) skeletonRound(ctx context.Context, f *blocksFetcher, target uint64) error {
+	pid, peerHead, err := s.skeletonPeer(ctx, f)
+	if err != nil {
+		return err
+	}
+	if peerHead.Uint64() < target {
+		target = peerHead.Uint64()
+	}
+	// Stop at the trusted block first, so that it ends up as the last skeleton header.
+	if s.trustedBlockPending() && s.cfg.TrustedBlock.Number < target {
+		target = s.cfg.TrustedBlock.Number
+	}
+	head := s.cfg.Chain.CurrentBlock().Header()
+	from := head.Number64().Uint64()
+	if target <= from {
+		return nil
+	}
+
+	anchors, err := s.fetchSkeleton(ctx, f, pid, from, target)
+	if err != nil {
+		s.cfg.P2P.Peers().Scorers().BadResponsesScorer().Increment(pid)
+		return err
+	}
+	if b := s.cfg.TrustedBlock; b != nil {
+		for _, anchor := range anchors {
+			if anchor.Number64().Uint64() == b.Number && anchor.Hash() != b.Hash {
+				s.cfg.P2P.Peers().Scorers().BadResponsesScorer().Increment(pid)
+				return errTrustedBlockMismatch
+			}
+		}
+	}
+	log.Info("Fetched skeleton headers", "peer", pid, "from", from+1, "to", anchors[len(anchors)-1].Number64().Uint64(), "anchors", len(anchors))
+
+	// Fill the gaps between anchors, the gap i ends with anchors[i].
+	overlay := newSkeletonChain(s.cfg.Chain)
+	gaps := make([][]block2.IHeader, len(anchors))
+	start := func(i int) uint64 {
+		if i == 0 {
+			return from + 1
+		}
+		return anchors[i-1].Number64().Uint64() + 1
+	}
+	fetchGap := func(ctx context.Context, gpid peer.ID, i int) error {
+		end := anchors[i].Number64().Uint64()
+		pbHeaders, err := f.requestHeaders(ctx, &sync_pb.HeadersByRangeRequest{
+			StartBlockNumber: utils.ConvertUint256IntToH256(uint256.NewInt(start(i))),
+			Count:            end - start(i) + 1,
+			Step:             1,
+		}, gpid)
+		if err != nil {
+			return err
+		}
+		if uint64(len(pbHeaders)) != end-start(i)+1 {
+			return astsync.ErrInvalidFetchedData
+		}
+		headers, err := toHeaders(pbHeaders)
+		if err != nil {
+			return astsync.ErrInvalidFetchedData
+		}
+		gaps[i] = headers
+		return nil
+	}
+	verifyGap := func(_ peer.ID, i int) error {
+		parent := head.Hash()
+		if i > 0 {
+			parent = anchors[i-1].Hash()
+		}
+		headers := gaps[i]
+		for _, h := range headers {
+			if h.(*block2.Header).ParentHash != parent {
+				return errSkeletonMismatch
+			}
+			parent = h.Hash()
+		}
+		if parent != anchors[i].Hash() {
+			return errSkeletonMismatch
+		}
+		if err := verifyHeaders(overlay, headers); err != nil {
+			return err
+		}
+		overlay.add(headers)
+		return nil
+	}
+	if err := s.fetchInOrder(ctx, len(anchors), func(i int) uint64 { return anchors[i].Number64().Uint64() }, fetchGap, verifyGap); err != nil {
+		if errors.Is(err, errTaskAttempts) {
+			// Every peer disagreed with the skeleton, blame its provider.
+			s.cfg.P2P.Peers().Scorers().BadResponsesScorer().Increment(pid)
+		}
+		return err
+	}
+
+	var headers []block2.IHeader
+	for _, gap := range gaps {
+		headers = append(headers, gap...)
+	}
+	return s.fetchBodies(ctx, f, headers)
+}
+
+// fetchSkeleton fetches every skeletonStride-th header after from, plus the header
+// at target when it is not on the stride.
+func (s *Service) fetchSkeleton(ctx context.Context, f *blocksFetcher, pid peer.ID, from, target uint64) ([]block2.IHeader, error) {
+	var anchors []block2.IHeader
+	if count := (target - from) / skeletonStride; count > 0 {
+		if count > maxSkeletonHeaders {
+			count = maxSkeletonHeaders
+		}
+		pbHeaders, err := f.requestHeaders(ctx, &sync_pb.HeadersByRangeRequest{
+			StartBlockNumber: utils.ConvertUint256IntToH256(uint256.NewInt(from + skeletonStride)),
+			Count:            count,
+			Step:             skeletonStride,
+		}, pid)
+		if err != nil {
+			return nil, err
+		}
+		if uint64(len(pbHeaders)) != count {
+			return nil, astsync.ErrInvalidFetchedData
+		}
+		if anchors, err = toHeaders(pbHeaders); err != nil {
+			return nil, err
+		}
+		if count == maxSkeletonHeaders {
+			return anchors, nil
+		}
+	}
+
+	if last := from + (target-from)/skeletonStride*skeletonStride; last < target {
+		pbHeaders, err := f.requestHeaders(ctx, &sync_pb.HeadersByRangeRequest{
+			StartBlockNumber: utils.ConvertUint256IntToH256(uint256.NewInt(target)),
+			Count:            1,
+			Step:             1,
+		}, pid)
+		if err != nil {
+			return nil, err
+		}
+		if len(pbHeaders) != 1 {
+			return nil, astsync.ErrInvalidFetchedData
+		}
+		tail, err := toHeaders(pbHeaders)
+		if err != nil {
+			return nil, err
+		}
+		anchors = append(anchors, tail...)
+	}
+	return anchors, nil
+}
+
+// fetchBodies downloads the bodies of the verified headers from many peers and
+// imports them in order.
+func (s *Service) fetchBodies(ctx context.Context, f *blocksFetcher, headers []block2.IHeader) error {
+	batch := uint64(s.cfg.P2P.GetConfig().P2PLimit.BlockBatchLimit)
+	if batch == 0 || batch > uint64(len(headers)) {
+		batch = uint64(len(headers))
+	}
+	n := (uint64(len(headers)) + batch - 1) / batch
+	bodies := make([][]*types_pb.Block, n)
+	span := func(i int) []block2.IHeader {
+		end := uint64(i+1) * batch
+		if end > uint64(len(headers)) {
+			end = uint64(len(headers))
+		}
+		return headers[uint64(i)*batch : end]
+	}
+
+	fetchBatch := func(ctx context.Context, pid peer.ID, i int) error {
+		want := span(i)
+		blks, err := f.requestBlocks(ctx, &sync_pb.BodiesByRangeRequest{
+			StartBlockNumber: utils.ConvertUint256IntToH256(want[0].Number64()),
+			Count:            uint64(len(want)),
+			Step:             1,
+		}, pid)
+		if err != nil {
+			return err
+		}
+		if len(blks) != len(want) {
+			return astsync.ErrInvalidFetchedData
+		}
+		bodies[i] = blks
+		return nil
+	}
+	importBatch := func(pid peer.ID, i int) error {
+		want := span(i)
+		blocks := make([]block2.IBlock, 0, len(want))
+		for j, blk := range bodies[i] {
+			b := new(block2.Block)
+			if err := b.FromProtoMessage(blk); err != nil {
+				return err
+			}
+			if b.Hash() != want[j].Hash() {
+				return fmt.Errorf("%w: block %d hash mismatch", errSkeletonMismatch, want[j].Number64().Uint64())
+			}
+			blocks = append(blocks, b)
+		}
+		s.logBatchSyncStatus(bodies[i])
+		if _, err := s.cfg.Chain.InsertChain(blocks); err != nil {
+			return err
+		}
+		s.cfg.P2P.Peers().Scorers().BlockProviderScorer().IncrementProcessedBlocks(pid, uint64(len(blocks)))
+		return nil
+	}
+	return s.fetchInOrder(ctx, int(n), func(i int) uint64 {
+		want := span(i)
+		return want[len(want)-1].Number64().Uint64()
+	}, fetchBatch, importBatch)
+}
+
+// verifyHeaders checks a contiguous batch of headers against the consensus rules.
+func verifyHeaders(chain *skeletonChain, headers []block2.IHeader) error {
+	seals := make([]bool, len(headers))
+	for i := range seals {
+		seals[i] = true
+	}
+	abort, results := chain.Engine().VerifyHeaders(chain, headers, seals)
+	defer close(abort)
+	for range headers {
+		if err := <-results; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func toHeaders(pbHeaders []*types_pb.Header) ([]block2.IHeader, error) {
+	headers := make([]block2.IHeader, 0, len(pbHeaders))
+	for _, pb := range pbHeaders {
+		h := new(block2.Header)
+		if err := h.FromProtoMessage(pb); err != nil {
+			return nil, err
+		}
+		headers = append(headers, h)
+	}
+	return headers, nil
+}
@@ -0,0 +1,391 @@
+package initialsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	coretest "github.com/libp2p/go-libp2p/core/test"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/n42blockchain/N42/api/protocol/sync_pb"
+	"github.com/n42blockchain/N42/common"
+	block2 "github.com/n42blockchain/N42/common/block"
+	"github.com/n42blockchain/N42/conf"
+	"github.com/n42blockchain/N42/internal/consensus"
+	"github.com/n42blockchain/N42/internal/p2p"
+	"github.com/n42blockchain/N42/internal/p2p/encoder"
+	"github.com/n42blockchain/N42/internal/p2p/peers"
+	"github.com/n42blockchain/N42/internal/p2p/peers/scorers"
+	astsync "github.com/n42blockchain/N42/internal/sync"
+	"github.com/n42blockchain/N42/params"
+	"github.com/n42blockchain/N42/utils"
+	ssz "github.com/prysmaticlabs/fastssz"
+)
+
+// testEngine accepts every header.
+type testEngine struct {
+	consensus.Engine
+}
+
+func (testEngine) VerifyHeaders(_ consensus.ChainHeaderReader, headers []block2.IHeader, _ []bool) (chan<- struct{}, <-chan error) {
+	results := make(chan error, len(headers))
+	for range headers {
+		results <- nil
+	}
+	return make(chan struct{}), results
+}
+
+// testChain is a chain of blocks which is extended by InsertChain.
+type testChain struct {
+	common.IBlockChain
+	mu     sync.Mutex
+	blocks []block2.IBlock
+}
+
+// newTestChain returns a chain of n blocks after the genesis block. Chains made
+// with another fork share the genesis block only.
+func newTestChain(n int, fork byte) *testChain {
+	genesis := block2.NewBlock(&block2.Header{Number: uint256.NewInt(0), Difficulty: uint256.NewInt(1), BaseFee: uint256.NewInt(0), Extra: []byte{}}, nil)
+	chain := &testChain{blocks: []block2.IBlock{genesis}}
+	for i := 1; i <= n; i++ {
+		chain.blocks = append(chain.blocks, block2.NewBlock(&block2.Header{
+			ParentHash: chain.blocks[i-1].Hash(),
+			Number:     uint256.NewInt(uint64(i)),
+			Difficulty: uint256.NewInt(1),
+			BaseFee:    uint256.NewInt(0),
+			Time:       uint64(i),
+			Extra:      []byte{fork},
+		}, nil))
+	}
+	return chain
+}
+
+func (c *testChain) Config() *params.ChainConfig { return &params.ChainConfig{} }
+func (c *testChain) GenesisBlock() block2.IBlock { return c.blocks[0] }
+func (c *testChain) Engine() consensus.Engine    { return testEngine{} }
+
+func (c *testChain) CurrentBlock() block2.IBlock {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.blocks[len(c.blocks)-1]
+}
+
+func (c *testChain) InsertChain(blocks []block2.IBlock) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, b := range blocks {
+		if head := c.blocks[len(c.blocks)-1]; b.ParentHash() != head.Hash() {
+			return i, fmt.Errorf("block %d does not extend the head %d", b.Number64().Uint64(), head.Number64().Uint64())
+		}
+		c.blocks = append(c.blocks, b)
+	}
+	return len(blocks), nil
+}
+
+// testP2P sends requests over a host and keeps the peers known to it.
+type testP2P struct {
+	p2p.P2P
+	host   host.Host
+	peers  *peers.Status
+	config *conf.P2PConfig
+}
+
+func newTestP2P(h host.Host) *testP2P {
+	return &testP2P{
+		host: h,
+		peers: peers.NewStatus(context.Background(), &peers.StatusConfig{
+			PeerLimit: 10,
+			ScorerParams: &scorers.Config{
+				BadResponsesScorerConfig: &scorers.BadResponsesScorerConfig{Threshold: 10},
+			},
+		}),
+		config: &conf.P2PConfig{P2PLimit: &conf.P2PLimit{BlockBatchLimit: 64, BlockBatchLimitBurstFactor: 4, BlockBatchLimiterPeriod: 1}},
+	}
+}
+
+func (p *testP2P) Encoding() encoder.NetworkEncoding { return encoder.SszNetworkEncoder{} }
+func (p *testP2P) Peers() *peers.Status              { return p.peers }
+func (p *testP2P) GetConfig() *conf.P2PConfig        { return p.config }
+
+func (p *testP2P) Send(ctx context.Context, message interface{}, baseTopic string, pid peer.ID) (network.Stream, error) {
+	stream, err := p.host.NewStream(ctx, pid, protocol.ID(baseTopic+p.Encoding().ProtocolSuffix()))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.Encoding().EncodeWithMaxLength(stream, message.(ssz.Marshaler)); err != nil {
+		_ = stream.Reset()
+		return nil, err
+	}
+	return stream, stream.CloseWrite()
+}
+
+// addPeer registers a connected peer with the given head.
+func (p *testP2P) addPeer(pid peer.ID, head uint64) {
+	p.peers.Add(nil, pid, nil, network.DirOutbound)
+	p.peers.SetConnectionState(pid, peers.PeerConnected)
+	p.peers.SetChainState(pid, &sync_pb.Status{CurrentHeight: utils.ConvertUint256IntToH256(uint256.NewInt(head))})
+}
+
+func (p *testP2P) badResponses(pid peer.ID) int {
+	count, _ := p.peers.Scorers().BadResponsesScorer().Count(pid)
+	return count
+}
+
+// testPeer serves the headers and bodies of a chain. Single headers and headers
+// requested with a step, as requested for a skeleton, are served from the skeleton
+// chain if set.
+type testPeer struct {
+	chain    *testChain
+	skeleton *testChain
+}
+
+func (tp *testPeer) serve(h host.Host) {
+	enc := encoder.SszNetworkEncoder{}
+	h.SetStreamHandler(protocol.ID(p2p.RPCHeadersDataTopicV1+enc.ProtocolSuffix()), func(stream network.Stream) {
+		defer func() { _ = stream.Reset() }()
+		req := new(sync_pb.HeadersByRangeRequest)
+		if err := enc.DecodeWithMaxLength(stream, req); err != nil {
+			return
+		}
+		chain := tp.chain
+		if tp.skeleton != nil && (req.Count == 1 || req.Step > 1) {
+			chain = tp.skeleton
+		}
+		start := utils.ConvertH256ToUint256Int(req.StartBlockNumber).Uint64()
+		for i := uint64(0); i < req.Count && start+i*req.Step < uint64(len(chain.blocks)); i++ {
+			if err := astsync.WriteHeaderChunk(stream, chain, enc, chain.blocks[start+i*req.Step].Header()); err != nil {
+				return
+			}
+		}
+		_ = stream.Close()
+	})
+	h.SetStreamHandler(protocol.ID(p2p.RPCBodiesDataTopicV1+enc.ProtocolSuffix()), func(stream network.Stream) {
+		defer func() { _ = stream.Reset() }()
+		req := new(sync_pb.BodiesByRangeRequest)
+		if err := enc.DecodeWithMaxLength(stream, req); err != nil {
+			return
+		}
+		start := utils.ConvertH256ToUint256Int(req.StartBlockNumber).Uint64()
+		for i := uint64(0); i < req.Count && start+i*req.Step < uint64(len(tp.chain.blocks)); i++ {
+			if err := astsync.WriteBlockChunk(stream, tp.chain, enc, tp.chain.blocks[start+i*req.Step]); err != nil {
+				return
+			}
+		}
+		_ = stream.Close()
+	})
+}
+
+// newSkeletonTestService connects a syncing node with an empty chain to the given
+// peers, the first of which is trusted and thus provides the skeleton.
+func newSkeletonTestService(t *testing.T, head uint64, remotes ...*testPeer) (*Service, *testP2P, []peer.ID) {
+	mn, err := mocknet.FullMeshConnected(len(remotes) + 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = mn.Close() })
+	local := newTestP2P(mn.Hosts()[0])
+	pids := make([]peer.ID, len(remotes))
+	for i, remote := range remotes {
+		h := mn.Hosts()[i+1]
+		remote.serve(h)
+		local.addPeer(h.ID(), head)
+		pids[i] = h.ID()
+	}
+	local.peers.SetTrusted(pids[0], true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	s := &Service{cfg: &Config{P2P: local, Chain: newTestChain(0, 0)}, ctx: ctx}
+	return s, local, pids
+}
+
+func TestFetchInOrder(t *testing.T) {
+	local := newTestP2P(nil)
+	full, short := coretest.RandPeerIDFatal(t), coretest.RandPeerIDFatal(t)
+	local.addPeer(full, 100)
+	local.addPeer(short, 50)
+	s := &Service{cfg: &Config{P2P: local, Chain: newTestChain(0, 0)}}
+
+	const n = 10
+	var (
+		mu        sync.Mutex
+		fetchedBy = make(map[int][]peer.ID)
+		delivered []int
+		failed    bool
+	)
+	need := func(i int) uint64 { return uint64(i+1) * 10 }
+	fetch := func(_ context.Context, pid peer.ID, i int) error {
+		// Later tasks complete first.
+		time.Sleep(time.Duration(n-i) * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		fetchedBy[i] = append(fetchedBy[i], pid)
+		return nil
+	}
+	deliver := func(pid peer.ID, i int) error {
+		// The first delivery of task 3 is invalid, whoever fetched it.
+		if i == 3 && !failed {
+			failed = true
+			return errSkeletonMismatch
+		}
+		delivered = append(delivered, i)
+		return nil
+	}
+	if err := s.fetchInOrder(context.Background(), n, need, fetch, deliver); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, task := range delivered {
+		if task != i {
+			t.Fatalf("delivered %v, want tasks in order", delivered)
+		}
+	}
+	if len(delivered) != n {
+		t.Fatalf("delivered %d tasks, want %d", len(delivered), n)
+	}
+	for i, pids := range fetchedBy {
+		for _, pid := range pids {
+			if pid == short && need(i) > 50 {
+				t.Errorf("task %d needing block %d fetched by a peer at block 50", i, need(i))
+			}
+		}
+	}
+	if got := len(fetchedBy[3]); got != 2 {
+		t.Fatalf("task 3 fetched %d times, want 2", got)
+	}
+	if first, second := fetchedBy[3][0], fetchedBy[3][1]; first == second {
+		t.Errorf("task 3 refetched by the peer which delivered it invalid")
+	} else if local.badResponses(first) != 1 || local.badResponses(second) != 0 {
+		t.Errorf("bad responses %d and %d, want the invalid delivery penalised", local.badResponses(first), local.badResponses(second))
+	}
+}
+
+func TestFetchInOrderAttempts(t *testing.T) {
+	local := newTestP2P(nil)
+	pids := make([]peer.ID, maxFetchAttempts+1)
+	for i := range pids {
+		pids[i] = coretest.RandPeerIDFatal(t)
+		local.addPeer(pids[i], 100)
+	}
+	s := &Service{cfg: &Config{P2P: local, Chain: newTestChain(0, 0)}}
+
+	var mu sync.Mutex
+	attempts := 0
+	fetch := func(context.Context, peer.ID, int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		return astsync.ErrInvalidFetchedData
+	}
+	deliver := func(peer.ID, int) error { return nil }
+	if err := s.fetchInOrder(context.Background(), 1, func(int) uint64 { return 1 }, fetch, deliver); !errors.Is(err, errTaskAttempts) {
+		t.Fatalf("error %v, want %v", err, errTaskAttempts)
+	}
+	if attempts != maxFetchAttempts {
+		t.Errorf("fetched %d times, want %d", attempts, maxFetchAttempts)
+	}
+}
+
+func TestSkeletonSync(t *testing.T) {
+	// Three skeleton gaps, the last one ending off the stride.
+	const head = 2*skeletonStride + 50
+	canonical := newTestChain(head, 0)
+	s, local, pids := newSkeletonTestService(t, head,
+		&testPeer{chain: canonical}, &testPeer{chain: canonical}, &testPeer{chain: canonical})
+
+	if err := s.skeletonSync(uint256.NewInt(head)); err != nil {
+		t.Fatal(err)
+	}
+	chain := s.cfg.Chain.(*testChain)
+	if len(chain.blocks) != head+1 {
+		t.Fatalf("synced %d blocks, want %d", len(chain.blocks)-1, head)
+	}
+	for i, b := range chain.blocks {
+		if b.Hash() != canonical.blocks[i].Hash() {
+			t.Fatalf("block %d is %#x, want %#x", i, b.Hash(), canonical.blocks[i].Hash())
+		}
+	}
+	for _, pid := range pids {
+		if count := local.badResponses(pid); count > 0 {
+			t.Errorf("honest peer %s has %d bad responses", pid, count)
+		}
+	}
+}
+
+func TestSkeletonSyncBadGapPeer(t *testing.T) {
+	const head = 2*skeletonStride + 50
+	canonical := newTestChain(head, 0)
+	s, local, pids := newSkeletonTestService(t, head,
+		&testPeer{chain: canonical}, &testPeer{chain: canonical}, &testPeer{chain: newTestChain(head, 1)})
+
+	if err := s.skeletonSync(uint256.NewInt(head)); err != nil {
+		t.Fatal(err)
+	}
+	if current := s.cfg.Chain.CurrentBlock(); current.Hash() != canonical.blocks[head].Hash() {
+		t.Fatalf("synced to block %d %#x, want %#x", current.Number64().Uint64(), current.Hash(), canonical.blocks[head].Hash())
+	}
+	if count := local.badResponses(pids[2]); count == 0 {
+		t.Errorf("peer serving another chain not penalised")
+	}
+	for _, pid := range pids[:2] {
+		if count := local.badResponses(pid); count > 0 {
+			t.Errorf("honest peer %s has %d bad responses", pid, count)
+		}
+	}
+}
+
+func TestSkeletonSyncLyingSkeletonPeer(t *testing.T) {
+	// A single skeleton gap, which every peer fills in turn.
+	const head = skeletonStride - 10
+	canonical := newTestChain(head, 0)
+	liar := &testPeer{chain: canonical, skeleton: newTestChain(head, 1)}
+	s, local, pids := newSkeletonTestService(t, head,
+		liar, &testPeer{chain: canonical}, &testPeer{chain: canonical}, &testPeer{chain: canonical})
+
+	if err := s.skeletonSync(uint256.NewInt(head)); !errors.Is(err, errTaskAttempts) {
+		t.Fatalf("error %v, want %v", err, errTaskAttempts)
+	}
+	if current := s.cfg.Chain.CurrentBlock().Number64().Uint64(); current != 0 {
+		t.Errorf("imported up to block %d from a lying skeleton", current)
+	}
+	// Every gap disagreed with the skeleton, which is blamed on top.
+	if count := local.badResponses(pids[0]); count != 2 {
+		t.Errorf("skeleton peer has %d bad responses, want 2", count)
+	}
+	for _, pid := range pids[1:] {
+		if count := local.badResponses(pid); count != 1 {
+			t.Errorf("gap peer %s has %d bad responses, want 1", pid, count)
+		}
+	}
+}
+
+func TestSkeletonSyncTrustedBlock(t *testing.T) {
+	const head, trusted = 150, 100
+	canonical := newTestChain(head, 0)
+	// The trusted peer is on another chain, up to the trusted block.
+	fork := &testPeer{chain: newTestChain(trusted, 1)}
+	s, local, pids := newSkeletonTestService(t, head, fork, &testPeer{chain: canonical})
+	local.addPeer(pids[0], trusted)
+	s.cfg.TrustedBlock = &params.TrustedBlock{Number: trusted, Hash: canonical.blocks[trusted].Hash()}
+
+	if err := s.skeletonSync(uint256.NewInt(head)); err != nil {
+		t.Fatal(err)
+	}
+	if current := s.cfg.Chain.CurrentBlock(); current.Hash() != canonical.blocks[head].Hash() {
+		t.Fatalf("synced to block %d %#x, want %#x", current.Number64().Uint64(), current.Hash(), canonical.blocks[head].Hash())
+	}
+	if count := local.badResponses(pids[0]); count == 0 {
+		t.Errorf("peer without the trusted block not penalised")
+	}
+	if count := local.badResponses(pids[1]); count > 0 {
+		t.Errorf("peer serving the trusted block has %d bad responses", count)
+	}
+}
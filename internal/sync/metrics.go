@@ -77,6 +77,41 @@ var (
 			Buckets: []float64{5, 10, 50, 100, 150, 250, 500, 1000, 2000},
 		},
 	)
+	protocolRequestsCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "p2p_protocol_requests_total",
+			Help: "Count of rpc requests and gossip messages admitted by the protocol limiter.",
+		},
+		[]string{"protocol"},
+	)
+	protocolBytesCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "p2p_protocol_bytes_total",
+			Help: "Count of rpc and gossip bytes exchanged with peers.",
+		},
+		[]string{"protocol"},
+	)
+	protocolLimitedCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "p2p_protocol_rate_limited_total",
+			Help: "Count of rpc requests and gossip messages dropped by the protocol limiter, by the limit exceeded.",
+		},
+		[]string{"protocol", "limit"},
+	)
+	protocolActiveStreamsGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "p2p_protocol_active_streams",
+			Help: "Number of rpc requests and gossip messages currently handled.",
+		},
+		[]string{"protocol"},
+	)
+	rpcHeadersByRangeResponseLatency = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "rpc_headers_by_range_response_latency_milliseconds",
+			Help:    "Captures total time to respond to rpc headers by range requests in a milliseconds distribution",
+			Buckets: []float64{5, 10, 50, 100, 150, 250, 500, 1000, 2000},
+		},
+	)
 	arrivalBlockPropagationHistogram = promauto.NewHistogram(
 		prometheus.HistogramOpts{
 			Name:    "block_arrival_latency_milliseconds",
@@ -88,6 +123,14 @@ var (
 		Name: "block_arrival_latency_milliseconds_gauge",
 		Help: "Captures blocks propagation time. Blocks arrival in milliseconds",
 	})
+	blockExecutionFailedCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gossip_block_execution_failed_total",
+		Help: "Count of gossiped blocks relayed on their seal which failed their execution.",
+	})
+	announcedBlockFetchedCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gossip_announced_block_fetched_total",
+		Help: "Count of announced blocks fetched because they were not received in full.",
+	})
 
 	// Attestation processing granular error tracking.
 	attBadBlockCount = promauto.NewCounter(prometheus.CounterOpts{
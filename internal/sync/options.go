@@ -21,6 +21,14 @@ func WithChainService(chain common.IBlockChain) Option {
 	}
 }
 
+// WithTxsPool enables announcement based transaction propagation backed by the given pool.
+func WithTxsPool(pool common.ITxsPool) Option {
+	return func(s *Service) error {
+		s.cfg.txsPool = pool
+		return nil
+	}
+}
+
 func WithInitialSync(initialSync Checker) Option {
 	return func(s *Service) error {
 		s.cfg.initialSync = initialSync
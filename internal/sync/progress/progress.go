@@ -0,0 +1,143 @@
+// Package progress tracks the synchronization of the chain with the network,
+// combining the sync cycles of the downloader and of initial-sync with the
+// heads reported by the peers, for the eth_syncing RPC and the sync metrics.
+package progress
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/n42blockchain/N42/common"
+	"github.com/n42blockchain/N42/internal/metrics/prometheus"
+	event "github.com/n42blockchain/N42/modules/event/v2"
+	"github.com/n42blockchain/N42/utils"
+)
+
+// MaxLag is the number of blocks the local head may trail the highest head
+// reported by the peers before the node is considered syncing, even if no
+// sync cycle is running.
+const MaxLag = 5
+
+// metricsInterval is the interval at which the sync gauges are refreshed.
+const metricsInterval = 3 * time.Second
+
+var (
+	startingBlockGauge = prometheus.GetOrCreateCounter("sync_starting_block", true)
+	currentBlockGauge  = prometheus.GetOrCreateCounter("sync_current_block", true)
+	highestBlockGauge  = prometheus.GetOrCreateCounter("sync_highest_block", true)
+	syncingGauge       = prometheus.GetOrCreateCounter("sync_syncing", true)
+)
+
+// Progress is a snapshot of the synchronization of the chain.
+type Progress struct {
+	StartingBlock uint64 // Block number where the sync started
+	CurrentBlock  uint64 // Block number of the local head
+	HighestBlock  uint64 // Highest block number reported by the peers
+}
+
+// Tracker follows the sync cycles announced by DownloaderStartEvent and
+// DownloaderFinishEvent, and the distance between the local head and the
+// highest head of the peers.
+type Tracker struct {
+	current func() uint64
+	highest func() uint64
+
+	lock     sync.Mutex
+	cycles   int  // Number of sync cycles running
+	behind   bool // Whether the head was behind the peers at the last check
+	starting uint64
+}
+
+// NewTracker creates a tracker reading the number of the local head from
+// current and the highest number reported by the peers from highest.
+func NewTracker(current, highest func() uint64) *Tracker {
+	return &Tracker{current: current, highest: highest}
+}
+
+// Start follows the sync events and refreshes the sync gauges until ctx is done.
+func (t *Tracker) Start(ctx context.Context) {
+	startCh := make(chan common.DownloaderStartEvent)
+	startSub := event.GlobalEvent.Subscribe(startCh)
+	finishCh := make(chan common.DownloaderFinishEvent)
+	finishSub := event.GlobalEvent.Subscribe(finishCh)
+
+	go func() {
+		defer startSub.Unsubscribe()
+		defer finishSub.Unsubscribe()
+		for {
+			select {
+			case <-startCh:
+				t.begin()
+			case <-finishCh:
+				t.end()
+			case <-startSub.Err():
+				return
+			case <-finishSub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	utils.RunEvery(ctx, metricsInterval, t.updateMetrics)
+}
+
+// begin records the start of a sync cycle.
+func (t *Tracker) begin() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.cycles == 0 && !t.behind {
+		t.starting = t.current()
+	}
+	t.cycles++
+}
+
+// end records the end of a sync cycle. An end without a matching begin, like
+// the one a bootstrap node sends to start mining, is ignored.
+func (t *Tracker) end() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.cycles > 0 {
+		t.cycles--
+	}
+}
+
+// Progress returns the progress of the synchronization and whether the node
+// is syncing: a sync cycle is running or the head trails the highest head of
+// the peers by more than MaxLag blocks.
+func (t *Tracker) Progress() (Progress, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	current, highest := t.current(), t.highest()
+	if highest < current {
+		highest = current
+	}
+	behind := highest-current > MaxLag
+	if behind && !t.behind && t.cycles == 0 {
+		t.starting = current
+	}
+	t.behind = behind
+
+	return Progress{
+		StartingBlock: t.starting,
+		CurrentBlock:  current,
+		HighestBlock:  highest,
+	}, t.cycles > 0 || behind
+}
+
+// updateMetrics refreshes the sync gauges.
+func (t *Tracker) updateMetrics() {
+	progress, syncing := t.Progress()
+	startingBlockGauge.Set(progress.StartingBlock)
+	currentBlockGauge.Set(progress.CurrentBlock)
+	highestBlockGauge.Set(progress.HighestBlock)
+	if syncing {
+		syncingGauge.Set(1)
+	} else {
+		syncingGauge.Set(0)
+	}
+}
@@ -0,0 +1,100 @@
+package progress
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/n42blockchain/N42/common"
+	event "github.com/n42blockchain/N42/modules/event/v2"
+)
+
+// step moves the heads to current and highest, applies the sync event, if
+// any, and checks the progress.
+type step struct {
+	event            string // "start", "finish" or none
+	current, highest uint64
+}
+
+func TestTrackerProgress(t *testing.T) {
+	tests := []struct {
+		name    string
+		steps   []step
+		want    Progress
+		syncing bool
+	}{
+		{"in sync", []step{{"", 10, 12}}, Progress{0, 10, 12}, false},
+		{"within max lag", []step{{"", 10, 10 + MaxLag}}, Progress{0, 10, 10 + MaxLag}, false},
+		{"highest below current", []step{{"", 10, 5}}, Progress{0, 10, 10}, false},
+		{"cycle running", []step{{"start", 10, 10}}, Progress{10, 10, 10}, true},
+		{"cycle finished", []step{{"start", 10, 20}, {"finish", 20, 20}}, Progress{10, 20, 20}, false},
+		{"nested cycles", []step{{"start", 10, 20}, {"start", 12, 20}, {"finish", 15, 20}}, Progress{10, 15, 20}, true},
+		{"nested cycles finished", []step{{"start", 10, 20}, {"start", 12, 20}, {"finish", 15, 20}, {"finish", 20, 20}}, Progress{10, 20, 20}, false},
+		{"finish without start", []step{{"finish", 10, 10}}, Progress{0, 10, 10}, false},
+		{"start after unbalanced finish", []step{{"finish", 10, 10}, {"start", 10, 20}}, Progress{10, 10, 20}, true},
+		{"behind", []step{{"", 10, 11 + MaxLag}}, Progress{10, 10, 11 + MaxLag}, true},
+		{"still behind", []step{{"", 10, 20}, {"", 15, 25}}, Progress{10, 15, 25}, true},
+		{"caught up", []step{{"", 10, 20}, {"", 20, 20}}, Progress{10, 20, 20}, false},
+		{"behind again", []step{{"", 10, 20}, {"", 20, 20}, {"", 30, 40}}, Progress{30, 30, 40}, true},
+		{"cycle started behind", []step{{"", 10, 20}, {"start", 12, 20}}, Progress{10, 12, 20}, true},
+		{"behind during cycle", []step{{"start", 10, 10}, {"", 12, 20}}, Progress{10, 12, 20}, true},
+		{"cycle started in sync", []step{{"", 10, 10}, {"start", 12, 20}}, Progress{12, 12, 20}, true},
+	}
+	for _, tt := range tests {
+		var current, highest uint64
+		tracker := NewTracker(func() uint64 { return current }, func() uint64 { return highest })
+		var (
+			have    Progress
+			syncing bool
+		)
+		for _, s := range tt.steps {
+			current, highest = s.current, s.highest
+			switch s.event {
+			case "start":
+				tracker.begin()
+			case "finish":
+				tracker.end()
+			}
+			have, syncing = tracker.Progress()
+		}
+		if have != tt.want || syncing != tt.syncing {
+			t.Errorf("%s: progress %+v, syncing %v, want %+v, syncing %v", tt.name, have, syncing, tt.want, tt.syncing)
+		}
+	}
+}
+
+func TestTrackerEvents(t *testing.T) {
+	var current atomic.Uint64
+	current.Store(10)
+	tracker := NewTracker(current.Load, current.Load)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tracker.Start(ctx)
+
+	// waitSyncing waits for the tracker to process the events sent so far.
+	waitSyncing := func(want bool) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+			if _, syncing := tracker.Progress(); syncing == want {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("syncing never became %v", want)
+			}
+		}
+	}
+	// A finish without a start, as sent by a bootstrap node, leaves the next
+	// cycle running until its own finish.
+	event.GlobalEvent.Send(common.DownloaderFinishEvent{})
+	event.GlobalEvent.Send(common.DownloaderStartEvent{})
+	waitSyncing(true)
+	current.Store(20)
+	event.GlobalEvent.Send(common.DownloaderStartEvent{})
+	event.GlobalEvent.Send(common.DownloaderFinishEvent{})
+	if progress, syncing := tracker.Progress(); !syncing || progress.StartingBlock != 10 {
+		t.Errorf("nested cycle: progress %+v, syncing %v, want starting block 10 and syncing", progress, syncing)
+	}
+	event.GlobalEvent.Send(common.DownloaderFinishEvent{})
+	waitSyncing(false)
+}
@@ -0,0 +1,252 @@
+package sync
+
+import (
+	"math"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/n42blockchain/N42/conf"
+	"github.com/n42blockchain/N42/internal/p2p"
+	leakybucket "github.com/n42blockchain/N42/internal/p2p/leaky-bucket"
+	p2ptypes "github.com/n42blockchain/N42/internal/p2p/types"
+	"github.com/n42blockchain/N42/log"
+	"github.com/pkg/errors"
+)
+
+// protocolBurstFactor is how many seconds worth of requests or bytes may be
+// used up at once.
+const protocolBurstFactor = 2
+
+// Key of the collectors shared by all peers.
+const globalLimiterKey = "global"
+
+// errProtocolBusy is returned when a protocol reached its global limits. It is
+// not the fault of the requesting peer.
+var errProtocolBusy = errors.New("protocol limit reached")
+
+// protocolLimiter enforces the request rate and bandwidth limits of every rpc and
+// gossip protocol, and the concurrent stream limits of every rpc protocol, per
+// peer and over all peers.
+type protocolLimiter struct {
+	cfg       *conf.P2PLimit
+	protocols map[string]*protocolLimits
+	sync.Mutex
+}
+
+// protocolLimits tracks the usage of a single protocol.
+type protocolLimits struct {
+	limit          conf.ProtocolLimit
+	peerRequests   *leakybucket.Collector
+	peerBytes      *leakybucket.Collector
+	globalRequests *leakybucket.Collector
+	globalBytes    *leakybucket.Collector
+	peerStreams    map[peer.ID]int
+	streams        int
+}
+
+func newProtocolLimiter(cfg *conf.P2PLimit) *protocolLimiter {
+	if cfg == nil {
+		cfg = &conf.P2PLimit{}
+	}
+	return &protocolLimiter{cfg: cfg, protocols: make(map[string]*protocolLimits)}
+}
+
+// newRateCollector returns a collector leaking rate units per second, or nil if
+// rate is unlimited.
+func newRateCollector(rate float64, deleteEmptyBuckets bool) *leakybucket.Collector {
+	if rate <= 0 {
+		return nil
+	}
+	capacity := int64(math.Ceil(rate * protocolBurstFactor))
+	return leakybucket.NewCollector(rate, capacity, time.Second, deleteEmptyBuckets)
+}
+
+// protocol returns the limits of the named protocol, creating them on first use.
+// Callers must hold the lock.
+func (l *protocolLimiter) protocol(name string) *protocolLimits {
+	if p, ok := l.protocols[name]; ok {
+		return p
+	}
+	limit := l.cfg.ProtocolLimit(name)
+	p := &protocolLimits{
+		limit:          limit,
+		peerRequests:   newRateCollector(limit.PeerRequests, true /* deleteEmptyBuckets */),
+		peerBytes:      newRateCollector(float64(limit.PeerBytes), true /* deleteEmptyBuckets */),
+		globalRequests: newRateCollector(limit.GlobalRequests, false /* deleteEmptyBuckets */),
+		globalBytes:    newRateCollector(float64(limit.GlobalBytes), false /* deleteEmptyBuckets */),
+		peerStreams:    make(map[peer.ID]int),
+	}
+	l.protocols[name] = p
+	return p
+}
+
+// exceeded returns the name of the first limit the peer would exceed, if any. The
+// stream limits are only checked for requests holding a stream.
+func (p *protocolLimits) exceeded(pid peer.ID, stream bool) string {
+	key := pid.String()
+	switch {
+	case stream && p.limit.PeerStreams > 0 && p.peerStreams[pid] >= p.limit.PeerStreams:
+		return "peer_streams"
+	case p.peerRequests != nil && p.peerRequests.Remaining(key) < 1:
+		return "peer_requests"
+	case p.peerBytes != nil && p.peerBytes.Remaining(key) <= 0:
+		return "peer_bytes"
+	case stream && p.limit.GlobalStreams > 0 && p.streams >= p.limit.GlobalStreams:
+		return "global_streams"
+	case p.globalRequests != nil && p.globalRequests.Remaining(globalLimiterKey) < 1:
+		return "global_requests"
+	case p.globalBytes != nil && p.globalBytes.Remaining(globalLimiterKey) <= 0:
+		return "global_bytes"
+	}
+	return ""
+}
+
+// acquire admits a request of size bytes from the peer on the named rpc protocol
+// and holds one of its concurrent streams until release is called. Bandwidth is
+// checked before and charged after the fact, as response sizes are not known up
+// front. ErrRateLimited is returned if the peer exceeds its own limits.
+func (l *protocolLimiter) acquire(name string, pid peer.ID, size int64) (release func(), err error) {
+	l.Lock()
+	defer l.Unlock()
+
+	p := l.protocol(name)
+	if err := l.admit(name, p, pid, size, true /* stream */); err != nil {
+		return nil, err
+	}
+	p.peerStreams[pid]++
+	p.streams++
+	protocolActiveStreamsGauge.WithLabelValues(name).Inc()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.Lock()
+			defer l.Unlock()
+			if p.peerStreams[pid]--; p.peerStreams[pid] <= 0 {
+				delete(p.peerStreams, pid)
+			}
+			p.streams--
+			protocolActiveStreamsGauge.WithLabelValues(name).Dec()
+		})
+	}, nil
+}
+
+// admitMessage admits a gossip message of size bytes from the peer on the named
+// protocol. Gossip messages do not hold a stream, so only the request rate and
+// bandwidth limits apply to them.
+func (l *protocolLimiter) admitMessage(name string, pid peer.ID, size int64) error {
+	l.Lock()
+	defer l.Unlock()
+	return l.admit(name, l.protocol(name), pid, size, false /* stream */)
+}
+
+// admit checks the limits of the protocol and charges the request of size bytes
+// against them. Callers must hold the lock.
+func (l *protocolLimiter) admit(name string, p *protocolLimits, pid peer.ID, size int64, stream bool) error {
+	if reason := p.exceeded(pid, stream); reason != "" {
+		protocolLimitedCounter.WithLabelValues(name, reason).Inc()
+		if strings.HasPrefix(reason, "global") {
+			return errProtocolBusy
+		}
+		return p2ptypes.ErrRateLimited
+	}
+	if p.peerRequests != nil {
+		p.peerRequests.Add(pid.String(), 1)
+	}
+	if p.globalRequests != nil {
+		p.globalRequests.Add(globalLimiterKey, 1)
+	}
+	l.chargeBytes(name, p, pid, size)
+	protocolRequestsCounter.WithLabelValues(name).Inc()
+	return nil
+}
+
+// addBytes charges bytes exchanged with the peer on the named protocol.
+func (l *protocolLimiter) addBytes(name string, pid peer.ID, size int64) {
+	l.Lock()
+	defer l.Unlock()
+	l.chargeBytes(name, l.protocol(name), pid, size)
+}
+
+// chargeBytes is the lock-free version of addBytes.
+func (l *protocolLimiter) chargeBytes(name string, p *protocolLimits, pid peer.ID, size int64) {
+	if size <= 0 {
+		return
+	}
+	if p.peerBytes != nil {
+		p.peerBytes.Add(pid.String(), size)
+	}
+	if p.globalBytes != nil {
+		p.globalBytes.Add(globalLimiterKey, size)
+	}
+	protocolBytesCounter.WithLabelValues(name).Add(float64(size))
+}
+
+// free releases the collectors of all protocols.
+func (l *protocolLimiter) free() {
+	l.Lock()
+	defer l.Unlock()
+	for name, p := range l.protocols {
+		for _, c := range []*leakybucket.Collector{p.peerRequests, p.peerBytes, p.globalRequests, p.globalBytes} {
+			if c != nil {
+				c.Free()
+			}
+		}
+		delete(l.protocols, name)
+	}
+}
+
+// rpcProtocolName returns the message name of an rpc topic, such as "status".
+func rpcProtocolName(baseTopic string) string {
+	_, message, _, err := p2p.TopicDeconstructor(baseTopic)
+	if err != nil || message == "" {
+		return baseTopic
+	}
+	return strings.TrimPrefix(message, "/")
+}
+
+// gossipProtocolName returns the message name of a full gossip topic, such as "block".
+func (s *Service) gossipProtocolName(topic string) string {
+	return path.Base(strings.TrimSuffix(topic, s.cfg.p2p.Encoding().ProtocolSuffix()))
+}
+
+// rejectOverLimit penalizes a peer exceeding its own protocol limits, and says
+// goodbye once its score turned bad.
+func (s *Service) rejectOverLimit(pid peer.ID, name string, err error) {
+	if !errors.Is(err, p2ptypes.ErrRateLimited) {
+		log.Trace("Dropped request over the global protocol limits", "peer", pid, "protocol", name)
+		return
+	}
+	log.Debug("Peer exceeded the protocol limits", "peer", pid, "protocol", name)
+	s.cfg.p2p.Peers().Scorers().BadResponsesScorer().Increment(pid)
+	if s.cfg.p2p.Peers().IsBad(pid) {
+		go func() {
+			if err := s.sendGoodByeAndDisconnect(s.ctx, p2ptypes.GoodbyeCodeBadScore, pid); err != nil {
+				log.Debug("Could not disconnect from peer", "peer", pid, "err", err)
+			}
+		}()
+	}
+}
+
+// meteredStream counts the bytes read from and written to a stream.
+type meteredStream struct {
+	network.Stream
+	bytes atomic.Int64
+}
+
+func (m *meteredStream) Read(b []byte) (int, error) {
+	n, err := m.Stream.Read(b)
+	m.bytes.Add(int64(n))
+	return n, err
+}
+
+func (m *meteredStream) Write(b []byte) (int, error) {
+	n, err := m.Stream.Write(b)
+	m.bytes.Add(int64(n))
+	return n, err
+}
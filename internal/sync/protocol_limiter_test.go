@@ -0,0 +1,204 @@
+package sync
+
+import (
+	"errors"
+	"testing"
+
+	coretest "github.com/libp2p/go-libp2p/core/test"
+	"github.com/n42blockchain/N42/conf"
+	p2ptypes "github.com/n42blockchain/N42/internal/p2p/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestProtocolLimiterStreams(t *testing.T) {
+	l := newProtocolLimiter(&conf.P2PLimit{Protocol: conf.ProtocolLimit{PeerStreams: 2, GlobalStreams: 3}})
+	defer l.free()
+	a, b, c := coretest.RandPeerIDFatal(t), coretest.RandPeerIDFatal(t), coretest.RandPeerIDFatal(t)
+
+	releaseA1, err := l.acquire("status", a, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.acquire("status", a, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.acquire("status", a, 0); !errors.Is(err, p2ptypes.ErrRateLimited) {
+		t.Errorf("third stream of peer: error %v, want %v", err, p2ptypes.ErrRateLimited)
+	}
+	// Streams are limited per protocol.
+	if _, err := l.acquire("ping", a, 0); err != nil {
+		t.Errorf("stream on other protocol: %v", err)
+	}
+	if _, err := l.acquire("status", b, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.acquire("status", b, 0); !errors.Is(err, errProtocolBusy) {
+		t.Errorf("stream over global limit: error %v, want %v", err, errProtocolBusy)
+	}
+
+	// Releasing twice frees a single stream.
+	releaseA1()
+	releaseA1()
+	if _, err := l.acquire("status", b, 0); err != nil {
+		t.Errorf("stream after release: %v", err)
+	}
+	if _, err := l.acquire("status", c, 0); !errors.Is(err, errProtocolBusy) {
+		t.Errorf("stream after double release: error %v, want %v", err, errProtocolBusy)
+	}
+	l.Lock()
+	defer l.Unlock()
+	if p := l.protocols["status"]; p.streams != 3 || p.peerStreams[a] != 1 || p.peerStreams[b] != 2 {
+		t.Errorf("streams %d, peer streams %v, want 3 in total, 1 of a and 2 of b", p.streams, p.peerStreams)
+	}
+}
+
+func TestProtocolLimiterRequests(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit conf.ProtocolLimit
+		want  error
+	}{
+		{"unlimited", conf.ProtocolLimit{}, nil},
+		{"peer requests", conf.ProtocolLimit{PeerRequests: 1}, p2ptypes.ErrRateLimited},
+		{"global requests", conf.ProtocolLimit{GlobalRequests: 1}, errProtocolBusy},
+	}
+	for _, tt := range tests {
+		l := newProtocolLimiter(&conf.P2PLimit{Protocol: tt.limit})
+		pid := coretest.RandPeerIDFatal(t)
+		// Requests may burst to twice the rate, and each peer has its own allowance.
+		for i := 0; i < protocolBurstFactor; i++ {
+			release, err := l.acquire("status", pid, 0)
+			if err != nil {
+				t.Fatalf("%s: request %d: %v", tt.name, i, err)
+			}
+			release()
+		}
+		if _, err := l.acquire("status", pid, 0); !errors.Is(err, tt.want) {
+			t.Errorf("%s: error %v, want %v", tt.name, err, tt.want)
+		}
+		if _, err := l.acquire("status", coretest.RandPeerIDFatal(t), 0); (err == nil) != (tt.want != errProtocolBusy) {
+			t.Errorf("%s: other peer error %v", tt.name, err)
+		}
+		l.free()
+	}
+}
+
+func TestProtocolLimiterRequestsCounter(t *testing.T) {
+	l := newProtocolLimiter(&conf.P2PLimit{Protocol: conf.ProtocolLimit{PeerStreams: 1}})
+	defer l.free()
+	pid := coretest.RandPeerIDFatal(t)
+	counter := protocolRequestsCounter.WithLabelValues("counted")
+	before := testutil.ToFloat64(counter)
+
+	// Every admitted request and message is counted once, rejected ones not at all.
+	if _, err := l.acquire("counted", pid, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.acquire("counted", pid, 0); !errors.Is(err, p2ptypes.ErrRateLimited) {
+		t.Fatalf("stream over peer limit: error %v, want %v", err, p2ptypes.ErrRateLimited)
+	}
+	if err := l.admitMessage("counted", pid, 0); err != nil {
+		t.Fatal(err)
+	}
+	if have := testutil.ToFloat64(counter) - before; have != 2 {
+		t.Errorf("counted %v requests, want 2", have)
+	}
+}
+
+func TestProtocolLimiterBytes(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit conf.ProtocolLimit
+		want  error
+	}{
+		{"peer bytes", conf.ProtocolLimit{PeerBytes: 100}, p2ptypes.ErrRateLimited},
+		{"global bytes", conf.ProtocolLimit{GlobalBytes: 100}, errProtocolBusy},
+	}
+	for _, tt := range tests {
+		l := newProtocolLimiter(&conf.P2PLimit{Protocol: tt.limit})
+		pid := coretest.RandPeerIDFatal(t)
+		// The size of a request is charged up front, response bytes after the fact.
+		if _, err := l.acquire("block", pid, 150); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if _, err := l.acquire("block", pid, 0); err != nil {
+			t.Fatalf("%s: request below the burst: %v", tt.name, err)
+		}
+		l.addBytes("block", pid, 50)
+		if _, err := l.acquire("block", pid, 0); !errors.Is(err, tt.want) {
+			t.Errorf("%s: error %v, want %v", tt.name, err, tt.want)
+		}
+		if err := l.admitMessage("block", pid, 1); !errors.Is(err, tt.want) {
+			t.Errorf("%s: message error %v, want %v", tt.name, err, tt.want)
+		}
+		// Other protocols are charged separately.
+		if _, err := l.acquire("status", pid, 0); err != nil {
+			t.Errorf("%s: other protocol: %v", tt.name, err)
+		}
+		l.free()
+	}
+}
+
+func TestProtocolLimiterOverrides(t *testing.T) {
+	l := newProtocolLimiter(&conf.P2PLimit{
+		Protocol:  conf.ProtocolLimit{PeerStreams: 1},
+		Protocols: map[string]conf.ProtocolLimit{"bodies_by_range": {PeerStreams: 2}},
+	})
+	defer l.free()
+	pid := coretest.RandPeerIDFatal(t)
+
+	for i := 0; i < 2; i++ {
+		if _, err := l.acquire("bodies_by_range", pid, 0); err != nil {
+			t.Fatalf("overridden protocol stream %d: %v", i, err)
+		}
+	}
+	if _, err := l.acquire("bodies_by_range", pid, 0); !errors.Is(err, p2ptypes.ErrRateLimited) {
+		t.Errorf("overridden protocol: error %v, want %v", err, p2ptypes.ErrRateLimited)
+	}
+	if _, err := l.acquire("status", pid, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.acquire("status", pid, 0); !errors.Is(err, p2ptypes.ErrRateLimited) {
+		t.Errorf("default protocol: error %v, want %v", err, p2ptypes.ErrRateLimited)
+	}
+}
+
+func TestProtocolLimiterGossip(t *testing.T) {
+	l := newProtocolLimiter(&conf.P2PLimit{Protocol: conf.ProtocolLimit{PeerStreams: 1, GlobalStreams: 1, PeerRequests: 5}})
+	defer l.free()
+	pid := coretest.RandPeerIDFatal(t)
+
+	// Gossip messages hold no stream, so only the request rate limits them.
+	for i := 0; i < 5*protocolBurstFactor; i++ {
+		if err := l.admitMessage("block", pid, 100); err != nil {
+			t.Fatalf("message %d: %v", i, err)
+		}
+	}
+	if err := l.admitMessage("block", pid, 100); !errors.Is(err, p2ptypes.ErrRateLimited) {
+		t.Errorf("message over the request rate: error %v, want %v", err, p2ptypes.ErrRateLimited)
+	}
+	l.Lock()
+	defer l.Unlock()
+	if p := l.protocols["block"]; p.streams != 0 || len(p.peerStreams) != 0 {
+		t.Errorf("gossip holds %d streams, peer streams %v", p.streams, p.peerStreams)
+	}
+}
+
+func TestRejectOverLimit(t *testing.T) {
+	s := &Service{cfg: &config{p2p: newTestP2P(t, nil)}}
+	tests := []struct {
+		name      string
+		err       error
+		penalised bool
+	}{
+		{"peer limit", p2ptypes.ErrRateLimited, true},
+		{"global limit", errProtocolBusy, false},
+	}
+	for _, tt := range tests {
+		pid := coretest.RandPeerIDFatal(t)
+		s.rejectOverLimit(pid, "status", tt.err)
+		if penalised := badResponses(s, pid) == 1; penalised != tt.penalised {
+			t.Errorf("%s: penalised %v, want %v", tt.name, penalised, tt.penalised)
+		}
+	}
+}
@@ -12,6 +12,7 @@ import (
 	libp2pcore "github.com/libp2p/go-libp2p/core"
 	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/pkg/errors"
 	ssz "github.com/prysmaticlabs/fastssz"
 	"go.opencensus.io/trace"
 )
@@ -35,6 +36,10 @@ func (s *Service) registerRPCHandlers() {
 		p2p.RPCStatusTopicV1,
 		s.statusRPCHandler,
 	)
+	s.registerRPC(
+		p2p.RPCStatusTopicV2,
+		s.statusRPCHandler,
+	)
 	s.registerRPC(
 		p2p.RPCGoodByeTopicV1,
 		s.goodbyeRPCHandler,
@@ -47,24 +52,47 @@ func (s *Service) registerRPCHandlers() {
 		p2p.RPCBodiesDataTopicV1,
 		s.bodiesByRangeRPCHandler,
 	)
+	s.registerRPC(
+		p2p.RPCHeadersDataTopicV1,
+		s.headersByRangeRPCHandler,
+	)
+	if s.txsFetcher != nil {
+		s.registerRPC(
+			p2p.RPCTxHashesTopicV1,
+			s.txHashesRPCHandler,
+		)
+		s.registerRPC(
+			p2p.RPCPooledTxsTopicV1,
+			s.pooledTxsRPCHandler,
+		)
+	}
 }
 
 // Remove all Stream handlers
 func (s *Service) unregisterHandlers() {
 	fullBodiesRangeTopic := p2p.RPCBodiesDataTopicV1 + s.cfg.p2p.Encoding().ProtocolSuffix()
+	fullHeadersRangeTopic := p2p.RPCHeadersDataTopicV1 + s.cfg.p2p.Encoding().ProtocolSuffix()
 	fullStatusTopic := p2p.RPCStatusTopicV1 + s.cfg.p2p.Encoding().ProtocolSuffix()
+	fullStatusV2Topic := p2p.RPCStatusTopicV2 + s.cfg.p2p.Encoding().ProtocolSuffix()
 	fullGoodByeTopic := p2p.RPCGoodByeTopicV1 + s.cfg.p2p.Encoding().ProtocolSuffix()
 	fullPingTopic := p2p.RPCPingTopicV1 + s.cfg.p2p.Encoding().ProtocolSuffix()
+	fullTxHashesTopic := p2p.RPCTxHashesTopicV1 + s.cfg.p2p.Encoding().ProtocolSuffix()
+	fullPooledTxsTopic := p2p.RPCPooledTxsTopicV1 + s.cfg.p2p.Encoding().ProtocolSuffix()
 
 	s.cfg.p2p.Host().RemoveStreamHandler(protocol.ID(fullBodiesRangeTopic))
+	s.cfg.p2p.Host().RemoveStreamHandler(protocol.ID(fullHeadersRangeTopic))
 	s.cfg.p2p.Host().RemoveStreamHandler(protocol.ID(fullStatusTopic))
+	s.cfg.p2p.Host().RemoveStreamHandler(protocol.ID(fullStatusV2Topic))
 	s.cfg.p2p.Host().RemoveStreamHandler(protocol.ID(fullGoodByeTopic))
 	s.cfg.p2p.Host().RemoveStreamHandler(protocol.ID(fullPingTopic))
+	s.cfg.p2p.Host().RemoveStreamHandler(protocol.ID(fullTxHashesTopic))
+	s.cfg.p2p.Host().RemoveStreamHandler(protocol.ID(fullPooledTxsTopic))
 }
 
 // registerRPC for a given topic with an expected protobuf message type.
 func (s *Service) registerRPC(baseTopic string, handle rpcHandler) {
 	topic := baseTopic + s.cfg.p2p.Encoding().ProtocolSuffix()
+	name := rpcProtocolName(baseTopic)
 	s.cfg.p2p.SetStreamHandler(topic, func(stream network.Stream) {
 		defer func() {
 			if r := recover(); r != nil {
@@ -105,6 +133,25 @@ func (s *Service) registerRPC(baseTopic string, handle rpcHandler) {
 		}
 		s.rateLimiter.addRawStream(stream)
 
+		// Validate request according to the protocol limits, and meter its bandwidth.
+		remotePeer := stream.Conn().RemotePeer()
+		release, err := s.protocolLimiter.acquire(name, remotePeer, 0)
+		if err != nil {
+			code := responseCodeServerError
+			if errors.Is(err, p2ptypes.ErrRateLimited) {
+				code = responseCodeInvalidRequest
+			}
+			s.writeErrorResponseToStream(code, err.Error(), stream)
+			s.rejectOverLimit(remotePeer, name, err)
+			return
+		}
+		defer release()
+		metered := &meteredStream{Stream: stream}
+		defer func() {
+			s.protocolLimiter.addBytes(name, remotePeer, metered.bytes.Load())
+		}()
+		stream = metered
+
 		if err := stream.SetReadDeadline(time.Now().Add(ttfbTimeout)); err != nil {
 			log.Debug("Could not set stream read deadline", "peer", stream.Conn().RemotePeer().String(), "topic", stream.Protocol(), "err", err)
 			return
@@ -10,6 +10,7 @@ import (
 	"github.com/n42blockchain/N42/internal/p2p/encoder"
 	"github.com/n42blockchain/N42/utils"
 	"github.com/pkg/errors"
+	ssz "github.com/prysmaticlabs/fastssz"
 )
 
 // chunkBlockWriter writes the given message as a chunked response to the given network
@@ -27,7 +28,7 @@ func WriteBlockChunk(stream libp2pcore.Stream, chain common.IBlockChain, encodin
 		return err
 	}
 
-	digest, err := utils.CreateForkDigest(blk.Number64(), chain.GenesisBlock().Hash())
+	digest, err := utils.CreateForkDigest(chain.Config(), blk.Number64(), chain.GenesisBlock().Hash())
 	if err != nil {
 		return err
 	}
@@ -43,51 +44,92 @@ func WriteBlockChunk(stream libp2pcore.Stream, chain common.IBlockChain, encodin
 // ReadChunkedBlock handles each response chunk that is sent by the
 // peer and converts it into a beacon block.
 func ReadChunkedBlock(stream libp2pcore.Stream, p2p p2p.EncodingProvider, isFirstChunk bool) (*types_pb.Block, error) {
-	// Handle deadlines differently for first chunk
+	blk := &types_pb.Block{}
+	if err := readChunk(stream, p2p, isFirstChunk, blk); err != nil {
+		return nil, err
+	}
+	return blk, nil
+}
+
+// chunkHeaderWriter writes the given header as a chunked response to the given network
+// stream.
+func (s *Service) chunkHeaderWriter(stream libp2pcore.Stream, header types.IHeader) error {
+	SetStreamWriteDeadline(stream, defaultWriteDuration)
+	return WriteHeaderChunk(stream, s.cfg.chain, s.cfg.p2p.Encoding(), header)
+}
+
+// WriteHeaderChunk writes header chunk object to stream.
+// response_chunk  ::= <result> | <context-bytes> | <encoding-dependent-header> | <encoded-payload>
+func WriteHeaderChunk(stream libp2pcore.Stream, chain common.IBlockChain, encoding encoder.NetworkEncoding, header types.IHeader) error {
+	if _, err := stream.Write([]byte{responseCodeSuccess}); err != nil {
+		return err
+	}
+
+	digest, err := utils.CreateForkDigest(chain.Config(), header.Number64(), chain.GenesisBlock().Hash())
+	if err != nil {
+		return err
+	}
+
+	if err = writeContextToStream(digest[:], stream, chain); err != nil {
+		return err
+	}
+	_, err = encoding.EncodeWithMaxLength(stream, header.ToProtoMessage().(*types_pb.Header))
+	return err
+}
+
+// ReadChunkedHeader handles each response chunk that is sent by the
+// peer and converts it into a header.
+func ReadChunkedHeader(stream libp2pcore.Stream, p2p p2p.EncodingProvider, isFirstChunk bool) (*types_pb.Header, error) {
+	header := &types_pb.Header{}
+	if err := readChunk(stream, p2p, isFirstChunk, header); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// readChunk reads a response chunk into msg. Deadlines are handled differently for
+// the first chunk.
+func readChunk(stream libp2pcore.Stream, p2p p2p.EncodingProvider, isFirstChunk bool, msg ssz.Unmarshaler) error {
 	if isFirstChunk {
-		return readFirstChunkedBlock(stream, p2p)
+		return readFirstChunk(stream, p2p, msg)
 	}
 
-	return readResponseChunk(stream, p2p)
+	return readResponseChunk(stream, p2p, msg)
 }
 
-// readFirstChunkedBlock reads the first chunked block and applies the appropriate deadlines to
+// readFirstChunk reads the first chunk and applies the appropriate deadlines to
 // it.
-func readFirstChunkedBlock(stream libp2pcore.Stream, p2p p2p.EncodingProvider) (*types_pb.Block, error) {
+func readFirstChunk(stream libp2pcore.Stream, p2p p2p.EncodingProvider, msg ssz.Unmarshaler) error {
 	code, errMsg, err := ReadStatusCode(stream, p2p.Encoding())
 	if err != nil {
-		return nil, err
+		return err
 	}
 	if code != 0 {
-		return nil, fmt.Errorf("%s", errMsg)
+		return fmt.Errorf("%s", errMsg)
 	}
 	_, err = readContextFromStream(stream)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	blk := &types_pb.Block{}
-	err = p2p.Encoding().DecodeWithMaxLength(stream, blk)
-	return blk, err
+	return p2p.Encoding().DecodeWithMaxLength(stream, msg)
 }
 
 // readResponseChunk reads the response from the stream and decodes it into the
 // provided message type.
-func readResponseChunk(stream libp2pcore.Stream, p2p p2p.EncodingProvider) (*types_pb.Block, error) {
+func readResponseChunk(stream libp2pcore.Stream, p2p p2p.EncodingProvider, msg ssz.Unmarshaler) error {
 	SetStreamReadDeadline(stream, respTimeout)
 	code, errMsg, err := readStatusCodeNoDeadline(stream, p2p.Encoding())
 	if err != nil {
-		return nil, err
+		return err
 	}
 	if code != 0 {
-		return nil, errors.New(errMsg)
+		return errors.New(errMsg)
 	}
 	// No-op for now with the rpc context. todo
 	_, err = readContextFromStream(stream)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	blk := &types_pb.Block{}
-	err = p2p.Encoding().DecodeWithMaxLength(stream, blk)
-	return blk, err
+	return p2p.Encoding().DecodeWithMaxLength(stream, msg)
 }
@@ -0,0 +1,77 @@
+package sync
+
+import (
+	"context"
+	"time"
+
+	"github.com/holiman/uint256"
+	libp2pcore "github.com/libp2p/go-libp2p/core"
+	"github.com/n42blockchain/N42/api/protocol/sync_pb"
+	p2ptypes "github.com/n42blockchain/N42/internal/p2p/types"
+	"github.com/n42blockchain/N42/log"
+	"github.com/n42blockchain/N42/utils"
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+)
+
+// MaxRequestHeaders is the maximum number of headers served for a single headers_by_range request.
+const MaxRequestHeaders = 192
+
+// HeaderLimitFactor scales the block rate limits up for header requests.
+const HeaderLimitFactor = 4
+
+// headersByRangeRPCHandler looks up the requested headers from the database from a given start block.
+func (s *Service) headersByRangeRPCHandler(ctx context.Context, msg interface{}, stream libp2pcore.Stream) error {
+	ctx, span := trace.StartSpan(ctx, "sync.HeadersByRangeHandler")
+	defer span.End()
+	_, cancel := context.WithTimeout(ctx, respTimeout)
+	defer cancel()
+	SetRPCStreamDeadlines(stream)
+
+	m, ok := msg.(*sync_pb.HeadersByRangeRequest)
+	if !ok {
+		return errors.New("message is not type *sync_pb.HeadersByRangeRequest")
+	}
+	if err := s.validateHeadersRangeRequest(m); err != nil {
+		s.writeErrorResponseToStream(responseCodeInvalidRequest, err.Error(), stream)
+		s.cfg.p2p.Peers().Scorers().BadResponsesScorer().Increment(stream.Conn().RemotePeer())
+		return err
+	}
+	if err := s.rateLimiter.validateRequest(stream, m.Count); err != nil {
+		return err
+	}
+	s.rateLimiter.add(stream, int64(m.Count))
+
+	start := time.Now()
+	number := utils.ConvertH256ToUint256Int(m.StartBlockNumber)
+	for i := uint64(0); i < m.Count; i++ {
+		header := s.cfg.chain.GetHeaderByNumber(number)
+		if header == nil {
+			// The remote peer asked beyond our head, return what we have.
+			break
+		}
+		if err := s.chunkHeaderWriter(stream, header); err != nil {
+			log.Debug("Could not send a chunked response", "err", err)
+			s.writeErrorResponseToStream(responseCodeServerError, p2ptypes.ErrGeneric.Error(), stream)
+			return err
+		}
+		number = new(uint256.Int).AddUint64(number, m.Step)
+	}
+	rpcHeadersByRangeResponseLatency.Observe(float64(time.Since(start).Milliseconds()))
+
+	closeStream(stream)
+	return nil
+}
+
+func (s *Service) validateHeadersRangeRequest(r *sync_pb.HeadersByRangeRequest) error {
+	if r.StartBlockNumber == nil {
+		return p2ptypes.ErrInvalidRequest
+	}
+	if r.Count == 0 || r.Count > MaxRequestHeaders {
+		return p2ptypes.ErrInvalidRequest
+	}
+	if r.Step == 0 || r.Step > rangeLimit {
+		return p2ptypes.ErrInvalidRequest
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/n42blockchain/N42/api/protocol/sync_pb"
+	"github.com/n42blockchain/N42/common/block"
+	"github.com/n42blockchain/N42/internal/p2p"
+	"github.com/n42blockchain/N42/internal/p2p/encoder"
+	"github.com/n42blockchain/N42/params"
+	"github.com/n42blockchain/N42/utils"
+)
+
+// headerTestChain is a chain of which all headers are known.
+type headerTestChain struct {
+	*testChain
+	headers []block.IHeader
+}
+
+func newHeaderTestChain(head uint64) *headerTestChain {
+	chain := &headerTestChain{testChain: newTestChain(&params.ChainConfig{}, head)}
+	chain.headers = append(chain.headers, chain.genesis.Header())
+	for i := uint64(1); i <= head; i++ {
+		chain.headers = append(chain.headers, &block.Header{
+			ParentHash: chain.headers[i-1].Hash(),
+			Number:     uint256.NewInt(i),
+			Difficulty: uint256.NewInt(1),
+			BaseFee:    uint256.NewInt(0),
+			Time:       i,
+			Extra:      []byte{},
+		})
+	}
+	return chain
+}
+
+func (c *headerTestChain) GetHeaderByNumber(number *uint256.Int) block.IHeader {
+	if !number.IsUint64() || number.Uint64() >= uint64(len(c.headers)) {
+		return nil
+	}
+	return c.headers[number.Uint64()]
+}
+
+// newHeadersTestPeers connects a requesting peer to a peer serving headers with handler.
+func newHeadersTestPeers(t *testing.T, handler func(stream network.Stream, req *sync_pb.HeadersByRangeRequest)) (client, server *testP2P) {
+	mn, err := mocknet.FullMeshConnected(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = mn.Close() })
+	client, server = newTestP2P(t, mn.Hosts()[0]), newTestP2P(t, mn.Hosts()[1])
+
+	server.host.SetStreamHandler(protocol.ID(p2p.RPCHeadersDataTopicV1+server.Encoding().ProtocolSuffix()), func(stream network.Stream) {
+		defer func() { _ = stream.Reset() }()
+		req := new(sync_pb.HeadersByRangeRequest)
+		if err := server.Encoding().DecodeWithMaxLength(stream, req); err != nil {
+			return
+		}
+		handler(stream, req)
+	})
+	return client, server
+}
+
+func headersRequest(start, count, step uint64) *sync_pb.HeadersByRangeRequest {
+	return &sync_pb.HeadersByRangeRequest{
+		StartBlockNumber: utils.ConvertUint256IntToH256(uint256.NewInt(start)),
+		Count:            count,
+		Step:             step,
+	}
+}
+
+func TestHeadersByRangeRPCHandler(t *testing.T) {
+	chain := newHeaderTestChain(20)
+	var s *Service
+	served := make(chan struct{}, 1)
+	client, server := newHeadersTestPeers(t, func(stream network.Stream, req *sync_pb.HeadersByRangeRequest) {
+		_ = s.headersByRangeRPCHandler(context.Background(), req, stream)
+		served <- struct{}{}
+	})
+	s = &Service{cfg: &config{chain: chain, p2p: server}, rateLimiter: newRateLimiter(server)}
+
+	tests := []struct {
+		name    string
+		req     *sync_pb.HeadersByRangeRequest
+		numbers []uint64
+		ok      bool
+	}{
+		{"range", headersRequest(1, 5, 1), []uint64{1, 2, 3, 4, 5}, true},
+		{"step", headersRequest(1, 4, 3), []uint64{1, 4, 7, 10}, true},
+		{"beyond head", headersRequest(18, 5, 1), []uint64{18, 19, 20}, true},
+		{"after head", headersRequest(21, 5, 1), nil, true},
+		{"no count", headersRequest(1, 0, 1), nil, false},
+		{"too many", headersRequest(1, MaxRequestHeaders+1, 1), nil, false},
+		{"no step", headersRequest(1, 5, 0), nil, false},
+	}
+	for _, tt := range tests {
+		pbHeaders, err := SendHeadersByRangeRequest(context.Background(), client, server.PeerID(), tt.req)
+		<-served
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: error %v, want ok %v", tt.name, err, tt.ok)
+			continue
+		}
+		if len(pbHeaders) != len(tt.numbers) {
+			t.Errorf("%s: got %d headers, want %d", tt.name, len(pbHeaders), len(tt.numbers))
+			continue
+		}
+		for i, pbHeader := range pbHeaders {
+			header := new(block.Header)
+			if err := header.FromProtoMessage(pbHeader); err != nil {
+				t.Fatal(err)
+			}
+			if want := chain.headers[tt.numbers[i]]; header.Hash() != want.Hash() {
+				t.Errorf("%s: header %d is %d %#x, want %d %#x", tt.name, i, header.Number.Uint64(), header.Hash(), tt.numbers[i], want.Hash())
+			}
+		}
+	}
+	// Every invalid request counts against the requester.
+	if count, _ := server.Peers().Scorers().BadResponsesScorer().Count(client.PeerID()); count != 3 {
+		t.Errorf("requester has %d bad responses, want 3", count)
+	}
+}
+
+func TestSendHeadersByRangeRequestInvalid(t *testing.T) {
+	chain := newHeaderTestChain(20)
+	tests := []struct {
+		name    string
+		numbers []uint64
+	}{
+		{"gap", []uint64{1, 2, 4}},
+		{"too many", []uint64{1, 2, 3, 4}},
+		{"wrong start", []uint64{2, 3, 4}},
+	}
+	for _, tt := range tests {
+		client, server := newHeadersTestPeers(t, func(stream network.Stream, _ *sync_pb.HeadersByRangeRequest) {
+			for _, number := range tt.numbers {
+				if err := WriteHeaderChunk(stream, chain, encoder.SszNetworkEncoder{}, chain.headers[number]); err != nil {
+					return
+				}
+			}
+			closeStream(stream)
+		})
+		if _, err := SendHeadersByRangeRequest(context.Background(), client, server.PeerID(), headersRequest(1, 3, 1)); !errors.Is(err, ErrInvalidFetchedData) {
+			t.Errorf("%s: error %v, want %v", tt.name, err, ErrInvalidFetchedData)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package sync
+
+import (
+	"context"
+	"io"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	libp2pcore "github.com/libp2p/go-libp2p/core"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/n42blockchain/N42/api/protocol/types_pb"
+	"github.com/n42blockchain/N42/common"
+	"github.com/n42blockchain/N42/common/transaction"
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/internal/p2p"
+	p2ptypes "github.com/n42blockchain/N42/internal/p2p/types"
+	"github.com/n42blockchain/N42/internal/txspool"
+	"github.com/n42blockchain/N42/log"
+	event "github.com/n42blockchain/N42/modules/event/v2"
+	"github.com/pkg/errors"
+)
+
+// maxKnownTxs is the maximum number of transaction hashes remembered per peer,
+// used to avoid announcing a transaction back to a peer that already has it.
+const maxKnownTxs = 32768
+
+// txHashesRPCHandler receives transaction announcements and hands them to the fetcher.
+func (s *Service) txHashesRPCHandler(_ context.Context, msg interface{}, stream libp2pcore.Stream) error {
+	SetRPCStreamDeadlines(stream)
+	m, ok := msg.(*p2ptypes.TxHashes)
+	if !ok {
+		return errors.New("message is not type *p2ptypes.TxHashes")
+	}
+	if err := s.rateLimiter.validateRequest(stream, 1); err != nil {
+		return err
+	}
+	s.rateLimiter.add(stream, 1)
+
+	pid := stream.Conn().RemotePeer()
+	hashes := make([]types.Hash, len(*m))
+	for i, h := range *m {
+		hashes[i] = h
+	}
+	s.markKnownTxs(pid, hashes...)
+
+	if err := s.txsFetcher.Notify(pid, hashes); err != nil {
+		s.writeErrorResponseToStream(responseCodeInvalidRequest, err.Error(), stream)
+		s.cfg.p2p.Peers().Scorers().BadResponsesScorer().Increment(pid)
+		return err
+	}
+	if _, err := stream.Write([]byte{responseCodeSuccess}); err != nil {
+		return err
+	}
+	closeStream(stream)
+	return nil
+}
+
+// pooledTxsRPCHandler serves the requested transactions from the local pool. Unknown
+// hashes are skipped and the response is cut once txspool.MaxTxPacketSize is reached.
+func (s *Service) pooledTxsRPCHandler(_ context.Context, msg interface{}, stream libp2pcore.Stream) error {
+	SetRPCStreamDeadlines(stream)
+	m, ok := msg.(*p2ptypes.TxHashes)
+	if !ok {
+		return errors.New("message is not type *p2ptypes.TxHashes")
+	}
+	if err := s.rateLimiter.validateRequest(stream, 1); err != nil {
+		return err
+	}
+	s.rateLimiter.add(stream, 1)
+
+	var sent uint64
+	for _, hash := range *m {
+		tx := s.cfg.txsPool.GetTx(hash)
+		if tx == nil {
+			continue
+		}
+		protoMsg, ok := tx.ToProtoMessage().(*types_pb.Transaction)
+		if !ok {
+			continue
+		}
+		size := uint64(protoMsg.SizeSSZ())
+		if sent > 0 && sent+size > txspool.MaxTxPacketSize {
+			break
+		}
+		SetStreamWriteDeadline(stream, defaultWriteDuration)
+		if _, err := stream.Write([]byte{responseCodeSuccess}); err != nil {
+			return err
+		}
+		if _, err := s.cfg.p2p.Encoding().EncodeWithMaxLength(stream, protoMsg); err != nil {
+			return err
+		}
+		sent += size
+	}
+	s.cfg.p2p.Peers().Scorers().TxProviderScorer().TxsServed(stream.Conn().RemotePeer(), sent)
+	closeStream(stream)
+	return nil
+}
+
+// sendPooledTxsRequest requests the given transactions from a peer and enqueues the
+// response into the fetcher.
+// The whole exchange is bounded by txspool.TxFetchTimeout, after which the fetcher
+// has given up on the request and asked another peer.
+func (s *Service) sendPooledTxsRequest(pid peer.ID, hashes []types.Hash) error {
+	deadline := time.Now().Add(txspool.TxFetchTimeout)
+	ctx, cancel := context.WithDeadline(s.ctx, deadline)
+	defer cancel()
+
+	topic, err := p2p.TopicFromMessage(p2p.PooledTxsMessageName)
+	if err != nil {
+		return err
+	}
+	req := make(p2ptypes.TxHashes, len(hashes))
+	requested := make(map[types.Hash]struct{}, len(hashes))
+	for i, h := range hashes {
+		req[i] = h
+		requested[h] = struct{}{}
+	}
+	stream, err := s.cfg.p2p.Send(ctx, &req, topic, pid)
+	if err != nil {
+		return err
+	}
+	defer closeStream(stream)
+	SetStreamReadDeadline(stream, time.Until(deadline))
+
+	var (
+		txs   = make([]*transaction.Transaction, 0, len(hashes))
+		bytes uint64
+	)
+	for {
+		code, errMsg, err := readStatusCodeNoDeadline(stream, s.cfg.p2p.Encoding())
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if code != 0 {
+			s.cfg.p2p.Peers().Scorers().BadResponsesScorer().Increment(pid)
+			return errors.New(errMsg)
+		}
+		protoMsg := new(types_pb.Transaction)
+		if err := s.cfg.p2p.Encoding().DecodeWithMaxLength(stream, protoMsg); err != nil {
+			return err
+		}
+		tx, err := transaction.FromProtoMessage(protoMsg)
+		if err != nil {
+			s.cfg.p2p.Peers().Scorers().BadResponsesScorer().Increment(pid)
+			return err
+		}
+		if _, ok := requested[tx.Hash()]; !ok {
+			s.cfg.p2p.Peers().Scorers().BadResponsesScorer().Increment(pid)
+			return txspool.ErrUnrequestedTxs
+		}
+		txs = append(txs, tx)
+		bytes += uint64(protoMsg.SizeSSZ())
+	}
+	for _, tx := range txs {
+		s.markKnownTxs(pid, tx.Hash())
+	}
+	return s.txsFetcher.Enqueue(pid, txs, bytes, true)
+}
+
+// sendTxHashes announces a batch of transaction hashes to a peer.
+func (s *Service) sendTxHashes(pid peer.ID, hashes []types.Hash) error {
+	ctx, cancel := context.WithTimeout(s.ctx, respTimeout)
+	defer cancel()
+
+	topic, err := p2p.TopicFromMessage(p2p.TxHashesMessageName)
+	if err != nil {
+		return err
+	}
+	req := make(p2ptypes.TxHashes, len(hashes))
+	for i, h := range hashes {
+		req[i] = h
+	}
+	stream, err := s.cfg.p2p.Send(ctx, &req, topic, pid)
+	if err != nil {
+		return err
+	}
+	defer closeStream(stream)
+
+	code, errMsg, err := ReadStatusCode(stream, s.cfg.p2p.Encoding())
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return errors.New(errMsg)
+	}
+	s.cfg.p2p.Peers().Scorers().TxProviderScorer().TxsServed(pid, uint64(len(hashes)*types.HashLength))
+	return nil
+}
+
+// announceTxsLoop announces every transaction entering the pool to all connected
+// peers that are not yet known to have it.
+func (s *Service) announceTxsLoop() {
+	ch := make(chan common.NewTxsEvent, 10)
+	sub := event.GlobalEvent.Subscribe(ch)
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case ev := <-ch:
+			hashes := make([]types.Hash, len(ev.Txs))
+			for i, tx := range ev.Txs {
+				hashes[i] = tx.Hash()
+			}
+			for _, pid := range s.cfg.p2p.Peers().Connected() {
+				unknown := s.unknownTxs(pid, hashes)
+				for len(unknown) > 0 {
+					batch := unknown
+					if len(batch) > p2ptypes.MaxTxHashes {
+						batch = batch[:p2ptypes.MaxTxHashes]
+					}
+					unknown = unknown[len(batch):]
+					go func(pid peer.ID, batch []types.Hash) {
+						if err := s.sendTxHashes(pid, batch); err != nil {
+							log.Trace("Failed to announce transactions", "peer", pid, "count", len(batch), "err", err)
+						}
+					}(pid, batch)
+				}
+			}
+		case <-sub.Err():
+			return
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// unknownTxs filters the hashes the peer is not known to have and marks them as known.
+func (s *Service) unknownTxs(pid peer.ID, hashes []types.Hash) []types.Hash {
+	known := s.knownTxsCache(pid)
+	unknown := make([]types.Hash, 0, len(hashes))
+	for _, h := range hashes {
+		if ok, _ := known.ContainsOrAdd(h, struct{}{}); !ok {
+			unknown = append(unknown, h)
+		}
+	}
+	return unknown
+}
+
+// markKnownTxs records that the peer has the given transactions.
+func (s *Service) markKnownTxs(pid peer.ID, hashes ...types.Hash) {
+	known := s.knownTxsCache(pid)
+	for _, h := range hashes {
+		known.Add(h, struct{}{})
+	}
+}
+
+func (s *Service) knownTxsCache(pid peer.ID) *lru.Cache[types.Hash, struct{}] {
+	s.knownTxsLock.Lock()
+	defer s.knownTxsLock.Unlock()
+	known, ok := s.knownTxs[pid]
+	if !ok {
+		known, _ = lru.New[types.Hash, struct{}](maxKnownTxs)
+		s.knownTxs[pid] = known
+	}
+	return known
+}
+
+// dropTxsPeer releases the propagation state of a disconnected peer.
+func (s *Service) dropTxsPeer(pid peer.ID) {
+	s.knownTxsLock.Lock()
+	delete(s.knownTxs, pid)
+	s.knownTxsLock.Unlock()
+	s.txsFetcher.Drop(pid)
+}
+
+// startTxsPropagation boots the transaction fetcher and the announcement loop.
+func (s *Service) startTxsPropagation() {
+	if s.txsFetcher == nil {
+		return
+	}
+	if err := s.txsFetcher.Start(); err != nil {
+		log.Error("Failed to start txs fetcher", "err", err)
+		return
+	}
+	go s.announceTxsLoop()
+}
@@ -88,3 +88,43 @@ func SendBodiesByRangeRequest(ctx context.Context, chain common.IBlockChain, p2p
 
 	return blocks, nil
 }
+
+// SendHeadersByRangeRequest sends HeadersByRange and returns fetched headers, if any.
+func SendHeadersByRangeRequest(ctx context.Context, p2pProvider p2p.SenderEncoder, pid peer.ID, req *sync_pb.HeadersByRangeRequest) ([]*types_pb.Header, error) {
+	topic, err := p2p.TopicFromMessage(p2p.HeadersByRangeMessageName)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := p2pProvider.Send(ctx, req, topic, pid)
+	if err != nil {
+		return nil, err
+	}
+	defer closeStream(stream)
+
+	headers := make([]*types_pb.Header, 0, req.Count)
+	start := utils.ConvertH256ToUint256Int(req.StartBlockNumber)
+	for i := uint64(0); ; i++ {
+		header, err := ReadChunkedHeader(stream, p2pProvider, i == 0)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		// The response MUST contain no more than `count` headers.
+		if i >= req.Count || i >= MaxRequestHeaders {
+			return nil, ErrInvalidFetchedData
+		}
+		if header.Number == nil {
+			return nil, ErrInvalidFetchedData
+		}
+		// Returned headers MUST be exactly start + i * step, gaps are not allowed.
+		nr := utils.ConvertH256ToUint256Int(header.Number)
+		if nr.Cmp(new(uint256.Int).AddUint64(start, i*req.Step)) != 0 {
+			return nil, ErrInvalidFetchedData
+		}
+		headers = append(headers, header)
+	}
+
+	return headers, nil
+}
@@ -1,8 +1,8 @@
 package sync
 
 import (
-	"bytes"
 	"context"
+	"fmt"
 	"github.com/holiman/uint256"
 	"github.com/n42blockchain/N42/api/protocol/sync_pb"
 	"github.com/n42blockchain/N42/internal/p2p"
@@ -17,6 +17,7 @@ import (
 	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/pkg/errors"
+	ssz "github.com/prysmaticlabs/fastssz"
 )
 
 // maintainPeerStatuses by infrequently polling peers for their latest status.
@@ -95,21 +96,17 @@ func (s *Service) resyncIfBehind() {
 }
 
 // sendRPCStatusRequest for a given topic with an expected protobuf message type.
+// The v2 status carrying the fork ID is sent, or the v1 one to the peers not
+// speaking v2 until the chain reaches the fork ID network upgrade.
 func (s *Service) sendRPCStatusRequest(ctx context.Context, id peer.ID) error {
 	ctx, cancel := context.WithTimeout(ctx, respTimeout)
 	defer cancel()
 
-	//forkDigest, err := s.currentForkDigest()
-
-	resp := &sync_pb.Status{
-		GenesisHash:   utils.ConvertHashToH256(s.cfg.chain.GenesisBlock().Hash()),
-		CurrentHeight: utils.ConvertUint256IntToH256(s.cfg.chain.CurrentBlock().Number64()),
+	stream, err := s.cfg.p2p.Send(ctx, s.forkStatus(), p2p.RPCStatusTopicV2, id)
+	if err != nil && s.acceptsLegacyStatus() {
+		log.Trace("Could not send v2 status, falling back to v1", "peer", id, "err", err)
+		stream, err = s.cfg.p2p.Send(ctx, s.status(), p2p.RPCStatusTopicV1, id)
 	}
-	topic, err := p2p.TopicFromMessage(p2p.StatusMessageName)
-	if err != nil {
-		return err
-	}
-	stream, err := s.cfg.p2p.Send(ctx, resp, topic, id)
 	if err != nil {
 		return err
 	}
@@ -125,14 +122,21 @@ func (s *Service) sendRPCStatusRequest(ctx context.Context, id peer.ID) error {
 		s.cfg.p2p.Peers().Scorers().BadResponsesScorer().Increment(id)
 		return errors.New(errMsg)
 	}
-	msg := &sync_pb.Status{}
-	if err := s.cfg.p2p.Encoding().DecodeWithMaxLength(stream, msg); err != nil {
+	var resp ssz.Unmarshaler = &sync_pb.Status{}
+	if isStatusV2(stream) {
+		resp = &p2ptypes.ForkStatus{}
+	}
+	if err := s.cfg.p2p.Encoding().DecodeWithMaxLength(stream, resp); err != nil {
 		s.cfg.p2p.Peers().Scorers().BadResponsesScorer().Increment(stream.Conn().RemotePeer())
 		return err
 	}
+	msg, forkID, err := splitStatus(resp)
+	if err != nil {
+		return err
+	}
 
 	// If validation fails, validation error is logged, and peer status scorer will mark peer as bad.
-	err = s.validateStatusMessage(ctx, msg)
+	err = s.validateStatusMessage(ctx, msg, forkID)
 	s.cfg.p2p.Peers().Scorers().PeerStatusScorer().SetPeerStatus(id, msg, err)
 	if s.cfg.p2p.Peers().IsBad(id) {
 		s.disconnectBadPeer(s.ctx, id)
@@ -158,9 +162,9 @@ func (s *Service) statusRPCHandler(ctx context.Context, msg interface{}, stream
 	ctx, cancel := context.WithTimeout(ctx, ttfbTimeout)
 	defer cancel()
 	SetRPCStreamDeadlines(stream)
-	m, ok := msg.(*sync_pb.Status)
-	if !ok {
-		return errors.New("message is not type *pb.Status")
+	m, forkID, err := splitStatus(msg)
+	if err != nil {
+		return err
 	}
 	if err := s.rateLimiter.validateRequest(stream, 1); err != nil {
 		return err
@@ -168,7 +172,7 @@ func (s *Service) statusRPCHandler(ctx context.Context, msg interface{}, stream
 	s.rateLimiter.add(stream, 1)
 
 	remotePeer := stream.Conn().RemotePeer()
-	if err := s.validateStatusMessage(ctx, m); err != nil {
+	if err := s.validateStatusMessage(ctx, m, forkID); err != nil {
 		log.Debug("Invalid status message from peer", "handler", "status", "peer", remotePeer, "error", err)
 
 		respCode := byte(0)
@@ -215,15 +219,12 @@ func (s *Service) statusRPCHandler(ctx context.Context, msg interface{}, stream
 	return nil
 }
 
+// respondWithStatus writes the local status, in the version of the protocol of
+// stream.
 func (s *Service) respondWithStatus(ctx context.Context, stream network.Stream) error {
-
-	//forkDigest, err := s.currentForkDigest()
-	//if err != nil {
-	//	return err
-	//}
-	resp := &sync_pb.Status{
-		GenesisHash:   utils.ConvertHashToH256(s.cfg.chain.GenesisBlock().Hash()),
-		CurrentHeight: utils.ConvertUint256IntToH256(s.cfg.chain.CurrentBlock().Number64()),
+	var resp ssz.Marshaler = s.status()
+	if isStatusV2(stream) {
+		resp = s.forkStatus()
 	}
 
 	if _, err := stream.Write([]byte{responseCodeSuccess}); err != nil {
@@ -233,21 +234,63 @@ func (s *Service) respondWithStatus(ctx context.Context, stream network.Stream)
 	return err
 }
 
-func (s *Service) validateStatusMessage(ctx context.Context, msg *sync_pb.Status) error {
-	forkDigest, err := s.currentForkDigest()
-	if err != nil {
-		return err
+// status returns the status of the local chain: its genesis and head.
+func (s *Service) status() *sync_pb.Status {
+	return &sync_pb.Status{
+		GenesisHash:   utils.ConvertHashToH256(s.cfg.chain.GenesisBlock().Hash()),
+		CurrentHeight: utils.ConvertUint256IntToH256(s.cfg.chain.CurrentBlock().Number64()),
 	}
-	remoteDigest, err := utils.CreateForkDigest(
-		utils.ConvertH256ToUint256Int(msg.CurrentHeight),
-		utils.ConvertH256ToHash(msg.GenesisHash),
-	)
-	if err != nil {
-		return err
+}
+
+// forkStatus returns the v2 status of the local chain: its status along with
+// its fork ID at the head.
+func (s *Service) forkStatus() *p2ptypes.ForkStatus {
+	status := s.status()
+	forkID := utils.NewForkID(s.cfg.chain.Config(), s.cfg.chain.GenesisBlock().Hash(), utils.ConvertH256ToUint256Int(status.CurrentHeight).Uint64())
+	return &p2ptypes.ForkStatus{Status: status, ForkHash: forkID.Hash, ForkNext: forkID.Next}
+}
+
+// splitStatus returns the status of a v1 or v2 status message, and the fork ID
+// of the latter.
+func splitStatus(msg interface{}) (*sync_pb.Status, *utils.ForkID, error) {
+	switch m := msg.(type) {
+	case *sync_pb.Status:
+		return m, nil, nil
+	case *p2ptypes.ForkStatus:
+		return m.Status, &utils.ForkID{Hash: m.ForkHash, Next: m.ForkNext}, nil
+	default:
+		return nil, nil, errors.Errorf("message is not a status: %T", msg)
+	}
+}
+
+// isStatusV2 reports whether stream speaks the v2 status protocol.
+func isStatusV2(stream withProtocol) bool {
+	return validateVersion(p2p.SchemaVersionV2, stream) == nil
+}
+
+// acceptsLegacyStatus reports whether the peers without a fork ID, which only
+// speak the v1 status protocol, are still accepted: until the head of the chain
+// reaches the fork ID network upgrade.
+func (s *Service) acceptsLegacyStatus() bool {
+	return !s.cfg.chain.Config().IsForkID(s.cfg.chain.CurrentBlock().Number64().Uint64())
+}
+
+// validateStatusMessage checks that the peer is on the same chain, and that its
+// fork ID, if any, is compatible with ours following the rules of EIP-2124.
+func (s *Service) validateStatusMessage(ctx context.Context, msg *sync_pb.Status, forkID *utils.ForkID) error {
+	if msg.GenesisHash == nil || msg.CurrentHeight == nil || utils.ConvertH256ToHash(msg.GenesisHash) != s.cfg.chain.GenesisBlock().Hash() {
+		return p2ptypes.ErrWrongForkDigestVersion
+	}
+	if forkID == nil {
+		if !s.acceptsLegacyStatus() {
+			log.Debug("Status of peer without fork ID past the fork ID network upgrade")
+			return p2ptypes.ErrWrongForkDigestVersion
+		}
+		return nil
 	}
-	if !bytes.Equal(forkDigest[:], remoteDigest[:]) {
+	if err := s.forkFilter(*forkID); err != nil {
+		log.Debug("Incompatible fork ID of peer", "forkHash", fmt.Sprintf("%#x", forkID.Hash), "forkNext", forkID.Next, "err", err)
 		return p2ptypes.ErrWrongForkDigestVersion
 	}
-
 	return nil
 }
@@ -0,0 +1,77 @@
+package sync
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/n42blockchain/N42/api/protocol/sync_pb"
+	"github.com/n42blockchain/N42/common"
+	"github.com/n42blockchain/N42/common/block"
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/params"
+	"github.com/n42blockchain/N42/utils"
+)
+
+// testChain is a chain of which only the genesis and head blocks are known.
+type testChain struct {
+	common.IBlockChain
+	config  *params.ChainConfig
+	genesis block.IBlock
+	head    uint64
+}
+
+func newTestChain(config *params.ChainConfig, head uint64) *testChain {
+	genesis := block.NewBlock(&block.Header{Number: uint256.NewInt(0), Difficulty: uint256.NewInt(1), BaseFee: uint256.NewInt(0)}, nil)
+	return &testChain{config: config, genesis: genesis, head: head}
+}
+
+func (c *testChain) Config() *params.ChainConfig { return c.config }
+func (c *testChain) GenesisBlock() block.IBlock  { return c.genesis }
+func (c *testChain) CurrentBlock() block.IBlock {
+	return block.NewBlock(&block.Header{Number: uint256.NewInt(c.head)}, nil)
+}
+
+func newStatusTestService(chain *testChain) *Service {
+	s := &Service{cfg: &config{chain: chain}}
+	s.forkFilter = utils.NewForkFilter(chain.config, chain.genesis.Hash(), func() uint64 { return chain.head })
+	return s
+}
+
+func TestValidateStatusMessage(t *testing.T) {
+	config := &params.ChainConfig{BeijingBlock: big.NewInt(100), ForkIDBlock: big.NewInt(200)}
+	pre, post := newStatusTestService(newTestChain(config, 150)), newStatusTestService(newTestChain(config, 250))
+	genesis := pre.cfg.chain.GenesisBlock().Hash()
+	status := func(genesis types.Hash) *sync_pb.Status {
+		return &sync_pb.Status{
+			GenesisHash:   utils.ConvertHashToH256(genesis),
+			CurrentHeight: utils.ConvertUint256IntToH256(uint256.NewInt(150)),
+		}
+	}
+	forkID := func(head uint64) *utils.ForkID {
+		id := utils.NewForkID(config, genesis, head)
+		return &id
+	}
+	tests := []struct {
+		name   string
+		local  *Service
+		status *sync_pb.Status
+		forkID *utils.ForkID
+		ok     bool
+	}{
+		{"v1 before the upgrade", pre, status(genesis), nil, true},
+		{"v1 after the upgrade", post, status(genesis), nil, false},
+		{"v1 of another chain", pre, status(types.Hash{1}), nil, false},
+		{"v2 ahead", pre, status(genesis), forkID(250), true},
+		{"v2 behind", post, status(genesis), forkID(150), true},
+		{"v2 of another chain", post, status(types.Hash{1}), forkID(250), false},
+		{"v2 incompatible", post, status(genesis), &utils.ForkID{Hash: [4]byte{1, 2, 3, 4}}, false},
+		{"no genesis", pre, &sync_pb.Status{}, nil, false},
+	}
+	for _, tt := range tests {
+		if err := tt.local.validateStatusMessage(context.Background(), tt.status, tt.forkID); (err == nil) != tt.ok {
+			t.Errorf("%s: error %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
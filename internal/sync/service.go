@@ -11,6 +11,7 @@ import (
 	block2 "github.com/n42blockchain/N42/common/block"
 	"github.com/n42blockchain/N42/common/types"
 	"github.com/n42blockchain/N42/internal/p2p"
+	"github.com/n42blockchain/N42/internal/txspool"
 	"github.com/n42blockchain/N42/utils"
 	"sync"
 	"time"
@@ -54,6 +55,7 @@ type config struct {
 	p2p         p2p.P2P
 	chain       common.IBlockChain
 	initialSync Checker
+	txsPool     common.ITxsPool
 }
 
 // This defines the interface for interacting with block chain service
@@ -67,14 +69,21 @@ type Service struct {
 	ctx    context.Context
 	cancel context.CancelFunc
 
-	subHandler  *subTopicHandler
-	rateLimiter *limiter
+	subHandler      *subTopicHandler
+	rateLimiter     *limiter
+	protocolLimiter *protocolLimiter
 
 	seenBlockCache *lru.Cache[types.Hash, *block2.Block]
 	seenBlockLock  sync.RWMutex
 	badBlockLock   sync.RWMutex
 	badBlockCache  *lru.Cache[types.Hash, bool]
 
+	forkFilter func(utils.ForkID) error
+
+	txsFetcher   *txspool.TxsFetcher
+	knownTxs     map[peer.ID]*lru.Cache[types.Hash, struct{}]
+	knownTxsLock sync.Mutex
+
 	validateBlockLock               sync.RWMutex
 	seenExitLock                    sync.RWMutex
 	seenSyncMessageLock             sync.RWMutex
@@ -97,10 +106,19 @@ func NewService(ctx context.Context, opts ...Option) *Service {
 		}
 	}
 
+	r.forkFilter = utils.NewForkFilter(r.cfg.chain.Config(), r.cfg.chain.GenesisBlock().Hash(), func() uint64 {
+		return r.cfg.chain.CurrentBlock().Number64().Uint64()
+	})
 	r.subHandler = newSubTopicHandler()
 	r.rateLimiter = newRateLimiter(r.cfg.p2p)
+	r.protocolLimiter = newProtocolLimiter(r.cfg.p2p.GetConfig().P2PLimit)
 	r.initCaches()
 
+	if r.cfg.txsPool != nil {
+		r.knownTxs = make(map[peer.ID]*lru.Cache[types.Hash, struct{}])
+		r.txsFetcher = txspool.NewTxsFetcher(ctx, r.cfg.txsPool.Has, r.cfg.txsPool.AddRemotes, r.sendPooledTxsRequest, r.cfg.p2p.Peers().Scorers().TxProviderScorer())
+	}
+
 	r.registerRPCHandlers()
 
 	digest, err := r.currentForkDigest()
@@ -108,7 +126,6 @@ func NewService(ctx context.Context, opts ...Option) *Service {
 		panic("Could not retrieve current fork digest")
 	}
 	r.registerSubscribers(digest)
-	//go r.forkWatcher()
 
 	return r
 }
@@ -117,7 +134,9 @@ func NewService(ctx context.Context, opts ...Option) *Service {
 func (s *Service) Start() {
 	s.cfg.p2p.AddConnectionHandler(s.reValidatePeer, s.sendGoodbye)
 	s.cfg.p2p.AddDisconnectionHandler(func(_ context.Context, p peer.ID) error {
-		// no-op
+		if s.txsFetcher != nil {
+			s.dropTxsPeer(p)
+		}
 		//for no reason disconnect
 		//todo
 		if nextValidTime, err := s.cfg.p2p.Peers().NextValidTime(p); err == nil && time.Now().After(nextValidTime) {
@@ -128,6 +147,8 @@ func (s *Service) Start() {
 	s.cfg.p2p.AddPingMethod(s.sendPingRequest)
 	s.maintainPeerStatuses()
 	s.resyncIfBehind()
+	s.startTxsPropagation()
+	s.forkWatcher()
 
 	// Update sync metrics.
 	utils.RunEvery(s.ctx, syncMetricsInterval, s.updateMetrics)
@@ -139,6 +160,9 @@ func (s *Service) Stop() error {
 		if s.rateLimiter != nil {
 			s.rateLimiter.free()
 		}
+		if s.protocolLimiter != nil {
+			s.protocolLimiter.free()
+		}
 	}()
 	// Removing RPC Stream handlers.
 	for _, p := range s.cfg.p2p.Host().Mux().Protocols() {
@@ -148,6 +172,9 @@ func (s *Service) Stop() error {
 	for _, t := range s.cfg.p2p.PubSub().GetTopics() {
 		s.unSubscribeFromTopic(t)
 	}
+	if s.txsFetcher != nil {
+		_ = s.txsFetcher.Stop()
+	}
 	defer s.cancel()
 	return nil
 }
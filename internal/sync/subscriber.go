@@ -9,6 +9,7 @@ import (
 	"github.com/n42blockchain/N42/internal/p2p"
 	"github.com/n42blockchain/N42/internal/p2p/peers"
 	"github.com/n42blockchain/N42/log"
+	"github.com/n42blockchain/N42/params"
 	"github.com/n42blockchain/N42/utils"
 	"runtime/debug"
 	"strings"
@@ -26,8 +27,9 @@ const pubsubMessageTimeout = 30 * time.Second
 // wrappedVal represents a gossip validator which also returns an error along with the result.
 type wrappedVal func(context.Context, peer.ID, *pubsub.Message) (pubsub.ValidationResult, error)
 
-// subHandler represents handler for a given subscription.
-type subHandler func(context.Context, proto.Message) error
+// subHandler represents handler for a given subscription. It is given the peer
+// the message was received from.
+type subHandler func(context.Context, peer.ID, proto.Message) error
 
 // noopValidator is a no-op that only decodes the message, but does not check its contents.
 func (s *Service) noopValidator(_ context.Context, _ peer.ID, msg *pubsub.Message) (pubsub.ValidationResult, error) {
@@ -48,6 +50,12 @@ func (s *Service) registerSubscribers(digest [4]byte) {
 		s.blockSubscriber,
 		digest,
 	)
+	s.subscribe(
+		p2p.BlockHashTopicFormat,
+		s.validateBlockHashPubSub,
+		s.blockHashSubscriber,
+		digest,
+	)
 	//todo txs?
 	//s.subscribe(
 	//	p2p.TransactionTopicFormat,
@@ -123,7 +131,7 @@ func (s *Service) subscribeWithBase(topic string, validator wrappedVal, handle s
 			return
 		}
 
-		if err := handle(ctx, msg.ValidatorData.(proto.Message)); err != nil {
+		if err := handle(ctx, msg.ReceivedFrom, msg.ValidatorData.(proto.Message)); err != nil {
 			//tracing.AnnotateError(span, err)
 			log.Error("Could not handle p2p pubsub", "err", err, "topic", topic)
 			messageFailedProcessingCounter.WithLabelValues(topic).Inc()
@@ -162,6 +170,7 @@ func (s *Service) subscribeWithBase(topic string, validator wrappedVal, handle s
 // Wrap the pubsub validator with a metric monitoring function. This function increments the
 // appropriate counter if the particular message fails to validate.
 func (s *Service) wrapAndReportValidation(topic string, v wrappedVal) (string, pubsub.ValidatorEx) {
+	name := s.gossipProtocolName(topic)
 	return topic, func(ctx context.Context, pid peer.ID, msg *pubsub.Message) (res pubsub.ValidationResult) {
 		defer s.handlePanic(ctx, msg)
 		res = pubsub.ValidationIgnore // Default: ignore any message that panics.
@@ -172,17 +181,25 @@ func (s *Service) wrapAndReportValidation(topic string, v wrappedVal) (string, p
 			messageFailedValidationCounter.WithLabelValues(topic).Inc()
 			return pubsub.ValidationReject
 		}
+		// Validate message according to the protocol limits, our own messages are not limited.
+		if pid != s.cfg.p2p.PeerID() {
+			if err := s.protocolLimiter.admitMessage(name, pid, int64(len(msg.Data))); err != nil {
+				s.rejectOverLimit(pid, name, err)
+				messageIgnoredValidationCounter.WithLabelValues(topic).Inc()
+				return pubsub.ValidationIgnore
+			}
+		}
 		retDigest, err := p2p.ExtractGossipDigest(topic)
 		if err != nil {
 			log.Error(fmt.Sprintf("Invalid topic format of pubsub topic: %v", err), "topic", topic)
 			return pubsub.ValidationIgnore
 		}
-		currDigest, err := s.currentForkDigest()
+		valid, err := isDigestValid(retDigest, s.cfg.chain.Config(), s.cfg.chain.CurrentBlock().Number64(), s.cfg.chain.GenesisBlock().Hash())
 		if err != nil {
 			log.Error(fmt.Sprintf("Unable to retrieve fork data: %v", err), "topic", topic)
 			return pubsub.ValidationIgnore
 		}
-		if currDigest != retDigest {
+		if !valid {
 			log.Debug(fmt.Sprintf("Received message from outdated fork digest %#x", retDigest), "topic", topic)
 			return pubsub.ValidationIgnore
 		}
@@ -264,23 +281,24 @@ func (_ *Service) addDigestAndIndexToTopic(topic string, digest [4]byte, idx uin
 	return fmt.Sprintf(topic, digest, idx)
 }
 
+// currentForkDigest returns the fork digest of the chain at its head.
 func (s *Service) currentForkDigest() ([4]byte, error) {
-	genRoot := s.cfg.chain.GenesisBlock().Header().Hash()
-	return utils.CreateForkDigest(s.cfg.chain.CurrentBlock().Number64(), genRoot)
+	return utils.ForkDigest(s.cfg.chain.Config(), s.cfg.chain.GenesisBlock().Hash(), s.cfg.chain.CurrentBlock().Number64().Uint64()), nil
 }
 
-// Checks if the provided digest matches up with the current supposed digest.
-func isDigestValid(digest [4]byte, blockNr *uint256.Int, genValRoot types.Hash) (bool, error) {
-	retDigest, err := utils.CreateForkDigest(blockNr, genValRoot)
+// Checks if the provided digest matches up with the digest at the given block
+// or at the next one, whose first block of a fork is published under the new
+// digest before the head moves to it.
+func isDigestValid(digest [4]byte, config *params.ChainConfig, blockNr *uint256.Int, genValRoot types.Hash) (bool, error) {
+	retDigest, err := utils.CreateForkDigest(config, blockNr, genValRoot)
 	if err != nil {
 		return false, err
 	}
-	//isNextEpoch, err := utils.CreateForkDigest(new(uint256.Int).AddUint64(blockNr, 1), genValRoot)
-	//if err != nil {
-	//	return false, err
-	//}
-
-	return retDigest == digest, nil
+	nextDigest, err := utils.CreateForkDigest(config, new(uint256.Int).AddUint64(blockNr, 1), genValRoot)
+	if err != nil {
+		return false, err
+	}
+	return retDigest == digest || nextDigest == digest, nil
 }
 
 func agentString(pid peer.ID, hst host.Host) string {
@@ -0,0 +1,77 @@
+package sync
+
+import (
+	"context"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/n42blockchain/N42/api/protocol/sync_pb"
+	block2 "github.com/n42blockchain/N42/common/block"
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/log"
+	"github.com/n42blockchain/N42/utils"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+)
+
+// blockArriveTimeout is the time given to an announced block to be received in full
+// on the block topic before it is fetched.
+const blockArriveTimeout = 500 * time.Millisecond
+
+// blockHashSubscriber fetches the announced block, and the blocks between the head of
+// the chain and it, from the peer the announcement was received from, unless the
+// block is received in full in the meantime.
+func (s *Service) blockHashSubscriber(ctx context.Context, pid peer.ID, msg proto.Message) error {
+	announce, ok := msg.(*sync_pb.NewBlockHash)
+	if !ok {
+		return errWrongMessage
+	}
+	hash := types.Hash(utils.ConvertH256ToHash(announce.Hash))
+	number := utils.ConvertH256ToUint256Int(announce.Number).Uint64()
+
+	select {
+	case <-time.After(blockArriveTimeout):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if s.cfg.chain.HasBlock(hash, number) || s.hasBadBlock(hash) {
+		return nil
+	}
+
+	start := number
+	if head := s.cfg.chain.CurrentBlock().Number64().Uint64(); number > head+1 {
+		start = head + 1
+	}
+	log.Debug("Fetching announced block", "hash", hash, "number", number, "from", start, "peer", pid)
+	pbBlocks, err := SendBodiesByRangeRequest(ctx, s.cfg.chain, s.cfg.p2p, pid, &sync_pb.BodiesByRangeRequest{
+		StartBlockNumber: utils.ConvertUint256IntToH256(uint256.NewInt(start)),
+		Count:            number - start + 1,
+		Step:             1,
+	}, nil)
+	if err != nil {
+		return errors.Wrap(err, "could not fetch announced block")
+	}
+	blocks := make([]block2.IBlock, 0, len(pbBlocks))
+	for _, pbBlock := range pbBlocks {
+		iBlock := new(block2.Block)
+		if err := iBlock.FromProtoMessage(pbBlock); err != nil {
+			return err
+		}
+		blocks = append(blocks, iBlock)
+	}
+	// The peer relayed the announcement without having the block yet, or is on another branch.
+	if len(blocks) == 0 || blocks[len(blocks)-1].Hash() != hash {
+		return errors.Errorf("peer %s did not serve announced block %#x", pid, hash)
+	}
+	announcedBlockFetchedCounter.Inc()
+
+	if n, err := s.cfg.chain.InsertChain(blocks); err != nil {
+		if n < len(blocks) {
+			hash = blocks[n].Hash()
+		}
+		s.reportImportFailure(ctx, pid, hash, err)
+		return err
+	}
+	return nil
+}
@@ -2,12 +2,20 @@ package sync
 
 import (
 	"context"
+	"errors"
+
+	"github.com/libp2p/go-libp2p/core/peer"
 	block2 "github.com/n42blockchain/N42/common/block"
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/internal/consensus"
 	"github.com/n42blockchain/N42/log"
 	"google.golang.org/protobuf/proto"
 )
 
-func (s *Service) blockSubscriber(ctx context.Context, msg proto.Message) error {
+// blockSubscriber executes the blocks relayed by validateBlockPubSub. A block failing
+// its validation or execution is marked as bad, and the peer it was received from is
+// penalised.
+func (s *Service) blockSubscriber(ctx context.Context, pid peer.ID, msg proto.Message) error {
 
 	iBlock := new(block2.Block)
 	if err := iBlock.FromProtoMessage(msg); err != nil {
@@ -24,10 +32,22 @@ func (s *Service) blockSubscriber(ctx context.Context, msg proto.Message) error
 			return err
 		}
 	} else if _, err := s.cfg.chain.InsertChain(blocks); err != nil {
-		// todo bad block
-		//if errors.Is(err, Badblock) {
-		s.setBadBlock(ctx, iBlock.Hash())
+		s.reportImportFailure(ctx, pid, iBlock.Hash(), err)
 		return err
 	}
 	return nil
 }
+
+// reportImportFailure marks the block as bad and penalises the peer it was received
+// from when the import of the block failed on its validation or execution. Blocks that
+// cannot be imported yet, or imports that were interrupted, are not held against the peer.
+func (s *Service) reportImportFailure(ctx context.Context, pid peer.ID, hash types.Hash, err error) {
+	if !errors.Is(err, consensus.ErrInvalidBlock) {
+		return
+	}
+	if ctx.Err() == nil {
+		s.cfg.p2p.Peers().Scorers().BadResponsesScorer().Increment(pid)
+		blockExecutionFailedCounter.Inc()
+	}
+	s.setBadBlock(ctx, hash)
+}
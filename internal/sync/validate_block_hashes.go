@@ -0,0 +1,62 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/n42blockchain/N42/api/protocol/sync_pb"
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/utils"
+	"go.opencensus.io/trace"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/pkg/errors"
+)
+
+// maxAnnounceDistance is the number of blocks ahead of the head of the chain up to
+// which announced blocks are fetched. Those further ahead are left to the initial sync.
+const maxAnnounceDistance = 16
+
+// validateBlockHashPubSub checks the incoming block announcement. Announcements of
+// bad blocks are rejected and those too far ahead of the head of the chain are
+// ignored. Announcements of blocks not received yet are relayed, so that they reach
+// the peers the full block did not.
+func (s *Service) validateBlockHashPubSub(ctx context.Context, pid peer.ID, msg *pubsub.Message) (pubsub.ValidationResult, error) {
+	// Validation runs on publish (not just subscriptions), so we should approve any message from
+	// ourselves.
+	if pid == s.cfg.p2p.PeerID() {
+		return pubsub.ValidationAccept, nil
+	}
+
+	if s.cfg.initialSync.Syncing() {
+		return pubsub.ValidationIgnore, nil
+	}
+
+	_, span := trace.StartSpan(ctx, "sync.validateBlockHashPubSub")
+	defer span.End()
+
+	m, err := s.decodePubsubMessage(msg)
+	if err != nil {
+		return pubsub.ValidationReject, errors.Wrap(err, "Could not decode message")
+	}
+	announce, ok := m.(*sync_pb.NewBlockHash)
+	if !ok {
+		return pubsub.ValidationReject, errWrongMessage
+	}
+	if announce.Hash == nil || announce.Number == nil {
+		return pubsub.ValidationReject, errNilMessage
+	}
+
+	hash := types.Hash(utils.ConvertH256ToHash(announce.Hash))
+	if s.hasBadBlock(hash) {
+		return pubsub.ValidationReject, fmt.Errorf("received announcement of bad block %#x", hash)
+	}
+	number := utils.ConvertH256ToUint256Int(announce.Number).Uint64()
+	if head := s.cfg.chain.CurrentBlock().Number64().Uint64(); number > head+maxAnnounceDistance {
+		return pubsub.ValidationIgnore, nil
+	}
+
+	msg.ValidatorData = announce // Used in downstream subscriber
+	return pubsub.ValidationAccept, nil
+}
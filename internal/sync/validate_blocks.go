@@ -19,9 +19,10 @@ var (
 	ErrOptimisticParent = errors.New("parent of the block is optimistic")
 )
 
-// validateBlockPubSub checks that the incoming block has a valid BLS signature.
-// Blocks that have already been seen are ignored. If the BLS signature is any valid signature,
-// this method rebroadcasts the message.
+// validateBlockPubSub checks the seal and the aggregate signature of the verifiers of
+// the incoming block, without executing it. Blocks that have already been seen are ignored.
+// If the seal and the signature are valid, this method rebroadcasts the message: the block
+// is executed afterwards by the subscriber, and the peer is penalised if that fails.
 func (s *Service) validateBlockPubSub(ctx context.Context, pid peer.ID, msg *pubsub.Message) (pubsub.ValidationResult, error) {
 	receivedTime := time.Now()
 	// Validation runs on publish (not just subscriptions), so we should approve any message from
@@ -71,14 +72,19 @@ func (s *Service) validateBlockPubSub(ctx context.Context, pid peer.ID, msg *pub
 	// Broadcast the block on a feed to notify other services in the beacon node
 	// of a received block (even if it does not process correctly through a state transition).
 
-	if s.cfg.chain.HasBlock(header.Root, header.Number.Uint64()) {
+	hash := iBlock.Hash()
+	if s.cfg.chain.HasBlock(hash, header.Number.Uint64()) {
 		return pubsub.ValidationIgnore, nil
 	}
 
+	if s.hasBadBlock(hash) {
+		return pubsub.ValidationReject, fmt.Errorf("received bad block %#x", hash)
+	}
+
 	// Check if parent is a bad block and then reject the block.
 	if s.hasBadBlock(header.ParentHash) {
-		s.setBadBlock(ctx, header.Root)
-		err := fmt.Errorf("received block with root %#x that has an invalid parent %#x", header.Root, header.ParentHash)
+		s.setBadBlock(ctx, hash)
+		err := fmt.Errorf("received block %#x that has an invalid parent %#x", hash, header.ParentHash)
 		log.Debug("Received block with an invalid parent", "err", err)
 		return pubsub.ValidationReject, err
 	}
@@ -98,14 +104,24 @@ func (s *Service) validateBlockPubSub(ctx context.Context, pid peer.ID, msg *pub
 		return pubsub.ValidationIgnore, nil
 	}
 
-	// Handle block when the parent is unknown.
+	// Handle block when the parent is unknown. Its seal cannot be verified without the
+	// parent, the block is queued until the parent is imported and is not relayed.
 	if !s.cfg.chain.HasBlock(header.ParentHash, header.Number.Uint64()-1) {
-		// todo feature?
+		if err := s.cfg.chain.AddFutureBlock(iBlock); err != nil {
+			return pubsub.ValidationIgnore, err
+		}
+		log.Debug("Queued block with unknown parent", "hash", hash, "number", header.Number.Uint64())
+		return pubsub.ValidationIgnore, nil
+	}
+
+	if err := s.cfg.chain.VerifyBlockSeal(iBlock); err != nil {
+		s.setBadBlock(ctx, hash)
+		return pubsub.ValidationReject, errors.Wrap(err, "could not verify block seal")
 	}
 
 	msg.ValidatorData = iBlock.ToProtoMessage() // Used in downstream subscriber
 
-	log.Debug("Received block")
+	log.Debug("Relaying block before execution", "hash", hash, "number", header.Number.Uint64())
 
 	blockVerificationGossipSummary.Observe(float64(time.Since(receivedTime).Milliseconds()))
 	return pubsub.ValidationAccept, nil
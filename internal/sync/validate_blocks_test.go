@@ -0,0 +1,220 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/holiman/uint256"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	coretest "github.com/libp2p/go-libp2p/core/test"
+	"github.com/n42blockchain/N42/api/protocol/types_pb"
+	"github.com/n42blockchain/N42/common"
+	"github.com/n42blockchain/N42/common/block"
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/conf"
+	"github.com/n42blockchain/N42/internal/consensus"
+	"github.com/n42blockchain/N42/internal/p2p"
+	"github.com/n42blockchain/N42/internal/p2p/encoder"
+	"github.com/n42blockchain/N42/internal/p2p/peers"
+	"github.com/n42blockchain/N42/internal/p2p/peers/scorers"
+	ssz "github.com/prysmaticlabs/fastssz"
+	"google.golang.org/protobuf/proto"
+)
+
+// blockTestChain is a chain of which the head and the known blocks are given, and
+// of which the seal verification and the import results are set by the test.
+type blockTestChain struct {
+	common.IBlockChain
+	head      uint64
+	known     map[types.Hash]bool
+	sealErr   error
+	insertErr error
+	future    []block.IBlock
+	inserted  []block.IBlock
+}
+
+func (c *blockTestChain) CurrentBlock() block.IBlock {
+	return block.NewBlock(&block.Header{Number: uint256.NewInt(c.head)}, nil)
+}
+func (c *blockTestChain) HasBlock(hash types.Hash, _ uint64) bool { return c.known[hash] }
+func (c *blockTestChain) VerifyBlockSeal(block.IBlock) error      { return c.sealErr }
+func (c *blockTestChain) AddFutureBlock(b block.IBlock) error {
+	c.future = append(c.future, b)
+	return nil
+}
+func (c *blockTestChain) InsertChain(blocks []block.IBlock) (int, error) {
+	if c.insertErr != nil {
+		return 0, c.insertErr
+	}
+	c.inserted = append(c.inserted, blocks...)
+	return len(blocks), nil
+}
+
+// testP2P provides the encoding and the peer scoring of the p2p service and, when
+// backed by a host, sends requests over it.
+type testP2P struct {
+	p2p.P2P
+	host   host.Host
+	self   peer.ID
+	peers  *peers.Status
+	config *conf.P2PConfig
+}
+
+func newTestP2P(t *testing.T, h host.Host) *testP2P {
+	p := &testP2P{
+		host: h,
+		peers: peers.NewStatus(context.Background(), &peers.StatusConfig{
+			PeerLimit: 10,
+			ScorerParams: &scorers.Config{
+				BadResponsesScorerConfig: &scorers.BadResponsesScorerConfig{Threshold: 5},
+			},
+		}),
+		config: &conf.P2PConfig{P2PLimit: &conf.P2PLimit{BlockBatchLimit: 64, BlockBatchLimitBurstFactor: 2, BlockBatchLimiterPeriod: 5}},
+	}
+	if h != nil {
+		p.self = h.ID()
+	} else {
+		p.self = coretest.RandPeerIDFatal(t)
+	}
+	return p
+}
+
+func (p *testP2P) PeerID() peer.ID                   { return p.self }
+func (p *testP2P) Encoding() encoder.NetworkEncoding { return encoder.SszNetworkEncoder{} }
+func (p *testP2P) Peers() *peers.Status              { return p.peers }
+func (p *testP2P) GetConfig() *conf.P2PConfig        { return p.config }
+
+func (p *testP2P) Send(ctx context.Context, message interface{}, baseTopic string, pid peer.ID) (network.Stream, error) {
+	stream, err := p.host.NewStream(ctx, pid, protocol.ID(baseTopic+p.Encoding().ProtocolSuffix()))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.Encoding().EncodeWithMaxLength(stream, message.(ssz.Marshaler)); err != nil {
+		_ = stream.Reset()
+		return nil, err
+	}
+	return stream, stream.CloseWrite()
+}
+
+type testSyncChecker struct {
+	Checker
+	syncing bool
+}
+
+func (c *testSyncChecker) Syncing() bool { return c.syncing }
+
+func newBlockTestService(t *testing.T, chain *blockTestChain) *Service {
+	badBlocks, _ := lru.New[types.Hash, bool](badBlockSize)
+	return &Service{
+		cfg:           &config{chain: chain, p2p: newTestP2P(t, nil), initialSync: &testSyncChecker{}},
+		badBlockCache: badBlocks,
+	}
+}
+
+// newTestBlock returns a block on top of parent, received in the past.
+func newTestBlock(parent types.Hash, number uint64) *block.Block {
+	return block.NewBlock(&block.Header{
+		ParentHash: parent,
+		Number:     uint256.NewInt(number),
+		Difficulty: uint256.NewInt(1),
+		BaseFee:    uint256.NewInt(0),
+		Time:       1,
+		Extra:      []byte{},
+	}, nil).(*block.Block)
+}
+
+func blockMessage(t *testing.T, b *block.Block) *pubsub.Message {
+	var buf bytes.Buffer
+	if _, err := (encoder.SszNetworkEncoder{}).EncodeGossip(&buf, b.ToProtoMessage().(*types_pb.Block)); err != nil {
+		t.Fatal(err)
+	}
+	topic := fmt.Sprintf(p2p.BlockTopicFormat, [4]byte{1, 2, 3, 4}) + encoder.SszNetworkEncoder{}.ProtocolSuffix()
+	return &pubsub.Message{Message: &pb.Message{Topic: &topic, Data: buf.Bytes()}}
+}
+
+func badResponses(s *Service, pid peer.ID) int {
+	count, err := s.cfg.p2p.Peers().Scorers().BadResponsesScorer().Count(pid)
+	if err != nil {
+		return 0
+	}
+	return count
+}
+
+func TestValidateBlockPubSub(t *testing.T) {
+	parent := types.Hash{1}
+	tests := []struct {
+		name    string
+		known   bool
+		sealErr error
+		result  pubsub.ValidationResult
+		queued  bool
+		bad     bool
+	}{
+		{"valid seal", true, nil, pubsub.ValidationAccept, false, false},
+		{"invalid seal", true, errors.New("invalid seal"), pubsub.ValidationReject, false, true},
+		{"unknown parent", false, nil, pubsub.ValidationIgnore, true, false},
+	}
+	for _, tt := range tests {
+		chain := &blockTestChain{head: 1, known: map[types.Hash]bool{parent: tt.known}, sealErr: tt.sealErr}
+		s := newBlockTestService(t, chain)
+		b := newTestBlock(parent, 2)
+		msg := blockMessage(t, b)
+
+		result, err := s.validateBlockPubSub(context.Background(), coretest.RandPeerIDFatal(t), msg)
+		if result != tt.result {
+			t.Errorf("%s: result %v, want %v: %v", tt.name, result, tt.result, err)
+		}
+		if queued := len(chain.future) == 1 && chain.future[0].Hash() == b.Hash(); queued != tt.queued {
+			t.Errorf("%s: queued %v, want %v", tt.name, queued, tt.queued)
+		}
+		if bad := s.hasBadBlock(b.Hash()); bad != tt.bad {
+			t.Errorf("%s: bad block %v, want %v", tt.name, bad, tt.bad)
+		}
+		if (msg.ValidatorData != nil) != (tt.result == pubsub.ValidationAccept) {
+			t.Errorf("%s: validator data %v", tt.name, msg.ValidatorData)
+		}
+	}
+}
+
+func TestBlockSubscriberPenalty(t *testing.T) {
+	parent := types.Hash{1}
+	tests := []struct {
+		name      string
+		insertErr error
+		penalised bool
+	}{
+		{"imported", nil, false},
+		{"invalid block", fmt.Errorf("%w: %w", consensus.ErrInvalidBlock, errors.New("invalid merkle root")), true},
+		{"unknown ancestor", consensus.ErrUnknownAncestor, false},
+		{"database failure", errors.New("database closed"), false},
+	}
+	for _, tt := range tests {
+		chain := &blockTestChain{head: 1, known: map[types.Hash]bool{parent: true}, insertErr: tt.insertErr}
+		s := newBlockTestService(t, chain)
+		b := newTestBlock(parent, 2)
+		msg := blockMessage(t, b)
+		pid := coretest.RandPeerIDFatal(t)
+
+		if result, err := s.validateBlockPubSub(context.Background(), pid, msg); result != pubsub.ValidationAccept {
+			t.Fatalf("%s: block not accepted: %v", tt.name, err)
+		}
+		if err := s.blockSubscriber(context.Background(), pid, msg.ValidatorData.(proto.Message)); !errors.Is(err, tt.insertErr) {
+			t.Errorf("%s: error %v, want %v", tt.name, err, tt.insertErr)
+		}
+		if penalised := badResponses(s, pid) == 1; penalised != tt.penalised {
+			t.Errorf("%s: penalised %v, want %v", tt.name, penalised, tt.penalised)
+		}
+		if bad := s.hasBadBlock(b.Hash()); bad != tt.penalised {
+			t.Errorf("%s: bad block %v, want %v", tt.name, bad, tt.penalised)
+		}
+	}
+}
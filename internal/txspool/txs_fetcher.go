@@ -18,232 +18,390 @@ package txspool
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"google.golang.org/protobuf/proto"
-	"math/rand"
+	"sync"
 	"time"
 
 	"github.com/libp2p/go-libp2p/core/peer"
-	"github.com/n42blockchain/N42/api/protocol/sync_proto"
-	"github.com/n42blockchain/N42/api/protocol/types_pb"
-	"github.com/n42blockchain/N42/common"
-	"github.com/n42blockchain/N42/common/message"
 	"github.com/n42blockchain/N42/common/transaction"
 	"github.com/n42blockchain/N42/common/types"
 	"github.com/n42blockchain/N42/log"
-	"go.uber.org/zap"
 )
 
 const (
-	BloomFetcherMaxTime      = 10 * time.Minute
-	BloomSendTransactionTime = 10 * time.Second
-	BloomSendMaxTransactions = 100
+	// maxTxAnnounces is the maximum number of unique transactions a peer can have
+	// in-flight announcements for, before further announcements are dropped.
+	maxTxAnnounces = 4096
+
+	// maxTxRetrievals is the maximum number of transactions that can be fetched
+	// in one request.
+	maxTxRetrievals = 256
+
+	// MaxTxPacketSize is the soft size limit of a pooled transactions response.
+	// A response is closed as soon as this limit is reached, the remaining hashes
+	// are requested again in a follow-up request.
+	MaxTxPacketSize = 100 * 1024
+
+	// txArriveTimeout is the time allowance before an announced transaction is
+	// explicitly requested, giving the full broadcast a chance to arrive first.
+	txArriveTimeout = 500 * time.Millisecond
+
+	// TxFetchTimeout is the maximum allotted time to return an explicitly
+	// requested transaction, before it is requested from an alternate peer.
+	// Requests sent through fetchTxs must not outlive it.
+	TxFetchTimeout = 5 * time.Second
+
+	// txGatherSlack is the interval of the scheduling loop, used to batch up
+	// announcements and requests.
+	txGatherSlack = 100 * time.Millisecond
 )
 
 var (
-	ErrBadPeer = fmt.Errorf("bad peer error")
+	ErrBadPeer           = fmt.Errorf("bad peer error")
+	ErrTooManyAnnounces  = fmt.Errorf("too many transaction announcements")
+	ErrUnrequestedTxs    = fmt.Errorf("unrequested transactions delivered")
+	ErrFetcherNotRunning = fmt.Errorf("txs fetcher not running")
 )
 
-// txsRequest
-type txsRequest struct {
-	hashes hashes       //
-	bloom  *types.Bloom //
-	peer   peer.ID      //
+// TxsPeerScorer receives the per peer propagation statistics gathered by the fetcher.
+type TxsPeerScorer interface {
+	TxsDelivered(pid peer.ID, useful, duplicates int, bytes uint64)
+	TxsRequestTimeout(pid peer.ID)
 }
 
-type hashes []types.Hash
+// txAnnounce tracks a single announced transaction until it is delivered or given up on.
+type txAnnounce struct {
+	first   time.Time            // arrival time of the first announcement
+	peers   map[peer.ID]struct{} // peers that announced the hash and may be asked for it
+	tried   map[peer.ID]struct{} // peers that already failed to deliver the hash
+	fetcher peer.ID              // peer the hash is currently requested from, empty if idle
+}
 
-func (h hashes) pop() types.Hash {
-	old := h
-	n := len(old)
-	x := old[n-1]
-	h = old[0 : n-1]
-	return x
+// txRequest is an in-flight request for transactions to a single peer.
+type txRequest struct {
+	hashes []types.Hash
+	time   time.Time
 }
 
+// TxsFetcher is responsible for retrieving new transactions based on announcements.
+//
+// Peers announce the hashes of transactions they pooled, the fetcher gives the
+// full transaction a short time to arrive by itself, then requests the unknown
+// ones from one of the announcing peers. Requests time out and are retried from
+// alternate announcers, and every delivery is accounted to the delivering peer.
 type TxsFetcher struct {
-	quit chan struct{}
-
-	finished  bool
-	peers     common.PeerMap
-	p2pServer common.INetwork
-
-	peerRequests map[peer.ID]*txsRequest // other peer requests
-
-	bloom   *types.Bloom
-	fetched map[types.Hash]bool //
+	mu sync.Mutex
 
-	getTx      func(hash types.Hash) *transaction.Transaction
-	addTxs     func([]*transaction.Transaction) []error
-	pendingTxs func(enforceTips bool) map[types.Address][]*transaction.Transaction
+	announces map[types.Hash]*txAnnounce
+	announced map[peer.ID]int // number of tracked announcements per peer
+	requests  map[peer.ID]*txRequest
 
-	// fetchTxs
-	//fetchTxs func(string, []types.Hash)
-	// ch
-	peerJoinCh chan *common.PeerJoinEvent
-	peerDropCh chan *common.PeerDropEvent
-	//
+	hasTx    func(hash types.Hash) bool
+	addTxs   func([]*transaction.Transaction) []error
+	fetchTxs func(peer.ID, []types.Hash) error
+	scorer   TxsPeerScorer
 
 	ctx    context.Context
 	cancel context.CancelFunc
 }
 
-func NewTxsFetcher(ctx context.Context, getTx func(hash types.Hash) *transaction.Transaction, addTxs func([]*transaction.Transaction) []error, pendingTxs func(enforceTips bool) map[types.Address][]*transaction.Transaction, p2pServer common.INetwork, peers common.PeerMap, bloom *types.Bloom) *TxsFetcher {
-
+// NewTxsFetcher creates a transaction fetcher. hasTx and addTxs access the local pool,
+// fetchTxs sends a pooled transactions request to a peer and scorer, if not nil,
+// receives the delivery statistics of every peer.
+func NewTxsFetcher(ctx context.Context, hasTx func(hash types.Hash) bool, addTxs func([]*transaction.Transaction) []error, fetchTxs func(peer.ID, []types.Hash) error, scorer TxsPeerScorer) *TxsFetcher {
 	c, cancel := context.WithCancel(ctx)
-	f := &TxsFetcher{
-		quit:         make(chan struct{}),
-		peers:        peers,
-		peerRequests: make(map[peer.ID]*txsRequest),
-		fetched:      make(map[types.Hash]bool),
-		p2pServer:    p2pServer,
-		addTxs:       addTxs,
-		getTx:        getTx,
-		pendingTxs:   pendingTxs,
-		bloom:        bloom,
-		peerJoinCh:   make(chan *common.PeerJoinEvent, 10),
-		peerDropCh:   make(chan *common.PeerDropEvent, 10),
-		finished:     false,
-
-		ctx:    c,
-		cancel: cancel,
-	}
-
-	return f
-}
-
-func (f TxsFetcher) Start() error {
-	go f.sendBloomTransactionLoop()
-	go f.bloomBroadcastLoop()
+	return &TxsFetcher{
+		announces: make(map[types.Hash]*txAnnounce),
+		announced: make(map[peer.ID]int),
+		requests:  make(map[peer.ID]*txRequest),
+		hasTx:     hasTx,
+		addTxs:    addTxs,
+		fetchTxs:  fetchTxs,
+		scorer:    scorer,
+		ctx:       c,
+		cancel:    cancel,
+	}
+}
+
+// Start boots up the announcement based synchroniser.
+func (f *TxsFetcher) Start() error {
+	go f.loop()
+	return nil
+}
+
+// Stop terminates the announcement based synchroniser.
+func (f *TxsFetcher) Stop() error {
+	f.cancel()
 	return nil
 }
 
-// sendBloomTransactionLoop
-func (f TxsFetcher) sendBloomTransactionLoop() {
-	tick := time.NewTicker(BloomSendTransactionTime)
-
-	select {
-	case <-tick.C:
-		for ID, req := range f.peerRequests {
-			if p, ok := f.peers[ID]; ok == true {
-
-				var txs []*types_pb.Transaction
-
-				for i := 0; i < BloomSendMaxTransactions; i++ {
-					hash := req.hashes.pop()
-					tx := f.getTx(hash)
-					txs = append(txs, tx.ToProtoMessage().(*types_pb.Transaction))
-				}
-				msg := &sync_proto.SyncTask{
-					Id:       rand.Uint64(),
-					Ok:       true,
-					SyncType: sync_proto.SyncType_TransactionRes,
-					Payload: &sync_proto.SyncTask_SyncTransactionResponse{
-						SyncTransactionResponse: &sync_proto.SyncTransactionResponse{
-							Transactions: txs,
-						},
-					},
-				}
-				data, _ := proto.Marshal(msg)
-				p.WriteMsg(message.MsgTransaction, data)
+// Notify announces the fetcher of the potential availability of a new batch of
+// transactions in the network.
+func (f *TxsFetcher) Notify(pid peer.ID, hashes []types.Hash) error {
+	if f.ctx.Err() != nil {
+		return ErrFetcherNotRunning
+	}
+	// Skip any transaction announcements that we already know of, checking the
+	// pool outside of the lock.
+	unknowns := make([]types.Hash, 0, len(hashes))
+	for _, hash := range hashes {
+		if !f.hasTx(hash) {
+			unknowns = append(unknowns, hash)
+		}
+	}
+	if len(unknowns) == 0 {
+		return nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	for _, hash := range unknowns {
+		ann, ok := f.announces[hash]
+		if ok {
+			if _, known := ann.peers[pid]; known {
+				continue
+			}
+			if _, failed := ann.tried[pid]; failed {
+				continue
 			}
 		}
-	case <-f.ctx.Done():
-		return
+		if f.announced[pid] >= maxTxAnnounces {
+			return ErrTooManyAnnounces
+		}
+		if !ok {
+			ann = &txAnnounce{
+				first: now,
+				peers: make(map[peer.ID]struct{}),
+				tried: make(map[peer.ID]struct{}),
+			}
+			f.announces[hash] = ann
+		}
+		ann.peers[pid] = struct{}{}
+		f.announced[pid]++
 	}
+	return nil
 }
 
-func (f TxsFetcher) bloomBroadcastLoop() {
-	if f.bloom == nil {
-		return
+// Enqueue imports a batch of received transactions into the pool and accounts
+// them to the delivering peer. If direct is set, the transactions are the reply
+// to a request of ours and any requested hash missing from the batch is
+// rescheduled to an alternate peer.
+func (f *TxsFetcher) Enqueue(pid peer.ID, txs []*transaction.Transaction, bytes uint64, direct bool) error {
+	var (
+		useful, duplicates int
+		delivered          = make(map[types.Hash]struct{}, len(txs))
+	)
+	for i, err := range f.addTxs(txs) {
+		delivered[txs[i].Hash()] = struct{}{}
+		switch {
+		case err == nil:
+			useful++
+		case errors.Is(err, ErrAlreadyKnown):
+			duplicates++
+		default:
+			log.Trace("Fetched transaction rejected", "peer", pid, "hash", txs[i].Hash(), "err", err)
+		}
+	}
+	if f.scorer != nil {
+		f.scorer.TxsDelivered(pid, useful, duplicates, bytes)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for hash := range delivered {
+		f.forget(hash)
 	}
-	tick := time.NewTicker(BloomFetcherMaxTime)
-	bloom, err := f.bloom.Marshal()
-	if err != nil {
-		log.Warn("txs fetcher bloom Marshal err", zap.Error(err))
+	if !direct {
+		return nil
+	}
+	req, ok := f.requests[pid]
+	if !ok {
+		return ErrUnrequestedTxs
+	}
+	delete(f.requests, pid)
+
+	// Anything requested but not delivered is rescheduled to another announcer.
+	for _, hash := range req.hashes {
+		if _, ok := delivered[hash]; ok {
+			continue
+		}
+		f.reschedule(pid, hash)
+	}
+	return nil
+}
+
+// Drop should be called when a peer disconnects. It cleans up all the internal
+// data structures of the given node.
+func (f *TxsFetcher) Drop(pid peer.ID) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if req, ok := f.requests[pid]; ok {
+		delete(f.requests, pid)
+		for _, hash := range req.hashes {
+			f.reschedule(pid, hash)
+		}
+	}
+	// Forget the peer entirely, also where it failed to deliver already, so that
+	// it doesn't release the counter of a reconnected peer later on.
+	for hash, ann := range f.announces {
+		_, announced := ann.peers[pid]
+		_, tried := ann.tried[pid]
+		if !announced && !tried {
+			continue
+		}
+		delete(ann.peers, pid)
+		delete(ann.tried, pid)
+		if len(ann.peers) == 0 && ann.fetcher == "" {
+			f.forget(hash)
+		}
+	}
+	delete(f.announced, pid)
+}
+
+// forget removes a transaction from all tracking structures.
+//
+// Note, this method assumes the fetcher lock is held!
+func (f *TxsFetcher) forget(hash types.Hash) {
+	ann, ok := f.announces[hash]
+	if !ok {
 		return
 	}
+	for pid := range ann.peers {
+		f.release(pid)
+	}
+	for pid := range ann.tried {
+		f.release(pid)
+	}
+	delete(f.announces, hash)
+}
 
-	msg := &sync_proto.SyncTask{
-		Id:       rand.Uint64(),
-		Ok:       true,
-		SyncType: sync_proto.SyncType_TransactionReq,
-		Payload: &sync_proto.SyncTask_SyncTransactionRequest{
-			SyncTransactionRequest: &sync_proto.SyncTransactionRequest{
-				Bloom: bloom,
-			},
-		},
-	}
-	request, _ := proto.Marshal(msg)
-	select {
-	case peerId := <-f.peerJoinCh:
-		f.peers[peerId.Peer].WriteMsg(message.MsgTransaction, request)
-	case <-tick.C:
-	case <-f.ctx.Done():
+// reschedule marks a hash as failed by the given peer so that it is requested from
+// an alternate announcer in the next scheduling round.
+//
+// Note, this method assumes the fetcher lock is held!
+func (f *TxsFetcher) reschedule(pid peer.ID, hash types.Hash) {
+	ann, ok := f.announces[hash]
+	if !ok || ann.fetcher != pid {
 		return
 	}
+	ann.fetcher = ""
+	if _, ok := ann.peers[pid]; ok {
+		delete(ann.peers, pid)
+		ann.tried[pid] = struct{}{}
+	}
 }
 
-// ConnHandler handler peer message
-func (f TxsFetcher) ConnHandler(data []byte, ID peer.ID) error {
-	_, ok := f.peers[ID]
-	if !ok {
-		return ErrBadPeer
+// release decrements the announcement counter of a peer.
+//
+// Note, this method assumes the fetcher lock is held!
+func (f *TxsFetcher) release(pid peer.ID) {
+	if f.announced[pid] > 1 {
+		f.announced[pid]--
+	} else {
+		delete(f.announced, pid)
 	}
+}
 
-	syncTask := sync_proto.SyncTask{}
-	if err := proto.Unmarshal(data, &syncTask); err != nil {
-		log.Errorf("receive sync task(headersResponse) msg err: %v", err)
-		return err
+// loop periodically expires timed out requests and schedules new ones.
+func (f *TxsFetcher) loop() {
+	tick := time.NewTicker(txGatherSlack)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-tick.C:
+			f.expire()
+			for pid, req := range f.schedule() {
+				go func(pid peer.ID, req *txRequest) {
+					if err := f.fetchTxs(pid, req.hashes); err != nil {
+						log.Debug("Failed to request transactions", "peer", pid, "count", len(req.hashes), "err", err)
+						f.fail(pid, req)
+					}
+				}(pid, req)
+			}
+		case <-f.ctx.Done():
+			return
+		}
 	}
+}
 
-	//taskID := syncTask.Id
-	log.Debugf("receive synctask msg from :%v, task type: %v, ok:%v", ID, syncTask.SyncType, syncTask.Ok)
+// fail reschedules the hashes of a request that could not be completed. The
+// request may have expired already, in which case a newer one is left alone.
+func (f *TxsFetcher) fail(pid peer.ID, req *txRequest) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 
-	switch syncTask.SyncType {
+	if f.requests[pid] != req {
+		return
+	}
+	delete(f.requests, pid)
+	for _, hash := range req.hashes {
+		f.reschedule(pid, hash)
+	}
+}
 
-	case sync_proto.SyncType_TransactionReq:
-		request := syncTask.Payload.(*sync_proto.SyncTask_SyncTransactionRequest).SyncTransactionRequest
-		if _, ok := f.peerRequests[ID]; ok == false {
+// expire drops all requests that exceeded TxFetchTimeout, penalising the peers
+// and rescheduling the hashes to alternate announcers.
+func (f *TxsFetcher) expire() {
+	f.mu.Lock()
+	var timeouts []peer.ID
+	for pid, req := range f.requests {
+		if time.Since(req.time) < TxFetchTimeout {
+			continue
+		}
+		delete(f.requests, pid)
+		for _, hash := range req.hashes {
+			f.reschedule(pid, hash)
+		}
+		timeouts = append(timeouts, pid)
+	}
+	f.mu.Unlock()
 
-			bloom := new(types.Bloom)
-			err := bloom.UnMarshalBloom(request.Bloom)
-			if err == nil {
-				var txs []*transaction.Transaction
-				var hashes []types.Hash
-				pending := f.pendingTxs(false)
-				for _, batch := range pending {
-					txs = append(txs, batch...)
-				}
-				for _, tx := range txs {
-					hash := tx.Hash()
-					if !bloom.Contain(hash.Bytes()) {
-						hashes = append(hashes, hash)
-					}
-				}
-				f.peerRequests[ID] = &txsRequest{
-					bloom:  bloom,
-					hashes: hashes,
-					peer:   ID,
-				}
-			}
+	if f.scorer != nil {
+		for _, pid := range timeouts {
+			f.scorer.TxsRequestTimeout(pid)
 		}
+	}
+}
 
-	case sync_proto.SyncType_TransactionRes:
-		//response := syncTask.Payload.(*sync_proto.SyncTask_SyncTransactionResponse).SyncTransactionResponse
-		//var txs []*transaction.Transaction
-		//for _, tranPb := range response.Transactions {
-		//if _, ok := f.fetched[utils.ConvertH256ToHash(tranPb.Hash)]; ok == false {
-		//	f.fetched[utils.ConvertH256ToHash(tranPb.Hash)] = true
-		//	tx, err := transaction.FromProtoMessage(tranPb)
-		//	if err == nil {
-		//		txs = append(txs, tx)
-		//	}
-		//}
-		//}
-		//if len(txs) > 0 {
-		//	f.addTxs(txs)
-		//}
+// schedule assigns every idle announcement that waited at least txArriveTimeout
+// to one of its announcers, never having more than one request per peer in flight.
+// Announcements without any remaining announcer are dropped.
+func (f *TxsFetcher) schedule() map[peer.ID]*txRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	assigned := make(map[peer.ID][]types.Hash)
+	for hash, ann := range f.announces {
+		if ann.fetcher != "" || now.Sub(ann.first) < txArriveTimeout {
+			continue
+		}
+		if len(ann.peers) == 0 {
+			f.forget(hash)
+			continue
+		}
+		for pid := range ann.peers {
+			if _, busy := f.requests[pid]; busy {
+				continue
+			}
+			if len(assigned[pid]) >= maxTxRetrievals {
+				continue
+			}
+			assigned[pid] = append(assigned[pid], hash)
+			ann.fetcher = pid
+			break
+		}
 	}
-	return nil
+	requests := make(map[peer.ID]*txRequest, len(assigned))
+	for pid, hashes := range assigned {
+		requests[pid] = &txRequest{hashes: hashes, time: now}
+		f.requests[pid] = requests[pid]
+	}
+	return requests
 }
@@ -0,0 +1,347 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package txspool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/n42blockchain/N42/common/transaction"
+	"github.com/n42blockchain/N42/common/types"
+)
+
+type testScorer struct {
+	useful, duplicates map[peer.ID]int
+	timeouts           map[peer.ID]int
+}
+
+func newTestScorer() *testScorer {
+	return &testScorer{
+		useful:     make(map[peer.ID]int),
+		duplicates: make(map[peer.ID]int),
+		timeouts:   make(map[peer.ID]int),
+	}
+}
+
+func (s *testScorer) TxsDelivered(pid peer.ID, useful, duplicates int, _ uint64) {
+	s.useful[pid] += useful
+	s.duplicates[pid] += duplicates
+}
+
+func (s *testScorer) TxsRequestTimeout(pid peer.ID) {
+	s.timeouts[pid]++
+}
+
+// newTestFetcher creates a fetcher without its scheduling loop, backed by a pool
+// knowing the given hashes.
+func newTestFetcher(t *testing.T, known ...types.Hash) (*TxsFetcher, *testScorer) {
+	pool := make(map[types.Hash]struct{})
+	for _, h := range known {
+		pool[h] = struct{}{}
+	}
+	scorer := newTestScorer()
+	f := NewTxsFetcher(context.Background(),
+		func(h types.Hash) bool { _, ok := pool[h]; return ok },
+		func(txs []*transaction.Transaction) []error {
+			errs := make([]error, len(txs))
+			for i, tx := range txs {
+				if _, ok := pool[tx.Hash()]; ok {
+					errs[i] = ErrAlreadyKnown
+				}
+				pool[tx.Hash()] = struct{}{}
+			}
+			return errs
+		},
+		func(peer.ID, []types.Hash) error { return nil },
+		scorer,
+	)
+	t.Cleanup(func() { f.Stop() })
+	return f, scorer
+}
+
+// arrive makes all tracked announcements old enough to be requested.
+func (f *TxsFetcher) arrive() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, ann := range f.announces {
+		ann.first = ann.first.Add(-txArriveTimeout)
+	}
+}
+
+// age makes all in-flight requests time out.
+func (f *TxsFetcher) age() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, req := range f.requests {
+		req.time = req.time.Add(-TxFetchTimeout)
+	}
+}
+
+func TestTxsFetcherAnnounce(t *testing.T) {
+	known := types.Hash{0x01}
+	f, _ := newTestFetcher(t, known)
+
+	if err := f.Notify("A", []types.Hash{known, {0x02}, {0x03}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Notify("B", []types.Hash{{0x02}}); err != nil {
+		t.Fatal(err)
+	}
+	// A repeated announcement is not counted twice.
+	if err := f.Notify("A", []types.Hash{{0x02}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := f.announces[known]; ok {
+		t.Errorf("pooled transaction tracked")
+	}
+	if have := len(f.announces); have != 2 {
+		t.Errorf("tracked announcements mismatch: have %d, want 2", have)
+	}
+	if have := f.announced["A"]; have != 2 {
+		t.Errorf("announcements of A mismatch: have %d, want 2", have)
+	}
+	if have := f.announced["B"]; have != 1 {
+		t.Errorf("announcements of B mismatch: have %d, want 1", have)
+	}
+	// Announcements are not scheduled before they had a chance to arrive.
+	if reqs := f.schedule(); len(reqs) != 0 {
+		t.Errorf("premature requests scheduled: %v", reqs)
+	}
+}
+
+func TestTxsFetcherAnnounceLimit(t *testing.T) {
+	f, _ := newTestFetcher(t)
+
+	hashes := make([]types.Hash, maxTxAnnounces+1)
+	for i := range hashes {
+		hashes[i] = types.Hash{byte(i >> 8), byte(i)}
+	}
+	if err := f.Notify("A", hashes); !errors.Is(err, ErrTooManyAnnounces) {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrTooManyAnnounces)
+	}
+	if have := f.announced["A"]; have != maxTxAnnounces {
+		t.Errorf("announcements mismatch: have %d, want %d", have, maxTxAnnounces)
+	}
+}
+
+func TestTxsFetcherFetch(t *testing.T) {
+	f, scorer := newTestFetcher(t)
+	tx := dynamicFeeTx(0, 100, 1000)
+
+	if err := f.Notify("A", []types.Hash{tx.Hash(), {0x02}}); err != nil {
+		t.Fatal(err)
+	}
+	f.arrive()
+	reqs := f.schedule()
+	if len(reqs) != 1 || reqs["A"] == nil || len(reqs["A"].hashes) != 2 {
+		t.Fatalf("requests mismatch: %v", reqs)
+	}
+	// A peer has at most one request in flight.
+	if reqs := f.schedule(); len(reqs) != 0 {
+		t.Fatalf("second request scheduled: %v", reqs)
+	}
+	if err := f.Enqueue("A", []*transaction.Transaction{tx}, 100, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := f.announces[tx.Hash()]; ok {
+		t.Errorf("delivered transaction still tracked")
+	}
+	if _, ok := f.requests["A"]; ok {
+		t.Errorf("delivered request still in flight")
+	}
+	// The undelivered hash has no alternate announcer left and is dropped.
+	if reqs := f.schedule(); len(reqs) != 0 {
+		t.Errorf("undelivered hash requested again: %v", reqs)
+	}
+	if len(f.announces) != 0 || len(f.announced) != 0 {
+		t.Errorf("fetcher not empty: announces %d, peers %d", len(f.announces), len(f.announced))
+	}
+	if scorer.useful["A"] != 1 {
+		t.Errorf("useful deliveries mismatch: have %d, want 1", scorer.useful["A"])
+	}
+	// Unrequested direct deliveries are rejected.
+	if err := f.Enqueue("A", []*transaction.Transaction{tx}, 100, true); !errors.Is(err, ErrUnrequestedTxs) {
+		t.Errorf("error mismatch: have %v, want %v", err, ErrUnrequestedTxs)
+	}
+	if scorer.duplicates["A"] != 1 {
+		t.Errorf("duplicate deliveries mismatch: have %d, want 1", scorer.duplicates["A"])
+	}
+}
+
+func TestTxsFetcherTimeout(t *testing.T) {
+	f, scorer := newTestFetcher(t)
+	hash := types.Hash{0x01}
+
+	for _, pid := range []peer.ID{"A", "B"} {
+		if err := f.Notify(pid, []types.Hash{hash}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.arrive()
+	var first peer.ID
+	for pid := range f.schedule() {
+		first = pid
+	}
+	f.age()
+	f.expire()
+	if scorer.timeouts[first] != 1 {
+		t.Fatalf("timeouts of %s mismatch: have %d, want 1", first, scorer.timeouts[first])
+	}
+	reqs := f.schedule()
+	if len(reqs) != 1 || reqs[first] != nil {
+		t.Fatalf("hash not rescheduled to the alternate peer: %v", reqs)
+	}
+	// The timed out peer is not asked again, even if it announces anew.
+	if err := f.Notify(first, []types.Hash{hash}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := f.announces[hash].peers[first]; ok {
+		t.Errorf("timed out peer tracked as announcer again")
+	}
+}
+
+func TestTxsFetcherFailedRequest(t *testing.T) {
+	f, _ := newTestFetcher(t)
+
+	if err := f.Notify("A", []types.Hash{{0x01}, {0x02}}); err != nil {
+		t.Fatal(err)
+	}
+	f.arrive()
+	stale := f.schedule()["A"]
+	f.age()
+	f.expire()
+
+	// A newer request must survive the failure of an expired one.
+	f.mu.Lock()
+	fresh := &txRequest{hashes: []types.Hash{{0x02}}, time: time.Now()}
+	f.requests["A"] = fresh
+	f.mu.Unlock()
+	f.fail("A", stale)
+	if f.requests["A"] != fresh {
+		t.Fatalf("newer request overwritten")
+	}
+	f.fail("A", fresh)
+	if _, ok := f.requests["A"]; ok {
+		t.Fatalf("failed request still in flight")
+	}
+}
+
+func TestTxsFetcherDrop(t *testing.T) {
+	f, _ := newTestFetcher(t)
+	hash := types.Hash{0x01}
+
+	for _, pid := range []peer.ID{"A", "B"} {
+		if err := f.Notify(pid, []types.Hash{hash}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.arrive()
+	var tried, other peer.ID = "A", "B"
+	if _, ok := f.schedule()["B"]; ok {
+		tried, other = "B", "A"
+	}
+	f.age()
+	f.expire()
+
+	// Dropping the last announcer must release the peer that already tried.
+	f.Drop(other)
+	if len(f.announces) != 0 {
+		t.Errorf("announcement still tracked")
+	}
+	if have := f.announced[tried]; have != 0 {
+		t.Errorf("announcements of %s not released: have %d", tried, have)
+	}
+	if len(f.announced) != 0 {
+		t.Errorf("peer counters not released: %v", f.announced)
+	}
+}
+
+func TestTxsFetcherDropInFlight(t *testing.T) {
+	f, _ := newTestFetcher(t)
+	hash := types.Hash{0x01}
+
+	for _, pid := range []peer.ID{"A", "B"} {
+		if err := f.Notify(pid, []types.Hash{hash}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.arrive()
+	var fetching, other peer.ID = "A", "B"
+	if _, ok := f.schedule()["B"]; ok {
+		fetching, other = "B", "A"
+	}
+	f.Drop(fetching)
+	if _, ok := f.requests[fetching]; ok {
+		t.Fatalf("request of dropped peer still in flight")
+	}
+	reqs := f.schedule()
+	if len(reqs) != 1 || reqs[other] == nil {
+		t.Fatalf("hash not rescheduled to the remaining peer: %v", reqs)
+	}
+}
+
+func TestTxsFetcherDropReconnect(t *testing.T) {
+	f, _ := newTestFetcher(t)
+	shared, own := types.Hash{0x01}, types.Hash{0x02}
+
+	if err := f.Notify("A", []types.Hash{shared, own}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Notify("B", []types.Hash{shared}); err != nil {
+		t.Fatal(err)
+	}
+	f.arrive()
+	// Make A fetch both hashes.
+	f.mu.Lock()
+	for _, hash := range []types.Hash{shared, own} {
+		f.announces[hash].fetcher = "A"
+	}
+	f.requests["A"] = &txRequest{hashes: []types.Hash{shared, own}, time: time.Now()}
+	f.mu.Unlock()
+
+	// Dropping A forgets the hash only it announced, and A everywhere else.
+	f.Drop("A")
+	if _, ok := f.announces[own]; ok {
+		t.Errorf("hash announced by the dropped peer only still tracked")
+	}
+	ann, ok := f.announces[shared]
+	if !ok {
+		t.Fatalf("hash announced by the remaining peer forgotten")
+	}
+	if _, ok := ann.tried["A"]; ok {
+		t.Errorf("dropped peer still tracked as tried")
+	}
+
+	// Once reconnected, A's announcements are counted afresh and not released
+	// by announcements of its previous connection.
+	if err := f.Notify("A", []types.Hash{{0x03}}); err != nil {
+		t.Fatal(err)
+	}
+	f.Drop("B")
+	if len(f.announces) != 1 {
+		t.Errorf("tracked announcements mismatch: have %d, want 1", len(f.announces))
+	}
+	if have := f.announced["A"]; have != 1 {
+		t.Errorf("announcements of reconnected A mismatch: have %d, want 1", have)
+	}
+	if _, ok := f.announced["B"]; ok {
+		t.Errorf("announcements of dropped B still counted")
+	}
+}
@@ -121,17 +121,19 @@ func (as *accountSet) merge(other *accountSet) {
 // This lookup set combines the notion of "local transactions", which is useful
 // to build upper-level structure.
 type txLookup struct {
-	slots   int
-	lock    sync.RWMutex
-	locals  map[types.Hash]*transaction.Transaction
-	remotes map[types.Hash]*transaction.Transaction
+	slots     int
+	lock      sync.RWMutex
+	locals    map[types.Hash]*transaction.Transaction
+	remotes   map[types.Hash]*transaction.Transaction
+	sponsored map[types.Address]*uint256.Int // gas cost of the fee-delegated transactions by fee payer
 }
 
 // newTxLookup returns a new txLookup structure.
 func newTxLookup() *txLookup {
 	return &txLookup{
-		locals:  make(map[types.Hash]*transaction.Transaction),
-		remotes: make(map[types.Hash]*transaction.Transaction),
+		locals:    make(map[types.Hash]*transaction.Transaction),
+		remotes:   make(map[types.Hash]*transaction.Transaction),
+		sponsored: make(map[types.Address]*uint256.Int),
 	}
 }
 
@@ -231,6 +233,12 @@ func (t *txLookup) Add(tx *transaction.Transaction, local bool) {
 	} else {
 		t.remotes[hash] = tx
 	}
+	if payer := tx.FeePayer(); payer != nil {
+		if t.sponsored[*payer] == nil {
+			t.sponsored[*payer] = new(uint256.Int)
+		}
+		t.sponsored[*payer].Add(t.sponsored[*payer], tx.GasCost())
+	}
 }
 
 // Remove removes a transaction from the lookup.
@@ -252,6 +260,38 @@ func (t *txLookup) Remove(hash types.Hash) {
 
 	delete(t.locals, hash)
 	delete(t.remotes, hash)
+
+	if payer := tx.FeePayer(); payer != nil {
+		if cost := t.sponsored[*payer]; cost != nil {
+			if cost.Sub(cost, tx.GasCost()); cost.IsZero() {
+				delete(t.sponsored, *payer)
+			}
+		}
+	}
+}
+
+// Sponsored returns the gas cost of all the pooled transactions the given
+// account pays for as fee payer.
+func (t *txLookup) Sponsored(payer types.Address) *uint256.Int {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if cost := t.sponsored[payer]; cost != nil {
+		return new(uint256.Int).Set(cost)
+	}
+	return new(uint256.Int)
+}
+
+// Payers returns the fee payers of the pooled fee-delegated transactions.
+func (t *txLookup) Payers() []types.Address {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	payers := make([]types.Address, 0, len(t.sponsored))
+	for payer := range t.sponsored {
+		payers = append(payers, payer)
+	}
+	return payers
 }
 
 // RemoteToLocals migrates the transactions belongs to the given locals to locals
@@ -562,7 +602,9 @@ func (l *txsList) Add(tx *transaction.Transaction, priceBump uint64) (bool, *tra
 	// Otherwise overwrite the old transaction with the current one
 	l.txs.Put(tx)
 
-	cost := uint256.NewInt(0).Add(new(uint256.Int).Mul(tx.GasPrice(), uint256.NewInt(tx.Gas())), tx.Value())
+	// The gas of fee-delegated transactions is accounted to the fee payer
+	// by the pool, only the sender's part limits the list.
+	cost := tx.Cost()
 
 	if l.costcap.Cmp(cost) < 0 {
 		l.costcap = *cost
@@ -599,8 +641,7 @@ func (l *txsList) Filter(costLimit uint256.Int, gasLimit uint64) ([]*transaction
 
 	// Filter out all the transactions above the account's funds
 	removed := l.txs.Filter(func(tx *transaction.Transaction) bool {
-		cost := uint256.NewInt(0).Add(tx.Value(), uint256.NewInt(0).Mul(tx.GasPrice(), uint256.NewInt(tx.Gas())))
-		return tx.Gas() > gasLimit || cost.Cmp(&costLimit) > 0
+		return tx.Gas() > gasLimit || tx.Cost().Cmp(&costLimit) > 0
 	})
 
 	if len(removed) == 0 {
@@ -17,6 +17,7 @@
 package txspool
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"github.com/holiman/uint256"
@@ -70,9 +71,14 @@ var (
 
 	ErrNonceTooLow  = fmt.Errorf("nonce too low")
 	ErrNonceTooHigh = fmt.Errorf("nonce too high")
+	ErrNonceGap     = fmt.Errorf("nonce gap")
 
 	ErrInsufficientFunds = fmt.Errorf("insufficient funds for gas * price + value")
 
+	// ErrInsufficientFeePayerFunds is returned if the fee payer of a fee-delegated
+	// transaction cannot cover its gas.
+	ErrInsufficientFeePayerFunds = fmt.Errorf("insufficient fee payer funds for gas * price")
+
 	// ErrTipAboveFeeCap is a sanity error to ensure no one is able to specify a
 	// transaction with a tip higher than the total fee cap.
 	ErrTipAboveFeeCap = fmt.Errorf("max priority fee per gas higher than max fee per gas")
@@ -82,6 +88,14 @@ var (
 	localGauge   = prometheus.GetOrCreateCounter("txpool_local", true)
 )
 
+// Eviction reasons reported through common.EvictedTxsEvent.
+const (
+	EvictPendingLimit = "pending-limit"
+	EvictQueueLimit   = "queue-limit"
+	EvictUnderpriced  = "underpriced"
+	EvictFeePayer     = "fee-payer-funds"
+)
+
 type txspoolResetRequest struct {
 	oldBlock, newBlock block.IBlock
 }
@@ -135,6 +149,7 @@ type TxsPool struct {
 	eip2718  bool // Fork indicator whether we are using EIP-2718 type transactions.
 	eip1559  bool // Fork indicator whether we are using EIP-1559 type transactions.
 	shanghai bool // Fork indicator whether we are in the Shanghai stage.
+	sakuragi bool // Fork indicator whether we are accepting fee-delegated transactions.
 
 	locals   *accountSet
 	pending  map[types.Address]*txsList
@@ -152,6 +167,7 @@ type TxsPool struct {
 	reorgShutdownCh chan struct{}
 
 	changesSinceReorg int
+	evictions         []common.EvictedTxsEvent
 
 	isRun uint32
 
@@ -296,7 +312,9 @@ func (pool *TxsPool) addTxs(txs []*transaction.Transaction, local, sync bool) []
 	// Process all the new transaction and merge any errors into the original slice
 	pool.mu.Lock()
 	newErrs, dirtyAddrs := pool.addTxsLocked(news, local)
+	evictions := pool.takeEvictions()
 	pool.mu.Unlock()
+	sendEvictions(evictions)
 
 	var nilSlot = 0
 	for _, err := range newErrs {
@@ -446,6 +464,7 @@ func (pool *TxsPool) add(tx *transaction.Transaction, local bool) (replaced bool
 		// Bump the counter of rejections-since-reorg
 		pool.changesSinceReorg += len(drop)
 		// Kick out the underpriced remote transactions.
+		pool.evict(EvictUnderpriced, drop)
 		for _, tx := range drop {
 			log.Debug("Discarding freshly underpriced transaction", "hash", hash, "gasTipCap", gasPrice, "gasFeeCap", gasPrice)
 			hash := tx.Hash()
@@ -545,6 +564,10 @@ func (pool *TxsPool) validateTx(tx *transaction.Transaction, local bool) error {
 	if !pool.eip1559 && tx.Type() == transaction.DynamicFeeTxType {
 		return internal.ErrTxTypeNotSupported
 	}
+	// Reject fee-delegated transactions until the Sakuragi upgrade activates.
+	if !pool.sakuragi && tx.Type() == transaction.SakuragiTxType {
+		return internal.ErrTxTypeNotSupported
+	}
 	// Reject transactions over defined size to prevent DOS attacks
 	//if tx.Size() > txMaxSize {
 	//	return ErrOversizedData
@@ -593,6 +616,27 @@ func (pool *TxsPool) validateTx(tx *transaction.Transaction, local bool) error {
 	if pool.currentState.GetBalance(addr).Cmp(tx.Cost()) < 0 {
 		return ErrInsufficientFunds
 	}
+	// The fee payer of a fee-delegated transaction must have signed it and be
+	// able to pay for its gas.
+	if payer := tx.FeePayer(); payer != nil {
+		signer := transaction.LatestSignerForChainID(pool.chainconfig.ChainID)
+		if from, err := transaction.FeePayerSender(signer, tx); err != nil || from != *payer {
+			return transaction.ErrInvalidFeePayer
+		}
+		cost := tx.GasCost()
+		if *payer == addr {
+			cost.Add(cost, tx.Value())
+		}
+		// The payer has to cover the gas of every transaction it sponsors in
+		// the pool, except that of the one being replaced.
+		cost.Add(cost, pool.all.Sponsored(*payer))
+		if old := pool.pooledTx(addr, tx.Nonce()); old != nil && old.FeePayer() != nil && *old.FeePayer() == *payer {
+			cost.Sub(cost, old.GasCost())
+		}
+		if pool.currentState.GetBalance(*payer).Cmp(cost) < 0 {
+			return ErrInsufficientFeePayerFunds
+		}
+	}
 
 	// Ensure the transaction has more gas than the basic tx fee.
 	intrGas, err := internal.IntrinsicGas(tx.Data(), tx.AccessList(), tx.To() == nil, true, pool.istanbul, pool.shanghai)
@@ -619,6 +663,22 @@ func (pool *TxsPool) validateTx(tx *transaction.Transaction, local bool) error {
 	return nil
 }
 
+// pooledTx returns the pending or queued transaction of the account with the
+// given nonce, if any.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxsPool) pooledTx(addr types.Address, nonce uint64) *transaction.Transaction {
+	if list := pool.pending[addr]; list != nil {
+		if tx := list.txs.Get(nonce); tx != nil {
+			return tx
+		}
+	}
+	if list := pool.queue[addr]; list != nil {
+		return list.txs.Get(nonce)
+	}
+	return nil
+}
+
 // validateSender verify todo
 func (pool *TxsPool) validateSender(tx *transaction.Transaction) bool {
 
@@ -749,6 +809,7 @@ func (pool *TxsPool) reset(oldBlock, newBlock block.IBlock) {
 	pool.istanbul = pool.chainconfig.IsIstanbul(next.Uint64())
 	pool.eip2718 = pool.chainconfig.IsBerlin(next.Uint64())
 	pool.eip1559 = pool.chainconfig.IsLondon(next.Uint64())
+	pool.sakuragi = pool.chainconfig.IsSakuragi(next.Uint64())
 }
 
 // promoteExecutables moves transactions that have become processable from the
@@ -758,6 +819,19 @@ func (pool *TxsPool) promoteExecutables(accounts []types.Address) []*transaction
 	// Track the promoted transactions to broadcast them at once
 	var promoted []*transaction.Transaction
 
+	// Drop the queued transactions whose fee payer can't pay for them anymore
+	var payers []types.Address
+	for _, addr := range accounts {
+		if list := pool.queue[addr]; list != nil {
+			for _, tx := range list.Flatten() {
+				if payer := tx.FeePayer(); payer != nil {
+					payers = append(payers, *payer)
+				}
+			}
+		}
+	}
+	pool.filterSponsored(payers)
+
 	// Iterate over all accounts and promote any executable transactions
 	for _, addr := range accounts {
 		list := pool.queue[addr]
@@ -838,6 +912,7 @@ func (pool *TxsPool) truncatePending() {
 		}
 	}
 	// Gradually drop transactions from offenders
+	var dropped []*transaction.Transaction
 	offenders := []types.Address{}
 	for pending > pool.config.GlobalSlots && !spammers.Empty() {
 		// Retrieve the next offender if not local address
@@ -855,6 +930,7 @@ func (pool *TxsPool) truncatePending() {
 					list := pool.pending[offenders[i]]
 
 					caps := list.Cap(list.Len() - 1)
+					dropped = append(dropped, caps...)
 					for _, tx := range caps {
 						// Drop the transaction from the global pools too
 						hash := tx.Hash()
@@ -882,6 +958,7 @@ func (pool *TxsPool) truncatePending() {
 				list := pool.pending[addr]
 
 				caps := list.Cap(list.Len() - 1)
+				dropped = append(dropped, caps...)
 				for _, tx := range caps {
 					// Drop the transaction from the global pools too
 					hash := tx.Hash()
@@ -900,6 +977,7 @@ func (pool *TxsPool) truncatePending() {
 			}
 		}
 	}
+	pool.evict(EvictPendingLimit, dropped)
 }
 
 // truncateQueue drops the oldes transactions in the queue if the pool is above the global queue limit.
@@ -922,6 +1000,7 @@ func (pool *TxsPool) truncateQueue() {
 	sort.Sort(addresses)
 
 	// Drop transactions until the total is below the limit or only locals remain
+	var dropped []*transaction.Transaction
 	for drop := queued - pool.config.GlobalQueue; drop > 0 && len(addresses) > 0; {
 		addr := addresses[len(addresses)-1]
 		list := pool.queue[addr.address]
@@ -931,6 +1010,7 @@ func (pool *TxsPool) truncateQueue() {
 		// Drop all transactions if they are less than the overflow
 		if size := uint64(list.Len()); size <= drop {
 			for _, tx := range list.Flatten() {
+				dropped = append(dropped, tx)
 				hash := tx.Hash()
 				pool.removeTx(hash, true)
 			}
@@ -940,11 +1020,13 @@ func (pool *TxsPool) truncateQueue() {
 		// Otherwise drop only last few transactions
 		txs := list.Flatten()
 		for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
+			dropped = append(dropped, txs[i])
 			hash := txs[i].Hash()
 			pool.removeTx(hash, true)
 			drop--
 		}
 	}
+	pool.evict(EvictQueueLimit, dropped)
 }
 
 // demoteUnexecutables removes invalid and processed transactions from the pools
@@ -1003,6 +1085,98 @@ func (pool *TxsPool) demoteUnexecutables() {
 			delete(pool.pending, addr)
 		}
 	}
+	// Drop the fee-delegated transactions whose fee payer's balance drained
+	pool.filterSponsored(pool.all.Payers())
+}
+
+// filterSponsored drops the fee-delegated transactions of the given fee payers
+// that their balance can't pay for anymore. The pending transactions are served
+// before the queued ones and those of a sender in nonce order, the transactions
+// following a dropped pending one are moved back to the future queue.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxsPool) filterSponsored(payers []types.Address) {
+	seen := make(map[types.Address]struct{}, len(payers))
+	for _, payer := range payers {
+		if _, ok := seen[payer]; ok {
+			continue
+		}
+		seen[payer] = struct{}{}
+
+		balance := pool.currentState.GetBalance(payer)
+		if pool.all.Sponsored(payer).Cmp(balance) <= 0 {
+			continue
+		}
+		var (
+			spent = new(uint256.Int)
+			drops []*transaction.Transaction
+		)
+		for _, lists := range []map[types.Address]*txsList{pool.pending, pool.queue} {
+			for _, tx := range sponsoredBy(lists, payer) {
+				cost := tx.GasCost()
+				if *tx.From() == payer {
+					cost.Add(cost, tx.Value())
+				}
+				if cost.Add(cost, spent); cost.Cmp(balance) > 0 {
+					drops = append(drops, tx)
+					continue
+				}
+				spent = cost
+			}
+		}
+		for _, tx := range drops {
+			pool.removeTx(tx.Hash(), true)
+		}
+		pool.evict(EvictFeePayer, drops)
+		log.Debug("Removed unpayable sponsored transactions", "payer", payer, "count", len(drops))
+	}
+}
+
+// sponsoredBy returns the transactions of the lists paid for by the given fee
+// payer, ordered by sender and nonce.
+func sponsoredBy(lists map[types.Address]*txsList, payer types.Address) []*transaction.Transaction {
+	senders := make([]types.Address, 0, len(lists))
+	for addr := range lists {
+		senders = append(senders, addr)
+	}
+	sort.Slice(senders, func(i, j int) bool { return bytes.Compare(senders[i][:], senders[j][:]) < 0 })
+
+	var txs []*transaction.Transaction
+	for _, addr := range senders {
+		for _, tx := range lists[addr].Flatten() {
+			if p := tx.FeePayer(); p != nil && *p == payer {
+				txs = append(txs, tx)
+			}
+		}
+	}
+	return txs
+}
+
+// evict records transactions dropped to keep the pool within its limits, so
+// that they can be announced once the pool lock is released.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxsPool) evict(reason string, txs []*transaction.Transaction) {
+	if len(txs) == 0 {
+		return
+	}
+	pool.evictions = append(pool.evictions, common.EvictedTxsEvent{Txs: txs, Reason: reason})
+}
+
+// takeEvictions returns and clears the evictions recorded since the last call.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxsPool) takeEvictions() []common.EvictedTxsEvent {
+	evictions := pool.evictions
+	pool.evictions = nil
+	return evictions
+}
+
+// sendEvictions announces evicted transactions to the subscribed subsystems.
+func sendEvictions(evictions []common.EvictedTxsEvent) {
+	for _, ev := range evictions {
+		event.GlobalEvent.Send(ev)
+	}
 }
 
 // runReorg runs reset and promoteExecutables on behalf of scheduleReorgLoop.
@@ -1061,8 +1235,12 @@ func (pool *TxsPool) runReorg(done chan struct{}, reset *txspoolResetRequest, di
 	pool.truncateQueue()
 
 	pool.changesSinceReorg = 0 // Reset change counter
+	evictions := pool.takeEvictions()
 	pool.mu.Unlock()
 
+	// Notify subsystems for evicted transactions
+	sendEvictions(evictions)
+
 	// Notify subsystems for newly added transactions
 	for _, tx := range promoted {
 		addr := *tx.From()
@@ -1282,6 +1460,67 @@ func (pool *TxsPool) Content() (map[types.Address][]*transaction.Transaction, ma
 	return pending, queued
 }
 
+// ContentFrom retrieves the pending and queued transactions of an address, grouped
+// by nonce, together with the reason why each queued transaction is not executable.
+func (pool *TxsPool) ContentFrom(addr types.Address) ([]*transaction.Transaction, []*transaction.Transaction, map[types.Hash]error) {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	var pending, queued []*transaction.Transaction
+	if list, ok := pool.pending[addr]; ok {
+		pending = list.Flatten()
+	}
+	reasons := make(map[types.Hash]error)
+	if list, ok := pool.queue[addr]; ok {
+		queued = list.Flatten()
+
+		next := pool.pendingNonces.get(addr)
+		balance := pool.currentState.GetBalance(addr)
+		for _, tx := range queued {
+			reasons[tx.Hash()] = pool.queuedReason(addr, tx, next, balance)
+		}
+	}
+	return pending, queued, reasons
+}
+
+// queuedReason reports why a queued transaction cannot be promoted to pending yet.
+// A nil result means it is executable and will be promoted on the next reorg.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxsPool) queuedReason(addr types.Address, tx *transaction.Transaction, next uint64, balance *uint256.Int) error {
+	if tx.Nonce() > next {
+		return fmt.Errorf("%w: next executable nonce is %d", ErrNonceGap, next)
+	}
+	if list := pool.pending[addr]; list != nil {
+		if old := list.txs.Get(tx.Nonce()); old != nil && old.Hash() != tx.Hash() {
+			return fmt.Errorf("%w: a %d%% bump over pending %v is required", ErrReplaceUnderpriced, pool.config.PriceBump, old.Hash())
+		}
+	}
+	if balance.Cmp(tx.Cost()) < 0 {
+		return fmt.Errorf("%w: balance %v, cost %v", ErrInsufficientFunds, balance, tx.Cost())
+	}
+	// The fee payer has to cover the gas of all the transactions it sponsors
+	// in the pool, this one included.
+	if payer := tx.FeePayer(); payer != nil {
+		cost := pool.all.Sponsored(*payer)
+		if *payer == addr {
+			cost.Add(cost, tx.Value())
+		}
+		if payerBalance := pool.currentState.GetBalance(*payer); payerBalance.Cmp(cost) < 0 {
+			return fmt.Errorf("%w: fee payer %v balance %v, sponsored cost %v", ErrInsufficientFeePayerFunds, *payer, payerBalance, cost)
+		}
+	}
+	if baseFee := pool.priced.urgent.baseFee; baseFee != nil && tx.GasFeeCapIntCmp(baseFee) < 0 {
+		return fmt.Errorf("%w: max fee per gas %v below base fee %v", ErrUnderpriced, tx.GasFeeCap(), baseFee)
+	}
+	return nil
+}
+
+// PriceBump returns the minimum price bump percentage required to replace a pooled transaction.
+func (pool *TxsPool) PriceBump() uint64 {
+	return pool.config.PriceBump
+}
+
 func (pool *TxsPool) Nonce(addr types.Address) uint64 {
 	pool.mu.RLock()
 	defer pool.mu.RUnlock()
@@ -0,0 +1,575 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package txspool
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/n42blockchain/N42/common"
+	"github.com/n42blockchain/N42/common/account"
+	"github.com/n42blockchain/N42/common/crypto"
+	"github.com/n42blockchain/N42/common/transaction"
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/internal"
+	event "github.com/n42blockchain/N42/modules/event/v2"
+	"github.com/n42blockchain/N42/params"
+)
+
+func dynamicFeeTx(nonce uint64, tip, feeCap uint64) *transaction.Transaction {
+	to := types.Address{0x01}
+	return transaction.NewTx(&transaction.DynamicFeeTx{
+		ChainID:   uint256.NewInt(1),
+		Nonce:     nonce,
+		GasTipCap: uint256.NewInt(tip),
+		GasFeeCap: uint256.NewInt(feeCap),
+		Gas:       21000,
+		To:        &to,
+		Value:     uint256.NewInt(0),
+	})
+}
+
+func TestPriceBump(t *testing.T) {
+	pool := &TxsPool{config: DefaultTxPoolConfig}
+	if have, want := pool.PriceBump(), DefaultTxPoolConfig.PriceBump; have != want {
+		t.Fatalf("price bump mismatch: have %d, want %d", have, want)
+	}
+}
+
+func TestReplacementPriceBump(t *testing.T) {
+	bump := DefaultTxPoolConfig.PriceBump
+	tests := []struct {
+		tip, feeCap uint64
+		replaced    bool
+	}{
+		{110, 1100, true},
+		{200, 2000, true},
+		{109, 1100, false},
+		{110, 1099, false},
+		{100, 1000, false},
+		{200, 1000, false},
+	}
+	for i, tt := range tests {
+		list := newTxsList(true)
+		if ok, _ := list.Add(dynamicFeeTx(0, 100, 1000), bump); !ok {
+			t.Fatalf("test %d: original transaction rejected", i)
+		}
+		ok, old := list.Add(dynamicFeeTx(0, tt.tip, tt.feeCap), bump)
+		if ok != tt.replaced {
+			t.Errorf("test %d: replaced mismatch: have %v, want %v", i, ok, tt.replaced)
+		}
+		if ok && old == nil {
+			t.Errorf("test %d: replaced transaction not returned", i)
+		}
+	}
+}
+
+// testState is a ReadState serving fixed balances and nonces.
+type testState struct {
+	balances map[types.Address]uint64
+	nonces   map[types.Address]uint64
+}
+
+func (s *testState) GetNonce(addr types.Address) uint64 { return s.nonces[addr] }
+
+func (s *testState) GetBalance(addr types.Address) *uint256.Int {
+	return uint256.NewInt(s.balances[addr])
+}
+
+func (s *testState) State(addr types.Address) (*account.StateAccount, error) {
+	return &account.StateAccount{Nonce: s.nonces[addr], Balance: *s.GetBalance(addr)}, nil
+}
+
+func newTestPool(state *testState) *TxsPool {
+	all := newTxLookup()
+	return &TxsPool{
+		config:         DefaultTxPoolConfig,
+		chainconfig:    &params.ChainConfig{ChainID: big.NewInt(1)},
+		currentState:   state,
+		pendingNonces:  newTxNoncer(state),
+		currentMaxGas:  30_000_000,
+		eip2718:        true,
+		eip1559:        true,
+		sakuragi:       true,
+		locals:         newAccountSet(),
+		pending:        make(map[types.Address]*txsList),
+		queue:          make(map[types.Address]*txsList),
+		beats:          make(map[types.Address]time.Time),
+		all:            all,
+		priced:         newTxPricedList(all),
+		gasPrice:       uint256.NewInt(1),
+		queueTxEventCh: make(chan *transaction.Transaction, 16),
+	}
+}
+
+// sponsoredTx returns a fee-delegated transaction of the sender paid for by
+// the payer, costing the payer feeCap * 21000.
+func sponsoredTx(t *testing.T, sender, payer *ecdsa.PrivateKey, nonce, feeCap uint64) *transaction.Transaction {
+	t.Helper()
+	signer := transaction.LatestSignerForChainID(big.NewInt(1))
+	from := crypto.PubkeyToAddress(sender.PublicKey)
+	to := types.Address{0x01}
+	tx, err := transaction.SignNewTx(sender, signer, &transaction.SakuragiTx{
+		ChainID:   uint256.NewInt(1),
+		Nonce:     nonce,
+		GasTipCap: uint256.NewInt(1),
+		GasFeeCap: uint256.NewInt(feeCap),
+		Gas:       21000,
+		To:        &to,
+		From:      &from,
+		Value:     uint256.NewInt(1),
+	})
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if tx, err = transaction.SignFeePayerTx(tx, signer, payer); err != nil {
+		t.Fatalf("failed to sign as fee payer: %v", err)
+	}
+	return tx
+}
+
+func TestSponsoredLookup(t *testing.T) {
+	sender, _ := crypto.GenerateKey()
+	payer, _ := crypto.GenerateKey()
+	payerAddr := crypto.PubkeyToAddress(payer.PublicKey)
+
+	all := newTxLookup()
+	txs := []*transaction.Transaction{sponsoredTx(t, sender, payer, 0, 10), sponsoredTx(t, sender, payer, 1, 20)}
+	all.Add(txs[0], false)
+	all.Add(txs[1], true)
+	all.Add(dynamicFeeTx(2, 1, 100), false)
+	if have, want := all.Sponsored(payerAddr), uint256.NewInt(30*21000); !have.Eq(want) {
+		t.Fatalf("sponsored cost mismatch: have %v, want %v", have, want)
+	}
+	if payers := all.Payers(); len(payers) != 1 || payers[0] != payerAddr {
+		t.Fatalf("payers mismatch: have %v, want [%v]", payers, payerAddr)
+	}
+	all.Remove(txs[1].Hash())
+	if have, want := all.Sponsored(payerAddr), uint256.NewInt(10*21000); !have.Eq(want) {
+		t.Fatalf("sponsored cost mismatch after removal: have %v, want %v", have, want)
+	}
+	all.Remove(txs[0].Hash())
+	if payers := all.Payers(); len(payers) != 0 {
+		t.Fatalf("payer tracked after its last transaction was removed: %v", payers)
+	}
+}
+
+func TestListFilterSponsored(t *testing.T) {
+	sender, _ := crypto.GenerateKey()
+	payer, _ := crypto.GenerateKey()
+
+	// The sender only pays for the value of a fee-delegated transaction.
+	list := newTxsList(true)
+	list.Add(sponsoredTx(t, sender, payer, 0, 10), DefaultTxPoolConfig.PriceBump)
+	if drops, invalids := list.Filter(*uint256.NewInt(1), 30_000_000); len(drops) != 0 || len(invalids) != 0 {
+		t.Fatalf("sponsored transaction filtered by sender balance: %d dropped, %d invalidated", len(drops), len(invalids))
+	}
+	if drops, _ := list.Filter(*uint256.NewInt(0), 30_000_000); len(drops) != 1 {
+		t.Fatalf("transaction with unpayable value kept")
+	}
+}
+
+func TestValidateSponsoredFunds(t *testing.T) {
+	senders := make([]*ecdsa.PrivateKey, 3)
+	for i := range senders {
+		senders[i], _ = crypto.GenerateKey()
+	}
+	payer, _ := crypto.GenerateKey()
+	payerAddr := crypto.PubkeyToAddress(payer.PublicKey)
+
+	state := &testState{balances: map[types.Address]uint64{payerAddr: 3 * 10 * 21000}, nonces: map[types.Address]uint64{}}
+	for _, sender := range senders {
+		state.balances[crypto.PubkeyToAddress(sender.PublicKey)] = 1
+	}
+	pool := newTestPool(state)
+
+	// The payer can pay for three transactions, from whichever senders.
+	for i, tx := range []*transaction.Transaction{
+		sponsoredTx(t, senders[0], payer, 0, 10),
+		sponsoredTx(t, senders[0], payer, 1, 10),
+		sponsoredTx(t, senders[1], payer, 0, 10),
+	} {
+		if _, err := pool.add(tx, false); err != nil {
+			t.Fatalf("transaction %d rejected: %v", i, err)
+		}
+	}
+	if _, err := pool.add(sponsoredTx(t, senders[2], payer, 0, 10), false); err != ErrInsufficientFeePayerFunds {
+		t.Fatalf("error mismatch over the payer balance: have %v, want %v", err, ErrInsufficientFeePayerFunds)
+	}
+	// A replacement only needs the payer to cover the difference.
+	state.balances[payerAddr] += 2 * 21000
+	if _, err := pool.add(sponsoredTx(t, senders[1], payer, 0, 12), false); err != nil {
+		t.Fatalf("replacement rejected: %v", err)
+	}
+	if _, err := pool.add(sponsoredTx(t, senders[1], payer, 0, 14), false); err != ErrInsufficientFeePayerFunds {
+		t.Fatalf("error mismatch replacing over the payer balance: have %v, want %v", err, ErrInsufficientFeePayerFunds)
+	}
+}
+
+func TestValidateSakuragiFork(t *testing.T) {
+	sender, _ := crypto.GenerateKey()
+	payer, _ := crypto.GenerateKey()
+	state := &testState{balances: map[types.Address]uint64{
+		crypto.PubkeyToAddress(sender.PublicKey): 1,
+		crypto.PubkeyToAddress(payer.PublicKey):  10 * 21000,
+	}, nonces: map[types.Address]uint64{}}
+
+	tests := []struct {
+		name     string
+		sakuragi bool
+		want     error
+	}{
+		{"before the fork", false, internal.ErrTxTypeNotSupported},
+		{"after the fork", true, nil},
+	}
+	for _, tt := range tests {
+		pool := newTestPool(state)
+		pool.sakuragi = tt.sakuragi
+		if _, err := pool.add(sponsoredTx(t, sender, payer, 0, 10), false); err != tt.want {
+			t.Errorf("%s: error mismatch: have %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestDemoteUnsponsored(t *testing.T) {
+	senders := make([]*ecdsa.PrivateKey, 2)
+	for i := range senders {
+		senders[i], _ = crypto.GenerateKey()
+	}
+	payer, _ := crypto.GenerateKey()
+	payerAddr := crypto.PubkeyToAddress(payer.PublicKey)
+
+	state := &testState{balances: map[types.Address]uint64{payerAddr: 4 * 10 * 21000}, nonces: map[types.Address]uint64{}}
+	var addrs []types.Address
+	for _, sender := range senders {
+		addr := crypto.PubkeyToAddress(sender.PublicKey)
+		state.balances[addr] = 10
+		addrs = append(addrs, addr)
+	}
+	pool := newTestPool(state)
+	for i := uint64(0); i < 2; i++ {
+		for _, sender := range senders {
+			if _, err := pool.add(sponsoredTx(t, sender, payer, i, 10), false); err != nil {
+				t.Fatalf("transaction rejected: %v", err)
+			}
+		}
+	}
+	if promoted := pool.promoteExecutables(addrs); len(promoted) != 4 {
+		t.Fatalf("promoted transactions mismatch: have %d, want 4", len(promoted))
+	}
+
+	// Once the payer can only pay for three transactions, the last pending
+	// transaction of the second sender in address order is dropped.
+	state.balances[payerAddr] = 3 * 10 * 21000
+	pool.demoteUnexecutables()
+	if have := pool.all.Count(); have != 3 {
+		t.Fatalf("pooled transactions mismatch: have %d, want 3", have)
+	}
+	if have, want := pool.all.Sponsored(payerAddr), uint256.NewInt(3*10*21000); !have.Eq(want) {
+		t.Fatalf("sponsored cost mismatch: have %v, want %v", have, want)
+	}
+	evictions := pool.takeEvictions()
+	if len(evictions) != 1 || evictions[0].Reason != EvictFeePayer || len(evictions[0].Txs) != 1 {
+		t.Fatalf("evictions mismatch: %v", evictions)
+	}
+	if dropped := evictions[0].Txs[0]; dropped.Nonce() != 1 {
+		t.Fatalf("dropped transaction nonce mismatch: have %d, want 1", dropped.Nonce())
+	}
+
+	// Dropping the first transaction of a sender moves its followers to the queue.
+	state.balances[payerAddr] = 10 * 21000
+	pool.demoteUnexecutables()
+	if have := pool.all.Count(); have != 1 {
+		t.Fatalf("pooled transactions mismatch: have %d, want 1", have)
+	}
+	var pending, queued int
+	for _, list := range pool.pending {
+		pending += list.Len()
+	}
+	for _, list := range pool.queue {
+		queued += list.Len()
+	}
+	if pending != 1 || queued != 0 {
+		t.Fatalf("pool content mismatch: have %d pending and %d queued, want 1 and 0", pending, queued)
+	}
+
+	// Queued transactions the payer can't pay for anymore are dropped on promotion.
+	late, _ := crypto.GenerateKey()
+	lateAddr := crypto.PubkeyToAddress(late.PublicKey)
+	state.balances[lateAddr] = 10
+	state.balances[payerAddr] = 2 * 10 * 21000
+	if _, err := pool.add(sponsoredTx(t, late, payer, 0, 10), false); err != nil {
+		t.Fatalf("transaction rejected: %v", err)
+	}
+	state.balances[payerAddr] = 10 * 21000
+	if promoted := pool.promoteExecutables([]types.Address{lateAddr}); len(promoted) != 0 {
+		t.Fatalf("promoted transactions mismatch: have %d, want 0", len(promoted))
+	}
+	if have := pool.all.Count(); have != 1 || len(pool.queue) != 0 {
+		t.Fatalf("pool content mismatch: have %d transactions, %d queued accounts", have, len(pool.queue))
+	}
+}
+
+// transferTx returns a dynamic fee transfer of the sender, costing it
+// feeCap * 21000 + 1.
+func transferTx(t *testing.T, sender *ecdsa.PrivateKey, nonce, feeCap uint64) *transaction.Transaction {
+	t.Helper()
+	from := crypto.PubkeyToAddress(sender.PublicKey)
+	to := types.Address{0x01}
+	tx, err := transaction.SignNewTx(sender, transaction.LatestSignerForChainID(big.NewInt(1)), &transaction.DynamicFeeTx{
+		ChainID:   uint256.NewInt(1),
+		Nonce:     nonce,
+		GasTipCap: uint256.NewInt(1),
+		GasFeeCap: uint256.NewInt(feeCap),
+		Gas:       21000,
+		To:        &to,
+		From:      &from,
+		Value:     uint256.NewInt(1),
+	})
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	return tx
+}
+
+// nonces returns the nonces of the transactions.
+func nonces(txs []*transaction.Transaction) []uint64 {
+	var nonces []uint64
+	for _, tx := range txs {
+		nonces = append(nonces, tx.Nonce())
+	}
+	return nonces
+}
+
+func TestQueuedReason(t *testing.T) {
+	tests := []struct {
+		name    string
+		nonce   uint64
+		balance uint64 // balance of the sender once the transaction is queued
+		baseFee uint64
+		pending bool // whether a pricier transaction with the same nonce is pending
+		want    error
+	}{
+		{"executable", 0, 1_000_000, 0, false, nil},
+		{"nonce gap", 2, 1_000_000, 0, false, ErrNonceGap},
+		{"insufficient balance", 0, 10 * 21000, 0, false, ErrInsufficientFunds},
+		{"below base fee", 0, 1_000_000, 20, false, ErrUnderpriced},
+		{"replacement underpriced", 0, 1_000_000, 0, true, ErrReplaceUnderpriced},
+	}
+	for _, tt := range tests {
+		key, _ := crypto.GenerateKey()
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		state := &testState{balances: map[types.Address]uint64{addr: 1_000_000}, nonces: map[types.Address]uint64{}}
+		pool := newTestPool(state)
+
+		tx := transferTx(t, key, tt.nonce, 10)
+		if tt.pending {
+			if _, err := pool.add(transferTx(t, key, tt.nonce, 30), false); err != nil {
+				t.Fatalf("%s: pending transaction rejected: %v", tt.name, err)
+			}
+			pool.promoteExecutables([]types.Address{addr})
+			pool.enqueueTx(tx.Hash(), tx, false, true)
+		} else if _, err := pool.add(tx, false); err != nil {
+			t.Fatalf("%s: transaction rejected: %v", tt.name, err)
+		}
+		state.balances[addr] = tt.balance
+		if tt.baseFee != 0 {
+			pool.priced.SetBaseFee(uint256.NewInt(tt.baseFee))
+		}
+
+		_, queued, reasons := pool.ContentFrom(addr)
+		if len(queued) != 1 || queued[0].Hash() != tx.Hash() {
+			t.Fatalf("%s: queued transactions mismatch: have %v, want [%d]", tt.name, nonces(queued), tt.nonce)
+		}
+		if err := reasons[tx.Hash()]; !errors.Is(err, tt.want) {
+			t.Errorf("%s: reason mismatch: have %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestQueuedReasonSponsored(t *testing.T) {
+	tests := []struct {
+		name    string
+		balance uint64 // balance of the fee payer once the transaction is queued
+		want    error
+	}{
+		{"executable", 10 * 21000, nil},
+		{"insufficient fee payer balance", 10*21000 - 1, ErrInsufficientFeePayerFunds},
+	}
+	for _, tt := range tests {
+		sender, _ := crypto.GenerateKey()
+		payer, _ := crypto.GenerateKey()
+		addr, payerAddr := crypto.PubkeyToAddress(sender.PublicKey), crypto.PubkeyToAddress(payer.PublicKey)
+		state := &testState{balances: map[types.Address]uint64{addr: 1, payerAddr: 10 * 21000}, nonces: map[types.Address]uint64{}}
+		pool := newTestPool(state)
+
+		tx := sponsoredTx(t, sender, payer, 0, 10)
+		if _, err := pool.add(tx, false); err != nil {
+			t.Fatalf("%s: transaction rejected: %v", tt.name, err)
+		}
+		state.balances[payerAddr] = tt.balance
+
+		// The sender balance only has to cover the value.
+		_, queued, reasons := pool.ContentFrom(addr)
+		if len(queued) != 1 || queued[0].Hash() != tx.Hash() {
+			t.Fatalf("%s: queued transactions mismatch: have %v, want [0]", tt.name, nonces(queued))
+		}
+		if err := reasons[tx.Hash()]; !errors.Is(err, tt.want) {
+			t.Errorf("%s: reason mismatch: have %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestContentFrom(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	state := &testState{balances: map[types.Address]uint64{addr: 1_000_000}, nonces: map[types.Address]uint64{}}
+	pool := newTestPool(state)
+
+	for _, nonce := range []uint64{0, 1, 3} {
+		if _, err := pool.add(transferTx(t, key, nonce, 10), false); err != nil {
+			t.Fatalf("transaction %d rejected: %v", nonce, err)
+		}
+	}
+	pool.promoteExecutables([]types.Address{addr})
+
+	pending, queued, reasons := pool.ContentFrom(addr)
+	if have := nonces(pending); len(have) != 2 || have[0] != 0 || have[1] != 1 {
+		t.Errorf("pending nonces mismatch: have %v, want [0 1]", have)
+	}
+	if have := nonces(queued); len(have) != 1 || have[0] != 3 {
+		t.Fatalf("queued nonces mismatch: have %v, want [3]", have)
+	}
+	// Only queued transactions have a reason.
+	if len(reasons) != 1 || !errors.Is(reasons[queued[0].Hash()], ErrNonceGap) {
+		t.Errorf("reasons mismatch: have %v, want a nonce gap for nonce 3", reasons)
+	}
+	if pending, queued, reasons := pool.ContentFrom(types.Address{0x02}); len(pending) != 0 || len(queued) != 0 || len(reasons) != 0 {
+		t.Errorf("unknown account content: %d pending, %d queued, %d reasons", len(pending), len(queued), len(reasons))
+	}
+}
+
+// checkEviction checks that the pool recorded a single eviction of the given
+// reason and nonces.
+func checkEviction(t *testing.T, pool *TxsPool, reason string, want ...uint64) {
+	t.Helper()
+	evictions := pool.takeEvictions()
+	if len(evictions) != 1 || evictions[0].Reason != reason {
+		t.Fatalf("evictions mismatch: have %v, want one of reason %s", evictions, reason)
+	}
+	have := nonces(evictions[0].Txs)
+	if len(have) != len(want) {
+		t.Fatalf("%s: evicted nonces mismatch: have %v, want %v", reason, have, want)
+	}
+	for i := range want {
+		if have[i] != want[i] {
+			t.Fatalf("%s: evicted nonces mismatch: have %v, want %v", reason, have, want)
+		}
+	}
+	if evictions := pool.takeEvictions(); len(evictions) != 0 {
+		t.Fatalf("evictions not cleared: %v", evictions)
+	}
+}
+
+func TestEvictPendingLimit(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	pool := newTestPool(&testState{balances: map[types.Address]uint64{addr: 1_000_000}, nonces: map[types.Address]uint64{}})
+	pool.config.AccountSlots, pool.config.GlobalSlots = 1, 2
+
+	for nonce := uint64(0); nonce < 4; nonce++ {
+		if _, err := pool.add(transferTx(t, key, nonce, 10), false); err != nil {
+			t.Fatalf("transaction %d rejected: %v", nonce, err)
+		}
+	}
+	if promoted := pool.promoteExecutables([]types.Address{addr}); len(promoted) != 4 {
+		t.Fatalf("promoted transactions mismatch: have %d, want 4", len(promoted))
+	}
+	pool.truncatePending()
+	checkEviction(t, pool, EvictPendingLimit, 3, 2)
+	if have := pool.pending[addr].Len(); have != 2 {
+		t.Errorf("pending transactions mismatch: have %d, want 2", have)
+	}
+}
+
+func TestEvictQueueLimit(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	pool := newTestPool(&testState{balances: map[types.Address]uint64{addr: 1_000_000}, nonces: map[types.Address]uint64{}})
+	pool.config.GlobalQueue = 2
+
+	for nonce := uint64(1); nonce <= 4; nonce++ {
+		if _, err := pool.add(transferTx(t, key, nonce, 10), false); err != nil {
+			t.Fatalf("transaction %d rejected: %v", nonce, err)
+		}
+	}
+	pool.truncateQueue()
+	checkEviction(t, pool, EvictQueueLimit, 4, 3)
+	if have := pool.queue[addr].Len(); have != 2 {
+		t.Errorf("queued transactions mismatch: have %d, want 2", have)
+	}
+}
+
+func TestEvictUnderpriced(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 4)
+	state := &testState{balances: map[types.Address]uint64{}, nonces: map[types.Address]uint64{}}
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		state.balances[crypto.PubkeyToAddress(keys[i].PublicKey)] = 1_000_000
+	}
+	pool := newTestPool(state)
+	pool.config.GlobalSlots, pool.config.GlobalQueue = 1, 1
+
+	cheap := transferTx(t, keys[0], 0, 10)
+	for i, tx := range []*transaction.Transaction{cheap, transferTx(t, keys[1], 0, 20)} {
+		if _, err := pool.add(tx, false); err != nil {
+			t.Fatalf("transaction %d rejected: %v", i, err)
+		}
+	}
+	// A full pool rejects transactions cheaper than all its own.
+	if _, err := pool.add(transferTx(t, keys[2], 0, 5), false); err != ErrUnderpriced {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrUnderpriced)
+	}
+	if evictions := pool.takeEvictions(); len(evictions) != 0 {
+		t.Fatalf("evictions for a rejected transaction: %v", evictions)
+	}
+	// A pricier one replaces the cheapest.
+	if _, err := pool.add(transferTx(t, keys[3], 0, 30), false); err != nil {
+		t.Fatalf("transaction rejected: %v", err)
+	}
+	if pool.all.Get(cheap.Hash()) != nil {
+		t.Fatalf("cheapest transaction kept")
+	}
+
+	// Evictions are announced once taken from the pool.
+	ch := make(chan common.EvictedTxsEvent, 1)
+	sub := event.GlobalEvent.Subscribe(ch)
+	defer sub.Unsubscribe()
+	sendEvictions(pool.takeEvictions())
+	select {
+	case ev := <-ch:
+		if ev.Reason != EvictUnderpriced || len(ev.Txs) != 1 || ev.Txs[0].Hash() != cheap.Hash() {
+			t.Fatalf("eviction mismatch: have %d transactions, reason %s", len(ev.Txs), ev.Reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("eviction not announced")
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package ancient implements the freezer, an append-only flat file store for
+// the finalized blocks moved out of the chain database.
+package ancient
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gofrs/flock"
+)
+
+// Kinds of the items stored for each frozen block, one table each.
+const (
+	Hashes       = "hashes"       // canonical block hash
+	Headers      = "headers"      // header in its database encoding
+	Bodies       = "bodies"       // body in its storage encoding
+	Transactions = "transactions" // raw transactions of the body
+	Receipts     = "receipts"     // receipts in their database encoding
+)
+
+// kinds lists the tables of the freezer along with their compression.
+var kinds = map[string]bool{
+	Hashes:       false,
+	Headers:      true,
+	Bodies:       false,
+	Transactions: true,
+	Receipts:     true,
+}
+
+// Kinds returns the kinds of the items stored for each frozen block, sorted.
+func Kinds() []string {
+	names := make([]string, 0, len(kinds))
+	for name := range kinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// maxFileSize is the size past which the data of a table goes to a new file.
+const maxFileSize = 2 * 1000 * 1000 * 1000
+
+var (
+	ErrOutOfBounds = errors.New("out of bounds")
+	ErrOutOfOrder  = errors.New("out of order insertion")
+	ErrUnknownKind = errors.New("unknown ancient kind")
+)
+
+// Freezer stores the frozen blocks in one table per kind of item, all tables
+// holding the same number of items.
+type Freezer struct {
+	frozen atomic.Uint64 // number of frozen blocks
+
+	writeLock    sync.Mutex
+	tables       map[string]*table
+	instanceLock *flock.Flock
+}
+
+// Open opens the freezer in datadir, creating it if needed, and drops the items
+// of the blocks which were only partially frozen.
+func Open(datadir string) (*Freezer, error) {
+	if err := os.MkdirAll(datadir, 0755); err != nil {
+		return nil, err
+	}
+	lock := flock.New(filepath.Join(datadir, "FLOCK"))
+	if locked, err := lock.TryLock(); err != nil {
+		return nil, err
+	} else if !locked {
+		return nil, fmt.Errorf("ancient store %s is in use by another process", datadir)
+	}
+
+	f := &Freezer{
+		tables:       make(map[string]*table),
+		instanceLock: lock,
+	}
+	frozen := ^uint64(0)
+	for name, compress := range kinds {
+		t, err := newTable(datadir, name, compress, maxFileSize)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		f.tables[name] = t
+		if t.items < frozen {
+			frozen = t.items
+		}
+	}
+	for _, t := range f.tables {
+		if err := t.truncate(frozen); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	f.frozen.Store(frozen)
+	return f, nil
+}
+
+// Ancients returns the number of frozen blocks.
+func (f *Freezer) Ancients() uint64 {
+	return f.frozen.Load()
+}
+
+// HasAncient reports whether the block with the given number is frozen.
+func (f *Freezer) HasAncient(number uint64) bool {
+	return number < f.frozen.Load()
+}
+
+// Ancient retrieves the item of the given kind of a frozen block.
+func (f *Freezer) Ancient(kind string, number uint64) ([]byte, error) {
+	t, ok := f.tables[kind]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKind, kind)
+	}
+	if number >= f.frozen.Load() {
+		return nil, fmt.Errorf("%w: %d blocks frozen, requested %d", ErrOutOfBounds, f.frozen.Load(), number)
+	}
+	return t.retrieve(number)
+}
+
+// Append freezes the block with the given number, which must directly follow
+// the frozen ones. Items must be given for all kinds.
+func (f *Freezer) Append(number uint64, items map[string][]byte) error {
+	f.writeLock.Lock()
+	defer f.writeLock.Unlock()
+
+	if frozen := f.frozen.Load(); number != frozen {
+		return fmt.Errorf("%w: %d blocks frozen, appending %d", ErrOutOfOrder, frozen, number)
+	}
+	for kind := range kinds {
+		if _, ok := items[kind]; !ok {
+			return fmt.Errorf("missing %s of block %d", kind, number)
+		}
+	}
+	for kind, blob := range items {
+		t, ok := f.tables[kind]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownKind, kind)
+		}
+		if err := t.append(number, blob); err != nil {
+			// Leave the tables in line, the failed block is not frozen.
+			for _, t := range f.tables {
+				t.truncate(number)
+			}
+			return err
+		}
+	}
+	f.frozen.Add(1)
+	return nil
+}
+
+// Size returns the total size of the freezer files per kind of item.
+func (f *Freezer) Size() (map[string]uint64, error) {
+	sizes := make(map[string]uint64, len(f.tables))
+	for kind, t := range f.tables {
+		size, err := t.size()
+		if err != nil {
+			return nil, err
+		}
+		sizes[kind] = size
+	}
+	return sizes, nil
+}
+
+// Sync flushes the freezer files to disk.
+func (f *Freezer) Sync() error {
+	f.writeLock.Lock()
+	defer f.writeLock.Unlock()
+
+	for _, t := range f.tables {
+		if err := t.sync(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the freezer files and releases the directory lock.
+func (f *Freezer) Close() error {
+	f.writeLock.Lock()
+	defer f.writeLock.Unlock()
+
+	var errs []error
+	for _, t := range f.tables {
+		if err := t.close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := f.instanceLock.Unlock(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package ancient
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testItem(i uint64) []byte {
+	return bytes.Repeat([]byte{byte(i)}, int(i%7)+3)
+}
+
+func TestTableRotation(t *testing.T) {
+	dir := t.TempDir()
+	for _, compress := range []bool{false, true} {
+		tab, err := newTable(dir, "test", compress, 20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := uint64(0); i < 50; i++ {
+			if err := tab.append(i, testItem(i)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := tab.append(51, testItem(51)); !errors.Is(err, ErrOutOfOrder) {
+			t.Fatalf("gap append: got %v, want %v", err, ErrOutOfOrder)
+		}
+		if tab.headId == 0 {
+			t.Fatal("data file not rotated")
+		}
+		tab.close()
+
+		tab, err = newTable(dir, "test", compress, 20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tab.items != 50 {
+			t.Fatalf("reopened with %d items, want 50", tab.items)
+		}
+		for i := uint64(0); i < 50; i++ {
+			blob, err := tab.retrieve(i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(blob, testItem(i)) {
+				t.Fatalf("item %d: got %x, want %x", i, blob, testItem(i))
+			}
+		}
+		if _, err := tab.retrieve(50); !errors.Is(err, ErrOutOfBounds) {
+			t.Fatalf("missing item: got %v, want %v", err, ErrOutOfBounds)
+		}
+		tab.close()
+		os.RemoveAll(dir)
+		os.MkdirAll(dir, 0755)
+	}
+}
+
+func TestTableRepair(t *testing.T) {
+	dir := t.TempDir()
+	tab, err := newTable(dir, "test", false, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := uint64(0); i < 10; i++ {
+		if err := tab.append(i, testItem(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tab.close()
+
+	// Cut the last item short, as an interrupted append would.
+	name := filepath.Join(dir, "test.0000.rdat")
+	stat, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(name, stat.Size()-1); err != nil {
+		t.Fatal(err)
+	}
+	tab, err = newTable(dir, "test", false, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tab.close()
+	if tab.items != 9 {
+		t.Fatalf("repaired to %d items, want 9", tab.items)
+	}
+	if err := tab.append(9, testItem(9)); err != nil {
+		t.Fatal(err)
+	}
+	blob, err := tab.retrieve(9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(blob, testItem(9)) {
+		t.Fatalf("item 9: got %x, want %x", blob, testItem(9))
+	}
+}
+
+func TestFreezer(t *testing.T) {
+	dir := t.TempDir()
+	f, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := func(i uint64) map[string][]byte {
+		m := make(map[string][]byte)
+		for kind := range kinds {
+			m[kind] = append([]byte(kind), testItem(i)...)
+		}
+		return m
+	}
+	for i := uint64(0); i < 5; i++ {
+		if err := f.Append(i, items(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := Open(dir); err == nil {
+		t.Fatal("opened a freezer in use")
+	}
+	// Leave one table ahead of the others, as a crash during Append would.
+	if err := f.tables[Headers].append(5, testItem(5)); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	if f, err = Open(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if f.Ancients() != 5 {
+		t.Fatalf("reopened with %d blocks, want 5", f.Ancients())
+	}
+	blob, err := f.Ancient(Receipts, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := items(3)[Receipts]; !bytes.Equal(blob, want) {
+		t.Fatalf("receipts of block 3: got %x, want %x", blob, want)
+	}
+	if _, err := f.Ancient(Headers, 5); !errors.Is(err, ErrOutOfBounds) {
+		t.Fatalf("unfrozen block: got %v, want %v", err, ErrOutOfBounds)
+	}
+}
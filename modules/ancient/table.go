@@ -0,0 +1,301 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package ancient
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/golang/snappy"
+)
+
+// indexEntrySize is the size of an index entry: the number of the data file
+// holding an item followed by the offset at which the item ends.
+const indexEntrySize = 8
+
+type indexEntry struct {
+	filenum uint32
+	offset  uint32
+}
+
+func (e *indexEntry) unmarshal(b []byte) {
+	e.filenum = binary.BigEndian.Uint32(b[:4])
+	e.offset = binary.BigEndian.Uint32(b[4:8])
+}
+
+func (e *indexEntry) marshal() []byte {
+	b := make([]byte, indexEntrySize)
+	binary.BigEndian.PutUint32(b[:4], e.filenum)
+	binary.BigEndian.PutUint32(b[4:], e.offset)
+	return b
+}
+
+// table is an append-only store of the items of one kind. Items are written to
+// data files of at most maxFileSize bytes each and located through an index of
+// fixed size entries: item i spans from the end of item i-1, or the start of
+// its data file, to the offset of index entry i+1. The first index entry only
+// marks the start of the first data file.
+type table struct {
+	lock        sync.RWMutex
+	name        string
+	path        string
+	compress    bool
+	maxFileSize uint32
+
+	index     *os.File
+	files     map[uint32]*os.File // data files, the head one included
+	headId    uint32              // number of the data file appended to
+	headBytes uint32              // size of the head data file
+	items     uint64              // number of items stored
+}
+
+func newTable(path, name string, compress bool, maxFileSize uint32) (*table, error) {
+	index, err := os.OpenFile(filepath.Join(path, name+".idx"), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	t := &table{
+		name:        name,
+		path:        path,
+		compress:    compress,
+		maxFileSize: maxFileSize,
+		index:       index,
+		files:       make(map[uint32]*os.File),
+	}
+	if err := t.repair(); err != nil {
+		t.close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// repair opens the data files and brings the index and the head data file back
+// in line after an unclean shutdown, dropping the items written only partially.
+func (t *table) repair() error {
+	stat, err := t.index.Stat()
+	if err != nil {
+		return err
+	}
+	size := stat.Size()
+	if size == 0 {
+		if _, err := t.index.Write((&indexEntry{}).marshal()); err != nil {
+			return err
+		}
+		size = indexEntrySize
+	}
+	size -= size % indexEntrySize
+
+	var last indexEntry
+	for ; ; size -= indexEntrySize {
+		buf := make([]byte, indexEntrySize)
+		if _, err := t.index.ReadAt(buf, size-indexEntrySize); err != nil {
+			return err
+		}
+		last.unmarshal(buf)
+		if size == indexEntrySize {
+			break
+		}
+		// The data is written before the index, so an entry pointing past the
+		// end of its data file belongs to an interrupted append.
+		stat, err := os.Stat(t.fileName(last.filenum))
+		if err == nil && stat.Size() >= int64(last.offset) {
+			break
+		}
+	}
+	if err := t.index.Truncate(size); err != nil {
+		return err
+	}
+	t.items = uint64(size/indexEntrySize) - 1
+	t.headId, t.headBytes = last.filenum, last.offset
+
+	for id := uint32(0); id <= t.headId; id++ {
+		f, err := os.OpenFile(t.fileName(id), os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return err
+		}
+		t.files[id] = f
+	}
+	// Files past the head one may only be left by an interrupted rotation and
+	// hold no indexed item.
+	for id := t.headId + 1; os.Remove(t.fileName(id)) == nil; id++ {
+	}
+	return t.files[t.headId].Truncate(int64(t.headBytes))
+}
+
+func (t *table) fileName(id uint32) string {
+	ext := "rdat"
+	if t.compress {
+		ext = "cdat"
+	}
+	return filepath.Join(t.path, fmt.Sprintf("%s.%04d.%s", t.name, id, ext))
+}
+
+// append stores the item, which must directly follow the stored ones.
+func (t *table) append(item uint64, blob []byte) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if item != t.items {
+		return fmt.Errorf("%w: %s has %d items, appending %d", ErrOutOfOrder, t.name, t.items, item)
+	}
+	if t.compress {
+		blob = snappy.Encode(nil, blob)
+	}
+	if uint64(t.headBytes)+uint64(len(blob)) > uint64(t.maxFileSize) && t.headBytes > 0 {
+		if err := t.files[t.headId].Sync(); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(t.fileName(t.headId+1), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return err
+		}
+		t.headId++
+		t.headBytes = 0
+		t.files[t.headId] = f
+	}
+	if _, err := t.files[t.headId].WriteAt(blob, int64(t.headBytes)); err != nil {
+		return err
+	}
+	t.headBytes += uint32(len(blob))
+
+	entry := indexEntry{filenum: t.headId, offset: t.headBytes}
+	if _, err := t.index.WriteAt(entry.marshal(), int64(t.items+1)*indexEntrySize); err != nil {
+		return err
+	}
+	t.items++
+	return nil
+}
+
+// retrieve returns the item with the given number.
+func (t *table) retrieve(item uint64) ([]byte, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if item >= t.items {
+		return nil, fmt.Errorf("%w: %s has %d items, requested %d", ErrOutOfBounds, t.name, t.items, item)
+	}
+	buf := make([]byte, 2*indexEntrySize)
+	if _, err := t.index.ReadAt(buf, int64(item)*indexEntrySize); err != nil {
+		return nil, err
+	}
+	var start, end indexEntry
+	start.unmarshal(buf[:indexEntrySize])
+	end.unmarshal(buf[indexEntrySize:])
+	if start.filenum != end.filenum {
+		start.offset = 0
+	}
+
+	blob := make([]byte, end.offset-start.offset)
+	if _, err := t.files[end.filenum].ReadAt(blob, int64(start.offset)); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if t.compress {
+		return snappy.Decode(nil, blob)
+	}
+	return blob, nil
+}
+
+// truncate drops the items past the first given number of items.
+func (t *table) truncate(items uint64) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if items >= t.items {
+		return nil
+	}
+	buf := make([]byte, indexEntrySize)
+	if _, err := t.index.ReadAt(buf, int64(items)*indexEntrySize); err != nil {
+		return err
+	}
+	var last indexEntry
+	last.unmarshal(buf)
+
+	if err := t.index.Truncate(int64(items+1) * indexEntrySize); err != nil {
+		return err
+	}
+	for id := last.filenum + 1; id <= t.headId; id++ {
+		t.files[id].Close()
+		delete(t.files, id)
+		if err := os.Remove(t.fileName(id)); err != nil {
+			return err
+		}
+	}
+	if err := t.files[last.filenum].Truncate(int64(last.offset)); err != nil {
+		return err
+	}
+	t.items = items
+	t.headId, t.headBytes = last.filenum, last.offset
+	return nil
+}
+
+// size returns the total size of the data and index files.
+func (t *table) size() (uint64, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	stat, err := t.index.Stat()
+	if err != nil {
+		return 0, err
+	}
+	total := uint64(stat.Size())
+	for id := range t.files {
+		if id == t.headId {
+			total += uint64(t.headBytes)
+			continue
+		}
+		stat, err := t.files[id].Stat()
+		if err != nil {
+			return 0, err
+		}
+		total += uint64(stat.Size())
+	}
+	return total, nil
+}
+
+func (t *table) sync() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if err := t.files[t.headId].Sync(); err != nil {
+		return err
+	}
+	return t.index.Sync()
+}
+
+func (t *table) close() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var errs []error
+	for _, f := range t.files {
+		if err := f.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	t.files = nil
+	if err := t.index.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
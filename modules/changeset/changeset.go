@@ -244,6 +244,27 @@ func Truncate(tx kv.RwTx, from uint64) error {
 	return nil
 }
 
+// PruneRange removes the changesets of blocks [from, to) from the bucket.
+func PruneRange(tx kv.RwTx, bucket string, from, to uint64) error {
+	c, err := tx.RwCursorDupSort(bucket)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	for k, _, err := c.Seek(modules.EncodeBlockNumber(from)); k != nil; k, _, err = c.NextNoDup() {
+		if err != nil {
+			return err
+		}
+		if binary.BigEndian.Uint64(k) >= to {
+			break
+		}
+		if err = c.DeleteCurrentDuplicates(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 var Mapper = map[string]struct {
 	IndexBucket   string
 	IndexChunkKey func([]byte, uint64) []byte
@@ -164,6 +164,50 @@ func TruncateRange(db kv.RwTx, bucket string, key []byte, to uint32) error {
 	})
 }
 
+// TruncateBelow64 - removes all values lower than `to` from the bitmap stored
+// under key. Shards are keyed by their maximum value, so every shard entirely
+// below `to` is deleted and only the first remaining one is rewritten.
+func TruncateBelow64(db kv.RwTx, bucket string, key []byte, to uint64) error {
+	c, err := db.RwCursor(bucket)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	for k, v, err := c.Seek(key); k != nil; k, v, err = c.Next() {
+		if err != nil {
+			return err
+		}
+		if !bytes.HasPrefix(k, key) || len(k) != len(key)+8 {
+			return nil
+		}
+		if binary.BigEndian.Uint64(k[len(key):]) < to {
+			if err := c.DeleteCurrent(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		bm := NewBitmap64()
+		defer ReturnToPool64(bm)
+		if _, err := bm.ReadFrom(bytes.NewReader(v)); err != nil {
+			return err
+		}
+		if bm.IsEmpty() || bm.Minimum() >= to {
+			return nil
+		}
+		bm.RemoveRange(0, to)
+		if bm.IsEmpty() {
+			return c.DeleteCurrent()
+		}
+		buf := bytes.NewBuffer(nil)
+		if _, err := bm.WriteTo(buf); err != nil {
+			return err
+		}
+		return c.Put(libcommon.Copy(k), buf.Bytes())
+	}
+	return nil
+}
+
 // Get - reading as much chunks as needed to satisfy [from, to] condition
 // join all chunks to 1 bitmap by Or operator
 func Get(db kv.Tx, bucket string, key []byte, from, to uint32) (*roaring.Bitmap, error) {
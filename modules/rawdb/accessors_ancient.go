@@ -0,0 +1,259 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/n42blockchain/N42/common/transaction"
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/log"
+	"github.com/n42blockchain/N42/modules"
+	"github.com/n42blockchain/N42/modules/ancient"
+)
+
+// ancients is the ancient store the block accessors fall back to for the data
+// moved out of the chain database.
+var ancients atomic.Pointer[ancient.Freezer]
+
+// SetAncientStore makes the block accessors read the frozen blocks from f, nil
+// disabling it.
+func SetAncientStore(f *ancient.Freezer) {
+	ancients.Store(f)
+}
+
+// AncientStore returns the ancient store in use, nil if there is none.
+func AncientStore() *ancient.Freezer {
+	return ancients.Load()
+}
+
+// readAncient retrieves the item of the given kind of a frozen canonical block,
+// nil if the block with the given hash is not frozen.
+func readAncient(kind string, hash types.Hash, number uint64) []byte {
+	f := ancients.Load()
+	if f == nil || !f.HasAncient(number) {
+		return nil
+	}
+	frozenHash, err := f.Ancient(ancient.Hashes, number)
+	if err != nil || !bytes.Equal(frozenHash, hash.Bytes()) {
+		return nil
+	}
+	data, err := f.Ancient(kind, number)
+	if err != nil {
+		log.Error("Failed to read ancient store", "kind", kind, "number", number, "err", err)
+		return nil
+	}
+	return data
+}
+
+// readAncientByNumber retrieves the item of the given kind of a frozen block,
+// nil if the block is not frozen.
+func readAncientByNumber(kind string, number uint64) []byte {
+	f := ancients.Load()
+	if f == nil || !f.HasAncient(number) {
+		return nil
+	}
+	data, err := f.Ancient(kind, number)
+	if err != nil {
+		log.Error("Failed to read ancient store", "kind", kind, "number", number, "err", err)
+		return nil
+	}
+	return data
+}
+
+// encodeRawTransactions packs the raw transactions of a block into a single
+// blob of length prefixed items.
+func encodeRawTransactions(txs [][]byte) []byte {
+	var enc []byte
+	for _, tx := range txs {
+		enc = binary.AppendUvarint(enc, uint64(len(tx)))
+		enc = append(enc, tx...)
+	}
+	return enc
+}
+
+func decodeRawTransactions(enc []byte) ([][]byte, error) {
+	var txs [][]byte
+	for len(enc) > 0 {
+		size, n := binary.Uvarint(enc)
+		if n <= 0 || uint64(len(enc)-n) < size {
+			return nil, fmt.Errorf("invalid ancient transactions")
+		}
+		txs = append(txs, enc[n:n+int(size)])
+		enc = enc[n+int(size):]
+	}
+	return txs, nil
+}
+
+// readRawTransactions retrieves the raw transactions of the body of the block
+// with the given hash, from the ancient store if the block is frozen.
+func readRawTransactions(db kv.Getter, hash types.Hash, number uint64, baseTxId uint64, amount uint32) ([][]byte, error) {
+	if data := readAncient(ancient.Transactions, hash, number); data != nil {
+		return decodeRawTransactions(data)
+	}
+	if amount == 0 {
+		return nil, nil
+	}
+	txs := make([][]byte, 0, amount)
+	if err := db.ForAmount(modules.BlockTx, modules.EncodeBlockNumber(baseTxId), amount, func(k, v []byte) error {
+		txs = append(txs, v)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return txs, nil
+}
+
+// readTransactions retrieves the transactions of the body of the block with
+// the given hash, from the ancient store if the block is frozen.
+func readTransactions(db kv.Getter, hash types.Hash, number uint64, baseTxId uint64, amount uint32) ([]*transaction.Transaction, error) {
+	if data := readAncient(ancient.Transactions, hash, number); data != nil {
+		raw, err := decodeRawTransactions(data)
+		if err != nil {
+			return nil, err
+		}
+		txs := make([]*transaction.Transaction, len(raw))
+		for i, v := range raw {
+			txs[i] = new(transaction.Transaction)
+			if err := txs[i].Unmarshal(v); err != nil {
+				return nil, err
+			}
+		}
+		return txs, nil
+	}
+	return CanonicalTransactions(db, baseTxId, amount)
+}
+
+// FreezeBlocks moves the canonical headers, bodies, transactions and receipts
+// of the blocks from the first unfrozen one up to, but excluding, block to out
+// of the chain database into the ancient store. Side chain blocks of the same
+// heights are dropped. It returns the number of frozen blocks.
+func FreezeBlocks(tx kv.RwTx, f *ancient.Freezer, to uint64) (uint64, error) {
+	from := f.Ancients()
+	// Clean up after an earlier run which froze blocks but did not get to
+	// commit their removal from the chain database.
+	for number := from; number > 0 && hasBlockData(tx, number-1); number-- {
+		if err := deleteFrozenBlock(tx, number-1); err != nil {
+			return 0, err
+		}
+	}
+	for number := from; number < to; number++ {
+		hash, err := ReadCanonicalHash(tx, number)
+		if err != nil {
+			return 0, err
+		}
+		if hash == (types.Hash{}) {
+			return 0, fmt.Errorf("canonical hash of block %d not found", number)
+		}
+		header := ReadHeaderRAW(tx, hash, number)
+		if len(header) == 0 {
+			return 0, fmt.Errorf("header of block %d not found", number)
+		}
+		body := ReadStorageBodyRAW(tx, hash, number)
+		if len(body) != 8+4 {
+			return 0, fmt.Errorf("body of block %d not found", number)
+		}
+		baseTxId, amount := binary.BigEndian.Uint64(body[:8]), binary.BigEndian.Uint32(body[8:])
+		if amount < 2 {
+			return 0, fmt.Errorf("body of block %d has too few transactions: %d", number, amount)
+		}
+		txs, err := readRawTransactions(tx, hash, number, baseTxId+1, amount-2)
+		if err != nil {
+			return 0, err
+		}
+		receipts, err := tx.GetOne(modules.Receipts, modules.EncodeBlockNumber(number))
+		if err != nil {
+			return 0, err
+		}
+		if err := f.Append(number, map[string][]byte{
+			ancient.Hashes:       hash.Bytes(),
+			ancient.Headers:      header,
+			ancient.Bodies:       body,
+			ancient.Transactions: encodeRawTransactions(txs),
+			ancient.Receipts:     receipts,
+		}); err != nil {
+			return 0, err
+		}
+	}
+	// The chain database is only cleaned up once the frozen data is on disk.
+	if err := f.Sync(); err != nil {
+		return 0, err
+	}
+	for number := from; number < to; number++ {
+		if err := deleteFrozenBlock(tx, number); err != nil {
+			return 0, err
+		}
+	}
+	return to - from, nil
+}
+
+// hasBlockData reports whether the chain database holds a header of a block
+// with the given number.
+func hasBlockData(tx kv.Tx, number uint64) bool {
+	c, err := tx.Cursor(modules.Headers)
+	if err != nil {
+		return false
+	}
+	defer c.Close()
+	prefix := modules.EncodeBlockNumber(number)
+	k, _, err := c.Seek(prefix)
+	return err == nil && bytes.HasPrefix(k, prefix)
+}
+
+// deleteFrozenBlock removes the headers, bodies and transactions of all blocks
+// with the given number, and the canonical receipts, from the chain database.
+func deleteFrozenBlock(tx kv.RwTx, number uint64) error {
+	prefix := modules.EncodeBlockNumber(number)
+	var keys [][]byte
+	if err := tx.ForPrefix(modules.BlockBody, prefix, func(k, v []byte) error {
+		keys = append(keys, types.CopyBytes(k))
+		if len(v) != 8+4 {
+			return nil
+		}
+		baseTxId, amount := binary.BigEndian.Uint64(v[:8]), binary.BigEndian.Uint32(v[8:])
+		for id := baseTxId; id < baseTxId+uint64(amount); id++ {
+			if err := tx.Delete(modules.BlockTx, modules.EncodeBlockNumber(id)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if err := tx.Delete(modules.BlockBody, k); err != nil {
+			return err
+		}
+	}
+	keys = keys[:0]
+	if err := tx.ForPrefix(modules.Headers, prefix, func(k, _ []byte) error {
+		keys = append(keys, types.CopyBytes(k))
+		return nil
+	}); err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if err := tx.Delete(modules.Headers, k); err != nil {
+			return err
+		}
+	}
+	return tx.Delete(modules.Receipts, prefix)
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/n42blockchain/N42/common/types"
 	"github.com/n42blockchain/N42/log"
 	"github.com/n42blockchain/N42/modules"
+	"github.com/n42blockchain/N42/modules/ancient"
 	"google.golang.org/protobuf/proto"
 
 	"github.com/holiman/uint256"
@@ -115,19 +116,23 @@ func DeleteHeaderNumber(db kv.Deleter, hash types.Hash) {
 	}
 }
 
-// ReadHeaderRAW retrieves a block header in its raw database encoding.
+// ReadHeaderRAW retrieves a block header in its raw database encoding, from the
+// ancient store if the block is frozen.
 func ReadHeaderRAW(db kv.Getter, hash types.Hash, number uint64) []byte {
 	data, err := db.GetOne(modules.Headers, modules.HeaderKey(number, hash))
 	if err != nil {
 		log.Error("ReadHeaderRAW failed", "err", err)
 	}
+	if len(data) == 0 {
+		return readAncient(ancient.Headers, hash, number)
+	}
 	return data
 }
 
 // HasHeader verifies the existence of a block header corresponding to the hash.
 func HasHeader(db kv.Has, hash types.Hash, number uint64) bool {
 	if has, err := db.Has(modules.Headers, modules.HeaderKey(number, hash)); !has || err != nil {
-		return false
+		return readAncient(ancient.Hashes, hash, number) != nil
 	}
 	return true
 }
@@ -215,6 +220,19 @@ func ReadHeadersByNumber(db kv.Tx, number uint64) ([]*block.Header, error) {
 		}
 		res = append(res, header)
 	}
+	if len(res) == 0 {
+		if data := readAncientByNumber(ancient.Headers, number); data != nil {
+			header := new(block.Header)
+			pbHeader := new(types_pb.Header)
+			if err := proto.Unmarshal(data, pbHeader); err != nil {
+				return nil, fmt.Errorf("invalid ancient block header RAW: number=%d, err=%w", number, err)
+			}
+			if err := header.FromProtoMessage(pbHeader); nil != err {
+				return nil, fmt.Errorf("invalid ancient block pbHeader: number=%d, err =%w", number, err)
+			}
+			res = append(res, header)
+		}
+	}
 	return res, nil
 }
 
@@ -267,14 +285,14 @@ func ReadStorageBodyRAW(db kv.Getter, hash types.Hash, number uint64) []byte {
 	if err != nil {
 		log.Error("ReadBodyRAW failed", "err", err)
 	}
+	if len(bodyRaw) == 0 {
+		return readAncient(ancient.Bodies, hash, number)
+	}
 	return bodyRaw
 }
 
 func ReadStorageBody(db kv.Getter, hash types.Hash, number uint64) (block.BodyForStorage, error) {
-	bodyRaw, err := db.GetOne(modules.BlockBody, modules.BlockBodyKey(number, hash))
-	if err != nil {
-		log.Error("ReadBodyRAW failed", "err", err)
-	}
+	bodyRaw := ReadStorageBodyRAW(db, hash, number)
 	if len(bodyRaw) != 8+4 {
 		return block.BodyForStorage{}, fmt.Errorf("invalid body raw")
 	}
@@ -400,7 +418,7 @@ func ReadCanonicalBodyWithTransactions(db kv.Getter, hash types.Hash, number uin
 		return nil
 	}
 	var err error
-	body.Txs, err = CanonicalTransactions(db, baseTxId, txAmount)
+	body.Txs, err = readTransactions(db, hash, number, baseTxId, txAmount)
 	if err != nil {
 		log.Error("failed ReadTransactionByHash", "hash", hash, "block", number, "err", err)
 		return nil
@@ -422,8 +440,10 @@ func ReadCanonicalBodyWithTransactions(db kv.Getter, hash types.Hash, number uin
 	return body
 }
 
+// RawTransactionsRange retrieves the raw user transactions of the canonical
+// blocks [from, to], in block order. The system transactions at the start and
+// end of each stored body are left out, as frozen blocks do not keep them.
 func RawTransactionsRange(db kv.Getter, from, to uint64) (res [][]byte, err error) {
-	blockKey := make([]byte, modules.NumberLength+types.HashLength)
 	encNum := make([]byte, 8)
 	for i := from; i < to+1; i++ {
 		binary.BigEndian.PutUint64(encNum, i)
@@ -435,26 +455,22 @@ func RawTransactionsRange(db kv.Getter, from, to uint64) (res [][]byte, err erro
 			continue
 		}
 
-		binary.BigEndian.PutUint64(blockKey, i)
-		copy(blockKey[modules.NumberLength:], hash)
-		bodyRaw, err := db.GetOne(modules.BlockBody, blockKey)
-		if err != nil {
-			return nil, err
-		}
+		bodyRaw := ReadStorageBodyRAW(db, types.BytesToHash(hash), i)
 		if len(bodyRaw) == 0 {
 			continue
 		}
 
 		baseTxId := binary.BigEndian.Uint64(bodyRaw[:8])
 		txAmount := binary.BigEndian.Uint32(bodyRaw[8:])
+		if txAmount < 2 {
+			return nil, fmt.Errorf("block body has too few txs amount: %d, %d", i, txAmount)
+		}
 
-		binary.BigEndian.PutUint64(encNum, baseTxId)
-		if err = db.ForAmount(modules.BlockTx, encNum, txAmount, func(k, v []byte) error {
-			res = append(res, v)
-			return nil
-		}); err != nil {
+		txs, err := readRawTransactions(db, types.BytesToHash(hash), i, baseTxId+1, txAmount-2)
+		if err != nil {
 			return nil, err
 		}
+		res = append(res, txs...)
 	}
 	return
 }
@@ -675,7 +691,7 @@ func TruncateTd(tx kv.RwTx, blockFrom uint64) error {
 // to a block.
 func HasReceipts(db kv.Has, number uint64) bool {
 	if has, err := db.Has(modules.Receipts, modules.EncodeBlockNumber(number)); !has || err != nil {
-		return false
+		return len(readAncientByNumber(ancient.Receipts, number)) > 0
 	}
 	return true
 }
@@ -689,6 +705,9 @@ func ReadRawReceipts(db kv.Tx, blockNum uint64) block.Receipts {
 	if err != nil {
 		log.Error("ReadRawReceipts failed", "err", err)
 	}
+	if len(data) == 0 {
+		data = readAncientByNumber(ancient.Receipts, blockNum)
+	}
 	if len(data) == 0 {
 		return nil
 	}
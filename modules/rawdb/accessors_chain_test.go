@@ -17,10 +17,18 @@
 package rawdb
 
 import (
+	"bytes"
+	"context"
+	"testing"
+
 	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
 	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	log2 "github.com/ledgerwatch/log/v3"
+	"github.com/n42blockchain/N42/common/block"
 	"github.com/n42blockchain/N42/common/types"
-	"testing"
+	"github.com/n42blockchain/N42/modules"
 )
 
 // Tests block total difficulty storage and retrieval operations.
@@ -86,3 +94,83 @@ func TestTdStorage(t *testing.T) {
 		t.Fatal("ReadTd returned nil")
 	}
 }
+
+// Tests that a transaction range holds the user transactions of the canonical
+// blocks only, without the system transaction slots of their bodies.
+func TestRawTransactionsRange(t *testing.T) {
+	modules.AstInit()
+	kv.ChaindataTablesCfg = modules.AstTableCfg
+	db := mdbx.NewMDBX(log2.New()).InMem(t.TempDir()).Label(kv.ChainDB).MustOpen()
+	defer db.Close()
+
+	blocks := [][][]byte{
+		{[]byte("tx1a"), []byte("tx1b")},
+		nil,
+		{[]byte("tx3a")},
+	}
+	if err := db.Update(context.Background(), func(tx kv.RwTx) error {
+		for i, txs := range blocks {
+			number, hash := uint64(i+1), types.Hash{byte(i + 1)}
+			if _, _, err := WriteRawBody(tx, hash, number, &block.RawBody{Transactions: txs}); err != nil {
+				return err
+			}
+			if err := WriteCanonicalHash(tx, hash, number); err != nil {
+				return err
+			}
+		}
+		// A side chain body is not part of the range.
+		_, _, err := WriteRawBody(tx, types.Hash{0xff}, 2, &block.RawBody{Transactions: [][]byte{[]byte("side")}})
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		from, to uint64
+		want     []string
+	}{
+		{1, 3, []string{"tx1a", "tx1b", "tx3a"}},
+		{1, 1, []string{"tx1a", "tx1b"}},
+		{2, 2, nil},
+		{2, 5, []string{"tx3a"}},
+		{4, 5, nil},
+	}
+	if err := db.View(context.Background(), func(tx kv.Tx) error {
+		for _, tt := range tests {
+			txs, err := RawTransactionsRange(tx, tt.from, tt.to)
+			if err != nil {
+				return err
+			}
+			if len(txs) != len(tt.want) {
+				t.Errorf("range [%d, %d]: have %d txs, want %d", tt.from, tt.to, len(txs), len(tt.want))
+				continue
+			}
+			for i, want := range tt.want {
+				if !bytes.Equal(txs[i], []byte(want)) {
+					t.Errorf("range [%d, %d]: tx %d is %q, want %q", tt.from, tt.to, i, txs[i], want)
+				}
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A body without room for its system transactions is corrupt.
+	if err := db.Update(context.Background(), func(tx kv.RwTx) error {
+		if err := WriteBodyForStorage(tx, types.Hash{4}, 4, &block.BodyForStorage{BaseTxId: 100, TxAmount: 1}); err != nil {
+			return err
+		}
+		return WriteCanonicalHash(tx, types.Hash{4}, 4)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.View(context.Background(), func(tx kv.Tx) error {
+		if _, err := RawTransactionsRange(tx, 1, 4); err == nil {
+			t.Errorf("corrupt body: have no error")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
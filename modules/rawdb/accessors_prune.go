@@ -0,0 +1,137 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/n42blockchain/N42/modules"
+)
+
+// prunedData names the data pruned along with each table tracked in
+// modules.PruneProgress.
+var prunedData = map[string]string{
+	modules.AccountChangeSet: "state history",
+	modules.Receipts:         "receipts",
+	modules.TxLookup:         "transaction index",
+}
+
+// PrunedError is returned when the requested data of a block is older than the
+// retention window of the node and has been pruned.
+type PrunedError struct {
+	Table         string // table tracking the pruned data
+	Number        uint64 // requested block
+	AvailableFrom uint64 // first block whose data is retained
+}
+
+func (e *PrunedError) Error() string {
+	return fmt.Sprintf("%s of block %d has been pruned, available from block %d", prunedData[e.Table], e.Number, e.AvailableFrom)
+}
+
+// ErrorCode implements jsonrpc.Error.
+func (e *PrunedError) ErrorCode() int { return -32000 }
+
+// ReadPruneProgress retrieves the first block whose data of the given table has
+// not been pruned, zero if the table was never pruned.
+func ReadPruneProgress(db kv.Getter, table string) (uint64, error) {
+	data, err := db.GetOne(modules.PruneProgress, []byte(table))
+	if err != nil {
+		return 0, err
+	}
+	if len(data) == 0 {
+		return 0, nil
+	}
+	return modules.DecodeBlockNumber(data)
+}
+
+// WritePruneProgress stores the first block whose data of the given table has
+// not been pruned.
+func WritePruneProgress(db kv.Putter, table string, from uint64) error {
+	return db.Put(modules.PruneProgress, []byte(table), modules.EncodeBlockNumber(from))
+}
+
+// CheckPruned returns a *PrunedError if the data of the given table at block
+// number has been pruned.
+func CheckPruned(db kv.Getter, table string, number uint64) error {
+	from, err := ReadPruneProgress(db, table)
+	if err != nil {
+		return err
+	}
+	if number < from {
+		return &PrunedError{Table: table, Number: number, AvailableFrom: from}
+	}
+	return nil
+}
+
+// PruneReceipts removes the receipts and transaction logs of blocks [from, to).
+func PruneReceipts(db kv.RwTx, from, to uint64) error {
+	for _, table := range []string{modules.Receipts, modules.Log} {
+		if err := pruneBlockRange(db, table, from, to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pruneBlockRange deletes the entries of a table keyed by block number that
+// belong to blocks [from, to), through the cursor walking them.
+func pruneBlockRange(db kv.RwTx, table string, from, to uint64) error {
+	c, err := db.RwCursor(table)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	for k, _, err := c.Seek(modules.EncodeBlockNumber(from)); k != nil; k, _, err = c.Next() {
+		if err != nil {
+			return err
+		}
+		if binary.BigEndian.Uint64(k) >= to {
+			return nil
+		}
+		if err := c.DeleteCurrent(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PruneTxLookupEntries removes the transaction hash lookups of the canonical
+// blocks [from, to).
+func PruneTxLookupEntries(db kv.RwTx, from, to uint64) error {
+	for number := from; number < to; number++ {
+		hash, err := ReadCanonicalHash(db, number)
+		if err != nil {
+			return err
+		}
+		body, baseTxId, txAmount := ReadBody(db, hash, number)
+		if body == nil {
+			continue
+		}
+		txs, err := readTransactions(db, hash, number, baseTxId, txAmount)
+		if err != nil {
+			return err
+		}
+		for _, tx := range txs {
+			if err := DeleteTxLookupEntry(db, tx.Hash()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
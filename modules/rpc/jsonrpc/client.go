@@ -199,6 +199,15 @@ func (c *Client) SupportedModules() (map[string]string, error) {
 	return result, err
 }
 
+// SupportedMethods returns the methods the server exposes, keyed by namespace.
+func (c *Client) SupportedMethods() (map[string][]string, error) {
+	var result map[string][]string
+	ctx, cancel := context.WithTimeout(context.Background(), subscribeTimeout)
+	defer cancel()
+	err := c.CallContext(ctx, &result, "rpc_methods")
+	return result, err
+}
+
 func (c *Client) Close() {
 	if c.isHTTP {
 		return
@@ -21,6 +21,7 @@ import (
 	mapset "github.com/deckarep/golang-set"
 	"github.com/n42blockchain/N42/log"
 	"io"
+	"sort"
 	"sync/atomic"
 )
 
@@ -108,3 +109,21 @@ func (s *RPCService) Modules() map[string]string {
 	}
 	return modules
 }
+
+// Methods returns the callable methods of every registered service, keyed by
+// namespace, which lets clients such as the console discover the API.
+func (s *RPCService) Methods() map[string][]string {
+	s.server.services.mu.Lock()
+	defer s.server.services.mu.Unlock()
+
+	methods := make(map[string][]string)
+	for name, svc := range s.server.services.services {
+		list := make([]string, 0, len(svc.callbacks))
+		for method := range svc.callbacks {
+			list = append(list, method)
+		}
+		sort.Strings(list)
+		methods[name] = list
+	}
+	return methods
+}
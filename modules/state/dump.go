@@ -0,0 +1,162 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/n42blockchain/N42/common/account"
+	"github.com/n42blockchain/N42/common/hexutil"
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/modules"
+)
+
+// DumpAccount is an account of a state dump. Storage is keyed by plain slots.
+type DumpAccount struct {
+	Address  types.Address         `json:"address"`
+	Balance  string                `json:"balance"`
+	Nonce    uint64                `json:"nonce"`
+	CodeHash types.Hash            `json:"codeHash"`
+	Code     hexutil.Bytes         `json:"code,omitempty"`
+	Storage  map[types.Hash]string `json:"storage,omitempty"`
+	// NextStorage is the slot to continue the storage from, nil if it is
+	// complete.
+	NextStorage *types.Hash `json:"nextStorage,omitempty"`
+}
+
+// DumpConfig selects the accounts of a dump and what is included with them.
+type DumpConfig struct {
+	Start          types.Address // first address to dump
+	Max            int           // maximum number of accounts, zero for no limit
+	ExcludeCode    bool
+	ExcludeStorage bool
+	StorageStart   types.Hash // first storage slot of account Start
+	MaxStorage     int        // maximum number of storage slots per account, zero for no limit
+}
+
+// Dumper iterates the accounts of the state after a block, read from the plain
+// state and its history.
+type Dumper struct {
+	tx      kv.Tx
+	blockNr uint64
+}
+
+// NewDumper creates a dumper of the state after block blockNr.
+func NewDumper(tx kv.Tx, blockNr uint64) *Dumper {
+	return &Dumper{tx: tx, blockNr: blockNr}
+}
+
+// Dump calls f for the accounts selected by cfg, in address order. It returns
+// the address of the next account if the dump stopped at cfg.Max accounts.
+func (d *Dumper) Dump(cfg DumpConfig, f func(*DumpAccount) error) (*types.Address, error) {
+	var (
+		next  *types.Address
+		count int
+	)
+	err := WalkAsOfAccounts(d.tx, cfg.Start, d.blockNr+1, func(k, v []byte) (bool, error) {
+		if cfg.Max > 0 && count == cfg.Max {
+			addr := types.BytesToAddress(k)
+			next = &addr
+			return false, nil
+		}
+		var acc account.StateAccount
+		if err := acc.DecodeForStorage(v); err != nil {
+			return false, err
+		}
+		dump, err := d.dumpAccount(types.BytesToAddress(k), &acc, cfg)
+		if err != nil {
+			return false, err
+		}
+		if err := f(dump); err != nil {
+			return false, err
+		}
+		count++
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
+func (d *Dumper) dumpAccount(addr types.Address, acc *account.StateAccount, cfg DumpConfig) (*DumpAccount, error) {
+	// Contracts keep their code hash apart from the account encoding.
+	if acc.Incarnation > 0 && acc.IsEmptyCodeHash() {
+		codeHash, err := d.tx.GetOne(modules.PlainContractCode, modules.PlainGenerateStoragePrefix(addr[:], acc.Incarnation))
+		if err != nil {
+			return nil, err
+		}
+		if len(codeHash) > 0 {
+			acc.CodeHash = types.BytesToHash(codeHash)
+		}
+	}
+	dump := &DumpAccount{
+		Address:  addr,
+		Balance:  acc.Balance.ToBig().String(),
+		Nonce:    acc.Nonce,
+		CodeHash: acc.CodeHash,
+	}
+	if !cfg.ExcludeCode && !bytes.Equal(acc.CodeHash[:], emptyCodeHash) {
+		code, err := d.tx.GetOne(modules.Code, acc.CodeHash[:])
+		if err != nil {
+			return nil, err
+		}
+		dump.Code = code
+	}
+	if !cfg.ExcludeStorage && acc.Incarnation > 0 {
+		var start types.Hash
+		if addr == cfg.Start {
+			start = cfg.StorageStart
+		}
+		storage := make(map[types.Hash]string)
+		if err := d.ForEachStorage(addr, acc.Incarnation, start, 0, func(key types.Hash, value *uint256.Int) bool {
+			if cfg.MaxStorage > 0 && len(storage) == cfg.MaxStorage {
+				dump.NextStorage = &key
+				return false
+			}
+			storage[key] = value.Hex()
+			return true
+		}); err != nil {
+			return nil, err
+		}
+		if len(storage) > 0 {
+			dump.Storage = storage
+		}
+	}
+	return dump, nil
+}
+
+// ForEachStorage calls f for the non-empty storage slots of an account from
+// slot start on, at most max of them unless max is zero, until f returns false.
+func (d *Dumper) ForEachStorage(addr types.Address, incarnation uint16, start types.Hash, max int, f func(key types.Hash, value *uint256.Int) bool) error {
+	var count int
+	return WalkAsOfStorage(d.tx, addr, incarnation, start, d.blockNr+1, func(kAddr, kLoc, v []byte) (bool, error) {
+		if !bytes.Equal(kAddr, addr[:]) {
+			return false, nil
+		}
+		if len(v) == 0 {
+			return true, nil
+		}
+		if max > 0 && count == max {
+			return false, nil
+		}
+		count++
+		return f(types.BytesToHash(kLoc), new(uint256.Int).SetBytes(v)), nil
+	})
+}
@@ -0,0 +1,151 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"context"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
+	log2 "github.com/ledgerwatch/log/v3"
+	"github.com/n42blockchain/N42/common/account"
+	"github.com/n42blockchain/N42/common/crypto"
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/modules"
+)
+
+var (
+	dumpEOA      = types.Address{1}
+	dumpContract = types.Address{2}
+	dumpLast     = types.Address{3}
+	dumpCode     = []byte{0x60, 0x00}
+)
+
+// newDumpTestDB writes two accounts and a contract with storage slots 1 to 5
+// holding their own slot number.
+func newDumpTestDB(t *testing.T) kv.RwDB {
+	modules.AstInit()
+	kv.ChaindataTablesCfg = modules.AstTableCfg
+	db := mdbx.NewMDBX(log2.New()).InMem(t.TempDir()).Label(kv.ChainDB).MustOpen()
+	t.Cleanup(db.Close)
+
+	if err := db.Update(context.Background(), func(tx kv.RwTx) error {
+		w := NewPlainStateWriterNoHistory(tx)
+		for _, addr := range []types.Address{dumpEOA, dumpLast} {
+			acc := account.NewAccount()
+			acc.Balance.SetUint64(uint64(addr[0]))
+			if err := w.UpdateAccountData(addr, &account.StateAccount{}, &acc); err != nil {
+				return err
+			}
+		}
+		contract := account.NewAccount()
+		contract.Incarnation = 1
+		contract.CodeHash = crypto.Keccak256Hash(dumpCode)
+		if err := w.UpdateAccountData(dumpContract, &account.StateAccount{}, &contract); err != nil {
+			return err
+		}
+		if err := w.UpdateAccountCode(dumpContract, 1, contract.CodeHash, dumpCode); err != nil {
+			return err
+		}
+		for slot := uint64(1); slot <= 5; slot++ {
+			key := types.Hash{31: byte(slot)}
+			if err := w.WriteAccountStorage(dumpContract, 1, &key, uint256.NewInt(0), uint256.NewInt(slot)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestDump(t *testing.T) {
+	db := newDumpTestDB(t)
+	tests := []struct {
+		name     string
+		cfg      DumpConfig
+		accounts []types.Address
+		next     *types.Address
+		slots    []byte // storage slots of the contract
+		nextSlot byte   // zero if the storage is complete
+		code     bool
+	}{
+		{"all", DumpConfig{}, []types.Address{dumpEOA, dumpContract, dumpLast}, nil, []byte{1, 2, 3, 4, 5}, 0, true},
+		{"max", DumpConfig{Max: 2}, []types.Address{dumpEOA, dumpContract}, &dumpLast, []byte{1, 2, 3, 4, 5}, 0, true},
+		{"start", DumpConfig{Start: dumpContract}, []types.Address{dumpContract, dumpLast}, nil, []byte{1, 2, 3, 4, 5}, 0, true},
+		{"exclude", DumpConfig{ExcludeCode: true, ExcludeStorage: true}, []types.Address{dumpEOA, dumpContract, dumpLast}, nil, nil, 0, false},
+		{"storage limit", DumpConfig{MaxStorage: 2}, []types.Address{dumpEOA, dumpContract, dumpLast}, nil, []byte{1, 2}, 3, true},
+		{"storage page", DumpConfig{Start: dumpContract, Max: 1, StorageStart: types.Hash{31: 3}, MaxStorage: 2}, []types.Address{dumpContract}, &dumpLast, []byte{3, 4}, 5, true},
+		{"storage last page", DumpConfig{Start: dumpContract, Max: 1, StorageStart: types.Hash{31: 5}, MaxStorage: 2}, []types.Address{dumpContract}, &dumpLast, []byte{5}, 0, true},
+		// The storage start only applies to the start account.
+		{"storage start elsewhere", DumpConfig{StorageStart: types.Hash{31: 5}}, []types.Address{dumpEOA, dumpContract, dumpLast}, nil, []byte{1, 2, 3, 4, 5}, 0, true},
+	}
+	for _, tt := range tests {
+		if err := db.View(context.Background(), func(tx kv.Tx) error {
+			var dumped []*DumpAccount
+			next, err := NewDumper(tx, 0).Dump(tt.cfg, func(acc *DumpAccount) error {
+				dumped = append(dumped, acc)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if len(dumped) != len(tt.accounts) {
+				t.Fatalf("%s: have %d accounts, want %d", tt.name, len(dumped), len(tt.accounts))
+			}
+			for i, acc := range dumped {
+				if acc.Address != tt.accounts[i] {
+					t.Errorf("%s: account %d is %v, want %v", tt.name, i, acc.Address, tt.accounts[i])
+				}
+			}
+			if (next == nil) != (tt.next == nil) || next != nil && *next != *tt.next {
+				t.Errorf("%s: have next %v, want %v", tt.name, next, tt.next)
+			}
+			for _, acc := range dumped {
+				if acc.Address != dumpContract {
+					if acc.Storage != nil || acc.Code != nil || acc.Balance != uint256.NewInt(uint64(acc.Address[0])).ToBig().String() {
+						t.Errorf("%s: unexpected account %+v", tt.name, acc)
+					}
+					continue
+				}
+				if (acc.Code != nil) != tt.code {
+					t.Errorf("%s: have code %x, want code %v", tt.name, acc.Code, tt.code)
+				}
+				if len(acc.Storage) != len(tt.slots) {
+					t.Errorf("%s: have %d slots, want %d", tt.name, len(acc.Storage), len(tt.slots))
+				}
+				for _, slot := range tt.slots {
+					if v, ok := acc.Storage[types.Hash{31: slot}]; !ok || v != uint256.NewInt(uint64(slot)).Hex() {
+						t.Errorf("%s: slot %d is %q", tt.name, slot, v)
+					}
+				}
+				switch {
+				case tt.nextSlot == 0 && acc.NextStorage != nil:
+					t.Errorf("%s: have next slot %v, want none", tt.name, acc.NextStorage)
+				case tt.nextSlot != 0 && (acc.NextStorage == nil || *acc.NextStorage != types.Hash{31: tt.nextSlot}):
+					t.Errorf("%s: have next slot %v, want %d", tt.name, acc.NextStorage, tt.nextSlot)
+				}
+			}
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
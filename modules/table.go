@@ -112,6 +112,9 @@ const (
 
 	Stake = "Stake" // stakes   ast_stake -> bytes
 
+	// PruneProgress tracks how far the history of a table was pruned.
+	// table_name -> block_num_u64 of the first retained block
+	PruneProgress = "PruneProgress"
 )
 
 const (
@@ -153,6 +156,7 @@ var astTables = []string{
 	SignersDB,
 	PoaSnapshot,
 	Sequence,
+	PruneProgress,
 
 	Reward,
 	Deposit,
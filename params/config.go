@@ -75,6 +75,41 @@ var (
 	// TestnetChainConfig contains the chain parameters to run a node on the Test network.
 	TestnetChainConfig = readChainSpec("chainspecs/testnet.json")
 
+	// DevChainConfig contains the chain parameters of the single-node developer
+	// network. Every fork, Beijing included, is active from genesis; the node
+	// signs its own blocks with well-known development verifier keys.
+	DevChainConfig = &ChainConfig{
+		ChainName:             networkname.DevChainName,
+		ChainID:               big.NewInt(1337),
+		Consensus:             AposConsensu,
+		HomesteadBlock:        big.NewInt(0),
+		TangerineWhistleBlock: big.NewInt(0),
+		SpuriousDragonBlock:   big.NewInt(0),
+		ByzantiumBlock:        big.NewInt(0),
+		ConstantinopleBlock:   big.NewInt(0),
+		PetersburgBlock:       big.NewInt(0),
+		IstanbulBlock:         big.NewInt(0),
+		MuirGlacierBlock:      big.NewInt(0),
+		BerlinBlock:           big.NewInt(0),
+		LondonBlock:           big.NewInt(0),
+		ArrowGlacierBlock:     big.NewInt(0),
+		GrayGlacierBlock:      big.NewInt(0),
+		ShanghaiBlock:         big.NewInt(0),
+		CancunBlock:           big.NewInt(0),
+		PragueTime:            big.NewInt(0),
+		NanoBlock:             big.NewInt(0),
+		MoranBlock:            big.NewInt(0),
+		BeijingBlock:          big.NewInt(0),
+		ForkIDBlock:           big.NewInt(0),
+		SakuragiBlock:         big.NewInt(0),
+		Apos: &APosConfig{
+			Period:      0,
+			Epoch:       30000,
+			RewardEpoch: 10800,
+			RewardLimit: big.NewInt(500000000000000000),
+		},
+	}
+
 	TestChainConfig = &ChainConfig{
 		ChainID:               big.NewInt(1),
 		Consensus:             EtHashConsensus,
@@ -146,9 +181,11 @@ type ChainConfig struct {
 	//BrunoBlock      *big.Int    `json:"brunoBlock,omitempty" toml:",omitempty"`      // brunoBlock switch block (nil = no fork, 0 = already activated)
 	//EulerBlock      *big.Int    `json:"eulerBlock,omitempty" toml:",omitempty"`      // eulerBlock switch block (nil = no fork, 0 = already activated)
 	//GibbsBlock      *big.Int    `json:"gibbsBlock,omitempty" toml:",omitempty"`      // gibbsBlock switch block (nil = no fork, 0 = already activated)
-	NanoBlock    *big.Int `json:"nanoBlock,omitempty" toml:",omitempty"`    // nanoBlock switch block (nil = no fork, 0 = already activated)
-	MoranBlock   *big.Int `json:"moranBlock,omitempty" toml:",omitempty"`   // moranBlock switch block (nil = no fork, 0 = already activated)
-	BeijingBlock *big.Int `json:"beijingBlock,omitempty" toml:",omitempty"` // beijingBlock switch block (nil = no fork, 0 = already activated)
+	NanoBlock     *big.Int `json:"nanoBlock,omitempty" toml:",omitempty"`     // nanoBlock switch block (nil = no fork, 0 = already activated)
+	MoranBlock    *big.Int `json:"moranBlock,omitempty" toml:",omitempty"`    // moranBlock switch block (nil = no fork, 0 = already activated)
+	BeijingBlock  *big.Int `json:"beijingBlock,omitempty" toml:",omitempty"`  // beijingBlock switch block (nil = no fork, 0 = already activated)
+	ForkIDBlock   *big.Int `json:"forkIdBlock,omitempty" toml:",omitempty"`   // Network upgrade to EIP-2124 fork IDs in the ENR, status and gossip digests (nil = no fork, 0 = already activated)
+	SakuragiBlock *big.Int `json:"sakuragiBlock,omitempty" toml:",omitempty"` // Network upgrade to fee-delegated Sakuragi transactions (nil = no fork, 0 = already activated)
 	//Apos         *AposConfig `json:"apos,omitempty"`
 
 	// Gnosis Chain fork blocks
@@ -157,6 +194,9 @@ type ChainConfig struct {
 	Eip1559FeeCollector           *types.Address `json:"eip1559FeeCollector,omitempty"`           // (Optional) Address where burnt EIP-1559 fees go to
 	Eip1559FeeCollectorTransition *big.Int       `json:"eip1559FeeCollectorTransition,omitempty"` // (Optional) Block from which burnt EIP-1559 fees go to the Eip1559FeeCollector
 
+	// (Optional) EIP-1559 base fee parameters, each entry applying from its fork block on.
+	BaseFeeSchedule []*BaseFeeConfig `json:"baseFeeSchedule,omitempty" toml:",omitempty"`
+
 	// Various consensus engines
 	Ethash *EthashConfig `json:"ethash,omitempty"`
 	Clique *CliqueConfig `json:"clique,omitempty"`
@@ -289,6 +329,69 @@ func (c *BorConfig) calculateBorConfigHelper(field map[string]uint64, number uin
 	return field[keys[len(keys)-1]]
 }
 
+// BaseFeeConfig holds the EIP-1559 base fee parameters enforced from Block on.
+// Zero values inherit the value of the previous entry, or the protocol default.
+type BaseFeeConfig struct {
+	Block                    *big.Int `json:"block"`
+	ElasticityMultiplier     uint64   `json:"elasticityMultiplier,omitempty"`     // Ratio between the gas limit and the gas target
+	BaseFeeChangeDenominator uint64   `json:"baseFeeChangeDenominator,omitempty"` // Bounds the amount the base fee can change between blocks
+	MinBaseFee               *big.Int `json:"minBaseFee,omitempty"`               // Lower bound of the base fee
+}
+
+// String implements the fmt.Stringer interface.
+func (c *BaseFeeConfig) String() string {
+	return fmt.Sprintf("{Block: %v, Elasticity: %v, ChangeDenominator: %v, MinBaseFee: %v}",
+		c.Block,
+		c.ElasticityMultiplier,
+		c.BaseFeeChangeDenominator,
+		c.MinBaseFee,
+	)
+}
+
+// BaseFeeConfig returns the base fee parameters in force at the given block number.
+func (c *ChainConfig) BaseFeeConfig(num uint64) *BaseFeeConfig {
+	cfg := &BaseFeeConfig{
+		Block:                    new(big.Int),
+		ElasticityMultiplier:     ElasticityMultiplier,
+		BaseFeeChangeDenominator: BaseFeeChangeDenominator,
+		MinBaseFee:               new(big.Int),
+	}
+	for _, entry := range c.BaseFeeSchedule {
+		if !isForked(entry.Block, num) {
+			break
+		}
+		cfg.Block = entry.Block
+		if entry.ElasticityMultiplier != 0 {
+			cfg.ElasticityMultiplier = entry.ElasticityMultiplier
+		}
+		if entry.BaseFeeChangeDenominator != 0 {
+			cfg.BaseFeeChangeDenominator = entry.BaseFeeChangeDenominator
+		}
+		if entry.MinBaseFee != nil {
+			cfg.MinBaseFee = entry.MinBaseFee
+		}
+	}
+	return cfg
+}
+
+// checkBaseFeeSchedule checks that the base fee schedule is ordered by block.
+func (c *ChainConfig) checkBaseFeeSchedule() error {
+	var last *big.Int
+	for i, entry := range c.BaseFeeSchedule {
+		if entry == nil || entry.Block == nil {
+			return fmt.Errorf("base fee schedule entry %d has no block", i)
+		}
+		if last != nil && last.Cmp(entry.Block) >= 0 {
+			return fmt.Errorf("unsupported base fee schedule ordering: entry at %v follows entry at %v", entry.Block, last)
+		}
+		if entry.MinBaseFee != nil && entry.MinBaseFee.Sign() < 0 {
+			return fmt.Errorf("negative minimum base fee at block %v", entry.Block)
+		}
+		last = entry.Block
+	}
+	return nil
+}
+
 // String implements the fmt.Stringer interface.
 func (c *ChainConfig) String() string {
 	return fmt.Sprintf("{ChainID: %v, Homestead: %v, DAO: %v, DAO Support: %v, Tangerine Whistle: %v, Spurious Dragon: %v, Byzantium: %v, Constantinople: %v, Petersburg: %v, Istanbul: %v, Muir Glacier: %v, Berlin: %v, London: %v, Arrow Glacier: %v, Gray Glacier: %v, Terminal Total Difficulty: %v, Merge Netsplit: %v, Shanghai: %v, Cancun: %v}",
@@ -387,6 +490,15 @@ func (c *ChainConfig) Description() string {
 	//}
 	banner += "\n"
 
+	if len(c.BaseFeeSchedule) > 0 {
+		banner += "Base fee schedule:\n"
+		for _, entry := range c.BaseFeeSchedule {
+			cfg := c.BaseFeeConfig(entry.Block.Uint64())
+			banner += fmt.Sprintf(" - #%-8v elasticity %d, change denominator %d, min base fee %v wei\n", entry.Block, cfg.ElasticityMultiplier, cfg.BaseFeeChangeDenominator, cfg.MinBaseFee)
+		}
+		banner += "\n"
+	}
+
 	// Add a special section for the merge as it's non-obvious
 	//if c.TerminalTotalDifficulty == nil {
 	//	banner += "The Merge is not yet available for this network!\n"
@@ -510,6 +622,16 @@ func (c *ChainConfig) IsBeijing(num uint64) bool {
 	return isForked(c.BeijingBlock, num)
 }
 
+// IsForkID returns whether num is either equal to the fork ID network upgrade block or greater.
+func (c *ChainConfig) IsForkID(num uint64) bool {
+	return isForked(c.ForkIDBlock, num)
+}
+
+// IsSakuragi returns whether num is either equal to the Sakuragi fee delegation upgrade block or greater.
+func (c *ChainConfig) IsSakuragi(num uint64) bool {
+	return isForked(c.SakuragiBlock, num)
+}
+
 func (c *ChainConfig) IsEip1559FeeCollector(num uint64) bool {
 	return c.Eip1559FeeCollector != nil && isForked(c.Eip1559FeeCollectorTransition, num)
 }
@@ -551,6 +673,9 @@ func (c *ChainConfig) CheckConfigForkOrder() error {
 	if c != nil && c.ChainID != nil && c.ChainID.Uint64() == 77 {
 		return nil
 	}
+	if err := c.checkBaseFeeSchedule(); err != nil {
+		return err
+	}
 	type fork struct {
 		name     string
 		block    *big.Int
@@ -658,6 +783,9 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head uint64) *ConfigC
 	if isForkIncompatible(c.CancunBlock, newcfg.CancunBlock, head) {
 		return newCompatError("Cancun fork block", c.CancunBlock, newcfg.CancunBlock)
 	}
+	if err := c.checkBaseFeeCompatible(newcfg, head); err != nil {
+		return err
+	}
 
 	// Parlia forks
 	//if isForkIncompatible(c.RamanujanBlock, newcfg.RamanujanBlock, head) {
@@ -687,6 +815,27 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head uint64) *ConfigC
 	return nil
 }
 
+// checkBaseFeeCompatible returns an error if the base fee parameters of any block
+// up to head differ between the two configs.
+func (c *ChainConfig) checkBaseFeeCompatible(newcfg *ChainConfig, head uint64) *ConfigCompatError {
+	var forks []*big.Int
+	for _, entry := range append(append([]*BaseFeeConfig{}, c.BaseFeeSchedule...), newcfg.BaseFeeSchedule...) {
+		if isForked(entry.Block, head) {
+			forks = append(forks, entry.Block)
+		}
+	}
+	sort.Slice(forks, func(i, j int) bool { return forks[i].Cmp(forks[j]) < 0 })
+	for _, fork := range forks {
+		stored, next := c.BaseFeeConfig(fork.Uint64()), newcfg.BaseFeeConfig(fork.Uint64())
+		if stored.ElasticityMultiplier != next.ElasticityMultiplier ||
+			stored.BaseFeeChangeDenominator != next.BaseFeeChangeDenominator ||
+			stored.MinBaseFee.Cmp(next.MinBaseFee) != 0 {
+			return newCompatError("base fee schedule", fork, fork)
+		}
+	}
+	return nil
+}
+
 // isForkIncompatible returns true if a fork scheduled at s1 cannot be rescheduled to
 // block s2 because head is already past the fork.
 func isForkIncompatible(s1, s2 *big.Int, head uint64) bool {
@@ -755,6 +904,7 @@ type Rules struct {
 	IsNano, IsMoran                                         bool
 	IsEip1559FeeCollector                                   bool
 	IsParlia, IsStarknet, IsAura, IsBeijing                 bool
+	IsSakuragi                                              bool
 }
 
 // Rules ensures c's ChainID is not nil.
@@ -783,6 +933,7 @@ func (c *ChainConfig) Rules(num uint64) *Rules {
 		IsParlia:              c.Parlia != nil,
 		IsAura:                c.Aura != nil,
 		IsBeijing:             c.IsBeijing(num),
+		IsSakuragi:            c.IsSakuragi(num),
 	}
 }
 
@@ -792,6 +943,8 @@ func ChainConfigByChainName(chain string) *ChainConfig {
 		return MainnetChainConfig
 	case networkname.TestnetChainName:
 		return TestnetChainConfig
+	case networkname.DevChainName:
+		return DevChainConfig
 	default:
 		return nil
 	}
@@ -0,0 +1,111 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestBaseFeeConfig(t *testing.T) {
+	config := &ChainConfig{
+		BaseFeeSchedule: []*BaseFeeConfig{
+			{Block: big.NewInt(10), ElasticityMultiplier: 4, MinBaseFee: big.NewInt(7)},
+			{Block: big.NewInt(20), BaseFeeChangeDenominator: 16},
+			{Block: big.NewInt(30), MinBaseFee: big.NewInt(0)},
+		},
+	}
+	tests := []struct {
+		number                  uint64
+		block                   int64
+		elasticity, denominator uint64
+		minBaseFee              int64
+	}{
+		{0, 0, ElasticityMultiplier, BaseFeeChangeDenominator, 0},
+		{9, 0, ElasticityMultiplier, BaseFeeChangeDenominator, 0},
+		{10, 10, 4, BaseFeeChangeDenominator, 7},
+		{19, 10, 4, BaseFeeChangeDenominator, 7},
+		{20, 20, 4, 16, 7},
+		{29, 20, 4, 16, 7},
+		{30, 30, 4, 16, 0},
+		{1000, 30, 4, 16, 0},
+	}
+	for _, tt := range tests {
+		have := config.BaseFeeConfig(tt.number)
+		if have.Block.Int64() != tt.block || have.ElasticityMultiplier != tt.elasticity ||
+			have.BaseFeeChangeDenominator != tt.denominator || have.MinBaseFee.Int64() != tt.minBaseFee {
+			t.Errorf("block %d: have %v, want {Block: %d, Elasticity: %d, ChangeDenominator: %d, MinBaseFee: %d}",
+				tt.number, have, tt.block, tt.elasticity, tt.denominator, tt.minBaseFee)
+		}
+	}
+}
+
+func TestCheckBaseFeeSchedule(t *testing.T) {
+	tests := []struct {
+		schedule []*BaseFeeConfig
+		valid    bool
+	}{
+		{nil, true},
+		{[]*BaseFeeConfig{{Block: big.NewInt(0)}, {Block: big.NewInt(5)}}, true},
+		{[]*BaseFeeConfig{{Block: big.NewInt(5)}, {Block: big.NewInt(5)}}, false},
+		{[]*BaseFeeConfig{{Block: big.NewInt(5)}, {Block: big.NewInt(1)}}, false},
+		{[]*BaseFeeConfig{{}}, false},
+		{[]*BaseFeeConfig{nil}, false},
+		{[]*BaseFeeConfig{{Block: big.NewInt(1), MinBaseFee: big.NewInt(-1)}}, false},
+	}
+	for i, tt := range tests {
+		err := (&ChainConfig{BaseFeeSchedule: tt.schedule}).checkBaseFeeSchedule()
+		if valid := err == nil; valid != tt.valid {
+			t.Errorf("test %d: valid mismatch: have %v (%v), want %v", i, valid, err, tt.valid)
+		}
+	}
+}
+
+func TestDevChainConfig(t *testing.T) {
+	config := DevChainConfig
+	if config.Consensus != AposConsensu || config.Apos == nil || config.Clique != nil {
+		t.Fatalf("developer chain must run on APos, have consensus %q", config.Consensus)
+	}
+	if config.Apos.RewardEpoch == 0 || config.Apos.RewardLimit == nil {
+		t.Errorf("developer chain needs a reward epoch and limit, have %v", config.Apos)
+	}
+	forks := map[string]bool{
+		"homestead":        config.IsHomestead(0),
+		"tangerineWhistle": config.IsTangerineWhistle(0),
+		"spuriousDragon":   config.IsSpuriousDragon(0),
+		"byzantium":        config.IsByzantium(0),
+		"constantinople":   config.IsConstantinople(0),
+		"petersburg":       config.IsPetersburg(0),
+		"istanbul":         config.IsIstanbul(0),
+		"muirGlacier":      config.IsMuirGlacier(0),
+		"berlin":           config.IsBerlin(0),
+		"london":           config.IsLondon(0),
+		"arrowGlacier":     config.IsArrowGlacier(0),
+		"grayGlacier":      config.IsGrayGlacier(0),
+		"shanghai":         config.IsShanghai(0),
+		"cancun":           config.IsCancun(0),
+		"prague":           config.IsPrague(0),
+		"nano":             config.IsNano(0),
+		"moran":            config.IsMoran(0),
+		"beijing":          config.IsBeijing(0),
+	}
+	for name, active := range forks {
+		if !active {
+			t.Errorf("fork %s is not active at genesis", name)
+		}
+	}
+}
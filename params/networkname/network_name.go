@@ -3,9 +3,11 @@ package networkname
 const (
 	MainnetChainName = "mainnet"
 	TestnetChainName = "testnet"
+	DevChainName     = "dev"
 )
 
 var All = []string{
 	MainnetChainName,
 	TestnetChainName,
+	DevChainName,
 }
@@ -0,0 +1,84 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/params/networkname"
+)
+
+// TrustedBlock is a block trusted out of band. A syncing node only follows
+// peers whose chain contains it, but still downloads and executes every block
+// from genesis: the state at the trusted block is not fetched from peers.
+type TrustedBlock struct {
+	Number uint64     `json:"number"`
+	Hash   types.Hash `json:"hash"`
+}
+
+// MainnetTrustedBlocks are the built-in trusted blocks of the main N42 network,
+// in ascending order.
+var MainnetTrustedBlocks = []TrustedBlock{}
+
+// TestnetTrustedBlocks are the built-in trusted blocks of the N42 test network,
+// in ascending order.
+var TestnetTrustedBlocks = []TrustedBlock{}
+
+// ParseTrustedBlock parses a trusted block given as <number>:<hash>.
+func ParseTrustedBlock(s string) (*TrustedBlock, error) {
+	number, hash, ok := strings.Cut(s, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid trusted block %q, want <number>:<hash>", s)
+	}
+	n, err := strconv.ParseUint(number, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted block number %q: %v", number, err)
+	}
+	var h types.Hash
+	if err := h.UnmarshalText([]byte(hash)); err != nil {
+		return nil, fmt.Errorf("invalid trusted block hash %q: %v", hash, err)
+	}
+	if n == 0 || h == (types.Hash{}) {
+		return nil, fmt.Errorf("invalid trusted block %q", s)
+	}
+	return &TrustedBlock{Number: n, Hash: h}, nil
+}
+
+// String implements fmt.Stringer, in the format accepted by ParseTrustedBlock.
+func (c *TrustedBlock) String() string {
+	return fmt.Sprintf("%d:%s", c.Number, c.Hash.Hex())
+}
+
+// TrustedBlockByChainName returns the latest built-in trusted block of the named
+// network, or nil if there is none.
+func TrustedBlockByChainName(chain string) *TrustedBlock {
+	var blocks []TrustedBlock
+	switch chain {
+	case networkname.MainnetChainName:
+		blocks = MainnetTrustedBlocks
+	case networkname.TestnetChainName:
+		blocks = TestnetTrustedBlocks
+	}
+	if len(blocks) == 0 {
+		return nil
+	}
+	b := blocks[len(blocks)-1]
+	return &b
+}
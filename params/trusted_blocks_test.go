@@ -0,0 +1,74 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import (
+	"testing"
+
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/params/networkname"
+)
+
+func TestParseTrustedBlock(t *testing.T) {
+	hash := "0x00000000000000000000000000000000000000000000000000000000000000ff"
+	tests := []struct {
+		input string
+		want  *TrustedBlock
+	}{
+		{"100:" + hash, &TrustedBlock{Number: 100, Hash: types.Hash{31: 0xff}}},
+		{"100", nil},
+		{"100:", nil},
+		{":" + hash, nil},
+		{"-1:" + hash, nil},
+		{"0x64:" + hash, nil},
+		{"0:" + hash, nil},
+		{"100:0x00ff", nil},
+		{"100:" + hash[2:], nil},
+		{"100:0x0000000000000000000000000000000000000000000000000000000000000000", nil},
+	}
+	for _, tt := range tests {
+		cp, err := ParseTrustedBlock(tt.input)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("%q: parsed %v, want error", tt.input, cp)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.input, err)
+			continue
+		}
+		if *cp != *tt.want {
+			t.Errorf("%q: parsed %v, want %v", tt.input, cp, tt.want)
+		}
+		if cp.String() != tt.input {
+			t.Errorf("%q: formatted as %q", tt.input, cp.String())
+		}
+	}
+}
+
+func TestTrustedBlockByChainName(t *testing.T) {
+	defer func(blocks []TrustedBlock) { TestnetTrustedBlocks = blocks }(TestnetTrustedBlocks)
+	TestnetTrustedBlocks = []TrustedBlock{{Number: 10, Hash: types.Hash{1}}, {Number: 20, Hash: types.Hash{2}}}
+
+	if cp := TrustedBlockByChainName(networkname.TestnetChainName); cp == nil || *cp != TestnetTrustedBlocks[1] {
+		t.Errorf("testnet trusted block %v, want the latest %v", cp, TestnetTrustedBlocks[1])
+	}
+	if cp := TrustedBlockByChainName("unknown"); cp != nil {
+		t.Errorf("unknown chain has trusted block %v", cp)
+	}
+}
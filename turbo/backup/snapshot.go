@@ -0,0 +1,697 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/erigontech/mdbx-go/mdbx"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	mdbx2 "github.com/ledgerwatch/erigon-lib/kv/mdbx"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/modules"
+	"github.com/n42blockchain/N42/modules/ancient"
+	"github.com/n42blockchain/N42/modules/rawdb"
+)
+
+const (
+	// ManifestFile is the name of the manifest within a backup directory. It is
+	// written last, a backup without manifest is incomplete.
+	ManifestFile = "manifest.json"
+
+	// ChaindataDir and AncientDir are the directories of the chain database and
+	// of the ancient store within a backup directory.
+	ChaindataDir = "chaindata"
+	AncientDir   = "ancient"
+
+	manifestVersion = 1
+
+	// progressInterval is the minimum time between two progress reports.
+	progressInterval = 10 * time.Second
+
+	// mapSize is the upper bound of the chain database restored from a backup,
+	// the same as the one of the node.
+	mapSize = 8 * datasize.TB
+
+	// growthStep is the growth step of the copied chain database, smaller than
+	// the default so that its file is not much larger than its content.
+	growthStep = 16 * datasize.MB
+)
+
+var (
+	ErrNotEmpty      = errors.New("backup directory is not empty")
+	ErrNoManifest    = errors.New("no backup manifest, the backup is missing or incomplete")
+	ErrVersion       = errors.New("unsupported backup version")
+	ErrChecksum      = errors.New("backup checksum mismatch")
+	ErrHeadMismatch  = errors.New("restored chain head does not match the backup")
+	ErrRestoreExists = errors.New("previous data directory in the way")
+)
+
+// Head is the head block of the chain at the time of a backup.
+type Head struct {
+	Number uint64     `json:"number"`
+	Hash   types.Hash `json:"hash"`
+}
+
+// File is a file of a backup.
+type File struct {
+	Path   string `json:"path"` // slash separated, relative to the backup directory
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// Manifest describes a backup.
+type Manifest struct {
+	Version    int        `json:"version"`
+	Created    time.Time  `json:"created"`
+	Genesis    types.Hash `json:"genesis"`
+	Head       Head       `json:"head"`
+	Compressed bool       `json:"compressed"`
+	Files      []File     `json:"files"`
+}
+
+// Config tunes a backup.
+type Config struct {
+	Compress bool           // gzip the files of the backup
+	Checksum bool           // record the SHA-256 of the files in the manifest
+	Progress func(Progress) // called periodically and at the end of each stage, if set
+}
+
+// Progress reports the progress of a stage of a backup or restore.
+type Progress struct {
+	Stage string // "chaindata", "ancient" or "files"
+	Item  string // table or file being processed
+	Done  uint64 // entries, blocks or bytes processed in the stage
+	Total uint64 // entries, blocks or bytes to process in the stage
+}
+
+// reporter accumulates the progress of a stage and throttles its reports.
+type reporter struct {
+	fn   func(Progress)
+	cur  Progress
+	last time.Time
+}
+
+func (r *reporter) start(stage string, total uint64) {
+	r.cur = Progress{Stage: stage, Total: total}
+	r.last = time.Now()
+}
+
+func (r *reporter) add(ctx context.Context, item string, n uint64) error {
+	r.cur.Item = item
+	r.cur.Done += n
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+	if r.fn != nil && time.Since(r.last) >= progressInterval {
+		r.last = time.Now()
+		r.fn(r.cur)
+	}
+	return nil
+}
+
+func (r *reporter) done() {
+	if r.fn != nil {
+		r.cur.Item = ""
+		r.fn(r.cur)
+	}
+}
+
+// progressReader reports the bytes read through it.
+type progressReader struct {
+	ctx  context.Context
+	r    io.Reader
+	p    *reporter
+	name string
+}
+
+func (pr *progressReader) Read(b []byte) (int, error) {
+	n, err := pr.r.Read(b)
+	if perr := pr.p.add(pr.ctx, pr.name, uint64(n)); perr != nil {
+		return n, perr
+	}
+	return n, err
+}
+
+// Backup writes a consistent snapshot of the chain database and of the ancient
+// store, which may be nil, into dir, which must not exist or be empty.
+//
+// The chain database is copied within a single read transaction, so the node
+// may keep running. The ancient store is copied afterwards; as blocks are only
+// deleted from the chain database once frozen, it holds at least every block
+// missing from the snapshot.
+func Backup(ctx context.Context, db kv.RoDB, ancients *ancient.Freezer, dir string, cfg Config) (m *Manifest, err error) {
+	if err := createEmptyDir(dir); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(dir)
+		}
+	}()
+
+	tx, err := db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	m = &Manifest{
+		Version:    manifestVersion,
+		Created:    time.Now().UTC().Truncate(time.Second),
+		Compressed: cfg.Compress,
+	}
+	if m.Genesis, m.Head, err = readHead(tx); err != nil {
+		return nil, err
+	}
+	p := &reporter{fn: cfg.Progress}
+	if err := copyChaindata(ctx, db, tx, filepath.Join(dir, ChaindataDir), p); err != nil {
+		return nil, err
+	}
+	tx.Rollback()
+	if ancients != nil && ancients.Ancients() > 0 {
+		if err := copyAncients(ctx, ancients, filepath.Join(dir, AncientDir), p); err != nil {
+			return nil, err
+		}
+	}
+	if m.Files, err = sealFiles(ctx, dir, cfg, p); err != nil {
+		return nil, err
+	}
+	return m, writeManifest(dir, m)
+}
+
+// createEmptyDir creates dir unless it already exists empty.
+func createEmptyDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return os.MkdirAll(dir, 0755)
+	}
+	if err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("%w: %s", ErrNotEmpty, dir)
+	}
+	return nil
+}
+
+// readHead reads the genesis and the head block of a chain database.
+func readHead(tx kv.Tx) (types.Hash, Head, error) {
+	genesis, err := rawdb.ReadCanonicalHash(tx, 0)
+	if err != nil {
+		return types.Hash{}, Head{}, err
+	}
+	hash := rawdb.ReadHeadBlockHash(tx)
+	number := rawdb.ReadHeaderNumber(tx, hash)
+	if genesis == (types.Hash{}) || number == nil {
+		return types.Hash{}, Head{}, errors.New("no chain in the database")
+	}
+	return genesis, Head{Number: *number, Hash: hash}, nil
+}
+
+func copyChaindata(ctx context.Context, db kv.RoDB, tx kv.Tx, dir string, p *reporter) error {
+	tables := db.AllTables()
+	dst, err := mdbx2.NewMDBX(log.New()).Path(dir).
+		Label(kv.ChainDB).
+		PageSize(db.PageSize()).
+		MapSize(mapSize).
+		GrowthStep(growthStep).
+		Flags(func(flags uint) uint { return flags | mdbx.NoMemInit | mdbx.WriteMap }).
+		WithTableCfg(func(_ kv.TableCfg) kv.TableCfg { return tables }).
+		Open()
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	var (
+		names []string
+		total uint64
+	)
+	for name, cfg := range tables {
+		if cfg.IsDeprecated {
+			continue
+		}
+		c, err := tx.Cursor(name)
+		if err != nil {
+			return err
+		}
+		count, err := c.Count()
+		c.Close()
+		if err != nil {
+			return err
+		}
+		names = append(names, name)
+		total += count
+	}
+	sort.Strings(names)
+
+	p.start(ChaindataDir, total)
+	for _, name := range names {
+		if err := copyTable(ctx, tx, dst, name, p); err != nil {
+			return fmt.Errorf("table %s: %w", name, err)
+		}
+	}
+	p.done()
+	dst.Close()
+	return os.Remove(filepath.Join(dir, "mdbx.lck"))
+}
+
+func copyTable(ctx context.Context, tx kv.Tx, dst kv.RwDB, table string, p *reporter) error {
+	src, err := tx.Cursor(table)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dstTx, err := dst.BeginRw(ctx)
+	if err != nil {
+		return err
+	}
+	defer dstTx.Rollback()
+	c, err := dstTx.RwCursor(table)
+	if err != nil {
+		return err
+	}
+	dup, isDupsort := c.(kv.RwCursorDupSort)
+
+	k, v, err := src.First()
+	for ; k != nil && err == nil; k, v, err = src.Next() {
+		if isDupsort {
+			err = dup.AppendDup(k, v)
+		} else {
+			err = c.Append(k, v)
+		}
+		if err != nil {
+			return err
+		}
+		if err := p.add(ctx, table, 1); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return dstTx.Commit()
+}
+
+func copyAncients(ctx context.Context, src *ancient.Freezer, dir string, p *reporter) error {
+	dst, err := ancient.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = appendAncients(ctx, src, dst, p)
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Remove(filepath.Join(dir, "FLOCK"))
+}
+
+func appendAncients(ctx context.Context, src, dst *ancient.Freezer, p *reporter) error {
+	frozen := src.Ancients()
+	kinds := ancient.Kinds()
+	p.start(AncientDir, frozen)
+	for number := uint64(0); number < frozen; number++ {
+		items := make(map[string][]byte, len(kinds))
+		for _, kind := range kinds {
+			data, err := src.Ancient(kind, number)
+			if err != nil {
+				return err
+			}
+			items[kind] = data
+		}
+		if err := dst.Append(number, items); err != nil {
+			return err
+		}
+		if err := p.add(ctx, AncientDir, 1); err != nil {
+			return err
+		}
+	}
+	p.done()
+	return dst.Sync()
+}
+
+// sealFiles compresses and checksums the files of a backup as configured,
+// returning their manifest entries.
+func sealFiles(ctx context.Context, dir string, cfg Config, p *reporter) ([]File, error) {
+	var (
+		paths []string
+		total uint64
+	)
+	if err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		paths = append(paths, path)
+		total += uint64(info.Size())
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	p.start("files", total)
+	files := make([]File, 0, len(paths))
+	for _, path := range paths {
+		file, err := sealFile(ctx, dir, path, cfg, p)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	p.done()
+	return files, nil
+}
+
+func sealFile(ctx context.Context, dir, path string, cfg Config, p *reporter) (File, error) {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return File{}, err
+	}
+	file := File{Path: filepath.ToSlash(rel)}
+	if !cfg.Compress && !cfg.Checksum {
+		info, err := os.Stat(path)
+		if err != nil {
+			return File{}, err
+		}
+		file.Size = info.Size()
+		return file, p.add(ctx, file.Path, uint64(file.Size))
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		return File{}, err
+	}
+	defer in.Close()
+	var (
+		src = &progressReader{ctx: ctx, r: in, p: p, name: file.Path}
+		sum = sha256.New()
+	)
+	if !cfg.Compress {
+		if file.Size, err = io.Copy(sum, src); err != nil {
+			return File{}, err
+		}
+		file.SHA256 = hex.EncodeToString(sum.Sum(nil))
+		return file, nil
+	}
+
+	out, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	if err != nil {
+		return File{}, err
+	}
+	defer out.Close()
+	counter := &countingWriter{w: out}
+	var w io.Writer = counter
+	if cfg.Checksum {
+		w = io.MultiWriter(counter, sum)
+	}
+	gz := gzip.NewWriter(w)
+	if _, err := io.Copy(gz, src); err != nil {
+		return File{}, err
+	}
+	if err := gz.Close(); err != nil {
+		return File{}, err
+	}
+	if err := out.Sync(); err != nil {
+		return File{}, err
+	}
+	if err := out.Close(); err != nil {
+		return File{}, err
+	}
+	in.Close()
+	if err := os.Remove(path); err != nil {
+		return File{}, err
+	}
+	file.Path += ".gz"
+	file.Size = counter.n
+	if cfg.Checksum {
+		file.SHA256 = hex.EncodeToString(sum.Sum(nil))
+	}
+	return file, nil
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(b []byte) (int, error) {
+	n, err := cw.w.Write(b)
+	cw.n += int64(n)
+	return n, err
+}
+
+func writeManifest(dir string, m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, ManifestFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, ManifestFile))
+}
+
+// ReadManifest reads the manifest of the backup in dir.
+func ReadManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNoManifest, dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	m := new(Manifest)
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("invalid backup manifest: %v", err)
+	}
+	if m.Version != manifestVersion {
+		return nil, fmt.Errorf("%w: %d", ErrVersion, m.Version)
+	}
+	return m, nil
+}
+
+// Restore verifies the backup in dir and moves it in place of the chain
+// database chaindata and of the ancient store ancientDir, which must not be in
+// use. Their previous content is kept next to them with the suffix ".old".
+//
+// The backup is first extracted next to the targets, verifying the checksums
+// of its files, and the head of the extracted chain is checked against the
+// manifest; the targets are only swapped once all checks passed.
+func Restore(ctx context.Context, dir, chaindata, ancientDir string, progress func(Progress)) (*Manifest, error) {
+	m, err := ReadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	targets := map[string]string{ChaindataDir: chaindata, AncientDir: ancientDir}
+	for _, target := range targets {
+		if _, err := os.Stat(target + ".old"); err == nil {
+			return nil, fmt.Errorf("%w: %s.old", ErrRestoreExists, target)
+		}
+	}
+	staging := make(map[string]string, len(targets))
+	for name, target := range targets {
+		staging[name] = target + ".restore"
+		if err := os.RemoveAll(staging[name]); err != nil {
+			return nil, err
+		}
+	}
+	restored := false
+	defer func() {
+		if !restored {
+			for _, path := range staging {
+				os.RemoveAll(path)
+			}
+		}
+	}()
+
+	p := &reporter{fn: progress}
+	var total uint64
+	for _, file := range m.Files {
+		total += uint64(file.Size)
+	}
+	p.start("files", total)
+	for _, file := range m.Files {
+		name, rel, _ := strings.Cut(file.Path, "/")
+		root, ok := staging[name]
+		if !ok || rel == "" || !filepath.IsLocal(rel) {
+			return nil, fmt.Errorf("invalid backup file %q", file.Path)
+		}
+		if err := extractFile(ctx, dir, file, filepath.Join(root, filepath.FromSlash(rel)), p); err != nil {
+			return nil, err
+		}
+	}
+	p.done()
+
+	if err := verifyRestore(ctx, staging[ChaindataDir], staging[AncientDir], m); err != nil {
+		return nil, err
+	}
+	for name, target := range targets {
+		if _, err := os.Stat(target); err == nil {
+			if err := os.Rename(target, target+".old"); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := os.Stat(staging[name]); err == nil {
+			if err := os.Rename(staging[name], target); err != nil {
+				return nil, err
+			}
+		}
+	}
+	restored = true
+	return m, nil
+}
+
+// extractFile copies a file of the backup in dir to path, decompressing it and
+// verifying its size and checksum.
+func extractFile(ctx context.Context, dir string, file File, path string, p *reporter) error {
+	in, err := os.Open(filepath.Join(dir, filepath.FromSlash(file.Path)))
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	var (
+		sum hash.Hash = sha256.New()
+		src io.Reader = io.TeeReader(&progressReader{ctx: ctx, r: in, p: p, name: file.Path}, sum)
+	)
+	if strings.HasSuffix(file.Path, ".gz") {
+		gz, err := gzip.NewReader(src)
+		if err != nil {
+			return fmt.Errorf("%s: %v", file.Path, err)
+		}
+		defer gz.Close()
+		src = gz
+		path = strings.TrimSuffix(path, ".gz")
+	}
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, src); err != nil {
+		return fmt.Errorf("%s: %w", file.Path, err)
+	}
+	// Drain the input so that the checksum covers trailing data as well.
+	if _, err := io.Copy(io.Discard, in); err != nil {
+		return err
+	}
+	if info, err := in.Stat(); err != nil {
+		return err
+	} else if info.Size() != file.Size {
+		return fmt.Errorf("%w: %s is %d bytes, expected %d", ErrChecksum, file.Path, info.Size(), file.Size)
+	}
+	if file.SHA256 != "" && hex.EncodeToString(sum.Sum(nil)) != file.SHA256 {
+		return fmt.Errorf("%w: %s", ErrChecksum, file.Path)
+	}
+	if err := out.Sync(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// verifyRestore checks that the extracted chain database holds the head block
+// recorded in the manifest, and that the extracted ancient store belongs to
+// the same chain.
+func verifyRestore(ctx context.Context, chaindata, ancientDir string, m *Manifest) error {
+	modules.AstInit()
+	db, err := mdbx2.NewMDBX(log.New()).Path(chaindata).
+		Label(kv.ChainDB).
+		MapSize(mapSize).
+		WithTableCfg(func(_ kv.TableCfg) kv.TableCfg { return modules.AstTableCfg }).
+		Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	tx, err := db.BeginRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	genesis, head, err := readHead(tx)
+	if err != nil {
+		return err
+	}
+	if genesis != m.Genesis || head != m.Head {
+		return fmt.Errorf("%w: head %d [%x], genesis %x", ErrHeadMismatch, head.Number, head.Hash, genesis)
+	}
+	if canonical, err := rawdb.ReadCanonicalHash(tx, head.Number); err != nil {
+		return err
+	} else if canonical != head.Hash {
+		return fmt.Errorf("%w: head %d is not canonical", ErrHeadMismatch, head.Number)
+	}
+	if rawdb.ReadHeader(tx, head.Hash, head.Number) == nil {
+		return fmt.Errorf("%w: header of head %d missing", ErrHeadMismatch, head.Number)
+	}
+
+	if _, err := os.Stat(ancientDir); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	ancients, err := ancient.Open(ancientDir)
+	if err != nil {
+		return err
+	}
+	defer ancients.Close()
+	for _, number := range []uint64{0, ancients.Ancients() - 1} {
+		if number >= ancients.Ancients() {
+			continue
+		}
+		data, err := ancients.Ancient(ancient.Hashes, number)
+		if err != nil {
+			return err
+		}
+		canonical, err := rawdb.ReadCanonicalHash(tx, number)
+		if err != nil {
+			return err
+		}
+		if types.BytesToHash(data) != canonical {
+			return fmt.Errorf("%w: ancient block %d is not canonical", ErrHeadMismatch, number)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package backup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	mdbx2 "github.com/ledgerwatch/erigon-lib/kv/mdbx"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/n42blockchain/N42/common/block"
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/modules"
+	"github.com/n42blockchain/N42/modules/ancient"
+	"github.com/n42blockchain/N42/modules/rawdb"
+)
+
+const testBlocks = 4
+
+// newTestChain writes a chain of testBlocks headers, the first two of which are
+// frozen, and returns the database and the ancient store.
+func newTestChain(t *testing.T) (kv.RwDB, *ancient.Freezer) {
+	modules.AstInit()
+	kv.ChaindataTablesCfg = modules.AstTableCfg
+	db := mdbx2.NewMDBX(log.New()).InMem(t.TempDir()).Label(kv.ChainDB).MustOpen()
+	t.Cleanup(db.Close)
+	ancients, err := ancient.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ancients.Close() })
+
+	if err := db.Update(context.Background(), func(tx kv.RwTx) error {
+		var parent types.Hash
+		for n := uint64(0); n < testBlocks; n++ {
+			header := &block.Header{ParentHash: parent, Number: uint256.NewInt(n), Difficulty: uint256.NewInt(1), BaseFee: uint256.NewInt(0)}
+			rawdb.WriteHeader(tx, header)
+			if err := rawdb.WriteCanonicalHash(tx, header.Hash(), n); err != nil {
+				return err
+			}
+			rawdb.WriteHeadBlockHash(tx, header.Hash())
+			if n < 2 {
+				items := map[string][]byte{ancient.Hashes: header.Hash().Bytes()}
+				for _, kind := range ancient.Kinds() {
+					if kind != ancient.Hashes {
+						items[kind] = []byte{byte(n)}
+					}
+				}
+				if err := ancients.Append(n, items); err != nil {
+					return err
+				}
+			}
+			parent = header.Hash()
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return db, ancients
+}
+
+func TestBackupRestore(t *testing.T) {
+	for _, cfg := range []Config{{}, {Checksum: true}, {Compress: true, Checksum: true}} {
+		db, ancients := newTestChain(t)
+		dir := filepath.Join(t.TempDir(), "backup")
+		m, err := Backup(context.Background(), db, ancients, dir, cfg)
+		if err != nil {
+			t.Fatalf("%+v: backup failed: %v", cfg, err)
+		}
+		if m.Head.Number != testBlocks-1 {
+			t.Fatalf("%+v: head %d, want %d", cfg, m.Head.Number, testBlocks-1)
+		}
+		if _, err := Backup(context.Background(), db, ancients, dir, cfg); !errors.Is(err, ErrNotEmpty) {
+			t.Fatalf("%+v: backup into existing backup: got %v, want %v", cfg, err, ErrNotEmpty)
+		}
+
+		datadir := t.TempDir()
+		chaindata, ancientDir := filepath.Join(datadir, "chaindata"), filepath.Join(datadir, "ancient")
+		if err := os.Mkdir(chaindata, 0755); err != nil {
+			t.Fatal(err)
+		}
+		restored, err := Restore(context.Background(), dir, chaindata, ancientDir, nil)
+		if err != nil {
+			t.Fatalf("%+v: restore failed: %v", cfg, err)
+		}
+		if restored.Head != m.Head {
+			t.Fatalf("%+v: restored head %v, want %v", cfg, restored.Head, m.Head)
+		}
+		if _, err := os.Stat(chaindata + ".old"); err != nil {
+			t.Fatalf("%+v: previous chaindata not kept: %v", cfg, err)
+		}
+
+		frozen, err := ancient.Open(ancientDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if frozen.Ancients() != 2 {
+			t.Fatalf("%+v: %d blocks restored in the ancient store, want 2", cfg, frozen.Ancients())
+		}
+		for n := uint64(0); n < 2; n++ {
+			for _, kind := range ancient.Kinds() {
+				want, _ := ancients.Ancient(kind, n)
+				if have, err := frozen.Ancient(kind, n); err != nil || !bytes.Equal(have, want) {
+					t.Fatalf("%+v: ancient %s %d: got %x (%v), want %x", cfg, kind, n, have, err, want)
+				}
+			}
+		}
+		frozen.Close()
+	}
+}
+
+func TestRestoreCorrupted(t *testing.T) {
+	db, ancients := newTestChain(t)
+	dir := filepath.Join(t.TempDir(), "backup")
+	m, err := Backup(context.Background(), db, ancients, dir, Config{Checksum: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, filepath.FromSlash(m.Files[0].Path))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)/2] ^= 0xff
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	datadir := t.TempDir()
+	chaindata := filepath.Join(datadir, "chaindata")
+	if _, err := Restore(context.Background(), dir, chaindata, filepath.Join(datadir, "ancient"), nil); !errors.Is(err, ErrChecksum) {
+		t.Fatalf("restore of corrupted backup: got %v, want %v", err, ErrChecksum)
+	}
+	if entries, _ := os.ReadDir(datadir); len(entries) != 0 {
+		t.Fatalf("failed restore left %d entries in the data directory", len(entries))
+	}
+}
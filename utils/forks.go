@@ -1,14 +1,176 @@
 package utils
 
 import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+
 	"github.com/holiman/uint256"
 	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/params"
+)
+
+var (
+	// ErrRemoteStale is returned by a fork filter if a remote fork ID is a
+	// subset of the local one but the remote node is not aware of the next fork.
+	ErrRemoteStale = errors.New("remote needs update")
+
+	// ErrLocalIncompatibleOrStale is returned by a fork filter if a remote fork
+	// ID is neither a subset nor a superset of the local one.
+	ErrLocalIncompatibleOrStale = errors.New("local incompatible or needs update")
 )
 
-// CreateForkDigest creates a fork digest from a genesis time and genesis
-// validators root, utilizing the current slot to determine
-// the active fork version in the node.
-func CreateForkDigest(currentBlockNr *uint256.Int, genesisValidatorsRoot types.Hash) ([4]byte, error) {
-	//todo currentBlockNr
-	return ToBytes4(genesisValidatorsRoot[:]), nil
+// ForkID is a fork identifier as defined by EIP-2124: the CRC32 checksum of the
+// genesis hash and of the blocks of the forks passed, and the block of the next
+// fork, 0 if none is scheduled.
+type ForkID struct {
+	Hash [4]byte
+	Next uint64
+}
+
+// NewForkID returns the fork ID of the chain of config and genesis at block head.
+func NewForkID(config *params.ChainConfig, genesis types.Hash, head uint64) ForkID {
+	hash := crc32.ChecksumIEEE(genesis[:])
+	for _, fork := range Forks(config) {
+		if fork > head {
+			return ForkID{Hash: checksumToBytes(hash), Next: fork}
+		}
+		hash = checksumUpdate(hash, fork)
+	}
+	return ForkID{Hash: checksumToBytes(hash)}
+}
+
+// NewForkFilter returns a function validating the fork IDs of remote nodes
+// against the local chain of config and genesis, whose head block is returned
+// by head, following the rules of EIP-2124.
+func NewForkFilter(config *params.ChainConfig, genesis types.Hash, head func() uint64) func(ForkID) error {
+	forks := Forks(config)
+	sums := make([][4]byte, len(forks)+1) // 0th is the genesis
+	hash := crc32.ChecksumIEEE(genesis[:])
+	sums[0] = checksumToBytes(hash)
+	for i, fork := range forks {
+		hash = checksumUpdate(hash, fork)
+		sums[i+1] = checksumToBytes(hash)
+	}
+	// The last fork is never passed.
+	forks = append(forks, math.MaxUint64)
+
+	return func(id ForkID) error {
+		number := head()
+		for i, fork := range forks {
+			if number >= fork {
+				continue
+			}
+			// The first fork not passed: the remote node is on the same fork if
+			// the checksums match, unless it knows of a fork we already passed.
+			if sums[i] == id.Hash {
+				if id.Next > 0 && number >= id.Next {
+					return ErrLocalIncompatibleOrStale
+				}
+				return nil
+			}
+			// The remote node is behind, which is fine if it knows of the fork
+			// following its own.
+			for j := 0; j < i; j++ {
+				if sums[j] == id.Hash {
+					if forks[j] != id.Next {
+						return ErrRemoteStale
+					}
+					return nil
+				}
+			}
+			// The remote node is ahead, we are simply out of sync.
+			for j := i + 1; j < len(sums); j++ {
+				if sums[j] == id.Hash {
+					return nil
+				}
+			}
+			return ErrLocalIncompatibleOrStale
+		}
+		return nil
+	}
+}
+
+// Forks returns the blocks of the forks scheduled by config in ascending order,
+// without duplicates and the forks active from genesis: the *Block and
+// *Transition activations and the entries of the base fee schedule. The *Time
+// activations are not blocks and are left out, the fork ID covers block forks
+// only.
+func Forks(config *params.ChainConfig) []uint64 {
+	if config == nil {
+		return nil
+	}
+	var forks []uint64
+	add := func(n *big.Int) {
+		if n != nil && n.Sign() > 0 && n.IsUint64() {
+			forks = append(forks, n.Uint64())
+		}
+	}
+	v := reflect.ValueOf(config).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Name
+		if !strings.HasSuffix(name, "Block") && !strings.HasSuffix(name, "Transition") {
+			continue
+		}
+		if n, ok := v.Field(i).Interface().(*big.Int); ok {
+			add(n)
+		}
+	}
+	for _, entry := range config.BaseFeeSchedule {
+		add(entry.Block)
+	}
+	sort.Slice(forks, func(i, j int) bool { return forks[i] < forks[j] })
+	for i := 1; i < len(forks); i++ {
+		if forks[i] == forks[i-1] {
+			forks = append(forks[:i], forks[i+1:]...)
+			i--
+		}
+	}
+	return forks
+}
+
+// CreateForkDigest returns the fork digest of the chain of config and genesis
+// at the given block, see ForkDigest.
+func CreateForkDigest(config *params.ChainConfig, currentBlockNr *uint256.Int, genesisHash types.Hash) ([4]byte, error) {
+	var number uint64 = math.MaxUint64
+	if currentBlockNr.IsUint64() {
+		number = currentBlockNr.Uint64()
+	}
+	return ForkDigest(config, genesisHash, number), nil
+}
+
+// ForkDigest returns the fork digest of the chain of config and genesis at the
+// given block. From the ForkIDBlock on, it is the hash of the fork ID and
+// changes whenever a fork activates, separating the gossip topics of the nodes
+// on either side. Before, it is the LegacyForkDigest.
+func ForkDigest(config *params.ChainConfig, genesis types.Hash, number uint64) [4]byte {
+	if config == nil || !config.IsForkID(number) {
+		return LegacyForkDigest(genesis)
+	}
+	return NewForkID(config, genesis, number).Hash
+}
+
+// LegacyForkDigest returns the fork digest of the nodes predating the fork ID
+// network upgrade: the first bytes of the genesis hash, whatever the block.
+func LegacyForkDigest(genesis types.Hash) [4]byte {
+	return ToBytes4(genesis[:])
+}
+
+// checksumUpdate extends the fork checksum hash with the block of a fork.
+func checksumUpdate(hash uint32, fork uint64) uint32 {
+	var blob [8]byte
+	binary.BigEndian.PutUint64(blob[:], fork)
+	return crc32.Update(hash, crc32.IEEETable, blob[:])
+}
+
+// checksumToBytes converts a fork checksum into its big endian form.
+func checksumToBytes(hash uint32) [4]byte {
+	var blob [4]byte
+	binary.BigEndian.PutUint32(blob[:], hash)
+	return blob
 }
@@ -0,0 +1,146 @@
+// Copyright 2023 The N42 Authors
+// This file is part of the N42 library.
+//
+// The N42 library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The N42 library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the N42 library. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"hash/crc32"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/n42blockchain/N42/common/types"
+	"github.com/n42blockchain/N42/params"
+)
+
+var (
+	testGenesis = types.HexToHash("0x5a1f0d5e1f7c3a3b8d6e2a4d2c1b0e9f8a7b6c5d4e3f2a1b0c9d8e7f6a5b4c3d")
+	testConfig  = &params.ChainConfig{
+		HomesteadBlock: big.NewInt(0),
+		LondonBlock:    big.NewInt(0),
+		ShanghaiBlock:  big.NewInt(100),
+		CancunBlock:    big.NewInt(100),
+		BeijingBlock:   big.NewInt(200),
+		ForkIDBlock:    big.NewInt(0),
+		// Time based activations are not part of the fork ID.
+		PragueTime: big.NewInt(1700000000),
+		BaseFeeSchedule: []*params.BaseFeeConfig{
+			{Block: big.NewInt(0)},
+			{Block: big.NewInt(300)},
+		},
+	}
+)
+
+func checksum(forks ...uint64) [4]byte {
+	hash := crc32.ChecksumIEEE(testGenesis[:])
+	for _, fork := range forks {
+		hash = checksumUpdate(hash, fork)
+	}
+	return checksumToBytes(hash)
+}
+
+func TestForks(t *testing.T) {
+	if forks := Forks(testConfig); !reflect.DeepEqual(forks, []uint64{100, 200, 300}) {
+		t.Fatalf("forks %v, want [100 200 300]", forks)
+	}
+	if forks := Forks(nil); forks != nil {
+		t.Fatalf("forks of no config %v, want none", forks)
+	}
+}
+
+func TestNewForkID(t *testing.T) {
+	tests := []struct {
+		head uint64
+		want ForkID
+	}{
+		{0, ForkID{Hash: checksum(), Next: 100}},
+		{99, ForkID{Hash: checksum(), Next: 100}},
+		{100, ForkID{Hash: checksum(100), Next: 200}},
+		{299, ForkID{Hash: checksum(100, 200), Next: 300}},
+		{300, ForkID{Hash: checksum(100, 200, 300)}},
+		{1000000, ForkID{Hash: checksum(100, 200, 300)}},
+	}
+	for _, tt := range tests {
+		if have := NewForkID(testConfig, testGenesis, tt.head); have != tt.want {
+			t.Errorf("head %d: fork ID %x, want %x", tt.head, have, tt.want)
+		}
+	}
+
+	digest, _ := CreateForkDigest(testConfig, uint256.NewInt(150), testGenesis)
+	if digest != checksum(100) {
+		t.Errorf("fork digest %x, want %x", digest, checksum(100))
+	}
+}
+
+func TestForkDigest(t *testing.T) {
+	// The fork digest is derived from the fork ID from the ForkIDBlock on only.
+	config := *testConfig
+	config.ForkIDBlock = big.NewInt(250)
+	legacy := [4]byte{testGenesis[0], testGenesis[1], testGenesis[2], testGenesis[3]}
+	tests := []struct {
+		number uint64
+		want   [4]byte
+	}{
+		{0, legacy},
+		{150, legacy},
+		{249, legacy},
+		{250, checksum(100, 200, 250)},
+		{300, checksum(100, 200, 250, 300)},
+	}
+	for _, tt := range tests {
+		if have := ForkDigest(&config, testGenesis, tt.number); have != tt.want {
+			t.Errorf("block %d: fork digest %x, want %x", tt.number, have, tt.want)
+		}
+	}
+	config.ForkIDBlock = nil
+	if have := ForkDigest(&config, testGenesis, 1000); have != legacy {
+		t.Errorf("no fork ID upgrade: fork digest %x, want %x", have, legacy)
+	}
+}
+
+func TestForkFilter(t *testing.T) {
+	tests := []struct {
+		head uint64
+		id   ForkID
+		err  error
+	}{
+		// Same fork, same next fork.
+		{150, ForkID{Hash: checksum(100), Next: 200}, nil},
+		// Same fork, the remote node does not know of the next fork yet.
+		{150, ForkID{Hash: checksum(100)}, nil},
+		// Same fork, the remote node knows of a fork we passed without knowing it.
+		{150, ForkID{Hash: checksum(100), Next: 120}, ErrLocalIncompatibleOrStale},
+		// The remote node is behind and aware of the fork we passed.
+		{150, ForkID{Hash: checksum(), Next: 100}, nil},
+		// The remote node is behind and not aware of the fork we passed.
+		{150, ForkID{Hash: checksum()}, ErrRemoteStale},
+		// The remote node is ahead, we are syncing.
+		{150, ForkID{Hash: checksum(100, 200), Next: 300}, nil},
+		{50, ForkID{Hash: checksum(100, 200, 300)}, nil},
+		// Another chain.
+		{150, ForkID{Hash: [4]byte{1, 2, 3, 4}}, ErrLocalIncompatibleOrStale},
+		// All forks passed, same fork.
+		{500, ForkID{Hash: checksum(100, 200, 300)}, nil},
+	}
+	for i, tt := range tests {
+		head := tt.head
+		filter := NewForkFilter(testConfig, testGenesis, func() uint64 { return head })
+		if err := filter(tt.id); err != tt.err {
+			t.Errorf("test %d: head %d, fork ID %x: error %v, want %v", i, tt.head, tt.id, err, tt.err)
+		}
+	}
+}